@@ -6,20 +6,29 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // embed zone data so branch time zones resolve on minimal images
 
 	"booking-service/internal/api"
 	"booking-service/internal/config"
+	"booking-service/internal/consumer"
 	"booking-service/internal/db"
+	"booking-service/internal/metrics"
 	"booking-service/internal/repository"
 	"booking-service/internal/service"
+	"booking-service/internal/tracing"
+	"booking-service/internal/worker"
 
 	"github.com/EricsAntony/salon/salon-shared/auth"
 	"github.com/EricsAntony/salon/salon-shared/middleware"
 	sharedconfig "github.com/EricsAntony/salon/salon-shared/config"
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -30,14 +39,23 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid configuration")
+	}
 
 	// Setup logger
 	setupLogger(cfg.Environment)
 
 	log.Info().Msg("Starting booking-service...")
 
+	// Initialize tracing (optional - only if JAEGER_ENDPOINT is set)
+	if jaegerEndpoint := os.Getenv("JAEGER_ENDPOINT"); jaegerEndpoint != "" {
+		tracingCleanup := tracing.InitTracing("booking-service", jaegerEndpoint)
+		defer tracingCleanup()
+	}
+
 	// Initialize database
-	database, err := db.NewConnection(cfg.DatabaseURL)
+	database, err := db.NewConnection(cfg.DatabaseURL, cfg.DBPool())
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
@@ -58,20 +76,67 @@ func main() {
 		}{
 			AccessSecret:     cfg.JWTAccessSecret,
 			RefreshSecret:    cfg.JWTRefreshSecret,
-			AccessTTLMinutes: 60,
-			RefreshTTLDays:   7,
+			AccessTTLMinutes: cfg.JWTAccessTTLMinutes,
+			RefreshTTLDays:   cfg.JWTRefreshTTLDays,
 		},
 	}
 	jwtManager := auth.NewJWTManager(jwtConfig)
+	jwtManager.SetRevocationStore(auth.NewRevocationCache(repository.NewRevocationRepository(database), auth.DefaultRevocationCacheTTL))
 
 	// Initialize repositories
 	bookingRepo := repository.NewBookingRepository(database)
+	tenancyRepo := repository.NewTenancyRepository(database)
 	
+	// Initialize booking event publisher
+	var eventPublisher service.EventPublisher
+	if cfg.NotificationTransport == "broker" {
+		eventPublisher = service.NewKafkaEventPublisher(cfg.KafkaBrokers, cfg.KafkaTopic)
+		defer eventPublisher.Close()
+		log.Info().Strs("brokers", cfg.KafkaBrokers).Str("topic", cfg.KafkaTopic).Msg("Publishing booking events to message broker")
+	}
+
 	// Initialize services
-	bookingService := service.NewBookingService(bookingRepo, cfg)
+	bookingService := service.NewBookingService(bookingRepo, cfg, eventPublisher)
 	
+	// Start reminder worker
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	reminderWorker := worker.NewReminderWorker(bookingService, time.Duration(cfg.ReminderCheckIntervalMinutes)*time.Minute)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reminderWorker.Start(workerCtx)
+	}()
+
+	// Start expiry worker for unpaid initiated bookings
+	expiryWorker := worker.NewExpiryWorker(bookingService, time.Duration(cfg.ExpiryCheckIntervalMinutes)*time.Minute)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		expiryWorker.Start(workerCtx)
+	}()
+
+	// Start recurring booking worker
+	recurringWorker := worker.NewRecurringWorker(bookingService, time.Duration(cfg.RecurringCheckIntervalMinutes)*time.Minute)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		recurringWorker.Start(workerCtx)
+	}()
+
+	// Start payment event consumer
+	if cfg.PaymentEventsTopic != "" {
+		paymentConsumer := consumer.NewPaymentEventConsumer(bookingService, cfg.KafkaBrokers, cfg.PaymentEventsTopic, cfg.KafkaGroupID)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			paymentConsumer.Start(workerCtx)
+		}()
+	}
+
 	// Initialize handlers
-	handlers := api.NewHandlers(bookingService)
+	handlers := api.NewHandlers(bookingService, database, api.NewDependencyChecker(cfg), tenancyRepo)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -79,9 +144,17 @@ func main() {
 	// Global middleware
 	r.Use(chimiddleware.RequestID)
 	r.Use(chimiddleware.RealIP)
+	r.Use(metrics.Middleware)
+	r.Use(tracing.Middleware)
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.Timeout(60 * time.Second))
+	r.Use(api.LookupCacheBypass)
+	// Bodies are limited before the audit middleware reads them
+	r.Use(requestbody.Middleware(requestbody.Config{
+		MaxBytes:   int64(cfg.MaxBodyBytes),
+		WriteError: sharederrors.WriteError,
+	}))
 
 	// Audit middleware
 	auditConfig := middleware.DefaultAuditConfig("booking-service")
@@ -91,28 +164,88 @@ func main() {
 	r.Get("/health", handlers.Health)
 	r.Get("/ready", handlers.Ready)
 
+	// Prometheus metrics endpoint
+	r.Handle("/metrics", promhttp.Handler())
+
+	// Guest checkout is unauthenticated, so bookings are limited per client IP
+	guestCheckoutLimiter := middleware.NewRateLimiter(10, time.Hour)
+
 	// API routes with authentication
 	r.Route("/api/v1", func(r chi.Router) {
-		// Customer authentication middleware
-		r.Use(middleware.CustomerMiddleware(jwtManager))
-		
-		// Booking routes
-		r.Post("/bookings/initiate", handlers.InitiateBooking)
-		r.Get("/stylists/{stylistId}/availability", handlers.GetStylistAvailability)
-		r.Post("/bookings/summary", handlers.CalculateBookingSummary)
-		r.Post("/bookings/confirm", handlers.ConfirmBooking)
-		r.Get("/bookings/{bookingId}", handlers.GetBooking)
-		r.Get("/bookings/user/{userId}", handlers.GetUserBookings)
-		r.Patch("/bookings/{bookingId}/cancel", handlers.CancelBooking)
-		r.Patch("/bookings/{bookingId}/reschedule", handlers.RescheduleBooking)
-		
-		// Payment routes
-		r.Post("/bookings/{bookingId}/payment/initiate", handlers.InitiatePayment)
-		r.Post("/bookings/{bookingId}/payment/callback", handlers.ProcessPaymentCallback)
-		r.Post("/bookings/{bookingId}/payment/refund", handlers.RefundPayment)
-		
-		// Branch configuration
-		r.Get("/branches/{branchId}/config", handlers.GetBranchConfig)
+		r.Group(func(r chi.Router) {
+			// Guest checkout; guests present the booking access token instead of a JWT
+			r.With(middleware.GenericRateLimitMiddleware(guestCheckoutLimiter, middleware.IPBasedKeyExtractor)).Post("/bookings/guest", handlers.GuestInitiateBooking)
+			r.Get("/guest/bookings/{bookingId}", handlers.GetGuestBooking)
+			r.Patch("/guest/bookings/{bookingId}/cancel", handlers.CancelGuestBooking)
+			r.Post("/guest/bookings/{bookingId}/payment/initiate", handlers.InitiateGuestPayment)
+		})
+
+		r.Group(func(r chi.Router) {
+			// Customer authentication middleware
+			r.Use(middleware.CustomerMiddleware(jwtManager))
+
+			// Booking routes
+			r.Post("/bookings/initiate", handlers.InitiateBooking)
+			r.Get("/stylists/{stylistId}/availability", handlers.GetStylistAvailability)
+			r.Get("/branches/{branchId}/availability", handlers.GetBranchAvailability)
+			r.Post("/bookings/summary", handlers.CalculateBookingSummary)
+			r.Post("/bookings/confirm", handlers.ConfirmBooking)
+			r.Get("/bookings/{bookingId}", handlers.GetBooking)
+			r.Delete("/bookings/{bookingId}", handlers.AbandonBooking)
+			r.Get("/bookings/{bookingId}/history", handlers.GetBookingHistory)
+			r.Get("/bookings/{bookingId}/receipt", handlers.GetBookingReceipt)
+			r.Get("/bookings/{bookingId}/notes", handlers.GetBookingNotes)
+			r.Post("/bookings/{bookingId}/notes", handlers.AddBookingNote)
+			r.Get("/bookings/user/{userId}", handlers.GetUserBookings)
+			r.Patch("/bookings/{bookingId}/cancel", handlers.CancelBooking)
+			r.Patch("/bookings/{bookingId}/reschedule", handlers.RescheduleBooking)
+
+			// Waitlist routes
+			r.Post("/waitlist", handlers.JoinWaitlist)
+			r.Get("/waitlist", handlers.GetUserWaitlist)
+			r.Delete("/waitlist/{entryId}", handlers.LeaveWaitlist)
+
+			// Recurring booking routes
+			r.Post("/recurring-bookings", handlers.CreateRecurringBooking)
+			r.Get("/recurring-bookings", handlers.GetUserRecurringBookings)
+			r.Patch("/recurring-bookings/{recurringId}/pause", handlers.PauseRecurringBooking)
+			r.Patch("/recurring-bookings/{recurringId}/resume", handlers.ResumeRecurringBooking)
+			r.Patch("/recurring-bookings/{recurringId}/cancel", handlers.CancelRecurringBooking)
+
+			// Payment routes
+			r.Post("/bookings/{bookingId}/payment/initiate", handlers.InitiatePayment)
+			r.Post("/bookings/{bookingId}/payment/callback", handlers.ProcessPaymentCallback)
+			r.Post("/bookings/{bookingId}/payment/refund", handlers.RefundPayment)
+
+			// Branch configuration
+			r.Get("/branches/{branchId}/config", handlers.GetBranchConfig)
+		})
+
+		r.Group(func(r chi.Router) {
+			// Salon staff authentication middleware
+			r.Use(middleware.SalonUserMiddleware(jwtManager))
+			// Staff may only manage branches of their own salon
+			r.Use(middleware.BranchScopedMiddleware(tenancyRepo, "branchId"))
+
+			// Branch booking management
+			r.Get("/branches/{branchId}/bookings", handlers.ListBranchBookings)
+			r.Get("/branches/{branchId}/bookings/export", handlers.ExportBranchBookings)
+			r.Get("/branches/{branchId}/reports/revenue", handlers.GetRevenueReport)
+			r.Get("/branches/{branchId}/bookings/{bookingId}/history", handlers.GetBranchBookingHistory)
+			r.Get("/branches/{branchId}/bookings/{bookingId}/receipt", handlers.GetBranchBookingReceipt)
+			r.Get("/branches/{branchId}/bookings/{bookingId}/notes", handlers.GetBranchBookingNotes)
+			r.Post("/branches/{branchId}/bookings/{bookingId}/notes", handlers.AddBranchBookingNote)
+			r.Patch("/branches/{branchId}/config", handlers.UpdateBranchConfig)
+		})
+
+		r.Group(func(r chi.Router) {
+			// Salon staff authentication middleware; the branch is in the body
+			// and is checked by the handler
+			r.Use(middleware.SalonUserMiddleware(jwtManager))
+
+			// Walk-in bookings entered by front-desk staff
+			r.Post("/bookings/staff-initiate", handlers.StaffInitiateBooking)
+		})
 	})
 
 	// Start server
@@ -140,8 +273,27 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Stop accepting requests and let in-flight ones finish
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal().Err(err).Msg("Server forced to shutdown")
+		log.Error().Err(err).Msg("Server forced to shutdown")
+	}
+
+	// Stop the reminder worker and payment consumer after their current batch
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		log.Warn().Msg("Timed out waiting for background workers to stop")
+	}
+
+	// Drain notification sends started by requests and workers
+	if err := bookingService.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("Timed out draining in-flight notifications")
 	}
 
 	log.Info().Msg("Server exited")
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"net/http"
+
+	"booking-service/internal/model"
+	"booking-service/internal/service"
+
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// AddBookingNote handles POST /bookings/{bookingId}/notes. Customers can only
+// add customer-visible notes to their own bookings.
+func (h *Handlers) AddBookingNote(w http.ResponseWriter, r *http.Request) {
+	booking, userID, ok := h.customerBooking(w, r)
+	if !ok {
+		return
+	}
+
+	var request service.AddBookingNoteRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if request.Visibility == "" {
+		request.Visibility = model.BookingNoteVisibilityCustomer
+	}
+	if request.Visibility != model.BookingNoteVisibilityCustomer {
+		errors.WriteAPIError(w, errors.NewValidationError("visibility", "customers can only add customer-visible notes"))
+		return
+	}
+
+	h.addBookingNote(w, r, booking.ID, userID, &request)
+}
+
+// GetBookingNotes handles GET /bookings/{bookingId}/notes, listing the
+// customer-visible notes of the caller's booking
+func (h *Handlers) GetBookingNotes(w http.ResponseWriter, r *http.Request) {
+	booking, _, ok := h.customerBooking(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeBookingNotes(w, r, booking.ID, false)
+}
+
+// AddBranchBookingNote handles POST /branches/{branchId}/bookings/{bookingId}/notes.
+// Staff notes are internal unless visibility is customer.
+func (h *Handlers) AddBranchBookingNote(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.branchBooking(w, r)
+	if !ok {
+		return
+	}
+
+	staffID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var request service.AddBookingNoteRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if request.Visibility == "" {
+		request.Visibility = model.BookingNoteVisibilityInternal
+	}
+
+	h.addBookingNote(w, r, booking.ID, staffID, &request)
+}
+
+// GetBranchBookingNotes handles GET /branches/{branchId}/bookings/{bookingId}/notes,
+// listing all notes including internal ones
+func (h *Handlers) GetBranchBookingNotes(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.branchBooking(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeBookingNotes(w, r, booking.ID, true)
+}
+
+// customerBooking loads the booking in the URL, writing an error response
+// unless it belongs to the authenticated customer
+func (h *Handlers) customerBooking(w http.ResponseWriter, r *http.Request) (*model.Booking, uuid.UUID, bool) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return nil, uuid.Nil, false
+	}
+
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	booking, err := h.bookingService.GetBooking(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking")
+		errors.WriteAPIError(w, err)
+		return nil, uuid.Nil, false
+	}
+	if booking.UserID != userID {
+		errors.WriteAPIError(w, errors.NewAuthError("authorization", "unauthorized access"))
+		return nil, uuid.Nil, false
+	}
+
+	return booking, userID, true
+}
+
+// branchBooking loads the booking in the URL, writing a not found response
+// unless it belongs to the branch in the URL
+func (h *Handlers) branchBooking(w http.ResponseWriter, r *http.Request) (*model.Booking, bool) {
+	branchID, err := uuid.Parse(chi.URLParam(r, "branchId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return nil, false
+	}
+
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return nil, false
+	}
+
+	booking, err := h.bookingService.GetBooking(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking")
+		errors.WriteAPIError(w, err)
+		return nil, false
+	}
+	if booking.BranchID != branchID {
+		errors.WriteAPIError(w, &errors.NotFoundError{Resource: "booking", ID: bookingID.String()})
+		return nil, false
+	}
+
+	return booking, true
+}
+
+// addBookingNote adds a note and writes it
+func (h *Handlers) addBookingNote(w http.ResponseWriter, r *http.Request, bookingID, authorID uuid.UUID, request *service.AddBookingNoteRequest) {
+	note, err := h.bookingService.AddBookingNote(r.Context(), bookingID, authorID, request)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to add booking note")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, note)
+}
+
+// writeBookingNotes loads and writes the notes of a booking
+func (h *Handlers) writeBookingNotes(w http.ResponseWriter, r *http.Request, bookingID uuid.UUID, includeInternal bool) {
+	notes, err := h.bookingService.GetBookingNotes(r.Context(), bookingID, includeInternal)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking notes")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"booking_id": bookingID,
+		"notes":      notes,
+	})
+}
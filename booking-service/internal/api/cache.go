@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"booking-service/internal/service"
+)
+
+// LookupCacheBypass makes requests sent with "Cache-Control: no-cache" skip
+// the salon-service lookup cache, e.g. to price a booking right after a
+// service price change
+func LookupCacheBypass(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") {
+			r = r.WithContext(service.WithoutLookupCache(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"booking-service/internal/config"
+)
+
+// Downstream dependency names as used in critical_dependencies
+const (
+	DependencyUserService         = "user-service"
+	DependencySalonService        = "salon-service"
+	DependencyPaymentService      = "payment-service"
+	DependencyNotificationService = "notification-service"
+)
+
+// Dependency is a downstream service pinged by the readiness check
+type Dependency struct {
+	Name     string
+	URL      string
+	Critical bool
+}
+
+// DependencyStatus is the result of pinging a dependency
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DependencyChecker pings downstream health endpoints with a short timeout
+type DependencyChecker struct {
+	dependencies []Dependency
+	timeout      time.Duration
+	httpClient   *http.Client
+}
+
+// NewDependencyChecker creates a checker for the downstream services in the configuration
+func NewDependencyChecker(cfg *config.Config) *DependencyChecker {
+	critical := make(map[string]bool, len(cfg.CriticalDependencies))
+	for _, name := range cfg.CriticalDependencies {
+		critical[name] = true
+	}
+
+	// payment-service serves its health endpoint under the API prefix
+	healthURLs := []struct{ name, url string }{
+		{DependencyUserService, cfg.UserServiceURL + "/health"},
+		{DependencySalonService, cfg.SalonServiceURL + "/health"},
+		{DependencyPaymentService, cfg.PaymentServiceURL + "/api/v1/health"},
+		{DependencyNotificationService, cfg.NotificationServiceURL + "/health"},
+	}
+
+	var dependencies []Dependency
+	for _, h := range healthURLs {
+		if strings.HasPrefix(h.url, "/") {
+			continue // service URL not configured
+		}
+		dependencies = append(dependencies, Dependency{Name: h.name, URL: h.url, Critical: critical[h.name]})
+	}
+
+	timeout := time.Duration(cfg.HealthCheckTimeoutMS) * time.Millisecond
+	return &DependencyChecker{
+		dependencies: dependencies,
+		timeout:      timeout,
+		httpClient:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Check pings every dependency concurrently. healthy is false when any
+// critical dependency is down.
+func (c *DependencyChecker) Check(ctx context.Context) (statuses map[string]DependencyStatus, healthy bool) {
+	statuses = make(map[string]DependencyStatus, len(c.dependencies))
+	healthy = true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, dependency := range c.dependencies {
+		wg.Add(1)
+		go func(dependency Dependency) {
+			defer wg.Done()
+			status := c.ping(ctx, dependency)
+
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[dependency.Name] = status
+			if status.Status != "up" && dependency.Critical {
+				healthy = false
+			}
+		}(dependency)
+	}
+	wg.Wait()
+
+	return statuses, healthy
+}
+
+func (c *DependencyChecker) ping(ctx context.Context, dependency Dependency) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	status := DependencyStatus{Status: "down", Critical: dependency.Critical}
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dependency.URL, nil)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	resp, err := c.httpClient.Do(req)
+	status.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		status.Error = fmt.Sprintf("health endpoint returned status %d", resp.StatusCode)
+		return status
+	}
+
+	status.Status = "up"
+	return status
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// exportFlushEvery is how many rows are written between flushes to the client
+const exportFlushEvery = 100
+
+var exportCSVHeader = []string{
+	"booking_id", "user_id", "service_ids", "stylist_ids", "start_time", "end_time",
+	"total_amount", "gst", "booking_fee", "discount_amount", "status", "payment_status", "created_at",
+}
+
+// ExportBranchBookings handles GET /branches/{branchId}/bookings/export
+// (?from=&to=&status=&format=csv|json). Rows are streamed as they are read
+// from the database, so the response starts before the export is complete.
+func (h *Handlers) ExportBranchBookings(w http.ResponseWriter, r *http.Request) {
+	branchID, err := uuid.Parse(chi.URLParam(r, "branchId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(strings.TrimSpace(query.Get("format")))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		errors.WriteAPIError(w, errors.NewValidationError("format", "format must be csv or json"))
+		return
+	}
+
+	filter, err := parseBranchBookingFilter(branchID, query)
+	if err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	var export bookingExportWriter
+	if format == "csv" {
+		export = newCSVExportWriter(w)
+	} else {
+		export = newJSONExportWriter(w)
+	}
+
+	// Headers are sent with the first row so validation and query errors can
+	// still be reported with a proper status
+	filename := fmt.Sprintf("bookings-%s-%s.%s", branchID, time.Now().UTC().Format("20060102"), format)
+	started := false
+	rows := 0
+	err = h.bookingService.ExportBranchBookings(r.Context(), filter, func(row *model.BookingExportRow) error {
+		if !started {
+			startExport(w, format, filename)
+			if err := export.begin(); err != nil {
+				return err
+			}
+			started = true
+		}
+		if err := export.write(row); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			export.flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to export branch bookings")
+			errors.WriteAPIError(w, err)
+			return
+		}
+		// The status is already sent; the client sees a truncated file
+		log.Error().Err(err).Str("branch_id", branchID.String()).Int("rows", rows).Msg("Branch booking export aborted")
+		return
+	}
+
+	if !started {
+		startExport(w, format, filename)
+		if err := export.begin(); err != nil {
+			log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to write booking export")
+			return
+		}
+	}
+	if err := export.end(); err != nil {
+		log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to write booking export")
+	}
+}
+
+func startExport(w http.ResponseWriter, format, filename string) {
+	contentType := "text/csv; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+}
+
+// bookingExportWriter encodes exported bookings in one output format
+type bookingExportWriter interface {
+	begin() error
+	write(row *model.BookingExportRow) error
+	flush()
+	end() error
+}
+
+type csvExportWriter struct {
+	w   http.ResponseWriter
+	csv *csv.Writer
+}
+
+func newCSVExportWriter(w http.ResponseWriter) *csvExportWriter {
+	return &csvExportWriter{w: w, csv: csv.NewWriter(w)}
+}
+
+func (e *csvExportWriter) begin() error {
+	return e.csv.Write(exportCSVHeader)
+}
+
+func (e *csvExportWriter) write(row *model.BookingExportRow) error {
+	return e.csv.Write([]string{
+		row.ID.String(),
+		row.UserID.String(),
+		strings.Join(row.ServiceIDs, ";"),
+		strings.Join(row.StylistIDs, ";"),
+		row.StartTime.Format(time.RFC3339),
+		row.EndTime.Format(time.RFC3339),
+		formatAmount(row.TotalAmount),
+		formatAmount(row.GST),
+		formatAmount(row.BookingFee),
+		formatAmount(row.DiscountAmount),
+		string(row.Status),
+		string(row.PaymentStatus),
+		row.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *csvExportWriter) flush() {
+	e.csv.Flush()
+	flushResponse(e.w)
+}
+
+func (e *csvExportWriter) end() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// jsonExportWriter streams a JSON array one element at a time
+type jsonExportWriter struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	count   int
+}
+
+func newJSONExportWriter(w http.ResponseWriter) *jsonExportWriter {
+	return &jsonExportWriter{w: w, encoder: json.NewEncoder(w)}
+}
+
+func (e *jsonExportWriter) begin() error {
+	_, err := e.w.Write([]byte("["))
+	return err
+}
+
+func (e *jsonExportWriter) write(row *model.BookingExportRow) error {
+	if e.count > 0 {
+		if _, err := e.w.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.encoder.Encode(row)
+}
+
+func (e *jsonExportWriter) flush() {
+	flushResponse(e.w)
+}
+
+func (e *jsonExportWriter) end() error {
+	_, err := e.w.Write([]byte("]\n"))
+	return err
+}
+
+func flushResponse(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// formatPercentage prints a percentage without trailing zeros, e.g. 9 or 2.5
+func formatPercentage(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', -1, 64)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"booking-service/internal/service"
+
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// guestAccessTokenHeader carries the access token issued by guest checkout
+const guestAccessTokenHeader = "X-Booking-Token"
+
+// GuestInitiateBooking handles POST /bookings/guest for customers booking
+// without an account. The response carries the booking's access token.
+func (h *Handlers) GuestInitiateBooking(w http.ResponseWriter, r *http.Request) {
+	var request service.GuestInitiateBookingRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	request.Guest.Name = strings.TrimSpace(request.Guest.Name)
+	request.Guest.PhoneNumber = strings.TrimSpace(request.Guest.PhoneNumber)
+	if request.Guest.Name == "" || request.Guest.PhoneNumber == "" {
+		errors.WriteAPIError(w, errors.NewValidationError("guest", "guest name and phone_number are required"))
+		return
+	}
+	if request.UserID != uuid.Nil {
+		errors.WriteAPIError(w, errors.NewValidationError("user_id", "user_id cannot be set for guest bookings"))
+		return
+	}
+	if err := validateBookingDetails(&request.InitiateBookingRequest, time.Now()); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	response, err := h.bookingService.GuestInitiateBooking(r.Context(), &request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initiate guest booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+// GetGuestBooking handles GET /guest/bookings/{bookingId}
+func (h *Handlers) GetGuestBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	booking, err := h.bookingService.GetGuestBooking(r.Context(), bookingID, r.Header.Get(guestAccessTokenHeader))
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get guest booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, booking)
+}
+
+// CancelGuestBooking handles PATCH /guest/bookings/{bookingId}/cancel
+func (h *Handlers) CancelGuestBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	if err := h.bookingService.GuestCancelBooking(r.Context(), bookingID, r.Header.Get(guestAccessTokenHeader), request.Reason); err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to cancel guest booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Booking canceled successfully",
+	})
+}
+
+// InitiateGuestPayment handles POST /guest/bookings/{bookingId}/payment/initiate
+func (h *Handlers) InitiateGuestPayment(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	var request struct {
+		Gateway string `json:"gateway"`
+	}
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if request.Gateway == "" {
+		request.Gateway = "stripe" // Default gateway
+	}
+
+	paymentResponse, err := h.bookingService.GuestInitiatePayment(r.Context(), bookingID, r.Header.Get(guestAccessTokenHeader), request.Gateway)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to initiate guest payment")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, paymentResponse)
+}
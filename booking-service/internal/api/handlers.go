@@ -1,31 +1,43 @@
 package api
 
 import (
-	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"booking-service/internal/db"
+	"booking-service/internal/model"
 	"booking-service/internal/service"
 
 	"github.com/EricsAntony/salon/salon-shared/auth"
 	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/middleware"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
 	"github.com/EricsAntony/salon/salon-shared/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
 // Handlers contains all HTTP handlers for the booking service
 type Handlers struct {
 	bookingService service.BookingService
+	database       *pgxpool.Pool
+	dependencies   *DependencyChecker
+	// branchAccess checks staff access to branches named in request bodies
+	branchAccess middleware.BranchRepository
 }
 
 // NewHandlers creates a new handlers instance
-func NewHandlers(bookingService service.BookingService) *Handlers {
+func NewHandlers(bookingService service.BookingService, database *pgxpool.Pool, dependencies *DependencyChecker, branchAccess middleware.BranchRepository) *Handlers {
 	return &Handlers{
 		bookingService: bookingService,
+		database:       database,
+		dependencies:   dependencies,
+		branchAccess:   branchAccess,
 	}
 }
 
@@ -42,7 +54,8 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 // Ready returns the readiness status of the service
 func (h *Handlers) Ready(w http.ResponseWriter, r *http.Request) {
 	// Check database connectivity
-	if err := db.HealthCheck(nil); err != nil {
+	if err := db.HealthCheck(r.Context(), h.database); err != nil {
+		log.Error().Err(err).Msg("Readiness database check failed")
 		response := map[string]interface{}{
 			"status": "not ready",
 			"error":  "database connection failed",
@@ -51,10 +64,25 @@ func (h *Handlers) Ready(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check downstream services; only critical ones affect readiness
+	dependencies, healthy := h.dependencies.Check(r.Context())
+	if !healthy {
+		response := map[string]interface{}{
+			"status":       "not ready",
+			"service":      "booking-service",
+			"error":        "critical dependency unavailable",
+			"dependencies": dependencies,
+			"timestamp":    time.Now().UTC(),
+		}
+		utils.WriteJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
 	response := map[string]interface{}{
-		"status":    "ready",
-		"service":   "booking-service",
-		"timestamp": time.Now().UTC(),
+		"status":       "ready",
+		"service":      "booking-service",
+		"dependencies": dependencies,
+		"timestamp":    time.Now().UTC(),
 	}
 	utils.WriteJSON(w, http.StatusOK, response)
 }
@@ -62,8 +90,8 @@ func (h *Handlers) Ready(w http.ResponseWriter, r *http.Request) {
 // InitiateBooking handles POST /bookings/initiate
 func (h *Handlers) InitiateBooking(w http.ResponseWriter, r *http.Request) {
 	var request service.InitiateBookingRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -82,6 +110,11 @@ func (h *Handlers) InitiateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 	request.UserID = parsedUserID
 
+	// The Idempotency-Key header takes precedence over the body field
+	if key := strings.TrimSpace(r.Header.Get("Idempotency-Key")); key != "" {
+		request.IdempotencyKey = key
+	}
+
 	// Validate request
 	if err := h.validateInitiateBookingRequest(&request); err != nil {
 		errors.WriteAPIError(w, err)
@@ -92,7 +125,7 @@ func (h *Handlers) InitiateBooking(w http.ResponseWriter, r *http.Request) {
 	booking, err := h.bookingService.InitiateBooking(r.Context(), &request)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initiate booking")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "booking", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -135,7 +168,7 @@ func (h *Handlers) GetStylistAvailability(w http.ResponseWriter, r *http.Request
 	slots, err := h.bookingService.GetStylistAvailability(r.Context(), salonID, stylistID, date)
 	if err != nil {
 		log.Error().Err(err).Str("stylist_id", stylistID.String()).Msg("Failed to get stylist availability")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "availability", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -147,11 +180,58 @@ func (h *Handlers) GetStylistAvailability(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// GetBranchAvailability handles GET /branches/{branchId}/availability
+func (h *Handlers) GetBranchAvailability(w http.ResponseWriter, r *http.Request) {
+	branchID, err := uuid.Parse(chi.URLParam(r, "branchId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	salonIDStr := r.URL.Query().Get("salon_id")
+	if salonIDStr == "" {
+		errors.WriteAPIError(w, errors.NewValidationError("salon_id", "salon_id parameter is required"))
+		return
+	}
+
+	salonID, err := uuid.Parse(salonIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("salon_id", "invalid salon ID format"))
+		return
+	}
+
+	dateStr := r.URL.Query().Get("date")
+	if dateStr == "" {
+		errors.WriteAPIError(w, errors.NewValidationError("date", "date parameter is required"))
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("date", "invalid date format, use YYYY-MM-DD"))
+		return
+	}
+
+	stylists, err := h.bookingService.GetBranchAvailability(r.Context(), salonID, branchID, date)
+	if err != nil {
+		log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to get branch availability")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"salon_id":  salonID,
+		"branch_id": branchID,
+		"date":      date.Format("2006-01-02"),
+		"stylists":  stylists,
+	})
+}
+
 // CalculateBookingSummary handles POST /bookings/summary
 func (h *Handlers) CalculateBookingSummary(w http.ResponseWriter, r *http.Request) {
 	var request service.BookingSummaryRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -164,7 +244,7 @@ func (h *Handlers) CalculateBookingSummary(w http.ResponseWriter, r *http.Reques
 	summary, err := h.bookingService.CalculateBookingSummary(r.Context(), &request)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to calculate booking summary")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "booking_summary", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -178,8 +258,8 @@ func (h *Handlers) ConfirmBooking(w http.ResponseWriter, r *http.Request) {
 		PaymentID string    `json:"payment_id"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -196,7 +276,7 @@ func (h *Handlers) ConfirmBooking(w http.ResponseWriter, r *http.Request) {
 	booking, err := h.bookingService.ConfirmBooking(r.Context(), request.BookingID, request.PaymentID)
 	if err != nil {
 		log.Error().Err(err).Str("booking_id", request.BookingID.String()).Msg("Failed to confirm booking")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "booking", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -215,13 +295,89 @@ func (h *Handlers) GetBooking(w http.ResponseWriter, r *http.Request) {
 	booking, err := h.bookingService.GetBooking(r.Context(), bookingID)
 	if err != nil {
 		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking")
-		errors.WriteAPIError(w, &errors.NotFoundError{Resource: "booking", ID: bookingID.String()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, booking)
 }
 
+// GetBookingHistory handles GET /bookings/{bookingId}/history
+func (h *Handlers) GetBookingHistory(w http.ResponseWriter, r *http.Request) {
+	bookingIDStr := chi.URLParam(r, "bookingId")
+	bookingID, err := uuid.Parse(bookingIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	// Get authenticated user ID from context
+	authUserID, ok := r.Context().Value(auth.CtxUserID).(string)
+	if !ok {
+		errors.WriteAPIError(w, errors.NewAuthError("authentication", "user not authenticated"))
+		return
+	}
+
+	booking, err := h.bookingService.GetBooking(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	// Customers can only read the history of their own bookings
+	if booking.UserID.String() != authUserID {
+		errors.WriteAPIError(w, errors.NewAuthError("authorization", "unauthorized access"))
+		return
+	}
+
+	h.writeBookingHistory(w, r, bookingID)
+}
+
+// GetBranchBookingHistory handles GET /branches/{branchId}/bookings/{bookingId}/history
+func (h *Handlers) GetBranchBookingHistory(w http.ResponseWriter, r *http.Request) {
+	branchID, err := uuid.Parse(chi.URLParam(r, "branchId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	bookingIDStr := chi.URLParam(r, "bookingId")
+	bookingID, err := uuid.Parse(bookingIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	booking, err := h.bookingService.GetBooking(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if booking.BranchID != branchID {
+		errors.WriteAPIError(w, &errors.NotFoundError{Resource: "booking", ID: bookingID.String()})
+		return
+	}
+
+	h.writeBookingHistory(w, r, bookingID)
+}
+
+// writeBookingHistory loads and writes the history of a booking
+func (h *Handlers) writeBookingHistory(w http.ResponseWriter, r *http.Request, bookingID uuid.UUID) {
+	history, err := h.bookingService.GetBookingHistory(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking history")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"booking_id": bookingID,
+		"history":    history,
+	})
+}
+
 // GetUserBookings handles GET /bookings/user/{userId}
 func (h *Handlers) GetUserBookings(w http.ResponseWriter, r *http.Request) {
 	userIDStr := chi.URLParam(r, "userId")
@@ -244,34 +400,178 @@ func (h *Handlers) GetUserBookings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse pagination parameters
-	limit := 20 // default
-	offset := 0 // default
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		writePaginationError(w, err)
+		return
+	}
 
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-			limit = parsedLimit
-		}
+	filter := model.UserBookingFilter{
+		UserID: userID,
+		Sort:   model.UserBookingSort(r.URL.Query().Get("sort")),
+		Scope:  model.UserBookingScope(r.URL.Query().Get("scope")),
+		Limit:  page.Limit,
+		Offset: page.Offset,
 	}
 
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
+	// created_at keeps the original newest-first listing and remains the default
+	if filter.Sort == "" {
+		filter.Sort = model.UserBookingSortCreatedAt
+	}
+
+	switch filter.Sort {
+	case model.UserBookingSortCreatedAt, model.UserBookingSortStartTime:
+	default:
+		errors.WriteAPIError(w, errors.NewValidationError("sort", "sort must be created_at or start_time"))
+		return
+	}
+
+	switch filter.Scope {
+	case model.UserBookingScopeAll, model.UserBookingScopeUpcoming, model.UserBookingScopePast:
+	default:
+		errors.WriteAPIError(w, errors.NewValidationError("scope", "scope must be upcoming or past"))
+		return
+	}
+
+	if page.Cursor != "" {
+		filter.Cursor, err = model.ParseBookingCursor(page.Cursor)
+		if err != nil {
+			errors.WriteAPIError(w, errors.NewValidationError("cursor", "invalid cursor"))
+			return
 		}
 	}
 
-	bookings, err := h.bookingService.GetUserBookings(r.Context(), userID, limit, offset)
+	bookings, total, err := h.bookingService.GetUserBookings(r.Context(), filter)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get user bookings")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "bookings", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"bookings": bookings,
-		"limit":    limit,
-		"offset":   offset,
-	})
+	response := pagination.NewListResponse(bookings, total, page)
+	if len(bookings) == page.Limit {
+		response.NextCursor = model.NewBookingCursor(bookings[len(bookings)-1], filter.Sort).Encode()
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// ListBranchBookings handles GET /branches/{branchId}/bookings
+func (h *Handlers) ListBranchBookings(w http.ResponseWriter, r *http.Request) {
+	branchIDStr := chi.URLParam(r, "branchId")
+	branchID, err := uuid.Parse(branchIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	query := r.URL.Query()
+
+	page, err := pagination.Parse(query)
+	if err != nil {
+		writePaginationError(w, err)
+		return
+	}
+
+	filter, err := parseBranchBookingFilter(branchID, query)
+	if err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+	filter.Limit = page.Limit
+	filter.Offset = page.Offset
+
+	bookings, total, err := h.bookingService.ListBookings(r.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to list branch bookings")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, pagination.NewListResponse(bookings, total, page))
+}
+
+// GetRevenueReport handles GET /branches/{branchId}/reports/revenue
+// (?group_by=service|stylist&from=&to=&status=)
+func (h *Handlers) GetRevenueReport(w http.ResponseWriter, r *http.Request) {
+	branchID, err := uuid.Parse(chi.URLParam(r, "branchId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	query := r.URL.Query()
+	bookingFilter, err := parseBranchBookingFilter(branchID, query)
+	if err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	filter := model.RevenueReportFilter{
+		BranchID: branchID,
+		GroupBy:  model.RevenueGroupBy(query.Get("group_by")),
+		Statuses: bookingFilter.Statuses,
+	}
+	if filter.GroupBy == "" {
+		filter.GroupBy = model.RevenueGroupByService
+	}
+	if bookingFilter.From != nil {
+		filter.From = *bookingFilter.From
+	}
+	if bookingFilter.To != nil {
+		filter.To = *bookingFilter.To
+	}
+
+	report, err := h.bookingService.GetRevenueReport(r.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to build revenue report")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// parseBranchBookingFilter reads the status, from and to query parameters
+// shared by the branch booking list and export. A plain to date includes the whole day.
+func parseBranchBookingFilter(branchID uuid.UUID, query url.Values) (model.BookingFilter, error) {
+	filter := model.BookingFilter{BranchID: branchID}
+
+	if statusStr := query.Get("status"); statusStr != "" {
+		for _, value := range strings.Split(statusStr, ",") {
+			status := model.BookingStatus(strings.TrimSpace(value))
+			if !status.IsValid() {
+				return filter, errors.NewValidationError("status", "invalid booking status: "+string(status))
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err := parseDateOrTime(fromStr)
+		if err != nil {
+			return filter, errors.NewValidationError("from", "invalid date format, use YYYY-MM-DD or RFC3339")
+		}
+		filter.From = &from
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		to, err := parseDateOrTime(toStr)
+		if err != nil {
+			return filter, errors.NewValidationError("to", "invalid date format, use YYYY-MM-DD or RFC3339")
+		}
+		// A plain date includes the whole day
+		if _, err := time.Parse("2006-01-02", toStr); err == nil {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return filter, errors.NewValidationError("to", "to must be after from")
+	}
+
+	return filter, nil
 }
 
 // CancelBooking handles PATCH /bookings/{bookingId}/cancel
@@ -287,8 +587,8 @@ func (h *Handlers) CancelBooking(w http.ResponseWriter, r *http.Request) {
 		Reason string `json:"reason"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -307,7 +607,7 @@ func (h *Handlers) CancelBooking(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.bookingService.CancelBooking(r.Context(), bookingID, userID, request.Reason); err != nil {
 		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to cancel booking")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "booking", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -316,6 +616,37 @@ func (h *Handlers) CancelBooking(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// AbandonBooking handles DELETE /bookings/{bookingId}, voiding an unpaid booking
+func (h *Handlers) AbandonBooking(w http.ResponseWriter, r *http.Request) {
+	bookingIDStr := chi.URLParam(r, "bookingId")
+	bookingID, err := uuid.Parse(bookingIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	// Get user ID from context
+	userIDStr, ok := r.Context().Value(auth.CtxUserID).(string)
+	if !ok {
+		errors.WriteAPIError(w, errors.NewAuthError("authentication", "user not authenticated"))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("user_id", "invalid user ID format"))
+		return
+	}
+
+	if err := h.bookingService.AbandonBooking(r.Context(), bookingID, userID); err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to abandon booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // RescheduleBooking handles PATCH /bookings/{bookingId}/reschedule
 func (h *Handlers) RescheduleBooking(w http.ResponseWriter, r *http.Request) {
 	bookingIDStr := chi.URLParam(r, "bookingId")
@@ -326,8 +657,8 @@ func (h *Handlers) RescheduleBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request service.RescheduleBookingRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -356,7 +687,7 @@ func (h *Handlers) RescheduleBooking(w http.ResponseWriter, r *http.Request) {
 	booking, err := h.bookingService.RescheduleBooking(r.Context(), &request)
 	if err != nil {
 		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to reschedule booking")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "booking", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -375,18 +706,71 @@ func (h *Handlers) GetBranchConfig(w http.ResponseWriter, r *http.Request) {
 	config, err := h.bookingService.GetBranchConfiguration(r.Context(), branchID)
 	if err != nil {
 		log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to get branch configuration")
-		errors.WriteAPIError(w, &errors.NotFoundError{Resource: "branch_configuration", ID: branchID.String()})
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, config)
+}
+
+// parseDateOrTime parses a query value given either as YYYY-MM-DD or RFC3339
+func parseDateOrTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// writePaginationError reports an invalid limit or offset as a validation error
+func writePaginationError(w http.ResponseWriter, err error) {
+	field := "pagination"
+	if paramErr, ok := err.(*pagination.ParamError); ok {
+		field = paramErr.Param
+	}
+	errors.WriteAPIError(w, errors.NewValidationError(field, err.Error()))
+}
+
+// UpdateBranchConfig handles PATCH /branches/{branchId}/config
+func (h *Handlers) UpdateBranchConfig(w http.ResponseWriter, r *http.Request) {
+	branchIDStr := chi.URLParam(r, "branchId")
+	branchID, err := uuid.Parse(branchIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	var request service.UpdateBranchConfigurationRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	// Validate request
+	if err := h.validateUpdateBranchConfigRequest(&request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	config, err := h.bookingService.UpdateBranchConfiguration(r.Context(), branchID, &request)
+	if err != nil {
+		log.Error().Err(err).Str("branch_id", branchID.String()).Msg("Failed to update branch configuration")
+		errors.WriteAPIError(w, err)
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, config)
 }
 
-// Validation helper functions
 func (h *Handlers) validateInitiateBookingRequest(request *service.InitiateBookingRequest) error {
 	if request.UserID == uuid.Nil {
 		return errors.NewValidationError("user_id", "user_id is required")
 	}
+	return validateBookingDetails(request, time.Now())
+}
+
+// validateBookingDetails checks everything but the user of a new booking.
+// Services may not start before notBefore.
+func validateBookingDetails(request *service.InitiateBookingRequest, notBefore time.Time) error {
 	if request.SalonID == uuid.Nil {
 		return errors.NewValidationError("salon_id", "salon_id is required")
 	}
@@ -396,6 +780,9 @@ func (h *Handlers) validateInitiateBookingRequest(request *service.InitiateBooki
 	if len(request.Services) == 0 {
 		return errors.NewValidationError("services", "at least one service is required")
 	}
+	if len(request.IdempotencyKey) > 255 {
+		return errors.NewValidationError("idempotency_key", "idempotency_key must be at most 255 characters")
+	}
 
 	for i, service := range request.Services {
 		if service.ServiceID == uuid.Nil {
@@ -407,9 +794,12 @@ func (h *Handlers) validateInitiateBookingRequest(request *service.InitiateBooki
 		if service.StartTime.IsZero() {
 			return errors.NewValidationError("services", "start_time is required for service "+strconv.Itoa(i))
 		}
-		if service.StartTime.Before(time.Now()) {
+		if service.StartTime.Before(notBefore) {
 			return errors.NewValidationError("services", "start_time cannot be in the past for service "+strconv.Itoa(i))
 		}
+		if service.BeneficiaryName != nil && len(*service.BeneficiaryName) > 255 {
+			return errors.NewValidationError("services", "beneficiary_name must be at most 255 characters for service "+strconv.Itoa(i))
+		}
 	}
 
 	return nil
@@ -438,6 +828,78 @@ func (h *Handlers) validateBookingSummaryRequest(request *service.BookingSummary
 	return nil
 }
 
+func (h *Handlers) validateUpdateBranchConfigRequest(request *service.UpdateBranchConfigurationRequest) error {
+	if request.Version == nil {
+		return errors.NewValidationError("version", "version is required; send the version returned when the configuration was read")
+	}
+	if request.BufferTimeMinutes != nil && *request.BufferTimeMinutes < 0 {
+		return errors.NewValidationError("buffer_time_minutes", "buffer_time_minutes cannot be negative")
+	}
+	if request.CancellationCutoffHours != nil && *request.CancellationCutoffHours < 0 {
+		return errors.NewValidationError("cancellation_cutoff_hours", "cancellation_cutoff_hours cannot be negative")
+	}
+	if request.RescheduleWindowHours != nil && *request.RescheduleWindowHours < 0 {
+		return errors.NewValidationError("reschedule_window_hours", "reschedule_window_hours cannot be negative")
+	}
+	if request.MaxAdvanceBookingDays != nil && *request.MaxAdvanceBookingDays <= 0 {
+		return errors.NewValidationError("max_advance_booking_days", "max_advance_booking_days must be greater than zero")
+	}
+	if request.SlotIntervalMinutes != nil && !model.IsValidSlotInterval(*request.SlotIntervalMinutes) {
+		return errors.NewValidationError("slot_interval_minutes", "slot_interval_minutes must be one of 10, 15, 20, 30 or 60")
+	}
+	for _, leadTime := range request.ReminderLeadTimes {
+		if leadTime <= 0 || leadTime > 7*24*60 {
+			return errors.NewValidationError("reminder_lead_times_minutes", "reminder lead times must be between 1 minute and 7 days")
+		}
+	}
+	if request.CancellationFeePercentage != nil && (*request.CancellationFeePercentage < 0 || *request.CancellationFeePercentage > 100) {
+		return errors.NewValidationError("cancellation_fee_percentage", "cancellation_fee_percentage must be between 0 and 100")
+	}
+	if request.CancellationFeeWindowHours != nil && *request.CancellationFeeWindowHours < 0 {
+		return errors.NewValidationError("cancellation_fee_window_hours", "cancellation_fee_window_hours cannot be negative")
+	}
+	if request.BookingFeeAmount != nil && *request.BookingFeeAmount < 0 {
+		return errors.NewValidationError("booking_fee_amount", "booking_fee_amount cannot be negative")
+	}
+	if request.GSTPercentage != nil && (*request.GSTPercentage < 0 || *request.GSTPercentage > 100) {
+		return errors.NewValidationError("gst_percentage", "gst_percentage must be between 0 and 100")
+	}
+	if request.TaxComponents != nil {
+		if err := validateTaxComponents(request.TaxComponents); err != nil {
+			return err
+		}
+		if request.GSTPercentage != nil && *request.GSTPercentage != model.TotalTaxPercentage(request.TaxComponents) {
+			return errors.NewValidationError("gst_percentage", "gst_percentage must equal the total of tax_components; omit it to have it calculated")
+		}
+	}
+
+	return nil
+}
+
+// validateTaxComponents checks a branch's tax components, trimming their
+// names. Components need distinct names and may total at most 100 percent.
+func validateTaxComponents(components []model.TaxComponent) error {
+	seen := make(map[string]bool, len(components))
+	for i := range components {
+		components[i].Name = strings.TrimSpace(components[i].Name)
+		name := strings.ToUpper(components[i].Name)
+		if name == "" {
+			return errors.NewValidationError("tax_components", "name is required for tax component "+strconv.Itoa(i))
+		}
+		if seen[name] {
+			return errors.NewValidationError("tax_components", "duplicate tax component "+components[i].Name)
+		}
+		seen[name] = true
+		if components[i].Percentage < 0 || components[i].Percentage > 100 {
+			return errors.NewValidationError("tax_components", "percentage of tax component "+components[i].Name+" must be between 0 and 100")
+		}
+	}
+	if model.TotalTaxPercentage(components) > 100 {
+		return errors.NewValidationError("tax_components", "tax components cannot total more than 100 percent")
+	}
+	return nil
+}
+
 func (h *Handlers) validateRescheduleBookingRequest(request *service.RescheduleBookingRequest) error {
 	if request.BookingID == uuid.Nil {
 		return errors.NewValidationError("booking_id", "booking_id is required")
@@ -462,6 +924,9 @@ func (h *Handlers) validateRescheduleBookingRequest(request *service.RescheduleB
 		if service.StartTime.Before(time.Now()) {
 			return errors.NewValidationError("services", "start_time cannot be in the past for service "+strconv.Itoa(i))
 		}
+		if service.BeneficiaryName != nil && len(*service.BeneficiaryName) > 255 {
+			return errors.NewValidationError("services", "beneficiary_name must be at most 255 characters for service "+strconv.Itoa(i))
+		}
 	}
 
 	return nil
@@ -482,8 +947,8 @@ func (h *Handlers) InitiatePayment(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		Gateway string `json:"gateway"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -496,7 +961,7 @@ func (h *Handlers) InitiatePayment(w http.ResponseWriter, r *http.Request) {
 	paymentResponse, err := h.bookingService.InitiatePaymentForBooking(r.Context(), bookingID, request.Gateway)
 	if err != nil {
 		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to initiate payment")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "payment", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -517,8 +982,8 @@ func (h *Handlers) ProcessPaymentCallback(w http.ResponseWriter, r *http.Request
 		GatewayPaymentID string `json:"gateway_payment_id"`
 		Status           string `json:"status"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -543,7 +1008,7 @@ func (h *Handlers) ProcessPaymentCallback(w http.ResponseWriter, r *http.Request
 	// Process payment callback
 	if err := h.bookingService.ProcessPaymentCallback(r.Context(), bookingID, paymentID, request.GatewayPaymentID); err != nil {
 		log.Error().Err(err).Str("booking_id", bookingID.String()).Str("payment_id", request.PaymentID).Msg("Failed to process payment callback")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "payment_callback", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -567,8 +1032,8 @@ func (h *Handlers) RefundPayment(w http.ResponseWriter, r *http.Request) {
 		Amount *float64 `json:"amount,omitempty"`
 		Reason string   `json:"reason"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.NewValidationError("request_body", "invalid JSON format"))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -582,7 +1047,7 @@ func (h *Handlers) RefundPayment(w http.ResponseWriter, r *http.Request) {
 	refundResponse, err := h.bookingService.RefundBookingPayment(r.Context(), bookingID, request.Reason, request.Amount)
 	if err != nil {
 		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to initiate refund")
-		errors.WriteAPIError(w, &errors.ConflictError{Resource: "refund", Detail: err.Error()})
+		errors.WriteAPIError(w, err)
 		return
 	}
 
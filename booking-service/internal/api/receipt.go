@@ -0,0 +1,240 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/rs/zerolog/log"
+)
+
+// GetBookingReceipt handles GET /bookings/{bookingId}/receipt. Customers may
+// only download receipts for their own bookings.
+func (h *Handlers) GetBookingReceipt(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	authUserID, ok := r.Context().Value(auth.CtxUserID).(string)
+	if !ok {
+		errors.WriteAPIError(w, errors.NewAuthError("authentication", "user not authenticated"))
+		return
+	}
+
+	receipt, err := h.bookingService.GetBookingReceipt(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking receipt")
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if receipt.UserID.String() != authUserID {
+		errors.WriteAPIError(w, errors.NewAuthError("authorization", "unauthorized access"))
+		return
+	}
+
+	writeReceipt(w, r, receipt)
+}
+
+// GetBranchBookingReceipt handles GET /branches/{branchId}/bookings/{bookingId}/receipt
+// for salon staff of the branch
+func (h *Handlers) GetBranchBookingReceipt(w http.ResponseWriter, r *http.Request) {
+	branchID, err := uuid.Parse(chi.URLParam(r, "branchId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "invalid branch ID format"))
+		return
+	}
+
+	bookingID, err := uuid.Parse(chi.URLParam(r, "bookingId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("booking_id", "invalid booking ID format"))
+		return
+	}
+
+	receipt, err := h.bookingService.GetBookingReceipt(r.Context(), bookingID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to get booking receipt")
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if receipt.BranchID != branchID {
+		errors.WriteAPIError(w, &errors.NotFoundError{Resource: "booking", ID: bookingID.String()})
+		return
+	}
+
+	writeReceipt(w, r, receipt)
+}
+
+// writeReceipt renders the receipt as a PDF. Rendering is deterministic, so
+// the ETag only changes with the booking and clients may revalidate cheaply.
+func writeReceipt(w http.ResponseWriter, r *http.Request, receipt *model.BookingReceipt) {
+	var buf bytes.Buffer
+	if err := renderReceiptPDF(&buf, receipt); err != nil {
+		log.Error().Err(err).Str("booking_id", receipt.BookingID.String()).Msg("Failed to render booking receipt")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Last-Modified", receipt.UpdatedAt.UTC().Format(http.TimeFormat))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "receipt-"+receipt.Number+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Error().Err(err).Str("booking_id", receipt.BookingID.String()).Msg("Failed to write booking receipt")
+	}
+}
+
+// renderReceiptPDF draws the receipt on an A4 page with the core Helvetica
+// font. Document dates come from the receipt, not the clock, so the same
+// receipt always renders to the same bytes.
+func renderReceiptPDF(out *bytes.Buffer, receipt *model.BookingReceipt) error {
+	loc := time.UTC
+	if receipt.Timezone != "" {
+		if l, err := time.LoadLocation(receipt.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCatalogSort(true)
+	pdf.SetCreationDate(receipt.IssuedAt)
+	pdf.SetModificationDate(receipt.UpdatedAt)
+	pdf.SetTitle("Receipt "+receipt.Number, true)
+	pdf.SetAuthor(receipt.SalonName, true)
+	pdf.SetCreator("booking-service", false)
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+
+	// The core fonts are Latin-1, so text is translated from UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 30
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(contentWidth, 8, tr(receipt.SalonName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range []string{receipt.SalonAddress, receipt.BranchName, receipt.BranchAddress, phoneLine(receipt.BranchPhone)} {
+		if line != "" {
+			pdf.CellFormat(contentWidth, 5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(contentWidth, 7, "Payment Receipt", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	receiptField(pdf, contentWidth, "Receipt number", receipt.Number)
+	receiptField(pdf, contentWidth, "Date", receipt.IssuedAt.In(loc).Format("02 Jan 2006 15:04 MST"))
+	receiptField(pdf, contentWidth, "Booking", receipt.BookingID.String())
+	if receipt.PaymentReference != "" {
+		receiptField(pdf, contentWidth, "Payment reference", receipt.PaymentReference)
+	}
+	if receipt.PaymentStatus == model.PaymentStatusRefunded {
+		receiptField(pdf, contentWidth, "Payment status", "Refunded")
+	}
+	pdf.Ln(6)
+
+	// Itemized services
+	amountWidth := 35.0
+	timeWidth := 45.0
+	descWidth := contentWidth - amountWidth - timeWidth
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(descWidth, 7, "Service", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(timeWidth, 7, "Time", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(amountWidth, 7, "Amount", "B", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, item := range receipt.Items {
+		desc := item.ServiceName
+		var details []string
+		if item.StylistName != "" {
+			details = append(details, "with "+item.StylistName)
+		}
+		if item.BeneficiaryName != "" {
+			details = append(details, "for "+item.BeneficiaryName)
+		}
+		if len(details) > 0 {
+			desc += " (" + strings.Join(details, ", ") + ")"
+		}
+		pdf.CellFormat(descWidth, 6, truncateText(pdf, tr(desc), descWidth-2), "", 0, "L", false, 0, "")
+		pdf.CellFormat(timeWidth, 6, item.StartTime.In(loc).Format("02 Jan 2006 15:04"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(amountWidth, 6, formatAmount(item.Price), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(2)
+
+	// Totals
+	labelWidth := contentWidth - amountWidth
+	totalLine := func(label string, amount float64) {
+		pdf.CellFormat(labelWidth, 6, tr(label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(amountWidth, 6, formatAmount(amount), "", 1, "R", false, 0, "")
+	}
+	totalLine("Subtotal", receipt.Subtotal)
+	if receipt.Discount > 0 {
+		label := "Discount"
+		if receipt.PromoCode != nil {
+			label += " (" + *receipt.PromoCode + ")"
+		}
+		totalLine(label, -receipt.Discount)
+	}
+	for _, tax := range receipt.Taxes {
+		totalLine(fmt.Sprintf("%s %s%% on %s", tax.Name, formatPercentage(tax.Percentage), formatAmount(tax.TaxableAmount)), tax.Amount)
+	}
+	if len(receipt.Taxes) == 0 && receipt.GST > 0 {
+		totalLine(fmt.Sprintf("GST on %s", formatAmount(receipt.TaxableAmount)), receipt.GST)
+	}
+	if receipt.BookingFee > 0 {
+		totalLine("Booking fee", receipt.BookingFee)
+	}
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(labelWidth, 8, "Total ("+receipt.Currency+")", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(amountWidth, 8, formatAmount(receipt.Total), "T", 1, "R", false, 0, "")
+
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.MultiCell(contentWidth, 4, "This receipt was generated electronically and is valid without a signature.", "", "L", false)
+
+	return pdf.Output(out)
+}
+
+func receiptField(pdf *gofpdf.Fpdf, width float64, label, value string) {
+	pdf.CellFormat(40, 5, label, "", 0, "L", false, 0, "")
+	pdf.CellFormat(width-40, 5, value, "", 1, "L", false, 0, "")
+}
+
+func phoneLine(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	return "Phone: " + phone
+}
+
+// truncateText shortens already translated text to fit width, marking the cut
+func truncateText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
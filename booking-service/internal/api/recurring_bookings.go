@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"booking-service/internal/model"
+	"booking-service/internal/service"
+
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
+	"github.com/EricsAntony/salon/salon-shared/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateRecurringBooking handles POST /recurring-bookings
+func (h *Handlers) CreateRecurringBooking(w http.ResponseWriter, r *http.Request) {
+	var request service.CreateRecurringBookingRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+	request.UserID = userID
+
+	if request.SalonID == uuid.Nil {
+		errors.WriteAPIError(w, errors.NewValidationError("salon_id", "salon_id is required"))
+		return
+	}
+	if request.BranchID == uuid.Nil {
+		errors.WriteAPIError(w, errors.NewValidationError("branch_id", "branch_id is required"))
+		return
+	}
+	if request.StylistID == uuid.Nil {
+		errors.WriteAPIError(w, errors.NewValidationError("stylist_id", "stylist_id is required"))
+		return
+	}
+	if len(request.ServiceIDs) == 0 {
+		errors.WriteAPIError(w, errors.NewValidationError("service_ids", "at least one service is required"))
+		return
+	}
+	for _, serviceID := range request.ServiceIDs {
+		if serviceID == uuid.Nil {
+			errors.WriteAPIError(w, errors.NewValidationError("service_ids", "service_ids must not contain empty IDs"))
+			return
+		}
+	}
+	if request.Weekday == nil || *request.Weekday < 0 || *request.Weekday > 6 {
+		errors.WriteAPIError(w, errors.NewValidationError("weekday", "weekday is required and must be between 0 (Sunday) and 6 (Saturday)"))
+		return
+	}
+	startTime, err := time.Parse("15:04", request.StartTime)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("start_time", "start_time must be a time in HH:MM format"))
+		return
+	}
+	request.StartTime = startTime.Format("15:04")
+
+	recurring, err := h.bookingService.CreateRecurringBooking(r.Context(), &request)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to create recurring booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, recurring)
+}
+
+// GetUserRecurringBookings handles GET /recurring-bookings, listing the caller's recurring bookings
+func (h *Handlers) GetUserRecurringBookings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		writePaginationError(w, err)
+		return
+	}
+
+	recurrences, total, err := h.bookingService.GetUserRecurringBookings(r.Context(), userID, page)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get recurring bookings")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, pagination.NewListResponse(recurrences, total, page))
+}
+
+// PauseRecurringBooking handles PATCH /recurring-bookings/{recurringId}/pause
+func (h *Handlers) PauseRecurringBooking(w http.ResponseWriter, r *http.Request) {
+	h.changeRecurringBooking(w, r, "pause", h.bookingService.PauseRecurringBooking)
+}
+
+// ResumeRecurringBooking handles PATCH /recurring-bookings/{recurringId}/resume
+func (h *Handlers) ResumeRecurringBooking(w http.ResponseWriter, r *http.Request) {
+	h.changeRecurringBooking(w, r, "resume", h.bookingService.ResumeRecurringBooking)
+}
+
+// CancelRecurringBooking handles PATCH /recurring-bookings/{recurringId}/cancel
+func (h *Handlers) CancelRecurringBooking(w http.ResponseWriter, r *http.Request) {
+	h.changeRecurringBooking(w, r, "cancel", h.bookingService.CancelRecurringBooking)
+}
+
+// changeRecurringBooking applies a status change to one of the caller's
+// recurring bookings and writes the updated recurring booking
+func (h *Handlers) changeRecurringBooking(w http.ResponseWriter, r *http.Request, action string,
+	change func(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error)) {
+	recurringID, err := uuid.Parse(chi.URLParam(r, "recurringId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("recurring_id", "invalid recurring booking ID format"))
+		return
+	}
+
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	recurring, err := change(r.Context(), recurringID, userID)
+	if err != nil {
+		log.Error().Err(err).Str("recurring_booking_id", recurringID.String()).Msg("Failed to " + action + " recurring booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, recurring)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+
+	"booking-service/internal/service"
+
+	"github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
+	"github.com/EricsAntony/salon/salon-shared/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// JoinWaitlist handles POST /waitlist
+func (h *Handlers) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	var request service.JoinWaitlistRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+	request.UserID = userID
+
+	if request.SalonID == uuid.Nil {
+		errors.WriteAPIError(w, errors.NewValidationError("salon_id", "salon ID is required"))
+		return
+	}
+	if request.StylistID == uuid.Nil {
+		errors.WriteAPIError(w, errors.NewValidationError("stylist_id", "stylist ID is required"))
+		return
+	}
+	if request.DesiredStart.IsZero() || request.DesiredEnd.IsZero() {
+		errors.WriteAPIError(w, errors.NewValidationError("desired_start", "desired_start and desired_end are required"))
+		return
+	}
+
+	entry, err := h.bookingService.JoinWaitlist(r.Context(), &request)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to join waitlist")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, entry)
+}
+
+// LeaveWaitlist handles DELETE /waitlist/{entryId}
+func (h *Handlers) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	entryID, err := uuid.Parse(chi.URLParam(r, "entryId"))
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("entry_id", "invalid waitlist entry ID format"))
+		return
+	}
+
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.bookingService.LeaveWaitlist(r.Context(), entryID, userID); err != nil {
+		log.Error().Err(err).Str("waitlist_entry_id", entryID.String()).Msg("Failed to leave waitlist")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserWaitlist handles GET /waitlist, listing the caller's waitlist entries
+func (h *Handlers) GetUserWaitlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		writePaginationError(w, err)
+		return
+	}
+
+	entries, total, err := h.bookingService.GetUserWaitlist(r.Context(), userID, page)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get waitlist entries")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, pagination.NewListResponse(entries, total, page))
+}
+
+// authenticatedUserID returns the customer ID set by the auth middleware,
+// writing an error response when it is missing or malformed
+func authenticatedUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := r.Context().Value(auth.CtxUserID).(string)
+	if !ok {
+		errors.WriteAPIError(w, errors.NewAuthError("authentication", "user not authenticated"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewValidationError("user_id", "invalid user ID format"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
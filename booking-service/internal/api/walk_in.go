@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"booking-service/internal/service"
+
+	"github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/utils"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// StaffInitiateBooking handles POST /bookings/staff-initiate for front-desk
+// staff creating a walk-in booking for an existing user_id or a guest
+func (h *Handlers) StaffInitiateBooking(w http.ResponseWriter, r *http.Request) {
+	var request service.StaffInitiateBookingRequest
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	staffIDStr, ok := r.Context().Value(auth.CtxUserID).(string)
+	if !ok {
+		errors.WriteAPIError(w, errors.NewAuthError("authentication", "user not authenticated"))
+		return
+	}
+	staffID, err := uuid.Parse(staffIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.NewAuthError("authentication", "invalid staff ID"))
+		return
+	}
+
+	// The Idempotency-Key header takes precedence over the body field
+	if key := strings.TrimSpace(r.Header.Get("Idempotency-Key")); key != "" {
+		request.IdempotencyKey = key
+	}
+
+	if request.UserID == uuid.Nil && request.Guest == nil {
+		errors.WriteAPIError(w, errors.NewValidationError("user_id", "user_id or guest is required"))
+		return
+	}
+	if request.Guest != nil {
+		request.Guest.Name = strings.TrimSpace(request.Guest.Name)
+		request.Guest.PhoneNumber = strings.TrimSpace(request.Guest.PhoneNumber)
+		if request.Guest.Name == "" || request.Guest.PhoneNumber == "" {
+			errors.WriteAPIError(w, errors.NewValidationError("guest", "guest name and phone_number are required"))
+			return
+		}
+	}
+	if err := validateBookingDetails(&request.InitiateBookingRequest, time.Now().Add(-service.WalkInStartGrace)); err != nil {
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	// Staff may only book into branches of their own salon
+	hasAccess, err := h.branchAccess.StaffHasAccessToBranch(r.Context(), staffIDStr, request.BranchID.String())
+	if err != nil {
+		log.Error().Err(err).Str("staff_id", staffIDStr).Str("branch_id", request.BranchID.String()).Msg("Failed to check branch access")
+		errors.WriteAPIError(w, err)
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("staff_id", staffIDStr).Str("branch_id", request.BranchID.String()).Msg("Unauthorized walk-in booking attempt")
+		errors.WriteError(w, http.StatusForbidden, "access denied to branch")
+		return
+	}
+
+	booking, err := h.bookingService.StaffInitiateBooking(r.Context(), staffID, &request)
+	if err != nil {
+		log.Error().Err(err).Str("staff_id", staffIDStr).Msg("Failed to initiate walk-in booking")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, booking)
+}
@@ -4,9 +4,16 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
+	sharedconfig "github.com/EricsAntony/salon/salon-shared/config"
+	"github.com/EricsAntony/salon/salon-shared/configcheck"
+	"github.com/EricsAntony/salon/salon-shared/currency"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
 	"github.com/spf13/viper"
 	"github.com/subosito/gotenv"
+
+	"booking-service/internal/db"
 )
 
 // Config holds all configuration for the booking service
@@ -14,8 +21,17 @@ type Config struct {
 	Environment      string `mapstructure:"environment"`
 	Port             int    `mapstructure:"port"`
 	DatabaseURL      string `mapstructure:"database_url"`
+	// Connection pool limits
+	DBMaxConns                 int `mapstructure:"db_max_conns"`
+	DBMinConns                 int `mapstructure:"db_min_conns"`
+	DBMaxConnIdleMinutes       int `mapstructure:"db_max_conn_idle_minutes"`
+	DBMaxConnLifetimeMinutes   int `mapstructure:"db_max_conn_lifetime_minutes"`
+	DBHealthCheckPeriodSeconds int `mapstructure:"db_health_check_period_seconds"`
 	JWTAccessSecret  string `mapstructure:"jwt_access_secret"`
 	JWTRefreshSecret string `mapstructure:"jwt_refresh_secret"`
+	// Token lifetimes; defaults are shared with the other services
+	JWTAccessTTLMinutes int `mapstructure:"jwt_access_ttl_minutes"`
+	JWTRefreshTTLDays   int `mapstructure:"jwt_refresh_ttl_days"`
 	
 	// External service URLs
 	UserServiceURL         string `mapstructure:"user_service_url"`
@@ -23,13 +39,63 @@ type Config struct {
 	PaymentServiceURL      string `mapstructure:"payment_service_url"`
 	NotificationServiceURL string `mapstructure:"notification_service_url"`
 	
+	// Bearer token sent to other services when there is no caller token to
+	// forward, e.g. from background workers and event consumers
+	ServiceAccountToken string `mapstructure:"service_account_token"`
+	
+	// Event publishing: "broker" publishes booking events to Kafka,
+	// "http" calls the notification service directly (local dev)
+	NotificationTransport string   `mapstructure:"notification_transport"`
+	KafkaBrokers          []string `mapstructure:"kafka_brokers"`
+	KafkaTopic            string   `mapstructure:"kafka_topic"`
+	
+	// Payment events from payment-service; consumption is disabled when the topic is empty
+	PaymentEventsTopic string `mapstructure:"payment_events_topic"`
+	KafkaGroupID       string `mapstructure:"kafka_group_id"`
+	
+	// Outgoing calls: retries for idempotent requests and a per-host circuit breaker
+	HTTPMaxRetries                 int `mapstructure:"http_max_retries"`
+	HTTPRetryBaseDelayMS           int `mapstructure:"http_retry_base_delay_ms"`
+	CircuitBreakerFailureThreshold int `mapstructure:"circuit_breaker_failure_threshold"`
+	CircuitBreakerOpenSeconds      int `mapstructure:"circuit_breaker_open_seconds"`
+	
+	// Readiness pings downstream services; only critical ones fail /ready
+	HealthCheckTimeoutMS int      `mapstructure:"health_check_timeout_ms"`
+	CriticalDependencies []string `mapstructure:"critical_dependencies"`
+	
+	// Largest request body accepted; larger bodies are rejected with 413
+	MaxBodyBytes int `mapstructure:"max_body_bytes"`
+	
+	// How long branch, service and stylist lookups are cached; 0 disables the cache
+	LookupCacheTTLSeconds int `mapstructure:"lookup_cache_ttl_seconds"`
+	
+	// Idempotency
+	IdempotencyTTLHours int `mapstructure:"idempotency_ttl_hours"`
+	
+	// Reminder worker
+	ReminderCheckIntervalMinutes int `mapstructure:"reminder_check_interval_minutes"`
+	
+	// Expiry worker: initiated bookings unpaid after the payment timeout are voided
+	PaymentTimeoutMinutes      int `mapstructure:"payment_timeout_minutes"`
+	ExpiryCheckIntervalMinutes int `mapstructure:"expiry_check_interval_minutes"`
+	
+	// Recurring booking generator: occurrences are booked this many days ahead
+	RecurringHorizonDays          int `mapstructure:"recurring_horizon_days"`
+	RecurringCheckIntervalMinutes int `mapstructure:"recurring_check_interval_minutes"`
+	
 	// Default configuration values
-	DefaultBufferTimeMinutes       int     `mapstructure:"default_buffer_time_minutes"`
-	DefaultCancellationCutoffHours int     `mapstructure:"default_cancellation_cutoff_hours"`
-	DefaultRescheduleWindowHours   int     `mapstructure:"default_reschedule_window_hours"`
-	DefaultMaxAdvanceBookingDays   int     `mapstructure:"default_max_advance_booking_days"`
-	DefaultBookingFeeAmount        float64 `mapstructure:"default_booking_fee_amount"`
-	DefaultGSTPercentage           float64 `mapstructure:"default_gst_percentage"`
+	DefaultBufferTimeMinutes          int     `mapstructure:"default_buffer_time_minutes"`
+	DefaultCancellationCutoffHours    int     `mapstructure:"default_cancellation_cutoff_hours"`
+	DefaultRescheduleWindowHours      int     `mapstructure:"default_reschedule_window_hours"`
+	DefaultMaxAdvanceBookingDays      int     `mapstructure:"default_max_advance_booking_days"`
+	DefaultSlotIntervalMinutes        int     `mapstructure:"default_slot_interval_minutes"`
+	DefaultReminderLeadTimes          []int   `mapstructure:"default_reminder_lead_times_minutes"`
+	DefaultCancellationFeePercentage  float64 `mapstructure:"default_cancellation_fee_percentage"`
+	DefaultCancellationFeeWindowHours int     `mapstructure:"default_cancellation_fee_window_hours"`
+	DefaultBookingFeeAmount           float64 `mapstructure:"default_booking_fee_amount"`
+	DefaultGSTPercentage              float64 `mapstructure:"default_gst_percentage"`
+	// ISO 4217 currency bookings are charged in
+	Currency string `mapstructure:"currency"`
 }
 
 // Load loads configuration from environment variables and config files
@@ -64,9 +130,9 @@ func Load() (*Config, error) {
 	// Override with environment-specific variables
 	overrideWithEnv(&config)
 
-	// Validate required fields
-	if err := validate(&config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+	// Validate reports an unknown currency; normalize the ones that parse
+	if code, err := currency.Normalize(config.Currency); err == nil {
+		config.Currency = code
 	}
 
 	return &config, nil
@@ -79,14 +145,46 @@ func setDefaults() {
 	viper.SetDefault("salon_service_url", "http://localhost:8081")
 	viper.SetDefault("payment_service_url", "http://localhost:8082")
 	viper.SetDefault("notification_service_url", "http://localhost:8084")
+	viper.SetDefault("db_max_conns", 30)
+	viper.SetDefault("db_min_conns", 5)
+	viper.SetDefault("db_max_conn_idle_minutes", 30)
+	viper.SetDefault("db_max_conn_lifetime_minutes", 60)
+	viper.SetDefault("db_health_check_period_seconds", 60)
+	viper.SetDefault("jwt_access_ttl_minutes", sharedconfig.DefaultAccessTTLMinutes)
+	viper.SetDefault("jwt_refresh_ttl_days", sharedconfig.DefaultRefreshTTLDays)
+	
+	viper.SetDefault("notification_transport", "broker")
+	viper.SetDefault("kafka_brokers", []string{"localhost:9092"})
+	viper.SetDefault("kafka_topic", "booking-events")
+	viper.SetDefault("payment_events_topic", "payment-events")
+	viper.SetDefault("kafka_group_id", "booking-service")
+	viper.SetDefault("http_max_retries", 2)
+	viper.SetDefault("http_retry_base_delay_ms", 200)
+	viper.SetDefault("circuit_breaker_failure_threshold", 5)
+	viper.SetDefault("circuit_breaker_open_seconds", 30)
+	viper.SetDefault("health_check_timeout_ms", 2000)
+	viper.SetDefault("critical_dependencies", []string{"user-service", "salon-service", "payment-service"})
+	viper.SetDefault("max_body_bytes", requestbody.DefaultMaxBytes)
+	viper.SetDefault("lookup_cache_ttl_seconds", 30)
+	viper.SetDefault("idempotency_ttl_hours", 24)
+	viper.SetDefault("reminder_check_interval_minutes", 5)
+	viper.SetDefault("payment_timeout_minutes", 15)
+	viper.SetDefault("expiry_check_interval_minutes", 1)
+	viper.SetDefault("recurring_horizon_days", 28)
+	viper.SetDefault("recurring_check_interval_minutes", 60)
 	
 	// Default booking configuration
 	viper.SetDefault("default_buffer_time_minutes", 15)
 	viper.SetDefault("default_cancellation_cutoff_hours", 2)
 	viper.SetDefault("default_reschedule_window_hours", 4)
 	viper.SetDefault("default_max_advance_booking_days", 30)
+	viper.SetDefault("default_slot_interval_minutes", 30)
+	viper.SetDefault("default_reminder_lead_times_minutes", []int{1440, 120})
+	viper.SetDefault("default_cancellation_fee_percentage", 0.0)
+	viper.SetDefault("default_cancellation_fee_window_hours", 24)
 	viper.SetDefault("default_booking_fee_amount", 50.0)
 	viper.SetDefault("default_gst_percentage", 18.0)
+	viper.SetDefault("currency", "INR")
 }
 
 func overrideWithEnv(config *Config) {
@@ -104,6 +202,20 @@ func overrideWithEnv(config *Config) {
 		config.DatabaseURL = dbURL
 	}
 	
+	for env, target := range map[string]*int{
+		"BOOKING_SERVICE_DB_MAX_CONNS":                   &config.DBMaxConns,
+		"BOOKING_SERVICE_DB_MIN_CONNS":                   &config.DBMinConns,
+		"BOOKING_SERVICE_DB_MAX_CONN_IDLE_MINUTES":       &config.DBMaxConnIdleMinutes,
+		"BOOKING_SERVICE_DB_MAX_CONN_LIFETIME_MINUTES":   &config.DBMaxConnLifetimeMinutes,
+		"BOOKING_SERVICE_DB_HEALTH_CHECK_PERIOD_SECONDS": &config.DBHealthCheckPeriodSeconds,
+	} {
+		if value := os.Getenv(env); value != "" {
+			if v, err := strconv.Atoi(value); err == nil {
+				*target = v
+			}
+		}
+	}
+	
 	if secret := os.Getenv("BOOKING_SERVICE_JWT_ACCESSSECRET"); secret != "" {
 		config.JWTAccessSecret = secret
 	}
@@ -112,6 +224,18 @@ func overrideWithEnv(config *Config) {
 		config.JWTRefreshSecret = secret
 	}
 	
+	if ttl := os.Getenv("BOOKING_SERVICE_JWT_ACCESSTTLMINUTES"); ttl != "" {
+		if v, err := strconv.Atoi(ttl); err == nil {
+			config.JWTAccessTTLMinutes = v
+		}
+	}
+	
+	if ttl := os.Getenv("BOOKING_SERVICE_JWT_REFRESHTTLDAYS"); ttl != "" {
+		if v, err := strconv.Atoi(ttl); err == nil {
+			config.JWTRefreshTTLDays = v
+		}
+	}
+	
 	if url := os.Getenv("USER_SERVICE_URL"); url != "" {
 		config.UserServiceURL = url
 	}
@@ -127,24 +251,96 @@ func overrideWithEnv(config *Config) {
 	if url := os.Getenv("NOTIFICATION_SERVICE_URL"); url != "" {
 		config.NotificationServiceURL = url
 	}
+	
+	if token := os.Getenv("BOOKING_SERVICE_SERVICE_ACCOUNT_TOKEN"); token != "" {
+		config.ServiceAccountToken = token
+	}
 }
 
-func validate(config *Config) error {
-	if config.DatabaseURL == "" {
-		return fmt.Errorf("database_url is required")
+// Validate reports every missing or out of range setting at once. JWT secrets
+// must be at least configcheck.MinSecretLength characters outside development.
+func (c *Config) Validate() error {
+	var p configcheck.Problems
+	strict := !configcheck.IsDevelopment(c.Environment)
+
+	p.Range("port", c.Port, 1, 65535)
+	p.Required("database_url", c.DatabaseURL)
+	p.Positive("db_max_conns", c.DBMaxConns)
+	p.Range("db_min_conns", c.DBMinConns, 0, c.DBMaxConns)
+	p.Positive("db_max_conn_idle_minutes", c.DBMaxConnIdleMinutes)
+	p.Positive("db_max_conn_lifetime_minutes", c.DBMaxConnLifetimeMinutes)
+	p.Positive("db_health_check_period_seconds", c.DBHealthCheckPeriodSeconds)
+	p.Secret("jwt_access_secret", c.JWTAccessSecret, strict)
+	p.Secret("jwt_refresh_secret", c.JWTRefreshSecret, strict)
+	if c.JWTAccessSecret != "" && c.JWTAccessSecret == c.JWTRefreshSecret {
+		p.Addf("jwt_access_secret and jwt_refresh_secret must differ")
 	}
-	
-	if config.JWTAccessSecret == "" {
-		return fmt.Errorf("jwt_access_secret is required")
+	p.Check(sharedconfig.ValidateJWTTTLs(c.JWTAccessTTLMinutes, c.JWTRefreshTTLDays))
+	if c.ServiceAccountToken != "" {
+		p.Secret("service_account_token", c.ServiceAccountToken, strict)
 	}
-	
-	if config.JWTRefreshSecret == "" {
-		return fmt.Errorf("jwt_refresh_secret is required")
+
+	p.URL("user_service_url", c.UserServiceURL)
+	p.URL("salon_service_url", c.SalonServiceURL)
+	p.URL("payment_service_url", c.PaymentServiceURL)
+	p.URL("notification_service_url", c.NotificationServiceURL)
+
+	p.OneOf("notification_transport", c.NotificationTransport, "broker", "http")
+	if c.NotificationTransport == "broker" && len(c.KafkaBrokers) == 0 {
+		p.Addf("kafka_brokers is required when notification_transport is broker")
 	}
-	
-	if config.Port <= 0 || config.Port > 65535 {
-		return fmt.Errorf("port must be between 1 and 65535")
+	if c.PaymentEventsTopic != "" && len(c.KafkaBrokers) == 0 {
+		p.Addf("kafka_brokers is required when payment_events_topic is set")
+	}
+
+	p.NonNegative("http_max_retries", c.HTTPMaxRetries)
+	p.Positive("http_retry_base_delay_ms", c.HTTPRetryBaseDelayMS)
+	p.Positive("circuit_breaker_failure_threshold", c.CircuitBreakerFailureThreshold)
+	p.Positive("circuit_breaker_open_seconds", c.CircuitBreakerOpenSeconds)
+	p.Positive("health_check_timeout_ms", c.HealthCheckTimeoutMS)
+	for _, name := range c.CriticalDependencies {
+		switch name {
+		case "user-service", "salon-service", "payment-service", "notification-service":
+		default:
+			p.Addf("unknown critical dependency %q", name)
+		}
+	}
+
+	if _, err := currency.Normalize(c.Currency); err != nil {
+		p.Addf("currency: %v", err)
+	}
+	p.Positive("max_body_bytes", c.MaxBodyBytes)
+	p.Range("lookup_cache_ttl_seconds", c.LookupCacheTTLSeconds, 0, 60)
+	p.Positive("idempotency_ttl_hours", c.IdempotencyTTLHours)
+	p.Positive("reminder_check_interval_minutes", c.ReminderCheckIntervalMinutes)
+	p.Positive("payment_timeout_minutes", c.PaymentTimeoutMinutes)
+	p.Positive("expiry_check_interval_minutes", c.ExpiryCheckIntervalMinutes)
+	p.Range("recurring_horizon_days", c.RecurringHorizonDays, 1, 365)
+	p.Positive("recurring_check_interval_minutes", c.RecurringCheckIntervalMinutes)
+
+	p.Positive("default_slot_interval_minutes", c.DefaultSlotIntervalMinutes)
+	p.NonNegative("default_buffer_time_minutes", c.DefaultBufferTimeMinutes)
+	p.Positive("default_max_advance_booking_days", c.DefaultMaxAdvanceBookingDays)
+	if c.DefaultCancellationFeePercentage < 0 || c.DefaultCancellationFeePercentage > 100 {
+		p.Addf("default_cancellation_fee_percentage must be between 0 and 100")
+	}
+	if c.DefaultGSTPercentage < 0 || c.DefaultGSTPercentage > 100 {
+		p.Addf("default_gst_percentage must be between 0 and 100")
+	}
+	if c.DefaultBookingFeeAmount < 0 {
+		p.Addf("default_booking_fee_amount must not be negative")
+	}
+
+	return p.Err("booking-service")
+}
+
+// DBPool returns the connection pool limits for the pgx pool
+func (c *Config) DBPool() db.PoolConfig {
+	return db.PoolConfig{
+		MaxConns:          int32(c.DBMaxConns),
+		MinConns:          int32(c.DBMinConns),
+		MaxConnIdleTime:   time.Duration(c.DBMaxConnIdleMinutes) * time.Minute,
+		MaxConnLifetime:   time.Duration(c.DBMaxConnLifetimeMinutes) * time.Minute,
+		HealthCheckPeriod: time.Duration(c.DBHealthCheckPeriodSeconds) * time.Second,
 	}
-	
-	return nil
 }
@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"booking-service/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Payment event types published by payment-service
+const (
+	EventPaymentExpired   = "payment.expired"
+	EventPaymentConfirmed = "payment.confirmed"
+)
+
+// PaymentEvent is a payment lifecycle event published by payment-service
+type PaymentEvent struct {
+	Type      string                 `json:"type"`
+	PaymentID uuid.UUID              `json:"payment_id"`
+	BookingID uuid.UUID              `json:"booking_id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Data      map[string]interface{} `json:"data"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// PaymentEventConsumer consumes payment events and updates the affected bookings
+type PaymentEventConsumer struct {
+	bookingService service.BookingService
+	reader         *kafka.Reader
+}
+
+// NewPaymentEventConsumer creates a consumer for the payment events topic
+func NewPaymentEventConsumer(bookingService service.BookingService, brokers []string, topic, groupID string) *PaymentEventConsumer {
+	return &PaymentEventConsumer{
+		bookingService: bookingService,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			GroupID:  groupID,
+			Topic:    topic,
+			MinBytes: 1,
+			MaxBytes: 10e6,
+		}),
+	}
+}
+
+// Start reads payment events until the context is cancelled
+func (c *PaymentEventConsumer) Start(ctx context.Context) {
+	defer c.reader.Close()
+
+	log.Info().Str("topic", c.reader.Config().Topic).Msg("Consuming payment events")
+
+	for {
+		message, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Info().Msg("Payment event consumer stopped")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to fetch payment event")
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var event PaymentEvent
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			log.Error().Err(err).Int64("offset", message.Offset).Msg("Failed to decode payment event, skipping")
+		} else {
+			c.processEvent(ctx, &event)
+		}
+
+		if err := c.reader.CommitMessages(ctx, message); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to commit payment event offset")
+		}
+	}
+}
+
+// processEvent applies a payment event to its booking
+func (c *PaymentEventConsumer) processEvent(ctx context.Context, event *PaymentEvent) {
+	switch event.Type {
+	case EventPaymentExpired:
+		if err := c.bookingService.ReleaseExpiredPaymentBooking(ctx, event.BookingID, event.PaymentID); err != nil {
+			log.Error().
+				Err(err).
+				Str("booking_id", event.BookingID.String()).
+				Str("payment_id", event.PaymentID.String()).
+				Msg("Failed to release booking for expired payment")
+		}
+	case EventPaymentConfirmed:
+		// Published when payment-service finds a payment successful through a
+		// gateway webhook or reconciliation; a booking already confirmed by the
+		// payment callback is left as it is
+		gatewayPaymentID, _ := event.Data["gateway_payment_id"].(string)
+		if err := c.bookingService.ProcessPaymentCallback(ctx, event.BookingID, event.PaymentID, gatewayPaymentID); err != nil {
+			log.Error().
+				Err(err).
+				Str("booking_id", event.BookingID.String()).
+				Str("payment_id", event.PaymentID.String()).
+				Msg("Failed to confirm booking for reconciled payment")
+		}
+	default:
+		log.Debug().Str("event_type", event.Type).Msg("Ignoring payment event")
+	}
+}
@@ -9,21 +9,38 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// PoolConfig holds the connection pool limits applied to the pgx pool
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnIdleTime   time.Duration
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
 // NewConnection creates a new database connection pool
-func NewConnection(databaseURL string) (*pgxpool.Pool, error) {
+func NewConnection(databaseURL string, pool PoolConfig) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database URL: %w", err)
 	}
 
 	// Configure connection pool
-	config.MaxConns = 30
-	config.MinConns = 5
-	config.MaxConnLifetime = time.Hour
-	config.MaxConnIdleTime = time.Minute * 30
+	config.MaxConns = pool.MaxConns
+	config.MinConns = pool.MinConns
+	config.MaxConnLifetime = pool.MaxConnLifetime
+	config.MaxConnIdleTime = pool.MaxConnIdleTime
+	config.HealthCheckPeriod = pool.HealthCheckPeriod
+
+	// Read timestamps back in UTC regardless of the server and database zones;
+	// bookings carry their own time zone for display
+	config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
+	// Trace queries; spans are dropped unless tracing is initialized
+	config.ConnConfig.Tracer = queryTracer{}
 
 	// Create connection pool
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	conn, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
@@ -32,18 +49,28 @@ func NewConnection(databaseURL string) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
+	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Info().Msg("Database connection established successfully")
-	return pool, nil
+	log.Info().
+		Int32("max_conns", config.MaxConns).
+		Int32("min_conns", config.MinConns).
+		Dur("max_conn_idle_time", config.MaxConnIdleTime).
+		Dur("max_conn_lifetime", config.MaxConnLifetime).
+		Dur("health_check_period", config.HealthCheckPeriod).
+		Msg("Database connection established successfully")
+	return conn, nil
 }
 
 // HealthCheck performs a health check on the database connection
-func HealthCheck(pool *pgxpool.Pool) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func HealthCheck(ctx context.Context, pool *pgxpool.Pool) error {
+	if pool == nil {
+		return fmt.Errorf("database health check failed: no connection pool")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	var result int
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTP metrics
+	HTTPRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "booking_service_http_requests_total",
+			Help: "Total number of HTTP requests",
+		},
+		[]string{"method", "route", "status_code"},
+	)
+
+	HTTPRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "booking_service_http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status_code"},
+	)
+
+	HTTPRequestsInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "booking_service_http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served",
+		},
+	)
+
+	// Salon-service lookup cache metrics
+	LookupCacheRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "booking_service_lookup_cache_requests_total",
+			Help: "Total number of cached salon-service lookups by result (hit, miss, bypass)",
+		},
+		[]string{"lookup", "result"},
+	)
+)
+
+// RecordHTTPRequest records an HTTP request metric
+func RecordHTTPRequest(method, route, statusCode string, duration time.Duration) {
+	HTTPRequestsTotal.WithLabelValues(method, route, statusCode).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route, statusCode).Observe(duration.Seconds())
+}
+
+// RecordLookupCache records a lookup cache hit, miss or bypass
+func RecordLookupCache(lookup, result string) {
+	LookupCacheRequestsTotal.WithLabelValues(lookup, result).Inc()
+}
+
+// Middleware records request count, duration and in-flight requests. Requests
+// are labelled by chi route pattern rather than raw path to bound cardinality.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HTTPRequestsInFlight.Inc()
+		defer HTTPRequestsInFlight.Dec()
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		RecordHTTPRequest(r.Method, routePattern(r), strconv.Itoa(status), time.Since(start))
+	})
+}
+
+// routePattern returns the matched chi route, or "unmatched" for requests no route handled
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
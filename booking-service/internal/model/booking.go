@@ -1,30 +1,87 @@
 package model
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/EricsAntony/salon/salon-shared/money"
 	"github.com/google/uuid"
 )
 
 // Booking represents a booking in the system
 type Booking struct {
-	ID            uuid.UUID     `json:"id" db:"id"`
-	UserID        uuid.UUID     `json:"user_id" db:"user_id"`
-	SalonID       uuid.UUID     `json:"salon_id" db:"salon_id"`
-	BranchID      uuid.UUID     `json:"branch_id" db:"branch_id"`
-	Status        BookingStatus `json:"status" db:"status"`
-	TotalAmount   float64       `json:"total_amount" db:"total_amount"`
-	GST           float64       `json:"gst" db:"gst"`
-	BookingFee    float64       `json:"booking_fee" db:"booking_fee"`
-	PaymentStatus PaymentStatus `json:"payment_status" db:"payment_status"`
-	PaymentID     *string       `json:"payment_id,omitempty" db:"payment_id"`
-	Notes         *string       `json:"notes,omitempty" db:"notes"`
-	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID     `json:"id" db:"id"`
+	UserID         uuid.UUID     `json:"user_id" db:"user_id"`
+	SalonID        uuid.UUID     `json:"salon_id" db:"salon_id"`
+	BranchID       uuid.UUID     `json:"branch_id" db:"branch_id"`
+	Status         BookingStatus `json:"status" db:"status"`
+	TotalAmount    float64       `json:"total_amount" db:"total_amount"`
+	GST            float64       `json:"gst" db:"gst"`
+	BookingFee     float64       `json:"booking_fee" db:"booking_fee"`
+	PaymentStatus  PaymentStatus `json:"payment_status" db:"payment_status"`
+	PaymentID      *string       `json:"payment_id,omitempty" db:"payment_id"`
+	Notes          *string       `json:"notes,omitempty" db:"notes"`
+	PromoCode      *string       `json:"promo_code,omitempty" db:"promo_code"`
+	DiscountAmount float64       `json:"discount_amount" db:"discount_amount"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
+	
+	// Version increases with every update; writes made against an older
+	// version are rejected so concurrent changes are not lost
+	Version int `json:"version" db:"version"`
+	
+	// Timezone is the IANA time zone of the branch; service times are shown
+	// and compared in its wall-clock time
+	Timezone string `json:"timezone" db:"timezone"`
+	
+	// Taxes is the GST breakdown the booking was priced with; bookings priced
+	// before tax components existed have none
+	Taxes []BookingSummaryTax `json:"taxes,omitempty" db:"taxes"`
+	
+	// RecurringBookingID is the recurring booking this booking is an
+	// occurrence of, booked for OccurrenceDate (YYYY-MM-DD)
+	RecurringBookingID *uuid.UUID `json:"recurring_booking_id,omitempty" db:"recurring_booking_id"`
+	OccurrenceDate     *string    `json:"occurrence_date,omitempty" db:"occurrence_date"`
+	
+	// BalancePaymentID is the payment charging the difference after a paid
+	// booking was rescheduled to a higher total
+	BalancePaymentID *string `json:"balance_payment_id,omitempty" db:"balance_payment_id"`
+	
+	// GuestAccessTokenHash is the hash of the access token issued for a guest
+	// checkout; it is only written on creation and never serialized
+	GuestAccessTokenHash *string `json:"-" db:"guest_access_token_hash"`
 	
 	// Related data (loaded via joins)
 	Services []BookingService `json:"services,omitempty"`
 	History  []BookingHistory `json:"history,omitempty"`
+	
+	// PaymentAdjustment is set on the response of a reschedule that changed
+	// what a paid booking costs; it is not stored on the booking
+	PaymentAdjustment *PaymentAdjustment `json:"payment_adjustment,omitempty"`
+}
+
+// PaymentAdjustmentType distinguishes balance charges from partial refunds
+type PaymentAdjustmentType string
+
+const (
+	PaymentAdjustmentCharge PaymentAdjustmentType = "charge"
+	PaymentAdjustmentRefund PaymentAdjustmentType = "refund"
+)
+
+// PaymentAdjustment is the payment-service operation settling the difference
+// between a paid booking's old and new total
+type PaymentAdjustment struct {
+	Type PaymentAdjustmentType `json:"type"`
+	// Amount is the absolute difference charged or refunded
+	Amount    float64 `json:"amount"`
+	PaymentID *string `json:"payment_id,omitempty"`
+	RefundID  *string `json:"refund_id,omitempty"`
+	Status    string  `json:"status"`
+	// GatewayResponse lets the client complete a balance charge
+	GatewayResponse map[string]interface{} `json:"gateway_response,omitempty"`
 }
 
 // BookingService represents a service within a booking
@@ -39,6 +96,11 @@ type BookingService struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	
+	// Beneficiary of the service in a group booking; both are nil when the
+	// service is for the booking's own user
+	BeneficiaryName   *string    `json:"beneficiary_name,omitempty" db:"beneficiary_name"`
+	BeneficiaryUserID *uuid.UUID `json:"beneficiary_user_id,omitempty" db:"beneficiary_user_id"`
+	
 	// Related data (loaded via joins)
 	ServiceName *string `json:"service_name,omitempty"`
 	StylistName *string `json:"stylist_name,omitempty"`
@@ -58,15 +120,102 @@ type BookingHistory struct {
 
 // BranchConfiguration represents configuration settings for a branch
 type BranchConfiguration struct {
-	BranchID                uuid.UUID `json:"branch_id" db:"branch_id"`
-	BufferTimeMinutes       int       `json:"buffer_time_minutes" db:"buffer_time_minutes"`
-	CancellationCutoffHours int       `json:"cancellation_cutoff_hours" db:"cancellation_cutoff_hours"`
-	RescheduleWindowHours   int       `json:"reschedule_window_hours" db:"reschedule_window_hours"`
-	MaxAdvanceBookingDays   int       `json:"max_advance_booking_days" db:"max_advance_booking_days"`
-	BookingFeeAmount        float64   `json:"booking_fee_amount" db:"booking_fee_amount"`
-	GSTPercentage           float64   `json:"gst_percentage" db:"gst_percentage"`
-	CreatedAt               time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt               time.Time `json:"updated_at" db:"updated_at"`
+	BranchID                   uuid.UUID `json:"branch_id" db:"branch_id"`
+	BufferTimeMinutes          int       `json:"buffer_time_minutes" db:"buffer_time_minutes"`
+	CancellationCutoffHours    int       `json:"cancellation_cutoff_hours" db:"cancellation_cutoff_hours"`
+	RescheduleWindowHours      int       `json:"reschedule_window_hours" db:"reschedule_window_hours"`
+	MaxAdvanceBookingDays      int       `json:"max_advance_booking_days" db:"max_advance_booking_days"`
+	SlotIntervalMinutes        int       `json:"slot_interval_minutes" db:"slot_interval_minutes"`
+	ReminderLeadTimes          []int     `json:"reminder_lead_times_minutes" db:"reminder_lead_times_minutes"`
+	CancellationFeePercentage  float64   `json:"cancellation_fee_percentage" db:"cancellation_fee_percentage"`
+	CancellationFeeWindowHours int       `json:"cancellation_fee_window_hours" db:"cancellation_fee_window_hours"`
+	BookingFeeAmount           float64   `json:"booking_fee_amount" db:"booking_fee_amount"`
+	// GSTPercentage is the total of TaxComponents
+	GSTPercentage float64        `json:"gst_percentage" db:"gst_percentage"`
+	TaxComponents []TaxComponent `json:"tax_components" db:"tax_components"`
+	// Version increases on every update; clients send the version they read
+	// when updating so concurrent edits are detected
+	Version   int       `json:"version" db:"version"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TaxComponent is one tax a branch charges on taxable services, such as CGST
+// or SGST
+type TaxComponent struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+}
+
+// DefaultTaxName names the single component of a branch that only configures
+// a GST percentage
+const DefaultTaxName = "GST"
+
+// SingleTaxComponent returns the components of a flat GST percentage
+func SingleTaxComponent(percentage float64) []TaxComponent {
+	if percentage <= 0 {
+		return []TaxComponent{}
+	}
+	return []TaxComponent{{Name: DefaultTaxName, Percentage: percentage}}
+}
+
+// TotalTaxPercentage sums the percentages of tax components
+func TotalTaxPercentage(components []TaxComponent) float64 {
+	var total float64
+	for _, component := range components {
+		total += component.Percentage
+	}
+	return total
+}
+
+// EffectiveTaxComponents returns the branch's tax components, treating a
+// configuration without any as a single GST component
+func (c *BranchConfiguration) EffectiveTaxComponents() []TaxComponent {
+	if len(c.TaxComponents) == 0 {
+		return SingleTaxComponent(c.GSTPercentage)
+	}
+	return c.TaxComponents
+}
+
+// DefaultSlotIntervalMinutes is used when a branch has no valid slot interval configured
+const DefaultSlotIntervalMinutes = 30
+
+// IsValidSlotInterval checks if the slot interval is one of the supported values
+func IsValidSlotInterval(minutes int) bool {
+	switch minutes {
+	case 10, 15, 20, 30, 60:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetSlotDuration returns the slot duration for the branch, falling back to the default
+func (c *BranchConfiguration) GetSlotDuration() time.Duration {
+	if !IsValidSlotInterval(c.SlotIntervalMinutes) {
+		return DefaultSlotIntervalMinutes * time.Minute
+	}
+	return time.Duration(c.SlotIntervalMinutes) * time.Minute
+}
+
+// CalculateCancellationFee returns the fee charged when the booking is cancelled at the given time.
+// Cancellations made before the fee window starts are free.
+func (c *BranchConfiguration) CalculateCancellationFee(booking *Booking, at time.Time) float64 {
+	if c.CancellationFeePercentage <= 0 {
+		return 0
+	}
+
+	earliestStart := booking.GetEarliestStartTime()
+	if earliestStart == nil {
+		return 0
+	}
+
+	windowStart := earliestStart.Add(-time.Duration(c.CancellationFeeWindowHours) * time.Hour)
+	if at.Before(windowStart) {
+		return 0
+	}
+
+	return money.Percentage(booking.TotalAmount, c.CancellationFeePercentage)
 }
 
 // TimeSlot represents an available time slot for booking
@@ -76,12 +225,178 @@ type TimeSlot struct {
 	Available bool      `json:"available"`
 }
 
-// BookingSummary represents a summary of booking costs
+// StylistAvailability is one stylist's open slots within a branch availability grid
+type StylistAvailability struct {
+	StylistID   uuid.UUID   `json:"stylist_id"`
+	StylistName string      `json:"stylist_name"`
+	Slots       []*TimeSlot `json:"slots"`
+}
+
+// BookingSummary represents a summary of booking costs. The aggregate fields
+// are kept alongside the itemized breakdown for existing clients.
 type BookingSummary struct {
 	Subtotal   float64 `json:"subtotal"`
+	Discount   float64 `json:"discount"`
 	BookingFee float64 `json:"booking_fee"`
 	GST        float64 `json:"gst"`
 	Total      float64 `json:"total"`
+	PromoCode  *string `json:"promo_code,omitempty"`
+
+	LineItems []BookingSummaryLineItem `json:"line_items"`
+	Taxes     []BookingSummaryTax      `json:"taxes"`
+}
+
+// BookingSummaryLineItem is the price of one requested service
+type BookingSummaryLineItem struct {
+	ServiceID       uuid.UUID  `json:"service_id"`
+	ServiceName     string     `json:"service_name"`
+	StylistID       uuid.UUID  `json:"stylist_id"`
+	StylistName     string     `json:"stylist_name,omitempty"`
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Price           float64    `json:"price"`
+	TaxExempt       bool       `json:"tax_exempt"`
+
+	BeneficiaryName   *string    `json:"beneficiary_name,omitempty"`
+	BeneficiaryUserID *uuid.UUID `json:"beneficiary_user_id,omitempty"`
+}
+
+// BookingSummaryTax is one tax component applied to the taxable services of
+// a booking
+type BookingSummaryTax struct {
+	Name          string  `json:"name"`
+	Percentage    float64 `json:"percentage"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	Amount        float64 `json:"amount"`
+}
+
+// IdempotencyRecord links a client idempotency key to the booking it created
+type IdempotencyRecord struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	IdempotencyKey string    `json:"idempotency_key" db:"idempotency_key"`
+	RequestHash    string    `json:"request_hash" db:"request_hash"`
+	BookingID      uuid.UUID `json:"booking_id" db:"booking_id"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// DueReminder identifies a booking whose reminder threshold has been reached
+type DueReminder struct {
+	BookingID        uuid.UUID `json:"booking_id"`
+	ThresholdMinutes int       `json:"threshold_minutes"`
+	FirstStartTime   time.Time `json:"first_start_time"`
+}
+
+// BookingFilter holds the criteria used when listing bookings for a branch
+type BookingFilter struct {
+	BranchID uuid.UUID
+	Statuses []BookingStatus
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+// BookingExportRow is one booking in a branch export. Service and stylist IDs
+// are listed in service start order.
+type BookingExportRow struct {
+	ID             uuid.UUID     `json:"id"`
+	UserID         uuid.UUID     `json:"user_id"`
+	ServiceIDs     []string      `json:"service_ids"`
+	StylistIDs     []string      `json:"stylist_ids"`
+	StartTime      time.Time     `json:"start_time"`
+	EndTime        time.Time     `json:"end_time"`
+	TotalAmount    float64       `json:"total_amount"`
+	GST            float64       `json:"gst"`
+	BookingFee     float64       `json:"booking_fee"`
+	DiscountAmount float64       `json:"discount_amount"`
+	Status         BookingStatus `json:"status"`
+	PaymentStatus  PaymentStatus `json:"payment_status"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// UserBookingSort selects the order of a user's booking list
+type UserBookingSort string
+
+const (
+	// UserBookingSortCreatedAt lists the newest bookings first (default)
+	UserBookingSortCreatedAt UserBookingSort = "created_at"
+	// UserBookingSortStartTime lists bookings by their first service start time,
+	// ascending for upcoming bookings and descending for past ones
+	UserBookingSortStartTime UserBookingSort = "start_time"
+)
+
+// UserBookingScope restricts a user's booking list relative to the current time
+type UserBookingScope string
+
+const (
+	UserBookingScopeAll      UserBookingScope = ""
+	UserBookingScopeUpcoming UserBookingScope = "upcoming"
+	UserBookingScopePast     UserBookingScope = "past"
+)
+
+// UserBookingFilter holds the criteria used when listing a user's bookings.
+// When Cursor is set it replaces Offset and continues after the cursor's booking.
+type UserBookingFilter struct {
+	UserID uuid.UUID
+	Sort   UserBookingSort
+	Scope  UserBookingScope
+	Now    time.Time
+	Cursor *BookingCursor
+	Limit  int
+	Offset int
+}
+
+// Descending reports whether the filter lists bookings newest first
+func (f UserBookingFilter) Descending() bool {
+	return f.Sort != UserBookingSortStartTime || f.Scope == UserBookingScopePast
+}
+
+// BookingCursor marks the last booking of a page by its sort key
+type BookingCursor struct {
+	SortValue time.Time
+	BookingID uuid.UUID
+}
+
+// NewBookingCursor returns the cursor that continues a listing after booking
+func NewBookingCursor(booking *Booking, sort UserBookingSort) *BookingCursor {
+	sortValue := booking.CreatedAt
+	if sort == UserBookingSortStartTime {
+		if start := booking.GetEarliestStartTime(); start != nil {
+			sortValue = *start
+		}
+	}
+	return &BookingCursor{SortValue: sortValue, BookingID: booking.ID}
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c *BookingCursor) Encode() string {
+	raw := strconv.FormatInt(c.SortValue.UnixNano(), 10) + ":" + c.BookingID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseBookingCursor decodes a token produced by Encode
+func ParseBookingCursor(token string) (*BookingCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	bookingID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &BookingCursor{SortValue: time.Unix(0, unixNano), BookingID: bookingID}, nil
 }
 
 // GetDuration returns the total duration of the booking in minutes
@@ -146,6 +461,19 @@ func (b *Booking) CanBeCanceled(cutoffHours int) bool {
 	return earliestStart.After(cutoffTime)
 }
 
+// Location returns the booking's time zone, falling back to UTC for bookings
+// made before time zones were recorded or with an unknown zone
+func (b *Booking) Location() *time.Location {
+	if b.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // CanBeRescheduled checks if the booking can be rescheduled based on window
 func (b *Booking) CanBeRescheduled(windowHours int) bool {
 	if b.Status == BookingStatusCanceled || b.Status == BookingStatusCompleted {
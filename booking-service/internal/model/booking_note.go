@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookingNoteVisibility controls who can read a booking note
+type BookingNoteVisibility string
+
+const (
+	// BookingNoteVisibilityCustomer notes are shown to the booking's customer and to staff
+	BookingNoteVisibilityCustomer BookingNoteVisibility = "customer"
+	// BookingNoteVisibilityInternal notes are only shown to staff
+	BookingNoteVisibilityInternal BookingNoteVisibility = "internal"
+)
+
+// IsValid reports whether v is a known visibility
+func (v BookingNoteVisibility) IsValid() bool {
+	return v == BookingNoteVisibilityCustomer || v == BookingNoteVisibilityInternal
+}
+
+// BookingNote is a note added to a booking after creation, such as an allergy
+// or the products used. Notes are append-only.
+type BookingNote struct {
+	ID         uuid.UUID             `json:"id" db:"id"`
+	BookingID  uuid.UUID             `json:"booking_id" db:"booking_id"`
+	AuthorID   uuid.UUID             `json:"author_id" db:"author_id"`
+	Visibility BookingNoteVisibility `json:"visibility" db:"visibility"`
+	Note       string                `json:"note" db:"note"`
+	CreatedAt  time.Time             `json:"created_at" db:"created_at"`
+}
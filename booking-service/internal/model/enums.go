@@ -9,6 +9,8 @@ const (
 	BookingStatusRescheduled BookingStatus = "rescheduled"
 	BookingStatusCanceled   BookingStatus = "canceled"
 	BookingStatusCompleted  BookingStatus = "completed"
+	// BookingStatusExpired marks an initiated booking voided before payment
+	BookingStatusExpired    BookingStatus = "expired"
 )
 
 // PaymentStatus represents the payment status of a booking
@@ -19,6 +21,9 @@ const (
 	PaymentStatusPaid    PaymentStatus = "paid"
 	PaymentStatusFailed  PaymentStatus = "failed"
 	PaymentStatusRefunded PaymentStatus = "refunded"
+	// PaymentStatusPayAtSalon marks a confirmed booking that takes no online
+	// payment and is settled at the salon, as recurring booking occurrences are
+	PaymentStatusPayAtSalon PaymentStatus = "pay_at_salon"
 )
 
 // BookingAction represents actions performed on bookings for history tracking
@@ -30,12 +35,16 @@ const (
 	BookingActionRescheduled BookingAction = "rescheduled"
 	BookingActionCanceled    BookingAction = "canceled"
 	BookingActionCompleted   BookingAction = "completed"
+	BookingActionExpired     BookingAction = "expired"
+	// BookingActionPaymentAdjusted records a balance charge or partial refund
+	// after a reschedule changed the total of a paid booking, and refunds
+	BookingActionPaymentAdjusted BookingAction = "payment_adjusted"
 )
 
 // IsValid checks if the booking status is valid
 func (bs BookingStatus) IsValid() bool {
 	switch bs {
-	case BookingStatusInitiated, BookingStatusConfirmed, BookingStatusRescheduled, BookingStatusCanceled, BookingStatusCompleted:
+	case BookingStatusInitiated, BookingStatusConfirmed, BookingStatusRescheduled, BookingStatusCanceled, BookingStatusCompleted, BookingStatusExpired:
 		return true
 	default:
 		return false
@@ -45,7 +54,7 @@ func (bs BookingStatus) IsValid() bool {
 // IsValid checks if the payment status is valid
 func (ps PaymentStatus) IsValid() bool {
 	switch ps {
-	case PaymentStatusPending, PaymentStatusPaid, PaymentStatusFailed, PaymentStatusRefunded:
+	case PaymentStatusPending, PaymentStatusPaid, PaymentStatusFailed, PaymentStatusRefunded, PaymentStatusPayAtSalon:
 		return true
 	default:
 		return false
@@ -55,7 +64,7 @@ func (ps PaymentStatus) IsValid() bool {
 // IsValid checks if the booking action is valid
 func (ba BookingAction) IsValid() bool {
 	switch ba {
-	case BookingActionCreated, BookingActionConfirmed, BookingActionRescheduled, BookingActionCanceled, BookingActionCompleted:
+	case BookingActionCreated, BookingActionConfirmed, BookingActionRescheduled, BookingActionCanceled, BookingActionCompleted, BookingActionExpired, BookingActionPaymentAdjusted:
 		return true
 	default:
 		return false
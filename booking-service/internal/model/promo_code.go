@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/EricsAntony/salon/salon-shared/money"
+	"github.com/google/uuid"
+)
+
+// DiscountType represents how a promo code discount is calculated
+type DiscountType string
+
+const (
+	DiscountTypePercentage DiscountType = "percentage"
+	DiscountTypeFlat       DiscountType = "flat"
+)
+
+// PromoCode is a discount customers can apply when booking
+type PromoCode struct {
+	ID                uuid.UUID    `json:"id" db:"id"`
+	Code              string       `json:"code" db:"code"`
+	SalonID           *uuid.UUID   `json:"salon_id,omitempty" db:"salon_id"`
+	DiscountType      DiscountType `json:"discount_type" db:"discount_type"`
+	DiscountValue     float64      `json:"discount_value" db:"discount_value"`
+	MaxDiscountAmount *float64     `json:"max_discount_amount,omitempty" db:"max_discount_amount"`
+	MinSpend          float64      `json:"min_spend" db:"min_spend"`
+	StartsAt          *time.Time   `json:"starts_at,omitempty" db:"starts_at"`
+	ExpiresAt         *time.Time   `json:"expires_at,omitempty" db:"expires_at"`
+	UsageLimit        *int         `json:"usage_limit,omitempty" db:"usage_limit"`
+	PerUserLimit      *int         `json:"per_user_limit,omitempty" db:"per_user_limit"`
+	UsageCount        int          `json:"usage_count" db:"usage_count"`
+	IsActive          bool         `json:"is_active" db:"is_active"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// CalculateDiscount returns the discount on subtotal, rounded to two decimals
+// and never more than the subtotal itself
+func (p *PromoCode) CalculateDiscount(subtotal float64) float64 {
+	var discount float64
+	switch p.DiscountType {
+	case DiscountTypePercentage:
+		discount = money.Percentage(subtotal, p.DiscountValue)
+		if p.MaxDiscountAmount != nil && discount > *p.MaxDiscountAmount {
+			discount = *p.MaxDiscountAmount
+		}
+	case DiscountTypeFlat:
+		discount = p.DiscountValue
+	}
+
+	if discount > subtotal {
+		discount = subtotal
+	}
+	return money.Round(discount)
+}
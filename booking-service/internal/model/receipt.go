@@ -0,0 +1,62 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookingReceipt is the content of a payment receipt for a booking. It is
+// built only from stored booking data and lookups, so the same booking state
+// always produces the same receipt.
+type BookingReceipt struct {
+	BookingID uuid.UUID `json:"booking_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	BranchID  uuid.UUID `json:"branch_id"`
+	// Number is the receipt number printed on the document
+	Number string `json:"number"`
+	// IssuedAt is when the booking was paid for, falling back to its last update
+	IssuedAt time.Time `json:"issued_at"`
+	// UpdatedAt is the booking's last update; receipts change only when it does
+	UpdatedAt time.Time `json:"updated_at"`
+	Currency  string    `json:"currency"`
+
+	SalonName     string `json:"salon_name"`
+	SalonAddress  string `json:"salon_address,omitempty"`
+	BranchName    string `json:"branch_name"`
+	BranchAddress string `json:"branch_address,omitempty"`
+	BranchPhone   string `json:"branch_phone,omitempty"`
+	// Timezone is the branch's IANA timezone that times are printed in
+	Timezone string `json:"timezone,omitempty"`
+
+	Items []BookingReceiptItem `json:"items"`
+
+	Subtotal      float64 `json:"subtotal"`
+	Discount      float64 `json:"discount"`
+	PromoCode     *string `json:"promo_code,omitempty"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	GST           float64 `json:"gst"`
+	BookingFee    float64 `json:"booking_fee"`
+	Total         float64 `json:"total"`
+	// Taxes itemizes GST by component; bookings priced before tax components
+	// existed have none and are taxed on the whole discounted subtotal
+	Taxes []BookingSummaryTax `json:"taxes,omitempty"`
+
+	PaymentStatus    PaymentStatus `json:"payment_status"`
+	PaymentReference string        `json:"payment_reference,omitempty"`
+}
+
+// BookingReceiptItem is one service on a receipt
+type BookingReceiptItem struct {
+	ServiceName     string    `json:"service_name"`
+	StylistName     string    `json:"stylist_name,omitempty"`
+	BeneficiaryName string    `json:"beneficiary_name,omitempty"`
+	StartTime       time.Time `json:"start_time"`
+	Price           float64   `json:"price"`
+}
+
+// ReceiptNumber derives the printed receipt number from the booking ID
+func ReceiptNumber(bookingID uuid.UUID) string {
+	return "RCPT-" + strings.ToUpper(strings.ReplaceAll(bookingID.String(), "-", "")[:12])
+}
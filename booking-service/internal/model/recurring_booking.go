@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecurringBookingStatus represents the state of a recurring booking
+type RecurringBookingStatus string
+
+const (
+	// RecurringBookingStatusActive recurrences have their occurrences booked ahead
+	RecurringBookingStatusActive RecurringBookingStatus = "active"
+	// RecurringBookingStatusPaused recurrences keep booked occurrences but book no new ones
+	RecurringBookingStatusPaused RecurringBookingStatus = "paused"
+	// RecurringBookingStatusCancelled recurrences have ended; their upcoming occurrences were cancelled
+	RecurringBookingStatusCancelled RecurringBookingStatus = "cancelled"
+)
+
+// RecurringBooking is a standing appointment with one stylist, booked every
+// IntervalWeeks weeks on Weekday at StartTime in the branch time zone. Dates
+// are calendar days formatted as YYYY-MM-DD.
+type RecurringBooking struct {
+	ID         uuid.UUID   `json:"id" db:"id"`
+	UserID     uuid.UUID   `json:"user_id" db:"user_id"`
+	SalonID    uuid.UUID   `json:"salon_id" db:"salon_id"`
+	BranchID   uuid.UUID   `json:"branch_id" db:"branch_id"`
+	StylistID  uuid.UUID   `json:"stylist_id" db:"stylist_id"`
+	ServiceIDs []uuid.UUID `json:"service_ids" db:"service_ids"`
+	// Weekday is 0 for Sunday through 6 for Saturday, as time.Weekday
+	Weekday       int                    `json:"weekday" db:"weekday"`
+	StartTime     string                 `json:"start_time" db:"start_time"`
+	IntervalWeeks int                    `json:"interval_weeks" db:"interval_weeks"`
+	StartDate     string                 `json:"start_date" db:"start_date"`
+	EndDate       *string                `json:"end_date,omitempty" db:"end_date"`
+	Timezone      string                 `json:"timezone" db:"timezone"`
+	Notes         *string                `json:"notes,omitempty" db:"notes"`
+	Status        RecurringBookingStatus `json:"status" db:"status"`
+	// GeneratedThrough is the last occurrence date booked or skipped
+	GeneratedThrough *string   `json:"generated_through,omitempty" db:"generated_through"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
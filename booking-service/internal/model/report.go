@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevenueGroupBy selects how a revenue report is broken down
+type RevenueGroupBy string
+
+const (
+	RevenueGroupByService RevenueGroupBy = "service"
+	RevenueGroupByStylist RevenueGroupBy = "stylist"
+)
+
+// IsValid checks if the grouping is supported
+func (g RevenueGroupBy) IsValid() bool {
+	return g == RevenueGroupByService || g == RevenueGroupByStylist
+}
+
+// RevenueStatuses are the booking statuses that count towards revenue
+var RevenueStatuses = []BookingStatus{BookingStatusConfirmed, BookingStatusRescheduled, BookingStatusCompleted}
+
+// RevenueReportFilter holds the criteria for a branch revenue report. Booking
+// services are included when they start within [From, To).
+type RevenueReportFilter struct {
+	BranchID uuid.UUID
+	GroupBy  RevenueGroupBy
+	Statuses []BookingStatus
+	From     time.Time
+	To       time.Time
+}
+
+// RevenueReportRow aggregates the booked services of one service or stylist.
+// Revenue sums service prices before GST, booking fees and discounts.
+type RevenueReportRow struct {
+	ID           uuid.UUID `json:"id"`
+	BookingCount int       `json:"booking_count"`
+	ServiceCount int       `json:"service_count"`
+	Revenue      float64   `json:"revenue"`
+}
+
+// RevenueReport is a branch's revenue over a date range, highest revenue first
+type RevenueReport struct {
+	BranchID     uuid.UUID          `json:"branch_id"`
+	GroupBy      RevenueGroupBy     `json:"group_by"`
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Statuses     []BookingStatus    `json:"statuses"`
+	TotalRevenue float64            `json:"total_revenue"`
+	Rows         []RevenueReportRow `json:"rows"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WaitlistStatus represents the state of a waitlist entry
+type WaitlistStatus string
+
+const (
+	// WaitlistStatusWaiting entries are offered the next freed slot in their window
+	WaitlistStatusWaiting WaitlistStatus = "waiting"
+	// WaitlistStatusNotified entries were told about a freed slot
+	WaitlistStatusNotified  WaitlistStatus = "notified"
+	WaitlistStatusCancelled WaitlistStatus = "cancelled"
+	// WaitlistStatusExpired entries passed their desired window while still waiting
+	WaitlistStatusExpired WaitlistStatus = "expired"
+)
+
+// WaitlistEntry is a customer waiting for a stylist slot within a desired window
+type WaitlistEntry struct {
+	ID                uuid.UUID      `json:"id" db:"id"`
+	UserID            uuid.UUID      `json:"user_id" db:"user_id"`
+	SalonID           uuid.UUID      `json:"salon_id" db:"salon_id"`
+	BranchID          uuid.UUID      `json:"branch_id" db:"branch_id"`
+	StylistID         uuid.UUID      `json:"stylist_id" db:"stylist_id"`
+	ServiceID         *uuid.UUID     `json:"service_id,omitempty" db:"service_id"`
+	DesiredStart      time.Time      `json:"desired_start" db:"desired_start"`
+	DesiredEnd        time.Time      `json:"desired_end" db:"desired_end"`
+	Status            WaitlistStatus `json:"status" db:"status"`
+	NotifiedBookingID *uuid.UUID     `json:"notified_booking_id,omitempty" db:"notified_booking_id"`
+	NotifiedAt        *time.Time     `json:"notified_at,omitempty" db:"notified_at"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// FreedSlot is a stylist's time range released by a cancelled or rescheduled booking
+type FreedSlot struct {
+	BookingID uuid.UUID
+	StylistID uuid.UUID
+	StartTime time.Time
+	EndTime   time.Time
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// CreateBookingNote appends a note to a booking
+func (r *bookingRepository) CreateBookingNote(ctx context.Context, note *model.BookingNote) error {
+	query := `
+		INSERT INTO booking_notes (id, booking_id, author_id, visibility, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		note.ID, note.BookingID, note.AuthorID, note.Visibility, note.Note,
+	).Scan(&note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create booking note: %w", err)
+	}
+
+	return nil
+}
+
+// GetBookingNotes lists a booking's notes oldest first, leaving out internal
+// notes unless includeInternal is set
+func (r *bookingRepository) GetBookingNotes(ctx context.Context, bookingID uuid.UUID, includeInternal bool) ([]*model.BookingNote, error) {
+	query := `
+		SELECT id, booking_id, author_id, visibility, note, created_at
+		FROM booking_notes
+		WHERE booking_id = $1 AND ($2 OR visibility = 'customer')
+		ORDER BY created_at ASC, id
+	`
+
+	rows, err := r.db.Query(ctx, query, bookingID, includeInternal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []*model.BookingNote
+	for rows.Next() {
+		note := &model.BookingNote{}
+		if err := rows.Scan(&note.ID, &note.BookingID, &note.AuthorID, &note.Visibility, &note.Note, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan booking note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
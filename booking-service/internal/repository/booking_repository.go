@@ -2,24 +2,62 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"booking-service/internal/model"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	// ErrBookingNotFound is returned when no booking exists with the given ID
+	ErrBookingNotFound = errors.New("booking not found")
+	// ErrSlotUnavailable is returned when a stylist already has an overlapping booking
+	ErrSlotUnavailable = errors.New("stylist slot is no longer available")
+	// ErrDuplicateIdempotencyKey is returned when a user's idempotency key is already in use
+	ErrDuplicateIdempotencyKey = errors.New("idempotency key already used")
+	// ErrPromoCodeNotFound is returned when no promo code matches
+	ErrPromoCodeNotFound = errors.New("promo code not found")
+	// ErrPromoCodeExhausted is returned when a promo code reached its usage limit
+	ErrPromoCodeExhausted = errors.New("promo code usage limit reached")
+	// ErrPromoCodeUserLimit is returned when a user already used a promo code the allowed number of times
+	ErrPromoCodeUserLimit = errors.New("promo code already used the maximum number of times by this user")
+	// ErrBranchConfigVersionConflict is returned when a branch configuration changed since it was read
+	ErrBranchConfigVersionConflict = errors.New("branch configuration was modified by another update")
+	// ErrBookingVersionConflict is returned when a booking changed since it was read
+	ErrBookingVersionConflict = errors.New("booking was modified by another update")
+	// ErrRecurringOccurrenceExists is returned when a recurring booking's occurrence date is already booked
+	ErrRecurringOccurrenceExists = errors.New("recurring booking occurrence already booked")
+)
+
 // BookingRepository defines the interface for booking data operations
 type BookingRepository interface {
 	// Booking operations
 	Create(ctx context.Context, booking *model.Booking) error
+	CreateWithServices(ctx context.Context, booking *model.Booking, services []model.BookingService, buffer time.Duration, idempotency *model.IdempotencyRecord) error
+	RescheduleWithServices(ctx context.Context, booking *model.Booking, services []model.BookingService, buffer time.Duration) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Booking, error)
-	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Booking, error)
+	GetByUserID(ctx context.Context, filter model.UserBookingFilter) ([]*model.Booking, error)
+	CountByUserID(ctx context.Context, filter model.UserBookingFilter) (int, error)
+	List(ctx context.Context, filter model.BookingFilter) ([]*model.Booking, error)
+	GetBySalonID(ctx context.Context, salonID uuid.UUID, from, to time.Time) ([]*model.Booking, error)
+	GetByBranchID(ctx context.Context, branchID uuid.UUID, from, to time.Time) ([]*model.Booking, error)
+	Count(ctx context.Context, filter model.BookingFilter) (int, error)
+	ExportBranchBookings(ctx context.Context, filter model.BookingFilter, fn func(*model.BookingExportRow) error) error
+	RevenueReport(ctx context.Context, filter model.RevenueReportFilter) ([]model.RevenueReportRow, error)
 	Update(ctx context.Context, booking *model.Booking) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status model.BookingStatus) error
+	MarkConfirmed(ctx context.Context, id uuid.UUID, paymentID string) (bool, error)
+	MarkExpired(ctx context.Context, id uuid.UUID) (bool, error)
+	MarkBalancePaid(ctx context.Context, id uuid.UUID, balancePaymentID string) (bool, error)
+	GetStaleInitiatedBookings(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error)
 	
 	// Booking service operations
 	CreateBookingService(ctx context.Context, service *model.BookingService) error
@@ -29,12 +67,48 @@ type BookingRepository interface {
 	
 	// Availability operations
 	GetStylistBookings(ctx context.Context, stylistID uuid.UUID, startTime, endTime time.Time) ([]*model.BookingService, error)
+	GetStylistsBookings(ctx context.Context, stylistIDs []uuid.UUID, startTime, endTime time.Time) (map[uuid.UUID][]*model.BookingService, error)
 	CheckStylistAvailability(ctx context.Context, stylistID uuid.UUID, startTime, endTime time.Time) (bool, error)
 	
+	// Promo code operations
+	GetPromoCode(ctx context.Context, code string) (*model.PromoCode, error)
+	
+	// Waitlist operations
+	CreateWaitlistEntry(ctx context.Context, entry *model.WaitlistEntry) error
+	GetWaitlistByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.WaitlistEntry, error)
+	CountWaitlistByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	CancelWaitlistEntry(ctx context.Context, id, userID uuid.UUID) error
+	ClaimWaitlistEntryForSlot(ctx context.Context, slot model.FreedSlot) (*model.WaitlistEntry, error)
+	ExpireWaitlistEntries(ctx context.Context, now time.Time) (int, error)
+	
+	// Recurring booking operations
+	CreateRecurringBooking(ctx context.Context, recurring *model.RecurringBooking) error
+	GetRecurringBookingsByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.RecurringBooking, error)
+	CountRecurringBookingsByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	SetRecurringBookingStatus(ctx context.Context, id, userID uuid.UUID, status model.RecurringBookingStatus, from ...model.RecurringBookingStatus) (*model.RecurringBooking, error)
+	GetDueRecurringBookings(ctx context.Context, notGeneratedSince time.Time, limit int) ([]*model.RecurringBooking, error)
+	MarkRecurringBookingGenerated(ctx context.Context, id uuid.UUID, through *string) error
+	RecordRecurringBookingSkip(ctx context.Context, id uuid.UUID, occurrenceDate, reason string) (bool, error)
+	GetUpcomingRecurringOccurrences(ctx context.Context, id uuid.UUID, after time.Time) ([]uuid.UUID, error)
+	
 	// History operations
 	CreateHistory(ctx context.Context, history *model.BookingHistory) error
 	GetBookingHistory(ctx context.Context, bookingID uuid.UUID) ([]*model.BookingHistory, error)
 	
+	// Note operations
+	CreateBookingNote(ctx context.Context, note *model.BookingNote) error
+	GetBookingNotes(ctx context.Context, bookingID uuid.UUID, includeInternal bool) ([]*model.BookingNote, error)
+	
+	// Idempotency operations
+	GetIdempotencyRecord(ctx context.Context, userID uuid.UUID, key string) (*model.IdempotencyRecord, error)
+	
+	// Guest checkout operations
+	GetGuestAccessTokenHash(ctx context.Context, bookingID uuid.UUID) (*string, error)
+	
+	// Reminder operations
+	GetDueReminders(ctx context.Context, defaultLeadTimes []int, limit int) ([]*model.DueReminder, error)
+	MarkReminderSent(ctx context.Context, bookingID uuid.UUID, thresholdMinutes int) (bool, error)
+	
 	// Configuration operations
 	GetBranchConfiguration(ctx context.Context, branchID uuid.UUID) (*model.BranchConfiguration, error)
 	CreateBranchConfiguration(ctx context.Context, config *model.BranchConfiguration) error
@@ -42,7 +116,20 @@ type BookingRepository interface {
 }
 
 type bookingRepository struct {
-	db *pgxpool.Pool
+	db database
+}
+
+// database is the part of the connection pool the repository uses
+type database interface {
+	queryer
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// queryer is satisfied by both the connection pool and a transaction
+type queryer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
 // NewBookingRepository creates a new booking repository
@@ -52,19 +139,231 @@ func NewBookingRepository(db *pgxpool.Pool) BookingRepository {
 
 // Create creates a new booking
 func (r *bookingRepository) Create(ctx context.Context, booking *model.Booking) error {
+	return insertBooking(ctx, r.db, booking)
+}
+
+// CreateWithServices creates a booking and its services in a single transaction,
+// holding a per-stylist lock so concurrent requests cannot book overlapping slots.
+// When an idempotency record is given it is stored in the same transaction.
+func (r *bookingRepository) CreateWithServices(ctx context.Context, booking *model.Booking, services []model.BookingService, buffer time.Duration, idempotency *model.IdempotencyRecord) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockStylists(ctx, tx, services); err != nil {
+		return err
+	}
+
+	if booking.PromoCode != nil {
+		if err := redeemPromoCode(ctx, tx, *booking.PromoCode, booking.UserID); err != nil {
+			return err
+		}
+	}
+
+	if err := insertBooking(ctx, tx, booking); err != nil {
+		return err
+	}
+
+	if idempotency != nil {
+		idempotency.BookingID = booking.ID
+		if err := insertIdempotencyRecord(ctx, tx, idempotency); err != nil {
+			return err
+		}
+	}
+
+	if err := reserveBookingServices(ctx, tx, booking.ID, services, buffer); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit booking: %w", err)
+	}
+
+	return nil
+}
+
+// RescheduleWithServices replaces a booking's services and updates the booking in a single
+// transaction, holding a per-stylist lock so concurrent requests cannot book overlapping slots
+func (r *bookingRepository) RescheduleWithServices(ctx context.Context, booking *model.Booking, services []model.BookingService, buffer time.Duration) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockStylists(ctx, tx, services); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM booking_services WHERE booking_id = $1`, booking.ID); err != nil {
+		return fmt.Errorf("failed to delete booking services: %w", err)
+	}
+
+	// Reminders are rescheduled along with the booking
+	if _, err := tx.Exec(ctx, `DELETE FROM booking_reminders WHERE booking_id = $1`, booking.ID); err != nil {
+		return fmt.Errorf("failed to reset booking reminders: %w", err)
+	}
+
+	if err := updateBooking(ctx, tx, booking); err != nil {
+		return err
+	}
+
+	// Taxes are only repriced along with the services
+	if _, err := tx.Exec(ctx, `UPDATE bookings SET taxes = $2 WHERE id = $1`, booking.ID, booking.Taxes); err != nil {
+		return fmt.Errorf("failed to update booking taxes: %w", err)
+	}
+
+	if err := reserveBookingServices(ctx, tx, booking.ID, services, buffer); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reschedule: %w", err)
+	}
+
+	return nil
+}
+
+// insertIdempotencyRecord replaces any expired record for the same key and stores the new one
+func insertIdempotencyRecord(ctx context.Context, tx pgx.Tx, record *model.IdempotencyRecord) error {
+	_, err := tx.Exec(ctx, `
+		DELETE FROM booking_idempotency_records
+		WHERE user_id = $1 AND idempotency_key = $2 AND expires_at <= NOW()
+	`, record.UserID, record.IdempotencyKey)
+	if err != nil {
+		return fmt.Errorf("failed to clear expired idempotency record: %w", err)
+	}
+
 	query := `
-		INSERT INTO bookings (id, user_id, salon_id, branch_id, status, total_amount, gst, booking_fee, payment_status, payment_id, notes)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING created_at, updated_at
+		INSERT INTO booking_idempotency_records (id, user_id, idempotency_key, request_hash, booking_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		record.ID, record.UserID, record.IdempotencyKey, record.RequestHash, record.BookingID, record.ExpiresAt,
+	).Scan(&record.CreatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateIdempotencyKey
+		}
+		return fmt.Errorf("failed to create idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// lockStylists takes a transaction-scoped advisory lock for each stylist, in a stable order to avoid deadlocks
+func lockStylists(ctx context.Context, tx pgx.Tx, services []model.BookingService) error {
+	seen := make(map[uuid.UUID]bool)
+	var stylistIDs []string
+	for _, service := range services {
+		if !seen[service.StylistID] {
+			seen[service.StylistID] = true
+			stylistIDs = append(stylistIDs, service.StylistID.String())
+		}
+	}
+	sort.Strings(stylistIDs)
+
+	for _, stylistID := range stylistIDs {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, stylistID); err != nil {
+			return fmt.Errorf("failed to lock stylist schedule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// reserveBookingServices inserts a booking's services after checking them
+// against each other and against the stylists' other bookings
+func reserveBookingServices(ctx context.Context, tx pgx.Tx, bookingID uuid.UUID, services []model.BookingService, buffer time.Duration) error {
+	if err := checkOwnServicesOverlap(services); err != nil {
+		return err
+	}
+
+	for i := range services {
+		services[i].BookingID = bookingID
+		if err := reserveBookingService(ctx, tx, &services[i], buffer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkOwnServicesOverlap rejects a booking that gives one stylist two
+// overlapping services. The buffer separates different bookings only, so
+// back-to-back services within a booking are allowed.
+func checkOwnServicesOverlap(services []model.BookingService) error {
+	for i := range services {
+		for j := i + 1; j < len(services); j++ {
+			a, b := services[i], services[j]
+			if a.StylistID == b.StylistID && a.StartTime.Before(b.EndTime) && b.StartTime.Before(a.EndTime) {
+				return fmt.Errorf("%w: stylist %s has overlapping services at %s", ErrSlotUnavailable, b.StylistID, b.StartTime.Format("2006-01-02 15:04"))
+			}
+		}
+	}
+	return nil
+}
+
+// bufferedOverlapQuery finds a stylist's service in another active booking
+// that overlaps a slot widened by the buffer. The booking's own services are
+// excluded; they are checked against each other without the buffer.
+const bufferedOverlapQuery = `
+		SELECT bs.id
+		FROM booking_services bs
+		JOIN bookings b ON bs.booking_id = b.id
+		WHERE bs.stylist_id = $1
+		  AND bs.start_time < $3
+		  AND bs.end_time > $2
+		  AND bs.booking_id <> $4
+		  AND b.status IN ('initiated', 'confirmed', 'rescheduled')
+		LIMIT 1
+		FOR UPDATE OF bs
+	`
+
+// reserveBookingService checks the stylist has no overlapping booking (including buffer) and inserts the service
+func reserveBookingService(ctx context.Context, tx pgx.Tx, service *model.BookingService, buffer time.Duration) error {
+	var conflictID uuid.UUID
+	err := tx.QueryRow(ctx, bufferedOverlapQuery,
+		service.StylistID, service.StartTime.Add(-buffer), service.EndTime.Add(buffer), service.BookingID,
+	).Scan(&conflictID)
+	if err == nil {
+		return fmt.Errorf("%w: stylist %s at %s", ErrSlotUnavailable, service.StylistID, service.StartTime.Format("2006-01-02 15:04"))
+	}
+	if err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to check stylist availability: %w", err)
+	}
+
+	return insertBookingService(ctx, tx, service)
+}
+
+func insertBooking(ctx context.Context, q queryer, booking *model.Booking) error {
+	query := `
+		INSERT INTO bookings (id, user_id, salon_id, branch_id, status, total_amount, gst, booking_fee, payment_status, payment_id, notes,
+		                      promo_code, discount_amount, guest_access_token_hash, timezone, taxes,
+		                      recurring_booking_id, occurrence_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18::text::date)
+		RETURNING created_at, updated_at, version
 	`
 	
-	err := r.db.QueryRow(ctx, query,
+	err := q.QueryRow(ctx, query,
 		booking.ID, booking.UserID, booking.SalonID, booking.BranchID,
 		booking.Status, booking.TotalAmount, booking.GST, booking.BookingFee,
 		booking.PaymentStatus, booking.PaymentID, booking.Notes,
-	).Scan(&booking.CreatedAt, &booking.UpdatedAt)
+		booking.PromoCode, booking.DiscountAmount, booking.GuestAccessTokenHash, booking.Timezone,
+		booking.Taxes, booking.RecurringBookingID, booking.OccurrenceDate,
+	).Scan(&booking.CreatedAt, &booking.UpdatedAt, &booking.Version)
 	
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "uq_bookings_recurring_occurrence" {
+			return ErrRecurringOccurrenceExists
+		}
 		return fmt.Errorf("failed to create booking: %w", err)
 	}
 	
@@ -75,7 +374,8 @@ func (r *bookingRepository) Create(ctx context.Context, booking *model.Booking)
 func (r *bookingRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Booking, error) {
 	query := `
 		SELECT id, user_id, salon_id, branch_id, status, total_amount, gst, booking_fee,
-		       payment_status, payment_id, notes, created_at, updated_at
+		       payment_status, payment_id, notes, promo_code, discount_amount, created_at, updated_at,
+		       balance_payment_id, timezone, taxes, version, recurring_booking_id, to_char(occurrence_date, 'YYYY-MM-DD')
 		FROM bookings
 		WHERE id = $1
 	`
@@ -85,56 +385,76 @@ func (r *bookingRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.B
 		&booking.ID, &booking.UserID, &booking.SalonID, &booking.BranchID,
 		&booking.Status, &booking.TotalAmount, &booking.GST, &booking.BookingFee,
 		&booking.PaymentStatus, &booking.PaymentID, &booking.Notes,
+		&booking.PromoCode, &booking.DiscountAmount,
 		&booking.CreatedAt, &booking.UpdatedAt,
+		&booking.BalancePaymentID, &booking.Timezone, &booking.Taxes, &booking.Version,
+		&booking.RecurringBookingID, &booking.OccurrenceDate,
 	)
 	
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("booking not found")
+			return nil, ErrBookingNotFound
 		}
 		return nil, fmt.Errorf("failed to get booking: %w", err)
 	}
 	
-	// Load services
-	services, err := r.GetBookingServices(ctx, booking.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load booking services: %w", err)
-	}
-	booking.Services = make([]model.BookingService, len(services))
-	for i, service := range services {
-		booking.Services[i] = *service
-	}
-	
-	// Load history
-	history, err := r.GetBookingHistory(ctx, booking.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load booking history: %w", err)
+	bookings := []*model.Booking{booking}
+	if err := r.loadBookingServices(ctx, bookings); err != nil {
+		return nil, err
 	}
-	booking.History = make([]model.BookingHistory, len(history))
-	for i, h := range history {
-		booking.History[i] = *h
+	if err := r.loadBookingHistory(ctx, bookings); err != nil {
+		return nil, err
 	}
 	
 	return booking, nil
 }
 
-// GetByUserID retrieves bookings for a specific user
-func (r *bookingRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Booking, error) {
-	query := `
-		SELECT id, user_id, salon_id, branch_id, status, total_amount, gst, booking_fee,
-		       payment_status, payment_id, notes, created_at, updated_at
-		FROM bookings
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+// GetByUserID retrieves a user's bookings matching the filter. Bookings are
+// ordered by the filter's sort key with the booking ID as a tie-breaker so
+// cursors resume exactly where the previous page ended.
+func (r *bookingRepository) GetByUserID(ctx context.Context, filter model.UserBookingFilter) ([]*model.Booking, error) {
+	conditions, args := userBookingConditions(filter)
+
+	sortColumn := "b.created_at"
+	if filter.Sort == model.UserBookingSortStartTime {
+		sortColumn = "s.first_start_time"
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Descending() {
+		direction, comparison = "DESC", "<"
+	}
+
+	offset := filter.Offset
+	if filter.Cursor != nil {
+		args = append(args, filter.Cursor.SortValue, filter.Cursor.BookingID)
+		conditions = append(conditions, fmt.Sprintf("(%s, b.id) %s ($%d, $%d)", sortColumn, comparison, len(args)-1, len(args)))
+		offset = 0
+	}
+
+	args = append(args, filter.Limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT b.id, b.user_id, b.salon_id, b.branch_id, b.status, b.total_amount, b.gst, b.booking_fee,
+		       b.payment_status, b.payment_id, b.notes, b.promo_code, b.discount_amount, b.created_at, b.updated_at,
+		       b.timezone, b.taxes, b.version, b.recurring_booking_id, to_char(b.occurrence_date, 'YYYY-MM-DD')
+		FROM bookings b
+		LEFT JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE %s
+		ORDER BY %s %s, b.id %s
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conditions, " AND "), sortColumn, direction, direction, len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user bookings: %w", err)
 	}
 	defer rows.Close()
-	
+
 	var bookings []*model.Booking
 	for rows.Next() {
 		booking := &model.Booking{}
@@ -142,56 +462,384 @@ func (r *bookingRepository) GetByUserID(ctx context.Context, userID uuid.UUID, l
 			&booking.ID, &booking.UserID, &booking.SalonID, &booking.BranchID,
 			&booking.Status, &booking.TotalAmount, &booking.GST, &booking.BookingFee,
 			&booking.PaymentStatus, &booking.PaymentID, &booking.Notes,
+			&booking.PromoCode, &booking.DiscountAmount,
 			&booking.CreatedAt, &booking.UpdatedAt,
+			&booking.Timezone, &booking.Taxes, &booking.Version,
+			&booking.RecurringBookingID, &booking.OccurrenceDate,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan booking: %w", err)
 		}
-		
-		// Load services for each booking
-		services, err := r.GetBookingServices(ctx, booking.ID)
+		bookings = append(bookings, booking)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user bookings: %w", err)
+	}
+
+	if err := r.loadBookingServices(ctx, bookings); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// CountByUserID counts every booking of the user matching the filter's sort
+// and scope, ignoring the cursor and page window
+func (r *bookingRepository) CountByUserID(ctx context.Context, filter model.UserBookingFilter) (int, error) {
+	conditions, args := userBookingConditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM bookings b
+		LEFT JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE %s
+	`, strings.Join(conditions, " AND "))
+
+	var total int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count user bookings: %w", err)
+	}
+	return total, nil
+}
+
+// userBookingConditions builds the WHERE conditions shared by the user
+// booking list and count queries
+func userBookingConditions(filter model.UserBookingFilter) ([]string, []interface{}) {
+	conditions := []string{"b.user_id = $1"}
+	args := []interface{}{filter.UserID}
+
+	if filter.Sort == model.UserBookingSortStartTime {
+		conditions = append(conditions, "s.first_start_time IS NOT NULL")
+	}
+
+	switch filter.Scope {
+	case model.UserBookingScopeUpcoming:
+		args = append(args, filter.Now)
+		conditions = append(conditions, fmt.Sprintf("s.first_start_time >= $%d", len(args)))
+	case model.UserBookingScopePast:
+		args = append(args, filter.Now)
+		conditions = append(conditions, fmt.Sprintf("s.first_start_time < $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+// List retrieves bookings for a branch matching the filter, ordered by earliest service start time
+func (r *bookingRepository) List(ctx context.Context, filter model.BookingFilter) ([]*model.Booking, error) {
+	conditions, args := branchBookingConditions(filter)
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT b.id, b.user_id, b.salon_id, b.branch_id, b.status, b.total_amount, b.gst, b.booking_fee,
+		       b.payment_status, b.payment_id, b.notes, b.promo_code, b.discount_amount, b.created_at, b.updated_at,
+		       b.timezone, b.taxes, b.version, b.recurring_booking_id, to_char(b.occurrence_date, 'YYYY-MM-DD')
+		FROM bookings b
+		JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE %s
+		ORDER BY s.first_start_time ASC, b.created_at ASC
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []*model.Booking
+	for rows.Next() {
+		booking := &model.Booking{}
+		err := rows.Scan(
+			&booking.ID, &booking.UserID, &booking.SalonID, &booking.BranchID,
+			&booking.Status, &booking.TotalAmount, &booking.GST, &booking.BookingFee,
+			&booking.PaymentStatus, &booking.PaymentID, &booking.Notes,
+			&booking.PromoCode, &booking.DiscountAmount,
+			&booking.CreatedAt, &booking.UpdatedAt,
+			&booking.Timezone, &booking.Taxes, &booking.Version,
+			&booking.RecurringBookingID, &booking.OccurrenceDate,
+		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load booking services: %w", err)
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
 		}
-		booking.Services = make([]model.BookingService, len(services))
-		for i, service := range services {
-			booking.Services[i] = *service
+		bookings = append(bookings, booking)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bookings: %w", err)
+	}
+
+	if err := r.loadBookingServices(ctx, bookings); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// GetBySalonID retrieves the bookings of every branch of a salon whose first
+// service starts in [from, to), ordered by that start time, with their services
+func (r *bookingRepository) GetBySalonID(ctx context.Context, salonID uuid.UUID, from, to time.Time) ([]*model.Booking, error) {
+	return r.getInWindow(ctx, "b.salon_id", salonID, from, to)
+}
+
+// GetByBranchID retrieves the bookings of a branch whose first service starts
+// in [from, to), ordered by that start time, with their services
+func (r *bookingRepository) GetByBranchID(ctx context.Context, branchID uuid.UUID, from, to time.Time) ([]*model.Booking, error) {
+	return r.getInWindow(ctx, "b.branch_id", branchID, from, to)
+}
+
+// getInWindow loads the bookings matching column = id in the window with one
+// query and their services with a second, whatever the number of bookings
+func (r *bookingRepository) getInWindow(ctx context.Context, column string, id uuid.UUID, from, to time.Time) ([]*model.Booking, error) {
+	query := fmt.Sprintf(`
+		SELECT b.id, b.user_id, b.salon_id, b.branch_id, b.status, b.total_amount, b.gst, b.booking_fee,
+		       b.payment_status, b.payment_id, b.notes, b.promo_code, b.discount_amount, b.created_at, b.updated_at,
+		       b.timezone, b.taxes, b.version, b.recurring_booking_id, to_char(b.occurrence_date, 'YYYY-MM-DD')
+		FROM bookings b
+		JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE %s = $1
+		  AND s.first_start_time >= $2
+		  AND s.first_start_time < $3
+		ORDER BY s.first_start_time ASC, b.id ASC
+	`, column)
+
+	rows, err := r.db.Query(ctx, query, id, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []*model.Booking
+	for rows.Next() {
+		booking := &model.Booking{}
+		err := rows.Scan(
+			&booking.ID, &booking.UserID, &booking.SalonID, &booking.BranchID,
+			&booking.Status, &booking.TotalAmount, &booking.GST, &booking.BookingFee,
+			&booking.PaymentStatus, &booking.PaymentID, &booking.Notes,
+			&booking.PromoCode, &booking.DiscountAmount,
+			&booking.CreatedAt, &booking.UpdatedAt,
+			&booking.Timezone, &booking.Taxes, &booking.Version,
+			&booking.RecurringBookingID, &booking.OccurrenceDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
 		}
-		
 		bookings = append(bookings, booking)
 	}
-	
-	return bookings, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get bookings: %w", err)
+	}
+
+	if err := r.loadBookingServices(ctx, bookings); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// Count counts every branch booking matching the filter, ignoring the page window
+func (r *bookingRepository) Count(ctx context.Context, filter model.BookingFilter) (int, error) {
+	conditions, args := branchBookingConditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM bookings b
+		JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE %s
+	`, strings.Join(conditions, " AND "))
+
+	var total int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count bookings: %w", err)
+	}
+	return total, nil
+}
+
+// ExportBranchBookings streams every branch booking matching the filter to
+// fn, ordered by earliest service start time. Rows are read one at a time so
+// large exports are never held in memory; an error from fn stops the export.
+func (r *bookingRepository) ExportBranchBookings(ctx context.Context, filter model.BookingFilter, fn func(*model.BookingExportRow) error) error {
+	conditions, args := branchBookingConditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT b.id, b.user_id, s.service_ids, s.stylist_ids, s.first_start_time, s.last_end_time,
+		       b.total_amount, b.gst, b.booking_fee, b.discount_amount, b.status, b.payment_status, b.created_at
+		FROM bookings b
+		JOIN (
+			SELECT booking_id,
+			       array_agg(service_id::text ORDER BY start_time) AS service_ids,
+			       array_agg(stylist_id::text ORDER BY start_time) AS stylist_ids,
+			       MIN(start_time) AS first_start_time,
+			       MAX(end_time) AS last_end_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE %s
+		ORDER BY s.first_start_time ASC, b.created_at ASC
+	`, strings.Join(conditions, " AND "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export bookings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &model.BookingExportRow{}
+		err := rows.Scan(
+			&row.ID, &row.UserID, &row.ServiceIDs, &row.StylistIDs, &row.StartTime, &row.EndTime,
+			&row.TotalAmount, &row.GST, &row.BookingFee, &row.DiscountAmount,
+			&row.Status, &row.PaymentStatus, &row.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan exported booking: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export bookings: %w", err)
+	}
+	return nil
+}
+
+// revenueGroupColumns maps a report grouping to its booking_services column
+var revenueGroupColumns = map[model.RevenueGroupBy]string{
+	model.RevenueGroupByService: "bs.service_id",
+	model.RevenueGroupByStylist: "bs.stylist_id",
+}
+
+// RevenueReport sums booked service prices for a branch grouped by service or
+// stylist. The aggregation runs in the database, one row per group.
+func (r *bookingRepository) RevenueReport(ctx context.Context, filter model.RevenueReportFilter) ([]model.RevenueReportRow, error) {
+	column, ok := revenueGroupColumns[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported revenue grouping: %s", filter.GroupBy)
+	}
+
+	statuses := make([]string, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = string(status)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(DISTINCT b.id), COUNT(*), COALESCE(SUM(bs.price), 0)
+		FROM booking_services bs
+		JOIN bookings b ON b.id = bs.booking_id
+		WHERE b.branch_id = $1 AND b.status = ANY($2)
+		  AND bs.start_time >= $3 AND bs.start_time < $4
+		GROUP BY %[1]s
+		ORDER BY 4 DESC, 1
+	`, column)
+
+	rows, err := r.db.Query(ctx, query, filter.BranchID, statuses, filter.From, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build revenue report: %w", err)
+	}
+	defer rows.Close()
+
+	report := []model.RevenueReportRow{}
+	for rows.Next() {
+		var row model.RevenueReportRow
+		if err := rows.Scan(&row.ID, &row.BookingCount, &row.ServiceCount, &row.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue report row: %w", err)
+		}
+		report = append(report, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to build revenue report: %w", err)
+	}
+	return report, nil
 }
 
-// Update updates a booking
+// branchBookingConditions builds the WHERE conditions shared by the branch
+// booking list and count queries
+func branchBookingConditions(filter model.BookingFilter) ([]string, []interface{}) {
+	conditions := []string{"b.branch_id = $1"}
+	args := []interface{}{filter.BranchID}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		args = append(args, statuses)
+		conditions = append(conditions, fmt.Sprintf("b.status = ANY($%d)", len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("s.first_start_time >= $%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("s.first_start_time < $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+// Update updates a booking if it is still at booking.Version, then sets
+// booking.Version to the new version. It returns ErrBookingVersionConflict
+// when the booking was changed since it was read.
 func (r *bookingRepository) Update(ctx context.Context, booking *model.Booking) error {
+	return updateBooking(ctx, r.db, booking)
+}
+
+func updateBooking(ctx context.Context, q queryer, booking *model.Booking) error {
 	query := `
 		UPDATE bookings
 		SET status = $2, total_amount = $3, gst = $4, booking_fee = $5,
-		    payment_status = $6, payment_id = $7, notes = $8, updated_at = NOW()
-		WHERE id = $1
+		    payment_status = $6, payment_id = $7, notes = $8, discount_amount = $9,
+		    balance_payment_id = $10, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $11
+		RETURNING version, updated_at
 	`
 	
-	result, err := r.db.Exec(ctx, query,
+	err := q.QueryRow(ctx, query,
 		booking.ID, booking.Status, booking.TotalAmount, booking.GST,
 		booking.BookingFee, booking.PaymentStatus, booking.PaymentID, booking.Notes,
-	)
+		booking.DiscountAmount, booking.BalancePaymentID, booking.Version,
+	).Scan(&booking.Version, &booking.UpdatedAt)
 	
+	if err == pgx.ErrNoRows {
+		var exists bool
+		existsQuery := `SELECT EXISTS (SELECT 1 FROM bookings WHERE id = $1)`
+		if err := q.QueryRow(ctx, existsQuery, booking.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to update booking: %w", err)
+		}
+		if !exists {
+			return ErrBookingNotFound
+		}
+		return ErrBookingVersionConflict
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update booking: %w", err)
 	}
 	
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("booking not found")
-	}
-	
 	return nil
 }
 
 // UpdateStatus updates only the booking status
 func (r *bookingRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status model.BookingStatus) error {
-	query := `UPDATE bookings SET status = $2, updated_at = NOW() WHERE id = $1`
+	query := `UPDATE bookings SET status = $2, version = version + 1, updated_at = NOW() WHERE id = $1`
 	
 	result, err := r.db.Exec(ctx, query, id, status)
 	if err != nil {
@@ -205,17 +853,102 @@ func (r *bookingRepository) UpdateStatus(ctx context.Context, id uuid.UUID, stat
 	return nil
 }
 
+// MarkConfirmed confirms an initiated booking as paid. It reports false when the
+// booking was no longer initiated, so concurrent confirmations update it only once.
+func (r *bookingRepository) MarkConfirmed(ctx context.Context, id uuid.UUID, paymentID string) (bool, error) {
+	query := `
+		UPDATE bookings
+		SET status = 'confirmed', payment_status = 'paid', payment_id = $2, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND status = 'initiated'
+	`
+	
+	result, err := r.db.Exec(ctx, query, id, paymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm booking: %w", err)
+	}
+	
+	return result.RowsAffected() == 1, nil
+}
+
+// MarkExpired voids an initiated booking. It reports false when the booking was
+// no longer initiated, e.g. because it was confirmed concurrently.
+func (r *bookingRepository) MarkExpired(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE bookings
+		SET status = 'expired', version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND status = 'initiated'
+	`
+	
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to expire booking: %w", err)
+	}
+	
+	return result.RowsAffected() == 1, nil
+}
+
+// MarkBalancePaid settles the outstanding balance of a rescheduled booking and
+// reports whether this call settled it
+func (r *bookingRepository) MarkBalancePaid(ctx context.Context, id uuid.UUID, balancePaymentID string) (bool, error) {
+	query := `
+		UPDATE bookings
+		SET payment_status = 'paid', version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND balance_payment_id = $2 AND payment_status = 'pending'
+	`
+	
+	result, err := r.db.Exec(ctx, query, id, balancePaymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to settle booking balance: %w", err)
+	}
+	
+	return result.RowsAffected() == 1, nil
+}
+
+// GetStaleInitiatedBookings returns IDs of bookings still initiated that were created before the cutoff
+func (r *bookingRepository) GetStaleInitiatedBookings(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM bookings
+		WHERE status = 'initiated' AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	
+	rows, err := r.db.Query(ctx, query, createdBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale bookings: %w", err)
+	}
+	defer rows.Close()
+	
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan stale booking: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	
+	return ids, rows.Err()
+}
+
 // CreateBookingService creates a new booking service
 func (r *bookingRepository) CreateBookingService(ctx context.Context, service *model.BookingService) error {
+	return insertBookingService(ctx, r.db, service)
+}
+
+func insertBookingService(ctx context.Context, q queryer, service *model.BookingService) error {
 	query := `
-		INSERT INTO booking_services (id, booking_id, service_id, stylist_id, start_time, end_time, price)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO booking_services (id, booking_id, service_id, stylist_id, start_time, end_time, price,
+		                              beneficiary_name, beneficiary_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING created_at, updated_at
 	`
 	
-	err := r.db.QueryRow(ctx, query,
+	err := q.QueryRow(ctx, query,
 		service.ID, service.BookingID, service.ServiceID, service.StylistID,
 		service.StartTime, service.EndTime, service.Price,
+		service.BeneficiaryName, service.BeneficiaryUserID,
 	).Scan(&service.CreatedAt, &service.UpdatedAt)
 	
 	if err != nil {
@@ -228,7 +961,8 @@ func (r *bookingRepository) CreateBookingService(ctx context.Context, service *m
 // GetBookingServices retrieves all services for a booking
 func (r *bookingRepository) GetBookingServices(ctx context.Context, bookingID uuid.UUID) ([]*model.BookingService, error) {
 	query := `
-		SELECT id, booking_id, service_id, stylist_id, start_time, end_time, price, created_at, updated_at
+		SELECT id, booking_id, service_id, stylist_id, start_time, end_time, price, created_at, updated_at,
+		       beneficiary_name, beneficiary_user_id
 		FROM booking_services
 		WHERE booking_id = $1
 		ORDER BY start_time
@@ -247,6 +981,7 @@ func (r *bookingRepository) GetBookingServices(ctx context.Context, bookingID uu
 			&service.ID, &service.BookingID, &service.ServiceID, &service.StylistID,
 			&service.StartTime, &service.EndTime, &service.Price,
 			&service.CreatedAt, &service.UpdatedAt,
+			&service.BeneficiaryName, &service.BeneficiaryUserID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan booking service: %w", err)
@@ -257,17 +992,73 @@ func (r *bookingRepository) GetBookingServices(ctx context.Context, bookingID uu
 	return services, rows.Err()
 }
 
+// loadBookingServices sets the services of every booking with a single query
+// keyed by the booking IDs, instead of one query per booking
+func (r *bookingRepository) loadBookingServices(ctx context.Context, bookings []*model.Booking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(bookings))
+	byID := make(map[uuid.UUID]*model.Booking, len(bookings))
+	for i, booking := range bookings {
+		ids[i] = booking.ID
+		byID[booking.ID] = booking
+		booking.Services = []model.BookingService{}
+	}
+
+	query := `
+		SELECT id, booking_id, service_id, stylist_id, start_time, end_time, price, created_at, updated_at,
+		       beneficiary_name, beneficiary_user_id
+		FROM booking_services
+		WHERE booking_id = ANY($1)
+		ORDER BY booking_id, start_time
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load booking services: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var service model.BookingService
+		err := rows.Scan(
+			&service.ID, &service.BookingID, &service.ServiceID, &service.StylistID,
+			&service.StartTime, &service.EndTime, &service.Price,
+			&service.CreatedAt, &service.UpdatedAt,
+			&service.BeneficiaryName, &service.BeneficiaryUserID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan booking service: %w", err)
+		}
+		if booking, ok := byID[service.BookingID]; ok {
+			// Service times are returned in the booking's wall-clock time
+			loc := booking.Location()
+			service.StartTime = service.StartTime.In(loc)
+			service.EndTime = service.EndTime.In(loc)
+			booking.Services = append(booking.Services, service)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load booking services: %w", err)
+	}
+	return nil
+}
+
 // UpdateBookingService updates a booking service
 func (r *bookingRepository) UpdateBookingService(ctx context.Context, service *model.BookingService) error {
 	query := `
 		UPDATE booking_services
-		SET service_id = $2, stylist_id = $3, start_time = $4, end_time = $5, price = $6, updated_at = NOW()
+		SET service_id = $2, stylist_id = $3, start_time = $4, end_time = $5, price = $6,
+		    beneficiary_name = $7, beneficiary_user_id = $8, updated_at = NOW()
 		WHERE id = $1
 	`
 	
 	result, err := r.db.Exec(ctx, query,
 		service.ID, service.ServiceID, service.StylistID,
 		service.StartTime, service.EndTime, service.Price,
+		service.BeneficiaryName, service.BeneficiaryUserID,
 	)
 	
 	if err != nil {
@@ -302,7 +1093,7 @@ func (r *bookingRepository) GetStylistBookings(ctx context.Context, stylistID uu
 		WHERE bs.stylist_id = $1
 		  AND bs.start_time < $3
 		  AND bs.end_time > $2
-		  AND b.status IN ('confirmed', 'rescheduled')
+		  AND b.status IN ('initiated', 'confirmed', 'rescheduled')
 		ORDER BY bs.start_time
 	`
 	
@@ -329,20 +1120,51 @@ func (r *bookingRepository) GetStylistBookings(ctx context.Context, stylistID uu
 	return bookings, rows.Err()
 }
 
-// CheckStylistAvailability checks if a stylist is available for a time slot
-func (r *bookingRepository) CheckStylistAvailability(ctx context.Context, stylistID uuid.UUID, startTime, endTime time.Time) (bool, error) {
+// GetStylistsBookings retrieves bookings for several stylists in a time range
+// with a single query, grouped by stylist
+func (r *bookingRepository) GetStylistsBookings(ctx context.Context, stylistIDs []uuid.UUID, startTime, endTime time.Time) (map[uuid.UUID][]*model.BookingService, error) {
+	bookings := make(map[uuid.UUID][]*model.BookingService, len(stylistIDs))
+	if len(stylistIDs) == 0 {
+		return bookings, nil
+	}
+
 	query := `
-		SELECT COUNT(*)
+		SELECT bs.id, bs.booking_id, bs.service_id, bs.stylist_id, bs.start_time, bs.end_time, bs.price, bs.created_at, bs.updated_at
 		FROM booking_services bs
 		JOIN bookings b ON bs.booking_id = b.id
-		WHERE bs.stylist_id = $1
+		WHERE bs.stylist_id = ANY($1)
 		  AND bs.start_time < $3
 		  AND bs.end_time > $2
-		  AND b.status IN ('confirmed', 'rescheduled')
+		  AND b.status IN ('initiated', 'confirmed', 'rescheduled')
+		ORDER BY bs.stylist_id, bs.start_time
 	`
 	
+	rows, err := r.db.Query(ctx, query, stylistIDs, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stylists bookings: %w", err)
+	}
+	defer rows.Close()
+	
+	for rows.Next() {
+		booking := &model.BookingService{}
+		err := rows.Scan(
+			&booking.ID, &booking.BookingID, &booking.ServiceID, &booking.StylistID,
+			&booking.StartTime, &booking.EndTime, &booking.Price,
+			&booking.CreatedAt, &booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stylist booking: %w", err)
+		}
+		bookings[booking.StylistID] = append(bookings[booking.StylistID], booking)
+	}
+	
+	return bookings, rows.Err()
+}
+
+// CheckStylistAvailability checks if a stylist is available for a time slot
+func (r *bookingRepository) CheckStylistAvailability(ctx context.Context, stylistID uuid.UUID, startTime, endTime time.Time) (bool, error) {
 	var count int
-	err := r.db.QueryRow(ctx, query, stylistID, startTime, endTime).Scan(&count)
+	err := r.db.QueryRow(ctx, stylistOverlapCountQuery, stylistID, startTime, endTime).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check stylist availability: %w", err)
 	}
@@ -350,6 +1172,18 @@ func (r *bookingRepository) CheckStylistAvailability(ctx context.Context, stylis
 	return count == 0, nil
 }
 
+// stylistOverlapCountQuery counts a stylist's active bookings overlapping
+// [$2, $3). Migration 015 documents the plan it is expected to use.
+const stylistOverlapCountQuery = `
+		SELECT COUNT(*)
+		FROM booking_services bs
+		JOIN bookings b ON bs.booking_id = b.id
+		WHERE bs.stylist_id = $1
+		  AND bs.start_time < $3
+		  AND bs.end_time > $2
+		  AND b.status IN ('initiated', 'confirmed', 'rescheduled')
+	`
+
 // CreateHistory creates a new booking history entry
 func (r *bookingRepository) CreateHistory(ctx context.Context, history *model.BookingHistory) error {
 	query := `
@@ -401,11 +1235,223 @@ func (r *bookingRepository) GetBookingHistory(ctx context.Context, bookingID uui
 	return history, rows.Err()
 }
 
+// loadBookingHistory sets the history of every booking, newest first, with a
+// single query keyed by the booking IDs
+func (r *bookingRepository) loadBookingHistory(ctx context.Context, bookings []*model.Booking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(bookings))
+	byID := make(map[uuid.UUID]*model.Booking, len(bookings))
+	for i, booking := range bookings {
+		ids[i] = booking.ID
+		byID[booking.ID] = booking
+		booking.History = []model.BookingHistory{}
+	}
+
+	query := `
+		SELECT id, booking_id, action, old_values, new_values, user_id, reason, timestamp
+		FROM booking_history
+		WHERE booking_id = ANY($1)
+		ORDER BY booking_id, timestamp DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load booking history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h model.BookingHistory
+		err := rows.Scan(
+			&h.ID, &h.BookingID, &h.Action,
+			&h.OldValues, &h.NewValues, &h.UserID, &h.Reason, &h.Timestamp,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan booking history: %w", err)
+		}
+		if booking, ok := byID[h.BookingID]; ok {
+			booking.History = append(booking.History, h)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load booking history: %w", err)
+	}
+	return nil
+}
+
+// GetIdempotencyRecord retrieves an unexpired idempotency record for a user's key
+func (r *bookingRepository) GetIdempotencyRecord(ctx context.Context, userID uuid.UUID, key string) (*model.IdempotencyRecord, error) {
+	query := `
+		SELECT id, user_id, idempotency_key, request_hash, booking_id, expires_at, created_at
+		FROM booking_idempotency_records
+		WHERE user_id = $1 AND idempotency_key = $2 AND expires_at > NOW()
+	`
+	
+	record := &model.IdempotencyRecord{}
+	err := r.db.QueryRow(ctx, query, userID, key).Scan(
+		&record.ID, &record.UserID, &record.IdempotencyKey, &record.RequestHash, &record.BookingID,
+		&record.ExpiresAt, &record.CreatedAt,
+	)
+	
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil // Not found, but not an error
+		}
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+	
+	return record, nil
+}
+
+// GetGuestAccessTokenHash returns the access token hash of a guest checkout
+// booking, or nil when the booking was not made as a guest
+func (r *bookingRepository) GetGuestAccessTokenHash(ctx context.Context, bookingID uuid.UUID) (*string, error) {
+	var hash *string
+	err := r.db.QueryRow(ctx, `SELECT guest_access_token_hash FROM bookings WHERE id = $1`, bookingID).Scan(&hash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to get guest access token: %w", err)
+	}
+	return hash, nil
+}
+
+// GetDueReminders retrieves active bookings that have reached a reminder threshold not yet sent.
+// Branches without configuration use the given default lead times.
+func (r *bookingRepository) GetDueReminders(ctx context.Context, defaultLeadTimes []int, limit int) ([]*model.DueReminder, error) {
+	query := `
+		SELECT b.id, t.threshold_minutes, s.first_start_time
+		FROM bookings b
+		JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		LEFT JOIN branch_configurations bc ON bc.branch_id = b.branch_id
+		CROSS JOIN LATERAL unnest(COALESCE(bc.reminder_lead_times_minutes, $1::int[])) AS t(threshold_minutes)
+		WHERE b.status IN ('confirmed', 'rescheduled')
+		  AND s.first_start_time > NOW()
+		  AND s.first_start_time <= NOW() + make_interval(mins => t.threshold_minutes)
+		  AND NOT EXISTS (
+			SELECT 1 FROM booking_reminders br
+			WHERE br.booking_id = b.id AND br.threshold_minutes = t.threshold_minutes
+		  )
+		ORDER BY s.first_start_time, t.threshold_minutes
+		LIMIT $2
+	`
+	
+	rows, err := r.db.Query(ctx, query, defaultLeadTimes, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due reminders: %w", err)
+	}
+	defer rows.Close()
+	
+	var reminders []*model.DueReminder
+	for rows.Next() {
+		reminder := &model.DueReminder{}
+		if err := rows.Scan(&reminder.BookingID, &reminder.ThresholdMinutes, &reminder.FirstStartTime); err != nil {
+			return nil, fmt.Errorf("failed to scan due reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+	
+	return reminders, rows.Err()
+}
+
+// MarkReminderSent records a reminder threshold for a booking. It returns false when
+// the reminder was already recorded, so only one worker sends each reminder.
+func (r *bookingRepository) MarkReminderSent(ctx context.Context, bookingID uuid.UUID, thresholdMinutes int) (bool, error) {
+	query := `
+		INSERT INTO booking_reminders (booking_id, threshold_minutes)
+		VALUES ($1, $2)
+		ON CONFLICT (booking_id, threshold_minutes) DO NOTHING
+	`
+	
+	result, err := r.db.Exec(ctx, query, bookingID, thresholdMinutes)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reminder sent: %w", err)
+	}
+	
+	return result.RowsAffected() == 1, nil
+}
+
+// GetPromoCode retrieves a promo code, matching the code case-insensitively
+func (r *bookingRepository) GetPromoCode(ctx context.Context, code string) (*model.PromoCode, error) {
+	query := `
+		SELECT id, code, salon_id, discount_type, discount_value, max_discount_amount, min_spend,
+		       starts_at, expires_at, usage_limit, per_user_limit, usage_count, is_active,
+		       created_at, updated_at
+		FROM promo_codes
+		WHERE UPPER(code) = UPPER($1)
+	`
+	
+	promo := &model.PromoCode{}
+	err := r.db.QueryRow(ctx, query, code).Scan(
+		&promo.ID, &promo.Code, &promo.SalonID, &promo.DiscountType, &promo.DiscountValue,
+		&promo.MaxDiscountAmount, &promo.MinSpend, &promo.StartsAt, &promo.ExpiresAt,
+		&promo.UsageLimit, &promo.PerUserLimit, &promo.UsageCount, &promo.IsActive,
+		&promo.CreatedAt, &promo.UpdatedAt,
+	)
+	
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPromoCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+	
+	return promo, nil
+}
+
+// redeemPromoCode counts one use of a promo code within the booking transaction.
+// The usage update locks the promo row, so concurrent redemptions cannot exceed
+// the overall or per-user limits.
+func redeemPromoCode(ctx context.Context, tx pgx.Tx, code string, userID uuid.UUID) error {
+	var perUserLimit *int
+	err := tx.QueryRow(ctx, `
+		UPDATE promo_codes
+		SET usage_count = usage_count + 1
+		WHERE UPPER(code) = UPPER($1) AND (usage_limit IS NULL OR usage_count < usage_limit)
+		RETURNING per_user_limit
+	`, code).Scan(&perUserLimit)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrPromoCodeExhausted
+		}
+		return fmt.Errorf("failed to redeem promo code: %w", err)
+	}
+
+	if perUserLimit == nil {
+		return nil
+	}
+
+	var used int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM bookings
+		WHERE user_id = $1 AND UPPER(promo_code) = UPPER($2) AND status NOT IN ('canceled', 'expired')
+	`, userID, code).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to count promo code uses: %w", err)
+	}
+	if used >= *perUserLimit {
+		return ErrPromoCodeUserLimit
+	}
+
+	return nil
+}
+
 // GetBranchConfiguration retrieves configuration for a branch
 func (r *bookingRepository) GetBranchConfiguration(ctx context.Context, branchID uuid.UUID) (*model.BranchConfiguration, error) {
 	query := `
 		SELECT branch_id, buffer_time_minutes, cancellation_cutoff_hours, reschedule_window_hours,
-		       max_advance_booking_days, booking_fee_amount, gst_percentage, created_at, updated_at
+		       max_advance_booking_days, slot_interval_minutes, reminder_lead_times_minutes,
+		       cancellation_fee_percentage, cancellation_fee_window_hours,
+		       booking_fee_amount, gst_percentage, tax_components, version, created_at, updated_at
 		FROM branch_configurations
 		WHERE branch_id = $1
 	`
@@ -413,9 +1459,9 @@ func (r *bookingRepository) GetBranchConfiguration(ctx context.Context, branchID
 	config := &model.BranchConfiguration{}
 	err := r.db.QueryRow(ctx, query, branchID).Scan(
 		&config.BranchID, &config.BufferTimeMinutes, &config.CancellationCutoffHours,
-		&config.RescheduleWindowHours, &config.MaxAdvanceBookingDays,
-		&config.BookingFeeAmount, &config.GSTPercentage,
-		&config.CreatedAt, &config.UpdatedAt,
+		&config.RescheduleWindowHours, &config.MaxAdvanceBookingDays, &config.SlotIntervalMinutes,
+		&config.ReminderLeadTimes, &config.CancellationFeePercentage, &config.CancellationFeeWindowHours,
+		&config.BookingFeeAmount, &config.GSTPercentage, &config.TaxComponents, &config.Version, &config.CreatedAt, &config.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -433,16 +1479,19 @@ func (r *bookingRepository) CreateBranchConfiguration(ctx context.Context, confi
 	query := `
 		INSERT INTO branch_configurations (branch_id, buffer_time_minutes, cancellation_cutoff_hours,
 		                                 reschedule_window_hours, max_advance_booking_days,
-		                                 booking_fee_amount, gst_percentage)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING created_at, updated_at
+		                                 slot_interval_minutes, reminder_lead_times_minutes,
+		                                 cancellation_fee_percentage, cancellation_fee_window_hours,
+		                                 booking_fee_amount, gst_percentage, tax_components)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING version, created_at, updated_at
 	`
 	
 	err := r.db.QueryRow(ctx, query,
 		config.BranchID, config.BufferTimeMinutes, config.CancellationCutoffHours,
-		config.RescheduleWindowHours, config.MaxAdvanceBookingDays,
-		config.BookingFeeAmount, config.GSTPercentage,
-	).Scan(&config.CreatedAt, &config.UpdatedAt)
+		config.RescheduleWindowHours, config.MaxAdvanceBookingDays, config.SlotIntervalMinutes,
+		config.ReminderLeadTimes, config.CancellationFeePercentage, config.CancellationFeeWindowHours,
+		config.BookingFeeAmount, config.GSTPercentage, config.TaxComponents,
+	).Scan(&config.Version, &config.CreatedAt, &config.UpdatedAt)
 	
 	if err != nil {
 		return fmt.Errorf("failed to create branch configuration: %w", err)
@@ -451,28 +1500,42 @@ func (r *bookingRepository) CreateBranchConfiguration(ctx context.Context, confi
 	return nil
 }
 
-// UpdateBranchConfiguration updates a branch configuration
+// UpdateBranchConfiguration updates a branch configuration if it is still at
+// config.Version, then sets config.Version to the new version. It returns
+// ErrBranchConfigVersionConflict when another update got there first.
 func (r *bookingRepository) UpdateBranchConfiguration(ctx context.Context, config *model.BranchConfiguration) error {
 	query := `
 		UPDATE branch_configurations
 		SET buffer_time_minutes = $2, cancellation_cutoff_hours = $3, reschedule_window_hours = $4,
-		    max_advance_booking_days = $5, booking_fee_amount = $6, gst_percentage = $7, updated_at = NOW()
-		WHERE branch_id = $1
+		    max_advance_booking_days = $5, slot_interval_minutes = $6, reminder_lead_times_minutes = $7,
+		    cancellation_fee_percentage = $8, cancellation_fee_window_hours = $9,
+		    booking_fee_amount = $10, gst_percentage = $11, tax_components = $12,
+		    version = version + 1, updated_at = NOW()
+		WHERE branch_id = $1 AND version = $13
+		RETURNING version, updated_at
 	`
 	
-	result, err := r.db.Exec(ctx, query,
+	err := r.db.QueryRow(ctx, query,
 		config.BranchID, config.BufferTimeMinutes, config.CancellationCutoffHours,
-		config.RescheduleWindowHours, config.MaxAdvanceBookingDays,
-		config.BookingFeeAmount, config.GSTPercentage,
-	)
+		config.RescheduleWindowHours, config.MaxAdvanceBookingDays, config.SlotIntervalMinutes,
+		config.ReminderLeadTimes, config.CancellationFeePercentage, config.CancellationFeeWindowHours,
+		config.BookingFeeAmount, config.GSTPercentage, config.TaxComponents, config.Version,
+	).Scan(&config.Version, &config.UpdatedAt)
 	
+	if err == pgx.ErrNoRows {
+		var exists bool
+		existsQuery := `SELECT EXISTS (SELECT 1 FROM branch_configurations WHERE branch_id = $1)`
+		if err := r.db.QueryRow(ctx, existsQuery, config.BranchID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to update branch configuration: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("branch configuration not found")
+		}
+		return ErrBranchConfigVersionConflict
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update branch configuration: %w", err)
 	}
 	
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("branch configuration not found")
-	}
-	
 	return nil
 }
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TestStylistOverlapQueryPlan checks that the stylist overlap check can be
+// served by a stylist index rather than a sequential scan. It needs a migrated
+// database named by BOOKING_TEST_DATABASE_URL and is skipped otherwise.
+//
+// Without realistic statistics the planner may pick either stylist index, so
+// the test accepts both; migration 015 documents the plan expected on a
+// populated, analyzed table.
+func TestStylistOverlapQueryPlan(t *testing.T) {
+	databaseURL := os.Getenv("BOOKING_TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("BOOKING_TEST_DATABASE_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A test database holds too few rows for the planner to prefer an index,
+	// so rule out sequential scans and check an index can serve the query
+	if _, err := tx.Exec(ctx, "SET LOCAL enable_seqscan = off"); err != nil {
+		t.Fatalf("disable sequential scans: %v", err)
+	}
+
+	start := time.Now().Add(24 * time.Hour)
+	rows, err := tx.Query(ctx, "EXPLAIN "+stylistOverlapCountQuery, uuid.New(), start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan = append(plan, line)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read plan: %v", err)
+	}
+	text := strings.Join(plan, "\n")
+
+	if strings.Contains(text, "Seq Scan on booking_services") || strings.Contains(text, "Seq Scan on bookings") {
+		t.Errorf("overlap check scans a table sequentially:\n%s", text)
+	}
+	if !strings.Contains(text, "idx_booking_services_stylist_end_start") && !strings.Contains(text, "idx_booking_services_stylist_time") {
+		t.Errorf("overlap check does not use a stylist time index:\n%s", text)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultBuffer is the branch default buffer between bookings
+const defaultBuffer = 15 * time.Minute
+
+func TestCheckOwnServicesOverlap(t *testing.T) {
+	stylist, other := uuid.New(), uuid.New()
+	at := func(hour, minute int) time.Time {
+		return time.Date(2030, 3, 15, hour, minute, 0, 0, time.UTC)
+	}
+	service := func(stylistID uuid.UUID, start, end time.Time) model.BookingService {
+		return model.BookingService{ID: uuid.New(), StylistID: stylistID, StartTime: start, EndTime: end}
+	}
+
+	tests := []struct {
+		name     string
+		services []model.BookingService
+		wantErr  bool
+	}{
+		{
+			name:     "back to back with the same stylist",
+			services: []model.BookingService{service(stylist, at(10, 0), at(10, 30)), service(stylist, at(10, 30), at(11, 0))},
+		},
+		{
+			name:     "overlapping with different stylists",
+			services: []model.BookingService{service(stylist, at(10, 0), at(11, 0)), service(other, at(10, 0), at(11, 0))},
+		},
+		{
+			name:     "overlapping with the same stylist",
+			services: []model.BookingService{service(stylist, at(10, 0), at(10, 45)), service(stylist, at(10, 30), at(11, 0))},
+			wantErr:  true,
+		},
+		{
+			name:     "one service inside another",
+			services: []model.BookingService{service(stylist, at(10, 0), at(12, 0)), service(other, at(10, 0), at(10, 30)), service(stylist, at(11, 0), at(11, 30))},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOwnServicesOverlap(tt.services)
+			if tt.wantErr && !errors.Is(err, ErrSlotUnavailable) {
+				t.Errorf("err = %v, want ErrSlotUnavailable", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+// TestCreateWithBackToBackServices books two back-to-back services with one
+// stylist under the default buffer, which must not conflict with each other,
+// then checks another booking inside the buffer is still refused. It needs a
+// migrated database named by BOOKING_TEST_DATABASE_URL and is skipped otherwise.
+func TestCreateWithBackToBackServices(t *testing.T) {
+	databaseURL := os.Getenv("BOOKING_TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("BOOKING_TEST_DATABASE_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer pool.Close()
+	repo := NewBookingRepository(pool)
+
+	stylistID := uuid.New()
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	newBooking := func() *model.Booking {
+		booking := &model.Booking{
+			ID:            uuid.New(),
+			UserID:        uuid.New(),
+			SalonID:       uuid.New(),
+			BranchID:      uuid.New(),
+			Status:        model.BookingStatusInitiated,
+			PaymentStatus: model.PaymentStatusPending,
+			Timezone:      "UTC",
+		}
+		t.Cleanup(func() {
+			pool.Exec(context.Background(), `DELETE FROM bookings WHERE id = $1`, booking.ID)
+		})
+		return booking
+	}
+	service := func(from, to time.Duration) model.BookingService {
+		return model.BookingService{
+			ID:        uuid.New(),
+			ServiceID: uuid.New(),
+			StylistID: stylistID,
+			StartTime: start.Add(from),
+			EndTime:   start.Add(to),
+			Price:     500,
+		}
+	}
+
+	services := []model.BookingService{service(0, 30*time.Minute), service(30*time.Minute, time.Hour)}
+	if err := repo.CreateWithServices(ctx, newBooking(), services, defaultBuffer, nil); err != nil {
+		t.Fatalf("back-to-back services: %v", err)
+	}
+
+	// 11:10 is within the 15 minute buffer after the first booking ends at 11:00
+	clash := []model.BookingService{service(time.Hour+10*time.Minute, 2*time.Hour)}
+	if err := repo.CreateWithServices(ctx, newBooking(), clash, defaultBuffer, nil); !errors.Is(err, ErrSlotUnavailable) {
+		t.Errorf("booking inside the buffer: err = %v, want ErrSlotUnavailable", err)
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// countingDB answers the booking list queries from memory and counts the
+// queries made. Methods a test does not need are left to the embedded nil
+// interface and panic if called.
+type countingDB struct {
+	database
+
+	bookings []uuid.UUID
+	// servicesPerBooking services are returned for every booking
+	servicesPerBooking int
+	queries            int
+}
+
+func (d *countingDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	d.queries++
+
+	switch {
+	case strings.Contains(sql, "FROM booking_services") && strings.Contains(sql, "booking_id = ANY"):
+		ids, ok := args[0].([]uuid.UUID)
+		if !ok {
+			return nil, fmt.Errorf("booking IDs passed as %T", args[0])
+		}
+		var rows [][]any
+		start := time.Date(2030, 3, 15, 10, 0, 0, 0, time.UTC)
+		for _, bookingID := range ids {
+			for i := 0; i < d.servicesPerBooking; i++ {
+				row := make([]any, 11)
+				row[0], row[1] = uuid.New(), bookingID
+				row[4], row[5] = start.Add(time.Duration(i)*time.Hour), start.Add(time.Duration(i+1)*time.Hour)
+				rows = append(rows, row)
+			}
+		}
+		return &fakeRows{rows: rows}, nil
+	case strings.Contains(sql, "FROM bookings b"):
+		rows := make([][]any, len(d.bookings))
+		for i, id := range d.bookings {
+			rows[i] = make([]any, 20)
+			rows[i][0], rows[i][15] = id, "UTC"
+		}
+		return &fakeRows{rows: rows}, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", sql)
+}
+
+// fakeRows scans each row's non-nil values into the matching destinations
+type fakeRows struct {
+	pgx.Rows
+
+	rows    [][]any
+	current int
+}
+
+func (r *fakeRows) Next() bool {
+	r.current++
+	return r.current <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.rows[r.current-1]
+	if len(dest) != len(row) {
+		return fmt.Errorf("scanning %d columns into %d destinations", len(row), len(dest))
+	}
+	for i, value := range row {
+		if value != nil {
+			reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+		}
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
+func TestBookingListsUseFixedQueryCount(t *testing.T) {
+	lists := []struct {
+		name string
+		list func(repo *bookingRepository, limit int) ([]*model.Booking, error)
+	}{
+		{name: "GetByUserID", list: func(repo *bookingRepository, limit int) ([]*model.Booking, error) {
+			return repo.GetByUserID(context.Background(), model.UserBookingFilter{UserID: uuid.New(), Limit: limit})
+		}},
+		{name: "List", list: func(repo *bookingRepository, limit int) ([]*model.Booking, error) {
+			return repo.List(context.Background(), model.BookingFilter{BranchID: uuid.New(), Limit: limit})
+		}},
+	}
+
+	for _, tt := range lists {
+		for _, count := range []int{1, 10, 100} {
+			t.Run(fmt.Sprintf("%s with %d bookings", tt.name, count), func(t *testing.T) {
+				db := &countingDB{servicesPerBooking: 2}
+				for i := 0; i < count; i++ {
+					db.bookings = append(db.bookings, uuid.New())
+				}
+				repo := &bookingRepository{db: db}
+
+				bookings, err := tt.list(repo, count)
+				if err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+
+				// One query for the page of bookings and one for all their services
+				if db.queries != 2 {
+					t.Errorf("made %d queries, want 2", db.queries)
+				}
+				if len(bookings) != count {
+					t.Fatalf("got %d bookings, want %d", len(bookings), count)
+				}
+				for _, booking := range bookings {
+					if len(booking.Services) != db.servicesPerBooking {
+						t.Errorf("booking %s has %d services, want %d", booking.ID, len(booking.Services), db.servicesPerBooking)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkGetByUserID reports the queries made to load a page of 50
+// bookings with their services
+func BenchmarkGetByUserID(b *testing.B) {
+	db := &countingDB{servicesPerBooking: 2}
+	for i := 0; i < 50; i++ {
+		db.bookings = append(db.bookings, uuid.New())
+	}
+	repo := &bookingRepository{db: db}
+	filter := model.UserBookingFilter{UserID: uuid.New(), Limit: 50}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.GetByUserID(context.Background(), filter); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(db.queries)/float64(b.N), "queries/op")
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrRecurringBookingNotFound is returned when the user has no recurring booking with the given ID
+	ErrRecurringBookingNotFound = errors.New("recurring booking not found")
+	// ErrRecurringBookingStatus is returned when a recurring booking's status does not allow the change
+	ErrRecurringBookingStatus = errors.New("recurring booking status does not allow this change")
+)
+
+const recurringBookingColumns = `id, user_id, salon_id, branch_id, stylist_id, service_ids, weekday, start_time,
+	interval_weeks, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), timezone, notes, status,
+	to_char(generated_through, 'YYYY-MM-DD'), created_at, updated_at`
+
+// CreateRecurringBooking stores a new recurring booking
+func (r *bookingRepository) CreateRecurringBooking(ctx context.Context, recurring *model.RecurringBooking) error {
+	query := `
+		INSERT INTO recurring_bookings (id, user_id, salon_id, branch_id, stylist_id, service_ids, weekday, start_time,
+		                                interval_weeks, start_date, end_date, timezone, notes, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::text::date, $11::text::date, $12, $13, $14)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		recurring.ID, recurring.UserID, recurring.SalonID, recurring.BranchID, recurring.StylistID,
+		recurring.ServiceIDs, recurring.Weekday, recurring.StartTime, recurring.IntervalWeeks,
+		recurring.StartDate, recurring.EndDate, recurring.Timezone, recurring.Notes, recurring.Status,
+	).Scan(&recurring.CreatedAt, &recurring.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create recurring booking: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecurringBookingsByUserID lists a user's recurring bookings, newest first
+func (r *bookingRepository) GetRecurringBookingsByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.RecurringBooking, error) {
+	query := `SELECT ` + recurringBookingColumns + `
+		FROM recurring_bookings
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring bookings: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRecurringBookings(rows)
+}
+
+// CountRecurringBookingsByUserID counts a user's recurring bookings
+func (r *bookingRepository) CountRecurringBookingsByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM recurring_bookings WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count recurring bookings: %w", err)
+	}
+	return total, nil
+}
+
+// SetRecurringBookingStatus moves one of the user's recurring bookings to
+// status if it is currently in one of from. It returns
+// ErrRecurringBookingNotFound when the user has no such recurring booking and
+// ErrRecurringBookingStatus when its status is not one of from.
+func (r *bookingRepository) SetRecurringBookingStatus(ctx context.Context, id, userID uuid.UUID, status model.RecurringBookingStatus, from ...model.RecurringBookingStatus) (*model.RecurringBooking, error) {
+	query := `
+		UPDATE recurring_bookings
+		SET status = $3
+		WHERE id = $1 AND user_id = $2 AND status = ANY($4)
+		RETURNING ` + recurringBookingColumns
+
+	allowed := make([]string, len(from))
+	for i, s := range from {
+		allowed[i] = string(s)
+	}
+
+	recurring, err := scanRecurringBooking(r.db.QueryRow(ctx, query, id, userID, status, allowed))
+	if err == pgx.ErrNoRows {
+		var exists bool
+		existsQuery := `SELECT EXISTS (SELECT 1 FROM recurring_bookings WHERE id = $1 AND user_id = $2)`
+		if err := r.db.QueryRow(ctx, existsQuery, id, userID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to update recurring booking: %w", err)
+		}
+		if !exists {
+			return nil, ErrRecurringBookingNotFound
+		}
+		return nil, ErrRecurringBookingStatus
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update recurring booking: %w", err)
+	}
+
+	return recurring, nil
+}
+
+// GetDueRecurringBookings returns active recurring bookings that still have
+// dates to generate and were not generated since notGeneratedSince, least
+// recently generated first
+func (r *bookingRepository) GetDueRecurringBookings(ctx context.Context, notGeneratedSince time.Time, limit int) ([]*model.RecurringBooking, error) {
+	query := `SELECT ` + recurringBookingColumns + `
+		FROM recurring_bookings
+		WHERE status = 'active'
+		  AND (end_date IS NULL OR generated_through IS NULL OR generated_through < end_date)
+		  AND (last_generated_at IS NULL OR last_generated_at < $1)
+		ORDER BY last_generated_at NULLS FIRST, id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, notGeneratedSince, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due recurring bookings: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRecurringBookings(rows)
+}
+
+// MarkRecurringBookingGenerated records a generator run over a recurring
+// booking, advancing generated_through to through when it is given
+func (r *bookingRepository) MarkRecurringBookingGenerated(ctx context.Context, id uuid.UUID, through *string) error {
+	query := `
+		UPDATE recurring_bookings
+		SET generated_through = GREATEST(generated_through, $2::text::date), last_generated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, through); err != nil {
+		return fmt.Errorf("failed to mark recurring booking generated: %w", err)
+	}
+	return nil
+}
+
+// RecordRecurringBookingSkip records that an occurrence date could not be
+// booked. It reports false when the date was already recorded.
+func (r *bookingRepository) RecordRecurringBookingSkip(ctx context.Context, id uuid.UUID, occurrenceDate, reason string) (bool, error) {
+	query := `
+		INSERT INTO recurring_booking_skips (recurring_booking_id, occurrence_date, reason)
+		VALUES ($1, $2::text::date, $3)
+		ON CONFLICT (recurring_booking_id, occurrence_date) DO NOTHING
+	`
+
+	result, err := r.db.Exec(ctx, query, id, occurrenceDate, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to record recurring booking skip: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// GetUpcomingRecurringOccurrences returns the IDs of a recurring booking's
+// occurrences that are still to take place after the given time. Completed,
+// cancelled and expired occurrences are not included.
+func (r *bookingRepository) GetUpcomingRecurringOccurrences(ctx context.Context, id uuid.UUID, after time.Time) ([]uuid.UUID, error) {
+	query := `
+		SELECT b.id
+		FROM bookings b
+		JOIN (
+			SELECT booking_id, MIN(start_time) AS first_start_time
+			FROM booking_services
+			GROUP BY booking_id
+		) s ON s.booking_id = b.id
+		WHERE b.recurring_booking_id = $1
+		  AND b.status IN ('initiated', 'confirmed', 'rescheduled')
+		  AND s.first_start_time > $2
+		ORDER BY s.first_start_time
+	`
+
+	rows, err := r.db.Query(ctx, query, id, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring booking occurrences: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var bookingID uuid.UUID
+		if err := rows.Scan(&bookingID); err != nil {
+			return nil, fmt.Errorf("failed to scan recurring booking occurrence: %w", err)
+		}
+		ids = append(ids, bookingID)
+	}
+
+	return ids, rows.Err()
+}
+
+func scanRecurringBookings(rows pgx.Rows) ([]*model.RecurringBooking, error) {
+	var recurrences []*model.RecurringBooking
+	for rows.Next() {
+		recurring, err := scanRecurringBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recurring booking: %w", err)
+		}
+		recurrences = append(recurrences, recurring)
+	}
+	return recurrences, rows.Err()
+}
+
+func scanRecurringBooking(row pgx.Row) (*model.RecurringBooking, error) {
+	recurring := &model.RecurringBooking{}
+	err := row.Scan(
+		&recurring.ID, &recurring.UserID, &recurring.SalonID, &recurring.BranchID, &recurring.StylistID,
+		&recurring.ServiceIDs, &recurring.Weekday, &recurring.StartTime, &recurring.IntervalWeeks,
+		&recurring.StartDate, &recurring.EndDate, &recurring.Timezone, &recurring.Notes, &recurring.Status,
+		&recurring.GeneratedThrough, &recurring.CreatedAt, &recurring.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type revocationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRevocationRepository reads the access token revocations written by user-service
+func NewRevocationRepository(db *pgxpool.Pool) auth.RevocationStore {
+	return &revocationRepository{db: db}
+}
+
+// TokensValidAfter returns the zero time when the subject has no revocation
+func (r *revocationRepository) TokensValidAfter(ctx context.Context, subjectID string) (time.Time, error) {
+	var validAfter time.Time
+	err := r.db.QueryRow(ctx, `SELECT tokens_valid_after FROM token_revocations WHERE subject_id = $1`, subjectID).Scan(&validAfter)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return validAfter, err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TenancyRepository checks which branches a salon staff member may manage.
+// It reads the staff and branches tables owned by salon-service.
+type TenancyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTenancyRepository creates a new tenancy repository
+func NewTenancyRepository(db *pgxpool.Pool) *TenancyRepository {
+	return &TenancyRepository{db: db}
+}
+
+// StaffHasAccessToBranch reports whether an active staff member belongs to
+// the branch's salon and, when assigned to a single branch, to that branch
+func (r *TenancyRepository) StaffHasAccessToBranch(ctx context.Context, staffID, branchID string) (bool, error) {
+	staffUUID, err := uuid.Parse(staffID)
+	if err != nil {
+		return false, nil
+	}
+	branchUUID, err := uuid.Parse(branchID)
+	if err != nil {
+		return false, nil
+	}
+
+	var exists bool
+	err = r.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM staff s
+			JOIN branches b ON b.salon_id = s.salon_id
+			WHERE s.id = $1 AND b.id = $2
+			  AND s.status = 'active' AND s.deleted_at IS NULL AND b.deleted_at IS NULL
+			  AND (s.branch_id IS NULL OR s.branch_id = b.id)
+		)
+	`, staffUUID, branchUUID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
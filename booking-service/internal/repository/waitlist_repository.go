@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrWaitlistEntryNotFound is returned when no active waitlist entry matches
+	ErrWaitlistEntryNotFound = errors.New("waitlist entry not found")
+	// ErrDuplicateWaitlistEntry is returned when the user already waits for the same stylist and window
+	ErrDuplicateWaitlistEntry = errors.New("already on the waitlist for this slot")
+)
+
+const waitlistColumns = `id, user_id, salon_id, branch_id, stylist_id, service_id, desired_start, desired_end,
+	status, notified_booking_id, notified_at, created_at, updated_at`
+
+// CreateWaitlistEntry adds a waiting entry
+func (r *bookingRepository) CreateWaitlistEntry(ctx context.Context, entry *model.WaitlistEntry) error {
+	query := `
+		INSERT INTO booking_waitlist (id, user_id, salon_id, branch_id, stylist_id, service_id, desired_start, desired_end, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		entry.ID, entry.UserID, entry.SalonID, entry.BranchID, entry.StylistID, entry.ServiceID,
+		entry.DesiredStart, entry.DesiredEnd, entry.Status,
+	).Scan(&entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateWaitlistEntry
+		}
+		return fmt.Errorf("failed to create waitlist entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetWaitlistByUserID lists a user's waitlist entries, newest first
+func (r *bookingRepository) GetWaitlistByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.WaitlistEntry, error) {
+	query := `SELECT ` + waitlistColumns + `
+		FROM booking_waitlist
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*model.WaitlistEntry
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// CountWaitlistByUserID counts a user's waitlist entries
+func (r *bookingRepository) CountWaitlistByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM booking_waitlist WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count waitlist entries: %w", err)
+	}
+	return total, nil
+}
+
+// CancelWaitlistEntry withdraws one of the user's waiting entries. It returns
+// ErrWaitlistEntryNotFound when the user has no such waiting entry.
+func (r *bookingRepository) CancelWaitlistEntry(ctx context.Context, id, userID uuid.UUID) error {
+	query := `
+		UPDATE booking_waitlist
+		SET status = 'cancelled'
+		WHERE id = $1 AND user_id = $2 AND status = 'waiting'
+	`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel waitlist entry: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWaitlistEntryNotFound
+	}
+
+	return nil
+}
+
+// ClaimWaitlistEntryForSlot marks the earliest waiting entry whose window
+// overlaps the freed slot as notified and returns it, or nil when nobody is
+// waiting. Concurrent claims skip locked rows, so each entry is notified once.
+func (r *bookingRepository) ClaimWaitlistEntryForSlot(ctx context.Context, slot model.FreedSlot) (*model.WaitlistEntry, error) {
+	query := `
+		UPDATE booking_waitlist
+		SET status = 'notified', notified_booking_id = $4, notified_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM booking_waitlist
+			WHERE stylist_id = $1 AND status = 'waiting'
+			  AND desired_start < $3 AND desired_end > $2
+			  AND desired_end > NOW()
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + waitlistColumns
+
+	entry, err := scanWaitlistEntry(r.db.QueryRow(ctx, query, slot.StylistID, slot.StartTime, slot.EndTime, slot.BookingID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim waitlist entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// ExpireWaitlistEntries marks waiting entries whose window ended before now as
+// expired and returns how many were expired
+func (r *bookingRepository) ExpireWaitlistEntries(ctx context.Context, now time.Time) (int, error) {
+	query := `
+		UPDATE booking_waitlist
+		SET status = 'expired'
+		WHERE status = 'waiting' AND desired_end <= $1
+	`
+
+	result, err := r.db.Exec(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire waitlist entries: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+func scanWaitlistEntry(row pgx.Row) (*model.WaitlistEntry, error) {
+	entry := &model.WaitlistEntry{}
+	err := row.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.SalonID,
+		&entry.BranchID,
+		&entry.StylistID,
+		&entry.ServiceID,
+		&entry.DesiredStart,
+		&entry.DesiredEnd,
+		&entry.Status,
+		&entry.NotifiedBookingID,
+		&entry.NotifiedAt,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+// beneficiary is who receives one service of a group booking
+type beneficiary struct {
+	name   *string
+	userID *uuid.UUID
+}
+
+// resolveBeneficiaries validates the beneficiaries named on each requested
+// service of a booking paid for by payerID. Services for the payer resolve
+// to an empty beneficiary so single-user bookings are stored as before.
+// Registered beneficiaries without a name are given their account name.
+func (s *bookingService) resolveBeneficiaries(ctx context.Context, payerID uuid.UUID, services []InitiateBookingServiceItem) ([]beneficiary, error) {
+	resolved := make([]beneficiary, len(services))
+	names := make(map[uuid.UUID]string)
+
+	for i, serviceItem := range services {
+		var name *string
+		if serviceItem.BeneficiaryName != nil {
+			if trimmed := strings.TrimSpace(*serviceItem.BeneficiaryName); trimmed != "" {
+				name = &trimmed
+			}
+		}
+
+		userID := serviceItem.BeneficiaryUserID
+		if userID != nil && (*userID == uuid.Nil || *userID == payerID) {
+			userID = nil
+		}
+		if userID != nil {
+			accountName, ok := names[*userID]
+			if !ok {
+				user, err := s.externalService.ValidateUser(ctx, *userID)
+				if err != nil {
+					var notFound *sharederrors.NotFoundError
+					if errors.As(err, &notFound) {
+						return nil, sharederrors.NewValidationError("services", fmt.Sprintf("beneficiary user %s does not exist for service %d", *userID, i))
+					}
+					return nil, fmt.Errorf("failed to validate beneficiary %s: %w", *userID, err)
+				}
+				accountName = user.Name
+				names[*userID] = accountName
+			}
+			if name == nil {
+				name = &accountName
+			}
+		}
+
+		resolved[i] = beneficiary{name: name, userID: userID}
+	}
+
+	return resolved, nil
+}
+
+// countBeneficiaries counts the distinct people other than the booking user
+// that a booking's services are for
+func countBeneficiaries(services []model.BookingService) int {
+	seen := make(map[string]struct{})
+	for _, service := range services {
+		switch {
+		case service.BeneficiaryUserID != nil:
+			seen["user:"+service.BeneficiaryUserID.String()] = struct{}{}
+		case service.BeneficiaryName != nil:
+			seen["name:"+strings.ToLower(*service.BeneficiaryName)] = struct{}{}
+		}
+	}
+	return len(seen)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxBookingNoteLength is the longest note, in characters, that can be added to a booking
+const MaxBookingNoteLength = 2000
+
+// AddBookingNoteRequest is a note to append to a booking
+type AddBookingNoteRequest struct {
+	Note       string                      `json:"note"`
+	Visibility model.BookingNoteVisibility `json:"visibility"`
+}
+
+// AddBookingNote appends a note by authorID to a booking. Notes cannot be
+// edited or removed once added.
+func (s *bookingService) AddBookingNote(ctx context.Context, bookingID, authorID uuid.UUID, request *AddBookingNoteRequest) (*model.BookingNote, error) {
+	text := strings.TrimSpace(request.Note)
+	if text == "" {
+		return nil, sharederrors.NewValidationError("note", "note is required")
+	}
+	if utf8.RuneCountInString(text) > MaxBookingNoteLength {
+		return nil, sharederrors.NewValidationError("note", fmt.Sprintf("note must be at most %d characters", MaxBookingNoteLength))
+	}
+	if !request.Visibility.IsValid() {
+		return nil, sharederrors.NewValidationError("visibility", "visibility must be customer or internal")
+	}
+
+	if _, err := s.repo.GetByID(ctx, bookingID); err != nil {
+		return nil, bookingLookupError(bookingID, err)
+	}
+
+	note := &model.BookingNote{
+		ID:         uuid.New(),
+		BookingID:  bookingID,
+		AuthorID:   authorID,
+		Visibility: request.Visibility,
+		Note:       text,
+	}
+	if err := s.repo.CreateBookingNote(ctx, note); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("booking_id", bookingID.String()).
+		Str("author_id", authorID.String()).
+		Str("visibility", string(note.Visibility)).
+		Msg("Booking note added")
+
+	return note, nil
+}
+
+// GetBookingNotes lists a booking's notes oldest first. Internal notes are
+// only included for staff.
+func (s *bookingService) GetBookingNotes(ctx context.Context, bookingID uuid.UUID, includeInternal bool) ([]*model.BookingNote, error) {
+	notes, err := s.repo.GetBookingNotes(ctx, bookingID, includeInternal)
+	if err != nil {
+		return nil, err
+	}
+
+	if notes == nil {
+		notes = []*model.BookingNote{}
+	}
+
+	return notes, nil
+}
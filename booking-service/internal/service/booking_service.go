@@ -2,14 +2,21 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"booking-service/internal/config"
 	"booking-service/internal/model"
 	"booking-service/internal/repository"
 
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/money"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
@@ -18,25 +25,66 @@ import (
 type BookingService interface {
 	// Booking lifecycle
 	InitiateBooking(ctx context.Context, request *InitiateBookingRequest) (*model.Booking, error)
+	StaffInitiateBooking(ctx context.Context, staffID uuid.UUID, request *StaffInitiateBookingRequest) (*model.Booking, error)
 	ConfirmBooking(ctx context.Context, bookingID uuid.UUID, paymentID string) (*model.Booking, error)
 	CancelBooking(ctx context.Context, bookingID uuid.UUID, userID uuid.UUID, reason string) error
 	RescheduleBooking(ctx context.Context, request *RescheduleBookingRequest) (*model.Booking, error)
+	ReleaseExpiredPaymentBooking(ctx context.Context, bookingID, paymentID uuid.UUID) error
+	AbandonBooking(ctx context.Context, bookingID, userID uuid.UUID) error
+	ExpireStaleBookings(ctx context.Context) (int, error)
+	
+	// Guest checkout
+	GuestInitiateBooking(ctx context.Context, request *GuestInitiateBookingRequest) (*GuestBookingResponse, error)
+	GetGuestBooking(ctx context.Context, bookingID uuid.UUID, accessToken string) (*model.Booking, error)
+	GuestCancelBooking(ctx context.Context, bookingID uuid.UUID, accessToken, reason string) error
+	GuestInitiatePayment(ctx context.Context, bookingID uuid.UUID, accessToken, gateway string) (*InitiatePaymentResponse, error)
 	
 	// Booking queries
 	GetBooking(ctx context.Context, bookingID uuid.UUID) (*model.Booking, error)
-	GetUserBookings(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Booking, error)
+	GetUserBookings(ctx context.Context, filter model.UserBookingFilter) ([]*model.Booking, int, error)
+	ListBookings(ctx context.Context, filter model.BookingFilter) ([]*model.Booking, int, error)
+	ExportBranchBookings(ctx context.Context, filter model.BookingFilter, fn func(*model.BookingExportRow) error) error
+	GetRevenueReport(ctx context.Context, filter model.RevenueReportFilter) (*model.RevenueReport, error)
+	GetBookingHistory(ctx context.Context, bookingID uuid.UUID) ([]*model.BookingHistory, error)
+	GetBookingReceipt(ctx context.Context, bookingID uuid.UUID) (*model.BookingReceipt, error)
+	
+	// Booking notes
+	AddBookingNote(ctx context.Context, bookingID, authorID uuid.UUID, note *AddBookingNoteRequest) (*model.BookingNote, error)
+	GetBookingNotes(ctx context.Context, bookingID uuid.UUID, includeInternal bool) ([]*model.BookingNote, error)
 	
 	// Payment integration
 	InitiatePaymentForBooking(ctx context.Context, bookingID uuid.UUID, gateway string) (*InitiatePaymentResponse, error)
 	ProcessPaymentCallback(ctx context.Context, bookingID uuid.UUID, paymentID uuid.UUID, gatewayPaymentID string) error
-	RefundBookingPayment(ctx context.Context, bookingID uuid.UUID, reason string, amount *float64) (*RefundPaymentResponse, error)
+	RefundBookingPayment(ctx context.Context, bookingID uuid.UUID, reason string, amount *float64) (*BookingRefund, error)
 	
 	// Availability and pricing
 	GetStylistAvailability(ctx context.Context, salonID, stylistID uuid.UUID, date time.Time) ([]*model.TimeSlot, error)
+	GetBranchAvailability(ctx context.Context, salonID, branchID uuid.UUID, date time.Time) ([]*model.StylistAvailability, error)
 	CalculateBookingSummary(ctx context.Context, request *BookingSummaryRequest) (*model.BookingSummary, error)
 	
+	// Waitlist
+	JoinWaitlist(ctx context.Context, request *JoinWaitlistRequest) (*model.WaitlistEntry, error)
+	LeaveWaitlist(ctx context.Context, entryID, userID uuid.UUID) error
+	GetUserWaitlist(ctx context.Context, userID uuid.UUID, page pagination.Params) ([]*model.WaitlistEntry, int, error)
+	ExpireWaitlistEntries(ctx context.Context) (int, error)
+	
+	// Recurring bookings
+	CreateRecurringBooking(ctx context.Context, request *CreateRecurringBookingRequest) (*model.RecurringBooking, error)
+	GetUserRecurringBookings(ctx context.Context, userID uuid.UUID, page pagination.Params) ([]*model.RecurringBooking, int, error)
+	PauseRecurringBooking(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error)
+	ResumeRecurringBooking(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error)
+	CancelRecurringBooking(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error)
+	GenerateRecurringBookings(ctx context.Context) (int, error)
+	
+	// Reminders
+	SendDueReminders(ctx context.Context) (int, error)
+	
 	// Configuration
 	GetBranchConfiguration(ctx context.Context, branchID uuid.UUID) (*model.BranchConfiguration, error)
+	UpdateBranchConfiguration(ctx context.Context, branchID uuid.UUID, request *UpdateBranchConfigurationRequest) (*model.BranchConfiguration, error)
+
+	// Lifecycle
+	Shutdown(ctx context.Context) error
 }
 
 type bookingService struct {
@@ -44,37 +92,93 @@ type bookingService struct {
 	externalService    ExternalService
 	paymentClient      *PaymentClient
 	notificationClient *NotificationClient
+	eventPublisher     EventPublisher
 	config             *config.Config
+	// background tracks notification goroutines so shutdown can drain them
+	background sync.WaitGroup
 }
 
-// NewBookingService creates a new booking service
-func NewBookingService(repo repository.BookingRepository, cfg *config.Config) BookingService {
-	externalService := NewExternalService(cfg.UserServiceURL, cfg.SalonServiceURL)
-	paymentClient := NewPaymentClient(cfg.PaymentServiceURL)
-	notificationClient := NewNotificationClient(cfg.NotificationServiceURL)
+// NewBookingService creates a new booking service. When eventPublisher is nil,
+// notifications are sent to the notification service over HTTP.
+func NewBookingService(repo repository.BookingRepository, cfg *config.Config, eventPublisher EventPublisher) BookingService {
+	policy := ResiliencePolicy{
+		Timeout:                 30 * time.Second,
+		MaxRetries:              cfg.HTTPMaxRetries,
+		RetryBaseDelay:          time.Duration(cfg.HTTPRetryBaseDelayMS) * time.Millisecond,
+		BreakerFailureThreshold: cfg.CircuitBreakerFailureThreshold,
+		BreakerOpenDuration:     time.Duration(cfg.CircuitBreakerOpenSeconds) * time.Second,
+	}
+	externalService := NewExternalService(cfg.UserServiceURL, cfg.SalonServiceURL, cfg.ServiceAccountToken, policy,
+		time.Duration(cfg.LookupCacheTTLSeconds)*time.Second)
+	paymentClient := NewPaymentClient(cfg.PaymentServiceURL, policy)
+	notificationClient := NewNotificationClient(cfg.NotificationServiceURL, policy)
 	
 	return &bookingService{
 		repo:               repo,
 		externalService:    externalService,
 		paymentClient:      paymentClient,
 		notificationClient: notificationClient,
+		eventPublisher:     eventPublisher,
 		config:             cfg,
 	}
 }
 
+// goBackground runs fn in a goroutine that Shutdown waits for
+func (s *bookingService) goBackground(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// Shutdown waits for in-flight background work such as notification sends to
+// finish. It returns ctx.Err() if ctx expires first.
+func (s *bookingService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Request/Response structures
 type InitiateBookingRequest struct {
-	UserID   uuid.UUID                    `json:"user_id"`
-	SalonID  uuid.UUID                    `json:"salon_id"`
-	BranchID uuid.UUID                    `json:"branch_id"`
-	Services []InitiateBookingServiceItem `json:"services"`
-	Notes    *string                      `json:"notes,omitempty"`
+	UserID         uuid.UUID                    `json:"user_id"`
+	SalonID        uuid.UUID                    `json:"salon_id"`
+	BranchID       uuid.UUID                    `json:"branch_id"`
+	Services       []InitiateBookingServiceItem `json:"services"`
+	Notes          *string                      `json:"notes,omitempty"`
+	IdempotencyKey string                       `json:"idempotency_key,omitempty"`
+	PromoCode      *string                      `json:"promo_code,omitempty"`
+
+	// CreatedByStaffID is set for walk-in bookings made by salon staff on the
+	// customer's behalf; it is never read from the request body
+	CreatedByStaffID *uuid.UUID `json:"-"`
+	// GuestAccessTokenHash is set for guest checkouts; it is never read from
+	// the request body
+	GuestAccessTokenHash *string `json:"-"`
+	// RecurringBookingID and OccurrenceDate are set for occurrences booked by
+	// the recurring booking generator; they are never read from the request body
+	RecurringBookingID *uuid.UUID `json:"-"`
+	OccurrenceDate     *string    `json:"-"`
 }
 
 type InitiateBookingServiceItem struct {
 	ServiceID uuid.UUID `json:"service_id"`
 	StylistID uuid.UUID `json:"stylist_id"`
 	StartTime time.Time `json:"start_time"`
+
+	// Optional beneficiary for group bookings; omit both for the booking user
+	BeneficiaryName   *string    `json:"beneficiary_name,omitempty"`
+	BeneficiaryUserID *uuid.UUID `json:"beneficiary_user_id,omitempty"`
 }
 
 type RescheduleBookingRequest struct {
@@ -85,13 +189,41 @@ type RescheduleBookingRequest struct {
 }
 
 type BookingSummaryRequest struct {
-	SalonID  uuid.UUID                    `json:"salon_id"`
-	BranchID uuid.UUID                    `json:"branch_id"`
-	Services []InitiateBookingServiceItem `json:"services"`
+	SalonID   uuid.UUID                    `json:"salon_id"`
+	BranchID  uuid.UUID                    `json:"branch_id"`
+	Services  []InitiateBookingServiceItem `json:"services"`
+	PromoCode *string                      `json:"promo_code,omitempty"`
+}
+
+type UpdateBranchConfigurationRequest struct {
+	// Version is the configuration version the client last read; required
+	Version                    *int     `json:"version,omitempty"`
+	BufferTimeMinutes          *int     `json:"buffer_time_minutes,omitempty"`
+	CancellationCutoffHours    *int     `json:"cancellation_cutoff_hours,omitempty"`
+	RescheduleWindowHours      *int     `json:"reschedule_window_hours,omitempty"`
+	MaxAdvanceBookingDays      *int     `json:"max_advance_booking_days,omitempty"`
+	SlotIntervalMinutes        *int     `json:"slot_interval_minutes,omitempty"`
+	ReminderLeadTimes          []int    `json:"reminder_lead_times_minutes,omitempty"`
+	CancellationFeePercentage  *float64 `json:"cancellation_fee_percentage,omitempty"`
+	CancellationFeeWindowHours *int     `json:"cancellation_fee_window_hours,omitempty"`
+	BookingFeeAmount           *float64 `json:"booking_fee_amount,omitempty"`
+	GSTPercentage              *float64 `json:"gst_percentage,omitempty"`
+	// TaxComponents, when given, replace the branch's tax components and set
+	// gst_percentage to their total
+	TaxComponents []model.TaxComponent `json:"tax_components,omitempty"`
 }
 
 // InitiateBooking creates a new booking in initiated status
 func (s *bookingService) InitiateBooking(ctx context.Context, request *InitiateBookingRequest) (*model.Booking, error) {
+	// Return the original booking when the request is replayed with the same key
+	var requestHash string
+	if request.IdempotencyKey != "" {
+		requestHash = hashRequest(request)
+		if existing, err := s.getIdempotentBooking(ctx, request.UserID, request.IdempotencyKey, requestHash); err != nil || existing != nil {
+			return existing, err
+		}
+	}
+
 	// Validate user exists
 	user, err := s.externalService.ValidateUser(ctx, request.UserID)
 	if err != nil {
@@ -99,22 +231,42 @@ func (s *bookingService) InitiateBooking(ctx context.Context, request *InitiateB
 	}
 
 	// Validate branch exists
-	_, err = s.externalService.GetBranch(ctx, request.SalonID, request.BranchID)
+	branch, err := s.externalService.GetBranch(ctx, request.SalonID, request.BranchID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid branch: %w", err)
 	}
 
+	// The booking keeps the branch time zone; service times are held in its
+	// wall-clock time whatever offset the client sent
+	loc := branchLocation(branch)
+	inLocation(request.Services, loc)
+
 	// Get branch configuration
 	branchConfig, err := s.getBranchConfigWithDefaults(ctx, request.BranchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get branch configuration: %w", err)
 	}
 
+	// Walk-ins entered by staff may have just started
+	earliestStart := time.Now()
+	if request.CreatedByStaffID != nil {
+		earliestStart = earliestStart.Add(-WalkInStartGrace)
+	}
+	if err := validateBookingWindow(request.Services, branchConfig.MaxAdvanceBookingDays, earliestStart, loc); err != nil {
+		return nil, err
+	}
+
+	// Services may be for other people; the requesting user pays and is notified
+	beneficiaries, err := s.resolveBeneficiaries(ctx, request.UserID, request.Services)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate and process services
 	var bookingServices []model.BookingService
-	var totalAmount float64
+	var totalAmount, exemptAmount float64
 
-	for _, serviceItem := range request.Services {
+	for i, serviceItem := range request.Services {
 		// Validate service exists
 		serviceInfo, err := s.externalService.GetService(ctx, request.SalonID, serviceItem.ServiceID)
 		if err != nil {
@@ -129,7 +281,11 @@ func (s *bookingService) InitiateBooking(ctx context.Context, request *InitiateB
 
 		// Check if stylist belongs to the same branch
 		if stylist.BranchID != request.BranchID {
-			return nil, fmt.Errorf("stylist %s does not belong to branch %s", serviceItem.StylistID, request.BranchID)
+			return nil, sharederrors.NewValidationError("services", fmt.Sprintf("stylist %s does not belong to branch %s", serviceItem.StylistID, request.BranchID))
+		}
+
+		if err := s.validateStylistOffersService(ctx, request.SalonID, serviceItem.StylistID, serviceItem.ServiceID); err != nil {
+			return nil, err
 		}
 
 		// Calculate end time based on service duration and buffer
@@ -141,52 +297,98 @@ func (s *bookingService) InitiateBooking(ctx context.Context, request *InitiateB
 			return nil, fmt.Errorf("failed to check availability: %w", err)
 		}
 		if !available {
-			return nil, fmt.Errorf("stylist %s is not available at %s", serviceItem.StylistID, serviceItem.StartTime.Format("2006-01-02 15:04"))
+			return nil, bookingConflict("stylist %s is not available at %s", serviceItem.StylistID, serviceItem.StartTime.Format("2006-01-02 15:04 MST"))
 		}
 
 		// Create booking service
 		bookingService := model.BookingService{
-			ID:        uuid.New(),
-			ServiceID: serviceItem.ServiceID,
-			StylistID: serviceItem.StylistID,
-			StartTime: serviceItem.StartTime,
-			EndTime:   endTime,
-			Price:     serviceInfo.Price,
+			ID:                uuid.New(),
+			ServiceID:         serviceItem.ServiceID,
+			StylistID:         serviceItem.StylistID,
+			StartTime:         serviceItem.StartTime,
+			EndTime:           endTime,
+			Price:             serviceInfo.Price,
+			BeneficiaryName:   beneficiaries[i].name,
+			BeneficiaryUserID: beneficiaries[i].userID,
 		}
 
 		bookingServices = append(bookingServices, bookingService)
-		totalAmount += serviceInfo.Price
+		totalAmount = money.Sum(totalAmount, serviceInfo.Price)
+		if serviceInfo.TaxExempt {
+			exemptAmount = money.Sum(exemptAmount, serviceInfo.Price)
+		}
 	}
 
-	// Calculate GST and total
-	gst := totalAmount * (branchConfig.GSTPercentage / 100)
-	finalTotal := totalAmount + branchConfig.BookingFeeAmount + gst
+	// Apply any promo code before tax
+	promo, discount, err := s.resolvePromoCode(ctx, request.PromoCode, request.SalonID, totalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate GST on taxable services and the total
+	totals := calculateTotals(totalAmount, exemptAmount, discount, branchConfig)
+	gst, finalTotal := totals.GST, totals.Total
+
+	// Recurring occurrences are booked by the generator with nobody present to
+	// pay, so they are confirmed in pay-at-salon mode instead of waiting for an
+	// online payment that the expiry worker would void
+	status, paymentStatus := model.BookingStatusInitiated, model.PaymentStatusPending
+	if request.RecurringBookingID != nil {
+		status, paymentStatus = model.BookingStatusConfirmed, model.PaymentStatusPayAtSalon
+	}
 
 	// Create booking
 	booking := &model.Booking{
-		ID:            uuid.New(),
-		UserID:        request.UserID,
-		SalonID:       request.SalonID,
-		BranchID:      request.BranchID,
-		Status:        model.BookingStatusInitiated,
-		TotalAmount:   finalTotal,
-		GST:           gst,
-		BookingFee:    branchConfig.BookingFeeAmount,
-		PaymentStatus: model.PaymentStatusPending,
-		Notes:         request.Notes,
-	}
-
-	// Save booking
-	if err := s.repo.Create(ctx, booking); err != nil {
-		return nil, fmt.Errorf("failed to create booking: %w", err)
+		ID:             uuid.New(),
+		UserID:         request.UserID,
+		SalonID:        request.SalonID,
+		BranchID:       request.BranchID,
+		Status:         status,
+		TotalAmount:    finalTotal,
+		GST:            gst,
+		BookingFee:     branchConfig.BookingFeeAmount,
+		PaymentStatus:  paymentStatus,
+		Notes:          request.Notes,
+		DiscountAmount: discount,
+		Timezone:       loc.String(),
+		Taxes:          totals.Taxes,
+
+		GuestAccessTokenHash: request.GuestAccessTokenHash,
+		RecurringBookingID:   request.RecurringBookingID,
+		OccurrenceDate:       request.OccurrenceDate,
+	}
+	if promo != nil {
+		booking.PromoCode = &promo.Code
+	}
+
+	// Save booking and services atomically, re-checking availability under lock
+	var idempotency *model.IdempotencyRecord
+	if request.IdempotencyKey != "" {
+		idempotency = &model.IdempotencyRecord{
+			ID:             uuid.New(),
+			UserID:         request.UserID,
+			IdempotencyKey: request.IdempotencyKey,
+			RequestHash:    requestHash,
+			ExpiresAt:      time.Now().Add(time.Duration(s.config.IdempotencyTTLHours) * time.Hour),
+		}
 	}
 
-	// Save booking services
-	for i := range bookingServices {
-		bookingServices[i].BookingID = booking.ID
-		if err := s.repo.CreateBookingService(ctx, &bookingServices[i]); err != nil {
-			return nil, fmt.Errorf("failed to create booking service: %w", err)
+	buffer := time.Duration(branchConfig.BufferTimeMinutes) * time.Minute
+	if err := s.repo.CreateWithServices(ctx, booking, bookingServices, buffer, idempotency); err != nil {
+		if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+			// A concurrent request with the same key won the race
+			existing, getErr := s.getIdempotentBooking(ctx, request.UserID, request.IdempotencyKey, requestHash)
+			if getErr != nil || existing != nil {
+				return existing, getErr
+			}
+		}
+		if errors.Is(err, repository.ErrSlotUnavailable) {
+			return nil, bookingConflict("%v", err)
 		}
+		if errors.Is(err, repository.ErrPromoCodeExhausted) || errors.Is(err, repository.ErrPromoCodeUserLimit) {
+			return nil, invalidPromoCode("%s", err.Error())
+		}
+		return nil, fmt.Errorf("failed to create booking: %w", err)
 	}
 
 	// Create history entry
@@ -195,13 +397,29 @@ func (s *bookingService) InitiateBooking(ctx context.Context, request *InitiateB
 		"services":  len(bookingServices),
 		"total":     finalTotal,
 	}
+	if promo != nil {
+		historyData["promo_code"] = promo.Code
+		historyData["discount"] = discount
+	}
+	if count := countBeneficiaries(bookingServices); count > 0 {
+		historyData["beneficiaries"] = count
+	}
+	if request.RecurringBookingID != nil {
+		historyData["recurring_booking_id"] = request.RecurringBookingID.String()
+	}
+	actorID := &request.UserID
+	if request.CreatedByStaffID != nil {
+		historyData["walk_in"] = true
+		historyData["created_by_staff_id"] = request.CreatedByStaffID.String()
+		actorID = request.CreatedByStaffID
+	}
 	historyJSON, _ := json.Marshal(historyData)
 	history := &model.BookingHistory{
 		ID:        uuid.New(),
 		BookingID: booking.ID,
 		Action:    model.BookingActionCreated,
 		NewValues: stringPtr(string(historyJSON)),
-		UserID:    &request.UserID,
+		UserID:    actorID,
 	}
 	if err := s.repo.CreateHistory(ctx, history); err != nil {
 		log.Warn().Err(err).Msg("Failed to create booking history")
@@ -224,12 +442,12 @@ func (s *bookingService) InitiatePaymentForBooking(ctx context.Context, bookingI
 	// Get booking
 	booking, err := s.repo.GetByID(ctx, bookingID)
 	if err != nil {
-		return nil, fmt.Errorf("booking not found: %w", err)
+		return nil, bookingLookupError(bookingID, err)
 	}
 
 	// Validate booking status
 	if booking.Status != model.BookingStatusInitiated {
-		return nil, fmt.Errorf("payment can only be initiated for bookings in initiated status, current status: %s", booking.Status)
+		return nil, bookingConflict("payment can only be initiated for bookings in initiated status, current status: %s", booking.Status)
 	}
 
 	// Validate user exists
@@ -246,7 +464,7 @@ func (s *bookingService) InitiatePaymentForBooking(ctx context.Context, bookingI
 		BookingID:      bookingID,
 		UserID:         booking.UserID,
 		Amount:         booking.TotalAmount,
-		Currency:       "INR", // Default currency
+		Currency:       s.config.Currency,
 		IdempotencyKey: idempotencyKey,
 		Description:    fmt.Sprintf("Payment for booking %s", bookingID.String()),
 		Gateway:        gateway,
@@ -281,14 +499,28 @@ func (s *bookingService) ProcessPaymentCallback(ctx context.Context, bookingID u
 		return fmt.Errorf("failed to confirm payment: %w", err)
 	}
 
+	// Balance payments of rescheduled bookings settle the balance instead of confirming
+	if settled, err := s.settleBalancePayment(ctx, bookingID, paymentID); err != nil || settled {
+		return err
+	}
+
 	// Confirm booking
-	confirmedBooking, err := s.ConfirmBooking(ctx, bookingID, paymentID.String())
+	confirmedBooking, confirmed, err := s.confirmBooking(ctx, bookingID, paymentID.String())
 	if err != nil {
 		return fmt.Errorf("failed to confirm booking: %w", err)
 	}
 
+	// A redelivered callback finds the booking already confirmed; it was notified the first time
+	if !confirmed {
+		log.Info().
+			Str("booking_id", bookingID.String()).
+			Str("payment_id", paymentID.String()).
+			Msg("Payment callback replayed for confirmed booking")
+		return nil
+	}
+
 	// Send confirmation notifications
-	go s.sendBookingConfirmationNotifications(ctx, confirmedBooking)
+	s.goBackground(func() { s.sendBookingConfirmationNotifications(context.WithoutCancel(ctx), confirmedBooking) })
 
 	log.Info().
 		Str("booking_id", bookingID.String()).
@@ -298,79 +530,134 @@ func (s *bookingService) ProcessPaymentCallback(ctx context.Context, bookingID u
 	return nil
 }
 
-// RefundBookingPayment initiates a refund for a booking payment
-func (s *bookingService) RefundBookingPayment(ctx context.Context, bookingID uuid.UUID, reason string, amount *float64) (*RefundPaymentResponse, error) {
+// RefundBookingPayment initiates a refund for a booking, nil amount for a
+// full refund. A booking with a balance payment from a reschedule is refunded
+// from the balance payment first and then the original payment.
+func (s *bookingService) RefundBookingPayment(ctx context.Context, bookingID uuid.UUID, reason string, amount *float64) (*BookingRefund, error) {
 	// Get booking
 	booking, err := s.repo.GetByID(ctx, bookingID)
 	if err != nil {
-		return nil, fmt.Errorf("booking not found: %w", err)
+		return nil, bookingLookupError(bookingID, err)
 	}
 
 	// Validate booking has payment
 	if booking.PaymentID == nil {
-		return nil, fmt.Errorf("booking has no associated payment")
+		return nil, bookingConflict("booking has no associated payment")
 	}
 
-	// Parse payment ID
-	paymentID, err := uuid.Parse(*booking.PaymentID)
+	payments, err := s.refundablePayments(ctx, booking)
 	if err != nil {
-		return nil, fmt.Errorf("invalid payment ID: %w", err)
-	}
-
-	// Generate idempotency key for refund
-	idempotencyKey := fmt.Sprintf("refund-%s-%d", bookingID.String(), time.Now().Unix())
-
-	// Prepare refund request
-	refundRequest := &RefundPaymentRequest{
-		PaymentID:      paymentID,
-		Amount:         amount, // nil for full refund
-		Reason:         reason,
-		IdempotencyKey: idempotencyKey,
+		return nil, err
 	}
-
-	// Initiate refund
-	refundResponse, err := s.paymentClient.RefundPayment(ctx, refundRequest)
+	parts, err := splitRefund(payments, amount)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initiate refund: %w", err)
+		return nil, err
+	}
+
+	refund := &BookingRefund{BookingID: bookingID}
+	var refundErr error
+	for _, part := range parts {
+		refundResponse, err := s.paymentClient.RefundPayment(ctx, &RefundPaymentRequest{
+			PaymentID:      part.PaymentID,
+			Amount:         part.Amount,
+			Reason:         reason,
+			IdempotencyKey: fmt.Sprintf("refund-%s-%s-%d", bookingID.String(), part.PaymentID.String(), time.Now().Unix()),
+		})
+		if err != nil {
+			refundErr = fmt.Errorf("failed to initiate refund of payment %s: %w", part.PaymentID, err)
+			break
+		}
+		refund.Refunds = append(refund.Refunds, refundResponse)
+		refund.Amount = money.Sum(refund.Amount, refundResponse.Amount)
+	}
+	if len(refund.Refunds) == 0 {
+		return nil, refundErr
 	}
 
-	// Update booking payment status
-	booking.PaymentStatus = model.PaymentStatusRefunded
-	if err := s.repo.Update(ctx, booking); err != nil {
-		log.Error().Err(err).Msg("Failed to update booking payment status after refund")
+	// Only a booking whose whole payment was returned is marked refunded; one
+	// that kept a cancellation fee or other part of its payment stays paid
+	historyData := map[string]interface{}{
+		"refund_amount": refund.Amount,
+		"refunds":       refund.Refunds,
+	}
+	if refundErr == nil && (amount == nil || refund.Amount >= booking.TotalAmount) {
+		booking.PaymentStatus = model.PaymentStatusRefunded
+		if err := s.repo.Update(ctx, booking); err != nil {
+			log.Error().Err(err).Msg("Failed to update booking payment status after refund")
+		}
+	} else {
+		historyData["retained_amount"] = money.Sub(booking.TotalAmount, refund.Amount)
+	}
+	historyData["payment_status"] = booking.PaymentStatus
+	historyJSON, _ := json.Marshal(historyData)
+	history := &model.BookingHistory{
+		ID:        uuid.New(),
+		BookingID: bookingID,
+		Action:    model.BookingActionPaymentAdjusted,
+		NewValues: stringPtr(string(historyJSON)),
+		Reason:    &reason,
+	}
+	if err := s.repo.CreateHistory(ctx, history); err != nil {
+		log.Warn().Err(err).Msg("Failed to create booking history")
 	}
 
 	log.Info().
 		Str("booking_id", bookingID.String()).
-		Str("refund_id", refundResponse.RefundID.String()).
-		Float64("amount", refundResponse.Amount).
+		Int("refunds", len(refund.Refunds)).
+		Float64("amount", refund.Amount).
+		Str("payment_status", string(booking.PaymentStatus)).
 		Msg("Refund initiated for booking")
 
-	return refundResponse, nil
+	if refundErr != nil {
+		// Earlier payments were refunded; the rest can be retried through the refund endpoint
+		return nil, refundErr
+	}
+	return refund, nil
 }
 
 // ConfirmBooking confirms a booking after successful payment
 func (s *bookingService) ConfirmBooking(ctx context.Context, bookingID uuid.UUID, paymentID string) (*model.Booking, error) {
+	booking, _, err := s.confirmBooking(ctx, bookingID, paymentID)
+	return booking, err
+}
+
+// confirmBooking confirms an initiated booking and reports whether this call
+// confirmed it. Confirming again with the same payment ID returns the booking
+// unchanged so replayed payment callbacks succeed; a different payment ID is a conflict.
+func (s *bookingService) confirmBooking(ctx context.Context, bookingID uuid.UUID, paymentID string) (*model.Booking, bool, error) {
 	// Get booking
 	booking, err := s.repo.GetByID(ctx, bookingID)
 	if err != nil {
-		return nil, fmt.Errorf("booking not found: %w", err)
+		return nil, false, bookingLookupError(bookingID, err)
+	}
+
+	if booking.Status == model.BookingStatusInitiated {
+		confirmed, err := s.repo.MarkConfirmed(ctx, bookingID, paymentID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update booking: %w", err)
+		}
+		if !confirmed {
+			// A concurrent confirmation won; judge this one against its result
+			if booking, err = s.repo.GetByID(ctx, bookingID); err != nil {
+				return nil, false, bookingLookupError(bookingID, err)
+			}
+		}
 	}
 
-	// Validate booking status
 	if booking.Status != model.BookingStatusInitiated {
-		return nil, fmt.Errorf("booking cannot be confirmed in status: %s", booking.Status)
+		if booking.Status == model.BookingStatusConfirmed && booking.PaymentID != nil && *booking.PaymentID == paymentID {
+			return booking, false, nil
+		}
+		if booking.Status == model.BookingStatusConfirmed {
+			return nil, false, bookingConflict("booking is already confirmed with a different payment")
+		}
+		return nil, false, bookingConflict("booking cannot be confirmed in status: %s", booking.Status)
 	}
 
-	// Update booking status
 	booking.Status = model.BookingStatusConfirmed
 	booking.PaymentStatus = model.PaymentStatusPaid
 	booking.PaymentID = &paymentID
 
-	if err := s.repo.Update(ctx, booking); err != nil {
-		return nil, fmt.Errorf("failed to update booking: %w", err)
-	}
-
 	// Create history entry
 	history := &model.BookingHistory{
 		ID:        uuid.New(),
@@ -387,7 +674,7 @@ func (s *bookingService) ConfirmBooking(ctx context.Context, bookingID uuid.UUID
 		Str("payment_id", paymentID).
 		Msg("Booking confirmed successfully")
 
-	return booking, nil
+	return booking, true, nil
 }
 
 // Helper function to get branch configuration with defaults
@@ -396,15 +683,24 @@ func (s *bookingService) getBranchConfigWithDefaults(ctx context.Context, branch
 	if err != nil {
 		// If configuration doesn't exist, create default one
 		config = &model.BranchConfiguration{
-			BranchID:                branchID,
-			BufferTimeMinutes:       s.config.DefaultBufferTimeMinutes,
-			CancellationCutoffHours: s.config.DefaultCancellationCutoffHours,
-			RescheduleWindowHours:   s.config.DefaultRescheduleWindowHours,
-			MaxAdvanceBookingDays:   s.config.DefaultMaxAdvanceBookingDays,
-			BookingFeeAmount:        s.config.DefaultBookingFeeAmount,
-			GSTPercentage:           s.config.DefaultGSTPercentage,
+			BranchID:                   branchID,
+			BufferTimeMinutes:          s.config.DefaultBufferTimeMinutes,
+			CancellationCutoffHours:    s.config.DefaultCancellationCutoffHours,
+			RescheduleWindowHours:      s.config.DefaultRescheduleWindowHours,
+			MaxAdvanceBookingDays:      s.config.DefaultMaxAdvanceBookingDays,
+			SlotIntervalMinutes:        s.config.DefaultSlotIntervalMinutes,
+			ReminderLeadTimes:          s.config.DefaultReminderLeadTimes,
+			CancellationFeePercentage:  s.config.DefaultCancellationFeePercentage,
+			CancellationFeeWindowHours: s.config.DefaultCancellationFeeWindowHours,
+			BookingFeeAmount:           s.config.DefaultBookingFeeAmount,
+			GSTPercentage:              s.config.DefaultGSTPercentage,
+			TaxComponents:              model.SingleTaxComponent(s.config.DefaultGSTPercentage),
 		}
 		
+		if !model.IsValidSlotInterval(config.SlotIntervalMinutes) {
+			config.SlotIntervalMinutes = model.DefaultSlotIntervalMinutes
+		}
+
 		if createErr := s.repo.CreateBranchConfiguration(ctx, config); createErr != nil {
 			log.Warn().Err(createErr).Str("branch_id", branchID.String()).Msg("Failed to create default branch configuration")
 		}
@@ -412,6 +708,80 @@ func (s *bookingService) getBranchConfigWithDefaults(ctx context.Context, branch
 	return config, nil
 }
 
+// getIdempotentBooking returns the booking previously created with the user's
+// idempotency key, if any. Reusing a key for a different request is a conflict.
+func (s *bookingService) getIdempotentBooking(ctx context.Context, userID uuid.UUID, key, requestHash string) (*model.Booking, error) {
+	record, err := s.repo.GetIdempotencyRecord(ctx, userID, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotency key: %w", err)
+	}
+	if record == nil {
+		return nil, nil
+	}
+	if record.RequestHash != "" && record.RequestHash != requestHash {
+		return nil, bookingConflict("idempotency key was already used with different request parameters")
+	}
+
+	booking, err := s.repo.GetByID(ctx, record.BookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotent booking: %w", err)
+	}
+
+	log.Info().
+		Str("idempotency_key", key).
+		Str("booking_id", booking.ID.String()).
+		Msg("Returning existing booking for idempotency key")
+
+	return booking, nil
+}
+
+// hashRequest returns the SHA-256 of the request JSON, used to detect idempotency key reuse
+func hashRequest(request interface{}) string {
+	requestData, _ := json.Marshal(request)
+	hash := sha256.Sum256(requestData)
+	return hex.EncodeToString(hash[:])
+}
+
+// validateStylistOffersService ensures the stylist is assigned to perform the service
+func (s *bookingService) validateStylistOffersService(ctx context.Context, salonID, stylistID, serviceID uuid.UUID) error {
+	services, err := s.externalService.GetStylistServices(ctx, salonID, stylistID)
+	if err != nil {
+		return fmt.Errorf("failed to get services for stylist %s: %w", stylistID, err)
+	}
+
+	for _, service := range services {
+		if service.ID == serviceID {
+			return nil
+		}
+	}
+
+	return sharederrors.NewValidationError("services", fmt.Sprintf("stylist %s does not offer service %s", stylistID, serviceID))
+}
+
+// validateBookingWindow ensures every service starts after earliest and within the branch's advance booking window.
+// The window is counted in calendar days of the branch time zone, so it is not shifted by DST transitions.
+func validateBookingWindow(services []InitiateBookingServiceItem, maxAdvanceDays int, earliest time.Time, loc *time.Location) error {
+	latestStart := time.Now().In(loc).AddDate(0, 0, maxAdvanceDays)
+
+	for i, serviceItem := range services {
+		if !serviceItem.StartTime.After(earliest) {
+			return sharederrors.NewValidationError("services", fmt.Sprintf("start_time cannot be in the past for service %d", i))
+		}
+		if maxAdvanceDays > 0 && serviceItem.StartTime.After(latestStart) {
+			return sharederrors.NewValidationError("services", fmt.Sprintf("start_time for service %d is beyond the maximum advance booking window of %d days", i, maxAdvanceDays))
+		}
+	}
+
+	return nil
+}
+
+// inLocation converts requested service start times to loc
+func inLocation(services []InitiateBookingServiceItem, loc *time.Location) {
+	for i := range services {
+		services[i].StartTime = services[i].StartTime.In(loc)
+	}
+}
+
 // Helper function to check availability with buffer time
 func (s *bookingService) checkAvailabilityWithBuffer(ctx context.Context, stylistID uuid.UUID, startTime, endTime time.Time, bufferMinutes int) (bool, error) {
 	// Add buffer time to the requested slot
@@ -426,12 +796,12 @@ func (s *bookingService) CancelBooking(ctx context.Context, bookingID uuid.UUID,
 	// Get booking
 	booking, err := s.repo.GetByID(ctx, bookingID)
 	if err != nil {
-		return fmt.Errorf("booking not found: %w", err)
+		return bookingLookupError(bookingID, err)
 	}
 
 	// Validate user owns the booking
 	if booking.UserID != userID {
-		return fmt.Errorf("unauthorized: user does not own this booking")
+		return errNotBookingOwner
 	}
 
 	// Check if booking can be canceled
@@ -441,19 +811,51 @@ func (s *bookingService) CancelBooking(ctx context.Context, bookingID uuid.UUID,
 	}
 
 	if !booking.CanBeCanceled(branchConfig.CancellationCutoffHours) {
-		return fmt.Errorf("booking cannot be canceled within %d hours of appointment", branchConfig.CancellationCutoffHours)
+		return bookingConflict("booking cannot be canceled within %d hours of appointment", branchConfig.CancellationCutoffHours)
 	}
 
-	// Update booking status
-	if err := s.repo.UpdateStatus(ctx, bookingID, model.BookingStatusCanceled); err != nil {
-		return fmt.Errorf("failed to cancel booking: %w", err)
+	// Cancel the booking as read, so a concurrent reschedule is not overwritten
+	booking.Status = model.BookingStatusCanceled
+	if err := s.repo.Update(ctx, booking); err != nil {
+		return bookingUpdateError("cancel booking", err)
+	}
+
+	historyData := map[string]interface{}{
+		"status": model.BookingStatusCanceled,
+	}
+
+	// Refund paid bookings, keeping the cancellation fee when inside the penalty window
+	if booking.PaymentStatus == model.PaymentStatusPaid && booking.PaymentID != nil {
+		fee := branchConfig.CalculateCancellationFee(booking, time.Now())
+		refundAmount := money.Sub(booking.TotalAmount, fee)
+		historyData["cancellation_fee"] = fee
+		historyData["refund_amount"] = refundAmount
+
+		if refundAmount > 0 {
+			// A nil amount requests a full refund
+			var amount *float64
+			if fee > 0 {
+				amount = &refundAmount
+			}
+
+			refund, err := s.RefundBookingPayment(ctx, bookingID, reason, amount)
+			if err != nil {
+				// The booking stays cancelled; the refund can be retried through the refund endpoint
+				log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to refund cancelled booking")
+				historyData["refund_status"] = "failed"
+			} else {
+				historyData["refunds"] = refund.Refunds
+			}
+		}
 	}
+	historyJSON, _ := json.Marshal(historyData)
 
 	// Create history entry
 	history := &model.BookingHistory{
 		ID:        uuid.New(),
 		BookingID: bookingID,
 		Action:    model.BookingActionCanceled,
+		NewValues: stringPtr(string(historyJSON)),
 		Reason:    &reason,
 		UserID:    &userID,
 	}
@@ -461,8 +863,10 @@ func (s *bookingService) CancelBooking(ctx context.Context, bookingID uuid.UUID,
 		log.Warn().Err(err).Msg("Failed to create booking history")
 	}
 
-	// Send cancellation notifications
-	go s.sendBookingCancellationNotifications(ctx, booking, reason)
+	// Send cancellation notifications and offer the freed slots to the waitlist
+	s.goBackground(func() { s.sendBookingCancellationNotifications(context.WithoutCancel(ctx), booking, reason) })
+	slots := freedSlots(bookingID, booking.Services, nil)
+	s.goBackground(func() { s.notifyWaitlist(context.WithoutCancel(ctx), slots) })
 
 	log.Info().
 		Str("booking_id", bookingID.String()).
@@ -473,14 +877,246 @@ func (s *bookingService) CancelBooking(ctx context.Context, bookingID uuid.UUID,
 	return nil
 }
 
+// ReleaseExpiredPaymentBooking cancels an initiated booking whose payment expired,
+// releasing the held slot. Bookings that moved on or use another payment are left untouched.
+func (s *bookingService) ReleaseExpiredPaymentBooking(ctx context.Context, bookingID, paymentID uuid.UUID) error {
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return bookingLookupError(bookingID, err)
+	}
+
+	if booking.Status != model.BookingStatusInitiated {
+		// The booking keeps its slots; staff follow up on the unpaid balance
+		if booking.BalancePaymentID != nil && *booking.BalancePaymentID == paymentID.String() && booking.PaymentStatus == model.PaymentStatusPending {
+			log.Warn().
+				Str("booking_id", bookingID.String()).
+				Str("payment_id", paymentID.String()).
+				Msg("Balance payment of rescheduled booking expired; booking remains underpaid")
+		}
+		return nil
+	}
+	if booking.PaymentID != nil && *booking.PaymentID != paymentID.String() {
+		return nil
+	}
+
+	booking.Status = model.BookingStatusCanceled
+	booking.PaymentStatus = model.PaymentStatusFailed
+	if err := s.repo.Update(ctx, booking); err != nil {
+		return bookingUpdateError("release booking", err)
+	}
+
+	reason := "payment expired"
+	history := &model.BookingHistory{
+		ID:        uuid.New(),
+		BookingID: bookingID,
+		Action:    model.BookingActionCanceled,
+		NewValues: stringPtr(fmt.Sprintf(`{"payment_id": "%s", "payment_status": "%s"}`, paymentID, model.PaymentStatusFailed)),
+		Reason:    &reason,
+	}
+	if err := s.repo.CreateHistory(ctx, history); err != nil {
+		log.Warn().Err(err).Msg("Failed to create booking history")
+	}
+
+	slots := freedSlots(bookingID, booking.Services, nil)
+	s.goBackground(func() { s.notifyWaitlist(context.WithoutCancel(ctx), slots) })
+
+	log.Info().
+		Str("booking_id", bookingID.String()).
+		Str("payment_id", paymentID.String()).
+		Msg("Booking released after payment expiry")
+
+	return nil
+}
+
+// AbandonBooking voids an unpaid initiated booking on behalf of its owner,
+// freeing its slots. Bookings past initiation cannot be abandoned.
+func (s *bookingService) AbandonBooking(ctx context.Context, bookingID, userID uuid.UUID) error {
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return bookingLookupError(bookingID, err)
+	}
+
+	if booking.UserID != userID {
+		return errNotBookingOwner
+	}
+
+	if booking.Status != model.BookingStatusInitiated {
+		return bookingConflict("booking cannot be abandoned in status: %s", booking.Status)
+	}
+
+	expired, err := s.expireBooking(ctx, bookingID, "abandoned by user", &userID)
+	if err != nil {
+		return err
+	}
+	if !expired {
+		return bookingConflict("booking is no longer awaiting payment")
+	}
+
+	return nil
+}
+
+// staleBookingBatchSize limits how many bookings are expired per run
+const staleBookingBatchSize = 100
+
+// ExpireStaleBookings voids initiated bookings left unpaid for longer than the
+// payment timeout and returns how many were expired
+func (s *bookingService) ExpireStaleBookings(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-time.Duration(s.config.PaymentTimeoutMinutes) * time.Minute)
+	bookingIDs, err := s.repo.GetStaleInitiatedBookings(ctx, cutoff, staleBookingBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stale bookings: %w", err)
+	}
+
+	count := 0
+	for _, bookingID := range bookingIDs {
+		expired, err := s.expireBooking(ctx, bookingID, "payment not completed in time", nil)
+		if err != nil {
+			log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to expire booking")
+			continue
+		}
+		if expired {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// expireBooking moves an initiated booking to expired and records why. It
+// reports false when the booking had already left the initiated status.
+func (s *bookingService) expireBooking(ctx context.Context, bookingID uuid.UUID, reason string, userID *uuid.UUID) (bool, error) {
+	expired, err := s.repo.MarkExpired(ctx, bookingID)
+	if err != nil {
+		return false, err
+	}
+	if !expired {
+		return false, nil
+	}
+
+	history := &model.BookingHistory{
+		ID:        uuid.New(),
+		BookingID: bookingID,
+		Action:    model.BookingActionExpired,
+		OldValues: stringPtr(fmt.Sprintf(`{"status": "%s"}`, model.BookingStatusInitiated)),
+		NewValues: stringPtr(fmt.Sprintf(`{"status": "%s"}`, model.BookingStatusExpired)),
+		UserID:    userID,
+		Reason:    &reason,
+	}
+	if err := s.repo.CreateHistory(ctx, history); err != nil {
+		log.Warn().Err(err).Msg("Failed to create booking history")
+	}
+
+	log.Info().
+		Str("booking_id", bookingID.String()).
+		Str("reason", reason).
+		Msg("Booking expired")
+
+	return true, nil
+}
+
 // GetBooking retrieves a booking by ID
 func (s *bookingService) GetBooking(ctx context.Context, bookingID uuid.UUID) (*model.Booking, error) {
-	return s.repo.GetByID(ctx, bookingID)
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, bookingLookupError(bookingID, err)
+	}
+	return booking, nil
+}
+
+// GetUserBookings retrieves a page of a user's bookings and the total number matching the filter
+func (s *bookingService) GetUserBookings(ctx context.Context, filter model.UserBookingFilter) ([]*model.Booking, int, error) {
+	if filter.Sort == "" {
+		filter.Sort = model.UserBookingSortCreatedAt
+	}
+	if filter.Now.IsZero() {
+		filter.Now = time.Now()
+	}
+	bookings, err := s.repo.GetByUserID(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountByUserID(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bookings, total, nil
+}
+
+// ListBookings retrieves a page of a branch's bookings and the total number matching the filter
+func (s *bookingService) ListBookings(ctx context.Context, filter model.BookingFilter) ([]*model.Booking, int, error) {
+	bookings, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bookings, total, nil
+}
+
+// maxExportRange bounds a single branch export or report to roughly a year of bookings
+const maxExportRange = 366 * 24 * time.Hour
+
+// ExportBranchBookings streams a branch's bookings between filter.From and
+// filter.To to fn. Both bounds are required.
+func (s *bookingService) ExportBranchBookings(ctx context.Context, filter model.BookingFilter, fn func(*model.BookingExportRow) error) error {
+	if filter.From == nil || filter.To == nil {
+		return sharederrors.NewValidationError("from", "from and to are required for exports")
+	}
+	if filter.To.Sub(*filter.From) > maxExportRange {
+		return sharederrors.NewValidationError("to", "exports cannot span more than 366 days")
+	}
+	return s.repo.ExportBranchBookings(ctx, filter, fn)
+}
+
+// GetRevenueReport aggregates a branch's booked services between filter.From
+// and filter.To. Without explicit statuses only revenue-bearing bookings count.
+func (s *bookingService) GetRevenueReport(ctx context.Context, filter model.RevenueReportFilter) (*model.RevenueReport, error) {
+	if !filter.GroupBy.IsValid() {
+		return nil, sharederrors.NewValidationError("group_by", "group_by must be service or stylist")
+	}
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return nil, sharederrors.NewValidationError("from", "from and to are required for reports")
+	}
+	if filter.To.Sub(filter.From) > maxExportRange {
+		return nil, sharederrors.NewValidationError("to", "reports cannot span more than 366 days")
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = model.RevenueStatuses
+	}
+
+	rows, err := s.repo.RevenueReport(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.RevenueReport{
+		BranchID: filter.BranchID,
+		GroupBy:  filter.GroupBy,
+		From:     filter.From,
+		To:       filter.To,
+		Statuses: filter.Statuses,
+		Rows:     rows,
+	}
+	for _, row := range rows {
+		report.TotalRevenue = money.Sum(report.TotalRevenue, row.Revenue)
+	}
+	return report, nil
 }
 
-// GetUserBookings retrieves bookings for a user
-func (s *bookingService) GetUserBookings(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Booking, error) {
-	return s.repo.GetByUserID(ctx, userID, limit, offset)
+// GetBookingHistory retrieves the audit trail for a booking, newest first
+func (s *bookingService) GetBookingHistory(ctx context.Context, bookingID uuid.UUID) ([]*model.BookingHistory, error) {
+	history, err := s.repo.GetBookingHistory(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if history == nil {
+		history = []*model.BookingHistory{}
+	}
+
+	return history, nil
 }
 
 // GetBranchConfiguration retrieves branch configuration
@@ -488,17 +1124,98 @@ func (s *bookingService) GetBranchConfiguration(ctx context.Context, branchID uu
 	return s.getBranchConfigWithDefaults(ctx, branchID)
 }
 
+// UpdateBranchConfiguration applies a partial update to a branch configuration.
+// The update is rejected with a conflict unless request.Version is the current
+// version, so an admin never overwrites changes they have not seen.
+func (s *bookingService) UpdateBranchConfiguration(ctx context.Context, branchID uuid.UUID, request *UpdateBranchConfigurationRequest) (*model.BranchConfiguration, error) {
+	if request.Version == nil {
+		return nil, sharederrors.NewValidationError("version", "version is required")
+	}
+
+	config, err := s.getBranchConfigWithDefaults(ctx, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch configuration: %w", err)
+	}
+	if config.Version != *request.Version {
+		return nil, branchConfigConflict(config.Version)
+	}
+
+	if request.BufferTimeMinutes != nil {
+		config.BufferTimeMinutes = *request.BufferTimeMinutes
+	}
+	if request.CancellationCutoffHours != nil {
+		config.CancellationCutoffHours = *request.CancellationCutoffHours
+	}
+	if request.RescheduleWindowHours != nil {
+		config.RescheduleWindowHours = *request.RescheduleWindowHours
+	}
+	if request.MaxAdvanceBookingDays != nil {
+		config.MaxAdvanceBookingDays = *request.MaxAdvanceBookingDays
+	}
+	if request.SlotIntervalMinutes != nil {
+		if !model.IsValidSlotInterval(*request.SlotIntervalMinutes) {
+			return nil, sharederrors.NewValidationError("slot_interval_minutes", fmt.Sprintf("unsupported slot interval: %d minutes", *request.SlotIntervalMinutes))
+		}
+		config.SlotIntervalMinutes = *request.SlotIntervalMinutes
+	}
+	if request.ReminderLeadTimes != nil {
+		config.ReminderLeadTimes = request.ReminderLeadTimes
+	}
+	if request.CancellationFeePercentage != nil {
+		config.CancellationFeePercentage = *request.CancellationFeePercentage
+	}
+	if request.CancellationFeeWindowHours != nil {
+		config.CancellationFeeWindowHours = *request.CancellationFeeWindowHours
+	}
+	if request.BookingFeeAmount != nil {
+		config.BookingFeeAmount = *request.BookingFeeAmount
+	}
+	// Tax components replace the branch's taxes; a bare GST percentage is a
+	// single GST component
+	if request.TaxComponents != nil {
+		config.TaxComponents = request.TaxComponents
+		config.GSTPercentage = model.TotalTaxPercentage(request.TaxComponents)
+	} else if request.GSTPercentage != nil {
+		config.GSTPercentage = *request.GSTPercentage
+		config.TaxComponents = model.SingleTaxComponent(*request.GSTPercentage)
+	}
+
+	if err := s.repo.UpdateBranchConfiguration(ctx, config); err != nil {
+		if errors.Is(err, repository.ErrBranchConfigVersionConflict) {
+			current, getErr := s.repo.GetBranchConfiguration(ctx, branchID)
+			if getErr != nil {
+				return nil, sharederrors.NewConflictError("branch configuration", err.Error())
+			}
+			return nil, branchConfigConflict(current.Version)
+		}
+		return nil, fmt.Errorf("failed to update branch configuration: %w", err)
+	}
+
+	log.Info().
+		Str("branch_id", branchID.String()).
+		Int("version", config.Version).
+		Msg("Branch configuration updated successfully")
+
+	return s.repo.GetBranchConfiguration(ctx, branchID)
+}
+
 // RescheduleBooking reschedules an existing booking
 func (s *bookingService) RescheduleBooking(ctx context.Context, request *RescheduleBookingRequest) (*model.Booking, error) {
 	// Get existing booking
 	booking, err := s.repo.GetByID(ctx, request.BookingID)
 	if err != nil {
-		return nil, fmt.Errorf("booking not found: %w", err)
+		return nil, bookingLookupError(request.BookingID, err)
 	}
 
 	// Validate user owns the booking
 	if booking.UserID != request.UserID {
-		return nil, fmt.Errorf("unauthorized: user does not own this booking")
+		return nil, errNotBookingOwner
+	}
+
+	// What was paid is only known for fully paid bookings, so a balance from
+	// an earlier reschedule must be settled first
+	if booking.BalancePaymentID != nil && booking.PaymentStatus == model.PaymentStatusPending {
+		return nil, bookingConflict("booking has an outstanding balance from an earlier reschedule")
 	}
 
 	// Check if booking can be rescheduled
@@ -508,22 +1225,29 @@ func (s *bookingService) RescheduleBooking(ctx context.Context, request *Resched
 	}
 
 	if !booking.CanBeRescheduled(branchConfig.RescheduleWindowHours) {
-		return nil, fmt.Errorf("booking cannot be rescheduled within %d hours of appointment", branchConfig.RescheduleWindowHours)
+		return nil, bookingConflict("booking cannot be rescheduled within %d hours of appointment", branchConfig.RescheduleWindowHours)
+	}
+
+	loc := booking.Location()
+	inLocation(request.Services, loc)
+	if err := validateBookingWindow(request.Services, branchConfig.MaxAdvanceBookingDays, time.Now(), loc); err != nil {
+		return nil, err
 	}
 
 	// Store old values for history
 	oldServices, _ := json.Marshal(booking.Services)
+	previousServices := booking.Services
 
-	// Delete existing booking services
-	if err := s.repo.DeleteBookingServices(ctx, request.BookingID); err != nil {
-		return nil, fmt.Errorf("failed to delete old booking services: %w", err)
+	beneficiaries, err := s.resolveBeneficiaries(ctx, booking.UserID, request.Services)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate and create new services (similar to InitiateBooking)
 	var newBookingServices []model.BookingService
-	var totalAmount float64
+	var totalAmount, exemptAmount float64
 
-	for _, serviceItem := range request.Services {
+	for i, serviceItem := range request.Services {
 		// Validate service and stylist (same logic as InitiateBooking)
 		serviceInfo, err := s.externalService.GetService(ctx, booking.SalonID, serviceItem.ServiceID)
 		if err != nil {
@@ -536,51 +1260,83 @@ func (s *bookingService) RescheduleBooking(ctx context.Context, request *Resched
 		}
 
 		if stylist.BranchID != booking.BranchID {
-			return nil, fmt.Errorf("stylist %s does not belong to branch %s", serviceItem.StylistID, booking.BranchID)
+			return nil, sharederrors.NewValidationError("services", fmt.Sprintf("stylist %s does not belong to branch %s", serviceItem.StylistID, booking.BranchID))
 		}
 
-		endTime := serviceItem.StartTime.Add(time.Duration(serviceInfo.Duration) * time.Minute)
-		
-		available, err := s.checkAvailabilityWithBuffer(ctx, serviceItem.StylistID, serviceItem.StartTime, endTime, branchConfig.BufferTimeMinutes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check availability: %w", err)
-		}
-		if !available {
-			return nil, fmt.Errorf("stylist %s is not available at %s", serviceItem.StylistID, serviceItem.StartTime.Format("2006-01-02 15:04"))
+		if err := s.validateStylistOffersService(ctx, booking.SalonID, serviceItem.StylistID, serviceItem.ServiceID); err != nil {
+			return nil, err
 		}
 
+		// Availability is checked when the new services are saved, since the
+		// booking's current services must not count as conflicts
+		endTime := serviceItem.StartTime.Add(time.Duration(serviceInfo.Duration) * time.Minute)
+
 		bookingService := model.BookingService{
-			ID:        uuid.New(),
-			BookingID: request.BookingID,
-			ServiceID: serviceItem.ServiceID,
-			StylistID: serviceItem.StylistID,
-			StartTime: serviceItem.StartTime,
-			EndTime:   endTime,
-			Price:     serviceInfo.Price,
+			ID:                uuid.New(),
+			BookingID:         request.BookingID,
+			ServiceID:         serviceItem.ServiceID,
+			StylistID:         serviceItem.StylistID,
+			StartTime:         serviceItem.StartTime,
+			EndTime:           endTime,
+			Price:             serviceInfo.Price,
+			BeneficiaryName:   beneficiaries[i].name,
+			BeneficiaryUserID: beneficiaries[i].userID,
 		}
 
 		newBookingServices = append(newBookingServices, bookingService)
-		totalAmount += serviceInfo.Price
+		totalAmount = money.Sum(totalAmount, serviceInfo.Price)
+		if serviceInfo.TaxExempt {
+			exemptAmount = money.Sum(exemptAmount, serviceInfo.Price)
+		}
 	}
 
-	// Recalculate totals
-	gst := totalAmount * (branchConfig.GSTPercentage / 100)
-	finalTotal := totalAmount + branchConfig.BookingFeeAmount + gst
+	// Recalculate totals, keeping the promo code redeemed at booking time
+	discount := s.rescheduledDiscount(ctx, booking, totalAmount)
+	totals := calculateTotals(totalAmount, exemptAmount, discount, branchConfig)
+	gst, finalTotal := totals.GST, totals.Total
+
+	// A paid booking has paid its current total; payment is only touched when
+	// the total changes
+	previousTotal := booking.TotalAmount
+	var paymentDelta float64
+	if booking.PaymentStatus == model.PaymentStatusPaid {
+		paymentDelta = money.Sub(finalTotal, previousTotal)
+	}
+
+	// The balance is charged before saving so the booking is never left
+	// underpaid without a payment to settle it; an unused charge just expires
+	var adjustment *model.PaymentAdjustment
+	if paymentDelta > 0 {
+		payment, err := s.initiateBalanceCharge(ctx, booking, paymentDelta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initiate balance charge: %w", err)
+		}
+		balancePaymentID := payment.PaymentID.String()
+		booking.BalancePaymentID = &balancePaymentID
+		booking.PaymentStatus = model.PaymentStatusPending
+		adjustment = &model.PaymentAdjustment{
+			Type:            model.PaymentAdjustmentCharge,
+			Amount:          paymentDelta,
+			PaymentID:       &balancePaymentID,
+			Status:          payment.Status,
+			GatewayResponse: payment.GatewayResponse,
+		}
+	}
 
 	// Update booking
 	booking.Status = model.BookingStatusRescheduled
 	booking.TotalAmount = finalTotal
 	booking.GST = gst
-
-	if err := s.repo.Update(ctx, booking); err != nil {
-		return nil, fmt.Errorf("failed to update booking: %w", err)
-	}
-
-	// Create new booking services
-	for i := range newBookingServices {
-		if err := s.repo.CreateBookingService(ctx, &newBookingServices[i]); err != nil {
-			return nil, fmt.Errorf("failed to create new booking service: %w", err)
+	booking.DiscountAmount = discount
+	booking.Taxes = totals.Taxes
+
+	// Replace services and update booking atomically, re-checking availability under lock
+	buffer := time.Duration(branchConfig.BufferTimeMinutes) * time.Minute
+	if err := s.repo.RescheduleWithServices(ctx, booking, newBookingServices, buffer); err != nil {
+		if errors.Is(err, repository.ErrSlotUnavailable) {
+			return nil, bookingConflict("%v", err)
 		}
+		return nil, bookingUpdateError("update booking", err)
 	}
 
 	// Create history entry
@@ -598,12 +1354,25 @@ func (s *bookingService) RescheduleBooking(ctx context.Context, request *Resched
 		log.Warn().Err(err).Msg("Failed to create booking history")
 	}
 
+	if paymentDelta < 0 {
+		adjustment = s.refundRescheduleDifference(ctx, booking, -paymentDelta, request.Reason)
+	}
+	if adjustment != nil {
+		s.recordPaymentAdjustment(ctx, request.BookingID, &request.UserID, previousTotal, finalTotal, adjustment)
+		booking.PaymentAdjustment = adjustment
+	}
+
 	// Load updated services
 	booking.Services = make([]model.BookingService, len(newBookingServices))
 	for i, service := range newBookingServices {
 		booking.Services[i] = service
 	}
 
+	// Send reschedule notifications asynchronously and offer the vacated slots to the waitlist
+	s.goBackground(func() { s.sendBookingRescheduleNotifications(context.WithoutCancel(ctx), booking, request.Reason) })
+	slots := freedSlots(request.BookingID, previousServices, newBookingServices)
+	s.goBackground(func() { s.notifyWaitlist(context.WithoutCancel(ctx), slots) })
+
 	log.Info().
 		Str("booking_id", request.BookingID.String()).
 		Str("user_id", request.UserID.String()).
@@ -614,28 +1383,126 @@ func (s *bookingService) RescheduleBooking(ctx context.Context, request *Resched
 
 // GetStylistAvailability generates available time slots for a stylist
 func (s *bookingService) GetStylistAvailability(ctx context.Context, salonID, stylistID uuid.UUID, date time.Time) ([]*model.TimeSlot, error) {
+	// Get the stylist's branch to honour branch opening hours and holidays
+	stylist, err := s.externalService.GetStylist(ctx, salonID, stylistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stylist: %w", err)
+	}
+
+	branch, err := s.externalService.GetBranch(ctx, salonID, stylist.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	// The requested date is a calendar day in the branch's time zone
+	loc := branchLocation(branch)
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+
+	dayHours, err := s.branchHoursOn(ctx, salonID, branch, date)
+	if err != nil {
+		return nil, err
+	}
+	if !dayHours.open {
+		return []*model.TimeSlot{}, nil
+	}
+
+	branchConfig, err := s.getBranchConfigWithDefaults(ctx, stylist.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch configuration: %w", err)
+	}
+
 	// Get stylist schedule from salon-service
 	schedule, err := s.externalService.GetStylistSchedule(ctx, salonID, stylistID, date)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stylist schedule: %w", err)
 	}
+	if schedule.OnLeave {
+		return []*model.TimeSlot{}, nil
+	}
 
 	// Get stylist's existing bookings for the date
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	startOfDay := date
+	endOfDay := startOfDay.AddDate(0, 0, 1)
 	
 	existingBookings, err := s.repo.GetStylistBookings(ctx, stylistID, startOfDay, endOfDay)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get existing bookings: %w", err)
 	}
 
-	// Generate available slots (30-minute intervals)
-	var availableSlots []*model.TimeSlot
-	slotDuration := 30 * time.Minute
+	return generateSlots(schedule, existingBookings, dayHours, branchConfig.GetSlotDuration()), nil
+}
+
+// GetBranchAvailability generates available time slots for every active
+// stylist of a branch. Existing bookings for all stylists are loaded in one query.
+func (s *bookingService) GetBranchAvailability(ctx context.Context, salonID, branchID uuid.UUID, date time.Time) ([]*model.StylistAvailability, error) {
+	branch, err := s.externalService.GetBranch(ctx, salonID, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	stylists, err := s.externalService.GetBranchStylists(ctx, salonID, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch stylists: %w", err)
+	}
+
+	availability := make([]*model.StylistAvailability, 0, len(stylists))
+	for _, stylist := range stylists {
+		availability = append(availability, &model.StylistAvailability{
+			StylistID:   stylist.ID,
+			StylistName: stylist.Name,
+			Slots:       []*model.TimeSlot{},
+		})
+	}
+
+	loc := branchLocation(branch)
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+
+	dayHours, err := s.branchHoursOn(ctx, salonID, branch, date)
+	if err != nil {
+		return nil, err
+	}
+	if !dayHours.open || len(stylists) == 0 {
+		return availability, nil
+	}
+
+	branchConfig, err := s.getBranchConfigWithDefaults(ctx, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch configuration: %w", err)
+	}
+
+	stylistIDs := make([]uuid.UUID, len(stylists))
+	for i, stylist := range stylists {
+		stylistIDs[i] = stylist.ID
+	}
+
+	existingBookings, err := s.repo.GetStylistsBookings(ctx, stylistIDs, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing bookings: %w", err)
+	}
+
+	slotDuration := branchConfig.GetSlotDuration()
+	for _, stylistAvailability := range availability {
+		schedule, err := s.externalService.GetStylistSchedule(ctx, salonID, stylistAvailability.StylistID, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schedule for stylist %s: %w", stylistAvailability.StylistID, err)
+		}
+		stylistAvailability.Slots = generateSlots(schedule, existingBookings[stylistAvailability.StylistID], dayHours, slotDuration)
+	}
+
+	return availability, nil
+}
+
+// generateSlots splits a stylist's working hours into future slots of
+// slotDuration, skipping slots that overlap bookings, breaks or branch closing
+func generateSlots(schedule *StylistSchedule, existingBookings []*model.BookingService, dayHours branchDayHours, slotDuration time.Duration) []*model.TimeSlot {
+	availableSlots := []*model.TimeSlot{}
+	loc := dayHours.loc
+
+	for _, window := range dayHours.clip(schedule.WorkingHours) {
+		current := window.Start
+		windowEnd := window.End
 
-	for _, workingHour := range schedule.WorkingHours {
-		current := workingHour.StartTime
-		for current.Add(slotDuration).Before(workingHour.EndTime) || current.Add(slotDuration).Equal(workingHour.EndTime) {
+		for current.Add(slotDuration).Before(windowEnd) || current.Add(slotDuration).Equal(windowEnd) {
 			slotEnd := current.Add(slotDuration)
 			
 			// Check if slot conflicts with existing bookings
@@ -660,8 +1527,8 @@ func (s *bookingService) GetStylistAvailability(ctx context.Context, salonID, st
 			// Only include future slots (not past)
 			if available && current.After(time.Now()) {
 				availableSlots = append(availableSlots, &model.TimeSlot{
-					StartTime: current,
-					EndTime:   slotEnd,
+					StartTime: current.In(loc),
+					EndTime:   slotEnd.In(loc),
 					Available: true,
 				})
 			}
@@ -670,7 +1537,7 @@ func (s *bookingService) GetStylistAvailability(ctx context.Context, salonID, st
 		}
 	}
 
-	return availableSlots, nil
+	return availableSlots
 }
 
 // CalculateBookingSummary calculates pricing for a booking
@@ -681,123 +1548,265 @@ func (s *bookingService) CalculateBookingSummary(ctx context.Context, request *B
 		return nil, fmt.Errorf("failed to get branch configuration: %w", err)
 	}
 
-	var subtotal float64
+	var subtotal, exempt float64
+	lineItems := make([]model.BookingSummaryLineItem, 0, len(request.Services))
 
-	// Calculate subtotal from services
+	// Calculate subtotal from services, itemizing each one
 	for _, serviceItem := range request.Services {
 		serviceInfo, err := s.externalService.GetService(ctx, request.SalonID, serviceItem.ServiceID)
 		if err != nil {
 			return nil, fmt.Errorf("invalid service %s: %w", serviceItem.ServiceID, err)
 		}
-		subtotal += serviceInfo.Price
+		subtotal = money.Sum(subtotal, serviceInfo.Price)
+		if serviceInfo.TaxExempt {
+			exempt = money.Sum(exempt, serviceInfo.Price)
+		}
+
+		lineItem := model.BookingSummaryLineItem{
+			ServiceID:         serviceItem.ServiceID,
+			ServiceName:       serviceInfo.Name,
+			StylistID:         serviceItem.StylistID,
+			DurationMinutes:   serviceInfo.Duration,
+			Price:             serviceInfo.Price,
+			TaxExempt:         serviceInfo.TaxExempt,
+			BeneficiaryName:   serviceItem.BeneficiaryName,
+			BeneficiaryUserID: serviceItem.BeneficiaryUserID,
+		}
+		if !serviceItem.StartTime.IsZero() {
+			startTime := serviceItem.StartTime
+			lineItem.StartTime = &startTime
+		}
+		if serviceItem.StylistID != uuid.Nil {
+			stylist, err := s.externalService.GetStylist(ctx, request.SalonID, serviceItem.StylistID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stylist %s: %w", serviceItem.StylistID, err)
+			}
+			lineItem.StylistName = stylist.Name
+		}
+		lineItems = append(lineItems, lineItem)
+	}
+
+	// Apply any promo code before tax
+	promo, discount, err := s.resolvePromoCode(ctx, request.PromoCode, request.SalonID, subtotal)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate GST and total
-	gst := subtotal * (branchConfig.GSTPercentage / 100)
-	total := subtotal + branchConfig.BookingFeeAmount + gst
+	// Calculate GST and total; GST applies to discounted taxable services, not
+	// exempt services or the booking fee
+	totals := calculateTotals(subtotal, exempt, discount, branchConfig)
+	gst, total := totals.GST, totals.Total
 
-	return &model.BookingSummary{
+	summary := &model.BookingSummary{
 		Subtotal:   subtotal,
+		Discount:   discount,
 		BookingFee: branchConfig.BookingFeeAmount,
 		GST:        gst,
 		Total:      total,
-	}, nil
+		LineItems:  lineItems,
+		Taxes:      totals.Taxes,
+	}
+	if promo != nil {
+		summary.PromoCode = &promo.Code
+	}
+
+	return summary, nil
+}
+
+// reminderBatchSize limits how many reminders are processed per run
+const reminderBatchSize = 100
+
+// SendDueReminders sends reminders for bookings that reached a reminder threshold and
+// returns the number of bookings notified. Each threshold is recorded before sending so
+// a reminder goes out at most once, even across restarts or multiple instances.
+func (s *bookingService) SendDueReminders(ctx context.Context) (int, error) {
+	reminders, err := s.repo.GetDueReminders(ctx, s.config.DefaultReminderLeadTimes, reminderBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due reminders: %w", err)
+	}
+
+	// A booking can pass several thresholds at once (e.g. booked 1h before the
+	// appointment); record all of them but send a single reminder
+	claimed := make(map[uuid.UUID]int)
+	var bookingIDs []uuid.UUID
+	for _, reminder := range reminders {
+		ok, err := s.repo.MarkReminderSent(ctx, reminder.BookingID, reminder.ThresholdMinutes)
+		if err != nil {
+			log.Error().Err(err).Str("booking_id", reminder.BookingID.String()).Msg("Failed to record booking reminder")
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		threshold, exists := claimed[reminder.BookingID]
+		if !exists {
+			bookingIDs = append(bookingIDs, reminder.BookingID)
+		}
+		if !exists || reminder.ThresholdMinutes < threshold {
+			claimed[reminder.BookingID] = reminder.ThresholdMinutes
+		}
+	}
+
+	for _, bookingID := range bookingIDs {
+		booking, err := s.repo.GetByID(ctx, bookingID)
+		if err != nil {
+			log.Error().Err(err).Str("booking_id", bookingID.String()).Msg("Failed to load booking for reminder")
+			continue
+		}
+		s.sendBookingReminderNotifications(ctx, booking, claimed[bookingID])
+	}
+
+	return len(bookingIDs), nil
 }
 
 // sendBookingConfirmationNotifications sends confirmation notifications for a booking
 func (s *bookingService) sendBookingConfirmationNotifications(ctx context.Context, booking *model.Booking) {
-	// Get user details
-	user, err := s.externalService.ValidateUser(ctx, booking.UserID)
+	bookingEvent, err := s.buildBookingEvent(ctx, EventBookingConfirmed, booking)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get user details for notification")
+		log.Error().Err(err).Msg("Failed to prepare booking confirmation event")
 		return
 	}
+	bookingEvent.Data["total_amount"] = booking.TotalAmount
+	bookingEvent.Data["payment_id"] = booking.PaymentID
 
-	// Get salon details
-	salon, err := s.externalService.GetSalon(ctx, booking.SalonID)
+	s.emitBookingEvent(ctx, bookingEvent)
+}
+
+// sendBookingCancellationNotifications sends cancellation notifications for a booking
+func (s *bookingService) sendBookingCancellationNotifications(ctx context.Context, booking *model.Booking, reason string) {
+	bookingEvent, err := s.buildBookingEvent(ctx, EventBookingCancelled, booking)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get salon details for notification")
+		log.Error().Err(err).Msg("Failed to prepare booking cancellation event")
 		return
 	}
+	bookingEvent.Data["reason"] = reason
 
-	// Get branch details
-	branch, err := s.externalService.GetBranch(ctx, booking.SalonID, booking.BranchID)
+	s.emitBookingEvent(ctx, bookingEvent)
+}
+
+// sendBookingRescheduleNotifications sends reschedule notifications for a booking
+func (s *bookingService) sendBookingRescheduleNotifications(ctx context.Context, booking *model.Booking, reason string) {
+	bookingEvent, err := s.buildBookingEvent(ctx, EventBookingRescheduled, booking)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get branch details for notification")
+		log.Error().Err(err).Msg("Failed to prepare booking reschedule event")
 		return
 	}
+	bookingEvent.Data["total_amount"] = booking.TotalAmount
+	bookingEvent.Data["reason"] = reason
 
-	// Prepare booking event
-	bookingEvent := &BookingEvent{
-		Type:      "booking.confirmed",
-		BookingID: booking.ID,
-		UserID:    booking.UserID,
-		SalonID:   booking.SalonID,
-		BranchID:  booking.BranchID,
-		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"user_name":     user.Name,
-			"user_email":    user.Email,
-			"user_phone":    user.Phone,
-			"salon_name":    salon.Name,
-			"branch_name":   branch.Name,
-			"total_amount":  booking.TotalAmount,
-			"booking_time":  booking.CreatedAt.Format("2006-01-02 15:04"),
-			"payment_id":    booking.PaymentID,
-		},
+	s.emitBookingEvent(ctx, bookingEvent)
+}
+
+// sendBookingReminderNotifications sends reminder notifications for an upcoming booking
+func (s *bookingService) sendBookingReminderNotifications(ctx context.Context, booking *model.Booking, thresholdMinutes int) {
+	bookingEvent, err := s.buildBookingEvent(ctx, EventBookingReminder, booking)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to prepare booking reminder event")
+		return
+	}
+	bookingEvent.Data["threshold_minutes"] = thresholdMinutes
+
+	s.emitBookingEvent(ctx, bookingEvent)
+}
+
+// buildBookingEvent loads user, salon and branch details for a booking event
+func (s *bookingService) buildBookingEvent(ctx context.Context, eventType string, booking *model.Booking) (*BookingEvent, error) {
+	bookingTime := booking.CreatedAt
+	if start := booking.GetEarliestStartTime(); start != nil {
+		bookingTime = *start
 	}
 
-	// Send notifications
-	if err := s.notificationClient.SendBookingConfirmationNotification(ctx, bookingEvent); err != nil {
-		log.Error().Err(err).Msg("Failed to send booking confirmation notifications")
+	bookingEvent, err := s.newBookingEvent(ctx, eventType, booking.ID, booking.UserID, booking.SalonID, booking.BranchID, bookingTime)
+	if err != nil {
+		return nil, err
 	}
+	bookingEvent.Data["booking_id"] = booking.ID.String()
+
+	return bookingEvent, nil
 }
 
-// sendBookingCancellationNotifications sends cancellation notifications for a booking
-func (s *bookingService) sendBookingCancellationNotifications(ctx context.Context, booking *model.Booking, reason string) {
+// newBookingEvent builds an event addressed to userID, loading their contact
+// details and the salon and branch names. bookingID keys the event; it is not
+// added to the event data since the recipient may not own that booking.
+func (s *bookingService) newBookingEvent(ctx context.Context, eventType string, bookingID, userID, salonID, branchID uuid.UUID, bookingTime time.Time) (*BookingEvent, error) {
 	// Get user details
-	user, err := s.externalService.ValidateUser(ctx, booking.UserID)
+	user, err := s.externalService.ValidateUser(ctx, userID)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get user details for notification")
-		return
+		return nil, fmt.Errorf("failed to get user details: %w", err)
 	}
 
 	// Get salon details
-	salon, err := s.externalService.GetSalon(ctx, booking.SalonID)
+	salon, err := s.externalService.GetSalon(ctx, salonID)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get salon details for notification")
-		return
+		return nil, fmt.Errorf("failed to get salon details: %w", err)
 	}
 
 	// Get branch details
-	branch, err := s.externalService.GetBranch(ctx, booking.SalonID, booking.BranchID)
+	branch, err := s.externalService.GetBranch(ctx, salonID, branchID)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get branch details for notification")
-		return
+		return nil, fmt.Errorf("failed to get branch details: %w", err)
 	}
 
-	// Prepare booking event
-	bookingEvent := &BookingEvent{
-		Type:      "booking.cancelled",
-		BookingID: booking.ID,
-		UserID:    booking.UserID,
-		SalonID:   booking.SalonID,
-		BranchID:  booking.BranchID,
+	// Times are shown in the branch's wall-clock time
+	loc := branchLocation(branch)
+
+	// Only email addresses the user has verified receive notifications
+	userEmail := ""
+	if user.EmailVerified {
+		userEmail = user.Email
+	}
+
+	return &BookingEvent{
+		Type:      eventType,
+		BookingID: bookingID,
+		UserID:    userID,
+		SalonID:   salonID,
+		BranchID:  branchID,
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
+			"user_id":      userID.String(),
 			"user_name":    user.Name,
-			"user_email":   user.Email,
+			"user_email":   userEmail,
 			"user_phone":   user.Phone,
 			"salon_name":   salon.Name,
 			"branch_name":  branch.Name,
-			"booking_time": booking.CreatedAt.Format("2006-01-02 15:04"),
-			"reason":       reason,
+			"booking_time": bookingTime.In(loc).Format("2006-01-02 15:04"),
+			"timezone":     loc.String(),
 		},
+	}, nil
+}
+
+// emitBookingEvent publishes a booking event to the message broker, or calls the
+// notification service over HTTP when no publisher is configured (local dev)
+func (s *bookingService) emitBookingEvent(ctx context.Context, bookingEvent *BookingEvent) {
+	if s.eventPublisher != nil {
+		if err := s.eventPublisher.Publish(ctx, bookingEvent); err != nil {
+			log.Error().Err(err).
+				Str("event_type", bookingEvent.Type).
+				Str("booking_id", bookingEvent.BookingID.String()).
+				Msg("Failed to publish booking event")
+		}
+		return
 	}
 
-	// Send notifications
-	if err := s.notificationClient.SendBookingCancellationNotification(ctx, bookingEvent); err != nil {
-		log.Error().Err(err).Msg("Failed to send booking cancellation notifications")
+	var err error
+	switch bookingEvent.Type {
+	case EventBookingConfirmed:
+		err = s.notificationClient.SendBookingConfirmationNotification(ctx, bookingEvent)
+	case EventBookingCancelled:
+		err = s.notificationClient.SendBookingCancellationNotification(ctx, bookingEvent)
+	case EventBookingRescheduled:
+		err = s.notificationClient.SendBookingRescheduleNotification(ctx, bookingEvent)
+	case EventBookingReminder:
+		err = s.notificationClient.SendBookingReminderNotification(ctx, bookingEvent)
+	case EventBookingSlotFreed:
+		err = s.notificationClient.SendSlotFreedNotification(ctx, bookingEvent)
+	case EventBookingRecurrenceSkipped:
+		err = s.notificationClient.SendRecurrenceSkippedNotification(ctx, bookingEvent)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("event_type", bookingEvent.Type).Msg("Failed to send booking notifications")
 	}
 }
 
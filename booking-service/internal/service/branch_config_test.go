@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+func TestUpdateBranchConfigurationVersion(t *testing.T) {
+	branchID := uuid.New()
+	stored := func() *model.BranchConfiguration {
+		return &model.BranchConfiguration{BranchID: branchID, BufferTimeMinutes: 10, SlotIntervalMinutes: 30, Version: 3}
+	}
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name         string
+		version      int
+		concurrent   bool
+		wantConflict bool
+		wantVersion  int
+		wantBuffer   int
+	}{
+		{name: "current version", version: 3, wantVersion: 4, wantBuffer: 15},
+		{name: "stale version", version: 2, wantConflict: true, wantVersion: 3, wantBuffer: 10},
+		{name: "newer version than stored", version: 4, wantConflict: true, wantVersion: 3, wantBuffer: 10},
+		// Another update lands between the read and the write
+		{name: "concurrent update", version: 3, concurrent: true, wantConflict: true, wantVersion: 4, wantBuffer: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{branchConfig: stored()}
+			if tt.concurrent {
+				repo.beforeConfigUpdate = func(current *model.BranchConfiguration) {
+					current.BufferTimeMinutes = 20
+					current.Version++
+				}
+			}
+			s := newTestService(repo, &fakeExternalService{})
+
+			config, err := s.UpdateBranchConfiguration(context.Background(), branchID, &UpdateBranchConfigurationRequest{
+				Version:           intPtr(tt.version),
+				BufferTimeMinutes: intPtr(15),
+			})
+
+			if tt.wantConflict {
+				var conflict *sharederrors.ConflictError
+				if !errors.As(err, &conflict) {
+					t.Fatalf("got %v, want a conflict error", err)
+				}
+				if code := sharederrors.MapToAPIError(err).Code; code != http.StatusConflict {
+					t.Errorf("status = %d, want %d", code, http.StatusConflict)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("UpdateBranchConfiguration: %v", err)
+				}
+				if config.Version != tt.wantVersion {
+					t.Errorf("returned version = %d, want %d", config.Version, tt.wantVersion)
+				}
+			}
+
+			if repo.branchConfig.Version != tt.wantVersion || repo.branchConfig.BufferTimeMinutes != tt.wantBuffer {
+				t.Errorf("stored version %d with buffer %d, want version %d with buffer %d",
+					repo.branchConfig.Version, repo.branchConfig.BufferTimeMinutes, tt.wantVersion, tt.wantBuffer)
+			}
+		})
+	}
+}
+
+func TestUpdateBranchConfigurationRequiresVersion(t *testing.T) {
+	s := newTestService(&fakeRepository{}, &fakeExternalService{})
+	_, err := s.UpdateBranchConfiguration(context.Background(), uuid.New(), &UpdateBranchConfigurationRequest{})
+	if code := sharederrors.MapToAPIError(err).Code; code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d (err: %v)", code, http.StatusBadRequest, err)
+	}
+}
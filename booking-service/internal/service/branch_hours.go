@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EricsAntony/salon/salon-shared/hours"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Branch opening hours are resolved with hours.Resolve from the working hours,
+// date overrides and holidays salon-service stores on the branch and its salon,
+// so both services agree on when a branch is open (see the hours package for
+// the stored format and precedence).
+
+// branchLocation returns the branch time zone, falling back to UTC when the
+// branch has no time zone or an unknown one
+func branchLocation(branch *BranchInfo) *time.Location {
+	if branch == nil || branch.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(branch.Timezone)
+	if err != nil {
+		log.Warn().Err(err).Str("branch_id", branch.ID.String()).Str("timezone", branch.Timezone).Msg("Invalid branch timezone, using UTC")
+		return time.UTC
+	}
+
+	return loc
+}
+
+// branchDayHours is a branch's opening windows on one day. When restricted is
+// false neither the branch nor the salon has configured hours and stylist
+// schedules apply as-is.
+type branchDayHours struct {
+	open       bool
+	restricted bool
+	windows    []hours.Window
+	loc        *time.Location
+}
+
+// branchHoursOn resolves the branch hours on date, a midnight in the branch
+// time zone, layering the branch over the salon defaults
+func (s *bookingService) branchHoursOn(ctx context.Context, salonID uuid.UUID, branch *BranchInfo, date time.Time) (branchDayHours, error) {
+	salon, err := s.externalService.GetSalon(ctx, salonID)
+	if err != nil {
+		return branchDayHours{}, fmt.Errorf("failed to get salon: %w", err)
+	}
+
+	effective := hours.Resolve(date,
+		hours.Source{WorkingHours: branch.WorkingHours, Holidays: branch.Holidays},
+		hours.Source{WorkingHours: salon.WorkingHours, Holidays: salon.Holidays},
+	)
+	return branchDayHours{
+		open:       effective.Open,
+		restricted: effective.Restricted,
+		windows:    effective.Windows(date),
+		loc:        date.Location(),
+	}, nil
+}
+
+// clip returns the parts of the stylist's working hours during which the
+// branch is open
+func (d branchDayHours) clip(workingHours []WorkingHour) []hours.Window {
+	var clipped []hours.Window
+	for _, workingHour := range workingHours {
+		if !d.restricted {
+			clipped = append(clipped, hours.Window{Start: workingHour.StartTime, End: workingHour.EndTime})
+			continue
+		}
+		for _, open := range d.windows {
+			start, end := workingHour.StartTime, workingHour.EndTime
+			if start.Before(open.Start) {
+				start = open.Start
+			}
+			if end.After(open.End) {
+				end = open.End
+			}
+			if end.After(start) {
+				clipped = append(clipped, hours.Window{Start: start, End: end})
+			}
+		}
+	}
+	return clipped
+}
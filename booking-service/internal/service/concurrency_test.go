@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+func TestConcurrentCancellationsConflict(t *testing.T) {
+	userID, salonID, branchID, stylistID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	booking := &model.Booking{
+		ID:            uuid.New(),
+		UserID:        userID,
+		SalonID:       salonID,
+		BranchID:      branchID,
+		Status:        model.BookingStatusConfirmed,
+		PaymentStatus: model.PaymentStatusPending,
+		Version:       1,
+		Services: []model.BookingService{{
+			ID:        uuid.New(),
+			StylistID: stylistID,
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+		}},
+	}
+
+	// Hold both writers until each has read version 1, so neither sees the
+	// other's cancellation before writing
+	var read sync.WaitGroup
+	read.Add(2)
+	repo := &fakeRepository{
+		bookings:     map[uuid.UUID]*model.Booking{booking.ID: booking},
+		branchConfig: &model.BranchConfiguration{BranchID: branchID, CancellationCutoffHours: 24},
+		afterGetByID: func() {
+			read.Done()
+			read.Wait()
+		},
+	}
+	external := &fakeExternalService{
+		salon:  &SalonInfo{ID: salonID},
+		branch: &BranchInfo{ID: branchID, SalonID: salonID},
+	}
+	publisher := &fakeEventPublisher{}
+	s := newTestService(repo, external)
+	s.eventPublisher = publisher
+
+	ctx := context.Background()
+	errs := make([]error, 2)
+	var writers sync.WaitGroup
+	for i := range errs {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			errs[i] = s.CancelBooking(ctx, booking.ID, userID, "changed plans")
+		}(i)
+	}
+	writers.Wait()
+	repo.afterGetByID = nil
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("background work did not finish: %v", err)
+	}
+
+	var succeeded int
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var conflict *sharederrors.ConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("losing writer failed with %v, want a conflict", err)
+		}
+		if code := sharederrors.MapToAPIError(err).Code; code != http.StatusConflict {
+			t.Errorf("losing writer status = %d, want %d (err: %v)", code, http.StatusConflict, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d cancellations succeeded, want exactly 1: %v", succeeded, errs)
+	}
+
+	stored, err := repo.GetByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Status != model.BookingStatusCanceled || stored.Version != 2 {
+		t.Errorf("stored booking is %s at version %d, want %s at version 2", stored.Status, stored.Version, model.BookingStatusCanceled)
+	}
+	if len(repo.history) != 1 {
+		t.Errorf("recorded %d history entries, want 1", len(repo.history))
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("published %d events, want 1", len(publisher.events))
+	}
+}
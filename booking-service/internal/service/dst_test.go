@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// In 2030 America/New_York springs forward on 10 March (02:00 EST becomes
+// 03:00 EDT) and falls back on 3 November (02:00 EDT becomes 01:00 EST)
+
+func TestInLocationKeepsWallClockAcrossDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	booking := &model.Booking{Timezone: "America/New_York"}
+	if loc := booking.Location(); loc.String() != newYork.String() {
+		t.Fatalf("booking location = %s, want %s", loc, newYork)
+	}
+
+	tests := []struct {
+		name       string
+		start      time.Time
+		wantLocal  string
+		wantOffset int
+	}{
+		{name: "day before spring forward", start: time.Date(2030, 3, 9, 15, 0, 0, 0, time.UTC), wantLocal: "2030-03-09 10:00", wantOffset: -5 * 3600},
+		{name: "day after spring forward", start: time.Date(2030, 3, 11, 14, 0, 0, 0, time.UTC), wantLocal: "2030-03-11 10:00", wantOffset: -4 * 3600},
+		{name: "first 01:30 on fall back", start: time.Date(2030, 11, 3, 5, 30, 0, 0, time.UTC), wantLocal: "2030-11-03 01:30", wantOffset: -4 * 3600},
+		{name: "second 01:30 on fall back", start: time.Date(2030, 11, 3, 6, 30, 0, 0, time.UTC), wantLocal: "2030-11-03 01:30", wantOffset: -5 * 3600},
+		{name: "day after fall back", start: time.Date(2030, 11, 4, 15, 0, 0, 0, time.UTC), wantLocal: "2030-11-04 10:00", wantOffset: -5 * 3600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := []InitiateBookingServiceItem{{StartTime: tt.start}}
+			inLocation(services, booking.Location())
+
+			got := services[0].StartTime
+			if !got.Equal(tt.start) {
+				t.Fatalf("start moved from %s to %s", tt.start, got)
+			}
+			if local := got.Format("2006-01-02 15:04"); local != tt.wantLocal {
+				t.Errorf("wall clock = %s, want %s", local, tt.wantLocal)
+			}
+			if _, offset := got.Zone(); offset != tt.wantOffset {
+				t.Errorf("offset = %ds, want %ds", offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestGetStylistAvailabilityAcrossDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		date time.Time
+		// working hours in New York wall-clock time
+		fromHour, toHour int
+		existing         []*model.BookingService
+		wantStarts       []string
+		wantLookup       [2]time.Time
+	}{
+		{
+			name:     "spring forward skips 02:00",
+			date:     time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC),
+			fromHour: 1, toHour: 4,
+			wantStarts: []string{"2030-03-10T01:00:00-05:00", "2030-03-10T03:00:00-04:00"},
+			// The local day is 23 hours long
+			wantLookup: [2]time.Time{time.Date(2030, 3, 10, 5, 0, 0, 0, time.UTC), time.Date(2030, 3, 11, 4, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "fall back repeats 01:00",
+			date:     time.Date(2030, 11, 3, 0, 0, 0, 0, time.UTC),
+			fromHour: 0, toHour: 3,
+			wantStarts: []string{
+				"2030-11-03T00:00:00-04:00",
+				"2030-11-03T01:00:00-04:00",
+				"2030-11-03T01:00:00-05:00",
+				"2030-11-03T02:00:00-05:00",
+			},
+			// The local day is 25 hours long
+			wantLookup: [2]time.Time{time.Date(2030, 11, 3, 4, 0, 0, 0, time.UTC), time.Date(2030, 11, 4, 5, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "booking in the repeated hour only blocks its own 01:00",
+			date:     time.Date(2030, 11, 3, 0, 0, 0, 0, time.UTC),
+			fromHour: 0, toHour: 3,
+			// 01:00-02:00 EST, the second 01:00 of the day
+			existing: []*model.BookingService{{
+				StartTime: time.Date(2030, 11, 3, 6, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2030, 11, 3, 7, 0, 0, 0, time.UTC),
+			}},
+			wantStarts: []string{
+				"2030-11-03T00:00:00-04:00",
+				"2030-11-03T01:00:00-04:00",
+				"2030-11-03T02:00:00-05:00",
+			},
+			wantLookup: [2]time.Time{time.Date(2030, 11, 3, 4, 0, 0, 0, time.UTC), time.Date(2030, 11, 4, 5, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			salonID, branchID, stylistID := uuid.New(), uuid.New(), uuid.New()
+			external := &fakeExternalService{
+				salon:    &SalonInfo{ID: salonID},
+				branch:   &BranchInfo{ID: branchID, SalonID: salonID, Timezone: "America/New_York"},
+				stylists: map[uuid.UUID]*StylistInfo{stylistID: {ID: stylistID, BranchID: branchID}},
+				schedule: func(_ uuid.UUID, date time.Time) *StylistSchedule {
+					from := time.Date(date.Year(), date.Month(), date.Day(), tt.fromHour, 0, 0, 0, newYork)
+					to := time.Date(date.Year(), date.Month(), date.Day(), tt.toHour, 0, 0, 0, newYork)
+					return &StylistSchedule{StylistID: stylistID, Date: date, WorkingHours: []WorkingHour{{StartTime: from, EndTime: to}}}
+				},
+			}
+			for _, booking := range tt.existing {
+				booking.StylistID = stylistID
+			}
+			repo := &fakeRepository{
+				branchConfig:    &model.BranchConfiguration{BranchID: branchID, SlotIntervalMinutes: 60},
+				stylistBookings: tt.existing,
+			}
+			s := newTestService(repo, external)
+
+			slots, err := s.GetStylistAvailability(context.Background(), salonID, stylistID, tt.date)
+			if err != nil {
+				t.Fatalf("GetStylistAvailability: %v", err)
+			}
+
+			got := slotStarts(slots)
+			if len(got) != len(tt.wantStarts) {
+				t.Fatalf("slots start at %v, want %v", got, tt.wantStarts)
+			}
+			for i := range got {
+				if got[i] != tt.wantStarts[i] {
+					t.Errorf("slot %d starts %s, want %s", i, got[i], tt.wantStarts[i])
+				}
+				if length := slots[i].EndTime.Sub(slots[i].StartTime); length != time.Hour {
+					t.Errorf("slot %d lasts %s, want 1h", i, length)
+				}
+			}
+
+			if len(repo.bookingRanges) != 1 {
+				t.Fatalf("stylist bookings looked up %d times, want 1", len(repo.bookingRanges))
+			}
+			if from, to := repo.bookingRanges[0][0], repo.bookingRanges[0][1]; !from.Equal(tt.wantLookup[0]) || !to.Equal(tt.wantLookup[1]) {
+				t.Errorf("bookings looked up for %s to %s, want %s to %s", from.UTC(), to.UTC(), tt.wantLookup[0], tt.wantLookup[1])
+			}
+		})
+	}
+}
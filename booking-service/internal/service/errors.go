@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"booking-service/internal/repository"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+// The booking service reports client-facing failures with the shared error
+// types so handlers can map them to HTTP status codes:
+//
+//	sharederrors.ValidationErrors  -> 400
+//	*sharederrors.AuthError        -> 401
+//	sharederrors.ErrForbidden      -> 403
+//	*sharederrors.NotFoundError    -> 404
+//	*sharederrors.ConflictError    -> 409
+//
+// Any other error is internal and is reported to clients without its message.
+
+// errNotBookingOwner is returned when a user acts on another user's booking.
+// The user is authenticated but not allowed to act on it, so it maps to 403.
+var errNotBookingOwner = fmt.Errorf("user does not own this booking: %w", sharederrors.ErrForbidden)
+
+// bookingLookupError converts a failed booking lookup into a not found error
+// when the booking does not exist, and an internal error otherwise
+func bookingLookupError(bookingID uuid.UUID, err error) error {
+	if errors.Is(err, repository.ErrBookingNotFound) {
+		return sharederrors.NewNotFoundError("booking", bookingID.String())
+	}
+	return fmt.Errorf("failed to get booking: %w", err)
+}
+
+// branchConfigConflict reports an update made against a stale branch configuration
+func branchConfigConflict(currentVersion int) error {
+	return sharederrors.NewConflictError("branch configuration",
+		fmt.Sprintf("configuration was changed by another update (current version %d); reload and retry", currentVersion))
+}
+
+// bookingUpdateError converts a booking write rejected because the booking
+// changed since it was read into a conflict, so the client reloads and retries
+func bookingUpdateError(action string, err error) error {
+	if errors.Is(err, repository.ErrBookingVersionConflict) {
+		return bookingConflict("booking was changed by another request; reload and retry")
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// bookingConflict reports an operation the booking's current state does not allow
+func bookingConflict(format string, args ...interface{}) error {
+	return sharederrors.NewConflictError("booking", fmt.Sprintf(format, args...))
+}
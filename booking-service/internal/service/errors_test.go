@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+func TestActingOnAnotherUsersBookingIsForbidden(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	booking := &model.Booking{
+		ID:            uuid.New(),
+		UserID:        owner,
+		BranchID:      uuid.New(),
+		Status:        model.BookingStatusInitiated,
+		PaymentStatus: model.PaymentStatusPending,
+		Version:       1,
+	}
+	s := newTestService(&fakeRepository{bookings: map[uuid.UUID]*model.Booking{booking.ID: booking}}, &fakeExternalService{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		act  func() error
+	}{
+		{name: "cancel", act: func() error { return s.CancelBooking(ctx, booking.ID, other, "not mine") }},
+		{name: "abandon", act: func() error { return s.AbandonBooking(ctx, booking.ID, other) }},
+		{name: "reschedule", act: func() error {
+			_, err := s.RescheduleBooking(ctx, &RescheduleBookingRequest{
+				BookingID: booking.ID,
+				UserID:    other,
+				Services:  []InitiateBookingServiceItem{{StartTime: time.Now().Add(48 * time.Hour)}},
+			})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.act()
+			if err == nil {
+				t.Fatal("acting on another user's booking succeeded")
+			}
+			if code := sharederrors.MapToAPIError(err).Code; code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d (err: %v)", code, http.StatusForbidden, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Booking event types consumed by the notification service
+const (
+	EventBookingConfirmed   = "booking.confirmed"
+	EventBookingCancelled   = "booking.cancelled"
+	EventBookingRescheduled = "booking.rescheduled"
+	EventBookingReminder    = "booking.reminder"
+	// EventBookingSlotFreed is sent to a waitlisted user when a slot in their window opens
+	EventBookingSlotFreed = "booking.slot_freed"
+	// EventBookingRecurrenceSkipped tells a user an occurrence of their recurring booking could not be booked
+	EventBookingRecurrenceSkipped = "booking.recurrence_skipped"
+)
+
+// EventPublisher publishes booking events to the message broker
+type EventPublisher interface {
+	Publish(ctx context.Context, event *BookingEvent) error
+	Close() error
+}
+
+type kafkaEventPublisher struct {
+	writer *kafka.Writer
+	topic  string
+}
+
+// NewKafkaEventPublisher creates a publisher that writes booking events to a Kafka topic
+func NewKafkaEventPublisher(brokers []string, topic string) EventPublisher {
+	return &kafkaEventPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			WriteTimeout: 10 * time.Second,
+		},
+		topic: topic,
+	}
+}
+
+// Publish writes the event keyed by booking ID so events for a booking stay ordered
+func (p *kafkaEventPublisher) Publish(ctx context.Context, event *BookingEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal booking event: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.BookingID.String()),
+		Value: payload,
+		Time:  event.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish booking event: %w", err)
+	}
+
+	log.Info().
+		Str("event_type", event.Type).
+		Str("booking_id", event.BookingID.String()).
+		Str("topic", p.topic).
+		Msg("Booking event published")
+
+	return nil
+}
+
+// Close flushes pending messages and closes the writer
+func (p *kafkaEventPublisher) Close() error {
+	return p.writer.Close()
+}
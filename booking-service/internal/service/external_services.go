@@ -1,12 +1,15 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/EricsAntony/salon/salon-shared/auth"
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
 	"github.com/google/uuid"
 )
 
@@ -21,6 +24,9 @@ type ExternalService interface {
 // UserService interface for user-service integration
 type UserService interface {
 	ValidateUser(ctx context.Context, userID uuid.UUID) (*UserInfo, error)
+	// CreateGuestUser returns the user registered with the guest's phone
+	// number, creating a guest record for a walk-in when there is none
+	CreateGuestUser(ctx context.Context, guest *GuestCustomer) (*UserInfo, error)
 }
 
 // SalonService interface for salon-service integration
@@ -29,6 +35,7 @@ type SalonService interface {
 	GetBranch(ctx context.Context, salonID, branchID uuid.UUID) (*BranchInfo, error)
 	GetService(ctx context.Context, salonID, serviceID uuid.UUID) (*ServiceInfo, error)
 	GetStylist(ctx context.Context, salonID, stylistID uuid.UUID) (*StylistInfo, error)
+	GetBranchStylists(ctx context.Context, salonID, branchID uuid.UUID) ([]*StylistInfo, error)
 	GetStylistSchedule(ctx context.Context, salonID, stylistID uuid.UUID, date time.Time) (*StylistSchedule, error)
 	GetStylistServices(ctx context.Context, salonID, stylistID uuid.UUID) ([]*ServiceInfo, error)
 }
@@ -49,26 +56,40 @@ type NotificationService interface {
 
 // External service data structures
 type UserInfo struct {
-	ID    uuid.UUID `json:"id"`
-	Name  string    `json:"name"`
-	Email string    `json:"email"`
-	Phone string    `json:"phone"`
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Phone         string    `json:"phone"`
+	// IsGuest is set for customers who have not registered an account
+	IsGuest bool `json:"is_guest"`
+}
+
+// GuestCustomer identifies a walk-in or guest checkout customer who may not have an account
+type GuestCustomer struct {
+	Name        string  `json:"name"`
+	PhoneNumber string  `json:"phone_number"`
+	Email       *string `json:"email,omitempty"`
 }
 
 type SalonInfo struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Address     string    `json:"address"`
+	ID           uuid.UUID      `json:"id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Address      string         `json:"address"`
+	WorkingHours map[string]any `json:"working_hours,omitempty"`
+	Holidays     map[string]any `json:"holidays,omitempty"`
 }
 
 type BranchInfo struct {
-	ID       uuid.UUID `json:"id"`
-	SalonID  uuid.UUID `json:"salon_id"`
-	Name     string    `json:"name"`
-	Address  string    `json:"address"`
-	Phone    string    `json:"phone"`
-	Timezone string    `json:"timezone"`
+	ID           uuid.UUID      `json:"id"`
+	SalonID      uuid.UUID      `json:"salon_id"`
+	Name         string         `json:"name"`
+	Address      string         `json:"address"`
+	Phone        string         `json:"phone"`
+	Timezone     string         `json:"timezone"`
+	WorkingHours map[string]any `json:"working_hours,omitempty"`
+	Holidays     map[string]any `json:"holidays,omitempty"`
 }
 
 type ServiceInfo struct {
@@ -78,6 +99,7 @@ type ServiceInfo struct {
 	Duration    int       `json:"duration"` // in minutes
 	Price       float64   `json:"price"`
 	CategoryID  uuid.UUID `json:"category_id"`
+	TaxExempt   bool      `json:"tax_exempt"`
 }
 
 type StylistInfo struct {
@@ -93,6 +115,8 @@ type StylistSchedule struct {
 	Date         time.Time     `json:"date"`
 	WorkingHours []WorkingHour `json:"working_hours"`
 	Breaks       []BreakPeriod `json:"breaks"`
+	// OnLeave is set when the stylist has time off on the date; WorkingHours is then empty
+	OnLeave bool `json:"on_leave,omitempty"`
 }
 
 type WorkingHour struct {
@@ -148,25 +172,74 @@ type BookingNotification struct {
 type externalService struct {
 	userServiceURL  string
 	salonServiceURL string
-	httpClient      *http.Client
+	serviceToken    string
+	httpClient      *resilientClient
+
+	// Short-lived caches so price and staff changes propagate within the TTL
+	branches *lookupCache[BranchInfo]
+	services *lookupCache[ServiceInfo]
+	stylists *lookupCache[StylistInfo]
 }
 
-// NewExternalService creates a new external service client
-func NewExternalService(userServiceURL, salonServiceURL string) ExternalService {
+// NewExternalService creates a new external service client. serviceToken is
+// sent on calls made outside an authenticated request, e.g. from workers.
+// Branch, service and stylist lookups are cached for cacheTTL; zero disables caching.
+func NewExternalService(userServiceURL, salonServiceURL, serviceToken string, policy ResiliencePolicy, cacheTTL time.Duration) ExternalService {
 	return &externalService{
 		userServiceURL:  userServiceURL,
 		salonServiceURL: salonServiceURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		serviceToken:    serviceToken,
+		httpClient:      newResilientClient(policy),
+		branches:        newLookupCache[BranchInfo]("branch", cacheTTL),
+		services:        newLookupCache[ServiceInfo]("service", cacheTTL),
+		stylists:        newLookupCache[StylistInfo]("stylist", cacheTTL),
+	}
+}
+
+// lookupKey scopes cached salon-service entities by salon
+func lookupKey(salonID, id uuid.UUID) string {
+	return salonID.String() + ":" + id.String()
+}
+
+// newRequest creates an outgoing request carrying the caller's bearer token
+// from the context, falling back to the service account token
+func (e *externalService) newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := auth.AccessTokenFromContext(ctx)
+	if token == "" {
+		token = e.serviceToken
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// newServiceRequest builds a request authenticated with the service account
+// token only, for internal routes that reject end-user tokens
+func (e *externalService) newServiceRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.serviceToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.serviceToken)
 	}
+
+	return req, nil
 }
 
 // ValidateUser validates a user exists and returns user info
 func (e *externalService) ValidateUser(ctx context.Context, userID uuid.UUID) (*UserInfo, error) {
-	url := fmt.Sprintf("%s/user/%s", e.userServiceURL, userID)
+	url := fmt.Sprintf("%s/internal/users/%s", e.userServiceURL, userID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newServiceRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -178,7 +251,7 @@ func (e *externalService) ValidateUser(ctx context.Context, userID uuid.UUID) (*
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("user not found")
+		return nil, sharederrors.NewNotFoundError("user", userID.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -193,11 +266,55 @@ func (e *externalService) ValidateUser(ctx context.Context, userID uuid.UUID) (*
 	return &user, nil
 }
 
+// CreateGuestUser finds or creates the user for a walk-in customer
+func (e *externalService) CreateGuestUser(ctx context.Context, guest *GuestCustomer) (*UserInfo, error) {
+	url := fmt.Sprintf("%s/internal/users/guests", e.userServiceURL)
+
+	payload, err := json.Marshal(guest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode guest: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.serviceToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.serviceToken)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		var body sharederrors.ErrorResponse
+		message := "invalid guest details"
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Error != nil {
+			message = body.Error.Message
+		}
+		return nil, sharederrors.NewValidationError("guest", message)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+
+	var user UserInfo
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode user response: %w", err)
+	}
+
+	return &user, nil
+}
+
 // GetSalon retrieves salon information
 func (e *externalService) GetSalon(ctx context.Context, salonID uuid.UUID) (*SalonInfo, error) {
 	url := fmt.Sprintf("%s/salons/%s", e.salonServiceURL, salonID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -209,7 +326,7 @@ func (e *externalService) GetSalon(ctx context.Context, salonID uuid.UUID) (*Sal
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("salon not found")
+		return nil, sharederrors.NewNotFoundError("salon", salonID.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -226,9 +343,15 @@ func (e *externalService) GetSalon(ctx context.Context, salonID uuid.UUID) (*Sal
 
 // GetBranch retrieves branch information
 func (e *externalService) GetBranch(ctx context.Context, salonID, branchID uuid.UUID) (*BranchInfo, error) {
+	return e.branches.get(ctx, lookupKey(salonID, branchID), func() (*BranchInfo, error) {
+		return e.fetchBranch(ctx, salonID, branchID)
+	})
+}
+
+func (e *externalService) fetchBranch(ctx context.Context, salonID, branchID uuid.UUID) (*BranchInfo, error) {
 	url := fmt.Sprintf("%s/salons/%s/branches/%s", e.salonServiceURL, salonID, branchID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -240,7 +363,7 @@ func (e *externalService) GetBranch(ctx context.Context, salonID, branchID uuid.
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("branch not found")
+		return nil, sharederrors.NewNotFoundError("branch", branchID.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -257,9 +380,15 @@ func (e *externalService) GetBranch(ctx context.Context, salonID, branchID uuid.
 
 // GetService retrieves service information
 func (e *externalService) GetService(ctx context.Context, salonID, serviceID uuid.UUID) (*ServiceInfo, error) {
+	return e.services.get(ctx, lookupKey(salonID, serviceID), func() (*ServiceInfo, error) {
+		return e.fetchService(ctx, salonID, serviceID)
+	})
+}
+
+func (e *externalService) fetchService(ctx context.Context, salonID, serviceID uuid.UUID) (*ServiceInfo, error) {
 	url := fmt.Sprintf("%s/salons/%s/services/%s", e.salonServiceURL, salonID, serviceID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -271,7 +400,7 @@ func (e *externalService) GetService(ctx context.Context, salonID, serviceID uui
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("service not found")
+		return nil, sharederrors.NewNotFoundError("service", serviceID.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -288,9 +417,15 @@ func (e *externalService) GetService(ctx context.Context, salonID, serviceID uui
 
 // GetStylist retrieves stylist information
 func (e *externalService) GetStylist(ctx context.Context, salonID, stylistID uuid.UUID) (*StylistInfo, error) {
+	return e.stylists.get(ctx, lookupKey(salonID, stylistID), func() (*StylistInfo, error) {
+		return e.fetchStylist(ctx, salonID, stylistID)
+	})
+}
+
+func (e *externalService) fetchStylist(ctx context.Context, salonID, stylistID uuid.UUID) (*StylistInfo, error) {
 	url := fmt.Sprintf("%s/salons/%s/staff/%s", e.salonServiceURL, salonID, stylistID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -302,7 +437,7 @@ func (e *externalService) GetStylist(ctx context.Context, salonID, stylistID uui
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("stylist not found")
+		return nil, sharederrors.NewNotFoundError("stylist", stylistID.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -317,11 +452,42 @@ func (e *externalService) GetStylist(ctx context.Context, salonID, stylistID uui
 	return &stylist, nil
 }
 
+// GetBranchStylists retrieves the active stylists assigned to a branch
+func (e *externalService) GetBranchStylists(ctx context.Context, salonID, branchID uuid.UUID) ([]*StylistInfo, error) {
+	url := fmt.Sprintf("%s/salons/%s/staff?branch_id=%s&status=active", e.salonServiceURL, salonID, branchID)
+
+	req, err := e.newRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call salon service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, sharederrors.NewNotFoundError("salon", salonID.String())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("salon service returned status %d", resp.StatusCode)
+	}
+
+	var stylists []*StylistInfo
+	if err := json.NewDecoder(resp.Body).Decode(&stylists); err != nil {
+		return nil, fmt.Errorf("failed to decode stylists response: %w", err)
+	}
+
+	return stylists, nil
+}
+
 // GetStylistSchedule retrieves stylist schedule for a specific date
 func (e *externalService) GetStylistSchedule(ctx context.Context, salonID, stylistID uuid.UUID, date time.Time) (*StylistSchedule, error) {
 	url := fmt.Sprintf("%s/salons/%s/staff/%s/schedule?date=%s", e.salonServiceURL, salonID, stylistID, date.Format("2006-01-02"))
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -333,7 +499,7 @@ func (e *externalService) GetStylistSchedule(ctx context.Context, salonID, styli
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("stylist schedule not found")
+		return nil, sharederrors.NewNotFoundError("stylist_schedule", stylistID.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -352,7 +518,7 @@ func (e *externalService) GetStylistSchedule(ctx context.Context, salonID, styli
 func (e *externalService) GetStylistServices(ctx context.Context, salonID, stylistID uuid.UUID) ([]*ServiceInfo, error) {
 	url := fmt.Sprintf("%s/salons/%s/staff/%s/services", e.salonServiceURL, salonID, stylistID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := e.newRequest(ctx, "GET", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -367,11 +533,19 @@ func (e *externalService) GetStylistServices(ctx context.Context, salonID, styli
 		return nil, fmt.Errorf("salon service returned status %d", resp.StatusCode)
 	}
 
-	var services []*ServiceInfo
-	if err := json.NewDecoder(resp.Body).Decode(&services); err != nil {
+	// salon-service only returns the IDs of the services assigned to the stylist
+	var response struct {
+		ServiceIDs []uuid.UUID `json:"service_ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("failed to decode services response: %w", err)
 	}
 
+	services := make([]*ServiceInfo, 0, len(response.ServiceIDs))
+	for _, serviceID := range response.ServiceIDs {
+		services = append(services, &ServiceInfo{ID: serviceID})
+	}
+
 	return services, nil
 }
 
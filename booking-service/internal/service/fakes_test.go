@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"booking-service/internal/config"
+	"booking-service/internal/model"
+	"booking-service/internal/repository"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+// fakeExternalService serves fixed salon-service data. Methods a test does
+// not need are left to the embedded nil interface and panic if called.
+type fakeExternalService struct {
+	ExternalService
+
+	salon    *SalonInfo
+	branch   *BranchInfo
+	stylists map[uuid.UUID]*StylistInfo
+	// schedule returns the stylist's schedule on a date, a midnight in the branch time zone
+	schedule func(stylistID uuid.UUID, date time.Time) *StylistSchedule
+}
+
+func (f *fakeExternalService) ValidateUser(ctx context.Context, userID uuid.UUID) (*UserInfo, error) {
+	return &UserInfo{ID: userID, Name: "Test Customer"}, nil
+}
+
+func (f *fakeExternalService) GetSalon(ctx context.Context, salonID uuid.UUID) (*SalonInfo, error) {
+	return f.salon, nil
+}
+
+func (f *fakeExternalService) GetBranch(ctx context.Context, salonID, branchID uuid.UUID) (*BranchInfo, error) {
+	return f.branch, nil
+}
+
+func (f *fakeExternalService) GetStylist(ctx context.Context, salonID, stylistID uuid.UUID) (*StylistInfo, error) {
+	stylist, ok := f.stylists[stylistID]
+	if !ok {
+		return nil, sharederrors.NewNotFoundError("stylist", stylistID.String())
+	}
+	return stylist, nil
+}
+
+func (f *fakeExternalService) GetStylistSchedule(ctx context.Context, salonID, stylistID uuid.UUID, date time.Time) (*StylistSchedule, error) {
+	return f.schedule(stylistID, date), nil
+}
+
+// fakeRepository keeps the data a test needs in memory. Methods a test does
+// not need are left to the embedded nil interface and panic if called.
+type fakeRepository struct {
+	repository.BookingRepository
+
+	mu           sync.Mutex
+	bookings     map[uuid.UUID]*model.Booking
+	branchConfig *model.BranchConfiguration
+	// afterGetByID runs once GetByID has read a booking, letting a test hold
+	// concurrent writers until they have all read the same version
+	afterGetByID func()
+	history      []*model.BookingHistory
+	// beforeConfigUpdate runs inside UpdateBranchConfiguration before the
+	// version check, standing in for a concurrent writer
+	beforeConfigUpdate func(stored *model.BranchConfiguration)
+	// stylistBookings are returned by the stylist booking lookups
+	stylistBookings []*model.BookingService
+	// bookingRanges records the ranges stylist bookings were looked up for
+	bookingRanges [][2]time.Time
+}
+
+func (r *fakeRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Booking, error) {
+	r.mu.Lock()
+	booking, ok := r.bookings[id]
+	if !ok {
+		r.mu.Unlock()
+		return nil, repository.ErrBookingNotFound
+	}
+	stored := *booking
+	r.mu.Unlock()
+
+	if r.afterGetByID != nil {
+		r.afterGetByID()
+	}
+	return &stored, nil
+}
+
+// Update applies the update only when the version still matches the stored
+// one, like the versioned UPDATE in the real repository
+func (r *fakeRepository) Update(ctx context.Context, booking *model.Booking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.bookings[booking.ID]
+	if !ok {
+		return repository.ErrBookingNotFound
+	}
+	if stored.Version != booking.Version {
+		return repository.ErrBookingVersionConflict
+	}
+	booking.Version++
+	updated := *booking
+	r.bookings[booking.ID] = &updated
+	return nil
+}
+
+func (r *fakeRepository) CreateHistory(ctx context.Context, history *model.BookingHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history = append(r.history, history)
+	return nil
+}
+
+func (r *fakeRepository) ClaimWaitlistEntryForSlot(ctx context.Context, slot model.FreedSlot) (*model.WaitlistEntry, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) GetBranchConfiguration(ctx context.Context, branchID uuid.UUID) (*model.BranchConfiguration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.branchConfig == nil {
+		return nil, errors.New("branch configuration not found")
+	}
+	config := *r.branchConfig
+	return &config, nil
+}
+
+func (r *fakeRepository) CreateBranchConfiguration(ctx context.Context, config *model.BranchConfiguration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *config
+	r.branchConfig = &stored
+	return nil
+}
+
+// UpdateBranchConfiguration applies the update only when the version still
+// matches the stored one, like the versioned UPDATE in the real repository
+func (r *fakeRepository) UpdateBranchConfiguration(ctx context.Context, config *model.BranchConfiguration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.beforeConfigUpdate != nil {
+		r.beforeConfigUpdate(r.branchConfig)
+	}
+	if r.branchConfig == nil || r.branchConfig.Version != config.Version {
+		return repository.ErrBranchConfigVersionConflict
+	}
+	config.Version++
+	stored := *config
+	r.branchConfig = &stored
+	return nil
+}
+
+func (r *fakeRepository) GetStylistBookings(ctx context.Context, stylistID uuid.UUID, startTime, endTime time.Time) ([]*model.BookingService, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bookingRanges = append(r.bookingRanges, [2]time.Time{startTime, endTime})
+
+	var bookings []*model.BookingService
+	for _, booking := range r.stylistBookings {
+		if booking.StylistID == stylistID && booking.StartTime.Before(endTime) && booking.EndTime.After(startTime) {
+			bookings = append(bookings, booking)
+		}
+	}
+	return bookings, nil
+}
+
+// fakeEventPublisher records published booking events
+type fakeEventPublisher struct {
+	mu     sync.Mutex
+	events []*BookingEvent
+}
+
+func (p *fakeEventPublisher) Publish(ctx context.Context, event *BookingEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *fakeEventPublisher) Close() error {
+	return nil
+}
+
+// newTestService returns a booking service backed by the fakes
+func newTestService(repo *fakeRepository, external *fakeExternalService) *bookingService {
+	return &bookingService{
+		repo:            repo,
+		externalService: external,
+		config: &config.Config{
+			DefaultSlotIntervalMinutes:     model.DefaultSlotIntervalMinutes,
+			DefaultMaxAdvanceBookingDays:   30,
+			DefaultCancellationCutoffHours: 24,
+			DefaultRescheduleWindowHours:   24,
+		},
+	}
+}
+
+// mustLoadLocation loads an IANA time zone or fails the test
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
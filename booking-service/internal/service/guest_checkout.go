@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/utils"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// errInvalidGuestAccess is returned when a guest presents a missing or wrong
+// booking access token
+var errInvalidGuestAccess = sharederrors.NewAuthError("authorization", "invalid booking access token")
+
+// GuestInitiateBookingRequest is a booking made without an account. The guest
+// is identified by name and phone number instead of a user_id.
+type GuestInitiateBookingRequest struct {
+	InitiateBookingRequest
+	Guest GuestCustomer `json:"guest"`
+}
+
+// GuestBookingResponse is returned once, when a guest checkout is created. The
+// access token is not stored and cannot be retrieved again.
+type GuestBookingResponse struct {
+	Booking     *model.Booking `json:"booking"`
+	AccessToken string         `json:"access_token"`
+}
+
+// GuestInitiateBooking creates a booking for a customer without an account.
+// The guest is linked to a minimal guest profile in user-service, so payment
+// and notifications use the guest's contact details, and registering later
+// with the same phone number keeps the booking. Phone numbers of registered
+// customers must sign in instead.
+func (s *bookingService) GuestInitiateBooking(ctx context.Context, request *GuestInitiateBookingRequest) (*GuestBookingResponse, error) {
+	user, err := s.externalService.CreateGuestUser(ctx, &request.Guest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve guest: %w", err)
+	}
+	if !user.IsGuest {
+		return nil, bookingConflict("phone number belongs to a registered account; sign in to book")
+	}
+
+	token, err := newGuestAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	hash := utils.HashString(token)
+
+	request.UserID = user.ID
+	request.GuestAccessTokenHash = &hash
+	// A replayed key could not return the token of the original booking
+	request.IdempotencyKey = ""
+
+	booking, err := s.InitiateBooking(ctx, &request.InitiateBookingRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("booking_id", booking.ID.String()).Str("user_id", user.ID.String()).Msg("Guest booking initiated")
+	return &GuestBookingResponse{Booking: booking, AccessToken: token}, nil
+}
+
+// GetGuestBooking returns a guest checkout booking to the holder of its access token
+func (s *bookingService) GetGuestBooking(ctx context.Context, bookingID uuid.UUID, accessToken string) (*model.Booking, error) {
+	if err := s.verifyGuestAccess(ctx, bookingID, accessToken); err != nil {
+		return nil, err
+	}
+	return s.GetBooking(ctx, bookingID)
+}
+
+// GuestCancelBooking cancels a guest checkout booking for the holder of its
+// access token, under the same rules as customer cancellations
+func (s *bookingService) GuestCancelBooking(ctx context.Context, bookingID uuid.UUID, accessToken, reason string) error {
+	if err := s.verifyGuestAccess(ctx, bookingID, accessToken); err != nil {
+		return err
+	}
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return bookingLookupError(bookingID, err)
+	}
+	return s.CancelBooking(ctx, bookingID, booking.UserID, reason)
+}
+
+// GuestInitiatePayment starts payment of a guest checkout booking for the
+// holder of its access token
+func (s *bookingService) GuestInitiatePayment(ctx context.Context, bookingID uuid.UUID, accessToken, gateway string) (*InitiatePaymentResponse, error) {
+	if err := s.verifyGuestAccess(ctx, bookingID, accessToken); err != nil {
+		return nil, err
+	}
+	return s.InitiatePaymentForBooking(ctx, bookingID, gateway)
+}
+
+// verifyGuestAccess checks an access token against the booking's stored hash
+func (s *bookingService) verifyGuestAccess(ctx context.Context, bookingID uuid.UUID, accessToken string) error {
+	if accessToken == "" {
+		return errInvalidGuestAccess
+	}
+	hash, err := s.repo.GetGuestAccessTokenHash(ctx, bookingID)
+	if err != nil {
+		return bookingLookupError(bookingID, err)
+	}
+	if hash == nil || subtle.ConstantTimeCompare([]byte(*hash), []byte(utils.HashString(accessToken))) != 1 {
+		return errInvalidGuestAccess
+	}
+	return nil
+}
+
+// newGuestAccessToken returns a random URL-safe token
+func newGuestAccessToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
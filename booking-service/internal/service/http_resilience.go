@@ -0,0 +1,217 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"booking-service/internal/tracing"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCircuitOpen is returned without calling the downstream service while its
+// circuit breaker is open. It wraps ErrServiceUnavailable so callers report a 503.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", sharederrors.ErrServiceUnavailable)
+
+// ResiliencePolicy configures retries and circuit breaking for calls to other services
+type ResiliencePolicy struct {
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt. Only GETs and
+	// requests carrying an X-Idempotency-Key header are retried.
+	MaxRetries int
+	// RetryBaseDelay is the wait before the first retry; it doubles for each later retry
+	RetryBaseDelay time.Duration
+	// BreakerFailureThreshold consecutive failures open the breaker for BreakerOpenDuration
+	BreakerFailureThreshold int
+	BreakerOpenDuration     time.Duration
+}
+
+// resilientClient wraps http.Client with bounded retries and a circuit breaker
+// per downstream host. Network errors and 5xx responses count as failures.
+type resilientClient struct {
+	httpClient *http.Client
+	policy     ResiliencePolicy
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newResilientClient(policy ResiliencePolicy) *resilientClient {
+	return &resilientClient{
+		httpClient: &http.Client{Timeout: policy.Timeout},
+		policy:     policy,
+		breakers:   make(map[string]*circuitBreaker),
+	}
+}
+
+// Do sends the request in a client span that is propagated to the downstream
+// service, retrying idempotent requests on network errors and 5xx responses.
+// The last response is returned as-is when retries are exhausted.
+func (c *resilientClient) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.StartSpan(req.Context(), "HTTP "+req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.String()),
+		),
+	)
+	defer span.End()
+
+	req = req.WithContext(ctx)
+	tracing.InjectHeaders(ctx, req.Header)
+
+	resp, err := c.do(req)
+	if err != nil {
+		tracing.AddSpanError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	return resp, nil
+}
+
+func (c *resilientClient) do(req *http.Request) (*http.Response, error) {
+	breaker := c.breaker(req.URL.Host)
+	attempts := 1
+	if isRetryable(req) {
+		attempts += c.policy.MaxRetries
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := c.policy.RetryBaseDelay << (attempt - 1)
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(delay):
+			}
+
+			if req, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+
+		if !breaker.allow() {
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, ErrCircuitOpen)
+		}
+
+		resp, err = c.httpClient.Do(req)
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		breaker.record(!failed)
+		if !failed {
+			return resp, nil
+		}
+
+		if attempt < attempts-1 {
+			event := log.Warn().Str("method", req.Method).Str("url", req.URL.String()).Int("attempt", attempt+1)
+			if err != nil {
+				event = event.Err(err)
+			} else {
+				event = event.Int("status", resp.StatusCode)
+				resp.Body.Close()
+			}
+			event.Msg("Downstream call failed, retrying")
+		}
+	}
+
+	return resp, err
+}
+
+func (c *resilientClient) breaker(host string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	breaker, ok := c.breakers[host]
+	if !ok {
+		breaker = &circuitBreaker{
+			host:         host,
+			threshold:    c.policy.BreakerFailureThreshold,
+			openDuration: c.policy.BreakerOpenDuration,
+		}
+		c.breakers[host] = breaker
+	}
+	return breaker
+}
+
+// isRetryable reports whether repeating the request is safe. POSTs are only
+// retried when the downstream deduplicates them by idempotency key.
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	default:
+		return req.Header.Get("X-Idempotency-Key") != ""
+	}
+}
+
+// rewind returns a copy of the request with a fresh body for another attempt
+func rewind(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("request body cannot be replayed for retry")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// circuitBreaker opens after threshold consecutive failures. Once openDuration
+// has passed a single probe request is let through; its outcome closes or
+// reopens the breaker.
+type circuitBreaker struct {
+	host         string
+	threshold    int
+	openDuration time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		if b.failures >= b.threshold {
+			log.Info().Str("host", b.host).Msg("Circuit breaker closed")
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.openDuration)
+		if b.failures == b.threshold {
+			log.Warn().Str("host", b.host).Dur("open_for", b.openDuration).Msg("Circuit breaker opened")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"booking-service/internal/metrics"
+)
+
+type lookupCacheBypassKey struct{}
+
+// WithoutLookupCache returns a context whose salon lookups skip the cache and
+// always call salon-service. Fresh results still refresh the cache.
+func WithoutLookupCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lookupCacheBypassKey{}, true)
+}
+
+func lookupCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(lookupCacheBypassKey{}).(bool)
+	return bypass
+}
+
+type lookupCacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// lookupCache is a small TTL cache for salon-service lookups. A TTL of zero
+// disables it. Values are stored and returned by copy so callers cannot
+// mutate cached entries.
+type lookupCache[T any] struct {
+	name    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]lookupCacheEntry[T]
+}
+
+func newLookupCache[T any](name string, ttl time.Duration) *lookupCache[T] {
+	return &lookupCache[T]{
+		name:    name,
+		ttl:     ttl,
+		entries: make(map[string]lookupCacheEntry[T]),
+	}
+}
+
+// get returns the cached value for key, calling fetch on a miss, an expired
+// entry or when the context bypasses the cache. Errors are not cached.
+func (c *lookupCache[T]) get(ctx context.Context, key string, fetch func() (*T, error)) (*T, error) {
+	if c.ttl <= 0 {
+		return fetch()
+	}
+
+	if lookupCacheBypassed(ctx) {
+		metrics.RecordLookupCache(c.name, "bypass")
+	} else if value, ok := c.load(key); ok {
+		metrics.RecordLookupCache(c.name, "hit")
+		return value, nil
+	} else {
+		metrics.RecordLookupCache(c.name, "miss")
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, *value)
+	return value, nil
+}
+
+func (c *lookupCache[T]) load(key string) (*T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	value := entry.value
+	return &value, true
+}
+
+func (c *lookupCache[T]) store(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	// Sweep expired entries occasionally so IDs that are never read again do not accumulate
+	if len(c.entries) >= 1024 {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = lookupCacheEntry[T]{value: value, expiresAt: now.Add(c.ttl)}
+}
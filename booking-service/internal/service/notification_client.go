@@ -15,16 +15,14 @@ import (
 // NotificationClient handles communication with the notification service
 type NotificationClient struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *resilientClient
 }
 
 // NewNotificationClient creates a new notification service client
-func NewNotificationClient(baseURL string) *NotificationClient {
+func NewNotificationClient(baseURL string, policy ResiliencePolicy) *NotificationClient {
 	return &NotificationClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:    baseURL,
+		httpClient: newResilientClient(policy),
 	}
 }
 
@@ -34,7 +32,9 @@ type SendNotificationRequest struct {
 	Type      string                 `json:"type"`
 	Recipient string                 `json:"recipient"`
 	Subject   string                 `json:"subject,omitempty"`
-	Content   string                 `json:"content"`
+	Content   string                 `json:"content,omitempty"`
+	Template  string                 `json:"template,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -57,162 +57,75 @@ type BookingEvent struct {
 
 // SendBookingConfirmationNotification sends booking confirmation notifications
 func (c *NotificationClient) SendBookingConfirmationNotification(ctx context.Context, bookingEvent *BookingEvent) error {
-	// Get user details from the event data
-	userEmail, _ := bookingEvent.Data["user_email"].(string)
-	userPhone, _ := bookingEvent.Data["user_phone"].(string)
-	userName, _ := bookingEvent.Data["user_name"].(string)
-	salonName, _ := bookingEvent.Data["salon_name"].(string)
-	totalAmount, _ := bookingEvent.Data["total_amount"].(float64)
-	bookingTime, _ := bookingEvent.Data["booking_time"].(string)
-
-	if userEmail == "" && userPhone == "" {
-		return fmt.Errorf("no contact information available for user")
-	}
-
-	// Prepare notification metadata
-	metadata := map[string]interface{}{
-		"booking_id":   bookingEvent.BookingID.String(),
-		"user_id":      bookingEvent.UserID.String(),
-		"salon_name":   salonName,
-		"total_amount": totalAmount,
-		"booking_time": bookingTime,
-		"event_type":   bookingEvent.Type,
-	}
-
-	// Send email notification
-	if userEmail != "" {
-		emailRequest := &SendNotificationRequest{
-			UserID:    &bookingEvent.UserID,
-			Type:      "email",
-			Recipient: userEmail,
-			Subject:   fmt.Sprintf("Booking Confirmed - %s", salonName),
-			Content: fmt.Sprintf(`Dear %s,
-
-Your booking has been confirmed!
-
-Salon: %s
-Date & Time: %s
-Total Amount: ₹%.2f
-
-Thank you for choosing our services. We look forward to serving you!
-
-Best regards,
-%s Team`, userName, salonName, bookingTime, totalAmount, salonName),
-			Metadata: metadata,
-		}
-
-		if err := c.SendNotification(ctx, emailRequest); err != nil {
-			log.Error().Err(err).Msg("Failed to send booking confirmation email")
-		}
-	}
-
-	// Send SMS notification
-	if userPhone != "" {
-		smsRequest := &SendNotificationRequest{
-			UserID:    &bookingEvent.UserID,
-			Type:      "sms",
-			Recipient: userPhone,
-			Content: fmt.Sprintf("Hi %s! Your booking at %s on %s is confirmed. Amount: ₹%.2f. Thank you!",
-				userName, salonName, bookingTime, totalAmount),
-			Metadata: metadata,
-		}
-
-		if err := c.SendNotification(ctx, smsRequest); err != nil {
-			log.Error().Err(err).Msg("Failed to send booking confirmation SMS")
-		}
-	}
-
-	return nil
+	return c.sendBookingTemplate(ctx, bookingEvent, "booking_confirmed", "booking confirmation",
+		"user_name", "salon_name", "booking_time", "total_amount")
 }
 
 // SendBookingCancellationNotification sends booking cancellation notifications
 func (c *NotificationClient) SendBookingCancellationNotification(ctx context.Context, bookingEvent *BookingEvent) error {
-	userEmail, _ := bookingEvent.Data["user_email"].(string)
-	userPhone, _ := bookingEvent.Data["user_phone"].(string)
-	userName, _ := bookingEvent.Data["user_name"].(string)
-	salonName, _ := bookingEvent.Data["salon_name"].(string)
-	bookingTime, _ := bookingEvent.Data["booking_time"].(string)
-	reason, _ := bookingEvent.Data["reason"].(string)
-
-	if userEmail == "" && userPhone == "" {
-		return fmt.Errorf("no contact information available for user")
-	}
-
-	metadata := map[string]interface{}{
-		"booking_id":   bookingEvent.BookingID.String(),
-		"user_id":      bookingEvent.UserID.String(),
-		"salon_name":   salonName,
-		"booking_time": bookingTime,
-		"reason":       reason,
-		"event_type":   bookingEvent.Type,
-	}
-
-	// Send email notification
-	if userEmail != "" {
-		emailRequest := &SendNotificationRequest{
-			UserID:    &bookingEvent.UserID,
-			Type:      "email",
-			Recipient: userEmail,
-			Subject:   fmt.Sprintf("Booking Cancelled - %s", salonName),
-			Content: fmt.Sprintf(`Dear %s,
-
-Your booking has been cancelled.
-
-Salon: %s
-Date & Time: %s
-Reason: %s
-
-If you have any questions, please contact us.
-
-Best regards,
-%s Team`, userName, salonName, bookingTime, reason, salonName),
-			Metadata: metadata,
-		}
+	return c.sendBookingTemplate(ctx, bookingEvent, "booking_cancelled", "booking cancellation",
+		"user_name", "salon_name", "booking_time", "reason")
+}
 
-		if err := c.SendNotification(ctx, emailRequest); err != nil {
-			log.Error().Err(err).Msg("Failed to send booking cancellation email")
-		}
-	}
+// SendBookingRescheduleNotification sends booking reschedule notifications
+func (c *NotificationClient) SendBookingRescheduleNotification(ctx context.Context, bookingEvent *BookingEvent) error {
+	return c.sendBookingTemplate(ctx, bookingEvent, "booking_rescheduled", "booking reschedule",
+		"user_name", "salon_name", "booking_time")
+}
 
-	// Send SMS notification
-	if userPhone != "" {
-		smsRequest := &SendNotificationRequest{
-			UserID:    &bookingEvent.UserID,
-			Type:      "sms",
-			Recipient: userPhone,
-			Content: fmt.Sprintf("Hi %s! Your booking at %s on %s has been cancelled. Reason: %s",
-				userName, salonName, bookingTime, reason),
-			Metadata: metadata,
-		}
+// SendBookingReminderNotification sends upcoming booking reminder notifications
+func (c *NotificationClient) SendBookingReminderNotification(ctx context.Context, bookingEvent *BookingEvent) error {
+	return c.sendBookingTemplate(ctx, bookingEvent, "booking_reminder", "booking reminder",
+		"user_name", "salon_name", "branch_name", "booking_time", "threshold_minutes")
+}
 
-		if err := c.SendNotification(ctx, smsRequest); err != nil {
-			log.Error().Err(err).Msg("Failed to send booking cancellation SMS")
-		}
-	}
+// SendSlotFreedNotification tells a waitlisted user that a slot they wanted is free
+func (c *NotificationClient) SendSlotFreedNotification(ctx context.Context, bookingEvent *BookingEvent) error {
+	return c.sendBookingTemplate(ctx, bookingEvent, "waitlist_slot_freed", "waitlist slot freed",
+		"user_name", "salon_name", "branch_name", "stylist_name", "booking_time", "slot_end_time")
+}
 
-	return nil
+// SendRecurrenceSkippedNotification tells a user that an occurrence of their
+// recurring booking could not be booked
+func (c *NotificationClient) SendRecurrenceSkippedNotification(ctx context.Context, bookingEvent *BookingEvent) error {
+	return c.sendBookingTemplate(ctx, bookingEvent, "recurring_booking_skipped", "recurring booking skipped",
+		"user_name", "salon_name", "branch_name", "stylist_name", "booking_time", "reason")
 }
 
 // SendPaymentConfirmationNotification sends payment confirmation notifications
 func (c *NotificationClient) SendPaymentConfirmationNotification(ctx context.Context, bookingEvent *BookingEvent) error {
+	return c.sendBookingTemplate(ctx, bookingEvent, "payment_confirmed", "payment confirmation",
+		"user_name", "salon_name", "total_amount", "payment_id")
+}
+
+// sendBookingTemplate sends the named notification template by email and SMS to
+// whichever contacts the event carries. The listed event data fields are passed
+// to the template and recorded as notification metadata; the copy itself lives
+// in notification-service.
+func (c *NotificationClient) sendBookingTemplate(ctx context.Context, bookingEvent *BookingEvent, template, description string, fields ...string) error {
 	userEmail, _ := bookingEvent.Data["user_email"].(string)
 	userPhone, _ := bookingEvent.Data["user_phone"].(string)
-	userName, _ := bookingEvent.Data["user_name"].(string)
-	salonName, _ := bookingEvent.Data["salon_name"].(string)
-	totalAmount, _ := bookingEvent.Data["total_amount"].(float64)
-	paymentID, _ := bookingEvent.Data["payment_id"].(string)
 
 	if userEmail == "" && userPhone == "" {
 		return fmt.Errorf("no contact information available for user")
 	}
 
+	data := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := bookingEvent.Data[field]; ok {
+			data[field] = value
+		}
+	}
+
 	metadata := map[string]interface{}{
-		"booking_id":   bookingEvent.BookingID.String(),
-		"user_id":      bookingEvent.UserID.String(),
-		"salon_name":   salonName,
-		"total_amount": totalAmount,
-		"payment_id":   paymentID,
-		"event_type":   bookingEvent.Type,
+		"booking_id": bookingEvent.BookingID.String(),
+		"user_id":    bookingEvent.UserID.String(),
+		"event_type": bookingEvent.Type,
+	}
+	for key, value := range data {
+		if key != "user_name" {
+			metadata[key] = value
+		}
 	}
 
 	// Send email notification
@@ -221,24 +134,13 @@ func (c *NotificationClient) SendPaymentConfirmationNotification(ctx context.Con
 			UserID:    &bookingEvent.UserID,
 			Type:      "email",
 			Recipient: userEmail,
-			Subject:   fmt.Sprintf("Payment Received - %s", salonName),
-			Content: fmt.Sprintf(`Dear %s,
-
-Thank you! Your payment has been successfully processed.
-
-Salon: %s
-Amount Paid: ₹%.2f
-Payment ID: %s
-
-Your booking is now confirmed. We look forward to serving you!
-
-Best regards,
-%s Team`, userName, salonName, totalAmount, paymentID, salonName),
-			Metadata: metadata,
+			Template:  template,
+			Data:      data,
+			Metadata:  metadata,
 		}
 
 		if err := c.SendNotification(ctx, emailRequest); err != nil {
-			log.Error().Err(err).Msg("Failed to send payment confirmation email")
+			log.Error().Err(err).Msgf("Failed to send %s email", description)
 		}
 	}
 
@@ -248,13 +150,13 @@ Best regards,
 			UserID:    &bookingEvent.UserID,
 			Type:      "sms",
 			Recipient: userPhone,
-			Content: fmt.Sprintf("Hi %s! Payment of ₹%.2f for %s received successfully. Booking confirmed!",
-				userName, totalAmount, salonName),
-			Metadata: metadata,
+			Template:  template,
+			Data:      data,
+			Metadata:  metadata,
 		}
 
 		if err := c.SendNotification(ctx, smsRequest); err != nil {
-			log.Error().Err(err).Msg("Failed to send payment confirmation SMS")
+			log.Error().Err(err).Msgf("Failed to send %s SMS", description)
 		}
 	}
 
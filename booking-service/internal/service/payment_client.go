@@ -15,16 +15,14 @@ import (
 // PaymentClient handles communication with the payment service
 type PaymentClient struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *resilientClient
 }
 
 // NewPaymentClient creates a new payment service client
-func NewPaymentClient(baseURL string) *PaymentClient {
+func NewPaymentClient(baseURL string, policy ResiliencePolicy) *PaymentClient {
 	return &PaymentClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:    baseURL,
+		httpClient: newResilientClient(policy),
 	}
 }
 
@@ -49,6 +47,15 @@ type InitiatePaymentResponse struct {
 	CreatedAt       time.Time              `json:"created_at"`
 }
 
+// BookingPayment is a payment listed for a booking
+type BookingPayment struct {
+	ID       uuid.UUID `json:"id"`
+	Amount   float64   `json:"amount"`
+	Currency string    `json:"currency"`
+	Status   string    `json:"status"`
+	Gateway  string    `json:"gateway"`
+}
+
 type ConfirmPaymentRequest struct {
 	PaymentID         uuid.UUID `json:"payment_id"`
 	GatewayPaymentID  string    `json:"gateway_payment_id"`
@@ -200,7 +207,7 @@ func (c *PaymentClient) RefundPayment(ctx context.Context, request *RefundPaymen
 }
 
 // GetPaymentsByBooking retrieves payments for a booking
-func (c *PaymentClient) GetPaymentsByBooking(ctx context.Context, bookingID uuid.UUID) ([]InitiatePaymentResponse, error) {
+func (c *PaymentClient) GetPaymentsByBooking(ctx context.Context, bookingID uuid.UUID) ([]BookingPayment, error) {
 	url := fmt.Sprintf("%s/api/v1/bookings/%s/payments", c.baseURL, bookingID.String())
 	
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -219,8 +226,8 @@ func (c *PaymentClient) GetPaymentsByBooking(ctx context.Context, bookingID uuid
 	}
 
 	var response struct {
-		Payments []InitiatePaymentResponse `json:"payments"`
-		Count    int                       `json:"count"`
+		Payments []BookingPayment `json:"payments"`
+		Count    int              `json:"count"`
 	}
 	
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"booking-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/money"
+)
+
+// bookingTotals is the price breakdown of a booking after discount and tax
+type bookingTotals struct {
+	Taxable float64
+	GST     float64
+	Total   float64
+	// Taxes itemizes GST by the branch's tax components
+	Taxes []model.BookingSummaryTax
+}
+
+// calculateTotals applies a discount, GST and the branch booking fee to a
+// subtotal, of which exempt is the price of tax-exempt services. The discount
+// is shared between taxable and exempt services in proportion to their
+// prices, and each tax component is charged on the discounted taxable part.
+// Every component is rounded to two decimals so Total is exactly the sum of
+// the amounts shown to the customer.
+func calculateTotals(subtotal, exempt, discount float64, branchConfig *model.BranchConfiguration) bookingTotals {
+	discounted := money.Sub(subtotal, discount)
+	taxable := discounted
+	if exempt > 0 && subtotal > 0 {
+		taxable = money.Sub(discounted, money.Round(exempt*discounted/subtotal))
+	}
+
+	totals := bookingTotals{
+		Taxable: taxable,
+		Taxes:   []model.BookingSummaryTax{},
+	}
+	for _, component := range branchConfig.EffectiveTaxComponents() {
+		if component.Percentage <= 0 {
+			continue
+		}
+		amount := money.Percentage(taxable, component.Percentage)
+		totals.Taxes = append(totals.Taxes, model.BookingSummaryTax{
+			Name:          component.Name,
+			Percentage:    component.Percentage,
+			TaxableAmount: taxable,
+			Amount:        amount,
+		})
+		totals.GST = money.Sum(totals.GST, amount)
+	}
+	totals.Total = money.Sum(discounted, branchConfig.BookingFeeAmount, totals.GST)
+	return totals
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"booking-service/internal/model"
+)
+
+func TestCalculateTotalsWithExemptServices(t *testing.T) {
+	splitGST := []model.TaxComponent{{Name: "CGST", Percentage: 9}, {Name: "SGST", Percentage: 9}}
+
+	tests := []struct {
+		name                 string
+		subtotal, exempt     float64
+		discount             float64
+		config               *model.BranchConfiguration
+		wantTaxable, wantGST float64
+		wantTotal            float64
+		wantTaxes            []float64
+	}{
+		{
+			name:     "taxable and exempt services",
+			subtotal: 1500, exempt: 500,
+			config:      &model.BranchConfiguration{TaxComponents: splitGST},
+			wantTaxable: 1000, wantGST: 180, wantTotal: 1680,
+			wantTaxes: []float64{90, 90},
+		},
+		{
+			name:     "discount shared in proportion to price",
+			subtotal: 1500, exempt: 500, discount: 150,
+			config:      &model.BranchConfiguration{TaxComponents: splitGST, BookingFeeAmount: 20},
+			wantTaxable: 900, wantGST: 162, wantTotal: 1532,
+			wantTaxes: []float64{81, 81},
+		},
+		{
+			name:     "exempt share rounded to the paisa",
+			subtotal: 999.99, exempt: 333.33, discount: 100,
+			config:      &model.BranchConfiguration{GSTPercentage: 18},
+			wantTaxable: 599.99, wantGST: 108, wantTotal: 1007.99,
+			wantTaxes: []float64{108},
+		},
+		{
+			name:     "only exempt services",
+			subtotal: 500, exempt: 500,
+			config:      &model.BranchConfiguration{GSTPercentage: 18},
+			wantTaxable: 0, wantGST: 0, wantTotal: 500,
+			wantTaxes: []float64{0},
+		},
+		{
+			name:        "no exempt services",
+			subtotal:    1000,
+			config:      &model.BranchConfiguration{GSTPercentage: 18},
+			wantTaxable: 1000, wantGST: 180, wantTotal: 1180,
+			wantTaxes: []float64{180},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := calculateTotals(tt.subtotal, tt.exempt, tt.discount, tt.config)
+
+			if totals.Taxable != tt.wantTaxable {
+				t.Errorf("taxable = %v, want %v", totals.Taxable, tt.wantTaxable)
+			}
+			if totals.GST != tt.wantGST {
+				t.Errorf("tax = %v, want %v", totals.GST, tt.wantGST)
+			}
+			if totals.Total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", totals.Total, tt.wantTotal)
+			}
+			if len(totals.Taxes) != len(tt.wantTaxes) {
+				t.Fatalf("got %d tax lines, want %d: %+v", len(totals.Taxes), len(tt.wantTaxes), totals.Taxes)
+			}
+			for i, tax := range totals.Taxes {
+				if tax.Amount != tt.wantTaxes[i] || tax.TaxableAmount != tt.wantTaxable {
+					t.Errorf("tax line %s = %v on %v, want %v on %v", tax.Name, tax.Amount, tax.TaxableAmount, tt.wantTaxes[i], tt.wantTaxable)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"booking-service/internal/model"
+	"booking-service/internal/repository"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+)
+
+// resolvePromoCode validates a promo code for a booking at salonID and returns
+// it with the discount it gives on subtotal. An empty code yields no discount.
+func (s *bookingService) resolvePromoCode(ctx context.Context, code *string, salonID uuid.UUID, subtotal float64) (*model.PromoCode, float64, error) {
+	if code == nil || strings.TrimSpace(*code) == "" {
+		return nil, 0, nil
+	}
+	name := strings.TrimSpace(*code)
+
+	promo, err := s.repo.GetPromoCode(ctx, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrPromoCodeNotFound) {
+			return nil, 0, invalidPromoCode("promo code %s is not valid", name)
+		}
+		return nil, 0, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	now := time.Now()
+	switch {
+	case !promo.IsActive:
+		return nil, 0, invalidPromoCode("promo code %s is no longer active", promo.Code)
+	case promo.SalonID != nil && *promo.SalonID != salonID:
+		return nil, 0, invalidPromoCode("promo code %s is not valid at this salon", promo.Code)
+	case promo.StartsAt != nil && now.Before(*promo.StartsAt):
+		return nil, 0, invalidPromoCode("promo code %s is not valid until %s", promo.Code, promo.StartsAt.Format("2006-01-02"))
+	case promo.ExpiresAt != nil && !now.Before(*promo.ExpiresAt):
+		return nil, 0, invalidPromoCode("promo code %s expired on %s", promo.Code, promo.ExpiresAt.Format("2006-01-02"))
+	case promo.UsageLimit != nil && promo.UsageCount >= *promo.UsageLimit:
+		return nil, 0, invalidPromoCode("promo code %s has reached its usage limit", promo.Code)
+	case subtotal < promo.MinSpend:
+		return nil, 0, invalidPromoCode("promo code %s requires a minimum spend of %.2f", promo.Code, promo.MinSpend)
+	}
+
+	return promo, promo.CalculateDiscount(subtotal), nil
+}
+
+func invalidPromoCode(format string, args ...interface{}) error {
+	return sharederrors.NewValidationError("promo_code", fmt.Sprintf(format, args...))
+}
+
+// rescheduledDiscount recalculates a booking's promo discount for a new
+// subtotal. The code was already redeemed, so expiry and usage limits are not
+// checked again; if it can no longer be loaded the original discount is kept.
+func (s *bookingService) rescheduledDiscount(ctx context.Context, booking *model.Booking, subtotal float64) float64 {
+	if booking.PromoCode == nil {
+		return 0
+	}
+
+	discount := booking.DiscountAmount
+	if promo, err := s.repo.GetPromoCode(ctx, *booking.PromoCode); err == nil {
+		discount = promo.CalculateDiscount(subtotal)
+	}
+	if discount > subtotal {
+		discount = subtotal
+	}
+	return discount
+}
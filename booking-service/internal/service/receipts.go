@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"booking-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/money"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// GetBookingReceipt builds the payment receipt of a paid (or since refunded)
+// booking. Service and stylist names that can no longer be looked up are
+// printed generically rather than failing the receipt.
+func (s *bookingService) GetBookingReceipt(ctx context.Context, bookingID uuid.UUID) (*model.BookingReceipt, error) {
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, bookingLookupError(bookingID, err)
+	}
+	if booking.PaymentStatus != model.PaymentStatusPaid && booking.PaymentStatus != model.PaymentStatusRefunded {
+		return nil, bookingConflict("booking has not been paid (payment status %s)", booking.PaymentStatus)
+	}
+
+	salon, err := s.externalService.GetSalon(ctx, booking.SalonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get salon: %w", err)
+	}
+	branch, err := s.externalService.GetBranch(ctx, booking.SalonID, booking.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	receipt := &model.BookingReceipt{
+		BookingID:     booking.ID,
+		UserID:        booking.UserID,
+		BranchID:      booking.BranchID,
+		Number:        model.ReceiptNumber(booking.ID),
+		IssuedAt:      booking.UpdatedAt,
+		UpdatedAt:     booking.UpdatedAt,
+		Currency:      s.config.Currency,
+		SalonName:     salon.Name,
+		SalonAddress:  salon.Address,
+		BranchName:    branch.Name,
+		BranchAddress: branch.Address,
+		BranchPhone:   branch.Phone,
+		Timezone:      branch.Timezone,
+		Items:         make([]model.BookingReceiptItem, 0, len(booking.Services)),
+		Discount:      booking.DiscountAmount,
+		PromoCode:     booking.PromoCode,
+		GST:           booking.GST,
+		Taxes:         booking.Taxes,
+		BookingFee:    booking.BookingFee,
+		Total:         booking.TotalAmount,
+		PaymentStatus: booking.PaymentStatus,
+	}
+	if booking.PaymentID != nil {
+		receipt.PaymentReference = *booking.PaymentID
+	}
+	// History is newest first; the receipt is dated by the earliest confirmation
+	for _, h := range booking.History {
+		if h.Action == model.BookingActionConfirmed {
+			receipt.IssuedAt = h.Timestamp
+		}
+	}
+
+	for _, bs := range booking.Services {
+		item := model.BookingReceiptItem{
+			ServiceName: "Service",
+			StartTime:   bs.StartTime,
+			Price:       bs.Price,
+		}
+		if serviceInfo, err := s.externalService.GetService(ctx, booking.SalonID, bs.ServiceID); err == nil {
+			item.ServiceName = serviceInfo.Name
+		} else {
+			log.Warn().Err(err).Str("booking_id", bookingID.String()).Str("service_id", bs.ServiceID.String()).Msg("Failed to look up service for receipt")
+		}
+		if stylist, err := s.externalService.GetStylist(ctx, booking.SalonID, bs.StylistID); err == nil {
+			item.StylistName = stylist.Name
+		} else {
+			log.Warn().Err(err).Str("booking_id", bookingID.String()).Str("stylist_id", bs.StylistID.String()).Msg("Failed to look up stylist for receipt")
+		}
+		if bs.BeneficiaryName != nil {
+			item.BeneficiaryName = *bs.BeneficiaryName
+		}
+		receipt.Items = append(receipt.Items, item)
+		receipt.Subtotal = money.Sum(receipt.Subtotal, bs.Price)
+	}
+	if len(receipt.Taxes) > 0 {
+		receipt.TaxableAmount = receipt.Taxes[0].TaxableAmount
+	} else {
+		receipt.TaxableAmount = money.Sub(receipt.Subtotal, receipt.Discount)
+	}
+
+	return receipt, nil
+}
@@ -0,0 +1,509 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"booking-service/internal/model"
+	"booking-service/internal/repository"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/hours"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxRecurringIntervalWeeks is the longest cadence of a recurring booking
+	MaxRecurringIntervalWeeks = 8
+
+	// recurringBatchSize is how many recurring bookings the generator loads at a time
+	recurringBatchSize = 100
+
+	dateLayout      = "2006-01-02"
+	clockTimeLayout = "15:04"
+)
+
+// CreateRecurringBookingRequest asks for a standing appointment with one
+// stylist every IntervalWeeks weeks on Weekday at StartTime (HH:MM, branch
+// time). Services are performed back to back in the order given. StartDate
+// defaults to today and EndDate to no end; both are YYYY-MM-DD.
+type CreateRecurringBookingRequest struct {
+	UserID        uuid.UUID   `json:"user_id"`
+	SalonID       uuid.UUID   `json:"salon_id"`
+	BranchID      uuid.UUID   `json:"branch_id"`
+	StylistID     uuid.UUID   `json:"stylist_id"`
+	ServiceIDs    []uuid.UUID `json:"service_ids"`
+	Weekday       *int        `json:"weekday"`
+	StartTime     string      `json:"start_time"`
+	IntervalWeeks int         `json:"interval_weeks,omitempty"`
+	StartDate     *string     `json:"start_date,omitempty"`
+	EndDate       *string     `json:"end_date,omitempty"`
+	Notes         *string     `json:"notes,omitempty"`
+}
+
+// CreateRecurringBooking stores a recurring booking and books its first
+// occurrences in the background
+func (s *bookingService) CreateRecurringBooking(ctx context.Context, request *CreateRecurringBookingRequest) (*model.RecurringBooking, error) {
+	if request.IntervalWeeks == 0 {
+		request.IntervalWeeks = 1
+	}
+	if request.IntervalWeeks < 1 || request.IntervalWeeks > MaxRecurringIntervalWeeks {
+		return nil, sharederrors.NewValidationError("interval_weeks", fmt.Sprintf("interval_weeks must be between 1 and %d", MaxRecurringIntervalWeeks))
+	}
+
+	branch, err := s.externalService.GetBranch(ctx, request.SalonID, request.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid branch: %w", err)
+	}
+
+	stylist, err := s.externalService.GetStylist(ctx, request.SalonID, request.StylistID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stylist %s: %w", request.StylistID, err)
+	}
+	if stylist.BranchID != request.BranchID {
+		return nil, sharederrors.NewValidationError("stylist_id", fmt.Sprintf("stylist %s does not belong to branch %s", request.StylistID, request.BranchID))
+	}
+
+	for _, serviceID := range request.ServiceIDs {
+		if _, err := s.externalService.GetService(ctx, request.SalonID, serviceID); err != nil {
+			return nil, fmt.Errorf("invalid service %s: %w", serviceID, err)
+		}
+		if err := s.validateStylistOffersService(ctx, request.SalonID, request.StylistID, serviceID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Dates are calendar days in the branch time zone
+	loc := branchLocation(branch)
+	today := civilDate(time.Now().In(loc))
+	startDate := today
+	if request.StartDate != nil {
+		startDate, err = time.Parse(dateLayout, *request.StartDate)
+		if err != nil {
+			return nil, sharederrors.NewValidationError("start_date", "start_date must be a date in YYYY-MM-DD format")
+		}
+		if startDate.Before(today) {
+			return nil, sharederrors.NewValidationError("start_date", "start_date cannot be in the past")
+		}
+	}
+	if request.EndDate != nil {
+		endDate, err := time.Parse(dateLayout, *request.EndDate)
+		if err != nil {
+			return nil, sharederrors.NewValidationError("end_date", "end_date must be a date in YYYY-MM-DD format")
+		}
+		if endDate.Before(startDate) {
+			return nil, sharederrors.NewValidationError("end_date", "end_date must not be before start_date")
+		}
+	}
+
+	recurring := &model.RecurringBooking{
+		ID:            uuid.New(),
+		UserID:        request.UserID,
+		SalonID:       request.SalonID,
+		BranchID:      request.BranchID,
+		StylistID:     request.StylistID,
+		ServiceIDs:    request.ServiceIDs,
+		Weekday:       *request.Weekday,
+		StartTime:     request.StartTime,
+		IntervalWeeks: request.IntervalWeeks,
+		StartDate:     startDate.Format(dateLayout),
+		EndDate:       request.EndDate,
+		Timezone:      loc.String(),
+		Notes:         request.Notes,
+		Status:        model.RecurringBookingStatusActive,
+	}
+	if err := s.repo.CreateRecurringBooking(ctx, recurring); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("recurring_booking_id", recurring.ID.String()).
+		Str("user_id", recurring.UserID.String()).
+		Str("stylist_id", recurring.StylistID.String()).
+		Msg("Recurring booking created")
+
+	// The first occurrences are booked now rather than on the generator's next run
+	s.goBackground(func() { s.generateOccurrences(context.WithoutCancel(ctx), recurring, time.Now()) })
+
+	return recurring, nil
+}
+
+// GetUserRecurringBookings retrieves a page of the user's recurring bookings and the total number they have
+func (s *bookingService) GetUserRecurringBookings(ctx context.Context, userID uuid.UUID, page pagination.Params) ([]*model.RecurringBooking, int, error) {
+	page = page.Normalize()
+
+	recurrences, err := s.repo.GetRecurringBookingsByUserID(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.repo.CountRecurringBookingsByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return recurrences, total, nil
+}
+
+// PauseRecurringBooking stops booking new occurrences of an active recurring
+// booking. Occurrences already booked are kept.
+func (s *bookingService) PauseRecurringBooking(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error) {
+	recurring, err := s.repo.SetRecurringBookingStatus(ctx, id, userID, model.RecurringBookingStatusPaused, model.RecurringBookingStatusActive)
+	if err != nil {
+		return nil, recurringBookingError(id, err, "only active recurring bookings can be paused")
+	}
+	return recurring, nil
+}
+
+// ResumeRecurringBooking books occurrences of a paused recurring booking
+// again, from the next date that has not passed
+func (s *bookingService) ResumeRecurringBooking(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error) {
+	recurring, err := s.repo.SetRecurringBookingStatus(ctx, id, userID, model.RecurringBookingStatusActive, model.RecurringBookingStatusPaused)
+	if err != nil {
+		return nil, recurringBookingError(id, err, "only paused recurring bookings can be resumed")
+	}
+
+	s.goBackground(func() { s.generateOccurrences(context.WithoutCancel(ctx), recurring, time.Now()) })
+
+	return recurring, nil
+}
+
+// CancelRecurringBooking ends a recurring booking and cancels its upcoming
+// occurrences under the usual cancellation rules. Occurrences that already
+// took place, and upcoming ones inside the cancellation cutoff, are kept.
+func (s *bookingService) CancelRecurringBooking(ctx context.Context, id, userID uuid.UUID) (*model.RecurringBooking, error) {
+	recurring, err := s.repo.SetRecurringBookingStatus(ctx, id, userID, model.RecurringBookingStatusCancelled,
+		model.RecurringBookingStatusActive, model.RecurringBookingStatusPaused)
+	if err != nil {
+		return nil, recurringBookingError(id, err, "recurring booking is already cancelled")
+	}
+
+	occurrences, err := s.repo.GetUpcomingRecurringOccurrences(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bookingID := range occurrences {
+		if err := s.CancelBooking(ctx, bookingID, userID, "recurring booking cancelled"); err != nil {
+			log.Warn().Err(err).
+				Str("recurring_booking_id", id.String()).
+				Str("booking_id", bookingID.String()).
+				Msg("Kept occurrence of cancelled recurring booking")
+		}
+	}
+
+	log.Info().
+		Str("recurring_booking_id", id.String()).
+		Str("user_id", userID.String()).
+		Int("upcoming_occurrences", len(occurrences)).
+		Msg("Recurring booking cancelled")
+
+	return recurring, nil
+}
+
+// recurringBookingError converts a failed status change into a not found or
+// conflict error
+func recurringBookingError(id uuid.UUID, err error, conflict string) error {
+	switch {
+	case errors.Is(err, repository.ErrRecurringBookingNotFound):
+		return sharederrors.NewNotFoundError("recurring booking", id.String())
+	case errors.Is(err, repository.ErrRecurringBookingStatus):
+		return sharederrors.NewConflictError("recurring booking", conflict)
+	}
+	return err
+}
+
+// GenerateRecurringBookings books the occurrences of active recurring
+// bookings up to the configured horizon ahead and returns how many bookings
+// it created. Each recurring booking is visited at most once per run.
+func (s *bookingService) GenerateRecurringBookings(ctx context.Context) (int, error) {
+	runStart := time.Now()
+	created := 0
+
+	for {
+		recurrences, err := s.repo.GetDueRecurringBookings(ctx, runStart, recurringBatchSize)
+		if err != nil {
+			return created, err
+		}
+
+		for _, recurring := range recurrences {
+			created += s.generateOccurrences(ctx, recurring, runStart)
+		}
+
+		if len(recurrences) < recurringBatchSize {
+			return created, nil
+		}
+	}
+}
+
+// generateOccurrences books the dates of a recurring booking from the first
+// one not yet generated up to the horizon, or the branch's advance booking
+// window when that is shorter. Dates that cannot be booked are skipped and
+// the user is told; on any other failure generation stops and the remaining
+// dates are retried on the next run. It returns the number of bookings made.
+func (s *bookingService) generateOccurrences(ctx context.Context, recurring *model.RecurringBooking, now time.Time) int {
+	logger := log.With().Str("recurring_booking_id", recurring.ID.String()).Logger()
+
+	loc, err := time.LoadLocation(recurring.Timezone)
+	if err != nil {
+		logger.Warn().Err(err).Str("timezone", recurring.Timezone).Msg("Invalid recurring booking timezone, using UTC")
+		loc = time.UTC
+	}
+
+	startDate, startHour, startMinute, endDate, err := parseRecurrence(recurring)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid recurring booking")
+		s.markGenerated(ctx, recurring.ID, nil)
+		return 0
+	}
+
+	branchConfig, err := s.getBranchConfigWithDefaults(ctx, recurring.BranchID)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get branch configuration for recurring booking")
+		s.markGenerated(ctx, recurring.ID, nil)
+		return 0
+	}
+
+	horizon := now.AddDate(0, 0, s.config.RecurringHorizonDays)
+	if branchConfig.MaxAdvanceBookingDays > 0 {
+		if latest := now.AddDate(0, 0, branchConfig.MaxAdvanceBookingDays); latest.Before(horizon) {
+			horizon = latest
+		}
+	}
+
+	// Resume after the last generated date, never before today
+	from := civilDate(now.In(loc))
+	if recurring.GeneratedThrough != nil {
+		if through, err := time.Parse(dateLayout, *recurring.GeneratedThrough); err == nil && !through.Before(from) {
+			from = through.AddDate(0, 0, 1)
+		}
+	}
+
+	var generatedThrough *string
+	created := 0
+	for date := nextOccurrence(startDate, time.Weekday(recurring.Weekday), recurring.IntervalWeeks, from); ; date = date.AddDate(0, 0, 7*recurring.IntervalWeeks) {
+		if endDate != nil && date.After(*endDate) {
+			// Nothing is left to generate before the end date
+			generatedThrough = stringPtr(endDate.Format(dateLayout))
+			break
+		}
+
+		start := time.Date(date.Year(), date.Month(), date.Day(), startHour, startMinute, 0, 0, loc)
+		if start.After(horizon) {
+			break
+		}
+
+		dateStr := date.Format(dateLayout)
+		if start.After(now) {
+			booked, err := s.placeOccurrence(ctx, recurring, dateStr, start)
+			if err != nil {
+				logger.Error().Err(err).Str("occurrence_date", dateStr).Msg("Failed to book recurring booking occurrence")
+				break
+			}
+			if booked {
+				created++
+			}
+		}
+		generatedThrough = &dateStr
+	}
+
+	s.markGenerated(ctx, recurring.ID, generatedThrough)
+	return created
+}
+
+// markGenerated records a generator run, logging rather than returning failures
+// since the recurring booking is simply revisited on the next run
+func (s *bookingService) markGenerated(ctx context.Context, id uuid.UUID, through *string) {
+	if err := s.repo.MarkRecurringBookingGenerated(ctx, id, through); err != nil {
+		log.Error().Err(err).Str("recurring_booking_id", id.String()).Msg("Failed to record recurring booking generation")
+	}
+}
+
+// placeOccurrence books one occurrence starting at start. It reports whether
+// a booking was made; dates that cannot be booked, because the branch is
+// closed, the stylist is away or already booked, or a service is no longer
+// offered, are recorded as skipped and reported with a nil error.
+func (s *bookingService) placeOccurrence(ctx context.Context, recurring *model.RecurringBooking, occurrenceDate string, start time.Time) (bool, error) {
+	skip := func(reason string) (bool, error) {
+		return false, s.skipOccurrence(ctx, recurring, occurrenceDate, start, reason)
+	}
+
+	branch, err := s.externalService.GetBranch(ctx, recurring.SalonID, recurring.BranchID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	dayHours, err := s.branchHoursOn(ctx, recurring.SalonID, branch, day)
+	if err != nil {
+		return false, err
+	}
+	if !dayHours.open {
+		return skip("the branch is closed on this day")
+	}
+
+	schedule, err := s.externalService.GetStylistSchedule(ctx, recurring.SalonID, recurring.StylistID, day)
+	if err != nil {
+		return false, fmt.Errorf("failed to get stylist schedule: %w", err)
+	}
+	if schedule.OnLeave {
+		return skip("the stylist is on leave on this day")
+	}
+
+	// Services follow one another with the same stylist
+	services := make([]InitiateBookingServiceItem, len(recurring.ServiceIDs))
+	end := start
+	for i, serviceID := range recurring.ServiceIDs {
+		serviceInfo, err := s.externalService.GetService(ctx, recurring.SalonID, serviceID)
+		if err != nil {
+			var notFoundErr *sharederrors.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				return skip("a service of this recurring booking is no longer offered")
+			}
+			return false, fmt.Errorf("failed to get service %s: %w", serviceID, err)
+		}
+		services[i] = InitiateBookingServiceItem{ServiceID: serviceID, StylistID: recurring.StylistID, StartTime: end}
+		end = end.Add(time.Duration(serviceInfo.Duration) * time.Minute)
+	}
+
+	if !withinWorkingHours(dayHours.clip(schedule.WorkingHours), schedule.Breaks, start, end) {
+		return skip("the stylist is not working at this time")
+	}
+
+	booking, err := s.InitiateBooking(ctx, &InitiateBookingRequest{
+		UserID:             recurring.UserID,
+		SalonID:            recurring.SalonID,
+		BranchID:           recurring.BranchID,
+		Services:           services,
+		Notes:              recurring.Notes,
+		RecurringBookingID: &recurring.ID,
+		OccurrenceDate:     &occurrenceDate,
+	})
+	if err != nil {
+		var conflictErr *sharederrors.ConflictError
+		var notFoundErr *sharederrors.NotFoundError
+		var validationErrs sharederrors.ValidationErrors
+		switch {
+		case errors.Is(err, repository.ErrRecurringOccurrenceExists):
+			// Booked by a concurrent run
+			return false, nil
+		case errors.As(err, &conflictErr):
+			return skip(conflictErr.Detail)
+		case errors.As(err, &validationErrs) && len(validationErrs) > 0:
+			return skip(validationErrs[0].Message)
+		case errors.As(err, &notFoundErr):
+			return skip(notFoundErr.Error())
+		}
+		return false, err
+	}
+
+	log.Info().
+		Str("recurring_booking_id", recurring.ID.String()).
+		Str("booking_id", booking.ID.String()).
+		Str("occurrence_date", occurrenceDate).
+		Msg("Recurring booking occurrence booked")
+
+	s.goBackground(func() { s.sendBookingConfirmationNotifications(context.WithoutCancel(ctx), booking) })
+
+	return true, nil
+}
+
+// skipOccurrence records a date that could not be booked and tells the user,
+// once per date
+func (s *bookingService) skipOccurrence(ctx context.Context, recurring *model.RecurringBooking, occurrenceDate string, start time.Time, reason string) error {
+	recorded, err := s.repo.RecordRecurringBookingSkip(ctx, recurring.ID, occurrenceDate, reason)
+	if err != nil || !recorded {
+		return err
+	}
+
+	log.Info().
+		Str("recurring_booking_id", recurring.ID.String()).
+		Str("occurrence_date", occurrenceDate).
+		Str("reason", reason).
+		Msg("Recurring booking occurrence skipped")
+
+	s.goBackground(func() { s.sendRecurrenceSkippedNotifications(context.WithoutCancel(ctx), recurring, start, reason) })
+
+	return nil
+}
+
+// sendRecurrenceSkippedNotifications tells a user that an occurrence of their recurring booking was not booked
+func (s *bookingService) sendRecurrenceSkippedNotifications(ctx context.Context, recurring *model.RecurringBooking, start time.Time, reason string) {
+	bookingEvent, err := s.newBookingEvent(ctx, EventBookingRecurrenceSkipped, recurring.ID, recurring.UserID, recurring.SalonID, recurring.BranchID, start)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to prepare recurrence skipped event")
+		return
+	}
+	bookingEvent.Data["recurring_booking_id"] = recurring.ID.String()
+	bookingEvent.Data["stylist_id"] = recurring.StylistID.String()
+	bookingEvent.Data["reason"] = reason
+	if stylist, err := s.externalService.GetStylist(ctx, recurring.SalonID, recurring.StylistID); err == nil {
+		bookingEvent.Data["stylist_name"] = stylist.Name
+	}
+
+	s.emitBookingEvent(ctx, bookingEvent)
+}
+
+// parseRecurrence parses the stored dates and start time of a recurring booking
+func parseRecurrence(recurring *model.RecurringBooking) (startDate time.Time, hour, minute int, endDate *time.Time, err error) {
+	startDate, err = time.Parse(dateLayout, recurring.StartDate)
+	if err != nil {
+		return time.Time{}, 0, 0, nil, fmt.Errorf("invalid start date %q: %w", recurring.StartDate, err)
+	}
+
+	clock, err := time.Parse(clockTimeLayout, recurring.StartTime)
+	if err != nil {
+		return time.Time{}, 0, 0, nil, fmt.Errorf("invalid start time %q: %w", recurring.StartTime, err)
+	}
+
+	if recurring.EndDate != nil {
+		end, err := time.Parse(dateLayout, *recurring.EndDate)
+		if err != nil {
+			return time.Time{}, 0, 0, nil, fmt.Errorf("invalid end date %q: %w", *recurring.EndDate, err)
+		}
+		endDate = &end
+	}
+
+	return startDate, clock.Hour(), clock.Minute(), endDate, nil
+}
+
+// nextOccurrence returns the first occurrence on or after from of a
+// recurrence on weekday every intervalWeeks weeks, counted from the first
+// weekday on or after startDate. Dates are UTC midnights standing for
+// calendar days, so day arithmetic is unaffected by DST.
+func nextOccurrence(startDate time.Time, weekday time.Weekday, intervalWeeks int, from time.Time) time.Time {
+	first := startDate.AddDate(0, 0, (int(weekday)-int(startDate.Weekday())+7)%7)
+	if !from.After(first) {
+		return first
+	}
+
+	period := 7 * intervalWeeks
+	days := int(from.Sub(first).Hours() / 24)
+	periods := (days + period - 1) / period
+	return first.AddDate(0, 0, periods*period)
+}
+
+// civilDate returns the calendar day of t as a UTC midnight
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// withinWorkingHours reports whether [start, end) falls inside one working
+// window and overlaps no break
+func withinWorkingHours(windows []hours.Window, breaks []BreakPeriod, start, end time.Time) bool {
+	for _, breakPeriod := range breaks {
+		if start.Before(breakPeriod.EndTime) && end.After(breakPeriod.StartTime) {
+			return false
+		}
+	}
+	for _, window := range windows {
+		if !start.Before(window.Start) && !end.After(window.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/money"
+	"github.com/google/uuid"
+)
+
+// BookingRefund is a refund of a booking. A booking rescheduled to a higher
+// total was paid by its original payment and a balance payment, so its refund
+// may be made up of one refund per payment.
+type BookingRefund struct {
+	BookingID uuid.UUID                `json:"booking_id"`
+	Amount    float64                  `json:"amount"`
+	Refunds   []*RefundPaymentResponse `json:"refunds"`
+}
+
+// paidPayment is a payment a booking's refund can be taken from. A zero
+// amount means the paid amount is not known and left to payment-service.
+type paidPayment struct {
+	ID     uuid.UUID
+	Amount float64
+}
+
+// refundPart is the refund requested from one payment; a nil amount refunds
+// the payment's remaining balance
+type refundPart struct {
+	PaymentID uuid.UUID
+	Amount    *float64
+}
+
+// refundablePayments lists the payments a booking was paid with, the balance
+// payment of a reschedule first. Amounts are only looked up when there is a
+// balance payment to split the refund with.
+func (s *bookingService) refundablePayments(ctx context.Context, booking *model.Booking) ([]paidPayment, error) {
+	paymentID, err := uuid.Parse(*booking.PaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment ID: %w", err)
+	}
+
+	// An unsettled balance payment has nothing to refund
+	if booking.BalancePaymentID == nil || booking.PaymentStatus != model.PaymentStatusPaid {
+		return []paidPayment{{ID: paymentID}}, nil
+	}
+	balancePaymentID, err := uuid.Parse(*booking.BalancePaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid balance payment ID: %w", err)
+	}
+
+	payments, err := s.paymentClient.GetPaymentsByBooking(ctx, booking.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking payments: %w", err)
+	}
+	amounts := make(map[uuid.UUID]float64, len(payments))
+	for _, payment := range payments {
+		amounts[payment.ID] = payment.Amount
+	}
+
+	paid := make([]paidPayment, 0, 2)
+	for _, id := range []uuid.UUID{balancePaymentID, paymentID} {
+		amount, ok := amounts[id]
+		if !ok {
+			return nil, fmt.Errorf("payment %s of booking %s not found", id, booking.ID)
+		}
+		paid = append(paid, paidPayment{ID: id, Amount: amount})
+	}
+	return paid, nil
+}
+
+// splitRefund spreads a refund over payments in order, taking no more from
+// each than it paid. A nil amount refunds every payment in full.
+func splitRefund(payments []paidPayment, amount *float64) ([]refundPart, error) {
+	parts := make([]refundPart, 0, len(payments))
+	if amount == nil {
+		for _, payment := range payments {
+			parts = append(parts, refundPart{PaymentID: payment.ID})
+		}
+		return parts, nil
+	}
+
+	// A single payment is capped by payment-service, which knows its earlier refunds
+	if len(payments) == 1 {
+		return []refundPart{{PaymentID: payments[0].ID, Amount: amount}}, nil
+	}
+
+	remaining := *amount
+	for _, payment := range payments {
+		if remaining <= 0 {
+			break
+		}
+		share := remaining
+		if share > payment.Amount {
+			share = payment.Amount
+		}
+		if share <= 0 {
+			continue
+		}
+		parts = append(parts, refundPart{PaymentID: payment.ID, Amount: &share})
+		remaining = money.Sub(remaining, share)
+	}
+	if remaining > 0 {
+		return nil, sharederrors.NewValidationError("amount", fmt.Sprintf("refund amount %.2f exceeds the amount paid for the booking", *amount))
+	}
+	return parts, nil
+}
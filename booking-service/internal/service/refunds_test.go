@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func TestSplitRefund(t *testing.T) {
+	balance, original := uuid.New(), uuid.New()
+	payments := []paidPayment{{ID: balance, Amount: 500}, {ID: original, Amount: 1000}}
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		payments []paidPayment
+		amount   *float64
+		want     map[uuid.UUID]float64
+		wantErr  bool
+	}{
+		{name: "within the balance payment", payments: payments, amount: amount(300), want: map[uuid.UUID]float64{balance: 300}},
+		{name: "balance payment first", payments: payments, amount: amount(1350), want: map[uuid.UUID]float64{balance: 500, original: 850}},
+		{name: "everything paid", payments: payments, amount: amount(1500), want: map[uuid.UUID]float64{balance: 500, original: 1000}},
+		{name: "more than was paid", payments: payments, amount: amount(1500.01), wantErr: true},
+		{name: "single payment left to payment-service", payments: []paidPayment{{ID: original}}, amount: amount(900), want: map[uuid.UUID]float64{original: 900}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := splitRefund(tt.payments, tt.amount)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("split %v into %+v, want an error", *tt.amount, parts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitRefund: %v", err)
+			}
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got %d refunds, want %d", len(parts), len(tt.want))
+			}
+			for _, part := range parts {
+				if part.Amount == nil || *part.Amount != tt.want[part.PaymentID] {
+					t.Errorf("refund of %s = %v, want %v", part.PaymentID, part.Amount, tt.want[part.PaymentID])
+				}
+			}
+		})
+	}
+
+	t.Run("full refund", func(t *testing.T) {
+		parts, err := splitRefund(payments, nil)
+		if err != nil {
+			t.Fatalf("splitRefund: %v", err)
+		}
+		if len(parts) != 2 || parts[0].PaymentID != balance || parts[1].PaymentID != original {
+			t.Fatalf("full refund = %+v, want the balance then the original payment", parts)
+		}
+		for _, part := range parts {
+			if part.Amount != nil {
+				t.Errorf("full refund of %s asks for %v, want the remaining balance", part.PaymentID, *part.Amount)
+			}
+		}
+	})
+}
+
+// fakePaymentService serves the payment-service endpoints used for refunds
+type fakePaymentService struct {
+	mu       sync.Mutex
+	payments []BookingPayment
+	refunds  map[uuid.UUID]float64
+}
+
+func (f *fakePaymentService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/payments"):
+		json.NewEncoder(w).Encode(map[string]interface{}{"payments": f.payments, "count": len(f.payments)})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/refund"):
+		var request RefundPaymentRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		for _, payment := range f.payments {
+			if payment.ID != request.PaymentID {
+				continue
+			}
+			amount := payment.Amount
+			if request.Amount != nil {
+				amount = *request.Amount
+			}
+			if amount > payment.Amount-f.refunds[payment.ID] {
+				http.Error(w, "refund amount exceeds refundable balance", http.StatusBadRequest)
+				return
+			}
+			f.refunds[payment.ID] += amount
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(RefundPaymentResponse{RefundID: uuid.New(), PaymentID: payment.ID, Amount: amount, Status: "success"})
+			return
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCancelRefundsRescheduleBalancePayment(t *testing.T) {
+	userID, salonID, branchID := uuid.New(), uuid.New(), uuid.New()
+	originalID, balanceID := uuid.New(), uuid.New()
+	start := time.Now().Add(6 * time.Hour).Truncate(time.Minute)
+	booking := &model.Booking{
+		ID:            uuid.New(),
+		UserID:        userID,
+		SalonID:       salonID,
+		BranchID:      branchID,
+		Status:        model.BookingStatusRescheduled,
+		TotalAmount:   1500,
+		PaymentStatus: model.PaymentStatusPaid,
+		PaymentID:     stringPtr(originalID.String()),
+		// Rescheduled from 1000 to 1500 and the balance settled
+		BalancePaymentID: stringPtr(balanceID.String()),
+		Version:          3,
+		Services:         []model.BookingService{{ID: uuid.New(), StylistID: uuid.New(), StartTime: start, EndTime: start.Add(time.Hour)}},
+	}
+
+	payments := &fakePaymentService{
+		payments: []BookingPayment{{ID: originalID, Amount: 1000, Status: "success"}, {ID: balanceID, Amount: 500, Status: "success"}},
+		refunds:  make(map[uuid.UUID]float64),
+	}
+	server := httptest.NewServer(payments)
+	defer server.Close()
+
+	repo := &fakeRepository{
+		bookings: map[uuid.UUID]*model.Booking{booking.ID: booking},
+		// 10% fee inside 24 hours of the appointment
+		branchConfig: &model.BranchConfiguration{BranchID: branchID, CancellationCutoffHours: 2, CancellationFeePercentage: 10, CancellationFeeWindowHours: 24},
+	}
+	s := newTestService(repo, &fakeExternalService{
+		salon:  &SalonInfo{ID: salonID},
+		branch: &BranchInfo{ID: branchID, SalonID: salonID},
+	})
+	s.paymentClient = NewPaymentClient(server.URL, ResiliencePolicy{Timeout: 5 * time.Second})
+	s.eventPublisher = &fakeEventPublisher{}
+
+	ctx := context.Background()
+	if err := s.CancelBooking(ctx, booking.ID, userID, "cannot make it"); err != nil {
+		t.Fatalf("CancelBooking: %v", err)
+	}
+	s.Shutdown(ctx)
+
+	if got := payments.refunds[balanceID]; got != 500 {
+		t.Errorf("balance payment refunded %v, want 500", got)
+	}
+	if got := payments.refunds[originalID]; got != 850 {
+		t.Errorf("original payment refunded %v, want 850", got)
+	}
+
+	canceled := historyValues(t, repo, model.BookingActionCanceled)
+	if canceled["refund_status"] == "failed" {
+		t.Errorf("cancellation refund failed: %v", canceled)
+	}
+	if canceled["cancellation_fee"] != 150.0 || canceled["refund_amount"] != 1350.0 {
+		t.Errorf("history records fee %v and refund %v, want 150 and 1350", canceled["cancellation_fee"], canceled["refund_amount"])
+	}
+
+	// The salon kept the fee, so the booking is not fully refunded
+	stored, _ := repo.GetByID(ctx, booking.ID)
+	if stored.PaymentStatus != model.PaymentStatusPaid {
+		t.Errorf("payment status = %s, want %s", stored.PaymentStatus, model.PaymentStatusPaid)
+	}
+	refunded := historyValues(t, repo, model.BookingActionPaymentAdjusted)
+	if refunded["refund_amount"] != 1350.0 || refunded["retained_amount"] != 150.0 {
+		t.Errorf("refund history records %v refunded and %v retained, want 1350 and 150", refunded["refund_amount"], refunded["retained_amount"])
+	}
+}
+
+func TestCancelOutsideFeeWindowRefundsInFull(t *testing.T) {
+	userID, salonID, branchID, paymentID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	booking := &model.Booking{
+		ID:            uuid.New(),
+		UserID:        userID,
+		SalonID:       salonID,
+		BranchID:      branchID,
+		Status:        model.BookingStatusConfirmed,
+		TotalAmount:   1000,
+		PaymentStatus: model.PaymentStatusPaid,
+		PaymentID:     stringPtr(paymentID.String()),
+		Version:       2,
+		Services:      []model.BookingService{{ID: uuid.New(), StylistID: uuid.New(), StartTime: start, EndTime: start.Add(time.Hour)}},
+	}
+
+	payments := &fakePaymentService{
+		payments: []BookingPayment{{ID: paymentID, Amount: 1000, Status: "success"}},
+		refunds:  make(map[uuid.UUID]float64),
+	}
+	server := httptest.NewServer(payments)
+	defer server.Close()
+
+	repo := &fakeRepository{
+		bookings:     map[uuid.UUID]*model.Booking{booking.ID: booking},
+		branchConfig: &model.BranchConfiguration{BranchID: branchID, CancellationCutoffHours: 2, CancellationFeePercentage: 10, CancellationFeeWindowHours: 24},
+	}
+	s := newTestService(repo, &fakeExternalService{
+		salon:  &SalonInfo{ID: salonID},
+		branch: &BranchInfo{ID: branchID, SalonID: salonID},
+	})
+	s.paymentClient = NewPaymentClient(server.URL, ResiliencePolicy{Timeout: 5 * time.Second})
+	s.eventPublisher = &fakeEventPublisher{}
+
+	ctx := context.Background()
+	if err := s.CancelBooking(ctx, booking.ID, userID, "plans changed"); err != nil {
+		t.Fatalf("CancelBooking: %v", err)
+	}
+	s.Shutdown(ctx)
+
+	if got := payments.refunds[paymentID]; got != 1000 {
+		t.Errorf("payment refunded %v, want 1000", got)
+	}
+	stored, _ := repo.GetByID(ctx, booking.ID)
+	if stored.PaymentStatus != model.PaymentStatusRefunded {
+		t.Errorf("payment status = %s, want %s", stored.PaymentStatus, model.PaymentStatusRefunded)
+	}
+	if refunded := historyValues(t, repo, model.BookingActionPaymentAdjusted); refunded["retained_amount"] != nil {
+		t.Errorf("full refund records %v retained", refunded["retained_amount"])
+	}
+}
+
+// historyValues decodes the new values of the only history entry with the action
+func historyValues(t *testing.T, repo *fakeRepository, action model.BookingAction) map[string]interface{} {
+	t.Helper()
+	var found []*model.BookingHistory
+	for _, history := range repo.history {
+		if history.Action == action {
+			found = append(found, history)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("recorded %d %s history entries, want 1", len(found), action)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(*found[0].NewValues), &values); err != nil {
+		t.Fatalf("decode %s history: %v", action, err)
+	}
+	return values
+}
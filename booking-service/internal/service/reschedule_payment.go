@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/money"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// initiateBalanceCharge starts a payment for the difference when a paid
+// booking is rescheduled to a higher total. No gateway is named, so
+// payment-service routes the charge by currency like any other payment.
+func (s *bookingService) initiateBalanceCharge(ctx context.Context, booking *model.Booking, amount float64) (*InitiatePaymentResponse, error) {
+	return s.paymentClient.InitiatePayment(ctx, &InitiatePaymentRequest{
+		BookingID:      booking.ID,
+		UserID:         booking.UserID,
+		Amount:         amount,
+		Currency:       s.config.Currency,
+		IdempotencyKey: fmt.Sprintf("balance-%s-%d", booking.ID.String(), time.Now().Unix()),
+		Description:    fmt.Sprintf("Balance for rescheduled booking %s", booking.ID.String()),
+	})
+}
+
+// refundRescheduleDifference refunds part of the original payment when a paid
+// booking is rescheduled to a lower total. The reschedule has already been
+// saved, so a failed refund is reported in the adjustment rather than undoing it.
+func (s *bookingService) refundRescheduleDifference(ctx context.Context, booking *model.Booking, amount float64, reason string) *model.PaymentAdjustment {
+	adjustment := &model.PaymentAdjustment{
+		Type:      model.PaymentAdjustmentRefund,
+		Amount:    amount,
+		PaymentID: booking.PaymentID,
+		Status:    "failed",
+	}
+	if booking.PaymentID == nil {
+		log.Error().Str("booking_id", booking.ID.String()).Msg("Paid booking has no payment to refund reschedule difference from")
+		return adjustment
+	}
+	paymentID, err := uuid.Parse(*booking.PaymentID)
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", booking.ID.String()).Msg("Invalid payment ID on rescheduled booking")
+		return adjustment
+	}
+
+	if reason == "" {
+		reason = "booking rescheduled"
+	}
+	refund, err := s.paymentClient.RefundPayment(ctx, &RefundPaymentRequest{
+		PaymentID:      paymentID,
+		Amount:         &amount,
+		Reason:         reason,
+		IdempotencyKey: fmt.Sprintf("reschedule-refund-%s-%d", booking.ID.String(), time.Now().Unix()),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("booking_id", booking.ID.String()).Float64("amount", amount).Msg("Failed to refund reschedule difference")
+		return adjustment
+	}
+
+	refundID := refund.RefundID.String()
+	adjustment.RefundID = &refundID
+	adjustment.Status = refund.Status
+	return adjustment
+}
+
+// recordPaymentAdjustment adds the totals and payment-service operation of a
+// payment adjustment to the booking history
+func (s *bookingService) recordPaymentAdjustment(ctx context.Context, bookingID uuid.UUID, userID *uuid.UUID, oldTotal, newTotal float64, adjustment *model.PaymentAdjustment) {
+	oldValues, _ := json.Marshal(map[string]interface{}{"total_amount": oldTotal})
+	newValues, _ := json.Marshal(map[string]interface{}{
+		"total_amount": newTotal,
+		"delta":        money.Sub(newTotal, oldTotal),
+		"type":         adjustment.Type,
+		"amount":       adjustment.Amount,
+		"payment_id":   adjustment.PaymentID,
+		"refund_id":    adjustment.RefundID,
+		"status":       adjustment.Status,
+	})
+	history := &model.BookingHistory{
+		ID:        uuid.New(),
+		BookingID: bookingID,
+		Action:    model.BookingActionPaymentAdjusted,
+		OldValues: stringPtr(string(oldValues)),
+		NewValues: stringPtr(string(newValues)),
+		UserID:    userID,
+	}
+	if err := s.repo.CreateHistory(ctx, history); err != nil {
+		log.Warn().Err(err).Msg("Failed to create booking history")
+	}
+}
+
+// settleBalancePayment marks the outstanding balance of a rescheduled booking
+// paid when paymentID is its balance payment, and reports whether it was
+func (s *bookingService) settleBalancePayment(ctx context.Context, bookingID, paymentID uuid.UUID) (bool, error) {
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return false, bookingLookupError(bookingID, err)
+	}
+	if booking.BalancePaymentID == nil || *booking.BalancePaymentID != paymentID.String() {
+		return false, nil
+	}
+
+	settled, err := s.repo.MarkBalancePaid(ctx, bookingID, paymentID.String())
+	if err != nil {
+		return true, err
+	}
+	// A replayed callback finds the balance already settled
+	if !settled {
+		return true, nil
+	}
+
+	history := &model.BookingHistory{
+		ID:        uuid.New(),
+		BookingID: bookingID,
+		Action:    model.BookingActionPaymentAdjusted,
+		NewValues: stringPtr(fmt.Sprintf(`{"payment_id": "%s", "payment_status": "%s"}`, paymentID, model.PaymentStatusPaid)),
+	}
+	if err := s.repo.CreateHistory(ctx, history); err != nil {
+		log.Warn().Err(err).Msg("Failed to create booking history")
+	}
+
+	log.Info().
+		Str("booking_id", bookingID.String()).
+		Str("payment_id", paymentID.String()).
+		Msg("Balance payment settled for rescheduled booking")
+
+	return true, nil
+}
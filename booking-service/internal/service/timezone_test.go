@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"booking-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func TestBranchLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		branch   *BranchInfo
+		wantZone string
+	}{
+		{name: "no branch", branch: nil, wantZone: "UTC"},
+		{name: "no time zone", branch: &BranchInfo{}, wantZone: "UTC"},
+		{name: "unknown time zone", branch: &BranchInfo{Timezone: "Mars/Olympus_Mons"}, wantZone: "UTC"},
+		{name: "branch time zone", branch: &BranchInfo{Timezone: "Asia/Kolkata"}, wantZone: "Asia/Kolkata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := branchLocation(tt.branch).String(); got != tt.wantZone {
+				t.Errorf("branchLocation() = %s, want %s", got, tt.wantZone)
+			}
+		})
+	}
+}
+
+// Asia/Kolkata is UTC+05:30, so the first five and a half hours of a local
+// day fall on the previous UTC day
+func TestInLocationKolkataDayBoundary(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	loc := branchLocation(&BranchInfo{Timezone: "Asia/Kolkata"})
+
+	tests := []struct {
+		name      string
+		start     time.Time
+		wantDate  string
+		wantClock string
+	}{
+		{name: "just before local midnight", start: time.Date(2030, 3, 14, 18, 29, 0, 0, time.UTC), wantDate: "2030-03-14", wantClock: "23:59"},
+		{name: "at local midnight", start: time.Date(2030, 3, 14, 18, 30, 0, 0, time.UTC), wantDate: "2030-03-15", wantClock: "00:00"},
+		{name: "after local midnight on the previous UTC day", start: time.Date(2030, 3, 14, 19, 0, 0, 0, time.UTC), wantDate: "2030-03-15", wantClock: "00:30"},
+		{name: "late on the UTC day", start: time.Date(2030, 3, 15, 23, 45, 0, 0, time.UTC), wantDate: "2030-03-16", wantClock: "05:15"},
+		{name: "sent with the branch offset", start: time.Date(2030, 3, 15, 0, 30, 0, 0, kolkata), wantDate: "2030-03-15", wantClock: "00:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := []InitiateBookingServiceItem{{StartTime: tt.start}}
+			inLocation(services, loc)
+
+			got := services[0].StartTime
+			if !got.Equal(tt.start) {
+				t.Fatalf("start moved from %s to %s", tt.start, got)
+			}
+			if date := got.Format("2006-01-02"); date != tt.wantDate {
+				t.Errorf("local date = %s, want %s", date, tt.wantDate)
+			}
+			if clock := got.Format("15:04"); clock != tt.wantClock {
+				t.Errorf("local time = %s, want %s", clock, tt.wantClock)
+			}
+			if _, offset := got.Zone(); offset != 5*3600+30*60 {
+				t.Errorf("offset = %ds, want +05:30", offset)
+			}
+		})
+	}
+}
+
+// A Kolkata day from 00:00 to 02:00 local is 18:30 to 20:30 UTC on the
+// previous day; slots and the booking lookup must use the local day
+func TestGetStylistAvailabilityKolkataDayBoundary(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	salonID, branchID, stylistID := uuid.New(), uuid.New(), uuid.New()
+	localDay := time.Date(2030, 3, 15, 0, 0, 0, 0, kolkata)
+
+	external := &fakeExternalService{
+		salon:    &SalonInfo{ID: salonID},
+		branch:   &BranchInfo{ID: branchID, SalonID: salonID, Timezone: "Asia/Kolkata"},
+		stylists: map[uuid.UUID]*StylistInfo{stylistID: {ID: stylistID, BranchID: branchID}},
+		schedule: func(_ uuid.UUID, date time.Time) *StylistSchedule {
+			if !date.Equal(localDay) {
+				t.Errorf("schedule requested for %s, want %s", date, localDay)
+			}
+			return &StylistSchedule{
+				StylistID:    stylistID,
+				Date:         date,
+				WorkingHours: []WorkingHour{{StartTime: date, EndTime: date.Add(2 * time.Hour)}},
+			}
+		},
+	}
+	repo := &fakeRepository{
+		branchConfig: &model.BranchConfiguration{BranchID: branchID, SlotIntervalMinutes: 30},
+		// 00:30-01:00 in Kolkata, on 14 March in UTC
+		stylistBookings: []*model.BookingService{{
+			StylistID: stylistID,
+			StartTime: time.Date(2030, 3, 14, 19, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2030, 3, 14, 19, 30, 0, 0, time.UTC),
+		}},
+	}
+	s := newTestService(repo, external)
+
+	// Clients send the calendar date; the handler parses it as a UTC midnight
+	slots, err := s.GetStylistAvailability(context.Background(), salonID, stylistID, time.Date(2030, 3, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetStylistAvailability: %v", err)
+	}
+
+	wantStarts := []string{"2030-03-15T00:00:00+05:30", "2030-03-15T01:00:00+05:30", "2030-03-15T01:30:00+05:30"}
+	if len(slots) != len(wantStarts) {
+		t.Fatalf("got %d slots, want %d: %v", len(slots), len(wantStarts), slotStarts(slots))
+	}
+	for i, slot := range slots {
+		if got := slot.StartTime.Format(time.RFC3339); got != wantStarts[i] {
+			t.Errorf("slot %d starts %s, want %s", i, got, wantStarts[i])
+		}
+	}
+	if utcDate := slots[0].StartTime.UTC().Format("2006-01-02"); utcDate != "2030-03-14" {
+		t.Errorf("first slot is on %s in UTC, want the previous day 2030-03-14", utcDate)
+	}
+
+	if len(repo.bookingRanges) != 1 {
+		t.Fatalf("stylist bookings looked up %d times, want 1", len(repo.bookingRanges))
+	}
+	wantFrom, wantTo := time.Date(2030, 3, 14, 18, 30, 0, 0, time.UTC), time.Date(2030, 3, 15, 18, 30, 0, 0, time.UTC)
+	if from, to := repo.bookingRanges[0][0], repo.bookingRanges[0][1]; !from.Equal(wantFrom) || !to.Equal(wantTo) {
+		t.Errorf("bookings looked up for %s to %s, want %s to %s", from, to, wantFrom, wantTo)
+	}
+}
+
+func slotStarts(slots []*model.TimeSlot) []string {
+	starts := make([]string, len(slots))
+	for i, slot := range slots {
+		starts[i] = slot.StartTime.Format(time.RFC3339)
+	}
+	return starts
+}
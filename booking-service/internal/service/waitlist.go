@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"booking-service/internal/model"
+	"booking-service/internal/repository"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// JoinWaitlistRequest asks to be notified when a stylist has a slot free
+// within [DesiredStart, DesiredEnd)
+type JoinWaitlistRequest struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	SalonID      uuid.UUID  `json:"salon_id"`
+	StylistID    uuid.UUID  `json:"stylist_id"`
+	ServiceID    *uuid.UUID `json:"service_id,omitempty"`
+	DesiredStart time.Time  `json:"desired_start"`
+	DesiredEnd   time.Time  `json:"desired_end"`
+}
+
+// JoinWaitlist adds the user to a stylist's waitlist for the desired window.
+// The earliest waiting user is notified when a booking in that window is
+// cancelled or rescheduled.
+func (s *bookingService) JoinWaitlist(ctx context.Context, request *JoinWaitlistRequest) (*model.WaitlistEntry, error) {
+	if !request.DesiredEnd.After(request.DesiredStart) {
+		return nil, sharederrors.NewValidationError("desired_end", "desired_end must be after desired_start")
+	}
+	if !request.DesiredEnd.After(time.Now()) {
+		return nil, sharederrors.NewValidationError("desired_end", "desired window has already passed")
+	}
+
+	stylist, err := s.externalService.GetStylist(ctx, request.SalonID, request.StylistID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stylist %s: %w", request.StylistID, err)
+	}
+
+	if request.ServiceID != nil {
+		if err := s.validateStylistOffersService(ctx, request.SalonID, request.StylistID, *request.ServiceID); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := &model.WaitlistEntry{
+		ID:           uuid.New(),
+		UserID:       request.UserID,
+		SalonID:      request.SalonID,
+		BranchID:     stylist.BranchID,
+		StylistID:    request.StylistID,
+		ServiceID:    request.ServiceID,
+		DesiredStart: request.DesiredStart,
+		DesiredEnd:   request.DesiredEnd,
+		Status:       model.WaitlistStatusWaiting,
+	}
+	if err := s.repo.CreateWaitlistEntry(ctx, entry); err != nil {
+		if errors.Is(err, repository.ErrDuplicateWaitlistEntry) {
+			return nil, sharederrors.NewConflictError("waitlist", err.Error())
+		}
+		return nil, err
+	}
+
+	log.Info().
+		Str("waitlist_entry_id", entry.ID.String()).
+		Str("user_id", entry.UserID.String()).
+		Str("stylist_id", entry.StylistID.String()).
+		Msg("User joined waitlist")
+
+	return entry, nil
+}
+
+// LeaveWaitlist withdraws one of the user's waiting entries
+func (s *bookingService) LeaveWaitlist(ctx context.Context, entryID, userID uuid.UUID) error {
+	if err := s.repo.CancelWaitlistEntry(ctx, entryID, userID); err != nil {
+		if errors.Is(err, repository.ErrWaitlistEntryNotFound) {
+			return sharederrors.NewNotFoundError("waitlist entry", entryID.String())
+		}
+		return err
+	}
+	return nil
+}
+
+// GetUserWaitlist retrieves a page of the user's waitlist entries and the total number they have
+func (s *bookingService) GetUserWaitlist(ctx context.Context, userID uuid.UUID, page pagination.Params) ([]*model.WaitlistEntry, int, error) {
+	page = page.Normalize()
+
+	entries, err := s.repo.GetWaitlistByUserID(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.repo.CountWaitlistByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// ExpireWaitlistEntries expires waiting entries whose desired window has
+// passed and returns how many were expired
+func (s *bookingService) ExpireWaitlistEntries(ctx context.Context) (int, error) {
+	return s.repo.ExpireWaitlistEntries(ctx, time.Now())
+}
+
+// freedSlots lists the stylist slots a booking held, skipping any still
+// covered by the same stylist in replacement (the booking's new services
+// when it is rescheduled)
+func freedSlots(bookingID uuid.UUID, services, replacement []model.BookingService) []model.FreedSlot {
+	var slots []model.FreedSlot
+	for _, service := range services {
+		if !service.EndTime.After(time.Now()) {
+			continue
+		}
+
+		covered := false
+		for _, kept := range replacement {
+			if kept.StylistID == service.StylistID &&
+				!kept.StartTime.After(service.StartTime) && !kept.EndTime.Before(service.EndTime) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			slots = append(slots, model.FreedSlot{
+				BookingID: bookingID,
+				StylistID: service.StylistID,
+				StartTime: service.StartTime,
+				EndTime:   service.EndTime,
+			})
+		}
+	}
+	return slots
+}
+
+// notifyWaitlist offers each freed slot to the earliest user waiting for it
+func (s *bookingService) notifyWaitlist(ctx context.Context, slots []model.FreedSlot) {
+	for _, slot := range slots {
+		entry, err := s.repo.ClaimWaitlistEntryForSlot(ctx, slot)
+		if err != nil {
+			log.Error().Err(err).Str("stylist_id", slot.StylistID.String()).Msg("Failed to claim waitlist entry")
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+
+		log.Info().
+			Str("waitlist_entry_id", entry.ID.String()).
+			Str("user_id", entry.UserID.String()).
+			Str("stylist_id", slot.StylistID.String()).
+			Time("slot_start", slot.StartTime).
+			Msg("Notifying waitlisted user of freed slot")
+
+		s.sendSlotFreedNotifications(ctx, entry, slot)
+	}
+}
+
+// sendSlotFreedNotifications tells a waitlisted user that a slot in their window opened up
+func (s *bookingService) sendSlotFreedNotifications(ctx context.Context, entry *model.WaitlistEntry, slot model.FreedSlot) {
+	bookingEvent, err := s.newBookingEvent(ctx, EventBookingSlotFreed, slot.BookingID, entry.UserID, entry.SalonID, entry.BranchID, slot.StartTime)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to prepare slot freed event")
+		return
+	}
+	bookingEvent.Data["waitlist_entry_id"] = entry.ID.String()
+	bookingEvent.Data["stylist_id"] = slot.StylistID.String()
+	bookingEvent.Data["slot_end_time"] = slot.EndTime.Format("2006-01-02 15:04")
+	if stylist, err := s.externalService.GetStylist(ctx, entry.SalonID, slot.StylistID); err == nil {
+		bookingEvent.Data["stylist_name"] = stylist.Name
+	}
+
+	s.emitBookingEvent(ctx, bookingEvent)
+}
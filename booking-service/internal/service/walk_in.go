@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"booking-service/internal/model"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// WalkInStartGrace lets staff book a walk-in that has just started, since the
+// customer is usually already in the chair when the booking is entered
+const WalkInStartGrace = 15 * time.Minute
+
+// StaffInitiateBookingRequest is a walk-in booking created by front-desk staff
+// for an existing customer (user_id) or a guest identified by name and phone
+type StaffInitiateBookingRequest struct {
+	InitiateBookingRequest
+	Guest *GuestCustomer `json:"guest,omitempty"`
+}
+
+// StaffInitiateBooking creates a booking on a customer's behalf. A guest is
+// resolved to the user registered with their phone number, or to a new guest
+// record in user-service. Availability and pricing rules are the same as for
+// customer bookings; the staff member is recorded as the creator.
+func (s *bookingService) StaffInitiateBooking(ctx context.Context, staffID uuid.UUID, request *StaffInitiateBookingRequest) (*model.Booking, error) {
+	if request.Guest != nil {
+		if request.UserID != uuid.Nil {
+			return nil, sharederrors.NewValidationError("guest", "provide either user_id or guest, not both")
+		}
+		user, err := s.externalService.CreateGuestUser(ctx, request.Guest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve guest: %w", err)
+		}
+		request.UserID = user.ID
+		log.Info().Str("user_id", user.ID.String()).Str("staff_id", staffID.String()).Msg("Walk-in guest resolved")
+	}
+	if request.UserID == uuid.Nil {
+		return nil, sharederrors.NewValidationError("user_id", "user_id or guest is required")
+	}
+
+	request.CreatedByStaffID = &staffID
+	return s.InitiateBooking(ctx, &request.InitiateBookingRequest)
+}
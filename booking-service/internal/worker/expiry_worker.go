@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"booking-service/internal/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ExpiryWorker periodically voids initiated bookings whose payment never completed
+// and expires waitlist entries whose desired window has passed
+type ExpiryWorker struct {
+	bookingService service.BookingService
+	interval       time.Duration
+}
+
+// NewExpiryWorker creates a new expiry worker
+func NewExpiryWorker(bookingService service.BookingService, interval time.Duration) *ExpiryWorker {
+	return &ExpiryWorker{
+		bookingService: bookingService,
+		interval:       interval,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *ExpiryWorker) Start(ctx context.Context) {
+	log.Info().Dur("interval", w.interval).Msg("Starting booking expiry worker")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Booking expiry worker stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *ExpiryWorker) run(ctx context.Context) {
+	expired, err := w.bookingService.ExpireStaleBookings(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to expire stale bookings")
+	} else if expired > 0 {
+		log.Info().Int("count", expired).Msg("Stale bookings expired")
+	}
+
+	lapsed, err := w.bookingService.ExpireWaitlistEntries(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to expire waitlist entries")
+		return
+	}
+
+	if lapsed > 0 {
+		log.Info().Int("count", lapsed).Msg("Waitlist entries expired")
+	}
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"booking-service/internal/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RecurringWorker periodically books the upcoming occurrences of recurring bookings
+type RecurringWorker struct {
+	bookingService service.BookingService
+	interval       time.Duration
+}
+
+// NewRecurringWorker creates a new recurring booking worker
+func NewRecurringWorker(bookingService service.BookingService, interval time.Duration) *RecurringWorker {
+	return &RecurringWorker{
+		bookingService: bookingService,
+		interval:       interval,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *RecurringWorker) Start(ctx context.Context) {
+	log.Info().Dur("interval", w.interval).Msg("Starting recurring booking worker")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Recurring booking worker stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *RecurringWorker) run(ctx context.Context) {
+	created, err := w.bookingService.GenerateRecurringBookings(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate recurring bookings")
+		return
+	}
+
+	if created > 0 {
+		log.Info().Int("count", created).Msg("Recurring booking occurrences booked")
+	}
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"booking-service/internal/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReminderWorker periodically sends reminders for upcoming bookings
+type ReminderWorker struct {
+	bookingService service.BookingService
+	interval       time.Duration
+}
+
+// NewReminderWorker creates a new reminder worker
+func NewReminderWorker(bookingService service.BookingService, interval time.Duration) *ReminderWorker {
+	return &ReminderWorker{
+		bookingService: bookingService,
+		interval:       interval,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *ReminderWorker) Start(ctx context.Context) {
+	log.Info().Dur("interval", w.interval).Msg("Starting booking reminder worker")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Booking reminder worker stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *ReminderWorker) run(ctx context.Context) {
+	sent, err := w.bookingService.SendDueReminders(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send booking reminders")
+		return
+	}
+
+	if sent > 0 {
+		log.Info().Int("count", sent).Msg("Booking reminders sent")
+	}
+}
@@ -0,0 +1,4 @@
+-- Add configurable slot interval to branch_configurations table
+ALTER TABLE branch_configurations
+    ADD COLUMN IF NOT EXISTS slot_interval_minutes INTEGER NOT NULL DEFAULT 30
+    CHECK (slot_interval_minutes IN (10, 15, 20, 30, 60));
@@ -0,0 +1,16 @@
+-- Create booking idempotency records table
+CREATE TABLE IF NOT EXISTS booking_idempotency_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    -- Keys are scoped per user
+    CONSTRAINT uq_booking_idempotency_user_key UNIQUE (user_id, idempotency_key)
+);
+
+-- Create indexes for idempotency records
+CREATE INDEX IF NOT EXISTS idx_booking_idempotency_records_booking_id ON booking_idempotency_records(booking_id);
+CREATE INDEX IF NOT EXISTS idx_booking_idempotency_records_expires_at ON booking_idempotency_records(expires_at);
@@ -0,0 +1,12 @@
+-- Add configurable reminder lead times to branch_configurations table
+ALTER TABLE branch_configurations
+    ADD COLUMN IF NOT EXISTS reminder_lead_times_minutes INTEGER[] NOT NULL DEFAULT '{1440,120}';
+
+-- Create booking_reminders table to track sent reminders
+CREATE TABLE IF NOT EXISTS booking_reminders (
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    threshold_minutes INTEGER NOT NULL CHECK (threshold_minutes > 0),
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (booking_id, threshold_minutes)
+);
@@ -0,0 +1,6 @@
+-- Add cancellation fee policy to branch_configurations table
+ALTER TABLE branch_configurations
+    ADD COLUMN IF NOT EXISTS cancellation_fee_percentage DECIMAL(5,2) NOT NULL DEFAULT 0.00
+    CHECK (cancellation_fee_percentage >= 0 AND cancellation_fee_percentage <= 100),
+    ADD COLUMN IF NOT EXISTS cancellation_fee_window_hours INTEGER NOT NULL DEFAULT 24
+    CHECK (cancellation_fee_window_hours >= 0);
@@ -0,0 +1,39 @@
+-- Create promo_codes table
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) NOT NULL,
+    -- NULL makes the code valid at every salon
+    salon_id UUID,
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'flat')),
+    discount_value DECIMAL(10,2) NOT NULL CHECK (discount_value > 0),
+    -- Caps percentage discounts; NULL means no cap
+    max_discount_amount DECIMAL(10,2) CHECK (max_discount_amount > 0),
+    min_spend DECIMAL(10,2) NOT NULL DEFAULT 0.00 CHECK (min_spend >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    -- NULL limits mean unlimited redemptions
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    per_user_limit INTEGER CHECK (per_user_limit > 0),
+    usage_count INTEGER NOT NULL DEFAULT 0 CHECK (usage_count >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT chk_promo_codes_percentage CHECK (discount_type <> 'percentage' OR discount_value <= 100)
+);
+
+-- Codes are matched case-insensitively
+CREATE UNIQUE INDEX IF NOT EXISTS uq_promo_codes_code ON promo_codes(UPPER(code));
+
+CREATE TRIGGER update_promo_codes_updated_at
+    BEFORE UPDATE ON promo_codes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Record the promo code applied to a booking and the discount it gave
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS promo_code VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00
+    CHECK (discount_amount >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_user_promo_code ON bookings(user_id, promo_code) WHERE promo_code IS NOT NULL;
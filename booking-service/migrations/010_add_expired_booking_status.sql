@@ -0,0 +1,11 @@
+-- Allow initiated bookings to be voided as expired when payment never completes
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('initiated', 'confirmed', 'rescheduled', 'canceled', 'completed', 'expired'));
+
+ALTER TABLE booking_history DROP CONSTRAINT IF EXISTS booking_history_action_check;
+ALTER TABLE booking_history ADD CONSTRAINT booking_history_action_check
+    CHECK (action IN ('created', 'confirmed', 'rescheduled', 'canceled', 'completed', 'expired'));
+
+-- Supports the expiry worker's scan for stale initiated bookings
+CREATE INDEX IF NOT EXISTS idx_bookings_initiated_created_at ON bookings(created_at) WHERE status = 'initiated';
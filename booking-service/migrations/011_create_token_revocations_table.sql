@@ -0,0 +1,7 @@
+-- Shared with user-service, which records revocations; the auth middleware
+-- rejects access tokens issued before tokens_valid_after
+CREATE TABLE IF NOT EXISTS token_revocations (
+    subject_id UUID PRIMARY KEY,
+    tokens_valid_after TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
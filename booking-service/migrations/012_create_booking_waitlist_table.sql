@@ -0,0 +1,35 @@
+-- Create booking_waitlist table for customers waiting on a taken stylist slot
+CREATE TABLE IF NOT EXISTS booking_waitlist (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    salon_id UUID NOT NULL,
+    branch_id UUID NOT NULL,
+    stylist_id UUID NOT NULL,
+    -- NULL means any service the stylist offers
+    service_id UUID,
+    desired_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    desired_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'notified', 'cancelled', 'expired')),
+    -- Booking whose freed slot the user was notified about
+    notified_booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT chk_booking_waitlist_window CHECK (desired_end > desired_start)
+);
+
+-- Supports finding the earliest waiting entry when a stylist's slot is freed
+CREATE INDEX IF NOT EXISTS idx_booking_waitlist_waiting ON booking_waitlist(stylist_id, created_at) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS idx_booking_waitlist_user ON booking_waitlist(user_id, created_at DESC);
+-- Supports the expiry worker's scan for lapsed entries
+CREATE INDEX IF NOT EXISTS idx_booking_waitlist_waiting_end ON booking_waitlist(desired_end) WHERE status = 'waiting';
+
+-- A user can hold one active entry per stylist and window
+CREATE UNIQUE INDEX IF NOT EXISTS uq_booking_waitlist_active ON booking_waitlist(user_id, stylist_id, desired_start, desired_end)
+    WHERE status = 'waiting';
+
+CREATE TRIGGER update_booking_waitlist_updated_at
+    BEFORE UPDATE ON booking_waitlist
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
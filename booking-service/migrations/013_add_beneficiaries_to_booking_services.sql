@@ -0,0 +1,8 @@
+-- Group bookings: each booked service can be for someone other than the paying user.
+-- Both columns NULL means the service is for the booking's own user.
+ALTER TABLE booking_services
+    ADD COLUMN IF NOT EXISTS beneficiary_name VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS beneficiary_user_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_booking_services_beneficiary_user_id ON booking_services(beneficiary_user_id)
+    WHERE beneficiary_user_id IS NOT NULL;
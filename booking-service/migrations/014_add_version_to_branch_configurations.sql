@@ -0,0 +1,4 @@
+-- Optimistic locking: updates must name the version they read and bump it,
+-- so concurrent edits cannot silently overwrite each other
+ALTER TABLE branch_configurations
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1 CHECK (version > 0);
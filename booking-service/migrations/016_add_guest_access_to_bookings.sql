@@ -0,0 +1,4 @@
+-- Guest checkout: bookings made without an account carry the hash of the
+-- access token the guest uses to check and cancel the booking.
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS guest_access_token_hash VARCHAR(64);
@@ -0,0 +1,9 @@
+-- Rescheduling a paid booking to a higher total charges the difference as a
+-- separate balance payment; the booking stays payment_status 'pending' until
+-- that payment completes.
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS balance_payment_id VARCHAR(255);
+
+ALTER TABLE booking_history DROP CONSTRAINT IF EXISTS booking_history_action_check;
+ALTER TABLE booking_history ADD CONSTRAINT booking_history_action_check
+    CHECK (action IN ('created', 'confirmed', 'rescheduled', 'canceled', 'completed', 'expired', 'payment_adjusted'));
@@ -0,0 +1,6 @@
+-- Bookings record the IANA time zone of their branch so times can be shown
+-- and compared in branch wall-clock time. All booking time columns are
+-- already TIMESTAMP WITH TIME ZONE; bookings made before this migration have
+-- no recorded zone and are treated as UTC.
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
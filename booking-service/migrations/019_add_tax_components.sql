@@ -0,0 +1,15 @@
+-- Branches may split their tax into named components (e.g. CGST and SGST);
+-- gst_percentage stays as the total of the components. Existing branches get
+-- a single GST component at their current rate.
+ALTER TABLE branch_configurations
+    ADD COLUMN IF NOT EXISTS tax_components JSONB NOT NULL DEFAULT '[]';
+
+UPDATE branch_configurations
+SET tax_components = jsonb_build_array(jsonb_build_object('name', 'GST', 'percentage', gst_percentage))
+WHERE gst_percentage > 0 AND tax_components = '[]';
+
+-- The tax breakdown charged on a booking, so receipts show the components and
+-- taxable amount in effect when it was priced. Bookings made before this
+-- migration have none; their gst was charged on the whole discounted subtotal.
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS taxes JSONB;
@@ -0,0 +1,5 @@
+-- Optimistic locking: booking updates must name the version they read and
+-- bump it, so a reschedule and a cancellation racing on the same booking
+-- cannot silently overwrite each other
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1 CHECK (version > 0);
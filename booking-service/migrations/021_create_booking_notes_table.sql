@@ -0,0 +1,13 @@
+-- Create booking_notes table for notes added to a booking after creation,
+-- such as allergies or products used. Notes are append-only.
+CREATE TABLE IF NOT EXISTS booking_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL,
+    -- customer notes are shown to the booking's customer; internal notes only to staff
+    visibility VARCHAR(20) NOT NULL CHECK (visibility IN ('customer', 'internal')),
+    note TEXT NOT NULL CHECK (length(note) > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_notes_booking ON booking_notes(booking_id, created_at);
@@ -0,0 +1,55 @@
+-- Create recurring_bookings table for standing appointments. A generator
+-- books each occurrence as a regular booking a configurable horizon ahead.
+CREATE TABLE IF NOT EXISTS recurring_bookings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    salon_id UUID NOT NULL,
+    branch_id UUID NOT NULL,
+    stylist_id UUID NOT NULL,
+    -- Services are performed back to back by the stylist, in order
+    service_ids UUID[] NOT NULL CHECK (cardinality(service_ids) > 0),
+    -- 0 = Sunday ... 6 = Saturday
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    -- Start of the first service in branch wall-clock time, HH:MM
+    start_time VARCHAR(5) NOT NULL,
+    interval_weeks INTEGER NOT NULL DEFAULT 1 CHECK (interval_weeks BETWEEN 1 AND 8),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled')),
+    -- Last occurrence date the generator booked or skipped
+    generated_through DATE,
+    last_generated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT chk_recurring_bookings_dates CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_bookings_user ON recurring_bookings(user_id, created_at DESC);
+-- Supports the generator's scan for active recurrences
+CREATE INDEX IF NOT EXISTS idx_recurring_bookings_active ON recurring_bookings(last_generated_at NULLS FIRST) WHERE status = 'active';
+
+CREATE TRIGGER update_recurring_bookings_updated_at
+    BEFORE UPDATE ON recurring_bookings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Occurrences are regular bookings; each date of a recurrence is booked at most once
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS recurring_booking_id UUID REFERENCES recurring_bookings(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_bookings_recurring_occurrence ON bookings(recurring_booking_id, occurrence_date)
+    WHERE recurring_booking_id IS NOT NULL;
+
+-- Occurrences that could not be booked, so each is skipped and reported once
+CREATE TABLE IF NOT EXISTS recurring_booking_skips (
+    recurring_booking_id UUID NOT NULL REFERENCES recurring_bookings(id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (recurring_booking_id, occurrence_date)
+);
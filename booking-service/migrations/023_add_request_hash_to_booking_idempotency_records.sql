@@ -0,0 +1,6 @@
+-- Idempotency records remember a hash of the request that created them so a
+-- key reused with a different payload is rejected instead of replayed.
+-- Records created before this column existed keep an empty hash and are
+-- replayed as before until they expire.
+ALTER TABLE booking_idempotency_records
+    ADD COLUMN IF NOT EXISTS request_hash VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Recurring booking occurrences are confirmed without an online payment and
+-- settled at the salon; mark them explicitly rather than leaving them pending
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_payment_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_payment_status_check
+    CHECK (payment_status IN ('pending', 'paid', 'failed', 'refunded', 'pay_at_salon'));
+
+UPDATE bookings
+SET payment_status = 'pay_at_salon'
+WHERE recurring_booking_id IS NOT NULL AND status = 'confirmed' AND payment_status = 'pending';
@@ -16,6 +16,8 @@ import (
 	"notification-service/internal/provider"
 	"notification-service/internal/repository"
 	"notification-service/internal/service"
+	"notification-service/internal/tracing"
+	"notification-service/internal/worker"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -31,6 +33,9 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid configuration")
+	}
 
 	// Set log level
 	level, err := zerolog.ParseLevel(cfg.LogLevel)
@@ -45,8 +50,14 @@ func main() {
 		Str("port", cfg.Port).
 		Msg("Starting notification service")
 
+	// Initialize tracing (optional - only if JAEGER_ENDPOINT is set)
+	if jaegerEndpoint := os.Getenv("JAEGER_ENDPOINT"); jaegerEndpoint != "" {
+		tracingCleanup := tracing.InitTracing("notification-service", jaegerEndpoint)
+		defer tracingCleanup()
+	}
+
 	// Initialize database
-	database, err := db.NewConnection(cfg.DatabaseURL)
+	database, err := db.NewConnection(cfg.DatabaseURL, cfg.DBPool())
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
@@ -59,22 +70,33 @@ func main() {
 
 	// Initialize repositories
 	notificationRepo := repository.NewNotificationRepository(database)
+	templateRepo := repository.NewTemplateRepository(database)
+	preferenceRepo := repository.NewPreferenceRepository(database)
 
 	// Initialize notification providers
 	providerManager := provider.NewProviderManager(cfg)
 
 	// Initialize services
-	notificationService := service.NewNotificationService(notificationRepo, providerManager)
+	notificationService := service.NewNotificationService(notificationRepo, templateRepo, preferenceRepo, providerManager, service.RetryPolicy{
+		MaxRetries: cfg.MaxRetryAttempts,
+		BaseDelay:  time.Duration(cfg.RetryDelaySeconds) * time.Second,
+		MaxDelay:   time.Duration(cfg.RetryMaxDelaySeconds) * time.Second,
+		BatchSize:  cfg.BatchSize,
+	}, service.ResendPolicy{
+		MaxAge: time.Duration(cfg.ResendMaxAgeHours) * time.Hour,
+		Limit:  cfg.ResendLimit,
+		Window: time.Duration(cfg.ResendWindowMinutes) * time.Minute,
+	}, cfg.TransactionalEventTypes)
 
 	// Initialize HTTP server
-	router := api.SetupRoutes(notificationService)
+	router := api.SetupRoutes(notificationService, api.NewAuthenticator(cfg.JWTAccessSecret, cfg.ServiceToken), int64(cfg.MaxBodyBytes))
 	httpServer := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: router,
 	}
 	
 	// Initialize event consumers
-	eventConsumer := consumer.NewEventConsumer(notificationService)
+	eventConsumer := consumer.NewEventConsumer(notificationService, cfg)
 
 	// Start services
 	var wg sync.WaitGroup
@@ -115,6 +137,14 @@ func main() {
 		}()
 	}
 
+	// Start retry worker for failed notifications
+	retryWorker := worker.NewRetryWorker(notificationService, time.Duration(cfg.RetryPollIntervalSeconds)*time.Second)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		retryWorker.Start(ctx)
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	sharederrors "salon-shared/errors"
+)
+
+// userTypeSalon is the user type of salon staff tokens issued by salon-service
+const userTypeSalon = "salon_USER"
+
+// Caller identifies who made an authenticated request
+type Caller struct {
+	// Service is set for other services presenting the shared service token
+	Service  bool
+	UserID   string
+	UserType string
+}
+
+// CanAccessUser reports whether the caller may read data belonging to userID.
+// Services and salon staff may read any user's data; customers only their own.
+func (c *Caller) CanAccessUser(userID uuid.UUID) bool {
+	return c.IsStaffOrService() || c.UserID == userID.String()
+}
+
+// IsStaffOrService reports whether the caller is salon staff or another service
+func (c *Caller) IsStaffOrService() bool {
+	return c.Service || c.UserType == userTypeSalon
+}
+
+// Identity names the caller for audit records: the user ID, or "service"
+func (c *Caller) Identity() string {
+	if c.Service {
+		return "service"
+	}
+	return c.UserID
+}
+
+type callerCtxKey struct{}
+
+// CallerFromContext returns the caller stored by Authenticator.Middleware, if any
+func CallerFromContext(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(callerCtxKey{}).(*Caller)
+	return caller
+}
+
+// Authenticator accepts either the shared service token or an access token
+// issued by user-service or salon-service as a Bearer credential
+type Authenticator struct {
+	accessSecret []byte
+	serviceToken string
+}
+
+// NewAuthenticator creates an authenticator. An empty service token disables
+// service authentication.
+func NewAuthenticator(accessSecret, serviceToken string) *Authenticator {
+	return &Authenticator{
+		accessSecret: []byte(accessSecret),
+		serviceToken: serviceToken,
+	}
+}
+
+type accessClaims struct {
+	UserID   string `json:"uid"`
+	UserType string `json:"user_type"`
+	jwt.RegisteredClaims
+}
+
+// Middleware rejects requests without a valid credential and stores the
+// caller in the request context
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authz := r.Header.Get("Authorization")
+		parts := strings.SplitN(authz, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+			sharederrors.WriteError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		caller, err := a.authenticate(strings.TrimSpace(parts[1]))
+		if err != nil {
+			log.Warn().Err(err).Msg("Rejected notification API credential")
+			sharederrors.WriteError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerCtxKey{}, caller)))
+	})
+}
+
+func (a *Authenticator) authenticate(token string) (*Caller, error) {
+	if a.serviceToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.serviceToken)) == 1 {
+		return &Caller{Service: true}, nil
+	}
+	if len(a.accessSecret) == 0 {
+		return nil, errors.New("access tokens are not accepted: no secret configured")
+	}
+
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.accessSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserID == "" {
+		return nil, errors.New("token has no user id")
+	}
+	return &Caller{UserID: claims.UserID, UserType: claims.UserType}, nil
+}
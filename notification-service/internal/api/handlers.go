@@ -2,14 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"notification-service/internal/model"
+	"notification-service/internal/provider"
 	"notification-service/internal/service"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	sharederrors "salon-shared/errors"
+	"salon-shared/pagination"
 )
 
 type NotificationHandler struct {
@@ -26,35 +31,42 @@ func NewNotificationHandler(notificationService *service.NotificationService) *N
 func (h *NotificationHandler) SendNotification(w http.ResponseWriter, r *http.Request) {
 	var request model.SendNotificationRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate required fields
 	if request.Type == "" {
-		http.Error(w, "Notification type is required", http.StatusBadRequest)
+		sharederrors.WriteError(w, http.StatusBadRequest, "Notification type is required")
 		return
 	}
 
 	if request.Recipient == "" {
-		http.Error(w, "Recipient is required", http.StatusBadRequest)
+		sharederrors.WriteError(w, http.StatusBadRequest, "Recipient is required")
 		return
 	}
 
-	if request.Subject == "" && request.Type == "email" {
-		http.Error(w, "Subject is required for email notifications", http.StatusBadRequest)
-		return
-	}
+	// Templated notifications take their subject and content from the template
+	if request.Template == "" {
+		if request.Subject == "" && request.Type == "email" {
+			sharederrors.WriteError(w, http.StatusBadRequest, "Subject is required for email notifications")
+			return
+		}
 
-	if request.Content == "" {
-		http.Error(w, "Content is required", http.StatusBadRequest)
-		return
+		if request.Content == "" {
+			sharederrors.WriteError(w, http.StatusBadRequest, "Content or template is required")
+			return
+		}
 	}
 
 	notification, err := h.notificationService.SendNotification(r.Context(), &request)
 	if err != nil {
+		if errors.Is(err, service.ErrTemplateNotFound) || errors.Is(err, service.ErrInvalidTemplate) {
+			sharederrors.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		log.Error().Err(err).Msg("Failed to send notification")
-		http.Error(w, "Failed to send notification", http.StatusInternalServerError)
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to send notification")
 		return
 	}
 
@@ -68,14 +80,14 @@ func (h *NotificationHandler) GetNotification(w http.ResponseWriter, r *http.Req
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid notification ID")
 		return
 	}
 
 	notification, err := h.notificationService.GetNotification(r.Context(), id)
 	if err != nil {
 		log.Error().Err(err).Str("notification_id", id.String()).Msg("Failed to get notification")
-		http.Error(w, "Notification not found", http.StatusNotFound)
+		sharederrors.WriteError(w, http.StatusNotFound, "Notification not found")
 		return
 	}
 
@@ -94,7 +106,7 @@ func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Re
 	if userIDStr != "" {
 		parsed, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			sharederrors.WriteError(w, http.StatusBadRequest, "Invalid user ID")
 			return
 		}
 		userID = &parsed
@@ -103,7 +115,7 @@ func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Re
 	notifications, err := h.notificationService.GetNotifications(r.Context(), userID, notificationType, status)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get notifications")
-		http.Error(w, "Failed to get notifications", http.StatusInternalServerError)
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to get notifications")
 		return
 	}
 
@@ -114,6 +126,46 @@ func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Re
 	})
 }
 
+// maxCallbackBodyBytes caps the size of provider callback payloads
+const maxCallbackBodyBytes = 1 << 20
+
+// DeliveryCallback handles POST /api/v1/notifications/{provider}/callback
+func (h *NotificationHandler) DeliveryCallback(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBodyBytes))
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	updated, err := h.notificationService.HandleDeliveryCallback(r.Context(), providerName, &provider.CallbackRequest{
+		Path:   r.URL.RequestURI(),
+		Header: r.Header,
+		Body:   body,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownCallbackProvider) {
+			sharederrors.WriteError(w, http.StatusNotFound, "Unknown provider")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCallbackSignature) {
+			log.Warn().Err(err).Str("provider", providerName).Msg("Rejected delivery callback")
+			sharederrors.WriteError(w, http.StatusUnauthorized, "Invalid signature")
+			return
+		}
+		log.Error().Err(err).Str("provider", providerName).Msg("Failed to process delivery callback")
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to process callback")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"updated": updated,
+	})
+}
+
 // Health handles GET /health
 func (h *NotificationHandler) Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -123,3 +175,96 @@ func (h *NotificationHandler) Health(w http.ResponseWriter, r *http.Request) {
 		"timestamp": "2024-01-01T00:00:00Z",
 	})
 }
+
+// ListUserNotifications handles GET /api/v1/users/{userID}/notifications.
+// Customers may only list their own notifications; staff and services may
+// list anyone's.
+func (h *NotificationHandler) ListUserNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	caller := CallerFromContext(r.Context())
+	if caller == nil || !caller.CanAccessUser(userID) {
+		sharederrors.WriteError(w, http.StatusForbidden, "Not allowed to read this user's notifications")
+		return
+	}
+
+	query := r.URL.Query()
+	page, err := pagination.Parse(query)
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := model.UserNotificationFilter{
+		UserID:  userID,
+		Channel: query.Get("type"),
+		Status:  query.Get("status"),
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+	}
+	switch filter.Channel {
+	case "", model.ChannelEmail, model.ChannelSMS, model.ChannelPush:
+	default:
+		sharederrors.WriteError(w, http.StatusBadRequest, "type must be one of email, sms, push")
+		return
+	}
+	if filter.Status != "" && !model.IsValidStatus(filter.Status) {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	notifications, total, err := h.notificationService.ListUserNotifications(r.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to list user notifications")
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to list notifications")
+		return
+	}
+
+	summaries := make([]model.NotificationSummary, len(notifications))
+	for i, notification := range notifications {
+		summaries[i] = notification.Summary()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pagination.NewListResponse(summaries, total, page))
+}
+
+// ResendNotification handles POST /api/v1/notifications/{id}/resend. Only
+// salon staff and services may resend; the caller is recorded on the resend.
+func (h *NotificationHandler) ResendNotification(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	caller := CallerFromContext(r.Context())
+	if caller == nil || !caller.IsStaffOrService() {
+		sharederrors.WriteError(w, http.StatusForbidden, "Only staff can resend notifications")
+		return
+	}
+
+	resend, err := h.notificationService.ResendNotification(r.Context(), id, caller.Identity())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotificationNotFound):
+			sharederrors.WriteError(w, http.StatusNotFound, "Notification not found")
+		case errors.Is(err, service.ErrResendTooOld), errors.Is(err, service.ErrResendNotAllowed):
+			sharederrors.WriteError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrResendLimitReached):
+			sharederrors.WriteError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			log.Error().Err(err).Str("notification_id", id.String()).Msg("Failed to resend notification")
+			sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to resend notification")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resend)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"notification-service/internal/model"
+	"notification-service/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	sharederrors "salon-shared/errors"
+)
+
+// GetPreferences handles GET /api/v1/users/{userID}/notification-preferences
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	preferences, err := h.notificationService.GetPreferences(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get notification preferences")
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to get notification preferences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// UpdatePreferences handles PUT /api/v1/users/{userID}/notification-preferences
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var request model.NotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	preferences, err := h.notificationService.UpdatePreferences(r.Context(), userID, &request)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPreferences) {
+			sharederrors.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to update notification preferences")
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to update notification preferences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences)
+}
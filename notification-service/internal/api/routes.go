@@ -2,18 +2,26 @@ package api
 
 import (
 	"notification-service/internal/service"
+	"notification-service/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	sharederrors "salon-shared/errors"
+	"salon-shared/requestbody"
 )
 
-func SetupRoutes(notificationService *service.NotificationService) *chi.Mux {
+func SetupRoutes(notificationService *service.NotificationService, authenticator *Authenticator, maxBodyBytes int64) *chi.Mux {
 	r := chi.NewRouter()
 
+	bodyLimit := requestbody.Config{MaxBytes: maxBodyBytes, WriteError: sharederrors.WriteError}
+	requireJSON := requestbody.RequireJSON(bodyLimit)
+
 	// Middleware
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
+	r.Use(requestbody.Limit(bodyLimit))
 
 	// Create handlers
 	notificationHandler := NewNotificationHandler(notificationService)
@@ -24,9 +32,33 @@ func SetupRoutes(notificationService *service.NotificationService) *chi.Mux {
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Route("/notifications", func(r chi.Router) {
-			r.Post("/send", notificationHandler.SendNotification)
-			r.Get("/", notificationHandler.GetNotifications)
-			r.Get("/{id}", notificationHandler.GetNotification)
+			// Providers post delivery callbacks form-encoded as well as JSON
+			r.Post("/{provider}/callback", notificationHandler.DeliveryCallback)
+
+			r.Group(func(r chi.Router) {
+				r.Use(requireJSON)
+				r.Post("/send", notificationHandler.SendNotification)
+				r.Get("/", notificationHandler.GetNotifications)
+				r.Get("/{id}", notificationHandler.GetNotification)
+				r.With(authenticator.Middleware).Post("/{id}/resend", notificationHandler.ResendNotification)
+			})
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireJSON)
+
+			r.With(authenticator.Middleware).Get("/users/{userID}/notifications", notificationHandler.ListUserNotifications)
+
+			r.Get("/users/{userID}/notification-preferences", notificationHandler.GetPreferences)
+			r.Put("/users/{userID}/notification-preferences", notificationHandler.UpdatePreferences)
+
+			r.Route("/templates", func(r chi.Router) {
+				r.Post("/", notificationHandler.CreateTemplate)
+				r.Get("/", notificationHandler.ListTemplates)
+				r.Get("/{id}", notificationHandler.GetTemplate)
+				r.Put("/{id}", notificationHandler.UpdateTemplate)
+				r.Delete("/{id}", notificationHandler.DeleteTemplate)
+			})
 		})
 	})
 
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"notification-service/internal/model"
+	"notification-service/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	sharederrors "salon-shared/errors"
+)
+
+// CreateTemplate handles POST /api/v1/templates
+func (h *NotificationHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeTemplateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	template, err := h.notificationService.CreateTemplate(r.Context(), request)
+	if err != nil {
+		writeTemplateError(w, err, "Failed to create template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.TemplateResponse{
+		Template: template,
+		Message:  "Template created",
+	})
+}
+
+// ListTemplates handles GET /api/v1/templates
+func (h *NotificationHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.notificationService.ListTemplates(r.Context(), r.URL.Query().Get("channel"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list templates")
+		sharederrors.WriteError(w, http.StatusInternalServerError, "Failed to list templates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// GetTemplate handles GET /api/v1/templates/{id}
+func (h *NotificationHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	template, err := h.notificationService.GetTemplate(r.Context(), id)
+	if err != nil {
+		writeTemplateError(w, err, "Failed to get template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// UpdateTemplate handles PUT /api/v1/templates/{id}
+func (h *NotificationHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	request, ok := decodeTemplateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	template, err := h.notificationService.UpdateTemplate(r.Context(), id, request)
+	if err != nil {
+		writeTemplateError(w, err, "Failed to update template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.TemplateResponse{
+		Template: template,
+		Message:  "Template updated",
+	})
+}
+
+// DeleteTemplate handles DELETE /api/v1/templates/{id}
+func (h *NotificationHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.DeleteTemplate(r.Context(), id); err != nil {
+		writeTemplateError(w, err, "Failed to delete template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseTemplateID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid template ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func decodeTemplateRequest(w http.ResponseWriter, r *http.Request) (*model.TemplateRequest, bool) {
+	var request model.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	if request.Name == "" {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Template name is required")
+		return nil, false
+	}
+
+	if request.EventType == "" {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Event type is required")
+		return nil, false
+	}
+
+	switch request.Channel {
+	case model.ChannelEmail, model.ChannelSMS, model.ChannelPush:
+	default:
+		sharederrors.WriteError(w, http.StatusBadRequest, "Channel must be one of email, sms, push")
+		return nil, false
+	}
+
+	if request.Content == "" {
+		sharederrors.WriteError(w, http.StatusBadRequest, "Content is required")
+		return nil, false
+	}
+
+	return &request, true
+}
+
+func writeTemplateError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound):
+		sharederrors.WriteError(w, http.StatusNotFound, "Template not found")
+	case errors.Is(err, service.ErrDuplicateTemplate):
+		sharederrors.WriteError(w, http.StatusConflict, "Template already exists for this channel")
+	case errors.Is(err, service.ErrInvalidTemplate):
+		sharederrors.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Error().Err(err).Msg(message)
+		sharederrors.WriteError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -2,34 +2,91 @@ package consumer
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"notification-service/internal/config"
 	"notification-service/internal/model"
 	"notification-service/internal/service"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
 )
 
 type EventConsumer struct {
 	notificationService *service.NotificationService
+	cfg                 *config.Config
 }
 
-func NewEventConsumer(notificationService *service.NotificationService) *EventConsumer {
+func NewEventConsumer(notificationService *service.NotificationService, cfg *config.Config) *EventConsumer {
 	return &EventConsumer{
 		notificationService: notificationService,
+		cfg:                 cfg,
 	}
 }
 
-// Start starts the event consumer (mock implementation)
+// Start starts the event consumer. Kafka is consumed for real; other brokers fall back to the mock consumer.
 func (c *EventConsumer) Start(ctx context.Context) error {
 	log.Info().Msg("Starting event consumer")
 
-	// Mock event consumption - in a real implementation, this would connect to Kafka/RabbitMQ
+	if c.cfg.MessageBroker == "kafka" {
+		return c.consumeKafka(ctx)
+	}
+
+	// Mock event consumption - RabbitMQ is not wired up yet
 	go c.mockEventConsumer(ctx)
 
 	return nil
 }
 
+// consumeKafka reads events from the configured Kafka topics until the context is cancelled
+func (c *EventConsumer) consumeKafka(ctx context.Context) error {
+	if len(c.cfg.KafkaTopics) == 0 {
+		return fmt.Errorf("no kafka topics configured")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     c.cfg.KafkaBrokers,
+		GroupID:     c.cfg.KafkaGroupID,
+		GroupTopics: c.cfg.KafkaTopics,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+	})
+	defer reader.Close()
+
+	log.Info().
+		Strs("brokers", c.cfg.KafkaBrokers).
+		Strs("topics", c.cfg.KafkaTopics).
+		Str("group_id", c.cfg.KafkaGroupID).
+		Msg("Consuming events from Kafka")
+
+	for {
+		message, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Info().Msg("Event consumer stopped")
+				return nil
+			}
+			log.Error().Err(err).Msg("Failed to fetch event from Kafka")
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var event model.Event
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			log.Error().Err(err).Str("topic", message.Topic).Int64("offset", message.Offset).Msg("Failed to decode event, skipping")
+		} else {
+			c.processEvent(ctx, &event)
+		}
+
+		if err := reader.CommitMessages(ctx, message); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to commit Kafka offset")
+		}
+	}
+}
+
 // mockEventConsumer simulates consuming events from a message broker
 func (c *EventConsumer) mockEventConsumer(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
@@ -64,6 +121,14 @@ func (c *EventConsumer) processEvent(ctx context.Context, event *model.Event) {
 		c.handleBookingConfirmed(ctx, event)
 	case "booking.cancelled":
 		c.handleBookingCancelled(ctx, event)
+	case "booking.rescheduled":
+		c.handleBookingRescheduled(ctx, event)
+	case "booking.reminder":
+		c.handleBookingReminder(ctx, event)
+	case "booking.slot_freed":
+		c.handleBookingSlotFreed(ctx, event)
+	case "booking.recurrence_skipped":
+		c.handleBookingRecurrenceSkipped(ctx, event)
 	case "payment.completed":
 		c.handlePaymentCompleted(ctx, event)
 	case "payment.failed":
@@ -75,7 +140,7 @@ func (c *EventConsumer) processEvent(ctx context.Context, event *model.Event) {
 
 // handleBookingConfirmed handles booking confirmation events
 func (c *EventConsumer) handleBookingConfirmed(ctx context.Context, event *model.Event) {
-	email, ok := event.Data["email"].(string)
+	email, ok := eventString(event, "email", "user_email")
 	if !ok {
 		log.Error().Msg("Missing email in booking confirmed event")
 		return
@@ -90,6 +155,8 @@ func (c *EventConsumer) handleBookingConfirmed(ctx context.Context, event *model
 	// Send email notification
 	emailRequest := &model.SendNotificationRequest{
 		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
 		Recipient: email,
 		Subject:   "Booking Confirmed",
 		Content:   "Your booking has been confirmed. We look forward to seeing you!",
@@ -105,9 +172,11 @@ func (c *EventConsumer) handleBookingConfirmed(ctx context.Context, event *model
 	}
 
 	// Send SMS notification if phone number is available
-	if phone, ok := event.Data["phone"].(string); ok && phone != "" {
+	if phone, ok := eventString(event, "phone", "user_phone"); ok {
 		smsRequest := &model.SendNotificationRequest{
 			Type:      "sms",
+			EventType: event.Type,
+			UserID:    eventUserID(event),
 			Recipient: phone,
 			Content:   "Your booking has been confirmed. We look forward to seeing you!",
 			Metadata: map[string]interface{}{
@@ -125,7 +194,7 @@ func (c *EventConsumer) handleBookingConfirmed(ctx context.Context, event *model
 
 // handleBookingCancelled handles booking cancellation events
 func (c *EventConsumer) handleBookingCancelled(ctx context.Context, event *model.Event) {
-	email, ok := event.Data["email"].(string)
+	email, ok := eventString(event, "email", "user_email")
 	if !ok {
 		log.Error().Msg("Missing email in booking cancelled event")
 		return
@@ -134,6 +203,8 @@ func (c *EventConsumer) handleBookingCancelled(ctx context.Context, event *model
 	// Send email notification
 	emailRequest := &model.SendNotificationRequest{
 		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
 		Recipient: email,
 		Subject:   "Booking Cancelled",
 		Content:   "Your booking has been cancelled. If you have any questions, please contact us.",
@@ -145,6 +216,228 @@ func (c *EventConsumer) handleBookingCancelled(ctx context.Context, event *model
 	}
 }
 
+// handleBookingRescheduled handles booking reschedule events
+func (c *EventConsumer) handleBookingRescheduled(ctx context.Context, event *model.Event) {
+	email, ok := eventString(event, "email", "user_email")
+	if !ok {
+		log.Error().Msg("Missing email in booking rescheduled event")
+		return
+	}
+
+	content := "Your booking has been rescheduled. We look forward to seeing you!"
+	if bookingTime, ok := eventString(event, "booking_time"); ok {
+		content = fmt.Sprintf("Your booking has been rescheduled to %s. We look forward to seeing you!", bookingTime)
+	}
+
+	metadata := map[string]interface{}{
+		"event_type": event.Type,
+	}
+	if userID, ok := eventString(event, "user_id"); ok {
+		metadata["user_id"] = userID
+	}
+
+	// Send email notification
+	emailRequest := &model.SendNotificationRequest{
+		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
+		Recipient: email,
+		Subject:   "Booking Rescheduled",
+		Content:   content,
+		Metadata:  metadata,
+	}
+
+	_, err := c.notificationService.SendNotification(ctx, emailRequest)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send booking reschedule email")
+	}
+
+	// Send SMS notification if phone number is available
+	if phone, ok := eventString(event, "phone", "user_phone"); ok {
+		smsRequest := &model.SendNotificationRequest{
+			Type:      "sms",
+			EventType: event.Type,
+			UserID:    eventUserID(event),
+			Recipient: phone,
+			Content:   content,
+			Metadata:  metadata,
+		}
+
+		_, err := c.notificationService.SendNotification(ctx, smsRequest)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send booking reschedule SMS")
+		}
+	}
+}
+
+// handleBookingReminder handles upcoming booking reminder events
+func (c *EventConsumer) handleBookingReminder(ctx context.Context, event *model.Event) {
+	email, ok := eventString(event, "email", "user_email")
+	if !ok {
+		log.Error().Msg("Missing email in booking reminder event")
+		return
+	}
+
+	content := "This is a reminder of your upcoming appointment. We look forward to seeing you!"
+	if bookingTime, ok := eventString(event, "booking_time"); ok {
+		content = fmt.Sprintf("This is a reminder of your upcoming appointment on %s. We look forward to seeing you!", bookingTime)
+	}
+
+	metadata := map[string]interface{}{
+		"event_type":        event.Type,
+		"threshold_minutes": event.Data["threshold_minutes"],
+	}
+	if userID, ok := eventString(event, "user_id"); ok {
+		metadata["user_id"] = userID
+	}
+
+	// Send email notification
+	emailRequest := &model.SendNotificationRequest{
+		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
+		Recipient: email,
+		Subject:   "Appointment Reminder",
+		Content:   content,
+		Metadata:  metadata,
+	}
+
+	_, err := c.notificationService.SendNotification(ctx, emailRequest)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send booking reminder email")
+	}
+
+	// Send SMS notification if phone number is available
+	if phone, ok := eventString(event, "phone", "user_phone"); ok {
+		smsRequest := &model.SendNotificationRequest{
+			Type:      "sms",
+			EventType: event.Type,
+			UserID:    eventUserID(event),
+			Recipient: phone,
+			Content:   content,
+			Metadata:  metadata,
+		}
+
+		_, err := c.notificationService.SendNotification(ctx, smsRequest)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send booking reminder SMS")
+		}
+	}
+}
+
+// handleBookingSlotFreed tells a waitlisted user that a slot in their desired window opened up
+func (c *EventConsumer) handleBookingSlotFreed(ctx context.Context, event *model.Event) {
+	email, ok := eventString(event, "email", "user_email")
+	if !ok {
+		log.Error().Msg("Missing email in booking slot freed event")
+		return
+	}
+
+	content := "A slot you were waiting for has opened up. Book now before it is taken!"
+	if bookingTime, ok := eventString(event, "booking_time"); ok {
+		content = fmt.Sprintf("A slot you were waiting for on %s has opened up. Book now before it is taken!", bookingTime)
+	}
+
+	metadata := map[string]interface{}{
+		"event_type":        event.Type,
+		"waitlist_entry_id": event.Data["waitlist_entry_id"],
+	}
+	if userID, ok := eventString(event, "user_id"); ok {
+		metadata["user_id"] = userID
+	}
+
+	// Send email notification
+	emailRequest := &model.SendNotificationRequest{
+		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
+		Recipient: email,
+		Subject:   "A Slot Has Opened Up",
+		Content:   content,
+		Metadata:  metadata,
+	}
+
+	_, err := c.notificationService.SendNotification(ctx, emailRequest)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send slot freed email")
+	}
+
+	// Send SMS notification if phone number is available
+	if phone, ok := eventString(event, "phone", "user_phone"); ok {
+		smsRequest := &model.SendNotificationRequest{
+			Type:      "sms",
+			EventType: event.Type,
+			UserID:    eventUserID(event),
+			Recipient: phone,
+			Content:   content,
+			Metadata:  metadata,
+		}
+
+		_, err := c.notificationService.SendNotification(ctx, smsRequest)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send slot freed SMS")
+		}
+	}
+}
+
+// handleBookingRecurrenceSkipped tells a user that an occurrence of their recurring booking could not be booked
+func (c *EventConsumer) handleBookingRecurrenceSkipped(ctx context.Context, event *model.Event) {
+	email, ok := eventString(event, "email", "user_email")
+	if !ok {
+		log.Error().Msg("Missing email in booking recurrence skipped event")
+		return
+	}
+
+	content := "One of your recurring appointments could not be booked. Please book this visit separately."
+	if bookingTime, ok := eventString(event, "booking_time"); ok {
+		content = fmt.Sprintf("Your recurring appointment on %s could not be booked. Please book this visit separately.", bookingTime)
+	}
+	if reason, ok := eventString(event, "reason"); ok {
+		content = fmt.Sprintf("%s Reason: %s.", content, reason)
+	}
+
+	metadata := map[string]interface{}{
+		"event_type":           event.Type,
+		"recurring_booking_id": event.Data["recurring_booking_id"],
+	}
+	if userID, ok := eventString(event, "user_id"); ok {
+		metadata["user_id"] = userID
+	}
+
+	// Send email notification
+	emailRequest := &model.SendNotificationRequest{
+		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
+		Recipient: email,
+		Subject:   "Recurring Appointment Skipped",
+		Content:   content,
+		Metadata:  metadata,
+	}
+
+	_, err := c.notificationService.SendNotification(ctx, emailRequest)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send recurrence skipped email")
+	}
+
+	// Send SMS notification if phone number is available
+	if phone, ok := eventString(event, "phone", "user_phone"); ok {
+		smsRequest := &model.SendNotificationRequest{
+			Type:      "sms",
+			EventType: event.Type,
+			UserID:    eventUserID(event),
+			Recipient: phone,
+			Content:   content,
+			Metadata:  metadata,
+		}
+
+		_, err := c.notificationService.SendNotification(ctx, smsRequest)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send recurrence skipped SMS")
+		}
+	}
+}
+
 // handlePaymentCompleted handles payment completion events
 func (c *EventConsumer) handlePaymentCompleted(ctx context.Context, event *model.Event) {
 	email, ok := event.Data["email"].(string)
@@ -156,6 +449,8 @@ func (c *EventConsumer) handlePaymentCompleted(ctx context.Context, event *model
 	// Send email notification
 	emailRequest := &model.SendNotificationRequest{
 		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
 		Recipient: email,
 		Subject:   "Payment Received",
 		Content:   "Thank you! Your payment has been successfully processed.",
@@ -178,6 +473,8 @@ func (c *EventConsumer) handlePaymentFailed(ctx context.Context, event *model.Ev
 	// Send email notification
 	emailRequest := &model.SendNotificationRequest{
 		Type:      "email",
+		EventType: event.Type,
+		UserID:    eventUserID(event),
 		Recipient: email,
 		Subject:   "Payment Failed",
 		Content:   "We were unable to process your payment. Please try again or contact us for assistance.",
@@ -188,3 +485,26 @@ func (c *EventConsumer) handlePaymentFailed(ctx context.Context, event *model.Ev
 		log.Error().Err(err).Msg("Failed to send payment failure email")
 	}
 }
+
+// eventUserID returns the user ID carried by the event, if any
+func eventUserID(event *model.Event) *uuid.UUID {
+	value, ok := eventString(event, "user_id")
+	if !ok {
+		return nil
+	}
+	userID, err := uuid.Parse(value)
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// eventString returns the first non-empty string value found under the given keys
+func eventString(event *model.Event, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := event.Data[key].(string); ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -3,25 +3,44 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
 )
 
+// PoolConfig holds the connection pool limits applied to the database handle
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+}
+
 // NewConnection creates a new PostgreSQL database connection
-func NewConnection(dbURL string) (*sql.DB, error) {
+func NewConnection(dbURL string, pool PoolConfig) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	// Set connection pool settings
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
 	// Test the connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-
+	log.Info().
+		Int("max_open_conns", pool.MaxOpenConns).
+		Int("max_idle_conns", pool.MaxIdleConns).
+		Dur("conn_max_idle_time", pool.ConnMaxIdleTime).
+		Dur("conn_max_lifetime", pool.ConnMaxLifetime).
+		Msg("Database connection pool configured")
 	return db, nil
 }
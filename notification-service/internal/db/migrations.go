@@ -35,6 +35,60 @@ func RunMigrations(db *sql.DB, migrationsPath string) error {
 		return fmt.Errorf("failed to create notifications table: %w", err)
 	}
 
+	// Failed sends are retried with backoff until attempt_count reaches the limit
+	addRetryColumns := `
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS attempt_count INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS idx_notifications_next_retry_at
+			ON notifications(next_retry_at) WHERE status = 'retrying';
+	`
+
+	if _, err := db.Exec(addRetryColumns); err != nil {
+		return fmt.Errorf("failed to add notification retry columns: %w", err)
+	}
+
+	// Notifications are listed per user, newest first
+	addUserIDColumn := `
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS user_id UUID;
+		CREATE INDEX IF NOT EXISTS idx_notifications_user_id_created_at
+			ON notifications(user_id, created_at DESC) WHERE user_id IS NOT NULL;
+	`
+
+	if _, err := db.Exec(addUserIDColumn); err != nil {
+		return fmt.Errorf("failed to add notifications user_id column: %w", err)
+	}
+
+	// A resend is a new notification linked to the one it repeats, recording
+	// who asked for it; resends are rate limited per original
+	addResendColumns := `
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS resent_from_id UUID REFERENCES notifications(id);
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS resent_by VARCHAR(255);
+		CREATE INDEX IF NOT EXISTS idx_notifications_resent_from_id
+			ON notifications(resent_from_id, created_at) WHERE resent_from_id IS NOT NULL;
+	`
+
+	if _, err := db.Exec(addResendColumns); err != nil {
+		return fmt.Errorf("failed to add notification resend columns: %w", err)
+	}
+
+	// Channels fail over between providers, so record which one delivered
+	addProviderColumn := `
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS provider VARCHAR(50);
+	`
+
+	if _, err := db.Exec(addProviderColumn); err != nil {
+		return fmt.Errorf("failed to add notification provider column: %w", err)
+	}
+
+	// Delivery callbacks look notifications up by provider message ID
+	createProviderIDIndex := `
+		CREATE INDEX IF NOT EXISTS idx_notifications_provider_id ON notifications(provider_id);
+	`
+
+	if _, err := db.Exec(createProviderIDIndex); err != nil {
+		return fmt.Errorf("failed to create notifications provider_id index: %w", err)
+	}
+
 	// Create notification_templates table if it doesn't exist
 	createTemplatesTable := `
 		CREATE TABLE IF NOT EXISTS notification_templates (
@@ -55,6 +109,35 @@ func RunMigrations(db *sql.DB, migrationsPath string) error {
 		return fmt.Errorf("failed to create notification_templates table: %w", err)
 	}
 
+	// Templates are looked up by name for each channel
+	createTemplateNameIndex := `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_templates_name_channel
+		ON notification_templates(name, channel);
+	`
+
+	if _, err := db.Exec(createTemplateNameIndex); err != nil {
+		return fmt.Errorf("failed to create notification_templates name index: %w", err)
+	}
+
+	if _, err := db.Exec(seedDefaultTemplates); err != nil {
+		return fmt.Errorf("failed to seed notification templates: %w", err)
+	}
+
+	// Create notification_preferences table if it doesn't exist
+	createPreferencesTable := `
+		CREATE TABLE IF NOT EXISTS notification_preferences (
+			user_id UUID PRIMARY KEY,
+			channels JSONB NOT NULL DEFAULT '{}',
+			event_types JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
+	`
+
+	if _, err := db.Exec(createPreferencesTable); err != nil {
+		return fmt.Errorf("failed to create notification_preferences table: %w", err)
+	}
+
 	// Create notification_attempts table if it doesn't exist
 	createAttemptsTable := `
 		CREATE TABLE IF NOT EXISTS notification_attempts (
@@ -77,3 +160,39 @@ func RunMigrations(db *sql.DB, migrationsPath string) error {
 
 	return nil
 }
+
+// seedDefaultTemplates inserts the booking templates used by booking-service.
+// Existing templates are left untouched so copy edited through the API is kept.
+const seedDefaultTemplates = `
+	INSERT INTO notification_templates (id, name, event_type, channel, subject, content, is_active)
+	VALUES
+		(gen_random_uuid(), 'booking_confirmed', 'booking.confirmed', 'email', 'Booking Confirmed - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nYour booking has been confirmed!\n\nSalon: {{.salon_name}}\nDate & Time: {{.booking_time}}\nTotal Amount: ₹{{printf "%.2f" .total_amount}}\n\nThank you for choosing our services. We look forward to serving you!\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'booking_confirmed', 'booking.confirmed', 'sms', NULL,
+		 'Hi {{.user_name}}! Your booking at {{.salon_name}} on {{.booking_time}} is confirmed. Amount: ₹{{printf "%.2f" .total_amount}}. Thank you!', true),
+		(gen_random_uuid(), 'booking_cancelled', 'booking.cancelled', 'email', 'Booking Cancelled - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nYour booking has been cancelled.\n\nSalon: {{.salon_name}}\nDate & Time: {{.booking_time}}\nReason: {{.reason}}\n\nIf you have any questions, please contact us.\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'booking_cancelled', 'booking.cancelled', 'sms', NULL,
+		 'Hi {{.user_name}}! Your booking at {{.salon_name}} on {{.booking_time}} has been cancelled. Reason: {{.reason}}', true),
+		(gen_random_uuid(), 'booking_rescheduled', 'booking.rescheduled', 'email', 'Booking Rescheduled - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nYour booking has been rescheduled.\n\nSalon: {{.salon_name}}\nNew Date & Time: {{.booking_time}}\n\nWe look forward to serving you!\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'booking_rescheduled', 'booking.rescheduled', 'sms', NULL,
+		 'Hi {{.user_name}}! Your booking at {{.salon_name}} has been rescheduled to {{.booking_time}}.', true),
+		(gen_random_uuid(), 'booking_reminder', 'booking.reminder', 'email', 'Appointment Reminder - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nThis is a reminder of your upcoming appointment.\n\nSalon: {{.salon_name}}\nBranch: {{.branch_name}}\nDate & Time: {{.booking_time}}\n\nWe look forward to serving you!\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'booking_reminder', 'booking.reminder', 'sms', NULL,
+		 'Hi {{.user_name}}! Reminder: your appointment at {{.salon_name}} is on {{.booking_time}}. See you soon!', true),
+		(gen_random_uuid(), 'waitlist_slot_freed', 'booking.slot_freed', 'email', 'A Slot Has Opened Up - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nGood news! A slot you were waiting for is now free.\n\nSalon: {{.salon_name}}\nBranch: {{.branch_name}}\nStylist: {{.stylist_name}}\nDate & Time: {{.booking_time}}\n\nSlots are offered first come, first served, so book soon to secure it.\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'waitlist_slot_freed', 'booking.slot_freed', 'sms', NULL,
+		 'Hi {{.user_name}}! A slot with {{.stylist_name}} at {{.salon_name}} on {{.booking_time}} just opened up. Book now to secure it!', true),
+		(gen_random_uuid(), 'recurring_booking_skipped', 'booking.recurrence_skipped', 'email', 'Recurring Appointment Skipped - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nWe could not book one of your recurring appointments.\n\nSalon: {{.salon_name}}\nBranch: {{.branch_name}}\nStylist: {{.stylist_name}}\nDate & Time: {{.booking_time}}\nReason: {{.reason}}\n\nYour other appointments are unchanged. Please book this visit separately if you would still like to come in.\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'recurring_booking_skipped', 'booking.recurrence_skipped', 'sms', NULL,
+		 'Hi {{.user_name}}! Your recurring appointment at {{.salon_name}} on {{.booking_time}} could not be booked: {{.reason}}. Please book this visit separately.', true),
+		(gen_random_uuid(), 'payment_confirmed', 'payment.completed', 'email', 'Payment Received - {{.salon_name}}',
+		 E'Dear {{.user_name}},\n\nThank you! Your payment has been successfully processed.\n\nSalon: {{.salon_name}}\nAmount Paid: ₹{{printf "%.2f" .total_amount}}\nPayment ID: {{.payment_id}}\n\nYour booking is now confirmed. We look forward to serving you!\n\nBest regards,\n{{.salon_name}} Team', true),
+		(gen_random_uuid(), 'payment_confirmed', 'payment.completed', 'sms', NULL,
+		 'Hi {{.user_name}}! Payment of ₹{{printf "%.2f" .total_amount}} for {{.salon_name}} received successfully. Booking confirmed!', true)
+	ON CONFLICT (name, channel) DO NOTHING;
+`
@@ -10,9 +10,13 @@ import (
 const (
 	NotificationStatusPending   = "pending"
 	NotificationStatusSent      = "sent"
+	NotificationStatusDelivered = "delivered"
+	NotificationStatusBounced   = "bounced"
 	NotificationStatusFailed    = "failed"
 	NotificationStatusRetrying  = "retrying"
 	NotificationStatusCanceled  = "canceled"
+	// NotificationStatusSuppressed marks notifications the recipient opted out of
+	NotificationStatusSuppressed = "suppressed"
 )
 
 // Notification channel constants
@@ -33,6 +37,8 @@ const (
 // Notification represents a notification record
 type Notification struct {
 	ID          uuid.UUID  `json:"id" db:"id"`
+	// UserID is the user the notification was sent to, when known
+	UserID      *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
 	EventType   string     `json:"event_type" db:"event_type"`
 	Channel     string     `json:"channel" db:"channel"`
 	Recipient   string     `json:"recipient" db:"recipient"`
@@ -43,13 +49,23 @@ type Notification struct {
 	Priority    string     `json:"priority" db:"priority"`
 	Metadata    *string    `json:"metadata,omitempty" db:"metadata"`
 	ProviderID  *string    `json:"provider_id,omitempty" db:"provider_id"`
+	// Provider names the provider that delivered the notification
+	Provider    *string    `json:"provider,omitempty" db:"provider"`
 	ErrorMsg    *string    `json:"error_message,omitempty" db:"error_message"`
 	RetryCount  int        `json:"retry_count" db:"retry_count"`
+	// AttemptCount is the number of send attempts made so far
+	AttemptCount int `json:"attempt_count" db:"attempt_count"`
+	// NextRetryAt is set while the notification is waiting to be retried
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty" db:"next_retry_at"`
 	ScheduledAt *time.Time `json:"scheduled_at,omitempty" db:"scheduled_at"`
 	SentAt      *time.Time `json:"sent_at,omitempty" db:"sent_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	// ResentFromID links a resend to the notification it repeats
+	ResentFromID *uuid.UUID `json:"resent_from_id,omitempty" db:"resent_from_id"`
+	// ResentBy records who triggered the resend: a user ID or "service"
+	ResentBy  *string   `json:"resent_by,omitempty" db:"resent_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // NotificationTemplate represents a notification template
@@ -119,6 +135,57 @@ type NotificationListResponse struct {
 	PageSize      int             `json:"page_size"`
 }
 
+// UserNotificationFilter selects a page of the notifications sent to a user.
+// Channel and Status are optional.
+type UserNotificationFilter struct {
+	UserID  uuid.UUID
+	Channel string
+	Status  string
+	Limit   int
+	Offset  int
+}
+
+// NotificationSummary is a notification as listed in a user's history,
+// without its content
+type NotificationSummary struct {
+	ID        uuid.UUID  `json:"id"`
+	EventType string     `json:"event_type"`
+	Channel   string     `json:"channel"`
+	Status    string     `json:"status"`
+	Subject   *string    `json:"subject,omitempty"`
+	ErrorMsg  *string    `json:"error_message,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Summary returns the listing view of the notification
+func (n *Notification) Summary() NotificationSummary {
+	return NotificationSummary{
+		ID:        n.ID,
+		EventType: n.EventType,
+		Channel:   n.Channel,
+		Status:    n.Status,
+		Subject:   n.Subject,
+		ErrorMsg:  n.ErrorMsg,
+		SentAt:    n.SentAt,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+	}
+}
+
+// IsValidStatus reports whether status is a known notification status
+func IsValidStatus(status string) bool {
+	switch status {
+	case NotificationStatusPending, NotificationStatusSent, NotificationStatusDelivered,
+		NotificationStatusBounced, NotificationStatusFailed, NotificationStatusRetrying,
+		NotificationStatusCanceled, NotificationStatusSuppressed:
+		return true
+	default:
+		return false
+	}
+}
+
 // TemplateRequest represents a request to create/update a template
 type TemplateRequest struct {
 	Name      string                 `json:"name" validate:"required"`
@@ -127,7 +194,8 @@ type TemplateRequest struct {
 	Subject   *string                `json:"subject,omitempty"`
 	Content   string                 `json:"content" validate:"required"`
 	Variables map[string]interface{} `json:"variables,omitempty"`
-	IsActive  bool                   `json:"is_active"`
+	// IsActive defaults to true when omitted
+	IsActive *bool `json:"is_active,omitempty"`
 }
 
 // TemplateResponse represents a template response
@@ -145,16 +213,50 @@ type HealthResponse struct {
 	Providers string `json:"providers"`
 }
 
-// SendNotificationRequest represents a request to send a notification
+// SendNotificationRequest represents a request to send a notification. Callers
+// either send prebuilt content or name a template that is rendered with Data.
 type SendNotificationRequest struct {
 	UserID    *uuid.UUID             `json:"user_id,omitempty"`
 	Type      string                 `json:"type" validate:"required,oneof=email sms push"`
+	// EventType defaults to the template's event type, or "manual_send"
+	EventType string                 `json:"event_type,omitempty"`
 	Recipient string                 `json:"recipient" validate:"required"`
 	Subject   string                 `json:"subject,omitempty"`
-	Content   string                 `json:"content" validate:"required"`
+	Content   string                 `json:"content,omitempty" validate:"required_without=Template"`
+	Template  string                 `json:"template,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// NotificationPreferences holds a user's opt-outs. Channels toggles a channel
+// for every event type; EventTypes toggles a channel for one event type and
+// takes precedence. Anything not listed is enabled.
+type NotificationPreferences struct {
+	UserID     uuid.UUID                  `json:"user_id"`
+	Channels   map[string]bool            `json:"channels"`
+	EventTypes map[string]map[string]bool `json:"event_types"`
+	UpdatedAt  *time.Time                 `json:"updated_at,omitempty"`
+}
+
+// NotificationPreferencesRequest represents a request to replace a user's preferences
+type NotificationPreferencesRequest struct {
+	Channels   map[string]bool            `json:"channels"`
+	EventTypes map[string]map[string]bool `json:"event_types"`
+}
+
+// Allows reports whether the user accepts notifications of the event type on the channel
+func (p *NotificationPreferences) Allows(channel, eventType string) bool {
+	if channels, ok := p.EventTypes[eventType]; ok {
+		if enabled, ok := channels[channel]; ok {
+			return enabled
+		}
+	}
+	if enabled, ok := p.Channels[channel]; ok {
+		return enabled
+	}
+	return true
+}
+
 // Event represents an event from the message broker
 type Event struct {
 	Type string                 `json:"type"`
@@ -174,5 +276,8 @@ func (n *Notification) CanRetry(maxRetries int) bool {
 // IsTerminalStatus checks if the notification is in a terminal status
 func (n *Notification) IsTerminalStatus() bool {
 	return n.Status == NotificationStatusSent ||
-		n.Status == NotificationStatusCanceled
+		n.Status == NotificationStatusDelivered ||
+		n.Status == NotificationStatusBounced ||
+		n.Status == NotificationStatusCanceled ||
+		n.Status == NotificationStatusSuppressed
 }
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"notification-service/internal/model"
+)
+
+const twilioCallbackPath = "/api/v1/notifications/twilio/callback"
+
+// CallbackProvider returns "twilio" when SMS is sent through Twilio
+func (s *SMSProvider) CallbackProvider() string {
+	if s.provider == "twilio" {
+		return "twilio"
+	}
+	return ""
+}
+
+// ParseCallback verifies a Twilio status callback and maps the message status.
+// Twilio signs the full callback URL followed by the sorted form parameters
+// with HMAC-SHA1 keyed by the account auth token.
+func (s *SMSProvider) ParseCallback(req *CallbackRequest) ([]DeliveryUpdate, error) {
+	form, err := url.ParseQuery(string(req.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Twilio callback: %w", err)
+	}
+
+	if s.config.TwilioAuthToken == "" || s.config.CallbackBaseURL == "" {
+		return nil, fmt.Errorf("%w: Twilio callbacks are not configured", ErrInvalidSignature)
+	}
+
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var payload strings.Builder
+	payload.WriteString(s.config.CallbackBaseURL + req.Path)
+	for _, key := range keys {
+		for _, value := range form[key] {
+			payload.WriteString(key)
+			payload.WriteString(value)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(s.config.TwilioAuthToken))
+	mac.Write([]byte(payload.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(req.Header.Get("X-Twilio-Signature"))) {
+		return nil, ErrInvalidSignature
+	}
+
+	status := mapTwilioStatus(form.Get("MessageStatus"))
+	if status == "" || form.Get("MessageSid") == "" {
+		return nil, nil
+	}
+
+	update := DeliveryUpdate{ProviderMessageID: form.Get("MessageSid"), Status: status}
+	if code := form.Get("ErrorCode"); code != "" {
+		update.ErrorMessage = "Twilio error code " + code
+	}
+	return []DeliveryUpdate{update}, nil
+}
+
+// mapTwilioStatus maps final Twilio message statuses; intermediate statuses map to ""
+func mapTwilioStatus(status string) string {
+	switch status {
+	case "delivered", "read":
+		return model.NotificationStatusDelivered
+	case "undelivered", "failed":
+		return model.NotificationStatusFailed
+	default:
+		return ""
+	}
+}
+
+// sendGridEvent is a single entry of a SendGrid event webhook payload
+type sendGridEvent struct {
+	Event     string `json:"event"`
+	MessageID string `json:"sg_message_id"`
+	Reason    string `json:"reason"`
+	Type      string `json:"type"`
+}
+
+// CallbackProvider returns "sendgrid" when email is sent through SendGrid
+func (e *EmailProvider) CallbackProvider() string {
+	if e.provider == "sendgrid" {
+		return "sendgrid"
+	}
+	return ""
+}
+
+// ParseCallback verifies a signed SendGrid event webhook and maps its events.
+// SendGrid signs the timestamp header followed by the raw body with ECDSA
+// using the key configured as the webhook verification key.
+func (e *EmailProvider) ParseCallback(req *CallbackRequest) ([]DeliveryUpdate, error) {
+	if err := e.verifySendGridSignature(req); err != nil {
+		return nil, err
+	}
+
+	var events []sendGridEvent
+	if err := json.Unmarshal(req.Body, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse SendGrid events: %w", err)
+	}
+
+	var updates []DeliveryUpdate
+	for _, event := range events {
+		status := mapSendGridEvent(event.Event)
+		if status == "" || event.MessageID == "" {
+			continue
+		}
+		// sg_message_id is the X-Message-Id returned on send followed by a
+		// filter suffix, e.g. "abc123.filter0001.16648.5515E0B88.0"
+		messageID, _, _ := strings.Cut(event.MessageID, ".")
+		updates = append(updates, DeliveryUpdate{
+			ProviderMessageID: messageID,
+			Status:            status,
+			ErrorMessage:      event.Reason,
+		})
+	}
+	return updates, nil
+}
+
+func (e *EmailProvider) verifySendGridSignature(req *CallbackRequest) error {
+	if e.config.SendGridWebhookPublicKey == "" {
+		return fmt.Errorf("%w: SendGrid webhook verification key is not configured", ErrInvalidSignature)
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(e.config.SendGridWebhookPublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid webhook verification key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(keyBytes)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid webhook verification key: %w", err)
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("SendGrid webhook verification key is not an ECDSA key")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(req.Header.Get("X-Twilio-Email-Event-Webhook-Signature"))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+	timestamp := req.Header.Get("X-Twilio-Email-Event-Webhook-Timestamp")
+
+	digest := sha256.Sum256(append([]byte(timestamp), req.Body...))
+	if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// mapSendGridEvent maps final SendGrid delivery events; engagement and
+// intermediate events map to ""
+func mapSendGridEvent(event string) string {
+	switch event {
+	case "delivered":
+		return model.NotificationStatusDelivered
+	case "bounce":
+		return model.NotificationStatusBounced
+	case "dropped":
+		return model.NotificationStatusFailed
+	default:
+		return ""
+	}
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"notification-service/internal/config"
 	"notification-service/internal/model"
@@ -18,11 +19,11 @@ type EmailProvider struct {
 	provider string // "sendgrid" or "smtp"
 }
 
-// NewEmailProvider creates a new email provider
-func NewEmailProvider(cfg *config.Config) *EmailProvider {
+// NewEmailProvider creates an email provider for the named backend
+func NewEmailProvider(cfg *config.Config, name string) *EmailProvider {
 	return &EmailProvider{
 		config:   cfg,
-		provider: cfg.EmailProvider,
+		provider: name,
 	}
 }
 
@@ -77,8 +78,13 @@ func (e *EmailProvider) sendWithSendGrid(ctx context.Context, notification *mode
 
 	// Check response status
 	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		// Event webhook callbacks identify the message by this ID
+		messageID := http.Header(response.Headers).Get("X-Message-Id")
+		if messageID == "" {
+			messageID = fmt.Sprintf("sendgrid-%d", response.StatusCode)
+		}
 		return &SendResponse{
-			ProviderID: fmt.Sprintf("sendgrid-%d", response.StatusCode),
+			ProviderID: messageID,
 			Status:     StatusSent,
 			Message:    "Email sent successfully via SendGrid",
 			Metadata: map[string]interface{}{
@@ -95,7 +101,7 @@ func (e *EmailProvider) sendWithSendGrid(ctx context.Context, notification *mode
 			"status_code": response.StatusCode,
 			"body":        response.Body,
 		},
-	}, fmt.Errorf("SendGrid API error: status %d", response.StatusCode)
+	}, classifySendGridStatus(response.StatusCode, fmt.Errorf("SendGrid API error: status %d", response.StatusCode))
 }
 
 // sendWithSMTP sends email using SMTP
@@ -124,7 +130,7 @@ func (e *EmailProvider) sendWithSMTP(ctx context.Context, notification *model.No
 		return &SendResponse{
 			Status:       StatusFailed,
 			ErrorMessage: err.Error(),
-		}, err
+		}, classifySMTPError(err)
 	}
 
 	return &SendResponse{
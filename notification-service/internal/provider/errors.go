@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"errors"
+	"net/textproto"
+
+	twilioClient "github.com/twilio/twilio-go/client"
+)
+
+// PermanentError marks a send failure that will fail again on retry, such as
+// an invalid recipient. Failures not wrapped in PermanentError are transient.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err as a permanent failure
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether a send failure should not be retried
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// twilioPermanentCodes are Twilio error codes caused by the recipient or
+// message itself rather than by Twilio or the network
+var twilioPermanentCodes = map[int]bool{
+	21211: true, // invalid 'To' phone number
+	21408: true, // permission to send to region not enabled
+	21610: true, // recipient unsubscribed
+	21612: true, // 'To' number not reachable from 'From' number
+	21614: true, // 'To' number is not a mobile number
+	21617: true, // message body too long
+	30003: true, // unreachable destination handset
+	30005: true, // unknown destination handset
+	30006: true, // landline or unreachable carrier
+}
+
+// classifyTwilioError marks Twilio errors about the recipient or message as permanent
+func classifyTwilioError(err error) error {
+	var restErr *twilioClient.TwilioRestError
+	if errors.As(err, &restErr) && twilioPermanentCodes[restErr.Code] {
+		return Permanent(err)
+	}
+	return err
+}
+
+// classifySendGridStatus marks SendGrid rejections of the request itself as
+// permanent; auth, rate limit and server errors may succeed later
+func classifySendGridStatus(statusCode int, err error) error {
+	switch statusCode {
+	case 400, 403, 413:
+		return Permanent(err)
+	default:
+		return err
+	}
+}
+
+// classifySMTPError marks 5xx SMTP replies, e.g. 550 mailbox unavailable, as permanent
+func classifySMTPError(err error) error {
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"notification-service/internal/model"
+)
+
+// Circuit states reported for each provider
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen is returned by health checks for a provider that is being
+// skipped after repeated failures
+var ErrCircuitOpen = errors.New("provider circuit open")
+
+// circuitBreaker tracks consecutive send failures of one provider. Once the
+// threshold is reached the provider is skipped until the cooldown passes, after
+// which a single send is let through to probe it.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	lastErr   error
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a send may be attempted now
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked(now) != CircuitOpen
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.lastErr = nil
+}
+
+// failure records a failed send and reports whether it opened the circuit
+func (b *circuitBreaker) failure(err error, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.lastErr = err
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+func (b *circuitBreaker) state(now time.Time) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked(now), b.lastErr
+}
+
+func (b *circuitBreaker) stateLocked(now time.Time) string {
+	switch {
+	case b.failures < b.threshold:
+		return CircuitClosed
+	case now.Before(b.openUntil):
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// failoverMember is a provider in a failover chain together with its circuit
+type failoverMember struct {
+	provider NotificationProvider
+	breaker  *circuitBreaker
+}
+
+// failoverProvider sends through the providers of one channel in their
+// configured order, moving on to the next provider when a send fails for a
+// reason other than the message itself and skipping providers whose circuit
+// is open
+type failoverProvider struct {
+	channel string
+	members []*failoverMember
+}
+
+// GetName returns the member provider names in failover order
+func (f *failoverProvider) GetName() string {
+	names := make([]string, len(f.members))
+	for i, member := range f.members {
+		names[i] = member.provider.GetName()
+	}
+	return strings.Join(names, ",")
+}
+
+// GetChannel returns the notification channel
+func (f *failoverProvider) GetChannel() string {
+	return f.channel
+}
+
+// Send tries each available provider in order and records the one that
+// delivered the message in the response. Permanent failures and messages a
+// provider rejected are returned at once, since another provider would reject
+// them too.
+func (f *failoverProvider) Send(ctx context.Context, notification *model.Notification) (*SendResponse, error) {
+	var errs []error
+	for _, member := range f.members {
+		name := member.provider.GetName()
+		if !member.breaker.allow(time.Now()) {
+			log.Debug().Str("provider", name).Msg("Skipping provider with open circuit")
+			continue
+		}
+
+		response, err := member.provider.Send(ctx, notification)
+		if err == nil {
+			member.breaker.success()
+			if response != nil {
+				response.Provider = name
+			}
+			return response, nil
+		}
+		if IsPermanent(err) {
+			return response, err
+		}
+
+		if member.breaker.failure(err, time.Now()) {
+			log.Warn().Err(err).Str("provider", name).Msg("Provider circuit opened after repeated failures")
+		}
+		log.Warn().Err(err).Str("provider", name).Str("notification_id", notification.ID.String()).Msg("Provider send failed, trying next provider")
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w: every %s provider is unavailable", ErrCircuitOpen, f.channel)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// IsHealthy reports an error when no provider in the chain is usable
+func (f *failoverProvider) IsHealthy(ctx context.Context) error {
+	var errs []error
+	for _, member := range f.members {
+		err := member.health(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", member.provider.GetName(), err))
+	}
+	return errors.Join(errs...)
+}
+
+// health combines the provider's own check with its circuit state
+func (m *failoverMember) health(ctx context.Context) error {
+	if state, lastErr := m.breaker.state(time.Now()); state == CircuitOpen {
+		return fmt.Errorf("%w: %v", ErrCircuitOpen, lastErr)
+	}
+	return m.provider.IsHealthy(ctx)
+}
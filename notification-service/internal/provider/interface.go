@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"net/http"
 
 	"notification-service/internal/model"
 )
@@ -24,6 +26,8 @@ type NotificationProvider interface {
 // SendResponse represents a response from sending a notification
 type SendResponse struct {
 	ProviderID   string                 `json:"provider_id"`
+	// Provider names the provider that delivered the message, e.g. "sms-twilio"
+	Provider     string                 `json:"provider,omitempty"`
 	Status       string                 `json:"status"`
 	Message      string                 `json:"message"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
@@ -35,8 +39,39 @@ type ProviderManager interface {
 	GetProvider(channel string) (NotificationProvider, error)
 	GetAvailableChannels() []string
 	HealthCheck(ctx context.Context) map[string]error
+	GetCallbackHandler(provider string) (CallbackHandler, error)
 }
 
+// CallbackHandler is implemented by providers that report delivery status
+// back to us through an HTTP callback
+type CallbackHandler interface {
+	// CallbackProvider returns the provider name used in the callback route,
+	// or an empty string when the configured backend sends no callbacks
+	CallbackProvider() string
+
+	// ParseCallback verifies the callback signature and returns the delivery
+	// updates it carries. It returns ErrInvalidSignature when verification fails.
+	ParseCallback(req *CallbackRequest) ([]DeliveryUpdate, error)
+}
+
+// CallbackRequest is the raw provider callback as received over HTTP
+type CallbackRequest struct {
+	// Path is the request path and query the callback was delivered to
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// DeliveryUpdate is a delivery status change for a previously sent message
+type DeliveryUpdate struct {
+	ProviderMessageID string
+	Status            string
+	ErrorMessage      string
+}
+
+// ErrInvalidSignature is returned when a callback fails signature verification
+var ErrInvalidSignature = errors.New("invalid callback signature")
+
 // Provider status constants
 const (
 	StatusSent   = "sent"
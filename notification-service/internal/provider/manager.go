@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"notification-service/internal/config"
 	"notification-service/internal/model"
@@ -10,39 +11,52 @@ import (
 
 // providerManager implements ProviderManager interface
 type providerManager struct {
-	providers map[string]NotificationProvider
+	providers map[string]*failoverProvider
 	config    *config.Config
 }
 
-// NewProviderManager creates a new provider manager
+// NewProviderManager creates a new provider manager. Each channel gets the
+// providers configured for it in failover order.
 func NewProviderManager(cfg *config.Config) ProviderManager {
 	manager := &providerManager{
-		providers: make(map[string]NotificationProvider),
+		providers: make(map[string]*failoverProvider),
 		config:    cfg,
 	}
 
-	// Initialize email provider
-	if cfg.EmailProvider != "" {
-		emailProvider := NewEmailProvider(cfg)
-		manager.providers[model.ChannelEmail] = emailProvider
+	// Initialize email providers
+	for _, name := range cfg.EmailProviders {
+		manager.add(model.ChannelEmail, NewEmailProvider(cfg, name))
 	}
 
-	// Initialize SMS provider
-	if cfg.SMSProvider != "" {
-		smsProvider := NewSMSProvider(cfg)
-		manager.providers[model.ChannelSMS] = smsProvider
+	// Initialize SMS providers
+	for _, name := range cfg.SMSProviders {
+		manager.add(model.ChannelSMS, NewSMSProvider(cfg, name))
 	}
 
-	// Initialize push provider
-	if cfg.PushProvider != "" {
-		pushProvider := NewPushProvider(cfg)
-		manager.providers[model.ChannelPush] = pushProvider
+	// Initialize push providers
+	for _, name := range cfg.PushProviders {
+		manager.add(model.ChannelPush, NewPushProvider(cfg, name))
 	}
 
 	return manager
 }
 
-// GetProvider returns a notification provider for the specified channel
+// add appends a provider to the failover chain of its channel
+func (m *providerManager) add(channel string, provider NotificationProvider) {
+	chain, exists := m.providers[channel]
+	if !exists {
+		chain = &failoverProvider{channel: channel}
+		m.providers[channel] = chain
+	}
+	cooldown := time.Duration(m.config.ProviderCooldownSeconds) * time.Second
+	chain.members = append(chain.members, &failoverMember{
+		provider: provider,
+		breaker:  newCircuitBreaker(m.config.ProviderFailureThreshold, cooldown),
+	})
+}
+
+// GetProvider returns a notification provider for the specified channel. It
+// fails over between the channel's providers on send errors.
 func (m *providerManager) GetProvider(channel string) (NotificationProvider, error) {
 	provider, exists := m.providers[channel]
 	if !exists {
@@ -51,6 +65,18 @@ func (m *providerManager) GetProvider(channel string) (NotificationProvider, err
 	return provider, nil
 }
 
+// GetCallbackHandler returns the provider that accepts delivery callbacks under the given name
+func (m *providerManager) GetCallbackHandler(name string) (CallbackHandler, error) {
+	for _, chain := range m.providers {
+		for _, member := range chain.members {
+			if handler, ok := member.provider.(CallbackHandler); ok && handler.CallbackProvider() == name {
+				return handler, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("callback provider '%s' not found or not configured", name)
+}
+
 // GetAvailableChannels returns list of available notification channels
 func (m *providerManager) GetAvailableChannels() []string {
 	var channels []string
@@ -60,17 +86,16 @@ func (m *providerManager) GetAvailableChannels() []string {
 	return channels
 }
 
-// HealthCheck performs health checks on all providers
+// HealthCheck performs health checks on all providers, keyed by provider name.
+// Providers whose circuit is open report ErrCircuitOpen.
 func (m *providerManager) HealthCheck(ctx context.Context) map[string]error {
 	results := make(map[string]error)
-	
-	for channel, provider := range m.providers {
-		if err := provider.IsHealthy(ctx); err != nil {
-			results[channel] = err
-		} else {
-			results[channel] = nil // Healthy
+
+	for _, chain := range m.providers {
+		for _, member := range chain.members {
+			results[member.provider.GetName()] = member.health(ctx)
 		}
 	}
-	
+
 	return results
 }
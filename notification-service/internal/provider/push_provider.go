@@ -14,11 +14,11 @@ type PushProvider struct {
 	provider string // "fcm" or "apns"
 }
 
-// NewPushProvider creates a new push notification provider
-func NewPushProvider(cfg *config.Config) *PushProvider {
+// NewPushProvider creates a push notification provider for the named backend
+func NewPushProvider(cfg *config.Config, name string) *PushProvider {
 	return &PushProvider{
 		config:   cfg,
-		provider: cfg.PushProvider,
+		provider: name,
 	}
 }
 
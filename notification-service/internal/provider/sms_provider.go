@@ -18,11 +18,11 @@ type SMSProvider struct {
 	client   *twilio.RestClient
 }
 
-// NewSMSProvider creates a new SMS provider
-func NewSMSProvider(cfg *config.Config) *SMSProvider {
+// NewSMSProvider creates an SMS provider for the named backend
+func NewSMSProvider(cfg *config.Config, name string) *SMSProvider {
 	var client *twilio.RestClient
 	
-	if cfg.SMSProvider == "twilio" && cfg.TwilioAccountSID != "" && cfg.TwilioAuthToken != "" {
+	if name == "twilio" && cfg.TwilioAccountSID != "" && cfg.TwilioAuthToken != "" {
 		client = twilio.NewRestClientWithParams(twilio.ClientParams{
 			Username: cfg.TwilioAccountSID,
 			Password: cfg.TwilioAuthToken,
@@ -31,7 +31,7 @@ func NewSMSProvider(cfg *config.Config) *SMSProvider {
 
 	return &SMSProvider{
 		config:   cfg,
-		provider: cfg.SMSProvider,
+		provider: name,
 		client:   client,
 	}
 }
@@ -71,6 +71,9 @@ func (s *SMSProvider) sendWithTwilio(ctx context.Context, notification *model.No
 	params.SetFrom(s.config.TwilioFromNumber)
 	params.SetTo(notification.Recipient)
 	params.SetBody(notification.Content)
+	if s.config.CallbackBaseURL != "" {
+		params.SetStatusCallback(s.config.CallbackBaseURL + twilioCallbackPath)
+	}
 
 	// Send SMS
 	resp, err := s.client.Api.CreateMessage(params)
@@ -78,7 +81,7 @@ func (s *SMSProvider) sendWithTwilio(ctx context.Context, notification *model.No
 		return &SendResponse{
 			Status:       StatusFailed,
 			ErrorMessage: err.Error(),
-		}, err
+		}, classifyTwilioError(err)
 	}
 
 	// Check response
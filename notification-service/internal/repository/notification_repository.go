@@ -3,10 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"notification-service/internal/model"
+	"notification-service/internal/tracing"
 
 	"github.com/google/uuid"
 )
@@ -23,9 +26,12 @@ func NewNotificationRepository(db *sql.DB) *NotificationRepository {
 
 // CreateNotification creates a new notification record
 func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *model.Notification) error {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.CreateNotification")
+	defer span.End()
+
 	query := `
-		INSERT INTO notifications (id, event_type, channel, recipient, subject, content, status, priority, metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO notifications (id, event_type, channel, recipient, subject, content, template_id, status, priority, metadata, error_message, created_at, updated_at, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
@@ -35,11 +41,14 @@ func (r *NotificationRepository) CreateNotification(ctx context.Context, notific
 		notification.Recipient,
 		notification.Subject,
 		notification.Content,
+		notification.TemplateID,
 		notification.Status,
 		notification.Priority,
 		notification.Metadata,
+		notification.ErrorMsg,
 		notification.CreatedAt,
 		notification.UpdatedAt,
+		notification.UserID,
 	)
 
 	return err
@@ -47,19 +56,26 @@ func (r *NotificationRepository) CreateNotification(ctx context.Context, notific
 
 // GetNotificationByID retrieves a notification by ID
 func (r *NotificationRepository) GetNotificationByID(ctx context.Context, id uuid.UUID) (*model.Notification, error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.GetNotificationByID")
+	defer span.End()
+
 	query := `
 		SELECT id, event_type, channel, recipient, subject, content, status, priority, 
-		       provider_id, error_msg, metadata, sent_at, created_at, updated_at
+		       provider_id, error_message, metadata, retry_count, attempt_count, next_retry_at,
+		       sent_at, created_at, updated_at, user_id, template_id, resent_from_id, resent_by, provider
 		FROM notifications 
 		WHERE id = $1
 	`
 
 	notification := &model.Notification{}
 	var sentAt sql.NullTime
+	var nextRetryAt sql.NullTime
 	var subject sql.NullString
 	var providerID sql.NullString
 	var errorMsg sql.NullString
 	var metadata sql.NullString
+	var userID, templateID, resentFromID uuid.NullUUID
+	var resentBy, providerName sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&notification.ID,
@@ -73,15 +89,39 @@ func (r *NotificationRepository) GetNotificationByID(ctx context.Context, id uui
 		&providerID,
 		&errorMsg,
 		&metadata,
+		&notification.RetryCount,
+		&notification.AttemptCount,
+		&nextRetryAt,
 		&sentAt,
 		&notification.CreatedAt,
 		&notification.UpdatedAt,
+		&userID,
+		&templateID,
+		&resentFromID,
+		&resentBy,
+		&providerName,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if userID.Valid {
+		notification.UserID = &userID.UUID
+	}
+	if templateID.Valid {
+		notification.TemplateID = &templateID.UUID
+	}
+	if resentFromID.Valid {
+		notification.ResentFromID = &resentFromID.UUID
+	}
+	if resentBy.Valid {
+		notification.ResentBy = &resentBy.String
+	}
+	if providerName.Valid {
+		notification.Provider = &providerName.String
+	}
+
 	if subject.Valid {
 		notification.Subject = &subject.String
 	}
@@ -97,21 +137,34 @@ func (r *NotificationRepository) GetNotificationByID(ctx context.Context, id uui
 	if sentAt.Valid {
 		notification.SentAt = &sentAt.Time
 	}
+	if nextRetryAt.Valid {
+		notification.NextRetryAt = &nextRetryAt.Time
+	}
 
 	return notification, nil
 }
 
 // GetNotifications retrieves notifications with optional filters
 func (r *NotificationRepository) GetNotifications(ctx context.Context, userID *uuid.UUID, notificationType, status string) ([]*model.Notification, error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.GetNotifications")
+	defer span.End()
+
 	query := `
 		SELECT id, event_type, channel, recipient, subject, content, status, priority, 
-		       provider_id, error_msg, metadata, sent_at, created_at, updated_at
+		       provider_id, error_message, metadata, retry_count, attempt_count, next_retry_at,
+		       sent_at, created_at, updated_at
 		FROM notifications 
 		WHERE 1=1
 	`
 	args := []interface{}{}
 	argCount := 0
 
+	if userID != nil {
+		argCount++
+		query += fmt.Sprintf(" AND user_id = $%d", argCount)
+		args = append(args, *userID)
+	}
+
 	if notificationType != "" {
 		argCount++
 		query += fmt.Sprintf(" AND channel = $%d", argCount)
@@ -136,6 +189,7 @@ func (r *NotificationRepository) GetNotifications(ctx context.Context, userID *u
 	for rows.Next() {
 		notification := &model.Notification{}
 		var sentAt sql.NullTime
+		var nextRetryAt sql.NullTime
 		var subject sql.NullString
 		var providerID sql.NullString
 		var errorMsg sql.NullString
@@ -153,6 +207,9 @@ func (r *NotificationRepository) GetNotifications(ctx context.Context, userID *u
 			&providerID,
 			&errorMsg,
 			&metadata,
+			&notification.RetryCount,
+			&notification.AttemptCount,
+			&nextRetryAt,
 			&sentAt,
 			&notification.CreatedAt,
 			&notification.UpdatedAt,
@@ -176,6 +233,9 @@ func (r *NotificationRepository) GetNotifications(ctx context.Context, userID *u
 		if sentAt.Valid {
 			notification.SentAt = &sentAt.Time
 		}
+		if nextRetryAt.Valid {
+			notification.NextRetryAt = &nextRetryAt.Time
+		}
 
 		notifications = append(notifications, notification)
 	}
@@ -183,12 +243,172 @@ func (r *NotificationRepository) GetNotifications(ctx context.Context, userID *u
 	return notifications, rows.Err()
 }
 
+// ErrResendLimitReached is returned when a notification was already resent the
+// allowed number of times within the rate limit window
+var ErrResendLimitReached = errors.New("notification resend limit reached")
+
+// CreateResend stores resend as a repeat of its ResentFromID notification,
+// unless that notification was resent limit times since the given time. The
+// original row is locked so concurrent resends cannot both pass the check.
+func (r *NotificationRepository) CreateResend(ctx context.Context, resend *model.Notification, since time.Time, limit int) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.CreateResend")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var originalID uuid.UUID
+	if err = tx.QueryRowContext(ctx, `SELECT id FROM notifications WHERE id = $1 FOR UPDATE`, resend.ResentFromID).Scan(&originalID); err != nil {
+		return err
+	}
+
+	var recent int
+	if err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE resent_from_id = $1 AND created_at >= $2
+	`, originalID, since).Scan(&recent); err != nil {
+		return err
+	}
+	if recent >= limit {
+		return ErrResendLimitReached
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO notifications (id, event_type, channel, recipient, subject, content, template_id, status, priority, metadata, created_at, updated_at, user_id, resent_from_id, resent_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`,
+		resend.ID,
+		resend.EventType,
+		resend.Channel,
+		resend.Recipient,
+		resend.Subject,
+		resend.Content,
+		resend.TemplateID,
+		resend.Status,
+		resend.Priority,
+		resend.Metadata,
+		resend.CreatedAt,
+		resend.UpdatedAt,
+		resend.UserID,
+		resend.ResentFromID,
+		resend.ResentBy,
+	)
+	return err
+}
+
+// ListUserNotifications retrieves a page of the notifications sent to a user,
+// newest first
+func (r *NotificationRepository) ListUserNotifications(ctx context.Context, filter model.UserNotificationFilter) ([]*model.Notification, error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.ListUserNotifications")
+	defer span.End()
+
+	where, args := userNotificationConditions(filter)
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT id, user_id, event_type, channel, subject, status, error_message, sent_at, created_at, updated_at
+		FROM notifications
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*model.Notification{}
+	for rows.Next() {
+		notification := &model.Notification{}
+		var userID uuid.NullUUID
+		var subject sql.NullString
+		var errorMsg sql.NullString
+		var sentAt sql.NullTime
+
+		if err := rows.Scan(
+			&notification.ID,
+			&userID,
+			&notification.EventType,
+			&notification.Channel,
+			&subject,
+			&notification.Status,
+			&errorMsg,
+			&sentAt,
+			&notification.CreatedAt,
+			&notification.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if userID.Valid {
+			notification.UserID = &userID.UUID
+		}
+		if subject.Valid {
+			notification.Subject = &subject.String
+		}
+		if errorMsg.Valid {
+			notification.ErrorMsg = &errorMsg.String
+		}
+		if sentAt.Valid {
+			notification.SentAt = &sentAt.Time
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// CountUserNotifications counts every notification sent to a user matching
+// the filter, ignoring the page window
+func (r *NotificationRepository) CountUserNotifications(ctx context.Context, filter model.UserNotificationFilter) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.CountUserNotifications")
+	defer span.End()
+
+	where, args := userNotificationConditions(filter)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications WHERE "+where, args...).Scan(&total)
+	return total, err
+}
+
+// userNotificationConditions builds the WHERE clause shared by the user
+// notification list and count queries
+func userNotificationConditions(filter model.UserNotificationFilter) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{filter.UserID}
+
+	if filter.Channel != "" {
+		args = append(args, filter.Channel)
+		conditions = append(conditions, fmt.Sprintf("channel = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
 // UpdateNotificationStatus updates the status of a notification
 func (r *NotificationRepository) UpdateNotificationStatus(ctx context.Context, id uuid.UUID, status string, providerMessageID, errorMessage *string) error {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.UpdateNotificationStatus")
+	defer span.End()
+
 	query := `
 		UPDATE notifications 
-		SET status = $2, provider_id = $3, error_msg = $4, 
+		SET status = $2, provider_id = $3, error_message = $4, 
 		    sent_at = CASE WHEN $2 = 'sent' THEN $5 ELSE sent_at END,
+		    attempt_count = attempt_count + 1, next_retry_at = NULL,
 		    updated_at = $5
 		WHERE id = $1
 	`
@@ -197,3 +417,135 @@ func (r *NotificationRepository) UpdateNotificationStatus(ctx context.Context, i
 	_, err := r.db.ExecContext(ctx, query, id, status, providerMessageID, errorMessage, now)
 	return err
 }
+
+// MarkNotificationSent records a successful send and the provider that delivered it
+func (r *NotificationRepository) MarkNotificationSent(ctx context.Context, id uuid.UUID, provider, providerMessageID *string) error {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.MarkNotificationSent")
+	defer span.End()
+
+	query := `
+		UPDATE notifications
+		SET status = 'sent', provider = $2, provider_id = $3, error_message = NULL,
+		    sent_at = $4, attempt_count = attempt_count + 1, next_retry_at = NULL,
+		    updated_at = $4
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, provider, providerMessageID, time.Now())
+	return err
+}
+
+// GetNotificationByProviderID retrieves the notification a provider message ID was stored against
+func (r *NotificationRepository) GetNotificationByProviderID(ctx context.Context, providerID string) (*model.Notification, error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.GetNotificationByProviderID")
+	defer span.End()
+
+	query := `
+		SELECT id, status
+		FROM notifications
+		WHERE provider_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	notification := &model.Notification{}
+	if err := r.db.QueryRowContext(ctx, query, providerID).Scan(&notification.ID, &notification.Status); err != nil {
+		return nil, err
+	}
+	notification.ProviderID = &providerID
+
+	return notification, nil
+}
+
+// UpdateDeliveryStatus records a delivery status reported by a provider callback,
+// keeping the stored provider message ID
+func (r *NotificationRepository) UpdateDeliveryStatus(ctx context.Context, id uuid.UUID, status string, errorMessage *string) error {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.UpdateDeliveryStatus")
+	defer span.End()
+
+	query := `
+		UPDATE notifications
+		SET status = $2, error_message = COALESCE($3, error_message), updated_at = $4
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, status, errorMessage, time.Now())
+	return err
+}
+
+// ScheduleRetry records a failed send attempt and schedules the next retry
+func (r *NotificationRepository) ScheduleRetry(ctx context.Context, id uuid.UUID, errorMessage string, nextRetryAt time.Time) error {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.ScheduleRetry")
+	defer span.End()
+
+	query := `
+		UPDATE notifications
+		SET status = $2, error_message = $3, attempt_count = attempt_count + 1,
+		    next_retry_at = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, model.NotificationStatusRetrying, errorMessage, nextRetryAt, time.Now())
+	return err
+}
+
+// ClaimDueRetries moves up to limit notifications whose retry is due back to
+// pending and returns them. Rows locked by another worker are skipped so
+// concurrent workers never retry the same notification.
+func (r *NotificationRepository) ClaimDueRetries(ctx context.Context, now time.Time, limit int) ([]*model.Notification, error) {
+	ctx, span := tracing.StartSpan(ctx, "NotificationRepository.ClaimDueRetries")
+	defer span.End()
+
+	query := `
+		UPDATE notifications
+		SET status = $1, retry_count = retry_count + 1, next_retry_at = NULL, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = $3 AND next_retry_at <= $2
+			ORDER BY next_retry_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, channel, recipient, subject, content, priority,
+		          retry_count, attempt_count, expires_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.NotificationStatusPending, now, model.NotificationStatusRetrying, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.Notification
+	for rows.Next() {
+		notification := &model.Notification{Status: model.NotificationStatusPending}
+		var subject sql.NullString
+		var expiresAt sql.NullTime
+
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.EventType,
+			&notification.Channel,
+			&notification.Recipient,
+			&subject,
+			&notification.Content,
+			&notification.Priority,
+			&notification.RetryCount,
+			&notification.AttemptCount,
+			&expiresAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if subject.Valid {
+			notification.Subject = &subject.String
+		}
+		if expiresAt.Valid {
+			notification.ExpiresAt = &expiresAt.Time
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"notification-service/internal/model"
+	"notification-service/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type PreferenceRepository struct {
+	db *sql.DB
+}
+
+func NewPreferenceRepository(db *sql.DB) *PreferenceRepository {
+	return &PreferenceRepository{
+		db: db,
+	}
+}
+
+// GetPreferences retrieves a user's notification preferences. It returns
+// sql.ErrNoRows when the user has never saved any.
+func (r *PreferenceRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error) {
+	ctx, span := tracing.StartSpan(ctx, "PreferenceRepository.GetPreferences")
+	defer span.End()
+
+	query := `
+		SELECT channels, event_types, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	var channels, eventTypes []byte
+	var updatedAt time.Time
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&channels, &eventTypes, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	preferences := &model.NotificationPreferences{UserID: userID, UpdatedAt: &updatedAt}
+	if err := json.Unmarshal(channels, &preferences.Channels); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(eventTypes, &preferences.EventTypes); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// UpsertPreferences creates or replaces a user's notification preferences
+func (r *PreferenceRepository) UpsertPreferences(ctx context.Context, preferences *model.NotificationPreferences) error {
+	ctx, span := tracing.StartSpan(ctx, "PreferenceRepository.UpsertPreferences")
+	defer span.End()
+
+	channels, err := json.Marshal(preferences.Channels)
+	if err != nil {
+		return err
+	}
+	eventTypes, err := json.Marshal(preferences.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO notification_preferences (user_id, channels, event_types, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET channels = EXCLUDED.channels, event_types = EXCLUDED.event_types, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err = r.db.ExecContext(ctx, query, preferences.UserID, string(channels), string(eventTypes), *preferences.UpdatedAt)
+	return err
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"notification-service/internal/model"
+	"notification-service/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateTemplate is returned when a template name is already used for the channel
+var ErrDuplicateTemplate = errors.New("template already exists for channel")
+
+type TemplateRepository struct {
+	db *sql.DB
+}
+
+func NewTemplateRepository(db *sql.DB) *TemplateRepository {
+	return &TemplateRepository{
+		db: db,
+	}
+}
+
+const templateColumns = `id, name, event_type, channel, subject, content, variables, is_active, created_at, updated_at`
+
+// CreateTemplate creates a new notification template
+func (r *TemplateRepository) CreateTemplate(ctx context.Context, template *model.NotificationTemplate) error {
+	ctx, span := tracing.StartSpan(ctx, "TemplateRepository.CreateTemplate")
+	defer span.End()
+
+	query := `
+		INSERT INTO notification_templates (id, name, event_type, channel, subject, content, variables, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		template.ID,
+		template.Name,
+		template.EventType,
+		template.Channel,
+		template.Subject,
+		template.Content,
+		template.Variables,
+		template.IsActive,
+		template.CreatedAt,
+		template.UpdatedAt,
+	)
+
+	return mapTemplateError(err)
+}
+
+// GetTemplateByID retrieves a template by ID
+func (r *TemplateRepository) GetTemplateByID(ctx context.Context, id uuid.UUID) (*model.NotificationTemplate, error) {
+	ctx, span := tracing.StartSpan(ctx, "TemplateRepository.GetTemplateByID")
+	defer span.End()
+
+	query := `SELECT ` + templateColumns + ` FROM notification_templates WHERE id = $1`
+	return scanTemplate(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetActiveTemplate retrieves the active template with the given name for a channel
+func (r *TemplateRepository) GetActiveTemplate(ctx context.Context, name, channel string) (*model.NotificationTemplate, error) {
+	ctx, span := tracing.StartSpan(ctx, "TemplateRepository.GetActiveTemplate")
+	defer span.End()
+
+	query := `
+		SELECT ` + templateColumns + `
+		FROM notification_templates
+		WHERE name = $1 AND channel = $2 AND is_active = true
+	`
+	return scanTemplate(r.db.QueryRowContext(ctx, query, name, channel))
+}
+
+// ListTemplates retrieves templates, optionally filtered by channel
+func (r *TemplateRepository) ListTemplates(ctx context.Context, channel string) ([]*model.NotificationTemplate, error) {
+	ctx, span := tracing.StartSpan(ctx, "TemplateRepository.ListTemplates")
+	defer span.End()
+
+	query := `SELECT ` + templateColumns + ` FROM notification_templates WHERE 1=1`
+	args := []interface{}{}
+
+	if channel != "" {
+		query += fmt.Sprintf(" AND channel = $%d", len(args)+1)
+		args = append(args, channel)
+	}
+
+	query += " ORDER BY name, channel"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*model.NotificationTemplate{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// UpdateTemplate replaces the editable fields of a template. It returns
+// sql.ErrNoRows when the template does not exist.
+func (r *TemplateRepository) UpdateTemplate(ctx context.Context, template *model.NotificationTemplate) error {
+	ctx, span := tracing.StartSpan(ctx, "TemplateRepository.UpdateTemplate")
+	defer span.End()
+
+	query := `
+		UPDATE notification_templates
+		SET name = $2, event_type = $3, channel = $4, subject = $5, content = $6,
+		    variables = $7, is_active = $8, updated_at = $9
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		template.ID,
+		template.Name,
+		template.EventType,
+		template.Channel,
+		template.Subject,
+		template.Content,
+		template.Variables,
+		template.IsActive,
+		template.UpdatedAt,
+	)
+	if err != nil {
+		return mapTemplateError(err)
+	}
+
+	return requireRowsAffected(result)
+}
+
+// DeleteTemplate deletes a template. It returns sql.ErrNoRows when the template does not exist.
+func (r *TemplateRepository) DeleteTemplate(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.StartSpan(ctx, "TemplateRepository.DeleteTemplate")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notification_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(result)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row rowScanner) (*model.NotificationTemplate, error) {
+	template := &model.NotificationTemplate{}
+	var subject sql.NullString
+	var variables sql.NullString
+
+	err := row.Scan(
+		&template.ID,
+		&template.Name,
+		&template.EventType,
+		&template.Channel,
+		&subject,
+		&template.Content,
+		&variables,
+		&template.IsActive,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if subject.Valid {
+		template.Subject = &subject.String
+	}
+	if variables.Valid {
+		template.Variables = &variables.String
+	}
+
+	return template, nil
+}
+
+func requireRowsAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func mapTemplateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateTemplate
+	}
+	return err
+}
@@ -2,34 +2,92 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"notification-service/internal/model"
 	"notification-service/internal/provider"
 	"notification-service/internal/repository"
+	"notification-service/internal/tracing"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type NotificationService struct {
 	notificationRepo *repository.NotificationRepository
+	templateRepo     *repository.TemplateRepository
+	preferenceRepo   *repository.PreferenceRepository
 	providerManager  provider.ProviderManager
+	retryPolicy      RetryPolicy
+	resendPolicy     ResendPolicy
+	// transactionalEventTypes bypass user notification preferences
+	transactionalEventTypes map[string]bool
 }
 
-func NewNotificationService(notificationRepo *repository.NotificationRepository, providerManager provider.ProviderManager) *NotificationService {
+// RetryPolicy controls how failed sends are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first failed attempt
+	MaxRetries int
+	// BaseDelay is the wait before the first retry; it doubles for each later retry
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// BatchSize caps how many due retries are claimed per worker run
+	BatchSize int
+}
+
+// ResendPolicy limits manual resends of stored notifications
+type ResendPolicy struct {
+	// MaxAge is how old a notification may be and still be resent
+	MaxAge time.Duration
+	// Limit resends of one notification are allowed per Window
+	Limit  int
+	Window time.Duration
+}
+
+// backoff returns the wait before retrying after the given failed attempt (1-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func NewNotificationService(notificationRepo *repository.NotificationRepository, templateRepo *repository.TemplateRepository, preferenceRepo *repository.PreferenceRepository, providerManager provider.ProviderManager, retryPolicy RetryPolicy, resendPolicy ResendPolicy, transactionalEventTypes []string) *NotificationService {
+	transactional := make(map[string]bool, len(transactionalEventTypes))
+	for _, eventType := range transactionalEventTypes {
+		transactional[eventType] = true
+	}
+
 	return &NotificationService{
-		notificationRepo: notificationRepo,
-		providerManager:  providerManager,
+		notificationRepo:        notificationRepo,
+		templateRepo:            templateRepo,
+		preferenceRepo:          preferenceRepo,
+		providerManager:         providerManager,
+		retryPolicy:             retryPolicy,
+		resendPolicy:            resendPolicy,
+		transactionalEventTypes: transactional,
 	}
 }
 
-// SendNotification sends a notification using the appropriate provider
+// SendNotification sends a notification using the appropriate provider. When a
+// template is named, the subject and content are rendered from it; an explicit
+// subject in the request takes precedence over the template's. Notifications
+// the user opted out of are recorded as suppressed and not dispatched.
 func (s *NotificationService) SendNotification(ctx context.Context, request *model.SendNotificationRequest) (*model.Notification, error) {
 	// Create notification record
 	notification := &model.Notification{
 		ID:        uuid.New(),
+		UserID:    request.UserID,
 		EventType: "manual_send",
 		Channel:   request.Type,
 		Recipient: request.Recipient,
@@ -41,6 +99,32 @@ func (s *NotificationService) SendNotification(ctx context.Context, request *mod
 		UpdatedAt: time.Now(),
 	}
 
+	if request.Template != "" {
+		rendered, err := s.RenderTemplate(ctx, request.Template, request.Type, request.Data)
+		if err != nil {
+			return nil, err
+		}
+		notification.TemplateID = &rendered.TemplateID
+		notification.Content = rendered.Content
+		if request.Subject == "" {
+			notification.Subject = rendered.Subject
+		}
+		notification.EventType = rendered.EventType
+	}
+	if request.EventType != "" {
+		notification.EventType = request.EventType
+	}
+
+	allowed, err := s.allowedByPreferences(ctx, request.UserID, notification.Channel, notification.EventType)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load notification preferences")
+		return nil, err
+	}
+	if !allowed {
+		notification.Status = model.NotificationStatusSuppressed
+		notification.ErrorMsg = stringPtr(fmt.Sprintf("recipient opted out of %s notifications for %s", notification.Channel, notification.EventType))
+	}
+
 	// Add metadata if provided
 	if request.Metadata != nil {
 		metadataJSON, _ := json.Marshal(request.Metadata)
@@ -54,8 +138,17 @@ func (s *NotificationService) SendNotification(ctx context.Context, request *mod
 		return nil, err
 	}
 
-	// Send notification asynchronously
-	go s.sendNotificationAsync(ctx, notification)
+	if !allowed {
+		log.Info().
+			Str("notification_id", notification.ID.String()).
+			Str("channel", notification.Channel).
+			Str("event_type", notification.EventType).
+			Msg("Notification suppressed by user preferences")
+		return notification, nil
+	}
+
+	// Send notification asynchronously; the send outlives the request
+	go s.sendNotificationAsync(context.WithoutCancel(ctx), notification)
 
 	return notification, nil
 }
@@ -70,25 +163,157 @@ func (s *NotificationService) GetNotifications(ctx context.Context, userID *uuid
 	return s.notificationRepo.GetNotifications(ctx, userID, notificationType, status)
 }
 
+// Errors returned by ResendNotification
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrResendTooOld         = errors.New("notification is too old to resend")
+	ErrResendNotAllowed     = errors.New("notification cannot be resent in its current status")
+	ErrResendLimitReached   = repository.ErrResendLimitReached
+)
+
+// ResendNotification dispatches a stored notification again as a new
+// notification linked to the original, recording who triggered it. Suppressed
+// notifications stay suppressed and ones still being delivered are left alone.
+func (s *NotificationService) ResendNotification(ctx context.Context, id uuid.UUID, triggeredBy string) (*model.Notification, error) {
+	original, err := s.notificationRepo.GetNotificationByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, err
+	}
+
+	if time.Since(original.CreatedAt) > s.resendPolicy.MaxAge {
+		return nil, ErrResendTooOld
+	}
+	switch original.Status {
+	case model.NotificationStatusSuppressed, model.NotificationStatusPending, model.NotificationStatusRetrying:
+		return nil, fmt.Errorf("%w: %s", ErrResendNotAllowed, original.Status)
+	}
+
+	now := time.Now()
+	resend := &model.Notification{
+		ID:           uuid.New(),
+		UserID:       original.UserID,
+		EventType:    original.EventType,
+		Channel:      original.Channel,
+		Recipient:    original.Recipient,
+		Subject:      original.Subject,
+		Content:      original.Content,
+		TemplateID:   original.TemplateID,
+		Status:       model.NotificationStatusPending,
+		Priority:     original.Priority,
+		Metadata:     original.Metadata,
+		ResentFromID: &original.ID,
+		ResentBy:     &triggeredBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.notificationRepo.CreateResend(ctx, resend, now.Add(-s.resendPolicy.Window), s.resendPolicy.Limit); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("notification_id", resend.ID.String()).
+		Str("resent_from_id", original.ID.String()).
+		Str("resent_by", triggeredBy).
+		Msg("Notification resend requested")
+
+	go s.sendNotificationAsync(context.WithoutCancel(ctx), resend)
+
+	return resend, nil
+}
+
+// ListUserNotifications returns a page of the notifications sent to a user,
+// newest first, and the total number matching the filter
+func (s *NotificationService) ListUserNotifications(ctx context.Context, filter model.UserNotificationFilter) ([]*model.Notification, int, error) {
+	notifications, err := s.notificationRepo.ListUserNotifications(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.notificationRepo.CountUserNotifications(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
 // sendNotificationAsync sends the notification using the appropriate provider
 func (s *NotificationService) sendNotificationAsync(ctx context.Context, notification *model.Notification) {
 	// Get the appropriate provider
 	notificationProvider, err := s.providerManager.GetProvider(notification.Channel)
 	if err != nil {
 		log.Error().Err(err).Str("channel", notification.Channel).Msg("Failed to get provider")
-		s.updateNotificationStatus(ctx, notification.ID, "failed", nil, stringPtr(err.Error()))
+		s.handleSendFailure(ctx, notification, provider.Permanent(err))
 		return
 	}
 
 	// Send the notification
-	response, err := notificationProvider.Send(ctx, notification)
+	sendCtx, span := tracing.StartSpan(ctx, "provider.Send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("notification.id", notification.ID.String()),
+			attribute.String("notification.channel", notification.Channel),
+		),
+	)
+	response, err := notificationProvider.Send(sendCtx, notification)
+	if err == nil && response != nil && response.Status == provider.StatusFailed {
+		// The provider accepted the request but already rejected the message
+		err = provider.Permanent(fmt.Errorf("provider rejected notification: %s", response.ErrorMessage))
+	}
+	tracing.AddSpanError(span, err)
+	span.End()
 	if err != nil {
 		log.Error().Err(err).Str("notification_id", notification.ID.String()).Msg("Failed to send notification")
-		s.updateNotificationStatus(ctx, notification.ID, "failed", nil, stringPtr(err.Error()))
+		s.handleSendFailure(ctx, notification, err)
 	} else {
-		log.Info().Str("notification_id", notification.ID.String()).Str("provider_id", response.ProviderID).Msg("Notification sent successfully")
-		s.updateNotificationStatus(ctx, notification.ID, "sent", stringPtr(response.ProviderID), nil)
+		log.Info().Str("notification_id", notification.ID.String()).Str("provider", response.Provider).Str("provider_id", response.ProviderID).Msg("Notification sent successfully")
+		if err := s.notificationRepo.MarkNotificationSent(ctx, notification.ID, stringPtr(response.Provider), stringPtr(response.ProviderID)); err != nil {
+			log.Error().Err(err).Str("notification_id", notification.ID.String()).Msg("Failed to update notification status")
+		}
+	}
+}
+
+// handleSendFailure schedules a retry with exponential backoff, or marks the
+// notification failed when the error is permanent, the retries are used up or
+// the notification has expired
+func (s *NotificationService) handleSendFailure(ctx context.Context, notification *model.Notification, sendErr error) {
+	attempt := notification.AttemptCount + 1
+	if provider.IsPermanent(sendErr) || attempt > s.retryPolicy.MaxRetries || notification.IsExpired() {
+		s.updateNotificationStatus(ctx, notification.ID, "failed", nil, stringPtr(sendErr.Error()))
+		return
+	}
+
+	nextRetryAt := time.Now().Add(s.retryPolicy.backoff(attempt))
+	if err := s.notificationRepo.ScheduleRetry(ctx, notification.ID, sendErr.Error(), nextRetryAt); err != nil {
+		log.Error().Err(err).Str("notification_id", notification.ID.String()).Msg("Failed to schedule notification retry")
+		return
+	}
+
+	log.Info().
+		Str("notification_id", notification.ID.String()).
+		Int("attempt", attempt).
+		Time("next_retry_at", nextRetryAt).
+		Msg("Notification retry scheduled")
+}
+
+// RetryDueNotifications re-attempts notifications whose retry time has passed.
+// It returns the number of notifications retried.
+func (s *NotificationService) RetryDueNotifications(ctx context.Context) (int, error) {
+	notifications, err := s.notificationRepo.ClaimDueRetries(ctx, time.Now(), s.retryPolicy.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, notification := range notifications {
+		if notification.IsExpired() {
+			s.updateNotificationStatus(ctx, notification.ID, "failed", nil, stringPtr("notification expired before it could be sent"))
+			continue
+		}
+		s.sendNotificationAsync(ctx, notification)
 	}
+
+	return len(notifications), nil
 }
 
 // updateNotificationStatus updates the notification status in the database
@@ -112,6 +337,56 @@ func (s *NotificationService) getProviderName(notificationType string) string {
 	}
 }
 
+// ErrInvalidCallbackSignature is returned when a provider callback fails verification
+var ErrInvalidCallbackSignature = provider.ErrInvalidSignature
+
+// ErrUnknownCallbackProvider is returned when no configured provider accepts callbacks under the given name
+var ErrUnknownCallbackProvider = errors.New("unknown callback provider")
+
+// HandleDeliveryCallback verifies a provider delivery callback and applies the
+// reported statuses to the matching notifications. It returns the number of
+// notifications updated.
+func (s *NotificationService) HandleDeliveryCallback(ctx context.Context, providerName string, req *provider.CallbackRequest) (int, error) {
+	handler, err := s.providerManager.GetCallbackHandler(providerName)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnknownCallbackProvider, err)
+	}
+
+	updates, err := handler.ParseCallback(req)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, update := range updates {
+		notification, err := s.notificationRepo.GetNotificationByProviderID(ctx, update.ProviderMessageID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Warn().Str("provider", providerName).Str("provider_id", update.ProviderMessageID).Msg("Delivery callback for unknown message")
+				continue
+			}
+			return updated, err
+		}
+
+		var errorMessage *string
+		if update.ErrorMessage != "" {
+			errorMessage = stringPtr(update.ErrorMessage)
+		}
+		if err := s.notificationRepo.UpdateDeliveryStatus(ctx, notification.ID, update.Status, errorMessage); err != nil {
+			return updated, err
+		}
+		updated++
+
+		log.Info().
+			Str("notification_id", notification.ID.String()).
+			Str("provider", providerName).
+			Str("status", update.Status).
+			Msg("Notification delivery status updated")
+	}
+
+	return updated, nil
+}
+
 // stringPtr returns a pointer to a string
 func stringPtr(s string) *string {
 	return &s
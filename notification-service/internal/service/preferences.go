@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"notification-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPreferences is returned when preferences name an unknown channel
+var ErrInvalidPreferences = errors.New("invalid notification preferences")
+
+// GetPreferences returns a user's notification preferences, or the defaults
+// (everything enabled) when none are saved
+func (s *NotificationService) GetPreferences(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error) {
+	preferences, err := s.preferenceRepo.GetPreferences(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.NotificationPreferences{
+			UserID:     userID,
+			Channels:   map[string]bool{},
+			EventTypes: map[string]map[string]bool{},
+		}, nil
+	}
+	return preferences, err
+}
+
+// UpdatePreferences replaces a user's notification preferences
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid.UUID, request *model.NotificationPreferencesRequest) (*model.NotificationPreferences, error) {
+	if err := validatePreferenceChannels(request.Channels); err != nil {
+		return nil, err
+	}
+	for eventType, channels := range request.EventTypes {
+		if eventType == "" {
+			return nil, fmt.Errorf("%w: event type must not be empty", ErrInvalidPreferences)
+		}
+		if err := validatePreferenceChannels(channels); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	preferences := &model.NotificationPreferences{
+		UserID:     userID,
+		Channels:   request.Channels,
+		EventTypes: request.EventTypes,
+		UpdatedAt:  &now,
+	}
+	if preferences.Channels == nil {
+		preferences.Channels = map[string]bool{}
+	}
+	if preferences.EventTypes == nil {
+		preferences.EventTypes = map[string]map[string]bool{}
+	}
+
+	if err := s.preferenceRepo.UpsertPreferences(ctx, preferences); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// allowedByPreferences reports whether a notification may be sent to the user.
+// Transactional event types are always sent; notifications without a user are
+// not subject to preferences.
+func (s *NotificationService) allowedByPreferences(ctx context.Context, userID *uuid.UUID, channel, eventType string) (bool, error) {
+	if userID == nil || s.transactionalEventTypes[eventType] {
+		return true, nil
+	}
+
+	preferences, err := s.GetPreferences(ctx, *userID)
+	if err != nil {
+		return false, err
+	}
+	return preferences.Allows(channel, eventType), nil
+}
+
+func validatePreferenceChannels(channels map[string]bool) error {
+	for channel := range channels {
+		switch channel {
+		case model.ChannelEmail, model.ChannelSMS, model.ChannelPush:
+		default:
+			return fmt.Errorf("%w: unknown channel %q", ErrInvalidPreferences, channel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"notification-service/internal/model"
+	"notification-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTemplateNotFound is returned when no active template matches the name and channel
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrDuplicateTemplate is returned when a template name is already used for the channel
+	ErrDuplicateTemplate = repository.ErrDuplicateTemplate
+	// ErrInvalidTemplate is returned when a template fails to parse or render
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// RenderedTemplate is a template rendered with caller supplied data
+type RenderedTemplate struct {
+	TemplateID uuid.UUID
+	EventType  string
+	Subject    *string
+	Content    string
+}
+
+// templateFuncs are available to every template, e.g. {{default "there" .user_name}}
+var templateFuncs = template.FuncMap{
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil {
+			return fallback
+		}
+		if s, ok := value.(string); ok && s == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// RenderTemplate renders the active template with the given name for a channel.
+// Fields referenced by the template but missing from data render as empty strings.
+func (s *NotificationService) RenderTemplate(ctx context.Context, name, channel string, data map[string]interface{}) (*RenderedTemplate, error) {
+	tmpl, err := s.templateRepo.GetActiveTemplate(ctx, name, channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrTemplateNotFound, name, channel)
+		}
+		return nil, err
+	}
+
+	content, err := renderText(tmpl.Name, tmpl.Content, data)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &RenderedTemplate{TemplateID: tmpl.ID, EventType: tmpl.EventType, Content: content}
+	if tmpl.Subject != nil {
+		subject, err := renderText(tmpl.Name+".subject", *tmpl.Subject, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Subject = &subject
+	}
+
+	return rendered, nil
+}
+
+// CreateTemplate validates and stores a new template
+func (s *NotificationService) CreateTemplate(ctx context.Context, request *model.TemplateRequest) (*model.NotificationTemplate, error) {
+	now := time.Now()
+	tmpl := &model.NotificationTemplate{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := applyTemplateRequest(tmpl, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.CreateTemplate(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// GetTemplate retrieves a template by ID
+func (s *NotificationService) GetTemplate(ctx context.Context, id uuid.UUID) (*model.NotificationTemplate, error) {
+	tmpl, err := s.templateRepo.GetTemplateByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	return tmpl, err
+}
+
+// ListTemplates lists templates, optionally filtered by channel
+func (s *NotificationService) ListTemplates(ctx context.Context, channel string) ([]*model.NotificationTemplate, error) {
+	return s.templateRepo.ListTemplates(ctx, channel)
+}
+
+// UpdateTemplate validates and replaces an existing template
+func (s *NotificationService) UpdateTemplate(ctx context.Context, id uuid.UUID, request *model.TemplateRequest) (*model.NotificationTemplate, error) {
+	tmpl, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTemplateRequest(tmpl, request); err != nil {
+		return nil, err
+	}
+	tmpl.UpdatedAt = time.Now()
+
+	if err := s.templateRepo.UpdateTemplate(ctx, tmpl); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// DeleteTemplate deletes a template by ID
+func (s *NotificationService) DeleteTemplate(ctx context.Context, id uuid.UUID) error {
+	err := s.templateRepo.DeleteTemplate(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTemplateNotFound
+	}
+	return err
+}
+
+// applyTemplateRequest copies a create/update request onto a template after
+// checking that the subject and content parse
+func applyTemplateRequest(tmpl *model.NotificationTemplate, request *model.TemplateRequest) error {
+	if _, err := parseTemplate(request.Name, request.Content); err != nil {
+		return err
+	}
+	if request.Subject != nil {
+		if _, err := parseTemplate(request.Name+".subject", *request.Subject); err != nil {
+			return err
+		}
+	}
+
+	tmpl.Name = request.Name
+	tmpl.EventType = request.EventType
+	tmpl.Channel = request.Channel
+	tmpl.Subject = request.Subject
+	tmpl.Content = request.Content
+	tmpl.IsActive = request.IsActive == nil || *request.IsActive
+
+	tmpl.Variables = nil
+	if request.Variables != nil {
+		variablesJSON, err := json.Marshal(request.Variables)
+		if err != nil {
+			return fmt.Errorf("%w: variables: %v", ErrInvalidTemplate, err)
+		}
+		variables := string(variablesJSON)
+		tmpl.Variables = &variables
+	}
+
+	return nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+func renderText(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, withFieldDefaults(tmpl, data)); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return out.String(), nil
+}
+
+// withFieldDefaults copies data and fills every top-level field the template
+// references but data lacks (or holds as null) with an empty string, so the
+// output never contains "<no value>"
+func withFieldDefaults(tmpl *template.Template, data map[string]interface{}) map[string]interface{} {
+	fields := map[string]struct{}{}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectFields(t.Tree.Root, fields)
+		}
+	}
+
+	values := make(map[string]interface{}, len(data)+len(fields))
+	for key, value := range data {
+		values[key] = value
+	}
+	for field := range fields {
+		if values[field] == nil {
+			values[field] = ""
+		}
+	}
+	return values
+}
+
+func collectFields(node parse.Node, fields map[string]struct{}) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, fields)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, fields)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, fields)
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, fields)
+	case *parse.FieldNode:
+		fields[n.Ident[0]] = struct{}{}
+	case *parse.IfNode:
+		collectBranchFields(&n.BranchNode, fields)
+	case *parse.RangeNode:
+		collectBranchFields(&n.BranchNode, fields)
+	case *parse.WithNode:
+		collectBranchFields(&n.BranchNode, fields)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, fields)
+	}
+}
+
+func collectBranchFields(n *parse.BranchNode, fields map[string]struct{}) {
+	collectFields(n.Pipe, fields)
+	collectFields(n.List, fields)
+	collectFields(n.ElseList, fields)
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"notification-service/internal/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryWorker periodically re-attempts failed notifications whose backoff has elapsed
+type RetryWorker struct {
+	notificationService *service.NotificationService
+	interval            time.Duration
+}
+
+// NewRetryWorker creates a new notification retry worker
+func NewRetryWorker(notificationService *service.NotificationService, interval time.Duration) *RetryWorker {
+	return &RetryWorker{
+		notificationService: notificationService,
+		interval:            interval,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *RetryWorker) Start(ctx context.Context) {
+	log.Info().Dur("interval", w.interval).Msg("Starting notification retry worker")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Notification retry worker stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *RetryWorker) run(ctx context.Context) {
+	retried, err := w.notificationService.RetryDueNotifications(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retry notifications")
+		return
+	}
+
+	if retried > 0 {
+		log.Info().Int("count", retried).Msg("Retried failed notifications")
+	}
+}
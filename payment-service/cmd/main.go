@@ -13,6 +13,8 @@ import (
 	"payment-service/internal/db"
 	"payment-service/internal/repository"
 	"payment-service/internal/service"
+	"payment-service/internal/tracing"
+	"payment-service/internal/worker"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -28,6 +30,9 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid configuration")
+	}
 
 	// Set log level
 	level, err := zerolog.ParseLevel(cfg.LogLevel)
@@ -42,8 +47,14 @@ func main() {
 		Str("port", cfg.Port).
 		Msg("Starting payment service")
 
+	// Initialize tracing (optional - only if JAEGER_ENDPOINT is set)
+	if jaegerEndpoint := os.Getenv("JAEGER_ENDPOINT"); jaegerEndpoint != "" {
+		tracingCleanup := tracing.InitTracing("payment-service", jaegerEndpoint)
+		defer tracingCleanup()
+	}
+
 	// Initialize database
-	database, err := db.NewConnection(cfg.DatabaseURL)
+	database, err := db.NewConnection(cfg.DatabaseURL, cfg.DBPool())
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
@@ -58,8 +69,26 @@ func main() {
 	paymentRepo := repository.NewPaymentRepository(database)
 	gatewayRepo := repository.NewGatewayRepository(database)
 
+	// Initialize payment event publisher
+	var eventPublisher service.EventPublisher
+	if len(cfg.KafkaBrokers) > 0 {
+		eventPublisher = service.NewKafkaEventPublisher(cfg.KafkaBrokers, cfg.KafkaTopic)
+		defer eventPublisher.Close()
+		log.Info().Strs("brokers", cfg.KafkaBrokers).Str("topic", cfg.KafkaTopic).Msg("Publishing payment events to message broker")
+	}
+
 	// Initialize services
-	paymentService := service.NewPaymentService(paymentRepo, gatewayRepo, cfg)
+	paymentService := service.NewPaymentService(paymentRepo, gatewayRepo, cfg, eventPublisher)
+
+	// Start payment expiry sweeper
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	expirySweeper := worker.NewExpirySweeper(paymentService, time.Duration(cfg.ExpirySweepIntervalMinutes)*time.Minute)
+	go expirySweeper.Start(workerCtx)
+
+	// Start payment reconciler
+	reconciler := worker.NewReconciler(paymentService, time.Duration(cfg.ReconcileIntervalMinutes)*time.Minute)
+	go reconciler.Start(workerCtx)
 
 	// Initialize HTTP server
 	server := api.NewServer(paymentService, cfg)
@@ -88,6 +117,8 @@ func main() {
 
 	log.Info().Msg("Shutting down payment service...")
 
+	stopWorkers()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+	sharederrors "salon-shared/errors"
+)
+
+// userTypeSalon is the user type of salon staff tokens issued by salon-service
+const userTypeSalon = "salon_USER"
+
+// Caller identifies who made an authenticated request
+type Caller struct {
+	// Service is set for other services presenting the shared service token
+	Service bool
+	UserID  string
+}
+
+// Identity names the caller for audit records: "service" or "staff:<user ID>"
+func (c *Caller) Identity() string {
+	if c.Service {
+		return "service"
+	}
+	return "staff:" + c.UserID
+}
+
+type callerCtxKey struct{}
+
+// CallerFromContext returns the caller stored by Authenticator.RequireStaffOrService, if any
+func CallerFromContext(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(callerCtxKey{}).(*Caller)
+	return caller
+}
+
+// Authenticator accepts either the shared service token or a salon staff
+// access token issued by salon-service as a Bearer credential
+type Authenticator struct {
+	accessSecret []byte
+	serviceToken string
+}
+
+// NewAuthenticator creates an authenticator. An empty secret or service token
+// disables that kind of credential.
+func NewAuthenticator(accessSecret, serviceToken string) *Authenticator {
+	return &Authenticator{
+		accessSecret: []byte(accessSecret),
+		serviceToken: serviceToken,
+	}
+}
+
+type accessClaims struct {
+	UserID   string `json:"uid"`
+	UserType string `json:"user_type"`
+	jwt.RegisteredClaims
+}
+
+// RequireStaffOrService rejects requests that are not from salon staff or
+// another service and stores the caller in the request context
+func (a *Authenticator) RequireStaffOrService(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authz := r.Header.Get("Authorization")
+		parts := strings.SplitN(authz, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+			sharederrors.WriteError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		caller, err := a.authenticate(strings.TrimSpace(parts[1]))
+		if err != nil {
+			log.Warn().Err(err).Msg("Rejected payment API credential")
+			sharederrors.WriteError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if caller == nil {
+			sharederrors.WriteError(w, http.StatusForbidden, "salon staff or service credentials required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerCtxKey{}, caller)))
+	})
+}
+
+// authenticate returns the caller, or nil for a valid token of a customer
+func (a *Authenticator) authenticate(token string) (*Caller, error) {
+	if a.serviceToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.serviceToken)) == 1 {
+		return &Caller{Service: true}, nil
+	}
+	if len(a.accessSecret) == 0 {
+		return nil, errors.New("access tokens are not accepted: no secret configured")
+	}
+
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.accessSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserID == "" {
+		return nil, errors.New("token has no user id")
+	}
+	if claims.UserType != userTypeSalon {
+		return nil, nil
+	}
+	return &Caller{UserID: claims.UserID}, nil
+}
@@ -34,13 +34,12 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, response)
 }
 
-// Ready handles GET /api/v1/ready
+// Ready handles GET /api/v1/ready. Besides the database it reports each
+// gateway's reachability; only gateways listed as critical fail readiness.
 func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
-	// Ready check is similar to health but can be more strict
-	response, err := h.paymentService.HealthCheck(r.Context())
+	response, err := h.paymentService.ReadinessCheck(r.Context())
 	if err != nil {
 		log.Error().Err(err).Msg("Readiness check failed")
-		w.WriteHeader(http.StatusServiceUnavailable)
 		utils.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{
 			"status": "not ready",
 			"error":  err.Error(),
@@ -48,13 +47,15 @@ func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if database is healthy for readiness
-	if response.Database != "healthy" {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		utils.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"status":   "not ready",
-			"database": response.Database,
-		})
+	ready := response.Database == "healthy"
+	for _, gateway := range response.Gateways {
+		if gateway.Critical && gateway.Status != "healthy" {
+			ready = false
+		}
+	}
+	if !ready {
+		response.Status = "not ready"
+		utils.WriteJSON(w, http.StatusServiceUnavailable, response)
 		return
 	}
 
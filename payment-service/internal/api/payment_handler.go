@@ -1,9 +1,8 @@
 package api
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
+	"time"
 
 	"payment-service/internal/model"
 	"payment-service/internal/service"
@@ -12,7 +11,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"salon-shared/errors"
+	"salon-shared/pagination"
 	"salon-shared/utils"
+	"salon-shared/currency"
 )
 
 // PaymentHandler handles payment-related HTTP requests
@@ -30,8 +31,8 @@ func NewPaymentHandler(paymentService service.PaymentService) *PaymentHandler {
 // InitiatePayment handles POST /api/v1/payments/initiate
 func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request) {
 	var request model.InitiatePaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("request_body", "Invalid request body")))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -50,8 +51,10 @@ func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request)
 		validationErrors = errors.AppendValidationError(validationErrors, "amount", "Amount must be greater than 0")
 	}
 	
-	if request.Currency == "" {
-		validationErrors = errors.AppendValidationError(validationErrors, "currency", "Currency is required")
+	if code, err := currency.Normalize(request.Currency); err != nil {
+		validationErrors = errors.AppendValidationError(validationErrors, "currency", err.Error())
+	} else {
+		request.Currency = code
 	}
 	
 	if request.IdempotencyKey == "" {
@@ -75,7 +78,7 @@ func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request)
 	response, err := h.paymentService.InitiatePayment(r.Context(), &request)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initiate payment")
-		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -85,8 +88,8 @@ func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request)
 // ConfirmPayment handles POST /api/v1/payments/confirm
 func (h *PaymentHandler) ConfirmPayment(w http.ResponseWriter, r *http.Request) {
 	var request model.ConfirmPaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("request_body", "Invalid request body")))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -109,7 +112,7 @@ func (h *PaymentHandler) ConfirmPayment(w http.ResponseWriter, r *http.Request)
 	response, err := h.paymentService.ConfirmPayment(r.Context(), &request)
 	if err != nil {
 		log.Error().Err(err).Str("payment_id", request.PaymentID.String()).Msg("Failed to confirm payment")
-		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -135,6 +138,53 @@ func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, payment)
 }
 
+// GetPaymentEvents handles GET /api/v1/payments/{paymentID}/events
+func (h *PaymentHandler) GetPaymentEvents(w http.ResponseWriter, r *http.Request) {
+	paymentIDStr := chi.URLParam(r, "paymentID")
+	paymentID, err := uuid.Parse(paymentIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("payment_id", "Invalid payment ID")))
+		return
+	}
+
+	events, err := h.paymentService.GetPaymentEvents(r.Context(), paymentID)
+	if err != nil {
+		log.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to get payment events")
+		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// ReconcilePayment handles POST /api/v1/payments/{paymentID}/reconcile for
+// salon staff and services
+func (h *PaymentHandler) ReconcilePayment(w http.ResponseWriter, r *http.Request) {
+	paymentIDStr := chi.URLParam(r, "paymentID")
+	paymentID, err := uuid.Parse(paymentIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("payment_id", "Invalid payment ID")))
+		return
+	}
+
+	actor := model.ActorAPI
+	if caller := CallerFromContext(r.Context()); caller != nil {
+		actor = caller.Identity()
+	}
+
+	response, err := h.paymentService.ReconcilePayment(r.Context(), paymentID, actor)
+	if err != nil {
+		log.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to reconcile payment")
+		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
 // GetPaymentsByBooking handles GET /api/v1/bookings/{bookingID}/payments
 func (h *PaymentHandler) GetPaymentsByBooking(w http.ResponseWriter, r *http.Request) {
 	bookingIDStr := chi.URLParam(r, "bookingID")
@@ -166,25 +216,13 @@ func (h *PaymentHandler) GetPaymentsByUser(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Parse pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 20 // default
-	if limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-			limit = parsedLimit
-		}
-	}
-
-	offset := 0 // default
-	if offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		writePaginationError(w, err)
+		return
 	}
 
-	response, err := h.paymentService.GetPaymentsByUser(r.Context(), userID, limit, offset)
+	response, err := h.paymentService.GetPaymentsByUser(r.Context(), userID, page)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get payments by user")
 		errors.WriteAPIError(w, errors.MapToAPIError(err))
@@ -204,8 +242,8 @@ func (h *PaymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request model.RefundPaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("request_body", "Invalid request body")))
+	if err := utils.DecodeJSON(r.Body, &request); err != nil {
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -231,7 +269,7 @@ func (h *PaymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
 	response, err := h.paymentService.RefundPayment(r.Context(), &request)
 	if err != nil {
 		log.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to refund payment")
-		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		errors.WriteAPIError(w, err)
 		return
 	}
 
@@ -260,6 +298,31 @@ func (h *PaymentHandler) GetRefunds(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetRefundsByUser handles GET /api/v1/users/{userID}/refunds
+func (h *PaymentHandler) GetRefundsByUser(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "userID")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("user_id", "Invalid user ID")))
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		writePaginationError(w, err)
+		return
+	}
+
+	response, err := h.paymentService.GetRefundsByUser(r.Context(), userID, page)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get refunds by user")
+		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
 // GetRefund handles GET /api/v1/refunds/{refundID}
 func (h *PaymentHandler) GetRefund(w http.ResponseWriter, r *http.Request) {
 	refundIDStr := chi.URLParam(r, "refundID")
@@ -297,3 +360,89 @@ func (h *PaymentHandler) RetryPayment(w http.ResponseWriter, r *http.Request) {
 
 	utils.WriteJSON(w, http.StatusOK, response)
 }
+
+// defaultStatsWindow is the reporting range used when no from date is given
+const defaultStatsWindow = 30 * 24 * time.Hour
+
+// GetPaymentStats handles GET /api/v1/payments/stats?from=&to=&group_by=gateway
+func (h *PaymentHandler) GetPaymentStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	to := time.Now()
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, dateOnly, err := parseStatsTime(toStr)
+		if err != nil {
+			errors.WriteAPIError(w, errors.NewValidationError("to", "Invalid date, expected YYYY-MM-DD or RFC3339"))
+			return
+		}
+		// A bare date includes the whole day
+		if dateOnly {
+			parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultStatsWindow)
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, _, err := parseStatsTime(fromStr)
+		if err != nil {
+			errors.WriteAPIError(w, errors.NewValidationError("from", "Invalid date, expected YYYY-MM-DD or RFC3339"))
+			return
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		errors.WriteAPIError(w, errors.NewValidationError("from", "From must not be after to"))
+		return
+	}
+
+	groupBy := query.Get("group_by")
+	if groupBy != "" && groupBy != "gateway" {
+		errors.WriteAPIError(w, errors.NewValidationError("group_by", "Unsupported group_by, expected gateway"))
+		return
+	}
+
+	stats, err := h.paymentService.GetPaymentStats(r.Context(), from, to)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get payment stats")
+		errors.WriteAPIError(w, err)
+		return
+	}
+
+	response := &model.PaymentStatsResponse{
+		From:         from.Format(time.RFC3339),
+		To:           to.Format(time.RFC3339),
+		PaymentStats: *stats,
+	}
+
+	if groupBy == "gateway" {
+		response.ByGateway, err = h.paymentService.GetPaymentStatsByGateway(r.Context(), from, to)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get payment stats by gateway")
+			errors.WriteAPIError(w, err)
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// parseStatsTime accepts either a YYYY-MM-DD date (interpreted in UTC) or an RFC3339 timestamp
+func parseStatsTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// writePaginationError reports an invalid limit or offset as a validation error
+func writePaginationError(w http.ResponseWriter, err error) {
+	field := "pagination"
+	if paramErr, ok := err.(*pagination.ParamError); ok {
+		field = paramErr.Param
+	}
+	errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError(field, err.Error())))
+}
@@ -4,11 +4,16 @@ import (
 	"time"
 
 	"payment-service/internal/config"
+	"payment-service/internal/metrics"
 	"payment-service/internal/service"
+	"payment-service/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"salon-shared/errors"
+	"salon-shared/requestbody"
 )
 
 // NewServer creates a new HTTP server with all routes configured
@@ -20,7 +25,13 @@ func NewServer(paymentService service.PaymentService, cfg *config.Config) *chi.M
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(metrics.Middleware)
+	r.Use(tracing.Middleware)
 	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(requestbody.Middleware(requestbody.Config{
+		MaxBytes:   int64(cfg.MaxBodyBytes),
+		WriteError: errors.WriteError,
+	}))
 
 	// CORS configuration
 	r.Use(cors.Handler(cors.Options{
@@ -32,10 +43,14 @@ func NewServer(paymentService service.PaymentService, cfg *config.Config) *chi.M
 		MaxAge:           300,
 	}))
 
+	// Prometheus metrics endpoint
+	r.Handle("/metrics", promhttp.Handler())
+
 	// Initialize handlers
 	paymentHandler := NewPaymentHandler(paymentService)
 	webhookHandler := NewWebhookHandler(paymentService)
 	healthHandler := NewHealthHandler(paymentService)
+	authenticator := NewAuthenticator(cfg.JWTAccessSecret, cfg.ServiceToken)
 
 	// Routes
 	r.Route("/api/v1", func(r chi.Router) {
@@ -47,8 +62,11 @@ func NewServer(paymentService service.PaymentService, cfg *config.Config) *chi.M
 		r.Route("/payments", func(r chi.Router) {
 			r.Post("/initiate", paymentHandler.InitiatePayment)
 			r.Post("/confirm", paymentHandler.ConfirmPayment)
+			r.Get("/stats", paymentHandler.GetPaymentStats) // Admin reporting
 			r.Get("/{paymentID}", paymentHandler.GetPayment)
+			r.Get("/{paymentID}/events", paymentHandler.GetPaymentEvents) // Support audit trail
 			r.Post("/{paymentID}/retry", paymentHandler.RetryPayment)
+			r.With(authenticator.RequireStaffOrService).Post("/{paymentID}/reconcile", paymentHandler.ReconcilePayment)
 			
 			// Refund endpoints
 			r.Post("/{paymentID}/refund", paymentHandler.RefundPayment)
@@ -63,15 +81,19 @@ func NewServer(paymentService service.PaymentService, cfg *config.Config) *chi.M
 		// User-specific endpoints
 		r.Route("/users", func(r chi.Router) {
 			r.Get("/{userID}/payments", paymentHandler.GetPaymentsByUser)
+			r.Get("/{userID}/refunds", paymentHandler.GetRefundsByUser)
 		})
 
 		// Refund endpoints
 		r.Route("/refunds", func(r chi.Router) {
 			r.Get("/{refundID}", paymentHandler.GetRefund)
 		})
+
+		// Gateway webhooks (raw body is required for signature verification)
+		r.Post("/webhooks/{gateway}", webhookHandler.GatewayWebhook)
 	})
 
-	// Webhook endpoints (separate from API versioning)
+	// Legacy webhook endpoints (kept for gateways already configured with these URLs)
 	r.Route("/webhooks", func(r chi.Router) {
 		r.Post("/stripe", webhookHandler.StripeWebhook)
 		r.Post("/razorpay", webhookHandler.RazorpayWebhook)
@@ -1,16 +1,34 @@
 package api
 
 import (
+	stderrors "errors"
 	"io"
 	"net/http"
 
+	"payment-service/internal/model"
 	"payment-service/internal/service"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 	"salon-shared/errors"
 	"salon-shared/utils"
 )
 
+// maxWebhookPayloadBytes bounds the webhook body read into memory
+const maxWebhookPayloadBytes = 1 << 20
+
+// webhookSignatureHeaders maps each gateway to the header carrying its webhook signature
+var webhookSignatureHeaders = map[string]string{
+	model.GatewayStripe:   "Stripe-Signature",
+	model.GatewayRazorpay: "X-Razorpay-Signature",
+}
+
+// webhookDeliveryIDHeaders maps gateways whose signed payload has no event ID
+// to the header carrying it, used to drop redelivered events
+var webhookDeliveryIDHeaders = map[string]string{
+	model.GatewayRazorpay: "X-Razorpay-Event-Id",
+}
+
 // WebhookHandler handles webhook requests from payment gateways
 type WebhookHandler struct {
 	paymentService service.PaymentService
@@ -23,63 +41,71 @@ func NewWebhookHandler(paymentService service.PaymentService) *WebhookHandler {
 	}
 }
 
+// GatewayWebhook handles POST /api/v1/webhooks/{gateway}
+func (h *WebhookHandler) GatewayWebhook(w http.ResponseWriter, r *http.Request) {
+	h.handleWebhook(w, r, chi.URLParam(r, "gateway"))
+}
+
 // StripeWebhook handles POST /webhooks/stripe
 func (h *WebhookHandler) StripeWebhook(w http.ResponseWriter, r *http.Request) {
-	// Read the request body
-	payload, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to read Stripe webhook payload")
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("request_body", "Failed to read request body")))
-		return
-	}
+	h.handleWebhook(w, r, model.GatewayStripe)
+}
 
-	// Get Stripe signature from header
-	signature := r.Header.Get("Stripe-Signature")
-	if signature == "" {
-		log.Warn().Msg("Missing Stripe signature header")
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("signature", "Missing Stripe-Signature header")))
-		return
-	}
+// RazorpayWebhook handles POST /webhooks/razorpay
+func (h *WebhookHandler) RazorpayWebhook(w http.ResponseWriter, r *http.Request) {
+	h.handleWebhook(w, r, model.GatewayRazorpay)
+}
 
-	// Process webhook
-	err = h.paymentService.ProcessWebhook(r.Context(), "stripe", payload, signature)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to process Stripe webhook")
-		errors.WriteAPIError(w, errors.MapToAPIError(err))
+// handleWebhook verifies and processes a gateway webhook. The raw body is
+// passed through untouched because signatures are computed over the exact
+// bytes sent. Only signature failures and events older than the webhook
+// tolerance are rejected; processing errors are logged and acknowledged with
+// 200 so gateways do not retry in a storm.
+func (h *WebhookHandler) handleWebhook(w http.ResponseWriter, r *http.Request, gatewayName string) {
+	signatureHeader, ok := webhookSignatureHeaders[gatewayName]
+	if !ok {
+		log.Warn().Str("gateway", gatewayName).Msg("Webhook received for unknown gateway")
+		errors.WriteAPIError(w, errors.NewNotFoundError("gateway", gatewayName))
 		return
 	}
 
-	// Return success response
-	utils.WriteJSON(w, http.StatusOK, map[string]string{
-		"status": "success",
-	})
-
-	log.Info().Msg("Stripe webhook processed successfully")
-}
-
-// RazorpayWebhook handles POST /webhooks/razorpay
-func (h *WebhookHandler) RazorpayWebhook(w http.ResponseWriter, r *http.Request) {
-	// Read the request body
-	payload, err := io.ReadAll(r.Body)
+	// Read the raw request body before any decoding
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayloadBytes))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read Razorpay webhook payload")
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("request_body", "Failed to read request body")))
+		log.Error().Err(err).Str("gateway", gatewayName).Msg("Failed to read webhook payload")
+		errors.WriteAPIError(w, errors.NewValidationError("request_body", "Failed to read request body"))
 		return
 	}
 
-	// Get Razorpay signature from header
-	signature := r.Header.Get("X-Razorpay-Signature")
+	signature := r.Header.Get(signatureHeader)
 	if signature == "" {
-		log.Warn().Msg("Missing Razorpay signature header")
-		errors.WriteAPIError(w, errors.MapToAPIError(errors.NewValidationError("signature", "Missing X-Razorpay-Signature header")))
+		log.Warn().Str("gateway", gatewayName).Msg("Missing webhook signature header")
+		errors.WriteAPIError(w, errors.NewValidationError("signature", "Missing "+signatureHeader+" header"))
 		return
 	}
 
-	// Process webhook
-	err = h.paymentService.ProcessWebhook(r.Context(), "razorpay", payload, signature)
+	var deliveryID string
+	if header, ok := webhookDeliveryIDHeaders[gatewayName]; ok {
+		deliveryID = r.Header.Get(header)
+	}
+
+	err = h.paymentService.ProcessWebhook(r.Context(), gatewayName, payload, signature, deliveryID)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to process Razorpay webhook")
-		errors.WriteAPIError(w, errors.MapToAPIError(err))
+		if stderrors.Is(err, service.ErrInvalidWebhookSignature) {
+			log.Warn().Err(err).Str("gateway", gatewayName).Msg("Webhook signature verification failed")
+			errors.WriteAPIError(w, errors.NewValidationError("signature", "Invalid webhook signature"))
+			return
+		}
+		if stderrors.Is(err, service.ErrWebhookExpired) {
+			log.Warn().Err(err).Str("gateway", gatewayName).Msg("Rejected stale webhook")
+			errors.WriteAPIError(w, errors.NewValidationError("timestamp", "Webhook is older than the allowed tolerance"))
+			return
+		}
+
+		log.Error().Err(err).Str("gateway", gatewayName).Msg("Failed to process webhook, acknowledging to avoid retries")
+		utils.WriteJSON(w, http.StatusOK, map[string]string{
+			"status": "ignored",
+		})
 		return
 	}
 
@@ -88,5 +114,5 @@ func (h *WebhookHandler) RazorpayWebhook(w http.ResponseWriter, r *http.Request)
 		"status": "success",
 	})
 
-	log.Info().Msg("Razorpay webhook processed successfully")
+	log.Info().Str("gateway", gatewayName).Msg("Webhook processed successfully")
 }
@@ -6,20 +6,29 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
 )
 
+// PoolConfig holds the connection pool limits applied to the database handle
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+}
+
 // NewConnection creates a new database connection
-func NewConnection(databaseURL string) (*sql.DB, error) {
+func NewConnection(databaseURL string, pool PoolConfig) (*sql.DB, error) {
 	db, err := sql.Open("postgres", databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-	db.SetConnMaxIdleTime(1 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
@@ -27,5 +36,11 @@ func NewConnection(databaseURL string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	log.Info().
+		Int("max_open_conns", pool.MaxOpenConns).
+		Int("max_idle_conns", pool.MaxIdleConns).
+		Dur("conn_max_idle_time", pool.ConnMaxIdleTime).
+		Dur("conn_max_lifetime", pool.ConnMaxLifetime).
+		Msg("Database connection pool configured")
 	return db, nil
 }
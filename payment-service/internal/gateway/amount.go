@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"math"
+	"strings"
+)
+
+// defaultCurrencyExponent is the number of decimal places of currencies not
+// listed in currencyExponents
+const defaultCurrencyExponent = 2
+
+// currencyExponents lists currencies whose minor unit is not a hundredth of the
+// major unit: zero-decimal currencies are sent to gateways as whole units and
+// three-decimal currencies in thousandths
+var currencyExponents = map[string]int{
+	"BIF": 0,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"MGA": 0,
+	"PYG": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+
+	"BHD": 3,
+	"IQD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// currencyExponent returns the number of decimal places of the currency
+func currencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return exponent
+	}
+	return defaultCurrencyExponent
+}
+
+// currencyFactor returns the number of minor units in one major unit of the currency
+func currencyFactor(currency string) float64 {
+	return math.Pow10(currencyExponent(currency))
+}
+
+// toMinorUnits converts an amount to the smallest currency unit, rounding to
+// the nearest unit so values like 19.99 do not truncate to 1998 and 1000.4 JPY
+// becomes 1000
+func toMinorUnits(amount float64, currency string) int64 {
+	return int64(math.Round(amount * currencyFactor(currency)))
+}
+
+// fromMinorUnits converts an amount in the smallest currency unit back to the major unit
+func fromMinorUnits(amount int64, currency string) float64 {
+	return float64(amount) / currencyFactor(currency)
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import "testing"
+
+func TestToMinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		want     int64
+	}{
+		{name: "INR", amount: 1180.5, currency: "INR", want: 118050},
+		{name: "INR that truncates to one paisa less", amount: 19.99, currency: "INR", want: 1999},
+		{name: "USD", amount: 0.29, currency: "USD", want: 29},
+		{name: "USD rounds half up", amount: 10.125, currency: "USD", want: 1013},
+		{name: "lower case currency", amount: 12.34, currency: "usd", want: 1234},
+		{name: "unknown currency uses two decimals", amount: 5.55, currency: "XYZ", want: 555},
+		{name: "JPY has no minor unit", amount: 1500, currency: "JPY", want: 1500},
+		{name: "JPY rounds down to a whole yen", amount: 1000.4, currency: "JPY", want: 1000},
+		{name: "JPY rounds up to a whole yen", amount: 1000.5, currency: "JPY", want: 1001},
+		{name: "KWD has three decimals", amount: 12.345, currency: "KWD", want: 12345},
+		{name: "KWD rounds to a fils", amount: 1.0006, currency: "KWD", want: 1001},
+		{name: "BHD padded from two decimals", amount: 7.5, currency: " bhd ", want: 7500},
+		{name: "zero", amount: 0, currency: "INR", want: 0},
+		{name: "refund", amount: -2.5, currency: "USD", want: -250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toMinorUnits(tt.amount, tt.currency); got != tt.want {
+				t.Errorf("toMinorUnits(%v, %q) = %d, want %d", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMinorUnits(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     float64
+	}{
+		{amount: 118050, currency: "INR", want: 1180.5},
+		{amount: 29, currency: "USD", want: 0.29},
+		{amount: 1500, currency: "JPY", want: 1500},
+		{amount: 12345, currency: "KWD", want: 12.345},
+		{amount: 1, currency: "OMR", want: 0.001},
+	}
+
+	for _, tt := range tests {
+		if got := fromMinorUnits(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("fromMinorUnits(%d, %q) = %v, want %v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMinorUnitsRoundTrip(t *testing.T) {
+	for _, currency := range []string{"INR", "USD", "JPY", "KWD"} {
+		for minor := int64(0); minor <= 200000; minor++ {
+			if got := toMinorUnits(fromMinorUnits(minor, currency), currency); got != minor {
+				t.Fatalf("%s: toMinorUnits(fromMinorUnits(%d)) = %d", currency, minor, got)
+			}
+		}
+	}
+}
@@ -2,10 +2,19 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"payment-service/internal/model"
 )
 
+// ErrInvalidSignature is returned by VerifyWebhook when the payload signature does not match
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrWebhookExpired is returned by VerifyWebhook when a correctly signed event
+// is older than the webhook tolerance, so captured webhooks cannot be replayed
+var ErrWebhookExpired = errors.New("webhook timestamp outside tolerance")
+
 // PaymentGateway defines the interface for payment gateway implementations
 type PaymentGateway interface {
 	// GetName returns the gateway name
@@ -25,19 +34,28 @@ type PaymentGateway interface {
 
 	// GetPaymentStatus retrieves current payment status from gateway
 	GetPaymentStatus(ctx context.Context, gatewayPaymentID string) (*PaymentResponse, error)
+
+	// Ping makes a lightweight authenticated request, failing when the gateway
+	// is unreachable or rejects the configured credentials
+	Ping(ctx context.Context) error
 }
 
-// PaymentRequest represents a payment initiation request
+// PaymentRequest represents a payment initiation request. IdempotencyKey
+// identifies one initiation attempt; Resume is set when an earlier call with
+// the same key may already have created the order, which is then returned
+// instead of a new one.
 type PaymentRequest struct {
-	Amount        float64                `json:"amount"`
-	Currency      string                 `json:"currency"`
-	OrderID       string                 `json:"order_id"`
-	CustomerID    string                 `json:"customer_id"`
-	CustomerEmail string                 `json:"customer_email"`
-	CustomerPhone string                 `json:"customer_phone"`
-	Description   string                 `json:"description"`
-	CallbackURL   string                 `json:"callback_url"`
-	Metadata      map[string]interface{} `json:"metadata"`
+	Amount         float64                `json:"amount"`
+	Currency       string                 `json:"currency"`
+	OrderID        string                 `json:"order_id"`
+	CustomerID     string                 `json:"customer_id"`
+	CustomerEmail  string                 `json:"customer_email"`
+	CustomerPhone  string                 `json:"customer_phone"`
+	Description    string                 `json:"description"`
+	CallbackURL    string                 `json:"callback_url"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	IdempotencyKey string                 `json:"idempotency_key"`
+	Resume         bool                   `json:"resume"`
 }
 
 // PaymentResponse represents a payment response from gateway
@@ -58,6 +76,7 @@ type PaymentResponse struct {
 type RefundRequest struct {
 	GatewayPaymentID string                 `json:"gateway_payment_id"`
 	Amount           float64                `json:"amount"`
+	Currency         string                 `json:"currency"`
 	Reason           string                 `json:"reason"`
 	Metadata         map[string]interface{} `json:"metadata"`
 }
@@ -73,10 +92,14 @@ type RefundResponse struct {
 	ErrorMessage     string                 `json:"error_message"`
 }
 
-// WebhookEvent represents a webhook event from gateway
+// WebhookEvent represents a webhook event from gateway. EventID is the
+// gateway's event identifier when it is part of the signed payload.
 type WebhookEvent struct {
+	EventID          string                 `json:"event_id"`
 	EventType        string                 `json:"event_type"`
 	GatewayPaymentID string                 `json:"gateway_payment_id"`
+	GatewayOrderID   string                 `json:"gateway_order_id"`
+	GatewayRefundID  string                 `json:"gateway_refund_id"`
 	Status           string                 `json:"status"`
 	Amount           float64                `json:"amount"`
 	Currency         string                 `json:"currency"`
@@ -90,6 +113,21 @@ type GatewayManager interface {
 	GetGateway(name string) (PaymentGateway, error)
 	GetAvailableGateways() []string
 	SelectBestGateway(amount float64, currency string) (PaymentGateway, error)
+
+	// SupportedCurrencies returns the currencies accepted by at least one configured gateway
+	SupportedCurrencies() []string
+
+	// CheckCurrency returns a validation error when the named gateway does not accept the currency
+	CheckCurrency(name, currency string) error
+
+	// ReportFailure and ReportSuccess feed gateway call outcomes into routing so a
+	// failing gateway is skipped until its cooldown expires
+	ReportFailure(name string)
+	ReportSuccess(name string)
+
+	// PingAll pings every configured gateway concurrently, each bounded by
+	// timeout, and returns the error of each by gateway name
+	PingAll(ctx context.Context, timeout time.Duration) map[string]error
 }
 
 // Status mapping constants
@@ -104,6 +142,12 @@ const (
 
 // Gateway-specific status mapping
 func MapGatewayStatus(gateway, status string) string {
+	// Statuses already normalized by the gateway implementation pass through unchanged
+	switch status {
+	case StatusPending, StatusInitiated, StatusSuccess, StatusFailed, StatusCanceled, StatusRefunded:
+		return status
+	}
+
 	switch gateway {
 	case model.GatewayStripe:
 		return mapStripeStatus(status)
@@ -118,7 +162,7 @@ func mapStripeStatus(status string) string {
 	switch status {
 	case "requires_payment_method", "requires_confirmation":
 		return StatusPending
-	case "processing":
+	case "processing", "requires_capture":
 		return StatusInitiated
 	case "succeeded":
 		return StatusSuccess
@@ -135,7 +179,7 @@ func mapRazorpayStatus(status string) string {
 	switch status {
 	case "created":
 		return StatusPending
-	case "authorized", "captured":
+	case "authorized", "captured", "processed":
 		return StatusSuccess
 	case "failed":
 		return StatusFailed
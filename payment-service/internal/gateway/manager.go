@@ -1,39 +1,97 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"payment-service/internal/config"
-	"payment-service/internal/model"
+
+	"github.com/rs/zerolog/log"
+	"salon-shared/errors"
 )
 
 // gatewayManager implements GatewayManager interface
 type gatewayManager struct {
-	gateways map[string]PaymentGateway
-	config   *config.Config
+	gateways   map[string]PaymentGateway
+	currencies map[string]map[string]bool
+	preference []string
+	cooldown   time.Duration
+	config     *config.Config
+
+	mu             sync.RWMutex
+	unhealthyUntil map[string]time.Time
 }
 
 // NewGatewayManager creates a new gateway manager
 func NewGatewayManager(cfg *config.Config) GatewayManager {
 	manager := &gatewayManager{
-		gateways: make(map[string]PaymentGateway),
-		config:   cfg,
+		gateways:       make(map[string]PaymentGateway),
+		currencies:     make(map[string]map[string]bool),
+		cooldown:       time.Duration(cfg.GatewayFailureCooldownSeconds) * time.Second,
+		config:         cfg,
+		unhealthyUntil: make(map[string]time.Time),
 	}
 
 	// Initialize available gateways based on configuration
 	if cfg.StripeSecretKey != "" {
-		stripeGateway := NewStripeGateway(cfg.StripeSecretKey, cfg.StripeWebhookSecret)
-		manager.gateways[model.GatewayStripe] = stripeGateway
+		if cfg.StripeWebhookSecret == "" {
+			log.Warn().Msg("Stripe webhook secret not configured, Stripe webhooks will be rejected")
+		}
+		stripeGateway := NewStripeGateway(cfg.StripeSecretKey, cfg.StripeWebhookSecret, cfg.WebhookTolerance())
+		manager.register(stripeGateway, cfg.StripeCurrencies)
 	}
 
 	if cfg.RazorpayKeyID != "" && cfg.RazorpayKeySecret != "" {
-		razorpayGateway := NewRazorpayGateway(cfg.RazorpayKeyID, cfg.RazorpayKeySecret, cfg.RazorpayWebhookSecret)
-		manager.gateways[model.GatewayRazorpay] = razorpayGateway
+		razorpayGateway := NewRazorpayGateway(cfg.RazorpayKeyID, cfg.RazorpayKeySecret, cfg.RazorpayWebhookSecret, cfg.WebhookTolerance())
+		manager.register(razorpayGateway, cfg.RazorpayCurrencies)
 	}
 
+	manager.preference = routingOrder(cfg.GatewayPreference, manager.gateways)
+
 	return manager
 }
 
+// register adds a gateway along with the currencies it accepts
+func (m *gatewayManager) register(gateway PaymentGateway, currencies []string) {
+	supported := make(map[string]bool, len(currencies))
+	for _, currency := range currencies {
+		supported[strings.ToUpper(currency)] = true
+	}
+
+	m.gateways[gateway.GetName()] = gateway
+	m.currencies[gateway.GetName()] = supported
+}
+
+// routingOrder returns the configured gateways in preference order. Gateways
+// missing from the preference list are appended in name order so they are
+// still used as a last resort.
+func routingOrder(preference []string, gateways map[string]PaymentGateway) []string {
+	var order []string
+	seen := make(map[string]bool)
+
+	for _, name := range preference {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, exists := gateways[name]; exists && !seen[name] {
+			order = append(order, name)
+			seen[name] = true
+		}
+	}
+
+	var rest []string
+	for name := range gateways {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(order, rest...)
+}
+
 // GetGateway returns a specific payment gateway by name
 func (m *gatewayManager) GetGateway(name string) (PaymentGateway, error) {
 	gateway, exists := m.gateways[name]
@@ -43,37 +101,133 @@ func (m *gatewayManager) GetGateway(name string) (PaymentGateway, error) {
 	return gateway, nil
 }
 
-// GetAvailableGateways returns list of available gateway names
+// GetAvailableGateways returns list of available gateway names in routing order
 func (m *gatewayManager) GetAvailableGateways() []string {
-	var gateways []string
-	for name := range m.gateways {
-		gateways = append(gateways, name)
-	}
-	return gateways
+	return append([]string(nil), m.preference...)
 }
 
-// SelectBestGateway selects the best gateway based on amount and currency
+// SelectBestGateway picks the first gateway in preference order that supports
+// the currency and is not cooling down after a failure. When every supporting
+// gateway is unhealthy the most preferred one is returned anyway, since a
+// failed attempt is better than refusing the payment outright.
 func (m *gatewayManager) SelectBestGateway(amount float64, currency string) (PaymentGateway, error) {
-	// Gateway selection logic based on business rules
-	
-	// For INR currency, prefer Razorpay
-	if currency == "INR" {
-		if gateway, exists := m.gateways[model.GatewayRazorpay]; exists {
-			return gateway, nil
+	if len(m.gateways) == 0 {
+		return nil, fmt.Errorf("no payment gateways available")
+	}
+
+	currency = strings.ToUpper(currency)
+
+	var candidates []string
+	for _, name := range m.preference {
+		if m.currencies[name][currency] {
+			candidates = append(candidates, name)
 		}
 	}
 
-	// For international currencies, prefer Stripe
-	if currency != "INR" {
-		if gateway, exists := m.gateways[model.GatewayStripe]; exists {
-			return gateway, nil
+	if len(candidates) == 0 {
+		return nil, errors.NewValidationError("currency", fmt.Sprintf(
+			"currency %s is not supported; supported currencies: %s",
+			currency, strings.Join(m.SupportedCurrencies(), ", ")))
+	}
+
+	for _, name := range candidates {
+		if m.isHealthy(name) {
+			return m.gateways[name], nil
 		}
 	}
 
-	// Fallback to any available gateway
-	for _, gateway := range m.gateways {
-		return gateway, nil
+	log.Warn().
+		Str("currency", currency).
+		Strs("gateways", candidates).
+		Msg("All gateways for currency are unhealthy, using preferred gateway")
+
+	return m.gateways[candidates[0]], nil
+}
+
+// SupportedCurrencies returns the sorted union of currencies across configured gateways
+func (m *gatewayManager) SupportedCurrencies() []string {
+	seen := make(map[string]bool)
+	var currencies []string
+	for _, supported := range m.currencies {
+		for currency := range supported {
+			if !seen[currency] {
+				seen[currency] = true
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// CheckCurrency returns a validation error listing the gateway's supported
+// currencies when it does not accept currency
+func (m *gatewayManager) CheckCurrency(name, currency string) error {
+	supported := m.currencies[name]
+	if supported[strings.ToUpper(currency)] {
+		return nil
+	}
+
+	currencies := make([]string, 0, len(supported))
+	for code := range supported {
+		currencies = append(currencies, code)
+	}
+	sort.Strings(currencies)
+
+	return errors.NewValidationError("currency", fmt.Sprintf(
+		"currency %s is not supported by %s; supported currencies: %s",
+		strings.ToUpper(currency), name, strings.Join(currencies, ", ")))
+}
+
+// ReportFailure marks a gateway as unhealthy for the configured cooldown
+func (m *gatewayManager) ReportFailure(name string) {
+	if m.cooldown <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	m.unhealthyUntil[name] = time.Now().Add(m.cooldown)
+	m.mu.Unlock()
+
+	log.Warn().Str("gateway", name).Dur("cooldown", m.cooldown).Msg("Payment gateway marked unhealthy")
+}
+
+// ReportSuccess clears any unhealthy mark on a gateway
+func (m *gatewayManager) ReportSuccess(name string) {
+	m.mu.Lock()
+	delete(m.unhealthyUntil, name)
+	m.mu.Unlock()
+}
+
+// PingAll pings every configured gateway concurrently
+func (m *gatewayManager) PingAll(ctx context.Context, timeout time.Duration) map[string]error {
+	type result struct {
+		name string
+		err  error
 	}
 
-	return nil, fmt.Errorf("no payment gateways available")
+	results := make(chan result, len(m.gateways))
+	for name, gateway := range m.gateways {
+		go func(name string, gateway PaymentGateway) {
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results <- result{name: name, err: gateway.Ping(pingCtx)}
+		}(name, gateway)
+	}
+
+	errs := make(map[string]error, len(m.gateways))
+	for range m.gateways {
+		r := <-results
+		errs[r.name] = r.err
+	}
+	return errs
+}
+
+// isHealthy reports whether the gateway is outside its failure cooldown
+func (m *gatewayManager) isHealthy(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	until, marked := m.unhealthyUntil[name]
+	return !marked || time.Now().After(until)
 }
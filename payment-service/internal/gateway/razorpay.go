@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"payment-service/internal/model"
@@ -19,15 +20,17 @@ type RazorpayGateway struct {
 	client        *razorpay.Client
 	keyID         string
 	webhookSecret string
+	tolerance     time.Duration
 }
 
 // NewRazorpayGateway creates a new Razorpay gateway instance
-func NewRazorpayGateway(keyID, keySecret, webhookSecret string) *RazorpayGateway {
+func NewRazorpayGateway(keyID, keySecret, webhookSecret string, tolerance time.Duration) *RazorpayGateway {
 	client := razorpay.NewClient(keyID, keySecret)
 	return &RazorpayGateway{
 		client:        client,
 		keyID:         keyID,
 		webhookSecret: webhookSecret,
+		tolerance:     tolerance,
 	}
 }
 
@@ -39,12 +42,28 @@ func (r *RazorpayGateway) GetName() string {
 // InitiatePayment creates a Razorpay Order
 func (r *RazorpayGateway) InitiatePayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
 	// Convert amount to paise (Razorpay uses smallest currency unit)
-	amountPaise := int(request.Amount * 100)
+	amountPaise := toMinorUnits(request.Amount, request.Currency)
+
+	// Razorpay has no idempotency keys, so the key is stored as the order
+	// receipt and a resumed initiation looks the order up by it
+	receipt := request.OrderID
+	if request.IdempotencyKey != "" {
+		receipt = request.IdempotencyKey
+	}
+	if request.Resume {
+		orderID, err := r.findOrderByReceipt(receipt)
+		if err != nil {
+			return nil, err
+		}
+		if orderID != "" {
+			return r.orderResponse(orderID, request), nil
+		}
+	}
 
 	data := map[string]interface{}{
 		"amount":   amountPaise,
 		"currency": request.Currency,
-		"receipt":  request.OrderID,
+		"receipt":  receipt,
 	}
 
 	// Add notes (metadata)
@@ -70,7 +89,12 @@ func (r *RazorpayGateway) InitiatePayment(ctx context.Context, request *PaymentR
 		return nil, fmt.Errorf("invalid order ID from Razorpay")
 	}
 
-	response := &PaymentResponse{
+	return r.orderResponse(orderID, request), nil
+}
+
+// orderResponse describes a created order to the client
+func (r *RazorpayGateway) orderResponse(orderID string, request *PaymentRequest) *PaymentResponse {
+	return &PaymentResponse{
 		GatewayPaymentID: orderID,
 		GatewayOrderID:   orderID,
 		Status:           StatusPending,
@@ -82,8 +106,27 @@ func (r *RazorpayGateway) InitiatePayment(ctx context.Context, request *PaymentR
 			"key_id":           r.keyID,
 		},
 	}
+}
 
-	return response, nil
+// findOrderByReceipt returns the ID of the order created with receipt, or ""
+// when there is none
+func (r *RazorpayGateway) findOrderByReceipt(receipt string) (string, error) {
+	orders, err := r.client.Order.All(map[string]interface{}{"receipt": receipt}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up Razorpay order: %w", err)
+	}
+
+	items, _ := orders["items"].([]interface{})
+	for _, item := range items {
+		order, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if orderID, ok := order["id"].(string); ok {
+			return orderID, nil
+		}
+	}
+	return "", nil
 }
 
 // ConfirmPayment confirms a Razorpay payment
@@ -115,7 +158,7 @@ func (r *RazorpayGateway) ConfirmPayment(ctx context.Context, gatewayPaymentID s
 		GatewayPaymentID: gatewayPaymentID,
 		GatewayOrderID:   orderID,
 		Status:           MapGatewayStatus(model.GatewayRazorpay, status),
-		Amount:           amount / 100, // Convert from paise
+		Amount:           fromMinorUnits(int64(amount), currency),
 		Currency:         currency,
 		Metadata: map[string]interface{}{
 			"razorpay_payment_id": gatewayPaymentID,
@@ -142,7 +185,7 @@ func (r *RazorpayGateway) ConfirmPayment(ctx context.Context, gatewayPaymentID s
 // RefundPayment processes a refund through Razorpay
 func (r *RazorpayGateway) RefundPayment(ctx context.Context, request *RefundRequest) (*RefundResponse, error) {
 	// Convert amount to paise
-	amountPaise := int(request.Amount * 100)
+	amountPaise := int(toMinorUnits(request.Amount, request.Currency))
 
 	data := map[string]interface{}{
 		"amount": amountPaise,
@@ -187,7 +230,7 @@ func (r *RazorpayGateway) RefundPayment(ctx context.Context, request *RefundRequ
 	response := &RefundResponse{
 		GatewayRefundID: refundID,
 		Status:          MapGatewayStatus(model.GatewayRazorpay, status),
-		Amount:          amount / 100, // Convert from paise
+		Amount:          fromMinorUnits(int64(amount), currency),
 		Currency:        currency,
 		Metadata: map[string]interface{}{
 			"razorpay_refund_id": refundID,
@@ -205,11 +248,14 @@ func (r *RazorpayGateway) RefundPayment(ctx context.Context, request *RefundRequ
 	return response, nil
 }
 
-// VerifyWebhook verifies Razorpay webhook signature and returns event data
+// VerifyWebhook verifies Razorpay webhook signature and returns event data.
+// Razorpay signs only the body and sends no timestamp header, so the event's
+// created_at, which is covered by the signature, is checked against the
+// tolerance instead and older events are rejected with ErrWebhookExpired.
 func (r *RazorpayGateway) VerifyWebhook(ctx context.Context, payload []byte, signature string) (*WebhookEvent, error) {
 	// Verify webhook signature
 	if !r.verifyWebhookSignature(payload, signature) {
-		return nil, fmt.Errorf("invalid Razorpay webhook signature")
+		return nil, fmt.Errorf("%w: Razorpay signature mismatch", ErrInvalidSignature)
 	}
 
 	// Parse webhook payload
@@ -218,6 +264,14 @@ func (r *RazorpayGateway) VerifyWebhook(ctx context.Context, payload []byte, sig
 		return nil, fmt.Errorf("failed to parse Razorpay webhook payload: %w", err)
 	}
 
+	createdAt, ok := webhookData["created_at"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: Razorpay webhook has no created_at", ErrWebhookExpired)
+	}
+	if age := time.Since(time.Unix(int64(createdAt), 0)); age > r.tolerance || age < -r.tolerance {
+		return nil, fmt.Errorf("%w: Razorpay event created %s ago", ErrWebhookExpired, age.Round(time.Second))
+	}
+
 	eventType, ok := webhookData["event"].(string)
 	if !ok {
 		return nil, fmt.Errorf("invalid event type in Razorpay webhook")
@@ -245,18 +299,22 @@ func (r *RazorpayGateway) VerifyWebhook(ctx context.Context, payload []byte, sig
 			webhookEvent.GatewayPaymentID = paymentID
 		}
 
-		if status, ok := payment["status"].(string); ok {
-			webhookEvent.Status = MapGatewayStatus(model.GatewayRazorpay, status)
+		if orderID, ok := payment["order_id"].(string); ok {
+			webhookEvent.GatewayOrderID = orderID
 		}
 
-		if amount, ok := payment["amount"].(float64); ok {
-			webhookEvent.Amount = amount / 100 // Convert from paise
+		if status, ok := payment["status"].(string); ok {
+			webhookEvent.Status = MapGatewayStatus(model.GatewayRazorpay, status)
 		}
 
 		if currency, ok := payment["currency"].(string); ok {
 			webhookEvent.Currency = currency
 		}
 
+		if amount, ok := payment["amount"].(float64); ok {
+			webhookEvent.Amount = fromMinorUnits(int64(amount), webhookEvent.Currency)
+		}
+
 		if method, ok := payment["method"].(string); ok {
 			webhookEvent.PaymentMethod = method
 		}
@@ -283,23 +341,31 @@ func (r *RazorpayGateway) VerifyWebhook(ctx context.Context, payload []byte, sig
 			return nil, fmt.Errorf("invalid refund entity in Razorpay webhook")
 		}
 
+		if refundID, ok := refund["id"].(string); ok {
+			webhookEvent.GatewayRefundID = refundID
+		}
+
 		if paymentID, ok := refund["payment_id"].(string); ok {
 			webhookEvent.GatewayPaymentID = paymentID
 		}
 
-		webhookEvent.Status = StatusRefunded
+		webhookEvent.Status = StatusSuccess
 		if eventType == "refund.failed" {
 			webhookEvent.Status = StatusFailed
 		}
 
-		if amount, ok := refund["amount"].(float64); ok {
-			webhookEvent.Amount = amount / 100 // Convert from paise
+		if createdAt, ok := refund["created_at"].(float64); ok {
+			webhookEvent.ProcessedAt = time.Unix(int64(createdAt), 0).Format(time.RFC3339)
 		}
 
 		if currency, ok := refund["currency"].(string); ok {
 			webhookEvent.Currency = currency
 		}
 
+		if amount, ok := refund["amount"].(float64); ok {
+			webhookEvent.Amount = fromMinorUnits(int64(amount), webhookEvent.Currency)
+		}
+
 	default:
 		return nil, fmt.Errorf("unsupported Razorpay webhook event type: %s", eventType)
 	}
@@ -307,9 +373,68 @@ func (r *RazorpayGateway) VerifyWebhook(ctx context.Context, payload []byte, sig
 	return webhookEvent, nil
 }
 
-// GetPaymentStatus retrieves current payment status from Razorpay
+// GetPaymentStatus retrieves current payment status from Razorpay. Payments are
+// stored against their order ID until confirmed; for an order the captured or
+// authorized payment is reported, or the order's pending state when it has none.
 func (r *RazorpayGateway) GetPaymentStatus(ctx context.Context, gatewayPaymentID string) (*PaymentResponse, error) {
-	return r.ConfirmPayment(ctx, gatewayPaymentID)
+	if !strings.HasPrefix(gatewayPaymentID, "order_") {
+		return r.ConfirmPayment(ctx, gatewayPaymentID)
+	}
+
+	order, err := r.client.Order.Fetch(gatewayPaymentID, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Razorpay order: %w", err)
+	}
+	payments, err := r.client.Order.Payments(gatewayPaymentID, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Razorpay order payments: %w", err)
+	}
+
+	items, _ := payments["items"].([]interface{})
+	for _, item := range items {
+		payment, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status, _ := payment["status"].(string)
+		paymentID, _ := payment["id"].(string)
+		if (status == "captured" || status == "authorized") && paymentID != "" {
+			return r.ConfirmPayment(ctx, paymentID)
+		}
+	}
+
+	amount, _ := order["amount"].(float64)
+	currency, _ := order["currency"].(string)
+	return &PaymentResponse{
+		GatewayPaymentID: gatewayPaymentID,
+		GatewayOrderID:   gatewayPaymentID,
+		Status:           StatusPending,
+		Amount:           fromMinorUnits(int64(amount), currency),
+		Currency:         currency,
+		Metadata: map[string]interface{}{
+			"razorpay_order_id": gatewayPaymentID,
+		},
+	}, nil
+}
+
+// Ping lists at most one order. The Razorpay client takes no context, so the
+// call runs in the background and is abandoned when ctx is done.
+func (r *RazorpayGateway) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.client.Order.All(map[string]interface{}{"count": 1}, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("razorpay ping failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("razorpay ping failed: %w", ctx.Err())
+	}
 }
 
 // verifyWebhookSignature verifies Razorpay webhook signature
@@ -3,12 +3,15 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"payment-service/internal/model"
 
 	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/balance"
 	"github.com/stripe/stripe-go/v76/paymentintent"
 	"github.com/stripe/stripe-go/v76/refund"
 	"github.com/stripe/stripe-go/v76/webhook"
@@ -16,16 +19,23 @@ import (
 
 // StripeGateway implements PaymentGateway for Stripe
 type StripeGateway struct {
-	secretKey     string
-	webhookSecret string
+	paymentIntents *paymentintent.Client
+	refunds        *refund.Client
+	balance        *balance.Client
+	webhookSecret  string
+	tolerance      time.Duration
 }
 
-// NewStripeGateway creates a new Stripe gateway instance
-func NewStripeGateway(secretKey, webhookSecret string) *StripeGateway {
-	stripe.Key = secretKey
+// NewStripeGateway creates a new Stripe gateway instance. The secret key is
+// scoped to this gateway rather than set on the global stripe.Key.
+func NewStripeGateway(secretKey, webhookSecret string, tolerance time.Duration) *StripeGateway {
+	backend := stripe.GetBackend(stripe.APIBackend)
 	return &StripeGateway{
-		secretKey:     secretKey,
-		webhookSecret: webhookSecret,
+		paymentIntents: &paymentintent.Client{B: backend, Key: secretKey},
+		refunds:        &refund.Client{B: backend, Key: secretKey},
+		balance:        &balance.Client{B: backend, Key: secretKey},
+		webhookSecret:  webhookSecret,
+		tolerance:      tolerance,
 	}
 }
 
@@ -36,14 +46,18 @@ func (s *StripeGateway) GetName() string {
 
 // InitiatePayment creates a Stripe PaymentIntent
 func (s *StripeGateway) InitiatePayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
-	// Convert amount to cents (Stripe uses smallest currency unit)
-	amountCents := int64(request.Amount * 100)
-
+	// Stripe expects the amount in the smallest currency unit
 	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(amountCents),
-		Currency: stripe.String(request.Currency),
+		Amount:   stripe.Int64(toMinorUnits(request.Amount, request.Currency)),
+		Currency: stripe.String(strings.ToLower(request.Currency)),
 		Metadata: make(map[string]string),
 	}
+	params.Context = ctx
+	// Stripe replays the original PaymentIntent for a repeated key, so resumed
+	// initiations need no lookup
+	if request.IdempotencyKey != "" {
+		params.SetIdempotencyKey(request.IdempotencyKey)
+	}
 
 	// Add customer information if provided
 	if request.CustomerEmail != "" {
@@ -73,7 +87,7 @@ func (s *StripeGateway) InitiatePayment(ctx context.Context, request *PaymentReq
 	}
 
 	// Create PaymentIntent
-	pi, err := paymentintent.New(params)
+	pi, err := s.paymentIntents.New(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Stripe PaymentIntent: %w", err)
 	}
@@ -96,7 +110,11 @@ func (s *StripeGateway) InitiatePayment(ctx context.Context, request *PaymentReq
 
 // ConfirmPayment confirms a Stripe PaymentIntent
 func (s *StripeGateway) ConfirmPayment(ctx context.Context, gatewayPaymentID string) (*PaymentResponse, error) {
-	pi, err := paymentintent.Get(gatewayPaymentID, nil)
+	params := &stripe.PaymentIntentParams{}
+	params.Context = ctx
+	params.AddExpand("payment_method")
+
+	pi, err := s.paymentIntents.Get(gatewayPaymentID, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Stripe PaymentIntent: %w", err)
 	}
@@ -105,8 +123,8 @@ func (s *StripeGateway) ConfirmPayment(ctx context.Context, gatewayPaymentID str
 		GatewayPaymentID: pi.ID,
 		GatewayOrderID:   pi.ID,
 		Status:           MapGatewayStatus(model.GatewayStripe, string(pi.Status)),
-		Amount:           float64(pi.Amount) / 100, // Convert from cents
-		Currency:         string(pi.Currency),
+		Amount:           fromMinorUnits(pi.Amount, string(pi.Currency)),
+		Currency:         strings.ToUpper(string(pi.Currency)),
 		Metadata: map[string]interface{}{
 			"stripe_payment_intent_id": pi.ID,
 		},
@@ -127,15 +145,13 @@ func (s *StripeGateway) ConfirmPayment(ctx context.Context, gatewayPaymentID str
 
 // RefundPayment processes a refund through Stripe
 func (s *StripeGateway) RefundPayment(ctx context.Context, request *RefundRequest) (*RefundResponse, error) {
-	// Convert amount to cents
-	amountCents := int64(request.Amount * 100)
-
 	params := &stripe.RefundParams{
 		PaymentIntent: stripe.String(request.GatewayPaymentID),
-		Amount:        stripe.Int64(amountCents),
+		Amount:        stripe.Int64(toMinorUnits(request.Amount, request.Currency)),
 		Reason:        stripe.String("requested_by_customer"),
 		Metadata:      make(map[string]string),
 	}
+	params.Context = ctx
 
 	// Add metadata
 	if request.Metadata != nil {
@@ -148,7 +164,7 @@ func (s *StripeGateway) RefundPayment(ctx context.Context, request *RefundReques
 	params.Metadata["reason"] = request.Reason
 
 	// Create refund
-	r, err := refund.New(params)
+	r, err := s.refunds.New(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Stripe refund: %w", err)
 	}
@@ -156,8 +172,8 @@ func (s *StripeGateway) RefundPayment(ctx context.Context, request *RefundReques
 	response := &RefundResponse{
 		GatewayRefundID: r.ID,
 		Status:          MapGatewayStatus(model.GatewayStripe, string(r.Status)),
-		Amount:          float64(r.Amount) / 100, // Convert from cents
-		Currency:        string(r.Currency),
+		Amount:          fromMinorUnits(r.Amount, string(r.Currency)),
+		Currency:        strings.ToUpper(string(r.Currency)),
 		Metadata: map[string]interface{}{
 			"stripe_refund_id": r.ID,
 		},
@@ -171,20 +187,35 @@ func (s *StripeGateway) RefundPayment(ctx context.Context, request *RefundReques
 	return response, nil
 }
 
-// VerifyWebhook verifies Stripe webhook signature and returns event data
+// VerifyWebhook verifies Stripe webhook signature and returns event data. The
+// Stripe-Signature header signs its timestamp along with the body, and events
+// signed longer ago than the tolerance are rejected with ErrWebhookExpired.
 func (s *StripeGateway) VerifyWebhook(ctx context.Context, payload []byte, signature string) (*WebhookEvent, error) {
-	event, err := webhook.ConstructEvent(payload, signature, s.webhookSecret)
+	if err := webhook.ValidatePayloadWithTolerance(payload, signature, s.webhookSecret, s.tolerance); err != nil {
+		if errors.Is(err, webhook.ErrTooOld) {
+			return nil, fmt.Errorf("%w: Stripe signature timestamp too old", ErrWebhookExpired)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	// The signature is already verified; events pinned to an older account API
+	// version are still accepted since only stable fields are read
+	event, err := webhook.ConstructEventWithOptions(payload, signature, s.webhookSecret, webhook.ConstructEventOptions{
+		Tolerance:                s.tolerance,
+		IgnoreAPIVersionMismatch: true,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify Stripe webhook: %w", err)
+		return nil, fmt.Errorf("failed to parse Stripe webhook: %w", err)
 	}
 
-	webhookEvent := &WebhookEvent{}
+	webhookEvent := &WebhookEvent{EventID: event.ID}
 	webhookEvent.EventType = string(event.Type)
 	webhookEvent.Metadata = make(map[string]interface{})
 
 	// Parse event data based on type
 	switch event.Type {
-	case "payment_intent.succeeded", "payment_intent.payment_failed", "payment_intent.canceled":
+	case "payment_intent.succeeded", "payment_intent.payment_failed", "payment_intent.canceled",
+		"payment_intent.processing", "payment_intent.requires_action":
 		var pi stripe.PaymentIntent
 		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 			return nil, fmt.Errorf("failed to parse PaymentIntent from webhook: %w", err)
@@ -192,8 +223,8 @@ func (s *StripeGateway) VerifyWebhook(ctx context.Context, payload []byte, signa
 
 		webhookEvent.GatewayPaymentID = pi.ID
 		webhookEvent.Status = MapGatewayStatus(model.GatewayStripe, string(pi.Status))
-		webhookEvent.Amount = float64(pi.Amount) / 100
-		webhookEvent.Currency = string(pi.Currency)
+		webhookEvent.Amount = fromMinorUnits(pi.Amount, string(pi.Currency))
+		webhookEvent.Currency = strings.ToUpper(string(pi.Currency))
 		webhookEvent.ProcessedAt = time.Unix(pi.Created, 0).Format(time.RFC3339)
 
 		if pi.PaymentMethod != nil {
@@ -205,6 +236,25 @@ func (s *StripeGateway) VerifyWebhook(ctx context.Context, payload []byte, signa
 			webhookEvent.Metadata[key] = value
 		}
 
+	case "charge.refund.updated":
+		var r stripe.Refund
+		if err := json.Unmarshal(event.Data.Raw, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse Refund from webhook: %w", err)
+		}
+
+		webhookEvent.GatewayRefundID = r.ID
+		if r.PaymentIntent != nil {
+			webhookEvent.GatewayPaymentID = r.PaymentIntent.ID
+		}
+		webhookEvent.Status = MapGatewayStatus(model.GatewayStripe, string(r.Status))
+		webhookEvent.Amount = fromMinorUnits(r.Amount, string(r.Currency))
+		webhookEvent.Currency = strings.ToUpper(string(r.Currency))
+		webhookEvent.ProcessedAt = time.Unix(r.Created, 0).Format(time.RFC3339)
+
+		for key, value := range r.Metadata {
+			webhookEvent.Metadata[key] = value
+		}
+
 	case "charge.dispute.created":
 		// Handle dispute events if needed
 		webhookEvent.EventType = "dispute_created"
@@ -220,3 +270,13 @@ func (s *StripeGateway) VerifyWebhook(ctx context.Context, payload []byte, signa
 func (s *StripeGateway) GetPaymentStatus(ctx context.Context, gatewayPaymentID string) (*PaymentResponse, error) {
 	return s.ConfirmPayment(ctx, gatewayPaymentID)
 }
+
+// Ping retrieves the account balance, the cheapest authenticated Stripe call
+func (s *StripeGateway) Ping(ctx context.Context) error {
+	params := &stripe.BalanceParams{}
+	params.Context = ctx
+	if _, err := s.balance.Get(params); err != nil {
+		return fmt.Errorf("stripe ping failed: %w", err)
+	}
+	return nil
+}
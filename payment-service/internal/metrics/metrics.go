@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTP metrics
+	HTTPRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_http_requests_total",
+			Help: "Total number of HTTP requests",
+		},
+		[]string{"method", "route", "status_code"},
+	)
+
+	HTTPRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "payment_service_http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status_code"},
+	)
+
+	HTTPRequestsInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "payment_service_http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served",
+		},
+	)
+
+	// Payment metrics
+	PaymentsInitiatedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_payments_initiated_total",
+			Help: "Total number of payments initiated with a gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	PaymentsConfirmedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_payments_confirmed_total",
+			Help: "Total number of payments that completed successfully",
+		},
+		[]string{"gateway"},
+	)
+
+	PaymentsFailedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_payments_failed_total",
+			Help: "Total number of payments that failed or expired",
+		},
+		[]string{"gateway"},
+	)
+
+	PaymentsRefundedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_payments_refunded_total",
+			Help: "Total number of refunds accepted by a gateway",
+		},
+		[]string{"gateway"},
+	)
+)
+
+// RecordHTTPRequest records an HTTP request metric
+func RecordHTTPRequest(method, route, statusCode string, duration time.Duration) {
+	HTTPRequestsTotal.WithLabelValues(method, route, statusCode).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route, statusCode).Observe(duration.Seconds())
+}
+
+// RecordPaymentInitiated records a payment initiated with a gateway
+func RecordPaymentInitiated(gateway string) {
+	PaymentsInitiatedTotal.WithLabelValues(gateway).Inc()
+}
+
+// RecordPaymentConfirmed records a successful payment
+func RecordPaymentConfirmed(gateway string) {
+	PaymentsConfirmedTotal.WithLabelValues(gateway).Inc()
+}
+
+// RecordPaymentFailed records a failed or expired payment
+func RecordPaymentFailed(gateway string) {
+	PaymentsFailedTotal.WithLabelValues(gateway).Inc()
+}
+
+// RecordPaymentRefunded records a refund accepted by a gateway
+func RecordPaymentRefunded(gateway string) {
+	PaymentsRefundedTotal.WithLabelValues(gateway).Inc()
+}
+
+// Middleware records request count, duration and in-flight requests. Requests
+// are labelled by chi route pattern rather than raw path to bound cardinality.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HTTPRequestsInFlight.Inc()
+		defer HTTPRequestsInFlight.Dec()
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		RecordHTTPRequest(r.Method, routePattern(r), strconv.Itoa(status), time.Since(start))
+	})
+}
+
+// routePattern returns the matched chi route, or "unmatched" for requests no route handled
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
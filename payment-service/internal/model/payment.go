@@ -1,6 +1,7 @@
 package model
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,6 +31,14 @@ const (
 	PaymentMethodWallet = "wallet"
 )
 
+// Payment event actors. Webhook events are recorded as "webhook:<gateway>".
+const (
+	ActorAPI           = "api"
+	ActorWebhook       = "webhook"
+	ActorExpirySweeper = "expiry_sweeper"
+	ActorReconciler    = "reconciler"
+)
+
 // Payment represents a payment transaction
 type Payment struct {
 	ID                uuid.UUID  `json:"id" db:"id"`
@@ -70,6 +79,12 @@ type Refund struct {
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// UserRefund is a refund together with the booking of the payment it refunds
+type UserRefund struct {
+	Refund
+	BookingID uuid.UUID `json:"booking_id" db:"booking_id"`
+}
+
 // PaymentAttempt represents a payment retry attempt
 type PaymentAttempt struct {
 	ID            uuid.UUID  `json:"id" db:"id"`
@@ -83,6 +98,20 @@ type PaymentAttempt struct {
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 }
 
+// PaymentEvent is an audit record of a payment or refund status transition.
+// RefundID is set for refund transitions; FromStatus is nil on creation.
+type PaymentEvent struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	PaymentID       uuid.UUID  `json:"payment_id" db:"payment_id"`
+	RefundID        *uuid.UUID `json:"refund_id,omitempty" db:"refund_id"`
+	FromStatus      *string    `json:"from_status,omitempty" db:"from_status"`
+	ToStatus        string     `json:"to_status" db:"to_status"`
+	Actor           string     `json:"actor" db:"actor"`
+	Reason          *string    `json:"reason,omitempty" db:"reason"`
+	GatewayResponse *string    `json:"gateway_response,omitempty" db:"gateway_response"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
 // IdempotencyRecord represents an idempotency key record
 type IdempotencyRecord struct {
 	ID             uuid.UUID `json:"id" db:"id"`
@@ -94,6 +123,18 @@ type IdempotencyRecord struct {
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
 
+// RefundIdempotencyRecord caches the response of a refund request by idempotency key
+type RefundIdempotencyRecord struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	IdempotencyKey string    `json:"idempotency_key" db:"idempotency_key"`
+	PaymentID      uuid.UUID `json:"payment_id" db:"payment_id"`
+	RefundID       uuid.UUID `json:"refund_id" db:"refund_id"`
+	RequestHash    string    `json:"request_hash" db:"request_hash"`
+	ResponseData   string    `json:"response_data" db:"response_data"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
 // IsExpired checks if the payment has expired
 func (p *Payment) IsExpired() bool {
 	return p.ExpiresAt != nil && time.Now().After(*p.ExpiresAt)
@@ -104,6 +145,24 @@ func (p *Payment) CanBeRefunded() bool {
 	return p.Status == PaymentStatusSuccess
 }
 
+// RefundableAmount returns the balance still available for refund given the
+// payment's existing refunds. Failed and canceled refunds do not count.
+func (p *Payment) RefundableAmount(refunds []*Refund) float64 {
+	refunded := 0.0
+	for _, refund := range refunds {
+		if refund.Status == PaymentStatusFailed || refund.Status == PaymentStatusCanceled {
+			continue
+		}
+		refunded += refund.Amount
+	}
+
+	remaining := math.Round((p.Amount-refunded)*100) / 100
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 // IsTerminalStatus checks if the payment is in a terminal status
 func (p *Payment) IsTerminalStatus() bool {
 	return p.Status == PaymentStatusSuccess ||
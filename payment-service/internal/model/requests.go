@@ -43,22 +43,49 @@ type WebhookRequest struct {
 // PaymentResponse represents a payment response
 type PaymentResponse struct {
 	Payment    *Payment `json:"payment"`
+	Gateway    string   `json:"gateway"`
 	PaymentURL *string  `json:"payment_url,omitempty"`
 	Message    string   `json:"message"`
 }
 
 // RefundResponse represents a refund response
 type RefundResponse struct {
-	Refund  *Refund `json:"refund"`
-	Message string  `json:"message"`
+	Refund           *Refund `json:"refund"`
+	RefundableAmount float64 `json:"refundable_amount"`
+	Message          string  `json:"message"`
 }
 
-// PaymentListResponse represents a list of payments
-type PaymentListResponse struct {
-	Payments   []*Payment `json:"payments"`
-	TotalCount int        `json:"total_count"`
-	Page       int        `json:"page"`
-	PageSize   int        `json:"page_size"`
+// ReconcileResponse reports the outcome of reconciling a payment with its gateway
+type ReconcileResponse struct {
+	Payment        *Payment `json:"payment"`
+	PreviousStatus string   `json:"previous_status"`
+	Status         string   `json:"status"`
+	Changed        bool     `json:"changed"`
+}
+
+// PaymentStats holds aggregate payment figures for a date range. Amount
+// figures only include successful payments and success_rate is a percentage.
+type PaymentStats struct {
+	TotalPayments      int     `json:"total_payments"`
+	SuccessfulPayments int     `json:"successful_payments"`
+	FailedPayments     int     `json:"failed_payments"`
+	TotalAmount        float64 `json:"total_amount"`
+	AverageAmount      float64 `json:"average_amount"`
+	SuccessRate        float64 `json:"success_rate"`
+}
+
+// GatewayPaymentStats holds payment statistics for a single gateway
+type GatewayPaymentStats struct {
+	Gateway string `json:"gateway"`
+	PaymentStats
+}
+
+// PaymentStatsResponse represents the payment statistics endpoint response
+type PaymentStatsResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	PaymentStats
+	ByGateway []*GatewayPaymentStats `json:"by_gateway,omitempty"`
 }
 
 // HealthResponse represents a health check response
@@ -67,4 +94,15 @@ type HealthResponse struct {
 	Timestamp string `json:"timestamp"`
 	Version   string `json:"version"`
 	Database  string `json:"database"`
+	// Gateways is only reported by the readiness check
+	Gateways map[string]GatewayHealth `json:"gateways,omitempty"`
+}
+
+// GatewayHealth is the result of pinging one payment gateway. Only critical
+// gateways fail readiness when unhealthy.
+type GatewayHealth struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checked_at"`
 }
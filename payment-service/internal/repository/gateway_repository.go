@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"payment-service/internal/tracing"
+
 	"github.com/google/uuid"
 )
 
@@ -39,6 +41,9 @@ func NewGatewayRepository(db *sql.DB) GatewayRepository {
 
 // GetEnabledGateways retrieves all enabled payment gateways ordered by priority
 func (r *gatewayRepository) GetEnabledGateways(ctx context.Context) ([]*GatewayConfig, error) {
+	ctx, span := tracing.StartSpan(ctx, "GatewayRepository.GetEnabledGateways")
+	defer span.End()
+
 	query := `
 		SELECT id, gateway, is_enabled, priority, config, created_at, updated_at
 		FROM gateway_configs 
@@ -69,6 +74,9 @@ func (r *gatewayRepository) GetEnabledGateways(ctx context.Context) ([]*GatewayC
 
 // GetGatewayConfig retrieves configuration for a specific gateway
 func (r *gatewayRepository) GetGatewayConfig(ctx context.Context, gateway string) (*GatewayConfig, error) {
+	ctx, span := tracing.StartSpan(ctx, "GatewayRepository.GetGatewayConfig")
+	defer span.End()
+
 	query := `
 		SELECT id, gateway, is_enabled, priority, config, created_at, updated_at
 		FROM gateway_configs WHERE gateway = $1`
@@ -91,6 +99,9 @@ func (r *gatewayRepository) GetGatewayConfig(ctx context.Context, gateway string
 
 // UpdateGatewayConfig updates gateway configuration
 func (r *gatewayRepository) UpdateGatewayConfig(ctx context.Context, config *GatewayConfig) error {
+	ctx, span := tracing.StartSpan(ctx, "GatewayRepository.UpdateGatewayConfig")
+	defer span.End()
+
 	query := `
 		UPDATE gateway_configs SET
 			is_enabled = $2, priority = $3, config = $4, updated_at = $5
@@ -111,6 +122,9 @@ func (r *gatewayRepository) UpdateGatewayConfig(ctx context.Context, config *Gat
 
 // CreateGatewayConfig creates a new gateway configuration
 func (r *gatewayRepository) CreateGatewayConfig(ctx context.Context, config *GatewayConfig) error {
+	ctx, span := tracing.StartSpan(ctx, "GatewayRepository.CreateGatewayConfig")
+	defer span.End()
+
 	query := `
 		INSERT INTO gateway_configs (id, gateway, is_enabled, priority, config, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)`
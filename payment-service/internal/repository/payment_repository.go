@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"payment-service/internal/model"
+	"payment-service/internal/tracing"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -15,32 +16,55 @@ import (
 // PaymentRepository defines the interface for payment data operations
 type PaymentRepository interface {
 	// Payment operations
-	Create(ctx context.Context, payment *model.Payment) error
+	Create(ctx context.Context, payment *model.Payment, event *model.PaymentEvent) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Payment, error)
+	GetByGatewayPaymentID(ctx context.Context, gatewayPaymentID string) (*model.Payment, error)
+	GetByIdempotencyKey(ctx context.Context, idempotencyKey string) (*model.Payment, error)
 	GetByBookingID(ctx context.Context, bookingID uuid.UUID) ([]*model.Payment, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Payment, error)
-	Update(ctx context.Context, payment *model.Payment) error
+	CountByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	Update(ctx context.Context, payment *model.Payment, event *model.PaymentEvent) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
+	ExpireStalePayments(ctx context.Context, reason, actor string, limit int) ([]*model.Payment, error)
+	GetUnsettledPayments(ctx context.Context, idleMinutes, limit int) ([]*model.Payment, error)
 
 	// Refund operations
-	CreateRefund(ctx context.Context, refund *model.Refund) error
+	CreateRefund(ctx context.Context, refund *model.Refund, event *model.PaymentEvent) error
 	GetRefundByID(ctx context.Context, id uuid.UUID) (*model.Refund, error)
+	GetRefundByGatewayRefundID(ctx context.Context, gatewayRefundID string) (*model.Refund, error)
 	GetRefundsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.Refund, error)
-	UpdateRefund(ctx context.Context, refund *model.Refund) error
+	GetRefundsByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.UserRefund, error)
+	CountRefundsByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	UpdateRefund(ctx context.Context, refund *model.Refund, event *model.PaymentEvent) error
 
 	// Payment attempt operations
 	CreateAttempt(ctx context.Context, attempt *model.PaymentAttempt) error
 	GetAttemptsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentAttempt, error)
 
+	// Status audit trail
+	GetEventsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentEvent, error)
+
 	// Idempotency operations
 	CreateIdempotencyRecord(ctx context.Context, record *model.IdempotencyRecord) error
 	GetIdempotencyRecord(ctx context.Context, key string) (*model.IdempotencyRecord, error)
+	CreateRefundIdempotencyRecord(ctx context.Context, record *model.RefundIdempotencyRecord) error
+	GetRefundIdempotencyRecord(ctx context.Context, key string) (*model.RefundIdempotencyRecord, error)
 	CleanupExpiredIdempotencyRecords(ctx context.Context) error
 
+	// Webhook deduplication
+	RecordWebhookEvent(ctx context.Context, gateway, eventID string, expiresAt time.Time) (bool, error)
+	DeleteWebhookEvent(ctx context.Context, gateway, eventID string) error
+	CleanupExpiredWebhookEvents(ctx context.Context) (int, error)
+
 	// Analytics and reporting
-	GetPaymentStats(ctx context.Context, from, to time.Time) (map[string]interface{}, error)
+	GetPaymentStats(ctx context.Context, from, to time.Time) (*model.PaymentStats, error)
+	GetPaymentStatsByGateway(ctx context.Context, from, to time.Time) ([]*model.GatewayPaymentStats, error)
 }
 
+// userPaymentsFilter is shared by the user listing and count queries so that
+// pagination totals always match the rows being paged over
+const userPaymentsFilter = `WHERE user_id = $1`
+
 type paymentRepository struct {
 	db *sql.DB
 }
@@ -50,8 +74,12 @@ func NewPaymentRepository(db *sql.DB) PaymentRepository {
 	return &paymentRepository{db: db}
 }
 
-// Create creates a new payment
-func (r *paymentRepository) Create(ctx context.Context, payment *model.Payment) error {
+// Create creates a new payment. A non-nil event is recorded as the payment's
+// first status transition in the same transaction.
+func (r *paymentRepository) Create(ctx context.Context, payment *model.Payment, event *model.PaymentEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.Create")
+	defer span.End()
+
 	query := `
 		INSERT INTO payments (
 			id, booking_id, user_id, amount, currency, status, gateway,
@@ -62,13 +90,24 @@ func (r *paymentRepository) Create(ctx context.Context, payment *model.Payment)
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
 		)`
 
-	_, err := r.db.ExecContext(ctx, query,
-		payment.ID, payment.BookingID, payment.UserID, payment.Amount, payment.Currency,
-		payment.Status, payment.Gateway, payment.GatewayPaymentID, payment.GatewayOrderID,
-		payment.PaymentMethod, payment.PaymentURL, payment.IdempotencyKey, payment.Metadata,
-		payment.FailureReason, payment.ProcessedAt, payment.ExpiresAt,
-		payment.CreatedAt, payment.UpdatedAt,
-	)
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query,
+			payment.ID, payment.BookingID, payment.UserID, payment.Amount, payment.Currency,
+			payment.Status, payment.Gateway, payment.GatewayPaymentID, payment.GatewayOrderID,
+			payment.PaymentMethod, payment.PaymentURL, payment.IdempotencyKey, payment.Metadata,
+			payment.FailureReason, payment.ProcessedAt, payment.ExpiresAt,
+			payment.CreatedAt, payment.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		if event == nil {
+			return nil
+		}
+		event.PaymentID = payment.ID
+		event.FromStatus = nil
+		event.ToStatus = payment.Status
+		return insertEvent(ctx, tx, event)
+	})
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -82,6 +121,9 @@ func (r *paymentRepository) Create(ctx context.Context, payment *model.Payment)
 
 // GetByID retrieves a payment by ID
 func (r *paymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetByID")
+	defer span.End()
+
 	query := `
 		SELECT id, booking_id, user_id, amount, currency, status, gateway,
 			   gateway_payment_id, gateway_order_id, payment_method, payment_url,
@@ -108,8 +150,74 @@ func (r *paymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.P
 	return payment, nil
 }
 
+// GetByGatewayPaymentID retrieves a payment by the gateway's payment ID
+func (r *paymentRepository) GetByGatewayPaymentID(ctx context.Context, gatewayPaymentID string) (*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetByGatewayPaymentID")
+	defer span.End()
+
+	query := `
+		SELECT id, booking_id, user_id, amount, currency, status, gateway,
+			   gateway_payment_id, gateway_order_id, payment_method, payment_url,
+			   idempotency_key, metadata, failure_reason, processed_at, expires_at,
+			   created_at, updated_at
+		FROM payments WHERE gateway_payment_id = $1
+		ORDER BY created_at DESC LIMIT 1`
+
+	payment := &model.Payment{}
+	err := r.db.QueryRowContext(ctx, query, gatewayPaymentID).Scan(
+		&payment.ID, &payment.BookingID, &payment.UserID, &payment.Amount, &payment.Currency,
+		&payment.Status, &payment.Gateway, &payment.GatewayPaymentID, &payment.GatewayOrderID,
+		&payment.PaymentMethod, &payment.PaymentURL, &payment.IdempotencyKey, &payment.Metadata,
+		&payment.FailureReason, &payment.ProcessedAt, &payment.ExpiresAt,
+		&payment.CreatedAt, &payment.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found")
+		}
+		return nil, fmt.Errorf("failed to get payment by gateway payment ID: %w", err)
+	}
+
+	return payment, nil
+}
+
+// GetByIdempotencyKey retrieves the payment created with an idempotency key
+func (r *paymentRepository) GetByIdempotencyKey(ctx context.Context, idempotencyKey string) (*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetByIdempotencyKey")
+	defer span.End()
+
+	query := `
+		SELECT id, booking_id, user_id, amount, currency, status, gateway,
+			   gateway_payment_id, gateway_order_id, payment_method, payment_url,
+			   idempotency_key, metadata, failure_reason, processed_at, expires_at,
+			   created_at, updated_at
+		FROM payments WHERE idempotency_key = $1`
+
+	payment := &model.Payment{}
+	err := r.db.QueryRowContext(ctx, query, idempotencyKey).Scan(
+		&payment.ID, &payment.BookingID, &payment.UserID, &payment.Amount, &payment.Currency,
+		&payment.Status, &payment.Gateway, &payment.GatewayPaymentID, &payment.GatewayOrderID,
+		&payment.PaymentMethod, &payment.PaymentURL, &payment.IdempotencyKey, &payment.Metadata,
+		&payment.FailureReason, &payment.ProcessedAt, &payment.ExpiresAt,
+		&payment.CreatedAt, &payment.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found")
+		}
+		return nil, fmt.Errorf("failed to get payment by idempotency key: %w", err)
+	}
+
+	return payment, nil
+}
+
 // GetByBookingID retrieves payments by booking ID
 func (r *paymentRepository) GetByBookingID(ctx context.Context, bookingID uuid.UUID) ([]*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetByBookingID")
+	defer span.End()
+
 	query := `
 		SELECT id, booking_id, user_id, amount, currency, status, gateway,
 			   gateway_payment_id, gateway_order_id, payment_method, payment_url,
@@ -144,12 +252,15 @@ func (r *paymentRepository) GetByBookingID(ctx context.Context, bookingID uuid.U
 
 // GetByUserID retrieves payments by user ID with pagination
 func (r *paymentRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetByUserID")
+	defer span.End()
+
 	query := `
 		SELECT id, booking_id, user_id, amount, currency, status, gateway,
 			   gateway_payment_id, gateway_order_id, payment_method, payment_url,
 			   idempotency_key, metadata, failure_reason, processed_at, expires_at,
 			   created_at, updated_at
-		FROM payments WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+		FROM payments ` + userPaymentsFilter + ` ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 
 	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
@@ -176,8 +287,27 @@ func (r *paymentRepository) GetByUserID(ctx context.Context, userID uuid.UUID, l
 	return payments, nil
 }
 
-// Update updates a payment
-func (r *paymentRepository) Update(ctx context.Context, payment *model.Payment) error {
+// CountByUserID counts the payments belonging to a user
+func (r *paymentRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CountByUserID")
+	defer span.End()
+
+	query := `SELECT COUNT(*) FROM payments ` + userPaymentsFilter
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count payments by user ID: %w", err)
+	}
+
+	return count, nil
+}
+
+// Update updates a payment. When the status changes and event is non-nil, the
+// transition from the stored status is recorded in the same transaction.
+func (r *paymentRepository) Update(ctx context.Context, payment *model.Payment, event *model.PaymentEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.Update")
+	defer span.End()
+
 	query := `
 		UPDATE payments SET
 			status = $2, gateway_payment_id = $3, gateway_order_id = $4,
@@ -187,11 +317,31 @@ func (r *paymentRepository) Update(ctx context.Context, payment *model.Payment)
 
 	payment.UpdatedAt = time.Now()
 
-	_, err := r.db.ExecContext(ctx, query,
-		payment.ID, payment.Status, payment.GatewayPaymentID, payment.GatewayOrderID,
-		payment.PaymentMethod, payment.PaymentURL, payment.Metadata,
-		payment.FailureReason, payment.ProcessedAt, payment.ExpiresAt, payment.UpdatedAt,
-	)
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var previousStatus string
+		if event != nil {
+			err := tx.QueryRowContext(ctx, `SELECT status FROM payments WHERE id = $1 FOR UPDATE`, payment.ID).Scan(&previousStatus)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, query,
+			payment.ID, payment.Status, payment.GatewayPaymentID, payment.GatewayOrderID,
+			payment.PaymentMethod, payment.PaymentURL, payment.Metadata,
+			payment.FailureReason, payment.ProcessedAt, payment.ExpiresAt, payment.UpdatedAt,
+		); err != nil {
+			return err
+		}
+
+		if event == nil || previousStatus == payment.Status {
+			return nil
+		}
+		event.PaymentID = payment.ID
+		event.FromStatus = &previousStatus
+		event.ToStatus = payment.Status
+		return insertEvent(ctx, tx, event)
+	})
 
 	if err != nil {
 		return fmt.Errorf("failed to update payment: %w", err)
@@ -202,6 +352,9 @@ func (r *paymentRepository) Update(ctx context.Context, payment *model.Payment)
 
 // UpdateStatus updates only the payment status
 func (r *paymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.UpdateStatus")
+	defer span.End()
+
 	query := `UPDATE payments SET status = $2, updated_at = $3 WHERE id = $1`
 
 	_, err := r.db.ExecContext(ctx, query, id, status, time.Now())
@@ -212,8 +365,130 @@ func (r *paymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, stat
 	return nil
 }
 
-// CreateRefund creates a new refund
-func (r *paymentRepository) CreateRefund(ctx context.Context, refund *model.Refund) error {
+// ExpireStalePayments marks pending and initiated payments past their expiry as failed
+// and returns the payments that were transitioned. Rows locked by another sweeper are skipped.
+// Each transition is recorded as a payment event by actor in the same transaction.
+func (r *paymentRepository) ExpireStalePayments(ctx context.Context, reason, actor string, limit int) ([]*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.ExpireStalePayments")
+	defer span.End()
+
+	query := `
+		WITH stale AS (
+			SELECT id, status FROM payments
+			WHERE status IN ($3, $4) AND expires_at <= NOW()
+			ORDER BY expires_at
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE payments p SET status = $1, failure_reason = $2, updated_at = NOW()
+		FROM stale WHERE p.id = stale.id
+		RETURNING p.id, p.booking_id, p.user_id, p.amount, p.currency, p.status, p.gateway,
+			   p.gateway_payment_id, p.gateway_order_id, p.payment_method, p.payment_url,
+			   p.idempotency_key, p.metadata, p.failure_reason, p.processed_at, p.expires_at,
+			   p.created_at, p.updated_at, stale.status`
+
+	var payments []*model.Payment
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query,
+			model.PaymentStatusFailed, reason,
+			model.PaymentStatusPending, model.PaymentStatusInitiated, limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var previousStatuses []string
+		for rows.Next() {
+			payment := &model.Payment{}
+			var previousStatus string
+			err := rows.Scan(
+				&payment.ID, &payment.BookingID, &payment.UserID, &payment.Amount, &payment.Currency,
+				&payment.Status, &payment.Gateway, &payment.GatewayPaymentID, &payment.GatewayOrderID,
+				&payment.PaymentMethod, &payment.PaymentURL, &payment.IdempotencyKey, &payment.Metadata,
+				&payment.FailureReason, &payment.ProcessedAt, &payment.ExpiresAt,
+				&payment.CreatedAt, &payment.UpdatedAt, &previousStatus,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to scan payment: %w", err)
+			}
+			payments = append(payments, payment)
+			previousStatuses = append(previousStatuses, previousStatus)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		for i, payment := range payments {
+			event := &model.PaymentEvent{
+				PaymentID:  payment.ID,
+				FromStatus: &previousStatuses[i],
+				ToStatus:   payment.Status,
+				Actor:      actor,
+				Reason:     payment.FailureReason,
+			}
+			if err := insertEvent(ctx, tx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire stale payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// GetUnsettledPayments retrieves pending and initiated payments that have not
+// been updated for idleMinutes, least recently updated first
+func (r *paymentRepository) GetUnsettledPayments(ctx context.Context, idleMinutes, limit int) ([]*model.Payment, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetUnsettledPayments")
+	defer span.End()
+
+	query := `
+		SELECT id, booking_id, user_id, amount, currency, status, gateway,
+			   gateway_payment_id, gateway_order_id, payment_method, payment_url,
+			   idempotency_key, metadata, failure_reason, processed_at, expires_at,
+			   created_at, updated_at
+		FROM payments
+		WHERE status IN ($1, $2) AND updated_at <= NOW() - ($3 * INTERVAL '1 minute')
+		ORDER BY updated_at LIMIT $4`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		model.PaymentStatusPending, model.PaymentStatusInitiated, idleMinutes, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unsettled payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []*model.Payment
+	for rows.Next() {
+		payment := &model.Payment{}
+		err := rows.Scan(
+			&payment.ID, &payment.BookingID, &payment.UserID, &payment.Amount, &payment.Currency,
+			&payment.Status, &payment.Gateway, &payment.GatewayPaymentID, &payment.GatewayOrderID,
+			&payment.PaymentMethod, &payment.PaymentURL, &payment.IdempotencyKey, &payment.Metadata,
+			&payment.FailureReason, &payment.ProcessedAt, &payment.ExpiresAt,
+			&payment.CreatedAt, &payment.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// CreateRefund creates a new refund. A non-nil event is recorded as the
+// refund's first status transition in the same transaction.
+func (r *paymentRepository) CreateRefund(ctx context.Context, refund *model.Refund, event *model.PaymentEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CreateRefund")
+	defer span.End()
+
 	query := `
 		INSERT INTO refunds (
 			id, payment_id, amount, currency, status, gateway, gateway_refund_id,
@@ -223,12 +498,24 @@ func (r *paymentRepository) CreateRefund(ctx context.Context, refund *model.Refu
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		)`
 
-	_, err := r.db.ExecContext(ctx, query,
-		refund.ID, refund.PaymentID, refund.Amount, refund.Currency, refund.Status,
-		refund.Gateway, refund.GatewayRefundID, refund.Reason, refund.IdempotencyKey,
-		refund.Metadata, refund.FailureReason, refund.ProcessedAt,
-		refund.CreatedAt, refund.UpdatedAt,
-	)
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query,
+			refund.ID, refund.PaymentID, refund.Amount, refund.Currency, refund.Status,
+			refund.Gateway, refund.GatewayRefundID, refund.Reason, refund.IdempotencyKey,
+			refund.Metadata, refund.FailureReason, refund.ProcessedAt,
+			refund.CreatedAt, refund.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		if event == nil {
+			return nil
+		}
+		event.PaymentID = refund.PaymentID
+		event.RefundID = &refund.ID
+		event.FromStatus = nil
+		event.ToStatus = refund.Status
+		return insertEvent(ctx, tx, event)
+	})
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -242,6 +529,9 @@ func (r *paymentRepository) CreateRefund(ctx context.Context, refund *model.Refu
 
 // GetRefundByID retrieves a refund by ID
 func (r *paymentRepository) GetRefundByID(ctx context.Context, id uuid.UUID) (*model.Refund, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetRefundByID")
+	defer span.End()
+
 	query := `
 		SELECT id, payment_id, amount, currency, status, gateway, gateway_refund_id,
 			   reason, idempotency_key, metadata, failure_reason, processed_at,
@@ -266,8 +556,40 @@ func (r *paymentRepository) GetRefundByID(ctx context.Context, id uuid.UUID) (*m
 	return refund, nil
 }
 
+// GetRefundByGatewayRefundID retrieves a refund by the gateway's refund ID
+func (r *paymentRepository) GetRefundByGatewayRefundID(ctx context.Context, gatewayRefundID string) (*model.Refund, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetRefundByGatewayRefundID")
+	defer span.End()
+
+	query := `
+		SELECT id, payment_id, amount, currency, status, gateway, gateway_refund_id,
+			   reason, idempotency_key, metadata, failure_reason, processed_at,
+			   created_at, updated_at
+		FROM refunds WHERE gateway_refund_id = $1`
+
+	refund := &model.Refund{}
+	err := r.db.QueryRowContext(ctx, query, gatewayRefundID).Scan(
+		&refund.ID, &refund.PaymentID, &refund.Amount, &refund.Currency, &refund.Status,
+		&refund.Gateway, &refund.GatewayRefundID, &refund.Reason, &refund.IdempotencyKey,
+		&refund.Metadata, &refund.FailureReason, &refund.ProcessedAt,
+		&refund.CreatedAt, &refund.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refund not found")
+		}
+		return nil, fmt.Errorf("failed to get refund by gateway refund ID: %w", err)
+	}
+
+	return refund, nil
+}
+
 // GetRefundsByPaymentID retrieves refunds by payment ID
 func (r *paymentRepository) GetRefundsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.Refund, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetRefundsByPaymentID")
+	defer span.End()
+
 	query := `
 		SELECT id, payment_id, amount, currency, status, gateway, gateway_refund_id,
 			   reason, idempotency_key, metadata, failure_reason, processed_at,
@@ -298,8 +620,65 @@ func (r *paymentRepository) GetRefundsByPaymentID(ctx context.Context, paymentID
 	return refunds, nil
 }
 
-// UpdateRefund updates a refund
-func (r *paymentRepository) UpdateRefund(ctx context.Context, refund *model.Refund) error {
+// GetRefundsByUserID retrieves refunds across all of a user's payments with pagination
+func (r *paymentRepository) GetRefundsByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.UserRefund, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetRefundsByUserID")
+	defer span.End()
+
+	query := `
+		SELECT r.id, r.payment_id, r.amount, r.currency, r.status, r.gateway, r.gateway_refund_id,
+			   r.reason, r.idempotency_key, r.metadata, r.failure_reason, r.processed_at,
+			   r.created_at, r.updated_at, p.booking_id
+		FROM refunds r
+		JOIN payments p ON p.id = r.payment_id
+		WHERE p.user_id = $1
+		ORDER BY r.created_at DESC LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds by user ID: %w", err)
+	}
+	defer rows.Close()
+
+	var refunds []*model.UserRefund
+	for rows.Next() {
+		refund := &model.UserRefund{}
+		err := rows.Scan(
+			&refund.ID, &refund.PaymentID, &refund.Amount, &refund.Currency, &refund.Status,
+			&refund.Gateway, &refund.GatewayRefundID, &refund.Reason, &refund.IdempotencyKey,
+			&refund.Metadata, &refund.FailureReason, &refund.ProcessedAt,
+			&refund.CreatedAt, &refund.UpdatedAt, &refund.BookingID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+// CountRefundsByUserID counts the refunds across all of a user's payments
+func (r *paymentRepository) CountRefundsByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CountRefundsByUserID")
+	defer span.End()
+
+	query := `SELECT COUNT(*) FROM refunds r JOIN payments p ON p.id = r.payment_id WHERE p.user_id = $1`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count refunds by user ID: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpdateRefund updates a refund. When the status changes and event is non-nil,
+// the transition from the stored status is recorded in the same transaction.
+func (r *paymentRepository) UpdateRefund(ctx context.Context, refund *model.Refund, event *model.PaymentEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.UpdateRefund")
+	defer span.End()
+
 	query := `
 		UPDATE refunds SET
 			status = $2, gateway_refund_id = $3, metadata = $4,
@@ -308,10 +687,31 @@ func (r *paymentRepository) UpdateRefund(ctx context.Context, refund *model.Refu
 
 	refund.UpdatedAt = time.Now()
 
-	_, err := r.db.ExecContext(ctx, query,
-		refund.ID, refund.Status, refund.GatewayRefundID, refund.Metadata,
-		refund.FailureReason, refund.ProcessedAt, refund.UpdatedAt,
-	)
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var previousStatus string
+		if event != nil {
+			err := tx.QueryRowContext(ctx, `SELECT status FROM refunds WHERE id = $1 FOR UPDATE`, refund.ID).Scan(&previousStatus)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, query,
+			refund.ID, refund.Status, refund.GatewayRefundID, refund.Metadata,
+			refund.FailureReason, refund.ProcessedAt, refund.UpdatedAt,
+		); err != nil {
+			return err
+		}
+
+		if event == nil || previousStatus == refund.Status {
+			return nil
+		}
+		event.PaymentID = refund.PaymentID
+		event.RefundID = &refund.ID
+		event.FromStatus = &previousStatus
+		event.ToStatus = refund.Status
+		return insertEvent(ctx, tx, event)
+	})
 
 	if err != nil {
 		return fmt.Errorf("failed to update refund: %w", err)
@@ -322,6 +722,9 @@ func (r *paymentRepository) UpdateRefund(ctx context.Context, refund *model.Refu
 
 // CreateAttempt creates a new payment attempt
 func (r *paymentRepository) CreateAttempt(ctx context.Context, attempt *model.PaymentAttempt) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CreateAttempt")
+	defer span.End()
+
 	query := `
 		INSERT INTO payment_attempts (
 			id, payment_id, attempt_number, gateway, status,
@@ -343,6 +746,9 @@ func (r *paymentRepository) CreateAttempt(ctx context.Context, attempt *model.Pa
 
 // GetAttemptsByPaymentID retrieves payment attempts by payment ID
 func (r *paymentRepository) GetAttemptsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentAttempt, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetAttemptsByPaymentID")
+	defer span.End()
+
 	query := `
 		SELECT id, payment_id, attempt_number, gateway, status,
 			   error_message, response_data, attempted_at, created_at
@@ -371,8 +777,44 @@ func (r *paymentRepository) GetAttemptsByPaymentID(ctx context.Context, paymentI
 	return attempts, nil
 }
 
+// GetEventsByPaymentID retrieves the status transitions of a payment and its
+// refunds, oldest first
+func (r *paymentRepository) GetEventsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentEvent, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetEventsByPaymentID")
+	defer span.End()
+
+	query := `
+		SELECT id, payment_id, refund_id, from_status, to_status, actor,
+			   reason, gateway_response, created_at
+		FROM payment_events WHERE payment_id = $1 ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*model.PaymentEvent{}
+	for rows.Next() {
+		event := &model.PaymentEvent{}
+		err := rows.Scan(
+			&event.ID, &event.PaymentID, &event.RefundID, &event.FromStatus, &event.ToStatus,
+			&event.Actor, &event.Reason, &event.GatewayResponse, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
 // CreateIdempotencyRecord creates a new idempotency record
 func (r *paymentRepository) CreateIdempotencyRecord(ctx context.Context, record *model.IdempotencyRecord) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CreateIdempotencyRecord")
+	defer span.End()
+
 	query := `
 		INSERT INTO idempotency_records (
 			id, idempotency_key, payment_id, request_hash, response_data, expires_at, created_at
@@ -392,6 +834,9 @@ func (r *paymentRepository) CreateIdempotencyRecord(ctx context.Context, record
 
 // GetIdempotencyRecord retrieves an idempotency record by key
 func (r *paymentRepository) GetIdempotencyRecord(ctx context.Context, key string) (*model.IdempotencyRecord, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetIdempotencyRecord")
+	defer span.End()
+
 	query := `
 		SELECT id, idempotency_key, payment_id, request_hash, response_data, expires_at, created_at
 		FROM idempotency_records WHERE idempotency_key = $1 AND expires_at > NOW()`
@@ -412,8 +857,58 @@ func (r *paymentRepository) GetIdempotencyRecord(ctx context.Context, key string
 	return record, nil
 }
 
-// CleanupExpiredIdempotencyRecords removes expired idempotency records
+// CreateRefundIdempotencyRecord creates a new refund idempotency record
+func (r *paymentRepository) CreateRefundIdempotencyRecord(ctx context.Context, record *model.RefundIdempotencyRecord) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CreateRefundIdempotencyRecord")
+	defer span.End()
+
+	query := `
+		INSERT INTO refund_idempotency_records (
+			id, idempotency_key, payment_id, refund_id, request_hash, response_data, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		record.ID, record.IdempotencyKey, record.PaymentID, record.RefundID, record.RequestHash,
+		record.ResponseData, record.ExpiresAt, record.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create refund idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// GetRefundIdempotencyRecord retrieves a refund idempotency record by key
+func (r *paymentRepository) GetRefundIdempotencyRecord(ctx context.Context, key string) (*model.RefundIdempotencyRecord, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetRefundIdempotencyRecord")
+	defer span.End()
+
+	query := `
+		SELECT id, idempotency_key, payment_id, refund_id, request_hash, response_data, expires_at, created_at
+		FROM refund_idempotency_records WHERE idempotency_key = $1 AND expires_at > NOW()`
+
+	record := &model.RefundIdempotencyRecord{}
+	err := r.db.QueryRowContext(ctx, query, key).Scan(
+		&record.ID, &record.IdempotencyKey, &record.PaymentID, &record.RefundID, &record.RequestHash,
+		&record.ResponseData, &record.ExpiresAt, &record.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found, but not an error
+		}
+		return nil, fmt.Errorf("failed to get refund idempotency record: %w", err)
+	}
+
+	return record, nil
+}
+
+// CleanupExpiredIdempotencyRecords removes expired payment and refund idempotency records
 func (r *paymentRepository) CleanupExpiredIdempotencyRecords(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CleanupExpiredIdempotencyRecords")
+	defer span.End()
+
 	query := `DELETE FROM idempotency_records WHERE expires_at <= NOW()`
 
 	_, err := r.db.ExecContext(ctx, query)
@@ -421,40 +916,187 @@ func (r *paymentRepository) CleanupExpiredIdempotencyRecords(ctx context.Context
 		return fmt.Errorf("failed to cleanup expired idempotency records: %w", err)
 	}
 
+	query = `DELETE FROM refund_idempotency_records WHERE expires_at <= NOW()`
+
+	_, err = r.db.ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup expired refund idempotency records: %w", err)
+	}
+
 	return nil
 }
 
-// GetPaymentStats retrieves payment statistics for a date range
-func (r *paymentRepository) GetPaymentStats(ctx context.Context, from, to time.Time) (map[string]interface{}, error) {
+// RecordWebhookEvent remembers a webhook event until expiresAt. It returns
+// false when the event was already recorded and has not expired yet.
+func (r *paymentRepository) RecordWebhookEvent(ctx context.Context, gateway, eventID string, expiresAt time.Time) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.RecordWebhookEvent")
+	defer span.End()
+
 	query := `
-		SELECT 
+		INSERT INTO webhook_events (gateway, event_id, received_at, expires_at)
+		VALUES ($1, $2, NOW(), $3)
+		ON CONFLICT (gateway, event_id) DO UPDATE
+			SET received_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE webhook_events.expires_at <= NOW()`
+
+	result, err := r.db.ExecContext(ctx, query, gateway, eventID, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// DeleteWebhookEvent forgets a webhook event so a redelivery is processed again
+func (r *paymentRepository) DeleteWebhookEvent(ctx context.Context, gateway, eventID string) error {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.DeleteWebhookEvent")
+	defer span.End()
+
+	query := `DELETE FROM webhook_events WHERE gateway = $1 AND event_id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, gateway, eventID); err != nil {
+		return fmt.Errorf("failed to delete webhook event: %w", err)
+	}
+
+	return nil
+}
+
+// CleanupExpiredWebhookEvents deletes webhook events past their expiry
+func (r *paymentRepository) CleanupExpiredWebhookEvents(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.CleanupExpiredWebhookEvents")
+	defer span.End()
+
+	query := `DELETE FROM webhook_events WHERE expires_at <= NOW()`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired webhook events: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// paymentStatsColumns are the aggregate columns shared by the stats queries
+const paymentStatsColumns = `
 			COUNT(*) as total_payments,
 			COUNT(CASE WHEN status = 'success' THEN 1 END) as successful_payments,
 			COUNT(CASE WHEN status = 'failed' THEN 1 END) as failed_payments,
 			COALESCE(SUM(CASE WHEN status = 'success' THEN amount END), 0) as total_amount,
-			COALESCE(AVG(CASE WHEN status = 'success' THEN amount END), 0) as avg_amount
+			COALESCE(AVG(CASE WHEN status = 'success' THEN amount END), 0) as avg_amount`
+
+// GetPaymentStats retrieves payment statistics for a date range
+func (r *paymentRepository) GetPaymentStats(ctx context.Context, from, to time.Time) (*model.PaymentStats, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetPaymentStats")
+	defer span.End()
+
+	query := `
+		SELECT ` + paymentStatsColumns + `
 		FROM payments 
 		WHERE created_at >= $1 AND created_at <= $2`
 
-	var totalPayments, successfulPayments, failedPayments int
-	var totalAmount, avgAmount float64
-
+	stats := &model.PaymentStats{}
 	err := r.db.QueryRowContext(ctx, query, from, to).Scan(
-		&totalPayments, &successfulPayments, &failedPayments, &totalAmount, &avgAmount,
+		&stats.TotalPayments, &stats.SuccessfulPayments, &stats.FailedPayments, &stats.TotalAmount, &stats.AverageAmount,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get payment stats: %w", err)
 	}
 
-	stats := map[string]interface{}{
-		"total_payments":     totalPayments,
-		"successful_payments": successfulPayments,
-		"failed_payments":    failedPayments,
-		"total_amount":       totalAmount,
-		"average_amount":     avgAmount,
-		"success_rate":       float64(successfulPayments) / float64(totalPayments) * 100,
-	}
+	stats.SuccessRate = successRate(stats.SuccessfulPayments, stats.TotalPayments)
 
 	return stats, nil
 }
+
+// GetPaymentStatsByGateway retrieves payment statistics for a date range grouped by gateway
+func (r *paymentRepository) GetPaymentStatsByGateway(ctx context.Context, from, to time.Time) ([]*model.GatewayPaymentStats, error) {
+	ctx, span := tracing.StartSpan(ctx, "PaymentRepository.GetPaymentStatsByGateway")
+	defer span.End()
+
+	query := `
+		SELECT gateway, ` + paymentStatsColumns + `
+		FROM payments 
+		WHERE created_at >= $1 AND created_at <= $2
+		GROUP BY gateway
+		ORDER BY gateway`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment stats by gateway: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*model.GatewayPaymentStats
+	for rows.Next() {
+		gatewayStats := &model.GatewayPaymentStats{}
+		err := rows.Scan(
+			&gatewayStats.Gateway, &gatewayStats.TotalPayments, &gatewayStats.SuccessfulPayments,
+			&gatewayStats.FailedPayments, &gatewayStats.TotalAmount, &gatewayStats.AverageAmount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment stats: %w", err)
+		}
+
+		gatewayStats.SuccessRate = successRate(gatewayStats.SuccessfulPayments, gatewayStats.TotalPayments)
+		stats = append(stats, gatewayStats)
+	}
+
+	return stats, rows.Err()
+}
+
+// successRate returns successful as a percentage of total, or 0 when there are no payments
+func successRate(successful, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(successful) / float64(total) * 100
+}
+
+// withTx runs fn in a transaction, committing only if it succeeds
+func (r *paymentRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertEvent records a status transition inside the caller's transaction
+func insertEvent(ctx context.Context, tx *sql.Tx, event *model.PaymentEvent) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO payment_events (
+			id, payment_id, refund_id, from_status, to_status, actor,
+			reason, gateway_response, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := tx.ExecContext(ctx, query,
+		event.ID, event.PaymentID, event.RefundID, event.FromStatus, event.ToStatus,
+		event.Actor, event.Reason, event.GatewayResponse, event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record payment event: %w", err)
+	}
+	return nil
+}
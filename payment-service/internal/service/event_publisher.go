@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Payment event types consumed by other services
+const (
+	EventPaymentExpired   = "payment.expired"
+	EventPaymentConfirmed = "payment.confirmed"
+)
+
+// PaymentEvent represents a payment lifecycle event
+type PaymentEvent struct {
+	Type      string                 `json:"type"`
+	PaymentID uuid.UUID              `json:"payment_id"`
+	BookingID uuid.UUID              `json:"booking_id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Data      map[string]interface{} `json:"data"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// EventPublisher publishes payment events to the message broker
+type EventPublisher interface {
+	Publish(ctx context.Context, event *PaymentEvent) error
+	Close() error
+}
+
+type kafkaEventPublisher struct {
+	writer *kafka.Writer
+	topic  string
+}
+
+// NewKafkaEventPublisher creates a publisher that writes payment events to a Kafka topic
+func NewKafkaEventPublisher(brokers []string, topic string) EventPublisher {
+	return &kafkaEventPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			WriteTimeout: 10 * time.Second,
+		},
+		topic: topic,
+	}
+}
+
+// Publish writes the event keyed by booking ID so events for a booking stay ordered
+func (p *kafkaEventPublisher) Publish(ctx context.Context, event *PaymentEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment event: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.BookingID.String()),
+		Value: payload,
+		Time:  event.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish payment event: %w", err)
+	}
+
+	log.Info().
+		Str("event_type", event.Type).
+		Str("payment_id", event.PaymentID.String()).
+		Str("topic", p.topic).
+		Msg("Payment event published")
+
+	return nil
+}
+
+// Close flushes pending messages and closes the writer
+func (p *kafkaEventPublisher) Close() error {
+	return p.writer.Close()
+}
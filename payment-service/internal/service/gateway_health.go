@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"payment-service/internal/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// gatewayHealthCache holds the last gateway ping results so frequent
+// readiness probes do not call the gateways every time
+type gatewayHealthCache struct {
+	mu        sync.Mutex
+	results   map[string]model.GatewayHealth
+	checkedAt time.Time
+}
+
+// ReadinessCheck extends the health check with a ping of every configured
+// gateway. Gateway results are cached for GatewayHealthCacheSeconds.
+func (s *paymentService) ReadinessCheck(ctx context.Context) (*model.HealthResponse, error) {
+	response, err := s.HealthCheck(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response.Gateways = s.gatewayHealthResults(ctx)
+	return response, nil
+}
+
+// gatewayHealthResults returns cached gateway ping results, pinging again
+// once the cache has expired
+func (s *paymentService) gatewayHealthResults(ctx context.Context) map[string]model.GatewayHealth {
+	cache := &s.gatewayHealth
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	ttl := time.Duration(s.config.GatewayHealthCacheSeconds) * time.Second
+	if cache.results != nil && time.Since(cache.checkedAt) < ttl {
+		return cache.results
+	}
+
+	critical := make(map[string]bool, len(s.config.CriticalGateways))
+	for _, name := range s.config.CriticalGateways {
+		critical[strings.ToLower(name)] = true
+	}
+
+	errs := s.gatewayMgr.PingAll(ctx, time.Duration(s.config.GatewayHealthTimeoutSeconds)*time.Second)
+	checkedAt := time.Now()
+
+	results := make(map[string]model.GatewayHealth, len(errs))
+	for name, err := range errs {
+		health := model.GatewayHealth{
+			Status:    "healthy",
+			Critical:  critical[name],
+			CheckedAt: checkedAt.Format(time.RFC3339),
+		}
+		if err != nil {
+			health.Status = "unhealthy"
+			health.Error = err.Error()
+			log.Warn().Err(err).Str("gateway", name).Bool("critical", health.Critical).Msg("Payment gateway health check failed")
+		}
+		results[name] = health
+	}
+
+	cache.results = results
+	cache.checkedAt = checkedAt
+	return results
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"payment-service/internal/gateway"
+	"payment-service/internal/model"
+	"payment-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakePaymentRepository keeps one payment in memory. Methods a test does not
+// need are left to the embedded nil interface and panic if called.
+type fakePaymentRepository struct {
+	repository.PaymentRepository
+
+	mu      sync.Mutex
+	payment *model.Payment
+}
+
+func (r *fakePaymentRepository) GetByGatewayPaymentID(ctx context.Context, gatewayPaymentID string) (*model.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.payment.GatewayPaymentID == nil || *r.payment.GatewayPaymentID != gatewayPaymentID {
+		return nil, errors.New("payment not found")
+	}
+	stored := *r.payment
+	return &stored, nil
+}
+
+func (r *fakePaymentRepository) Update(ctx context.Context, payment *model.Payment, event *model.PaymentEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *payment
+	r.payment = &stored
+	return nil
+}
+
+func (r *fakePaymentRepository) GetAttemptsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentAttempt, error) {
+	return nil, nil
+}
+
+func (r *fakePaymentRepository) CreateAttempt(ctx context.Context, attempt *model.PaymentAttempt) error {
+	return nil
+}
+
+// fakeEventPublisher records published payment events
+type fakeEventPublisher struct {
+	mu     sync.Mutex
+	events []*PaymentEvent
+}
+
+func (p *fakeEventPublisher) Publish(ctx context.Context, event *PaymentEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *fakeEventPublisher) Close() error {
+	return nil
+}
+
+func TestPaymentWebhookPublishesConfirmation(t *testing.T) {
+	tests := []struct {
+		name          string
+		currentStatus string
+		webhookStatus string
+		wantConfirmed bool
+	}{
+		{name: "initiated payment succeeds", currentStatus: model.PaymentStatusInitiated, webhookStatus: "succeeded", wantConfirmed: true},
+		{name: "failed payment later succeeds", currentStatus: model.PaymentStatusFailed, webhookStatus: "succeeded", wantConfirmed: true},
+		{name: "payment fails", currentStatus: model.PaymentStatusInitiated, webhookStatus: "failed"},
+		{name: "redelivered success", currentStatus: model.PaymentStatusSuccess, webhookStatus: "succeeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gatewayPaymentID := "pi_" + uuid.NewString()
+			payment := &model.Payment{
+				ID:               uuid.New(),
+				BookingID:        uuid.New(),
+				UserID:           uuid.New(),
+				Amount:           1180,
+				Currency:         "INR",
+				Status:           tt.currentStatus,
+				Gateway:          model.GatewayStripe,
+				GatewayPaymentID: &gatewayPaymentID,
+			}
+			publisher := &fakeEventPublisher{}
+			s := &paymentService{paymentRepo: &fakePaymentRepository{payment: payment}, eventPublisher: publisher}
+
+			err := s.applyPaymentWebhook(context.Background(), model.GatewayStripe, &gateway.WebhookEvent{
+				EventID:          uuid.NewString(),
+				EventType:        "payment_intent." + tt.webhookStatus,
+				GatewayPaymentID: gatewayPaymentID,
+				Status:           tt.webhookStatus,
+			})
+			if err != nil {
+				t.Fatalf("applyPaymentWebhook: %v", err)
+			}
+
+			if !tt.wantConfirmed {
+				if len(publisher.events) != 0 {
+					t.Errorf("published %d events, want none", len(publisher.events))
+				}
+				return
+			}
+			if len(publisher.events) != 1 {
+				t.Fatalf("published %d events, want 1", len(publisher.events))
+			}
+			event := publisher.events[0]
+			if event.Type != EventPaymentConfirmed || event.PaymentID != payment.ID || event.BookingID != payment.BookingID {
+				t.Errorf("published %s for payment %s booking %s, want %s for %s booking %s",
+					event.Type, event.PaymentID, event.BookingID, EventPaymentConfirmed, payment.ID, payment.BookingID)
+			}
+			if event.Data["gateway_payment_id"] != gatewayPaymentID || event.Data["previous_status"] != tt.currentStatus {
+				t.Errorf("event data = %v", event.Data)
+			}
+		})
+	}
+}
@@ -6,17 +6,31 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"payment-service/internal/config"
 	"payment-service/internal/gateway"
+	"payment-service/internal/metrics"
 	"payment-service/internal/model"
 	"payment-service/internal/repository"
+	"payment-service/internal/tracing"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"salon-shared/errors"
+	"salon-shared/pagination"
 )
 
+// ErrInvalidWebhookSignature is returned by ProcessWebhook when the gateway rejects the webhook signature
+var ErrInvalidWebhookSignature = gateway.ErrInvalidSignature
+
+// ErrWebhookExpired is returned by ProcessWebhook when the event is older than the webhook tolerance
+var ErrWebhookExpired = gateway.ErrWebhookExpired
+
 // PaymentService defines the interface for payment business logic
 type PaymentService interface {
 	// Payment operations
@@ -24,50 +38,77 @@ type PaymentService interface {
 	ConfirmPayment(ctx context.Context, request *model.ConfirmPaymentRequest) (*model.PaymentResponse, error)
 	GetPayment(ctx context.Context, paymentID uuid.UUID) (*model.Payment, error)
 	GetPaymentsByBooking(ctx context.Context, bookingID uuid.UUID) ([]*model.Payment, error)
-	GetPaymentsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) (*model.PaymentListResponse, error)
+	GetPaymentsByUser(ctx context.Context, userID uuid.UUID, page pagination.Params) (*pagination.ListResponse[*model.Payment], error)
+	GetPaymentEvents(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentEvent, error)
 
 	// Refund operations
 	RefundPayment(ctx context.Context, request *model.RefundPaymentRequest) (*model.RefundResponse, error)
 	GetRefund(ctx context.Context, refundID uuid.UUID) (*model.Refund, error)
 	GetRefundsByPayment(ctx context.Context, paymentID uuid.UUID) ([]*model.Refund, error)
+	GetRefundsByUser(ctx context.Context, userID uuid.UUID, page pagination.Params) (*pagination.ListResponse[*model.UserRefund], error)
 
-	// Webhook operations
-	ProcessWebhook(ctx context.Context, gatewayName string, payload []byte, signature string) error
+	// Webhook operations. deliveryID is the gateway's event ID header, if any,
+	// used to drop redeliveries when the signed payload carries no event ID.
+	ProcessWebhook(ctx context.Context, gatewayName string, payload []byte, signature, deliveryID string) error
+	CleanupWebhookEvents(ctx context.Context) (int, error)
 
 	// Retry operations
 	RetryFailedPayment(ctx context.Context, paymentID uuid.UUID) (*model.PaymentResponse, error)
 
+	// Expiry operations
+	ExpireStalePayments(ctx context.Context) (int, error)
+
+	// Reconciliation. ReconcilePayment is the on-demand support operation;
+	// actor identifies the caller in the payment's audit trail.
+	ReconcileStalePayments(ctx context.Context) (int, error)
+	ReconcilePayment(ctx context.Context, paymentID uuid.UUID, actor string) (*model.ReconcileResponse, error)
+
+	// Reporting
+	GetPaymentStats(ctx context.Context, from, to time.Time) (*model.PaymentStats, error)
+	GetPaymentStatsByGateway(ctx context.Context, from, to time.Time) ([]*model.GatewayPaymentStats, error)
+
 	// Health check
 	HealthCheck(ctx context.Context) (*model.HealthResponse, error)
+	ReadinessCheck(ctx context.Context) (*model.HealthResponse, error)
 }
 
 type paymentService struct {
-	paymentRepo repository.PaymentRepository
-	gatewayRepo repository.GatewayRepository
-	gatewayMgr  gateway.GatewayManager
-	config      *config.Config
+	paymentRepo    repository.PaymentRepository
+	gatewayRepo    repository.GatewayRepository
+	gatewayMgr     gateway.GatewayManager
+	eventPublisher EventPublisher
+	config         *config.Config
+	gatewayHealth  gatewayHealthCache
 }
 
-// NewPaymentService creates a new payment service
+// NewPaymentService creates a new payment service. eventPublisher may be nil,
+// in which case payment events are only logged.
 func NewPaymentService(
 	paymentRepo repository.PaymentRepository,
 	gatewayRepo repository.GatewayRepository,
 	cfg *config.Config,
+	eventPublisher EventPublisher,
 ) PaymentService {
 	gatewayMgr := gateway.NewGatewayManager(cfg)
 
 	return &paymentService{
-		paymentRepo: paymentRepo,
-		gatewayRepo: gatewayRepo,
-		gatewayMgr:  gatewayMgr,
-		config:      cfg,
+		paymentRepo:    paymentRepo,
+		gatewayRepo:    gatewayRepo,
+		gatewayMgr:     gatewayMgr,
+		eventPublisher: eventPublisher,
+		config:         cfg,
 	}
 }
 
 // InitiatePayment creates a new payment and initiates it with the gateway
 func (s *paymentService) InitiatePayment(ctx context.Context, request *model.InitiatePaymentRequest) (*model.PaymentResponse, error) {
-	// Check for existing idempotency record
+	// Replay the original payment when the idempotency key was already used
+	requestHash := hashRequest(request)
 	if existingRecord, err := s.paymentRepo.GetIdempotencyRecord(ctx, request.IdempotencyKey); err == nil && existingRecord != nil {
+		if existingRecord.RequestHash != requestHash {
+			return nil, errors.NewConflictError("payment", "idempotency key was already used with different request parameters")
+		}
+
 		// Return cached response
 		var cachedResponse model.PaymentResponse
 		if err := json.Unmarshal([]byte(existingRecord.ResponseData), &cachedResponse); err == nil {
@@ -79,6 +120,12 @@ func (s *paymentService) InitiatePayment(ctx context.Context, request *model.Ini
 		}
 	}
 
+	// A payment without a cached response means an earlier call with this key
+	// did not finish; resume it instead of creating a second payment
+	if existing, err := s.paymentRepo.GetByIdempotencyKey(ctx, request.IdempotencyKey); err == nil {
+		return s.resumePayment(ctx, request, requestHash, existing)
+	}
+
 	// Get payment gateway
 	var paymentGateway gateway.PaymentGateway
 	var err error
@@ -88,10 +135,16 @@ func (s *paymentService) InitiatePayment(ctx context.Context, request *model.Ini
 		if err != nil {
 			return nil, fmt.Errorf("invalid gateway: %w", err)
 		}
+		if err := s.gatewayMgr.CheckCurrency(request.Gateway, request.Currency); err != nil {
+			return nil, err
+		}
 	} else {
 		// Auto-select best gateway
 		paymentGateway, err = s.gatewayMgr.SelectBestGateway(request.Amount, request.Currency)
 		if err != nil {
+			if validationErrs, ok := err.(errors.ValidationErrors); ok {
+				return nil, validationErrs
+			}
 			return nil, fmt.Errorf("no available gateway: %w", err)
 		}
 	}
@@ -118,21 +171,10 @@ func (s *paymentService) InitiatePayment(ctx context.Context, request *model.Ini
 	}
 
 	// Save payment to database
-	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+	if err := s.paymentRepo.Create(ctx, payment, statusEvent(model.ActorAPI, nil, nil)); err != nil {
 		return nil, fmt.Errorf("failed to create payment record: %w", err)
 	}
 
-	// Create payment attempt record
-	attempt := &model.PaymentAttempt{
-		ID:            uuid.New(),
-		PaymentID:     payment.ID,
-		AttemptNumber: 1,
-		Gateway:       payment.Gateway,
-		Status:        model.PaymentStatusPending,
-		AttemptedAt:   time.Now(),
-		CreatedAt:     time.Now(),
-	}
-
 	// Initiate payment with gateway
 	gatewayRequest := &gateway.PaymentRequest{
 		Amount:        request.Amount,
@@ -143,12 +185,122 @@ func (s *paymentService) InitiatePayment(ctx context.Context, request *model.Ini
 		Metadata:      request.Metadata,
 	}
 
-	gatewayResponse, err := paymentGateway.InitiatePayment(ctx, gatewayRequest)
+	gatewayResponse, err := s.initiateWithGateway(ctx, payment, paymentGateway, gatewayRequest, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create response
+	response := &model.PaymentResponse{
+		Payment:    payment,
+		Gateway:    payment.Gateway,
+		PaymentURL: &gatewayResponse.PaymentURL,
+		Message:    "Payment initiated successfully",
+	}
+
+	// Cache response for idempotency
+	s.cacheIdempotencyResponse(ctx, request, requestHash, payment.ID, response)
+
+	log.Info().
+		Str("payment_id", payment.ID.String()).
+		Str("gateway", payment.Gateway).
+		Float64("amount", payment.Amount).
+		Msg("Payment initiated successfully")
+
+	return response, nil
+}
+
+// resumePayment completes an initiation that was interrupted after the payment
+// row was created. A payment still pending is sent to the gateway again with
+// the same gateway idempotency key, so the gateway returns the order the
+// interrupted call created rather than a duplicate.
+func (s *paymentService) resumePayment(ctx context.Context, request *model.InitiatePaymentRequest, requestHash string, payment *model.Payment) (*model.PaymentResponse, error) {
+	if payment.BookingID != request.BookingID || payment.UserID != request.UserID ||
+		!amountsMatch(payment.Amount, request.Amount) || !strings.EqualFold(payment.Currency, request.Currency) {
+		return nil, errors.NewConflictError("payment", "idempotency key was already used with different request parameters")
+	}
+
+	if payment.Status != model.PaymentStatusPending {
+		return &model.PaymentResponse{
+			Payment:    payment,
+			Gateway:    payment.Gateway,
+			PaymentURL: payment.PaymentURL,
+			Message:    "Payment already processed",
+		}, nil
+	}
+	if payment.IsExpired() {
+		return nil, errors.NewConflictError("payment", "payment has expired")
+	}
+
+	paymentGateway, err := s.gatewayMgr.GetGateway(payment.Gateway)
+	if err != nil {
+		return nil, fmt.Errorf("gateway not available: %w", err)
+	}
+
+	attempts, err := s.paymentRepo.GetAttemptsByPaymentID(ctx, payment.ID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to get payment attempts: %w", err)
+	}
+
+	gatewayRequest := &gateway.PaymentRequest{
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		OrderID:     payment.ID.String(),
+		CustomerID:  payment.UserID.String(),
+		Description: fmt.Sprintf("Payment for booking %s", payment.BookingID.String()),
+		Metadata:    request.Metadata,
+		Resume:      true,
+	}
+
+	log.Info().
+		Str("payment_id", payment.ID.String()).
+		Str("idempotency_key", request.IdempotencyKey).
+		Msg("Resuming interrupted payment initiation")
+
+	gatewayResponse, err := s.initiateWithGateway(ctx, payment, paymentGateway, gatewayRequest, len(attempts)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.PaymentResponse{
+		Payment:    payment,
+		Gateway:    payment.Gateway,
+		PaymentURL: &gatewayResponse.PaymentURL,
+		Message:    "Payment initiated successfully",
+	}
+	s.cacheIdempotencyResponse(ctx, request, requestHash, payment.ID, response)
+
+	return response, nil
+}
+
+// initiateWithGateway creates the gateway order for a pending payment and
+// records the outcome on the payment and as attempt attemptNumber. The gateway
+// idempotency key is derived from the payment and attempt, so repeating an
+// attempt never creates a second order.
+func (s *paymentService) initiateWithGateway(ctx context.Context, payment *model.Payment, paymentGateway gateway.PaymentGateway, gatewayRequest *gateway.PaymentRequest, attemptNumber int) (*gateway.PaymentResponse, error) {
+	attempt := &model.PaymentAttempt{
+		ID:            uuid.New(),
+		PaymentID:     payment.ID,
+		AttemptNumber: attemptNumber,
+		Gateway:       payment.Gateway,
+		Status:        model.PaymentStatusPending,
+		AttemptedAt:   time.Now(),
+		CreatedAt:     time.Now(),
+	}
+	gatewayRequest.IdempotencyKey = gatewayIdempotencyKey(payment.ID, attemptNumber)
+
+	gatewayCtx, span := startGatewaySpan(ctx, "InitiatePayment", payment.Gateway)
+	gatewayResponse, err := paymentGateway.InitiatePayment(gatewayCtx, gatewayRequest)
+	tracing.AddSpanError(span, err)
+	span.End()
+	if err != nil {
+		s.gatewayMgr.ReportFailure(payment.Gateway)
+		metrics.RecordPaymentFailed(payment.Gateway)
+
 		// Update payment status to failed
 		payment.Status = model.PaymentStatusFailed
 		payment.FailureReason = stringPtr(err.Error())
-		s.paymentRepo.Update(ctx, payment)
+		s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, payment.FailureReason, nil))
 
 		// Update attempt record
 		attempt.Status = model.PaymentStatusFailed
@@ -158,13 +310,16 @@ func (s *paymentService) InitiatePayment(ctx context.Context, request *model.Ini
 		return nil, fmt.Errorf("failed to initiate payment with gateway: %w", err)
 	}
 
+	s.gatewayMgr.ReportSuccess(payment.Gateway)
+	metrics.RecordPaymentInitiated(payment.Gateway)
+
 	// Update payment with gateway response
 	payment.Status = model.PaymentStatusInitiated
 	payment.GatewayPaymentID = &gatewayResponse.GatewayPaymentID
 	payment.GatewayOrderID = &gatewayResponse.GatewayOrderID
 	payment.PaymentURL = &gatewayResponse.PaymentURL
 
-	if err := s.paymentRepo.Update(ctx, payment); err != nil {
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, nil, gatewayResponse)); err != nil {
 		log.Error().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to update payment after gateway initiation")
 	}
 
@@ -176,23 +331,13 @@ func (s *paymentService) InitiatePayment(ctx context.Context, request *model.Ini
 	}
 	s.paymentRepo.CreateAttempt(ctx, attempt)
 
-	// Create response
-	response := &model.PaymentResponse{
-		Payment:    payment,
-		PaymentURL: &gatewayResponse.PaymentURL,
-		Message:    "Payment initiated successfully",
-	}
-
-	// Cache response for idempotency
-	s.cacheIdempotencyResponse(ctx, request, payment.ID, response)
-
-	log.Info().
-		Str("payment_id", payment.ID.String()).
-		Str("gateway", payment.Gateway).
-		Float64("amount", payment.Amount).
-		Msg("Payment initiated successfully")
+	return gatewayResponse, nil
+}
 
-	return response, nil
+// gatewayIdempotencyKey identifies one initiation attempt of a payment at the
+// gateway. It fits Razorpay's 40 character receipt limit.
+func gatewayIdempotencyKey(paymentID uuid.UUID, attemptNumber int) string {
+	return fmt.Sprintf("%s-%d", paymentID, attemptNumber)
 }
 
 // ConfirmPayment confirms a payment after successful gateway processing
@@ -207,6 +352,7 @@ func (s *paymentService) ConfirmPayment(ctx context.Context, request *model.Conf
 	if payment.Status == model.PaymentStatusSuccess {
 		return &model.PaymentResponse{
 			Payment: payment,
+			Gateway: payment.Gateway,
 			Message: "Payment already confirmed",
 		}, nil
 	}
@@ -220,7 +366,8 @@ func (s *paymentService) ConfirmPayment(ctx context.Context, request *model.Conf
 	if payment.IsExpired() {
 		payment.Status = model.PaymentStatusFailed
 		payment.FailureReason = stringPtr("Payment expired")
-		s.paymentRepo.Update(ctx, payment)
+		s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, payment.FailureReason, nil))
+		metrics.RecordPaymentFailed(payment.Gateway)
 		return nil, fmt.Errorf("payment has expired")
 	}
 
@@ -230,16 +377,25 @@ func (s *paymentService) ConfirmPayment(ctx context.Context, request *model.Conf
 		return nil, fmt.Errorf("gateway not available: %w", err)
 	}
 
-	gatewayResponse, err := paymentGateway.ConfirmPayment(ctx, request.GatewayPaymentID)
+	gatewayCtx, span := startGatewaySpan(ctx, "ConfirmPayment", payment.Gateway)
+	gatewayResponse, err := paymentGateway.ConfirmPayment(gatewayCtx, request.GatewayPaymentID)
+	tracing.AddSpanError(span, err)
+	span.End()
 	if err != nil {
 		// Update payment status to failed
 		payment.Status = model.PaymentStatusFailed
 		payment.FailureReason = stringPtr(err.Error())
-		s.paymentRepo.Update(ctx, payment)
+		s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, payment.FailureReason, nil))
+		metrics.RecordPaymentFailed(payment.Gateway)
 
 		return nil, fmt.Errorf("failed to confirm payment with gateway: %w", err)
 	}
 
+	// Reject confirmations whose captured amount or currency differs from what was charged
+	if !amountsMatch(payment.Amount, gatewayResponse.Amount) || !strings.EqualFold(payment.Currency, gatewayResponse.Currency) {
+		return nil, s.failAmountMismatch(ctx, payment, gatewayResponse, model.ActorAPI)
+	}
+
 	// Update payment with confirmation details
 	payment.Status = gateway.MapGatewayStatus(payment.Gateway, gatewayResponse.Status)
 	payment.GatewayPaymentID = &gatewayResponse.GatewayPaymentID
@@ -271,12 +427,14 @@ func (s *paymentService) ConfirmPayment(ctx context.Context, request *model.Conf
 		payment.Metadata = stringPtr(string(metadataJSON))
 	}
 
-	if err := s.paymentRepo.Update(ctx, payment); err != nil {
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, nil, gatewayResponse)); err != nil {
 		return nil, fmt.Errorf("failed to update payment: %w", err)
 	}
+	recordPaymentOutcome(payment)
 
 	response := &model.PaymentResponse{
 		Payment: payment,
+		Gateway: payment.Gateway,
 		Message: "Payment confirmed successfully",
 	}
 
@@ -300,34 +458,46 @@ func (s *paymentService) GetPaymentsByBooking(ctx context.Context, bookingID uui
 }
 
 // GetPaymentsByUser retrieves payments for a user with pagination
-func (s *paymentService) GetPaymentsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) (*model.PaymentListResponse, error) {
-	payments, err := s.paymentRepo.GetByUserID(ctx, userID, limit, offset)
+func (s *paymentService) GetPaymentsByUser(ctx context.Context, userID uuid.UUID, page pagination.Params) (*pagination.ListResponse[*model.Payment], error) {
+	page = page.Normalize()
+	payments, err := s.paymentRepo.GetByUserID(ctx, userID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get total count (simplified - in production, you'd want a separate count query)
-	totalCount := len(payments)
-	if len(payments) == limit {
-		totalCount = limit + 1 // Indicate there might be more
+	totalCount, err := s.paymentRepo.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return &model.PaymentListResponse{
-		Payments:   payments,
-		TotalCount: totalCount,
-		Page:       offset/limit + 1,
-		PageSize:   limit,
-	}, nil
+	return pagination.NewListResponse(payments, totalCount, page), nil
+}
+
+// GetPaymentEvents retrieves the status audit trail of a payment and its refunds
+func (s *paymentService) GetPaymentEvents(ctx context.Context, paymentID uuid.UUID) ([]*model.PaymentEvent, error) {
+	if _, err := s.paymentRepo.GetByID(ctx, paymentID); err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.GetEventsByPaymentID(ctx, paymentID)
 }
 
 // RefundPayment processes a payment refund
 func (s *paymentService) RefundPayment(ctx context.Context, request *model.RefundPaymentRequest) (*model.RefundResponse, error) {
-	// Check for existing idempotency record
-	if existingRecord, err := s.paymentRepo.GetIdempotencyRecord(ctx, request.IdempotencyKey); err == nil && existingRecord != nil {
-		// Return cached response (simplified - you'd want a separate refund idempotency table)
-		return &model.RefundResponse{
-			Message: "Refund already processed",
-		}, nil
+	// Replay the original refund when the idempotency key was already used
+	requestHash := hashRequest(request)
+	if existingRecord, err := s.paymentRepo.GetRefundIdempotencyRecord(ctx, request.IdempotencyKey); err == nil && existingRecord != nil {
+		if existingRecord.RequestHash != requestHash {
+			return nil, errors.NewConflictError("refund", "idempotency key was already used with different request parameters")
+		}
+
+		var cachedResponse model.RefundResponse
+		if err := json.Unmarshal([]byte(existingRecord.ResponseData), &cachedResponse); err == nil {
+			log.Info().
+				Str("idempotency_key", request.IdempotencyKey).
+				Str("refund_id", existingRecord.RefundID.String()).
+				Msg("Returning cached refund response")
+			return &cachedResponse, nil
+		}
 	}
 
 	// Get payment
@@ -341,12 +511,26 @@ func (s *paymentService) RefundPayment(ctx context.Context, request *model.Refun
 		return nil, fmt.Errorf("payment cannot be refunded in status: %s", payment.Status)
 	}
 
-	// Determine refund amount
-	refundAmount := payment.Amount
+	// Determine remaining refundable balance from prior refunds
+	existingRefunds, err := s.paymentRepo.GetRefundsByPaymentID(ctx, payment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing refunds: %w", err)
+	}
+
+	refundableAmount := payment.RefundableAmount(existingRefunds)
+	if refundableAmount <= 0 {
+		return nil, errors.NewConflictError("refund", "payment has already been fully refunded")
+	}
+
+	// Determine refund amount (defaults to the remaining balance)
+	refundAmount := refundableAmount
 	if request.Amount != nil {
 		refundAmount = *request.Amount
-		if refundAmount > payment.Amount {
-			return nil, fmt.Errorf("refund amount cannot exceed payment amount")
+		if refundAmount <= 0 {
+			return nil, errors.NewValidationError("amount", "Refund amount must be greater than zero")
+		}
+		if refundAmount > refundableAmount {
+			return nil, errors.NewValidationError("amount", fmt.Sprintf("Refund amount exceeds refundable balance of %.2f", refundableAmount))
 		}
 	}
 
@@ -371,7 +555,7 @@ func (s *paymentService) RefundPayment(ctx context.Context, request *model.Refun
 	}
 
 	// Save refund to database
-	if err := s.paymentRepo.CreateRefund(ctx, refund); err != nil {
+	if err := s.paymentRepo.CreateRefund(ctx, refund, statusEvent(model.ActorAPI, &refund.Reason, nil)); err != nil {
 		return nil, fmt.Errorf("failed to create refund record: %w", err)
 	}
 
@@ -384,20 +568,26 @@ func (s *paymentService) RefundPayment(ctx context.Context, request *model.Refun
 	gatewayRequest := &gateway.RefundRequest{
 		GatewayPaymentID: *payment.GatewayPaymentID,
 		Amount:           refundAmount,
+		Currency:         payment.Currency,
 		Reason:           request.Reason,
 		Metadata:         request.Metadata,
 	}
 
-	gatewayResponse, err := paymentGateway.RefundPayment(ctx, gatewayRequest)
+	gatewayCtx, span := startGatewaySpan(ctx, "RefundPayment", payment.Gateway)
+	gatewayResponse, err := paymentGateway.RefundPayment(gatewayCtx, gatewayRequest)
+	tracing.AddSpanError(span, err)
+	span.End()
 	if err != nil {
 		// Update refund status to failed
 		refund.Status = model.PaymentStatusFailed
 		refund.FailureReason = stringPtr(err.Error())
-		s.paymentRepo.UpdateRefund(ctx, refund)
+		s.paymentRepo.UpdateRefund(ctx, refund, statusEvent(model.ActorAPI, refund.FailureReason, nil))
 
 		return nil, fmt.Errorf("failed to process refund with gateway: %w", err)
 	}
 
+	metrics.RecordPaymentRefunded(payment.Gateway)
+
 	// Update refund with gateway response
 	refund.Status = gateway.MapGatewayStatus(payment.Gateway, gatewayResponse.Status)
 	refund.GatewayRefundID = &gatewayResponse.GatewayRefundID
@@ -408,15 +598,19 @@ func (s *paymentService) RefundPayment(ctx context.Context, request *model.Refun
 		}
 	}
 
-	if err := s.paymentRepo.UpdateRefund(ctx, refund); err != nil {
+	if err := s.paymentRepo.UpdateRefund(ctx, refund, statusEvent(model.ActorAPI, nil, gatewayResponse)); err != nil {
 		log.Error().Err(err).Str("refund_id", refund.ID.String()).Msg("Failed to update refund after gateway processing")
 	}
 
 	response := &model.RefundResponse{
-		Refund:  refund,
-		Message: "Refund processed successfully",
+		Refund:           refund,
+		RefundableAmount: payment.RefundableAmount(append(existingRefunds, refund)),
+		Message:          "Refund processed successfully",
 	}
 
+	// Cache response for idempotency
+	s.cacheRefundIdempotencyResponse(ctx, request.IdempotencyKey, requestHash, refund, response)
+
 	log.Info().
 		Str("refund_id", refund.ID.String()).
 		Str("payment_id", payment.ID.String()).
@@ -436,8 +630,26 @@ func (s *paymentService) GetRefundsByPayment(ctx context.Context, paymentID uuid
 	return s.paymentRepo.GetRefundsByPaymentID(ctx, paymentID)
 }
 
-// ProcessWebhook processes webhook events from payment gateways
-func (s *paymentService) ProcessWebhook(ctx context.Context, gatewayName string, payload []byte, signature string) error {
+// GetRefundsByUser retrieves refunds across a user's payments with pagination
+func (s *paymentService) GetRefundsByUser(ctx context.Context, userID uuid.UUID, page pagination.Params) (*pagination.ListResponse[*model.UserRefund], error) {
+	page = page.Normalize()
+	refunds, err := s.paymentRepo.GetRefundsByUserID(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.paymentRepo.CountRefundsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.NewListResponse(refunds, totalCount, page), nil
+}
+
+// ProcessWebhook processes webhook events from payment gateways. Events are
+// remembered for the webhook tolerance window, so a redelivery within it is
+// acknowledged without being applied again; older events fail verification.
+func (s *paymentService) ProcessWebhook(ctx context.Context, gatewayName string, payload []byte, signature, deliveryID string) error {
 	// Get gateway
 	paymentGateway, err := s.gatewayMgr.GetGateway(gatewayName)
 	if err != nil {
@@ -450,9 +662,6 @@ func (s *paymentService) ProcessWebhook(ctx context.Context, gatewayName string,
 		return fmt.Errorf("failed to verify webhook: %w", err)
 	}
 
-	// Find payment by gateway payment ID
-	// Note: This is simplified - in production, you'd want an index on gateway_payment_id
-	// For now, we'll log the webhook event
 	log.Info().
 		Str("gateway", gatewayName).
 		Str("event_type", webhookEvent.EventType).
@@ -460,8 +669,187 @@ func (s *paymentService) ProcessWebhook(ctx context.Context, gatewayName string,
 		Str("status", webhookEvent.Status).
 		Msg("Webhook event received")
 
-	// TODO: Implement payment status update based on webhook
-	// This would involve finding the payment and updating its status
+	eventID := webhookDedupeKey(webhookEvent, payload, deliveryID)
+	recorded, err := s.paymentRepo.RecordWebhookEvent(ctx, gatewayName, eventID, time.Now().Add(s.config.WebhookTolerance()))
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		log.Info().Str("gateway", gatewayName).Str("event_id", eventID).Msg("Duplicate webhook delivery, skipping")
+		return nil
+	}
+
+	if err := s.applyWebhookEvent(ctx, gatewayName, webhookEvent); err != nil {
+		// Forget the event so a redelivery gets another chance
+		if deleteErr := s.paymentRepo.DeleteWebhookEvent(ctx, gatewayName, eventID); deleteErr != nil {
+			log.Error().Err(deleteErr).Str("gateway", gatewayName).Str("event_id", eventID).Msg("Failed to release webhook event")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// webhookDedupeKey identifies a webhook delivery: the signed event ID when the
+// gateway includes one, else the delivery ID header, else a hash of the payload
+func webhookDedupeKey(event *gateway.WebhookEvent, payload []byte, deliveryID string) string {
+	if event.EventID != "" {
+		return event.EventID
+	}
+	if deliveryID != "" {
+		return deliveryID
+	}
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// CleanupWebhookEvents forgets webhook events older than the dedupe window
+func (s *paymentService) CleanupWebhookEvents(ctx context.Context) (int, error) {
+	return s.paymentRepo.CleanupExpiredWebhookEvents(ctx)
+}
+
+// applyWebhookEvent routes a verified webhook event to the refund or payment handler
+func (s *paymentService) applyWebhookEvent(ctx context.Context, gatewayName string, webhookEvent *gateway.WebhookEvent) error {
+	if webhookEvent.GatewayRefundID != "" {
+		return s.applyRefundWebhook(ctx, gatewayName, webhookEvent)
+	}
+
+	if webhookEvent.GatewayPaymentID == "" {
+		// Events without a payment reference (e.g. disputes) are acknowledged only
+		return nil
+	}
+
+	return s.applyPaymentWebhook(ctx, gatewayName, webhookEvent)
+}
+
+// applyPaymentWebhook persists a webhook-driven payment status transition
+func (s *paymentService) applyPaymentWebhook(ctx context.Context, gatewayName string, event *gateway.WebhookEvent) error {
+	payment, err := s.paymentRepo.GetByGatewayPaymentID(ctx, event.GatewayPaymentID)
+	if err != nil && event.GatewayOrderID != "" {
+		// Razorpay payments are stored against the order ID until they are confirmed
+		payment, err = s.paymentRepo.GetByGatewayPaymentID(ctx, event.GatewayOrderID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find payment for webhook: %w", err)
+	}
+
+	newStatus := gateway.MapGatewayStatus(gatewayName, event.Status)
+
+	// Redelivered webhooks and late events for settled payments are ignored
+	if !canApplyWebhookStatus(payment, newStatus) {
+		log.Info().
+			Str("payment_id", payment.ID.String()).
+			Str("current_status", payment.Status).
+			Str("webhook_status", newStatus).
+			Msg("Webhook already applied, skipping")
+		return nil
+	}
+
+	previousStatus := payment.Status
+	payment.Status = newStatus
+	payment.GatewayPaymentID = &event.GatewayPaymentID
+
+	if event.PaymentMethod != "" {
+		payment.PaymentMethod = &event.PaymentMethod
+	}
+
+	processedAt := time.Now()
+	if event.ProcessedAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, event.ProcessedAt); err == nil {
+			processedAt = parsed
+		}
+	}
+	payment.ProcessedAt = &processedAt
+
+	if newStatus == model.PaymentStatusFailed {
+		payment.FailureReason = stringPtr(fmt.Sprintf("Payment failed via %s webhook", event.EventType))
+	}
+
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorWebhook+":"+gatewayName, &event.EventType, event)); err != nil {
+		return fmt.Errorf("failed to update payment from webhook: %w", err)
+	}
+	recordPaymentOutcome(payment)
+
+	// Record the webhook-driven transition as a payment attempt
+	attempts, err := s.paymentRepo.GetAttemptsByPaymentID(ctx, payment.ID)
+	if err != nil {
+		log.Warn().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to get payment attempts for webhook")
+	}
+
+	attempt := &model.PaymentAttempt{
+		ID:            uuid.New(),
+		PaymentID:     payment.ID,
+		AttemptNumber: len(attempts) + 1,
+		Gateway:       payment.Gateway,
+		Status:        newStatus,
+		ErrorMessage:  payment.FailureReason,
+		AttemptedAt:   processedAt,
+		CreatedAt:     time.Now(),
+	}
+	if eventData, err := json.Marshal(event); err == nil {
+		attempt.ResponseData = stringPtr(string(eventData))
+	}
+	if err := s.paymentRepo.CreateAttempt(ctx, attempt); err != nil {
+		log.Warn().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to record webhook payment attempt")
+	}
+
+	log.Info().
+		Str("payment_id", payment.ID.String()).
+		Str("previous_status", previousStatus).
+		Str("status", payment.Status).
+		Str("event_type", event.EventType).
+		Msg("Payment status updated from webhook")
+
+	if payment.Status == model.PaymentStatusSuccess {
+		s.publishPaymentConfirmed(ctx, payment, previousStatus)
+	}
+
+	return nil
+}
+
+// applyRefundWebhook persists a webhook-driven refund status transition
+func (s *paymentService) applyRefundWebhook(ctx context.Context, gatewayName string, event *gateway.WebhookEvent) error {
+	refund, err := s.paymentRepo.GetRefundByGatewayRefundID(ctx, event.GatewayRefundID)
+	if err != nil {
+		return fmt.Errorf("failed to find refund for webhook: %w", err)
+	}
+
+	newStatus := gateway.MapGatewayStatus(gatewayName, event.Status)
+
+	// Redelivered webhooks and late events for settled refunds are ignored
+	if refund.Status == newStatus || refund.Status == model.PaymentStatusSuccess || refund.Status == model.PaymentStatusFailed {
+		log.Info().
+			Str("refund_id", refund.ID.String()).
+			Str("current_status", refund.Status).
+			Str("webhook_status", newStatus).
+			Msg("Refund webhook already applied, skipping")
+		return nil
+	}
+
+	refund.Status = newStatus
+
+	processedAt := time.Now()
+	if event.ProcessedAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, event.ProcessedAt); err == nil {
+			processedAt = parsed
+		}
+	}
+	refund.ProcessedAt = &processedAt
+
+	if newStatus == model.PaymentStatusFailed {
+		refund.FailureReason = stringPtr(fmt.Sprintf("Refund failed via %s webhook", event.EventType))
+	}
+
+	if err := s.paymentRepo.UpdateRefund(ctx, refund, statusEvent(model.ActorWebhook+":"+gatewayName, &event.EventType, event)); err != nil {
+		return fmt.Errorf("failed to update refund from webhook: %w", err)
+	}
+
+	log.Info().
+		Str("refund_id", refund.ID.String()).
+		Str("payment_id", refund.PaymentID.String()).
+		Str("status", refund.Status).
+		Str("event_type", event.EventType).
+		Msg("Refund status updated from webhook")
 
 	return nil
 }
@@ -494,7 +882,7 @@ func (s *paymentService) RetryFailedPayment(ctx context.Context, paymentID uuid.
 	payment.FailureReason = nil
 	payment.UpdatedAt = time.Now()
 
-	if err := s.paymentRepo.Update(ctx, payment); err != nil {
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, stringPtr("retry requested"), nil)); err != nil {
 		return nil, fmt.Errorf("failed to update payment for retry: %w", err)
 	}
 
@@ -516,18 +904,25 @@ func (s *paymentService) RetryFailedPayment(ctx context.Context, paymentID uuid.
 	}
 
 	gatewayRequest := &gateway.PaymentRequest{
-		Amount:     payment.Amount,
-		Currency:   payment.Currency,
-		OrderID:    payment.ID.String(),
-		CustomerID: payment.UserID.String(),
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		OrderID:        payment.ID.String(),
+		CustomerID:     payment.UserID.String(),
+		IdempotencyKey: gatewayIdempotencyKey(payment.ID, attempt.AttemptNumber),
 	}
 
-	gatewayResponse, err := paymentGateway.InitiatePayment(ctx, gatewayRequest)
+	gatewayCtx, span := startGatewaySpan(ctx, "InitiatePayment", payment.Gateway)
+	gatewayResponse, err := paymentGateway.InitiatePayment(gatewayCtx, gatewayRequest)
+	tracing.AddSpanError(span, err)
+	span.End()
 	if err != nil {
+		s.gatewayMgr.ReportFailure(payment.Gateway)
+		metrics.RecordPaymentFailed(payment.Gateway)
+
 		// Update payment and attempt status to failed
 		payment.Status = model.PaymentStatusFailed
 		payment.FailureReason = stringPtr(err.Error())
-		s.paymentRepo.Update(ctx, payment)
+		s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, payment.FailureReason, nil))
 
 		attempt.Status = model.PaymentStatusFailed
 		attempt.ErrorMessage = stringPtr(err.Error())
@@ -536,12 +931,15 @@ func (s *paymentService) RetryFailedPayment(ctx context.Context, paymentID uuid.
 		return nil, fmt.Errorf("failed to retry payment with gateway: %w", err)
 	}
 
+	s.gatewayMgr.ReportSuccess(payment.Gateway)
+	metrics.RecordPaymentInitiated(payment.Gateway)
+
 	// Update payment with new gateway response
 	payment.Status = model.PaymentStatusInitiated
 	payment.GatewayPaymentID = &gatewayResponse.GatewayPaymentID
 	payment.PaymentURL = &gatewayResponse.PaymentURL
 
-	if err := s.paymentRepo.Update(ctx, payment); err != nil {
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(model.ActorAPI, nil, gatewayResponse)); err != nil {
 		log.Error().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to update payment after retry")
 	}
 
@@ -551,6 +949,7 @@ func (s *paymentService) RetryFailedPayment(ctx context.Context, paymentID uuid.
 
 	response := &model.PaymentResponse{
 		Payment:    payment,
+		Gateway:    payment.Gateway,
 		PaymentURL: &gatewayResponse.PaymentURL,
 		Message:    "Payment retry initiated successfully",
 	}
@@ -563,6 +962,236 @@ func (s *paymentService) RetryFailedPayment(ctx context.Context, paymentID uuid.
 	return response, nil
 }
 
+// expirySweepBatchSize limits how many payments are expired per sweep
+const expirySweepBatchSize = 100
+
+// ExpireStalePayments fails pending and initiated payments that are past their expiry
+// and publishes a payment.expired event for each so the booking slot can be released
+func (s *paymentService) ExpireStalePayments(ctx context.Context) (int, error) {
+	payments, err := s.paymentRepo.ExpireStalePayments(ctx, "expired", model.ActorExpirySweeper, expirySweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, payment := range payments {
+		metrics.RecordPaymentFailed(payment.Gateway)
+		log.Info().
+			Str("payment_id", payment.ID.String()).
+			Str("booking_id", payment.BookingID.String()).
+			Msg("Payment expired")
+
+		s.publishEvent(ctx, &PaymentEvent{
+			Type:      EventPaymentExpired,
+			PaymentID: payment.ID,
+			BookingID: payment.BookingID,
+			UserID:    payment.UserID,
+			Data: map[string]interface{}{
+				"status":         payment.Status,
+				"failure_reason": "expired",
+				"amount":         payment.Amount,
+				"currency":       payment.Currency,
+			},
+			Timestamp: time.Now(),
+		})
+	}
+
+	return len(payments), nil
+}
+
+// reconcileBatchSize limits how many payments are reconciled per run
+const reconcileBatchSize = 100
+
+// ReconcileStalePayments syncs pending and initiated payments that have been
+// idle for the reconcile threshold with their gateway, catching payments whose
+// webhook was lost or whose initiation was interrupted. It returns the number
+// of payments whose status changed.
+func (s *paymentService) ReconcileStalePayments(ctx context.Context) (int, error) {
+	payments, err := s.paymentRepo.GetUnsettledPayments(ctx, s.config.ReconcileAfterMinutes, reconcileBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	reconciled := 0
+	for _, payment := range payments {
+		changed, err := s.reconcilePayment(ctx, payment, model.ActorReconciler)
+		if err != nil {
+			log.Warn().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to reconcile payment")
+			continue
+		}
+		if changed {
+			reconciled++
+		}
+	}
+
+	return reconciled, nil
+}
+
+// ReconcilePayment syncs one payment with its gateway on demand, e.g. when a
+// customer was charged but the payment is stuck in initiated. Reconciling a
+// payment that already matches the gateway changes nothing.
+func (s *paymentService) ReconcilePayment(ctx context.Context, paymentID uuid.UUID, actor string) (*model.ReconcileResponse, error) {
+	payment, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	previousStatus := payment.Status
+	changed, err := s.reconcilePayment(ctx, payment, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ReconcileResponse{
+		Payment:        payment,
+		PreviousStatus: previousStatus,
+		Status:         payment.Status,
+		Changed:        changed,
+	}, nil
+}
+
+// reconcilePayment brings a payment in line with the gateway and reports
+// whether its status changed. Only settled gateway states are applied; a
+// payment the gateway still awaits is left as it is. A payment that becomes
+// successful publishes a payment.confirmed event, as it does from a webhook.
+//
+// A pending payment without a gateway order, idle past the reconcile
+// threshold, is the compensation case: initiation was interrupted before the
+// order was recorded, so the client never received it and the payment cannot
+// complete. It is failed; any order the gateway did create is never paid and
+// lapses at the gateway.
+func (s *paymentService) reconcilePayment(ctx context.Context, payment *model.Payment, actor string) (bool, error) {
+	if payment.GatewayPaymentID == nil || *payment.GatewayPaymentID == "" {
+		idleFor := time.Duration(s.config.ReconcileAfterMinutes) * time.Minute
+		if payment.Status != model.PaymentStatusPending || time.Since(payment.UpdatedAt) < idleFor {
+			return false, nil
+		}
+		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = stringPtr("Payment initiation did not complete")
+		if err := s.paymentRepo.Update(ctx, payment, statusEvent(actor, payment.FailureReason, nil)); err != nil {
+			return false, err
+		}
+		metrics.RecordPaymentFailed(payment.Gateway)
+		log.Info().Str("payment_id", payment.ID.String()).Msg("Failed payment with interrupted initiation")
+		return true, nil
+	}
+
+	paymentGateway, err := s.gatewayMgr.GetGateway(payment.Gateway)
+	if err != nil {
+		return false, fmt.Errorf("gateway not available: %w", err)
+	}
+
+	gatewayCtx, span := startGatewaySpan(ctx, "GetPaymentStatus", payment.Gateway)
+	gatewayResponse, err := paymentGateway.GetPaymentStatus(gatewayCtx, *payment.GatewayPaymentID)
+	tracing.AddSpanError(span, err)
+	span.End()
+	if err != nil {
+		return false, fmt.Errorf("failed to get payment status from gateway: %w", err)
+	}
+
+	newStatus := gateway.MapGatewayStatus(payment.Gateway, gatewayResponse.Status)
+	settled := newStatus == model.PaymentStatusSuccess || newStatus == model.PaymentStatusFailed || newStatus == model.PaymentStatusCanceled
+	if !settled || !canApplyWebhookStatus(payment, newStatus) {
+		return false, nil
+	}
+
+	if newStatus == model.PaymentStatusSuccess &&
+		(!amountsMatch(payment.Amount, gatewayResponse.Amount) || !strings.EqualFold(payment.Currency, gatewayResponse.Currency)) {
+		s.failAmountMismatch(ctx, payment, gatewayResponse, actor)
+		return true, nil
+	}
+
+	previousStatus := payment.Status
+	payment.Status = newStatus
+	payment.GatewayPaymentID = &gatewayResponse.GatewayPaymentID
+	if gatewayResponse.PaymentMethod != "" {
+		payment.PaymentMethod = &gatewayResponse.PaymentMethod
+	}
+	if gatewayResponse.ProcessedAt != nil {
+		if processedTime, err := time.Parse(time.RFC3339, *gatewayResponse.ProcessedAt); err == nil {
+			payment.ProcessedAt = &processedTime
+		}
+	}
+	if newStatus != model.PaymentStatusSuccess {
+		payment.FailureReason = stringPtr(fmt.Sprintf("Payment %s at gateway", newStatus))
+	}
+
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(actor, stringPtr("reconciled with gateway"), gatewayResponse)); err != nil {
+		return false, fmt.Errorf("failed to update reconciled payment: %w", err)
+	}
+	recordPaymentOutcome(payment)
+
+	log.Info().
+		Str("payment_id", payment.ID.String()).
+		Str("previous_status", previousStatus).
+		Str("status", payment.Status).
+		Str("actor", actor).
+		Msg("Payment reconciled with gateway")
+
+	if payment.Status == model.PaymentStatusSuccess {
+		s.publishPaymentConfirmed(ctx, payment, previousStatus)
+	}
+
+	return true, nil
+}
+
+// publishPaymentConfirmed publishes a payment.confirmed event for a payment
+// that payment-service itself found successful, through a gateway webhook or
+// reconciliation, so its booking is confirmed even if the payment callback
+// never reaches booking-service
+func (s *paymentService) publishPaymentConfirmed(ctx context.Context, payment *model.Payment, previousStatus string) {
+	gatewayPaymentID := ""
+	if payment.GatewayPaymentID != nil {
+		gatewayPaymentID = *payment.GatewayPaymentID
+	}
+
+	s.publishEvent(ctx, &PaymentEvent{
+		Type:      EventPaymentConfirmed,
+		PaymentID: payment.ID,
+		BookingID: payment.BookingID,
+		UserID:    payment.UserID,
+		Data: map[string]interface{}{
+			"status":             payment.Status,
+			"previous_status":    previousStatus,
+			"gateway_payment_id": gatewayPaymentID,
+			"amount":             payment.Amount,
+			"currency":           payment.Currency,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// publishEvent publishes a payment event when a publisher is configured
+func (s *paymentService) publishEvent(ctx context.Context, event *PaymentEvent) {
+	if s.eventPublisher == nil {
+		return
+	}
+
+	if err := s.eventPublisher.Publish(ctx, event); err != nil {
+		log.Error().
+			Err(err).
+			Str("event_type", event.Type).
+			Str("payment_id", event.PaymentID.String()).
+			Msg("Failed to publish payment event")
+	}
+}
+
+// GetPaymentStats returns aggregate payment statistics for a date range
+func (s *paymentService) GetPaymentStats(ctx context.Context, from, to time.Time) (*model.PaymentStats, error) {
+	return s.paymentRepo.GetPaymentStats(ctx, from, to)
+}
+
+// GetPaymentStatsByGateway returns payment statistics for a date range grouped by gateway
+func (s *paymentService) GetPaymentStatsByGateway(ctx context.Context, from, to time.Time) ([]*model.GatewayPaymentStats, error) {
+	stats, err := s.paymentRepo.GetPaymentStatsByGateway(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		stats = []*model.GatewayPaymentStats{}
+	}
+	return stats, nil
+}
+
 // HealthCheck performs a health check
 func (s *paymentService) HealthCheck(ctx context.Context) (*model.HealthResponse, error) {
 	// Check database connectivity
@@ -582,12 +1211,112 @@ func (s *paymentService) HealthCheck(ctx context.Context) (*model.HealthResponse
 
 // Helper functions
 
-func (s *paymentService) cacheIdempotencyResponse(ctx context.Context, request *model.InitiatePaymentRequest, paymentID uuid.UUID, response *model.PaymentResponse) {
-	// Create request hash for validation
+// amountMatchTolerance absorbs float rounding when comparing amounts converted from minor units
+const amountMatchTolerance = 0.005
+
+// amountsMatch reports whether two amounts are equal within rounding tolerance
+func amountsMatch(expected, actual float64) bool {
+	return math.Abs(expected-actual) < amountMatchTolerance
+}
+
+// failAmountMismatch marks a payment failed because the gateway reported a
+// different amount or currency, recording the observed values for auditing
+func (s *paymentService) failAmountMismatch(ctx context.Context, payment *model.Payment, gatewayResponse *gateway.PaymentResponse, actor string) error {
+	reason := fmt.Sprintf("Gateway amount %.2f %s does not match expected %.2f %s",
+		gatewayResponse.Amount, gatewayResponse.Currency, payment.Amount, payment.Currency)
+
+	payment.Status = model.PaymentStatusFailed
+	payment.FailureReason = stringPtr(reason)
+	if err := s.paymentRepo.Update(ctx, payment, statusEvent(actor, payment.FailureReason, gatewayResponse)); err != nil {
+		log.Error().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to mark payment failed after amount mismatch")
+	}
+	metrics.RecordPaymentFailed(payment.Gateway)
+
+	attemptNumber := 1
+	if attempts, err := s.paymentRepo.GetAttemptsByPaymentID(ctx, payment.ID); err == nil {
+		attemptNumber = len(attempts) + 1
+	}
+
+	responseData, _ := json.Marshal(gatewayResponse)
+	attempt := &model.PaymentAttempt{
+		ID:            uuid.New(),
+		PaymentID:     payment.ID,
+		AttemptNumber: attemptNumber,
+		Gateway:       payment.Gateway,
+		Status:        model.PaymentStatusFailed,
+		ErrorMessage:  stringPtr(reason),
+		ResponseData:  stringPtr(string(responseData)),
+		AttemptedAt:   time.Now(),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.paymentRepo.CreateAttempt(ctx, attempt); err != nil {
+		log.Error().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to record amount mismatch attempt")
+	}
+
+	log.Warn().
+		Str("payment_id", payment.ID.String()).
+		Float64("expected_amount", payment.Amount).
+		Float64("gateway_amount", gatewayResponse.Amount).
+		Str("expected_currency", payment.Currency).
+		Str("gateway_currency", gatewayResponse.Currency).
+		Msg("Payment confirmation amount mismatch")
+
+	return errors.NewConflictError("payment", reason)
+}
+
+// statusEvent starts an audit record of a status change made by actor. The
+// repository fills in the transition; gatewayResponse, if any, is stored as a
+// JSON snapshot.
+func statusEvent(actor string, reason *string, gatewayResponse interface{}) *model.PaymentEvent {
+	event := &model.PaymentEvent{
+		Actor:  actor,
+		Reason: reason,
+	}
+	// Client secrets let anyone complete the payment, so they stay out of the audit trail
+	if response, ok := gatewayResponse.(*gateway.PaymentResponse); ok && response.Metadata["client_secret"] != nil {
+		redacted := *response
+		redacted.Metadata = make(map[string]interface{}, len(response.Metadata))
+		for key, value := range response.Metadata {
+			if key != "client_secret" {
+				redacted.Metadata[key] = value
+			}
+		}
+		gatewayResponse = &redacted
+	}
+	if gatewayResponse != nil {
+		if data, err := json.Marshal(gatewayResponse); err == nil {
+			event.GatewayResponse = stringPtr(string(data))
+		}
+	}
+	return event
+}
+
+// startGatewaySpan starts a client span around a call to a payment gateway
+func startGatewaySpan(ctx context.Context, operation, gatewayName string) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, "gateway."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("payment.gateway", gatewayName)),
+	)
+}
+
+// recordPaymentOutcome counts payments that reached a terminal status
+func recordPaymentOutcome(payment *model.Payment) {
+	switch payment.Status {
+	case model.PaymentStatusSuccess:
+		metrics.RecordPaymentConfirmed(payment.Gateway)
+	case model.PaymentStatusFailed:
+		metrics.RecordPaymentFailed(payment.Gateway)
+	}
+}
+
+// hashRequest returns the SHA-256 of the request JSON, used to detect idempotency key reuse
+func hashRequest(request interface{}) string {
 	requestData, _ := json.Marshal(request)
 	hash := sha256.Sum256(requestData)
-	requestHash := hex.EncodeToString(hash[:])
+	return hex.EncodeToString(hash[:])
+}
 
+func (s *paymentService) cacheIdempotencyResponse(ctx context.Context, request *model.InitiatePaymentRequest, requestHash string, paymentID uuid.UUID, response *model.PaymentResponse) {
 	// Cache response
 	responseData, _ := json.Marshal(response)
 	
@@ -606,6 +1335,37 @@ func (s *paymentService) cacheIdempotencyResponse(ctx context.Context, request *
 	}
 }
 
+func (s *paymentService) cacheRefundIdempotencyResponse(ctx context.Context, idempotencyKey, requestHash string, refund *model.Refund, response *model.RefundResponse) {
+	responseData, _ := json.Marshal(response)
+
+	record := &model.RefundIdempotencyRecord{
+		ID:             uuid.New(),
+		IdempotencyKey: idempotencyKey,
+		PaymentID:      refund.PaymentID,
+		RefundID:       refund.ID,
+		RequestHash:    requestHash,
+		ResponseData:   string(responseData),
+		ExpiresAt:      time.Now().Add(time.Duration(s.config.IdempotencyTTLHours) * time.Hour),
+		CreatedAt:      time.Now(),
+	}
+
+	if err := s.paymentRepo.CreateRefundIdempotencyRecord(ctx, record); err != nil {
+		log.Warn().Err(err).Str("idempotency_key", idempotencyKey).Msg("Failed to cache refund idempotency response")
+	}
+}
+
+// canApplyWebhookStatus reports whether a webhook status moves the payment forward.
+// A failed payment may still succeed when the customer retries against the same order.
+func canApplyWebhookStatus(payment *model.Payment, status string) bool {
+	if payment.Status == status {
+		return false
+	}
+	if payment.Status == model.PaymentStatusFailed && status == model.PaymentStatusSuccess {
+		return true
+	}
+	return !payment.IsTerminalStatus()
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"payment-service/internal/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ExpirySweeper periodically fails payments that expired before completion
+// and forgets webhook events past their dedupe window
+type ExpirySweeper struct {
+	paymentService service.PaymentService
+	interval       time.Duration
+}
+
+// NewExpirySweeper creates a new payment expiry sweeper
+func NewExpirySweeper(paymentService service.PaymentService, interval time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{
+		paymentService: paymentService,
+		interval:       interval,
+	}
+}
+
+// Start runs the sweeper until the context is cancelled
+func (w *ExpirySweeper) Start(ctx context.Context) {
+	log.Info().Dur("interval", w.interval).Msg("Starting payment expiry sweeper")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Payment expiry sweeper stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *ExpirySweeper) run(ctx context.Context) {
+	expired, err := w.paymentService.ExpireStalePayments(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to expire stale payments")
+	} else if expired > 0 {
+		log.Info().Int("count", expired).Msg("Expired stale payments")
+	}
+
+	cleaned, err := w.paymentService.CleanupWebhookEvents(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to clean up webhook events")
+	} else if cleaned > 0 {
+		log.Debug().Int("count", cleaned).Msg("Cleaned up expired webhook events")
+	}
+}
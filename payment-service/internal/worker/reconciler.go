@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"payment-service/internal/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Reconciler periodically syncs payments stuck in pending or initiated with
+// their gateway, settling payments whose webhook never arrived
+type Reconciler struct {
+	paymentService service.PaymentService
+	interval       time.Duration
+}
+
+// NewReconciler creates a new payment reconciler
+func NewReconciler(paymentService service.PaymentService, interval time.Duration) *Reconciler {
+	return &Reconciler{
+		paymentService: paymentService,
+		interval:       interval,
+	}
+}
+
+// Start runs the reconciler until the context is cancelled
+func (w *Reconciler) Start(ctx context.Context) {
+	log.Info().Dur("interval", w.interval).Msg("Starting payment reconciler")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Payment reconciler stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *Reconciler) run(ctx context.Context) {
+	reconciled, err := w.paymentService.ReconcileStalePayments(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reconcile payments")
+	} else if reconciled > 0 {
+		log.Info().Int("count", reconciled).Msg("Reconciled payments with gateways")
+	}
+}
@@ -0,0 +1,2 @@
+-- Index used by the payment expiry sweeper to find stale non-terminal payments
+CREATE INDEX IF NOT EXISTS idx_payments_status_expires_at ON payments(status, expires_at);
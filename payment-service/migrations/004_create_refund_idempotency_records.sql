@@ -0,0 +1,13 @@
+-- Create refund idempotency records table so replayed refund requests return the original refund
+CREATE TABLE IF NOT EXISTS refund_idempotency_records (
+    id UUID PRIMARY KEY,
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    refund_id UUID NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    request_hash VARCHAR(64) NOT NULL,
+    response_data JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refund_idempotency_records_expires_at ON refund_idempotency_records(expires_at);
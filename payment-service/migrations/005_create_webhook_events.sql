@@ -0,0 +1,11 @@
+-- Recently processed webhook events, kept for the webhook tolerance window so
+-- redelivered events are not applied twice
+CREATE TABLE IF NOT EXISTS webhook_events (
+    gateway VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (gateway, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_expires_at ON webhook_events(expires_at);
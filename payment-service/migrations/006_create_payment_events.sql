@@ -0,0 +1,15 @@
+-- Audit trail of payment and refund status transitions. Rows are written in
+-- the same transaction as the status change they describe.
+CREATE TABLE IF NOT EXISTS payment_events (
+    id UUID PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    refund_id UUID REFERENCES refunds(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT,
+    gateway_response JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_events_payment_id ON payment_events(payment_id, created_at);
@@ -10,11 +10,11 @@ import (
 
     sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
     sharedLogger "github.com/EricsAntony/salon/salon-shared/logger"
-    "github.com/jackc/pgx/v5/pgxpool"
     "github.com/rs/zerolog/log"
 
     "salon-service/internal/api"
     "salon-service/internal/config"
+    "salon-service/internal/db"
     "salon-service/internal/repository"
     "salon-service/internal/service"
 )
@@ -24,13 +24,16 @@ func main() {
     if err != nil {
         log.Fatal().Err(err).Msg("failed to load config")
     }
+    if err := cfg.Validate(); err != nil {
+        log.Fatal().Err(err).Msg("invalid config")
+    }
 
     sharedCfg := cfg.ToShared()
 
     sharedLogger.Init(sharedCfg)
     log.Info().Str("service", sharedCfg.Log.ServiceName).Msg("starting service")
 
-    pool, err := pgxpool.New(context.Background(), sharedCfg.DB.URL)
+    pool, err := db.NewPool(context.Background(), sharedCfg.DB.URL, cfg.DBPool())
     if err != nil {
         log.Fatal().Err(err).Msg("failed to connect to database")
     }
@@ -39,9 +42,18 @@ func main() {
     store := repository.NewStore(pool)
     staffAuthRepo := repository.NewStaffAuthRepository(pool)
     jwtManager := sharedAuth.NewJWTManager(sharedCfg)
+    jwtManager.SetRevocationStore(sharedAuth.NewRevocationCache(repository.NewRevocationStore(pool), sharedAuth.DefaultRevocationCacheTTL))
 
-    svc := service.New(store, staffAuthRepo, sharedCfg, jwtManager)
-    handler := api.NewHandler(sharedCfg, svc, store)
+    lockoutStore := repository.NewLockoutStore(pool)
+    otpDispatcher, err := sharedAuth.NewOTPDispatcher(cfg.SMS)
+    if err != nil {
+        log.Fatal().Err(err).Msg("failed to configure OTP provider")
+    }
+    if _, ok := otpDispatcher.Sender().(sharedAuth.LogOTPSender); ok && !sharedCfg.IsDevelopment() {
+        log.Warn().Str("env", sharedCfg.Env).Msg("OTP codes are only logged; configure an SMS provider")
+    }
+    svc := service.New(store, staffAuthRepo, lockoutStore, sharedCfg, jwtManager, cfg.OTPPolicy(), otpDispatcher)
+    handler := api.NewHandler(sharedCfg, svc, store, jwtManager, cfg.OTPRateLimit(), cfg.RequestBodyLimit())
 
     router := handler.Routes()
 
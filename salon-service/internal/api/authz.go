@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
+	sharedMiddleware "github.com/EricsAntony/salon/salon-shared/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"salon-service/internal/model"
+	"salon-service/internal/repository"
+)
+
+type ctxKey string
+
+const ctxActingStaff ctxKey = "acting_staff"
+
+// StaffStore is the part of the repository the handler reads directly to
+// scope requests to a salon and to check the acting staff's role
+type StaffStore interface {
+	sharedMiddleware.SalonRepository
+	GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error)
+	GetStaffByID(ctx context.Context, staffID string) (*model.Staff, error)
+}
+
+// loadActingStaff looks up the authenticated staff member within the salon in
+// the path and stores it in the request context for role checks. It must run
+// after SalonScopedMiddleware has confirmed the staff belongs to the salon.
+func (h *Handler) loadActingStaff(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		staffID, _ := r.Context().Value(sharedAuth.CtxUserID).(string)
+		salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+
+		staff, err := h.store.GetStaff(r.Context(), salonID, staffID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				writeError(w, http.StatusForbidden, "access denied to salon")
+				return
+			}
+			log.Error().Err(err).Str("staff_id", staffID).Str("salon_id", salonID).Msg("failed to load acting staff")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxActingStaff, staff)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// actingStaff returns the staff member loaded by loadActingStaff
+func actingStaff(r *http.Request) *model.Staff {
+	staff, _ := r.Context().Value(ctxActingStaff).(*model.Staff)
+	return staff
+}
+
+// requireManager restricts a route to owners and managers
+func (h *Handler) requireManager(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		staff := actingStaff(r)
+		if staff == nil || !staff.CanManageSalon() {
+			denyRole(w, r, staff, "only salon owners and managers can perform this action")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireSelfOrManager lets owners and managers act on any staff member and
+// other staff act only on their own record
+func (h *Handler) requireSelfOrManager(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		staff := actingStaff(r)
+		targetID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+		if staff == nil || (!staff.CanManageSalon() && staff.ID != targetID) {
+			denyRole(w, r, staff, "stylists can only update their own profile")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorizeStaffChanges checks the role and status fields of a staff create or
+// update request against the acting staff's privileges. Stylists may not change
+// their own role or status, and only owners may grant the owner role.
+func authorizeStaffChanges(actor, current *model.Staff, role *string, status model.StaffStatus) string {
+	if role != nil && *role == model.StaffRoleOwner && actor.EffectiveRole() != model.StaffRoleOwner {
+		if current == nil || current.EffectiveRole() != model.StaffRoleOwner {
+			return "only salon owners can assign the owner role"
+		}
+	}
+
+	if actor.CanManageSalon() || current == nil {
+		return ""
+	}
+
+	if stringValue(role) != stringValue(current.Role) {
+		return "stylists cannot change their own role"
+	}
+	if status != current.Status {
+		return "stylists cannot change their own status"
+	}
+	return ""
+}
+
+// includeDeleted reports whether the request asked for soft-deleted records
+// with ?include_deleted=true. Only owners and managers may see deleted records;
+// other callers get a 403 and ok is false. On routes outside a salon the caller
+// is looked up by the authenticated staff ID.
+func (h *Handler) includeDeleted(w http.ResponseWriter, r *http.Request) (include, ok bool) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, true
+	}
+
+	staff := actingStaff(r)
+	if staff == nil {
+		staffID, _ := r.Context().Value(sharedAuth.CtxUserID).(string)
+		var err error
+		staff, err = h.store.GetStaffByID(r.Context(), staffID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Error().Err(err).Str("staff_id", staffID).Msg("failed to load staff for include_deleted")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return false, false
+		}
+	}
+	if staff == nil || !staff.CanManageSalon() {
+		denyRole(w, r, staff, "only salon owners and managers can view deleted records")
+		return false, false
+	}
+	return true, true
+}
+
+func denyRole(w http.ResponseWriter, r *http.Request, staff *model.Staff, message string) {
+	event := log.Warn().Str("path", r.URL.Path).Str("method", r.Method)
+	if staff != nil {
+		event = event.Str("staff_id", staff.ID).Str("role", staff.EffectiveRole())
+	}
+	event.Msg("staff role denied")
+	writeError(w, http.StatusForbidden, message)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
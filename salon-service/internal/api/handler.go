@@ -2,40 +2,51 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
 	sharedConfig "github.com/EricsAntony/salon/salon-shared/config"
 	sharedErrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/hours"
 	"github.com/EricsAntony/salon/salon-shared/logger"
 	sharedMiddleware "github.com/EricsAntony/salon/salon-shared/middleware"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
+	"github.com/EricsAntony/salon/salon-shared/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog/log"
 	"salon-service/internal/model"
-	"salon-service/internal/repository"
 	"salon-service/internal/service"
 )
 
 type Handler struct {
 	svc         service.SalonService
 	jwt         *sharedAuth.JWTManager
-	store       *repository.Store
+	store       StaffStore
 	rateLimiter *sharedMiddleware.RateLimiter
+	bodyLimit   requestbody.Config
+	// exposeOTP echoes staff OTP codes in responses; only set outside production
+	exposeOTP bool
 }
 
-func NewHandler(cfg *sharedConfig.Config, svc service.SalonService, store *repository.Store) *Handler {
+func NewHandler(cfg *sharedConfig.Config, svc service.SalonService, store StaffStore, jwt *sharedAuth.JWTManager, otpLimit sharedMiddleware.RateLimitConfig, bodyLimit requestbody.Config) *Handler {
 	logger.Init(cfg)
-	// Create rate limiter: 5 OTP requests per 15 minutes per IP
-	rateLimiter := sharedMiddleware.NewRateLimiter(5, 15*time.Minute)
+	// Rate limit OTP requests per client IP; per phone limits are enforced by the service
+	rateLimiter := sharedMiddleware.NewRateLimiter(otpLimit.Limit, otpLimit.Window)
 	
 	return &Handler{
 		svc:         svc,
-		jwt:         sharedAuth.NewJWTManager(cfg),
+		jwt:         jwt,
 		store:       store,
 		rateLimiter: rateLimiter,
+		bodyLimit:   bodyLimit,
+		exposeOTP:   cfg.IsDevelopment(),
 	}
 }
 
@@ -45,6 +56,8 @@ func (h *Handler) Routes() *chi.Mux {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Logger)
+	// Bodies are limited before the audit middleware reads them
+	r.Use(requestbody.Middleware(h.bodyLimit))
 	
 	// Use shared audit middleware with salon-service configuration
 	auditConfig := sharedMiddleware.DefaultAuditConfig("salon-service")
@@ -64,51 +77,71 @@ func (h *Handler) Routes() *chi.Mux {
 		// Use salon-specific middleware for protected routes
 		r.Use(sharedMiddleware.SalonUserMiddleware(h.jwt))
 		r.Route("/salons", func(r chi.Router) {
-			// Apply salon-scoped authorization
-			r.Use(sharedMiddleware.SalonScopedMiddleware(h.store))
 			r.Post("/", h.createSalon)
 			r.Get("/", h.listSalons)
+			r.Get("/search", h.searchSalons)
 			r.Route("/{salonID}", func(r chi.Router) {
+				// Apply salon-scoped authorization once the salon ID is routed,
+				// then load the acting staff for role checks
+				r.Use(sharedMiddleware.SalonScopedMiddleware(h.store))
+				r.Use(h.loadActingStaff)
+
 				r.Get("/", h.getSalon)
-				r.Put("/", h.updateSalon)
-				r.Delete("/", h.deleteSalon)
+				r.With(h.requireManager).Put("/", h.updateSalon)
+				r.With(h.requireManager).Delete("/", h.deleteSalon)
+				r.With(h.requireManager).Post("/restore", h.restoreSalon)
+				r.With(h.requireManager).Put("/hours", h.setSalonHours)
+				r.Get("/hours/effective", h.getEffectiveHours)
 
 				r.Route("/branches", func(r chi.Router) {
-					r.Post("/", h.createBranch)
+					r.With(h.requireManager).Post("/", h.createBranch)
 					r.Get("/", h.listBranches)
 					r.Route("/{branchID}", func(r chi.Router) {
 						r.Get("/", h.getBranch)
-						r.Put("/", h.updateBranch)
-						r.Delete("/", h.deleteBranch)
+						r.Get("/staff", h.listBranchStaff)
+						r.With(h.requireManager).Put("/", h.updateBranch)
+						r.With(h.requireManager).Delete("/", h.deleteBranch)
+						r.With(h.requireManager).Post("/restore", h.restoreBranch)
+						r.With(h.requireManager).Put("/hours", h.setBranchHours)
+						r.Get("/hours/effective", h.getEffectiveHours)
 					})
 				})
 
 				r.Route("/categories", func(r chi.Router) {
-					r.Post("/", h.createCategory)
+					r.With(h.requireManager).Post("/", h.createCategory)
 					r.Get("/", h.listCategories)
 					r.Route("/{categoryID}", func(r chi.Router) {
-						r.Put("/", h.updateCategory)
-						r.Delete("/", h.deleteCategory)
+						r.With(h.requireManager).Put("/", h.updateCategory)
+						r.With(h.requireManager).Delete("/", h.deleteCategory)
 					})
 				})
 
 				r.Route("/services", func(r chi.Router) {
-					r.Post("/", h.createService)
+					r.With(h.requireManager).Post("/", h.createService)
 					r.Get("/", h.listServices)
 					r.Route("/{serviceID}", func(r chi.Router) {
-						r.Put("/", h.updateService)
-						r.Delete("/", h.deleteService)
+						r.With(h.requireManager).Put("/", h.updateService)
+						r.With(h.requireManager).Delete("/", h.deleteService)
 					})
 				})
 
 				r.Route("/staff", func(r chi.Router) {
-					r.Post("/", h.createStaff)
+					r.With(h.requireManager).Post("/", h.createStaff)
 					r.Get("/", h.listStaff)
 					r.Route("/{staffID}", func(r chi.Router) {
-						r.Put("/", h.updateStaff)
-						r.Delete("/", h.deleteStaff)
-						r.Post("/services", h.setStaffServices)
+						r.Get("/", h.getStaff)
+						r.With(h.requireSelfOrManager).Put("/", h.updateStaff)
+						r.With(h.requireManager).Delete("/", h.deleteStaff)
+						r.With(h.requireManager).Post("/restore", h.restoreStaff)
+						r.With(h.requireManager).Put("/branch", h.setStaffBranch)
+						r.With(h.requireManager).Post("/services", h.setStaffServices)
 						r.Get("/services", h.listStaffServices)
+						r.With(h.requireManager).Put("/services/{serviceID}", h.addStaffService)
+						r.With(h.requireManager).Delete("/services/{serviceID}", h.removeStaffService)
+						r.Get("/schedule", h.getStaffSchedule)
+						r.With(h.requireSelfOrManager).Post("/time-off", h.createStaffTimeOff)
+						r.Get("/time-off", h.listStaffTimeOff)
+						r.With(h.requireSelfOrManager).Delete("/time-off/{timeOffID}", h.deleteStaffTimeOff)
 					})
 				})
 			})
@@ -137,7 +170,7 @@ func (h *Handler) ready(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) createSalon(w http.ResponseWriter, r *http.Request) {
 	var req createSalonRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := createSalonRequest(req).toCreateParams()
@@ -160,7 +193,16 @@ func (h *Handler) getSalon(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) listSalons(w http.ResponseWriter, r *http.Request) {
-	salons, err := h.svc.ListSalons(r.Context())
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeDeleted, ok := h.includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	salons, err := h.svc.ListSalons(r.Context(), page, includeDeleted)
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -172,7 +214,7 @@ func (h *Handler) updateSalon(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	var req updateSalonRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := updateSalonRequest(req).toUpdateParams(id)
@@ -193,11 +235,21 @@ func (h *Handler) deleteSalon(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) restoreSalon(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	salon, err := h.svc.RestoreSalon(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, salon)
+}
+
 func (h *Handler) createBranch(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	var req createBranchRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := createBranchRequest(req).toCreateParams(salonID)
@@ -222,7 +274,11 @@ func (h *Handler) getBranch(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) listBranches(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
-	branches, err := h.svc.ListBranches(r.Context(), salonID)
+	includeDeleted, ok := h.includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	branches, err := h.svc.ListBranches(r.Context(), salonID, includeDeleted)
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -235,7 +291,7 @@ func (h *Handler) updateBranch(w http.ResponseWriter, r *http.Request) {
 	branchID := strings.TrimSpace(chi.URLParam(r, "branchID"))
 	var req updateBranchRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := updateBranchRequest(req).toUpdateParams(salonID, branchID)
@@ -257,11 +313,22 @@ func (h *Handler) deleteBranch(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) restoreBranch(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	branchID := strings.TrimSpace(chi.URLParam(r, "branchID"))
+	branch, err := h.svc.RestoreBranch(r.Context(), salonID, branchID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, branch)
+}
+
 func (h *Handler) createCategory(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	var req createCategoryRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := req.toCreateParams(salonID)
@@ -288,7 +355,7 @@ func (h *Handler) updateCategory(w http.ResponseWriter, r *http.Request) {
 	categoryID := strings.TrimSpace(chi.URLParam(r, "categoryID"))
 	var req updateCategoryRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := req.toUpdateParams(salonID, categoryID)
@@ -314,7 +381,7 @@ func (h *Handler) createService(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	var req createServiceRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := req.toCreateParams(salonID)
@@ -326,13 +393,83 @@ func (h *Handler) createService(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, service)
 }
 
+// searchSalons handles GET /salons/search?lat=&lng=&radius=, with radius in
+// kilometres and optional service_id or category_id filters
+func (h *Handler) searchSalons(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lat, err := parseFloatQuery(query, "lat")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lng, err := parseFloatQuery(query, "lng")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if lat == nil || lng == nil {
+		writeError(w, http.StatusBadRequest, "lat and lng are required")
+		return
+	}
+	radius, err := parseFloatQuery(query, "radius")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	radiusKm := 0.0
+	if radius != nil {
+		if *radius <= 0 {
+			writeError(w, http.StatusBadRequest, "radius must be greater than 0")
+			return
+		}
+		radiusKm = *radius
+	}
+	page, err := pagination.Parse(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var filter model.NearbySalonFilter
+	if val := strings.TrimSpace(query.Get("service_id")); val != "" {
+		filter.ServiceID = &val
+	}
+	if val := strings.TrimSpace(query.Get("category_id")); val != "" {
+		filter.CategoryID = &val
+	}
+
+	salons, err := h.svc.SearchSalonsNearby(r.Context(), *lat, *lng, radiusKm, filter, page)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, salons)
+}
+
 func (h *Handler) listServices(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
-	var categoryID *string
-	if val := strings.TrimSpace(r.URL.Query().Get("category_id")); val != "" {
-		categoryID = &val
+	query := r.URL.Query()
+	filter := model.ServiceFilter{
+		Name: strings.TrimSpace(query.Get("name")),
+		Tags: splitQueryList(query.Get("tags")),
+	}
+	if val := strings.TrimSpace(query.Get("category_id")); val != "" {
+		filter.CategoryID = &val
+	}
+	page, err := pagination.Parse(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.MinPrice, err = parseFloatQuery(query, "min_price"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.MaxPrice, err = parseFloatQuery(query, "max_price"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	services, err := h.svc.ListServices(r.Context(), salonID, categoryID)
+	services, err := h.svc.ListServicesFiltered(r.Context(), salonID, filter, page)
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -345,7 +482,7 @@ func (h *Handler) updateService(w http.ResponseWriter, r *http.Request) {
 	serviceID := strings.TrimSpace(chi.URLParam(r, "serviceID"))
 	var req updateServiceRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	params := req.toUpdateParams(salonID, serviceID)
@@ -371,7 +508,11 @@ func (h *Handler) createStaff(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	var req createStaffRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
+		return
+	}
+	if msg := authorizeStaffChanges(actingStaff(r), nil, req.Role, req.Status); msg != "" {
+		writeError(w, http.StatusForbidden, msg)
 		return
 	}
 	params := req.toCreateParams(salonID)
@@ -385,12 +526,62 @@ func (h *Handler) createStaff(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) listStaff(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
-	var status *model.StaffStatus
-	if val := strings.TrimSpace(r.URL.Query().Get("status")); val != "" {
+	query := r.URL.Query()
+	filter := model.StaffFilter{
+		Name:           strings.TrimSpace(query.Get("name")),
+		Specialization: strings.TrimSpace(query.Get("specialization")),
+	}
+	if val := strings.TrimSpace(query.Get("status")); val != "" {
 		st := model.StaffStatus(val)
-		status = &st
+		filter.Status = &st
 	}
-	staff, err := h.svc.ListStaff(r.Context(), salonID, status)
+	if val := strings.TrimSpace(query.Get("branch_id")); val != "" {
+		filter.BranchID = &val
+	}
+	includeDeleted, ok := h.includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	filter.IncludeDeleted = includeDeleted
+	staff, err := h.svc.ListStaffFiltered(r.Context(), salonID, filter)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, staff)
+}
+
+func (h *Handler) getStaff(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	staff, err := h.svc.GetStaff(r.Context(), salonID, staffID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, staff)
+}
+
+func (h *Handler) listBranchStaff(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	branchID := strings.TrimSpace(chi.URLParam(r, "branchID"))
+	staff, err := h.svc.ListStaffFiltered(r.Context(), salonID, model.StaffFilter{BranchID: &branchID})
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, staff)
+}
+
+func (h *Handler) setStaffBranch(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	var req setStaffBranchRequest
+	if err := decodeRequest(r, &req); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	staff, err := h.svc.AssignStaffBranch(r.Context(), salonID, staffID, req.BranchID)
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -403,7 +594,16 @@ func (h *Handler) updateStaff(w http.ResponseWriter, r *http.Request) {
 	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
 	var req updateStaffRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
+		return
+	}
+	actor := actingStaff(r)
+	current := actor
+	if actor.ID != staffID {
+		current, _ = h.store.GetStaff(r.Context(), salonID, staffID)
+	}
+	if msg := authorizeStaffChanges(actor, current, req.Role, req.Status); msg != "" {
+		writeError(w, http.StatusForbidden, msg)
 		return
 	}
 	params := req.toUpdateParams(salonID, staffID)
@@ -425,12 +625,23 @@ func (h *Handler) deleteStaff(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) restoreStaff(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	staff, err := h.svc.RestoreStaff(r.Context(), salonID, staffID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, staff)
+}
+
 func (h *Handler) setStaffServices(w http.ResponseWriter, r *http.Request) {
 	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
 	var req setStaffServicesRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	if err := h.svc.SetStaffServices(r.Context(), salonID, staffID, req.ServiceIDs); err != nil {
@@ -441,8 +652,33 @@ func (h *Handler) setStaffServices(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) listStaffServices(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	ids, err := h.svc.ListStaffServices(r.Context(), salonID, staffID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"service_ids": ids})
+}
+
+func (h *Handler) addStaffService(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	serviceID := strings.TrimSpace(chi.URLParam(r, "serviceID"))
+	ids, err := h.svc.AddStaffService(r.Context(), salonID, staffID, serviceID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"service_ids": ids})
+}
+
+func (h *Handler) removeStaffService(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
 	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
-	ids, err := h.svc.ListStaffServices(r.Context(), staffID)
+	serviceID := strings.TrimSpace(chi.URLParam(r, "serviceID"))
+	ids, err := h.svc.RemoveStaffService(r.Context(), salonID, staffID, serviceID)
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -450,23 +686,159 @@ func (h *Handler) listStaffServices(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"service_ids": ids})
 }
 
+func (h *Handler) getStaffSchedule(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	query := r.URL.Query()
+
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(query.Get("date")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+		return
+	}
+	var branchID *string
+	if val := strings.TrimSpace(query.Get("branch_id")); val != "" {
+		branchID = &val
+	}
+
+	schedule, err := h.svc.GetStaffSchedule(r.Context(), salonID, staffID, branchID, date)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+func (h *Handler) createStaffTimeOff(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	var req createStaffTimeOffRequest
+	if err := decodeRequest(r, &req); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	timeOff, err := h.svc.CreateStaffTimeOff(r.Context(), service.CreateStaffTimeOffParams{
+		SalonID:   salonID,
+		StaffID:   staffID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, timeOff)
+}
+
+// listStaffTimeOff lists time off ending on or after ?from= (YYYY-MM-DD),
+// which defaults to today
+func (h *Handler) listStaffTimeOff(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if val := strings.TrimSpace(r.URL.Query().Get("from")); val != "" {
+		parsed, err := time.Parse("2006-01-02", val)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "from must be in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	blocks, err := h.svc.ListStaffTimeOff(r.Context(), salonID, staffID, from)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+func (h *Handler) deleteStaffTimeOff(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	staffID := strings.TrimSpace(chi.URLParam(r, "staffID"))
+	timeOffID := strings.TrimSpace(chi.URLParam(r, "timeOffID"))
+	if err := h.svc.DeleteStaffTimeOff(r.Context(), salonID, staffID, timeOffID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) setSalonHours(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	var req hours.WorkingHours
+	if err := decodeRequest(r, &req); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	salon, err := h.svc.SetSalonHours(r.Context(), salonID, req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hours.FromMap(salon.WorkingHours))
+}
+
+func (h *Handler) setBranchHours(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	branchID := strings.TrimSpace(chi.URLParam(r, "branchID"))
+	var req hours.WorkingHours
+	if err := decodeRequest(r, &req); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	branch, err := h.svc.SetBranchHours(r.Context(), salonID, branchID, req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hours.FromMap(branch.WorkingHours))
+}
+
+// getEffectiveHours serves both the salon and the branch effective hours
+// routes; the branch is taken from the path when present
+func (h *Handler) getEffectiveHours(w http.ResponseWriter, r *http.Request) {
+	salonID := strings.TrimSpace(chi.URLParam(r, "salonID"))
+	date, err := time.Parse(hours.DateLayout, strings.TrimSpace(r.URL.Query().Get("date")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+		return
+	}
+	var branchID *string
+	if val := strings.TrimSpace(chi.URLParam(r, "branchID")); val != "" {
+		branchID = &val
+	}
+
+	effective, err := h.svc.GetEffectiveHours(r.Context(), salonID, branchID, date)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, effective)
+}
+
 func (h *Handler) requestStaffOTP(w http.ResponseWriter, r *http.Request) {
 	var req requestStaffOTPRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
-	if err := h.svc.RequestStaffOTP(r.Context(), service.RequestStaffOTPParams{PhoneNumber: req.PhoneNumber}); err != nil {
+	sent, err := h.svc.RequestStaffOTP(r.Context(), service.RequestStaffOTPParams{PhoneNumber: req.PhoneNumber, Channel: req.Channel})
+	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusAccepted, map[string]any{"success": true})
+	resp := map[string]any{"success": true, "channel": sent.Channel}
+	if h.exposeOTP {
+		resp["code"] = sent.Code
+	}
+	writeJSON(w, http.StatusAccepted, resp)
 }
 
 func (h *Handler) authenticateStaff(w http.ResponseWriter, r *http.Request) {
 	var req authenticateStaffRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	res, err := h.svc.AuthenticateStaff(r.Context(), service.AuthenticateStaffParams{PhoneNumber: req.PhoneNumber, OTP: req.OTP})
@@ -480,7 +852,7 @@ func (h *Handler) authenticateStaff(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) refreshStaffSession(w http.ResponseWriter, r *http.Request) {
 	var req refreshStaffSessionRequest
 	if err := decodeRequest(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		handleServiceError(w, err)
 		return
 	}
 	claims, err := h.jwt.ValidateRefreshToken(req.RefreshToken)
@@ -496,12 +868,35 @@ func (h *Handler) refreshStaffSession(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+// decodeRequest decodes a JSON body, rejecting fields the request type does
+// not declare
 func decodeRequest(r *http.Request, v any) error {
 	defer r.Body.Close()
-	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
-		return err
+	return utils.DecodeJSON(r.Body, v)
+}
+
+// splitQueryList splits a comma-separated query value, dropping empty entries
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return nil
+	return items
+}
+
+// parseFloatQuery parses an optional numeric query parameter
+func parseFloatQuery(query url.Values, key string) (*float64, error) {
+	val := strings.TrimSpace(query.Get(key))
+	if val == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", key)
+	}
+	return &f, nil
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -511,17 +906,11 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 }
 
 func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, map[string]any{
-		"success": false,
-		"error": map[string]any{
-			"code":    status,
-			"type":    http.StatusText(status),
-			"message": message,
-		},
-	})
+	sharedErrors.WriteError(w, status, message)
 }
 
+// handleServiceError writes err in the shared error envelope; lockouts carry
+// Retry-After and retry_after_seconds
 func handleServiceError(w http.ResponseWriter, err error) {
-	// Use shared error handling
 	sharedErrors.WriteAPIError(w, err)
 }
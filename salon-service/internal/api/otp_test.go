@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
+	sharedConfig "github.com/EricsAntony/salon/salon-shared/config"
+	sharedMiddleware "github.com/EricsAntony/salon/salon-shared/middleware"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"salon-service/internal/service"
+)
+
+const testStaffOTPCode = "731904"
+
+// fakeStaffOTPService returns a fixed OTP. Other methods panic if called.
+type fakeStaffOTPService struct {
+	service.SalonService
+}
+
+func (fakeStaffOTPService) RequestStaffOTP(ctx context.Context, params service.RequestStaffOTPParams) (*service.StaffOTPSent, error) {
+	return &service.StaffOTPSent{Code: testStaffOTPCode, Channel: "sms"}, nil
+}
+
+func TestRequestStaffOTPEchoesCodeOnlyOutsideProduction(t *testing.T) {
+	tests := []struct {
+		env      string
+		wantCode bool
+	}{
+		{env: "production", wantCode: false},
+		{env: "staging", wantCode: false},
+		{env: "", wantCode: false},
+		{env: "local", wantCode: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("env "+tt.env, func(t *testing.T) {
+			cfg := &sharedConfig.Config{Env: tt.env}
+			routes := NewHandler(cfg, fakeStaffOTPService{}, &fakeStaffStore{}, sharedAuth.NewJWTManager(cfg),
+				sharedMiddleware.RateLimitConfig{Limit: 10, Window: time.Minute}, requestbody.Config{}).Routes()
+
+			// NewHandler initializes the global logger, so capture it afterwards
+			var logs bytes.Buffer
+			previous, previousLevel := log.Logger, zerolog.GlobalLevel()
+			log.Logger = zerolog.New(&logs)
+			zerolog.SetGlobalLevel(zerolog.TraceLevel)
+			t.Cleanup(func() {
+				log.Logger = previous
+				zerolog.SetGlobalLevel(previousLevel)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/staff/otp", strings.NewReader(`{"phone_number":"+919876543210"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			routes.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("POST /staff/otp = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+			}
+			if got := strings.Contains(rec.Body.String(), testStaffOTPCode); got != tt.wantCode {
+				t.Errorf("response contains the code = %v, want %v: %s", got, tt.wantCode, rec.Body.String())
+			}
+			if logs.Len() == 0 {
+				t.Fatal("request wrote no logs to check")
+			}
+			if strings.Contains(logs.String(), testStaffOTPCode) {
+				t.Errorf("logs contain the OTP code: %s", logs.String())
+			}
+		})
+	}
+}
@@ -71,11 +71,13 @@ type createServiceRequest struct {
 	Price           float64                `json:"price"`
 	Tags            []string               `json:"tags,omitempty"`
 	Status          model.ServiceStatus    `json:"status"`
+	TaxExempt       bool                   `json:"tax_exempt"`
 }
 
 type updateServiceRequest createServiceRequest
 
 type createStaffRequest struct {
+	BranchID       *string             `json:"branch_id,omitempty"`
 	Name           string              `json:"name"`
 	PhoneNumber    string              `json:"phone_number"`
 	Email          *string             `json:"email,omitempty"`
@@ -83,17 +85,28 @@ type createStaffRequest struct {
 	Specialization *string             `json:"specialization,omitempty"`
 	Photo          *string             `json:"photo,omitempty"`
 	Status         model.StaffStatus   `json:"status"`
-	Shifts         map[string]any      `json:"shifts,omitempty"`
+	Shifts         model.Shifts        `json:"shifts,omitempty"`
 }
 
 type updateStaffRequest createStaffRequest
 
+type setStaffBranchRequest struct {
+	BranchID *string `json:"branch_id"`
+}
+
 type setStaffServicesRequest struct {
 	ServiceIDs []string `json:"service_ids"`
 }
 
+type createStaffTimeOffRequest struct {
+	StartDate string  `json:"start_date"`
+	EndDate   string  `json:"end_date"`
+	Reason    *string `json:"reason,omitempty"`
+}
+
 type requestStaffOTPRequest struct {
 	PhoneNumber string `json:"phone_number"`
+	Channel     string `json:"channel,omitempty"`
 }
 
 type authenticateStaffRequest struct {
@@ -206,6 +219,7 @@ func (r createServiceRequest) toCreateParams(salonID string) service.CreateServi
 		Price:           r.Price,
 		Tags:            r.Tags,
 		Status:          r.Status,
+		TaxExempt:       r.TaxExempt,
 	}
 }
 
@@ -220,12 +234,14 @@ func (r updateServiceRequest) toUpdateParams(salonID, id string) service.UpdateS
 		Price:           r.Price,
 		Tags:            r.Tags,
 		Status:          r.Status,
+		TaxExempt:       r.TaxExempt,
 	}
 }
 
 func (r createStaffRequest) toCreateParams(salonID string) service.CreateStaffParams {
 	return service.CreateStaffParams{
 		SalonID:        salonID,
+		BranchID:       r.BranchID,
 		Name:           r.Name,
 		PhoneNumber:    r.PhoneNumber,
 		Email:          r.Email,
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
+	sharedConfig "github.com/EricsAntony/salon/salon-shared/config"
+	sharedMiddleware "github.com/EricsAntony/salon/salon-shared/middleware"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
+	"github.com/google/uuid"
+	"salon-service/internal/model"
+	"salon-service/internal/repository"
+	"salon-service/internal/service"
+)
+
+// fakeStaffStore keeps staff in memory keyed by ID
+type fakeStaffStore struct {
+	staff map[string]*model.Staff
+}
+
+func (f *fakeStaffStore) StaffHasAccessToSalon(ctx context.Context, staffID, salonID string) (bool, error) {
+	staff, ok := f.staff[staffID]
+	return ok && staff.SalonID == salonID, nil
+}
+
+func (f *fakeStaffStore) GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error) {
+	staff, ok := f.staff[staffID]
+	if !ok || staff.SalonID != salonID {
+		return nil, repository.ErrNotFound
+	}
+	return staff, nil
+}
+
+func (f *fakeStaffStore) GetStaffByID(ctx context.Context, staffID string) (*model.Staff, error) {
+	staff, ok := f.staff[staffID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return staff, nil
+}
+
+// fakeStaffServices implements the staff service assignments of
+// service.SalonService the way the real service scopes them: staff outside
+// the salon in the path are not found. Other methods panic if called.
+type fakeStaffServices struct {
+	service.SalonService
+
+	store *fakeStaffStore
+	mu    sync.Mutex
+	// assigned holds service IDs by staff ID
+	assigned map[string][]string
+}
+
+func (f *fakeStaffServices) ListStaffServices(ctx context.Context, salonID, staffID string) ([]string, error) {
+	if _, err := f.store.GetStaff(ctx, salonID, staffID); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.assigned[staffID], nil
+}
+
+func (f *fakeStaffServices) SetStaffServices(ctx context.Context, salonID, staffID string, serviceIDs []string) error {
+	if _, err := f.store.GetStaff(ctx, salonID, staffID); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.assigned[staffID] = serviceIDs
+	return nil
+}
+
+func TestStaffServicesAcrossTenants(t *testing.T) {
+	manager := model.StaffRoleManager
+	salonA, salonB := uuid.NewString(), uuid.NewString()
+	managerA := &model.Staff{ID: uuid.NewString(), SalonID: salonA, Role: &manager, Status: model.StaffStatusActive}
+	stylistA := &model.Staff{ID: uuid.NewString(), SalonID: salonA, Status: model.StaffStatusActive}
+	stylistB := &model.Staff{ID: uuid.NewString(), SalonID: salonB, Status: model.StaffStatusActive}
+	serviceB := uuid.NewString()
+
+	store := &fakeStaffStore{staff: map[string]*model.Staff{
+		managerA.ID: managerA,
+		stylistA.ID: stylistA,
+		stylistB.ID: stylistB,
+	}}
+	svc := &fakeStaffServices{store: store, assigned: map[string][]string{stylistB.ID: {serviceB}}}
+
+	cfg := &sharedConfig.Config{Env: "test"}
+	cfg.JWT.AccessSecret = "test-access-secret"
+	cfg.JWT.RefreshSecret = "test-refresh-secret"
+	cfg.JWT.AccessTTLMinutes = 5
+	cfg.Log.Level = "disabled"
+	jwt := sharedAuth.NewJWTManager(cfg)
+	routes := NewHandler(cfg, svc, store, jwt, sharedMiddleware.RateLimitConfig{Limit: 10, Window: time.Minute}, requestbody.Config{}).Routes()
+
+	token, _, err := jwt.GenerateAccessTokenWithType(managerA.ID, sharedAuth.UserTypeSalon)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		staffID    string
+		body       string
+		wantStatus int
+	}{
+		{name: "list own salon's staff", method: http.MethodGet, staffID: stylistA.ID, wantStatus: http.StatusOK},
+		{name: "list another salon's staff", method: http.MethodGet, staffID: stylistB.ID, wantStatus: http.StatusNotFound},
+		{name: "set another salon's staff", method: http.MethodPost, staffID: stylistB.ID, body: `{"service_ids":["` + uuid.NewString() + `"]}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/salons/" + salonA + "/staff/" + tt.staffID + "/services"
+			req := httptest.NewRequest(tt.method, path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			routes.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusNotFound && strings.Contains(rec.Body.String(), serviceB) {
+				t.Errorf("not found response leaks another salon's service assignment: %s", rec.Body.String())
+			}
+		})
+	}
+
+	if got := svc.assigned[stylistB.ID]; len(got) != 1 || got[0] != serviceB {
+		t.Errorf("another salon's staff services changed to %v", got)
+	}
+}
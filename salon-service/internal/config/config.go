@@ -3,19 +3,36 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
 	sharedConfig "github.com/EricsAntony/salon/salon-shared/config"
+	"github.com/EricsAntony/salon/salon-shared/configcheck"
+	sharedErrors "github.com/EricsAntony/salon/salon-shared/errors"
+	sharedMiddleware "github.com/EricsAntony/salon/salon-shared/middleware"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
 	"github.com/spf13/viper"
 	"github.com/subosito/gotenv"
+
+	"salon-service/internal/db"
+	"salon-service/internal/service"
 )
 
 type Config struct {
 	Env    string
 	Server struct {
 		Port string
+		// MaxBodyBytes is the largest request body accepted
+		MaxBodyBytes int
 	}
 	DB struct {
 		URL string
+		// Connection pool limits
+		MaxConns                 int
+		MinConns                 int
+		MaxConnIdleMinutes       int
+		MaxConnLifetimeMinutes   int
+		HealthCheckPeriodSeconds int
 	}
 	Log struct {
 		Level       string
@@ -27,6 +44,20 @@ type Config struct {
 		AccessTTLMinutes int
 		RefreshTTLDays   int
 	}
+	OTP struct {
+		ExpiryMinutes        int
+		MaxFailedAttempts    int
+		FailureWindowMinutes int
+		// LockoutMinutes is how long a phone stays locked after MaxFailedAttempts
+		LockoutMinutes int
+		// Per phone number limit on OTP requests
+		RequestsPerPhone     int
+		RequestWindowMinutes int
+		// Per client IP limit on OTP requests
+		RequestsPerIP int
+	}
+	// SMS selects the provider staff OTP codes are delivered through
+	SMS sharedAuth.OTPSenderConfig
 }
 
 func Load() (*Config, error) {
@@ -41,10 +72,32 @@ func Load() (*Config, error) {
 
 	v.SetDefault("env", "dev")
 	v.SetDefault("server.port", "8081")
+	v.SetDefault("server.maxbodybytes", requestbody.DefaultMaxBytes)
+	v.SetDefault("db.maxconns", 10)
+	v.SetDefault("db.minconns", 2)
+	v.SetDefault("db.maxconnidleminutes", 30)
+	v.SetDefault("db.maxconnlifetimeminutes", 60)
+	v.SetDefault("db.healthcheckperiodseconds", 60)
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.servicename", "salon-service")
-	v.SetDefault("jwt.accessttlminutes", 15)
-	v.SetDefault("jwt.refreshttldays", 7)
+	v.SetDefault("jwt.accessttlminutes", sharedConfig.DefaultAccessTTLMinutes)
+	v.SetDefault("jwt.refreshttldays", sharedConfig.DefaultRefreshTTLDays)
+	v.SetDefault("otp.expiryminutes", 5)
+	v.SetDefault("otp.maxfailedattempts", 5)
+	v.SetDefault("otp.failurewindowminutes", 15)
+	v.SetDefault("otp.lockoutminutes", 15)
+	v.SetDefault("otp.requestsperphone", 3)
+	v.SetDefault("otp.requestwindowminutes", 15)
+	v.SetDefault("otp.requestsperip", 5)
+	v.SetDefault("sms.provider", sharedAuth.OTPProviderLog)
+	v.SetDefault("sms.twilioaccountsid", "")
+	v.SetDefault("sms.twilioauthtoken", "")
+	v.SetDefault("sms.twiliofromnumber", "")
+	v.SetDefault("sms.msg91authkey", "")
+	v.SetDefault("sms.msg91templateid", "")
+	v.SetDefault("sms.twiliowhatsappfrom", "")
+	v.SetDefault("sms.channels", []string{sharedAuth.OTPChannelSMS})
+	v.SetDefault("sms.fallbackafterseconds", 0)
 
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -60,6 +113,77 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// Validate reports every missing or out of range setting at once. Secrets must
+// be at least configcheck.MinSecretLength characters outside development.
+func (c *Config) Validate() error {
+	var p configcheck.Problems
+	strict := !configcheck.IsDevelopment(c.Env)
+
+	p.Port("SALON_SERVICE_SERVER_PORT", c.Server.Port)
+	p.Positive("SALON_SERVICE_SERVER_MAXBODYBYTES", c.Server.MaxBodyBytes)
+	p.Required("SALON_SERVICE_DB_URL", c.DB.URL)
+	p.Positive("SALON_SERVICE_DB_MAXCONNS", c.DB.MaxConns)
+	p.Range("SALON_SERVICE_DB_MINCONNS", c.DB.MinConns, 0, c.DB.MaxConns)
+	p.Positive("SALON_SERVICE_DB_MAXCONNIDLEMINUTES", c.DB.MaxConnIdleMinutes)
+	p.Positive("SALON_SERVICE_DB_MAXCONNLIFETIMEMINUTES", c.DB.MaxConnLifetimeMinutes)
+	p.Positive("SALON_SERVICE_DB_HEALTHCHECKPERIODSECONDS", c.DB.HealthCheckPeriodSeconds)
+	p.Secret("SALON_SERVICE_JWT_ACCESSSECRET", c.JWT.AccessSecret, strict)
+	p.Secret("SALON_SERVICE_JWT_REFRESHSECRET", c.JWT.RefreshSecret, strict)
+	if c.JWT.AccessSecret != "" && c.JWT.AccessSecret == c.JWT.RefreshSecret {
+		p.Addf("SALON_SERVICE_JWT_ACCESSSECRET and SALON_SERVICE_JWT_REFRESHSECRET must differ")
+	}
+	p.Check(sharedConfig.ValidateJWTTTLs(c.JWT.AccessTTLMinutes, c.JWT.RefreshTTLDays))
+
+	p.Positive("SALON_SERVICE_OTP_EXPIRYMINUTES", c.OTP.ExpiryMinutes)
+	p.Positive("SALON_SERVICE_OTP_MAXFAILEDATTEMPTS", c.OTP.MaxFailedAttempts)
+	p.Positive("SALON_SERVICE_OTP_REQUESTSPERPHONE", c.OTP.RequestsPerPhone)
+	p.Positive("SALON_SERVICE_OTP_REQUESTSPERIP", c.OTP.RequestsPerIP)
+	p.Positive("SALON_SERVICE_OTP_FAILUREWINDOWMINUTES", c.OTP.FailureWindowMinutes)
+	p.Positive("SALON_SERVICE_OTP_REQUESTWINDOWMINUTES", c.OTP.RequestWindowMinutes)
+	p.Positive("SALON_SERVICE_OTP_LOCKOUTMINUTES", c.OTP.LockoutMinutes)
+	p.Check(c.SMS.Validate())
+
+	return p.Err("salon-service")
+}
+
+// DBPool returns the connection pool limits for the pgx pool
+func (c *Config) DBPool() db.PoolConfig {
+	return db.PoolConfig{
+		MaxConns:          int32(c.DB.MaxConns),
+		MinConns:          int32(c.DB.MinConns),
+		MaxConnIdleTime:   time.Duration(c.DB.MaxConnIdleMinutes) * time.Minute,
+		MaxConnLifetime:   time.Duration(c.DB.MaxConnLifetimeMinutes) * time.Minute,
+		HealthCheckPeriod: time.Duration(c.DB.HealthCheckPeriodSeconds) * time.Second,
+	}
+}
+
+// OTPPolicy returns the staff OTP brute force and request limits
+func (c *Config) OTPPolicy() service.OTPPolicy {
+	return service.OTPPolicy{
+		MaxFailedAttempts: c.OTP.MaxFailedAttempts,
+		FailureWindow:     time.Duration(c.OTP.FailureWindowMinutes) * time.Minute,
+		LockoutDuration:   time.Duration(c.OTP.LockoutMinutes) * time.Minute,
+		RequestsPerPhone:  c.OTP.RequestsPerPhone,
+		RequestWindow:     time.Duration(c.OTP.RequestWindowMinutes) * time.Minute,
+	}
+}
+
+// OTPRateLimit returns the per client IP limit applied to the OTP request route
+func (c *Config) OTPRateLimit() sharedMiddleware.RateLimitConfig {
+	return sharedMiddleware.RateLimitConfig{
+		Limit:  c.OTP.RequestsPerIP,
+		Window: time.Duration(c.OTP.RequestWindowMinutes) * time.Minute,
+	}
+}
+
+// RequestBodyLimit returns the body size limit applied to every request
+func (c *Config) RequestBodyLimit() requestbody.Config {
+	return requestbody.Config{
+		MaxBytes:   int64(c.Server.MaxBodyBytes),
+		WriteError: sharedErrors.WriteError,
+	}
+}
+
 func (c *Config) ToShared() *sharedConfig.Config {
 	return &sharedConfig.Config{
 		Env: c.Env,
@@ -76,6 +200,7 @@ func (c *Config) ToShared() *sharedConfig.Config {
 			AccessTTLMinutes: c.JWT.AccessTTLMinutes,
 			RefreshTTLDays:   c.JWT.RefreshTTLDays,
 		},
+		OTP: struct{ ExpiryMinutes int }{ExpiryMinutes: c.OTP.ExpiryMinutes},
 		Log: struct {
 			Level       string
 			ServiceName string
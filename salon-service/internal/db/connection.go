@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// PoolConfig holds the connection pool limits applied to the pgx pool
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnIdleTime   time.Duration
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+// NewPool creates a connection pool with the given limits and checks that the
+// database is reachable
+func NewPool(ctx context.Context, databaseURL string, pool PoolConfig) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	config.MaxConns = pool.MaxConns
+	config.MinConns = pool.MinConns
+	config.MaxConnIdleTime = pool.MaxConnIdleTime
+	config.MaxConnLifetime = pool.MaxConnLifetime
+	config.HealthCheckPeriod = pool.HealthCheckPeriod
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("create connection pool: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := conn.Ping(pingCtx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	log.Info().
+		Int32("max_conns", config.MaxConns).
+		Int32("min_conns", config.MinConns).
+		Dur("max_conn_idle_time", config.MaxConnIdleTime).
+		Dur("max_conn_lifetime", config.MaxConnLifetime).
+		Dur("health_check_period", config.HealthCheckPeriod).
+		Msg("database pool configured")
+	return conn, nil
+}
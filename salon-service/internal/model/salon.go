@@ -20,6 +20,21 @@ type Salon struct {
 	Settings           map[string]any     `json:"settings,omitempty"`
 	CreatedAt          time.Time          `json:"created_at"`
 	UpdatedAt          time.Time          `json:"updated_at"`
+	DeletedAt          *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// NearbySalon is a salon found by a proximity search with its distance from
+// the search point. Salon geo_location holds {"lat": ..., "lng": ...}.
+type NearbySalon struct {
+	*Salon
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// NearbySalonFilter narrows a proximity search to salons offering an active
+// service, or any active service of a category. Zero values mean no filtering.
+type NearbySalonFilter struct {
+	ServiceID  *string
+	CategoryID *string
 }
 
 type Branch struct {
@@ -34,6 +49,7 @@ type Branch struct {
 	Contact      map[string]any     `json:"contact,omitempty"`
 	CreatedAt    time.Time          `json:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at"`
+	DeletedAt    *time.Time         `json:"deleted_at,omitempty"`
 }
 
 type Category struct {
@@ -62,8 +78,19 @@ type Service struct {
 	Price       float64       `json:"price"`
 	Tags        []string      `json:"tags"`
 	Status      ServiceStatus `json:"status"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
+	// TaxExempt services (e.g. gift cards) are not taxed when booked
+	TaxExempt bool      `json:"tax_exempt"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ServiceFilter narrows a service listing. Zero values mean no filtering.
+type ServiceFilter struct {
+	CategoryID *string
+	Name       string
+	MinPrice   *float64
+	MaxPrice   *float64
+	Tags       []string
 }
 
 type StaffStatus string
@@ -76,6 +103,7 @@ const (
 type Staff struct {
 	ID             string             `json:"id"`
 	SalonID        string             `json:"salon_id"`
+	BranchID       *string            `json:"branch_id"`
 	Name           string             `json:"name"`
 	PhoneNumber    string             `json:"phone_number"`
 	Email          *string            `json:"email,omitempty"`
@@ -83,9 +111,63 @@ type Staff struct {
 	Specialization *string            `json:"specialization,omitempty"`
 	Photo          *string            `json:"photo,omitempty"`
 	Status         StaffStatus        `json:"status"`
-	Shifts         map[string]any     `json:"shifts,omitempty"`
+	Shifts         Shifts             `json:"shifts,omitempty"`
 	CreatedAt      time.Time          `json:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at"`
+	DeletedAt      *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// Shifts is a staff member's weekly schedule keyed by lowercase weekday
+// ("monday" ... "sunday"). Days without an entry are days off.
+type Shifts map[string][]ShiftBlock
+
+// ShiftBlock is a continuous working period on a weekday in HH:MM clock time
+type ShiftBlock struct {
+	Start  string       `json:"start"`
+	End    string       `json:"end"`
+	Breaks []ShiftBreak `json:"breaks,omitempty"`
+}
+
+// ShiftBreak is a break within a shift block, e.g. lunch
+type ShiftBreak struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Type  string `json:"type,omitempty"`
+}
+
+// Staff roles. Owners and managers may manage the salon; stylists may only
+// read salon data and update their own profile and shifts.
+const (
+	StaffRoleOwner   = "owner"
+	StaffRoleManager = "manager"
+	StaffRoleStylist = "stylist"
+)
+
+// EffectiveRole returns the staff role, treating a missing or unknown role as
+// stylist so unclassified staff get the least privilege
+func (s *Staff) EffectiveRole() string {
+	if s.Role != nil {
+		switch *s.Role {
+		case StaffRoleOwner, StaffRoleManager:
+			return *s.Role
+		}
+	}
+	return StaffRoleStylist
+}
+
+// CanManageSalon reports whether the staff may create, update or delete salon resources
+func (s *Staff) CanManageSalon() bool {
+	role := s.EffectiveRole()
+	return role == StaffRoleOwner || role == StaffRoleManager
+}
+
+// StaffFilter narrows a staff listing. Zero values mean no filtering.
+type StaffFilter struct {
+	Status         *StaffStatus
+	BranchID       *string
+	Name           string
+	Specialization string
+	IncludeDeleted bool
 }
 
 type StaffService struct {
@@ -111,3 +193,35 @@ type StaffRefreshToken struct {
 	CreatedAt time.Time `json:"created_at"`
 	Revoked   bool      `json:"revoked"`
 }
+
+// StylistSchedule is a staff member's concrete working hours and breaks for a
+// single date, in the shape booking-service consumes for slot generation
+type StylistSchedule struct {
+	StylistID    string           `json:"stylist_id"`
+	Date         time.Time        `json:"date"`
+	WorkingHours []ScheduleWindow `json:"working_hours"`
+	Breaks       []ScheduleBreak  `json:"breaks"`
+	OnLeave      bool             `json:"on_leave,omitempty"`
+}
+
+// StaffTimeOff is a block of leave for a staff member. StartDate and EndDate
+// are inclusive YYYY-MM-DD dates.
+type StaffTimeOff struct {
+	ID        string    `json:"id"`
+	StaffID   string    `json:"staff_id"`
+	StartDate string    `json:"start_date"`
+	EndDate   string    `json:"end_date"`
+	Reason    *string   `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduleWindow struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type ScheduleBreak struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Type      string    `json:"type"`
+}
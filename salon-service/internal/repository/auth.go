@@ -12,9 +12,13 @@ import (
 )
 
 type StaffAuthRepository interface {
-    CreateOTP(ctx context.Context, phone, codeHash string, expiresAt time.Time) error
+    CreateOTP(ctx context.Context, phone, codeHash string, expiresAt time.Time) (int64, error)
     GetLatestOTP(ctx context.Context, phone string) (*model.StaffOTP, error)
     IncrementOTPAttempts(ctx context.Context, id int64) error
+    SetOTPDeliveryChannel(ctx context.Context, id int64, channel string) error
+    MarkOTPVerified(ctx context.Context, id int64) error
+    IsOTPPending(ctx context.Context, id int64) (bool, error)
+    CountOTPsSince(ctx context.Context, phone string, since time.Time) (int, error)
     CreateRefreshToken(ctx context.Context, staffID, tokenHash string, expiresAt time.Time) error
     RevokeRefreshTokens(ctx context.Context, staffID string) error
     IsRefreshTokenValid(ctx context.Context, staffID, tokenHash string, now time.Time) (bool, error)
@@ -29,12 +33,14 @@ func NewStaffAuthRepository(db *pgxpool.Pool) StaffAuthRepository {
     return &staffAuthRepository{db: db}
 }
 
-func (r *staffAuthRepository) CreateOTP(ctx context.Context, phone, codeHash string, expiresAt time.Time) error {
-    _, err := r.db.Exec(ctx, `
+func (r *staffAuthRepository) CreateOTP(ctx context.Context, phone, codeHash string, expiresAt time.Time) (int64, error) {
+    var id int64
+    err := r.db.QueryRow(ctx, `
         INSERT INTO staff_otps (phone_number, code_hash, expires_at, created_at)
         VALUES ($1, $2, $3, NOW())
-    `, phone, codeHash, expiresAt)
-    return err
+        RETURNING id
+    `, phone, codeHash, expiresAt).Scan(&id)
+    return id, err
 }
 
 func (r *staffAuthRepository) GetLatestOTP(ctx context.Context, phone string) (*model.StaffOTP, error) {
@@ -60,6 +66,41 @@ func (r *staffAuthRepository) IncrementOTPAttempts(ctx context.Context, id int64
     return err
 }
 
+// SetOTPDeliveryChannel records the channel that last delivered the code
+func (r *staffAuthRepository) SetOTPDeliveryChannel(ctx context.Context, id int64, channel string) error {
+    _, err := r.db.Exec(ctx, `UPDATE staff_otps SET delivery_channel = $2 WHERE id = $1`, id, channel)
+    return err
+}
+
+func (r *staffAuthRepository) MarkOTPVerified(ctx context.Context, id int64) error {
+    _, err := r.db.Exec(ctx, `UPDATE staff_otps SET verified_at = NOW() WHERE id = $1 AND verified_at IS NULL`, id)
+    return err
+}
+
+// IsOTPPending reports whether the code is the latest for its phone number,
+// unexpired and not yet verified
+func (r *staffAuthRepository) IsOTPPending(ctx context.Context, id int64) (bool, error) {
+    var pending bool
+    err := r.db.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM staff_otps o
+            WHERE o.id = $1 AND o.verified_at IS NULL AND o.expires_at > NOW()
+              AND NOT EXISTS (SELECT 1 FROM staff_otps n WHERE n.phone_number = o.phone_number AND n.id > o.id)
+        )
+    `, id).Scan(&pending)
+    return pending, err
+}
+
+// CountOTPsSince returns how many OTPs were issued to the phone number since the given time
+func (r *staffAuthRepository) CountOTPsSince(ctx context.Context, phone string, since time.Time) (int, error) {
+    var count int
+    err := r.db.QueryRow(ctx, `
+        SELECT COUNT(*) FROM staff_otps
+        WHERE phone_number = $1 AND created_at > $2
+    `, phone, since).Scan(&count)
+    return count, err
+}
+
 func (r *staffAuthRepository) CreateRefreshToken(ctx context.Context, staffID, tokenHash string, expiresAt time.Time) error {
     _, err := r.db.Exec(ctx, `
         INSERT INTO staff_refresh_tokens (staff_id, token_hash, expires_at, created_at, revoked)
@@ -0,0 +1,62 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "github.com/EricsAntony/salon/salon-shared/lockout"
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgxpool"
+)
+
+type lockoutStore struct {
+    db *pgxpool.Pool
+}
+
+// NewLockoutStore returns the OTP lockout store shared with user-service
+func NewLockoutStore(db *pgxpool.Pool) lockout.Store {
+    return &lockoutStore{db: db}
+}
+
+func (r *lockoutStore) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+    var failures int
+    err := r.db.QueryRow(ctx, `
+        INSERT INTO otp_lockouts (phone_key, failures, window_started_at, updated_at)
+        VALUES ($1, 1, $2, $2)
+        ON CONFLICT (phone_key) DO UPDATE SET
+            failures = CASE WHEN otp_lockouts.window_started_at < $3 THEN 1 ELSE otp_lockouts.failures + 1 END,
+            window_started_at = CASE WHEN otp_lockouts.window_started_at < $3 THEN $2 ELSE otp_lockouts.window_started_at END,
+            updated_at = $2
+        RETURNING failures
+    `, key, now, windowStart).Scan(&failures)
+    return failures, err
+}
+
+func (r *lockoutStore) Lock(ctx context.Context, key string, until time.Time) error {
+    _, err := r.db.Exec(ctx, `
+        UPDATE otp_lockouts SET locked_until = $2, failures = 0, updated_at = NOW()
+        WHERE phone_key = $1
+    `, key, until)
+    return err
+}
+
+func (r *lockoutStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+    var until *time.Time
+    err := r.db.QueryRow(ctx, `SELECT locked_until FROM otp_lockouts WHERE phone_key = $1`, key).Scan(&until)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return time.Time{}, nil
+        }
+        return time.Time{}, err
+    }
+    if until == nil {
+        return time.Time{}, nil
+    }
+    return *until, nil
+}
+
+func (r *lockoutStore) Reset(ctx context.Context, key string) error {
+    _, err := r.db.Exec(ctx, `DELETE FROM otp_lockouts WHERE phone_key = $1`, key)
+    return err
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "github.com/EricsAntony/salon/salon-shared/auth"
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgxpool"
+)
+
+type revocationStore struct {
+    db *pgxpool.Pool
+}
+
+// NewRevocationStore reads the access token revocations written by user-service
+func NewRevocationStore(db *pgxpool.Pool) auth.RevocationStore {
+    return &revocationStore{db: db}
+}
+
+func (r *revocationStore) TokensValidAfter(ctx context.Context, subjectID string) (time.Time, error) {
+    var validAfter time.Time
+    err := r.db.QueryRow(ctx, `SELECT tokens_valid_after FROM token_revocations WHERE subject_id = $1`, subjectID).Scan(&validAfter)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return time.Time{}, nil
+    }
+    return validAfter, err
+}
@@ -5,14 +5,72 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
+	sharedErrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"salon-service/internal/model"
 )
 
-var ErrNotFound = errors.New("not found")
+// ErrNotFound aliases the shared not-found error so handlers map it to 404
+var ErrNotFound = sharedErrors.ErrNotFound
+
+// SalonRepository is the salon data the service reads and writes; Store
+// implements it against Postgres
+type SalonRepository interface {
+	CreateSalon(ctx context.Context, input *model.Salon) (*model.Salon, error)
+	GetSalon(ctx context.Context, id string) (*model.Salon, error)
+	ListSalonsPage(ctx context.Context, page pagination.Params, includeDeleted bool) ([]*model.Salon, int, error)
+	UpdateSalon(ctx context.Context, input *model.Salon) (*model.Salon, error)
+	UpdateSalonWorkingHours(ctx context.Context, id string, workingHours map[string]any) (*model.Salon, error)
+	DeleteSalon(ctx context.Context, id string) error
+	RestoreSalon(ctx context.Context, id string) (*model.Salon, error)
+	SearchSalonsNearby(ctx context.Context, lat, lng, radiusKm float64, filter model.NearbySalonFilter, page pagination.Params) ([]*model.NearbySalon, int, error)
+
+	CreateBranch(ctx context.Context, input *model.Branch) (*model.Branch, error)
+	GetBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error)
+	ListBranches(ctx context.Context, salonID string, includeDeleted bool) ([]*model.Branch, error)
+	UpdateBranch(ctx context.Context, input *model.Branch) (*model.Branch, error)
+	UpdateBranchWorkingHours(ctx context.Context, salonID, branchID string, workingHours map[string]any) (*model.Branch, error)
+	DeleteBranch(ctx context.Context, salonID, branchID string) error
+	RestoreBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error)
+
+	CreateCategory(ctx context.Context, input *model.Category) (*model.Category, error)
+	ListCategories(ctx context.Context, salonID string) ([]*model.Category, error)
+	UpdateCategory(ctx context.Context, input *model.Category) (*model.Category, error)
+	DeleteCategory(ctx context.Context, salonID, categoryID string) error
+
+	CreateService(ctx context.Context, input *model.Service) (*model.Service, error)
+	GetService(ctx context.Context, salonID, serviceID string) (*model.Service, error)
+	ListServices(ctx context.Context, salonID string, categoryID *string) ([]*model.Service, error)
+	ListServicesFiltered(ctx context.Context, salonID string, filter model.ServiceFilter, page pagination.Params) ([]*model.Service, int, error)
+	UpdateService(ctx context.Context, input *model.Service) (*model.Service, error)
+	DeleteService(ctx context.Context, salonID, serviceID string) error
+
+	CreateStaff(ctx context.Context, input *model.Staff) (*model.Staff, error)
+	GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error)
+	GetStaffByID(ctx context.Context, staffID string) (*model.Staff, error)
+	GetStaffByPhone(ctx context.Context, phone string) (*model.Staff, error)
+	ListStaffFiltered(ctx context.Context, salonID string, filter model.StaffFilter) ([]*model.Staff, error)
+	UpdateStaff(ctx context.Context, input *model.Staff) (*model.Staff, error)
+	SetStaffBranch(ctx context.Context, salonID, staffID string, branchID *string) (*model.Staff, error)
+	DeleteStaff(ctx context.Context, salonID, staffID string) error
+	RestoreStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error)
+	SetStaffServices(ctx context.Context, salonID, staffID string, serviceIDs []string) error
+	ListStaffServices(ctx context.Context, staffID string) ([]string, error)
+	AddStaffService(ctx context.Context, staffID, serviceID string) error
+	RemoveStaffService(ctx context.Context, staffID, serviceID string) error
+	CreateStaffTimeOff(ctx context.Context, salonID, staffID string, startDate, endDate time.Time, reason *string) (*model.StaffTimeOff, error)
+	ListStaffTimeOff(ctx context.Context, staffID string, from time.Time) ([]*model.StaffTimeOff, error)
+	DeleteStaffTimeOff(ctx context.Context, staffID, timeOffID string) error
+	IsStaffOnLeave(ctx context.Context, staffID string, date time.Time) (bool, error)
+
+	HealthCheck(ctx context.Context) error
+}
 
 type Store struct {
 	db *pgxpool.Pool
@@ -62,7 +120,7 @@ func (s *Store) CreateSalon(ctx context.Context, input *model.Salon) (*model.Sal
 		) RETURNING
 			id, name, description, contact, address, geo_location, logo, banner,
 			working_hours, holidays, cancellation_policy, payment_modes,
-			default_currency, tax_rate, settings, created_at, updated_at
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at
 	`,
 		input.ID,
 		input.Name,
@@ -87,21 +145,38 @@ func (s *Store) GetSalon(ctx context.Context, id string) (*model.Salon, error) {
 	row := s.db.QueryRow(ctx, `
 		SELECT id, name, description, contact, address, geo_location, logo, banner,
 			working_hours, holidays, cancellation_policy, payment_modes,
-			default_currency, tax_rate, settings, created_at, updated_at
-		FROM salons WHERE id = $1
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at
+		FROM salons WHERE id = $1 AND deleted_at IS NULL
 	`, id)
 	return scanSalon(row)
 }
 
 func (s *Store) ListSalons(ctx context.Context) ([]*model.Salon, error) {
+	salons, _, err := s.ListSalonsPage(ctx, pagination.Params{}, false)
+	return salons, err
+}
+
+// ListSalonsPage lists salons in name order along with the total salon count.
+// Soft-deleted salons are only included when includeDeleted is set.
+func (s *Store) ListSalonsPage(ctx context.Context, page pagination.Params, includeDeleted bool) ([]*model.Salon, int, error) {
+	whereClause := ` WHERE deleted_at IS NULL`
+	if includeDeleted {
+		whereClause = ""
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM salons`+whereClause).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limitClause, args := paginationClause(page, nil)
 	rows, err := s.db.Query(ctx, `
 		SELECT id, name, description, contact, address, geo_location, logo, banner,
 			working_hours, holidays, cancellation_policy, payment_modes,
-			default_currency, tax_rate, settings, created_at, updated_at
-		FROM salons ORDER BY name
-	`)
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at
+		FROM salons`+whereClause+` ORDER BY name, id`+limitClause, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -109,11 +184,75 @@ func (s *Store) ListSalons(ctx context.Context) ([]*model.Salon, error) {
 	for rows.Next() {
 		salon, err := scanSalon(rows)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		salons = append(salons, salon)
 	}
-	return salons, rows.Err()
+	return salons, total, rows.Err()
+}
+
+// SearchSalonsNearby lists salons within radiusKm of the given point, nearest
+// first, along with the number of salons in range. Distances use the
+// haversine formula on the lat/lng stored in geo_location; salons without
+// numeric coordinates are never matched.
+func (s *Store) SearchSalonsNearby(ctx context.Context, lat, lng, radiusKm float64, filter model.NearbySalonFilter, page pagination.Params) ([]*model.NearbySalon, int, error) {
+	args := []any{lat, lng, radiusKm}
+	where := []string{"distance_km <= $3"}
+	if filter.ServiceID != nil || filter.CategoryID != nil {
+		conditions := []string{"sv.salon_id = nearby.id", "sv.status = 'active'"}
+		if filter.ServiceID != nil {
+			args = append(args, *filter.ServiceID)
+			conditions = append(conditions, fmt.Sprintf("sv.id = $%d", len(args)))
+		}
+		if filter.CategoryID != nil {
+			args = append(args, *filter.CategoryID)
+			conditions = append(conditions, fmt.Sprintf("sv.category_id = $%d", len(args)))
+		}
+		where = append(where, "EXISTS (SELECT 1 FROM services sv WHERE "+strings.Join(conditions, " AND ")+")")
+	}
+
+	nearby := `
+		WITH located AS (
+			SELECT *, (geo_location->>'lat')::double precision AS lat, (geo_location->>'lng')::double precision AS lng
+			FROM salons
+			WHERE deleted_at IS NULL
+				AND jsonb_typeof(geo_location->'lat') = 'number'
+				AND jsonb_typeof(geo_location->'lng') = 'number'
+		), nearby AS (
+			SELECT *, 2 * 6371 * ASIN(LEAST(1, SQRT(
+				POWER(SIN(RADIANS(lat - $1) / 2), 2) +
+				COS(RADIANS($1)) * COS(RADIANS(lat)) * POWER(SIN(RADIANS(lng - $2) / 2), 2)
+			))) AS distance_km
+			FROM located
+		)`
+	whereClause := ` WHERE ` + strings.Join(where, " AND ")
+
+	var total int
+	if err := s.db.QueryRow(ctx, nearby+` SELECT COUNT(*) FROM nearby`+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limitClause, args := paginationClause(page, args)
+	rows, err := s.db.Query(ctx, nearby+`
+		SELECT id, name, description, contact, address, geo_location, logo, banner,
+			working_hours, holidays, cancellation_policy, payment_modes,
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at, distance_km
+		FROM nearby`+whereClause+` ORDER BY distance_km, id`+limitClause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var salons []*model.NearbySalon
+	for rows.Next() {
+		var distance float64
+		salon, err := scanSalonWith(rows, &distance)
+		if err != nil {
+			return nil, 0, err
+		}
+		salons = append(salons, &model.NearbySalon{Salon: salon, DistanceKm: math.Round(distance*100) / 100})
+	}
+	return salons, total, rows.Err()
 }
 
 func (s *Store) UpdateSalon(ctx context.Context, input *model.Salon) (*model.Salon, error) {
@@ -159,10 +298,10 @@ func (s *Store) UpdateSalon(ctx context.Context, input *model.Salon) (*model.Sal
 			tax_rate = $14,
 			settings = $15,
 			updated_at = NOW()
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING id, name, description, contact, address, geo_location, logo, banner,
 			working_hours, holidays, cancellation_policy, payment_modes,
-			default_currency, tax_rate, settings, created_at, updated_at
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at
 	`,
 		input.ID,
 		input.Name,
@@ -184,7 +323,10 @@ func (s *Store) UpdateSalon(ctx context.Context, input *model.Salon) (*model.Sal
 }
 
 func (s *Store) DeleteSalon(ctx context.Context, id string) error {
-	ct, err := s.db.Exec(ctx, `DELETE FROM salons WHERE id = $1`, id)
+	ct, err := s.db.Exec(ctx, `
+		UPDATE salons SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id)
 	if err != nil {
 		return err
 	}
@@ -194,6 +336,34 @@ func (s *Store) DeleteSalon(ctx context.Context, id string) error {
 	return nil
 }
 
+// RestoreSalon clears the soft-delete mark on a salon
+func (s *Store) RestoreSalon(ctx context.Context, id string) (*model.Salon, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE salons SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, name, description, contact, address, geo_location, logo, banner,
+			working_hours, holidays, cancellation_policy, payment_modes,
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at
+	`, id)
+	return scanSalon(row)
+}
+
+// UpdateSalonWorkingHours replaces a salon's working hours
+func (s *Store) UpdateSalonWorkingHours(ctx context.Context, id string, workingHours map[string]any) (*model.Salon, error) {
+	workingJSON, err := mapToJSONB(workingHours)
+	if err != nil {
+		return nil, fmt.Errorf("marshal working_hours: %w", err)
+	}
+	row := s.db.QueryRow(ctx, `
+		UPDATE salons SET working_hours = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, name, description, contact, address, geo_location, logo, banner,
+			working_hours, holidays, cancellation_policy, payment_modes,
+			default_currency, tax_rate, settings, created_at, updated_at, deleted_at
+	`, id, workingJSON)
+	return scanSalon(row)
+}
+
 // --- Branch operations ---
 
 func (s *Store) CreateBranch(ctx context.Context, input *model.Branch) (*model.Branch, error) {
@@ -226,7 +396,7 @@ func (s *Store) CreateBranch(ctx context.Context, input *model.Branch) (*model.B
 			$1, $2, $3, $4, $5, $6, $7,
 			$8, $9, NOW(), NOW()
 		)
-		RETURNING id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at
+		RETURNING id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at, deleted_at
 	`,
 		input.ID,
 		input.SalonID,
@@ -243,17 +413,19 @@ func (s *Store) CreateBranch(ctx context.Context, input *model.Branch) (*model.B
 
 func (s *Store) GetBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error) {
 	row := s.db.QueryRow(ctx, `
-		SELECT id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at
-		FROM branches WHERE id = $1 AND salon_id = $2
+		SELECT id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at, deleted_at
+		FROM branches WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
 	`, branchID, salonID)
 	return scanBranch(row)
 }
 
-func (s *Store) ListBranches(ctx context.Context, salonID string) ([]*model.Branch, error) {
+// ListBranches lists a salon's branches. Soft-deleted branches are only
+// included when includeDeleted is set.
+func (s *Store) ListBranches(ctx context.Context, salonID string, includeDeleted bool) ([]*model.Branch, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at
-		FROM branches WHERE salon_id = $1 ORDER BY name
-	`, salonID)
+		SELECT id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at, deleted_at
+		FROM branches WHERE salon_id = $1 AND ($2 OR deleted_at IS NULL) ORDER BY name
+	`, salonID, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -302,8 +474,8 @@ func (s *Store) UpdateBranch(ctx context.Context, input *model.Branch) (*model.B
 			images = $8,
 			contact = $9,
 			updated_at = NOW()
-		WHERE id = $1 AND salon_id = $2
-		RETURNING id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
+		RETURNING id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at, deleted_at
 	`,
 		input.ID,
 		input.SalonID,
@@ -319,7 +491,10 @@ func (s *Store) UpdateBranch(ctx context.Context, input *model.Branch) (*model.B
 }
 
 func (s *Store) DeleteBranch(ctx context.Context, salonID, branchID string) error {
-	ct, err := s.db.Exec(ctx, `DELETE FROM branches WHERE id = $1 AND salon_id = $2`, branchID, salonID)
+	ct, err := s.db.Exec(ctx, `
+		UPDATE branches SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
+	`, branchID, salonID)
 	if err != nil {
 		return err
 	}
@@ -329,6 +504,30 @@ func (s *Store) DeleteBranch(ctx context.Context, salonID, branchID string) erro
 	return nil
 }
 
+// RestoreBranch clears the soft-delete mark on a branch
+func (s *Store) RestoreBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE branches SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NOT NULL
+		RETURNING id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at, deleted_at
+	`, branchID, salonID)
+	return scanBranch(row)
+}
+
+// UpdateBranchWorkingHours replaces a branch's working hours
+func (s *Store) UpdateBranchWorkingHours(ctx context.Context, salonID, branchID string, workingHours map[string]any) (*model.Branch, error) {
+	workingJSON, err := mapToJSONB(workingHours)
+	if err != nil {
+		return nil, fmt.Errorf("marshal working_hours: %w", err)
+	}
+	row := s.db.QueryRow(ctx, `
+		UPDATE branches SET working_hours = $3, updated_at = NOW()
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
+		RETURNING id, salon_id, name, address, geo_location, working_hours, holidays, images, contact, created_at, updated_at, deleted_at
+	`, branchID, salonID, workingJSON)
+	return scanBranch(row)
+}
+
 // --- Category operations ---
 
 func (s *Store) CreateCategory(ctx context.Context, input *model.Category) (*model.Category, error) {
@@ -410,11 +609,11 @@ func (s *Store) DeleteCategory(ctx context.Context, salonID, categoryID string)
 func (s *Store) CreateService(ctx context.Context, input *model.Service) (*model.Service, error) {
 	row := s.db.QueryRow(ctx, `
 		INSERT INTO services (
-			id, salon_id, category_id, name, description, duration_minutes, price, tags, status, created_at, updated_at
+			id, salon_id, category_id, name, description, duration_minutes, price, tags, status, tax_exempt, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW()
 		)
-		RETURNING id, salon_id, category_id, name, description, duration_minutes, price, tags, status, created_at, updated_at
+		RETURNING id, salon_id, category_id, name, description, duration_minutes, price, tags, status, tax_exempt, created_at, updated_at
 	`,
 		input.ID,
 		input.SalonID,
@@ -425,36 +624,62 @@ func (s *Store) CreateService(ctx context.Context, input *model.Service) (*model
 		input.Price,
 		arrayOrNil(input.Tags),
 		input.Status,
+		input.TaxExempt,
 	)
 	return scanService(row)
 }
 
 func (s *Store) GetService(ctx context.Context, salonID, serviceID string) (*model.Service, error) {
 	row := s.db.QueryRow(ctx, `
-		SELECT id, salon_id, category_id, name, description, duration_minutes, price, tags, status, created_at, updated_at
+		SELECT id, salon_id, category_id, name, description, duration_minutes, price, tags, status, tax_exempt, created_at, updated_at
 		FROM services WHERE id = $1 AND salon_id = $2
 	`, serviceID, salonID)
 	return scanService(row)
 }
 
 func (s *Store) ListServices(ctx context.Context, salonID string, categoryID *string) ([]*model.Service, error) {
-	var (
-		rows pgx.Rows
-		err  error
-	)
-	if categoryID != nil {
-		rows, err = s.db.Query(ctx, `
-			SELECT id, salon_id, category_id, name, description, duration_minutes, price, tags, status, created_at, updated_at
-			FROM services WHERE salon_id = $1 AND category_id = $2 ORDER BY name
-		`, salonID, *categoryID)
-	} else {
-		rows, err = s.db.Query(ctx, `
-			SELECT id, salon_id, category_id, name, description, duration_minutes, price, tags, status, created_at, updated_at
-			FROM services WHERE salon_id = $1 ORDER BY name
-		`, salonID)
+	services, _, err := s.ListServicesFiltered(ctx, salonID, model.ServiceFilter{CategoryID: categoryID}, pagination.Params{})
+	return services, err
+}
+
+// ListServicesFiltered lists a salon's services matching every non-empty filter
+// field, along with the total number of matches across all pages
+func (s *Store) ListServicesFiltered(ctx context.Context, salonID string, filter model.ServiceFilter, page pagination.Params) ([]*model.Service, int, error) {
+	where := []string{"salon_id = $1"}
+	args := []any{salonID}
+	if filter.CategoryID != nil {
+		args = append(args, *filter.CategoryID)
+		where = append(where, fmt.Sprintf("category_id = $%d", len(args)))
+	}
+	if filter.Name != "" {
+		args = append(args, likePattern(filter.Name))
+		where = append(where, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		where = append(where, fmt.Sprintf("price >= $%d", len(args)))
+	}
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		where = append(where, fmt.Sprintf("price <= $%d", len(args)))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, filter.Tags)
+		where = append(where, fmt.Sprintf("tags @> $%d", len(args)))
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM services WHERE `+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
+
+	limitClause, args := paginationClause(page, args)
+	rows, err := s.db.Query(ctx, `
+		SELECT id, salon_id, category_id, name, description, duration_minutes, price, tags, status, tax_exempt, created_at, updated_at
+		FROM services WHERE `+whereClause+` ORDER BY name, id`+limitClause, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -462,11 +687,11 @@ func (s *Store) ListServices(ctx context.Context, salonID string, categoryID *st
 	for rows.Next() {
 		svc, err := scanService(rows)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		services = append(services, svc)
 	}
-	return services, rows.Err()
+	return services, total, rows.Err()
 }
 
 func (s *Store) UpdateService(ctx context.Context, input *model.Service) (*model.Service, error) {
@@ -479,9 +704,10 @@ func (s *Store) UpdateService(ctx context.Context, input *model.Service) (*model
 			price = $7,
 			tags = $8,
 			status = $9,
+			tax_exempt = $10,
 			updated_at = NOW()
 		WHERE id = $1 AND salon_id = $2
-		RETURNING id, salon_id, category_id, name, description, duration_minutes, price, tags, status, created_at, updated_at
+		RETURNING id, salon_id, category_id, name, description, duration_minutes, price, tags, status, tax_exempt, created_at, updated_at
 	`,
 		input.ID,
 		input.SalonID,
@@ -492,6 +718,7 @@ func (s *Store) UpdateService(ctx context.Context, input *model.Service) (*model
 		input.Price,
 		arrayOrNil(input.Tags),
 		input.Status,
+		input.TaxExempt,
 	)
 	return scanService(row)
 }
@@ -510,20 +737,21 @@ func (s *Store) DeleteService(ctx context.Context, salonID, serviceID string) er
 // --- Staff operations ---
 
 func (s *Store) CreateStaff(ctx context.Context, input *model.Staff) (*model.Staff, error) {
-	shiftsJSON, err := mapToJSONB(input.Shifts)
+	shiftsJSON, err := shiftsToJSONB(input.Shifts)
 	if err != nil {
 		return nil, fmt.Errorf("marshal shifts: %w", err)
 	}
 	row := s.db.QueryRow(ctx, `
 		INSERT INTO staff (
-			id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
+			id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW()
 		)
-		RETURNING id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
+		RETURNING id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
 	`,
 		input.ID,
 		input.SalonID,
+		input.BranchID,
 		input.Name,
 		input.PhoneNumber,
 		input.Email,
@@ -538,28 +766,53 @@ func (s *Store) CreateStaff(ctx context.Context, input *model.Staff) (*model.Sta
 
 func (s *Store) GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error) {
 	row := s.db.QueryRow(ctx, `
-		SELECT id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
-		FROM staff WHERE id = $1 AND salon_id = $2
+		SELECT id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
+		FROM staff WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
 	`, staffID, salonID)
 	return scanStaff(row)
 }
 
+// GetStaffByID fetches a staff member by primary key without scoping to a salon
+func (s *Store) GetStaffByID(ctx context.Context, staffID string) (*model.Staff, error) {
+	row := s.db.QueryRow(ctx, `
+		SELECT id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
+		FROM staff WHERE id = $1 AND deleted_at IS NULL
+	`, staffID)
+	return scanStaff(row)
+}
+
 func (s *Store) ListStaff(ctx context.Context, salonID string, status *model.StaffStatus) ([]*model.Staff, error) {
-	var (
-		rows pgx.Rows
-		err  error
-	)
-	if status != nil {
-		rows, err = s.db.Query(ctx, `
-			SELECT id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
-			FROM staff WHERE salon_id = $1 AND status = $2 ORDER BY name
-		`, salonID, *status)
-	} else {
-		rows, err = s.db.Query(ctx, `
-			SELECT id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
-			FROM staff WHERE salon_id = $1 ORDER BY name
-		`, salonID)
+	return s.ListStaffFiltered(ctx, salonID, model.StaffFilter{Status: status})
+}
+
+// ListStaffFiltered lists a salon's staff matching every non-empty filter field
+func (s *Store) ListStaffFiltered(ctx context.Context, salonID string, filter model.StaffFilter) ([]*model.Staff, error) {
+	where := []string{"salon_id = $1"}
+	args := []any{salonID}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.BranchID != nil {
+		args = append(args, *filter.BranchID)
+		where = append(where, fmt.Sprintf("branch_id = $%d", len(args)))
+	}
+	if filter.Name != "" {
+		args = append(args, likePattern(filter.Name))
+		where = append(where, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if filter.Specialization != "" {
+		args = append(args, likePattern(filter.Specialization))
+		where = append(where, fmt.Sprintf("specialization ILIKE $%d", len(args)))
 	}
+	if !filter.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
+		FROM staff WHERE `+strings.Join(where, " AND ")+` ORDER BY name
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -578,8 +831,8 @@ func (s *Store) ListStaff(ctx context.Context, salonID string, status *model.Sta
 
 func (s *Store) GetStaffByPhone(ctx context.Context, phone string) (*model.Staff, error) {
 	row := s.db.QueryRow(ctx, `
-		SELECT id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
-		FROM staff WHERE phone_number = $1
+		SELECT id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
+		FROM staff WHERE phone_number = $1 AND deleted_at IS NULL
 	`, phone)
 	st, err := scanStaff(row)
 	if err != nil {
@@ -592,7 +845,10 @@ func (s *Store) GetStaffByPhone(ctx context.Context, phone string) (*model.Staff
 }
 
 func (s *Store) DeleteStaff(ctx context.Context, salonID, staffID string) error {
-	ct, err := s.db.Exec(ctx, `DELETE FROM staff WHERE id = $1 AND salon_id = $2`, staffID, salonID)
+	ct, err := s.db.Exec(ctx, `
+		UPDATE staff SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
+	`, staffID, salonID)
 	if err != nil {
 		return err
 	}
@@ -602,8 +858,28 @@ func (s *Store) DeleteStaff(ctx context.Context, salonID, staffID string) error
 	return nil
 }
 
+// SetStaffBranch assigns a staff member to a branch, or unassigns them when branchID is nil
+func (s *Store) SetStaffBranch(ctx context.Context, salonID, staffID string, branchID *string) (*model.Staff, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE staff SET branch_id = $3, updated_at = NOW()
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
+		RETURNING id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
+	`, staffID, salonID, branchID)
+	return scanStaff(row)
+}
+
+// RestoreStaff clears the soft-delete mark on a staff member
+func (s *Store) RestoreStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE staff SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NOT NULL
+		RETURNING id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
+	`, staffID, salonID)
+	return scanStaff(row)
+}
+
 func (s *Store) UpdateStaff(ctx context.Context, input *model.Staff) (*model.Staff, error) {
-	shiftsJSON, err := mapToJSONB(input.Shifts)
+	shiftsJSON, err := shiftsToJSONB(input.Shifts)
 	if err != nil {
 		return nil, fmt.Errorf("marshal shifts: %w", err)
 	}
@@ -618,8 +894,8 @@ func (s *Store) UpdateStaff(ctx context.Context, input *model.Staff) (*model.Sta
 			status = $9,
 			shifts = $10,
 			updated_at = NOW()
-		WHERE id = $1 AND salon_id = $2
-		RETURNING id, salon_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at
+		WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL
+		RETURNING id, salon_id, branch_id, name, phone_number, email, role, specialization, photo, status, shifts, created_at, updated_at, deleted_at
 	`,
 		input.ID,
 		input.SalonID,
@@ -649,7 +925,7 @@ func (s *Store) SetStaffServices(ctx context.Context, salonID, staffID string, s
 	}()
 
 	var exists bool
-	if err = tx.QueryRow(ctx, `SELECT true FROM staff WHERE id = $1 AND salon_id = $2`, staffID, salonID).Scan(&exists); err != nil {
+	if err = tx.QueryRow(ctx, `SELECT true FROM staff WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL`, staffID, salonID).Scan(&exists); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrNotFound
 		}
@@ -679,6 +955,23 @@ func (s *Store) SetStaffServices(ctx context.Context, salonID, staffID string, s
 	return nil
 }
 
+// AddStaffService assigns one service to a staff member. Assigning a service
+// the staff already offers is a no-op.
+func (s *Store) AddStaffService(ctx context.Context, staffID, serviceID string) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO staff_services (id, staff_id, service_id) VALUES (gen_random_uuid(), $1, $2)
+		ON CONFLICT (staff_id, service_id) DO NOTHING
+	`, staffID, serviceID)
+	return err
+}
+
+// RemoveStaffService unassigns one service from a staff member. Removing a
+// service the staff does not offer is a no-op.
+func (s *Store) RemoveStaffService(ctx context.Context, staffID, serviceID string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM staff_services WHERE staff_id = $1 AND service_id = $2`, staffID, serviceID)
+	return err
+}
+
 func (s *Store) ListStaffServices(ctx context.Context, staffID string) ([]string, error) {
 	rows, err := s.db.Query(ctx, `SELECT service_id FROM staff_services WHERE staff_id = $1`, staffID)
 	if err != nil {
@@ -686,7 +979,7 @@ func (s *Store) ListStaffServices(ctx context.Context, staffID string) ([]string
 	}
 	defer rows.Close()
 
-	var serviceIDs []string
+	serviceIDs := []string{}
 	for rows.Next() {
 		var id string
 		if err := rows.Scan(&id); err != nil {
@@ -699,7 +992,28 @@ func (s *Store) ListStaffServices(ctx context.Context, staffID string) ([]string
 
 // --- Helpers ---
 
+// paginationClause appends LIMIT/OFFSET placeholders for the page to args.
+// A zero limit returns every row.
+func paginationClause(page pagination.Params, args []any) (string, []any) {
+	if page.Limit <= 0 {
+		return "", args
+	}
+	args = append(args, page.Limit, page.Offset)
+	return fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args
+}
+
+// likePattern builds a case-insensitive substring pattern, escaping LIKE wildcards in the input
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
+
 func scanSalon(row pgx.Row) (*model.Salon, error) {
+	return scanSalonWith(row)
+}
+
+// scanSalonWith scans a salon followed by extra computed columns
+func scanSalonWith(row pgx.Row, extra ...any) (*model.Salon, error) {
 	var (
 		salon              model.Salon
 		description        *string
@@ -713,7 +1027,7 @@ func scanSalon(row pgx.Row) (*model.Salon, error) {
 		cancellationPolicy *string
 	)
 
-	err := row.Scan(
+	err := row.Scan(append([]any{
 		&salon.ID,
 		&salon.Name,
 		&description,
@@ -731,7 +1045,8 @@ func scanSalon(row pgx.Row) (*model.Salon, error) {
 		&settingsRaw,
 		&salon.CreatedAt,
 		&salon.UpdatedAt,
-	)
+		&salon.DeletedAt,
+	}, extra...)...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -774,6 +1089,7 @@ func scanBranch(row pgx.Row) (*model.Branch, error) {
 		&contactRaw,
 		&branch.CreatedAt,
 		&branch.UpdatedAt,
+		&branch.DeletedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -817,6 +1133,7 @@ func scanService(row pgx.Row) (*model.Service, error) {
 		&svc.Price,
 		&tags,
 		&svc.Status,
+		&svc.TaxExempt,
 		&svc.CreatedAt,
 		&svc.UpdatedAt,
 	); err != nil {
@@ -843,6 +1160,7 @@ func scanStaff(row pgx.Row) (*model.Staff, error) {
 	if err := row.Scan(
 		&st.ID,
 		&st.SalonID,
+		&st.BranchID,
 		&st.Name,
 		&phone,
 		&email,
@@ -853,6 +1171,7 @@ func scanStaff(row pgx.Row) (*model.Staff, error) {
 		&shiftsRaw,
 		&st.CreatedAt,
 		&st.UpdatedAt,
+		&st.DeletedAt,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -864,7 +1183,7 @@ func scanStaff(row pgx.Row) (*model.Staff, error) {
 	st.Role = role
 	st.Specialization = special
 	st.Photo = photo
-	st.Shifts = decodeJSONMap(shiftsRaw)
+	st.Shifts = decodeShifts(shiftsRaw)
 	return &st, nil
 }
 
@@ -879,6 +1198,26 @@ func mapToJSONB(m map[string]any) ([]byte, error) {
 	return buf, nil
 }
 
+func shiftsToJSONB(shifts model.Shifts) ([]byte, error) {
+	if shifts == nil {
+		return nil, nil
+	}
+	return json.Marshal(shifts)
+}
+
+// decodeShifts decodes stored shifts, treating rows written before shifts were
+// typed that do not match the structure as having no shifts
+func decodeShifts(data []byte) model.Shifts {
+	if len(data) == 0 {
+		return nil
+	}
+	var shifts model.Shifts
+	if err := json.Unmarshal(data, &shifts); err != nil {
+		return nil
+	}
+	return shifts
+}
+
 func arrayOrNil[T any](in []T) []T {
 	if len(in) == 0 {
 		return nil
@@ -910,7 +1249,7 @@ func (s *Store) StaffHasAccessToSalon(ctx context.Context, staffID, salonID stri
 	err := s.db.QueryRow(ctx, `
 		SELECT EXISTS(
 			SELECT 1 FROM staff 
-			WHERE id = $1 AND salon_id = $2 AND status = 'active'
+			WHERE id = $1 AND salon_id = $2 AND status = 'active' AND deleted_at IS NULL
 		)
 	`, staffID, salonID).Scan(&exists)
 	
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"salon-service/internal/model"
+)
+
+// ErrTimeOffOverlap is returned when a time-off block overlaps another block of the same staff member
+var ErrTimeOffOverlap = errors.New("time off overlaps an existing block")
+
+const timeOffDateLayout = "2006-01-02"
+
+// CreateStaffTimeOff stores a time-off block unless it overlaps one the staff
+// member already has, in which case ErrTimeOffOverlap is returned. The staff
+// row is locked so concurrent requests cannot both pass the overlap check.
+func (s *Store) CreateStaffTimeOff(ctx context.Context, salonID, staffID string, startDate, endDate time.Time, reason *string) (timeOff *model.StaffTimeOff, err error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	var exists bool
+	if err = tx.QueryRow(ctx, `SELECT true FROM staff WHERE id = $1 AND salon_id = $2 AND deleted_at IS NULL FOR UPDATE`, staffID, salonID).Scan(&exists); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `
+		INSERT INTO staff_time_off (id, staff_id, start_date, end_date, reason, created_at)
+		SELECT gen_random_uuid(), $1, $2, $3, $4, NOW()
+		WHERE NOT EXISTS (
+			SELECT 1 FROM staff_time_off
+			WHERE staff_id = $1 AND start_date <= $3 AND end_date >= $2
+		)
+		RETURNING id, staff_id, start_date, end_date, reason, created_at
+	`, staffID, startDate, endDate, reason)
+	timeOff, err = scanStaffTimeOff(row)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrTimeOffOverlap
+	}
+	return timeOff, err
+}
+
+// ListStaffTimeOff lists a staff member's time-off blocks ending on or after
+// from, earliest first
+func (s *Store) ListStaffTimeOff(ctx context.Context, staffID string, from time.Time) ([]*model.StaffTimeOff, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, staff_id, start_date, end_date, reason, created_at
+		FROM staff_time_off
+		WHERE staff_id = $1 AND end_date >= $2
+		ORDER BY start_date, id
+	`, staffID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []*model.StaffTimeOff{}
+	for rows.Next() {
+		timeOff, err := scanStaffTimeOff(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, timeOff)
+	}
+	return blocks, rows.Err()
+}
+
+// DeleteStaffTimeOff removes a time-off block of the staff member
+func (s *Store) DeleteStaffTimeOff(ctx context.Context, staffID, timeOffID string) error {
+	ct, err := s.db.Exec(ctx, `DELETE FROM staff_time_off WHERE id = $1 AND staff_id = $2`, timeOffID, staffID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IsStaffOnLeave reports whether any time-off block of the staff member covers date
+func (s *Store) IsStaffOnLeave(ctx context.Context, staffID string, date time.Time) (bool, error) {
+	var onLeave bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM staff_time_off
+			WHERE staff_id = $1 AND start_date <= $2 AND end_date >= $2
+		)
+	`, staffID, date).Scan(&onLeave)
+	return onLeave, err
+}
+
+func scanStaffTimeOff(row pgx.Row) (*model.StaffTimeOff, error) {
+	var (
+		timeOff   model.StaffTimeOff
+		startDate time.Time
+		endDate   time.Time
+	)
+	if err := row.Scan(&timeOff.ID, &timeOff.StaffID, &startDate, &endDate, &timeOff.Reason, &timeOff.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	timeOff.StartDate = startDate.Format(timeOffDateLayout)
+	timeOff.EndDate = endDate.Format(timeOffDateLayout)
+	return &timeOff, nil
+}
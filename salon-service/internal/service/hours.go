@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"salon-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/hours"
+)
+
+// SetSalonHours replaces the salon's default weekly hours and date overrides.
+// Branches without hours of their own inherit them.
+func (s *salonService) SetSalonHours(ctx context.Context, salonID string, workingHours hours.WorkingHours) (*model.Salon, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if errs := workingHours.Validate(); len(errs) > 0 {
+		return nil, errs
+	}
+	return s.repo.UpdateSalonWorkingHours(ctx, salonID, workingHours.ToMap())
+}
+
+// SetBranchHours replaces a branch's weekly hours and date overrides. An
+// empty weekly schedule makes the branch fall back to the salon hours.
+func (s *salonService) SetBranchHours(ctx context.Context, salonID, branchID string, workingHours hours.WorkingHours) (*model.Branch, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("branch_id", branchID); err != nil {
+		return nil, err
+	}
+	if errs := workingHours.Validate(); len(errs) > 0 {
+		return nil, errs
+	}
+	return s.repo.UpdateBranchWorkingHours(ctx, salonID, branchID, workingHours.ToMap())
+}
+
+// GetEffectiveHours resolves the opening hours on date for a branch, or for
+// the salon itself when branchID is nil (see hours.Resolve for precedence)
+func (s *salonService) GetEffectiveHours(ctx context.Context, salonID string, branchID *string, date time.Time) (*hours.Effective, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if branchID != nil {
+		if err := validateUUID("branch_id", *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	salon, err := s.repo.GetSalon(ctx, salonID)
+	if err != nil {
+		return nil, err
+	}
+	var branch *model.Branch
+	if branchID != nil {
+		if branch, err = s.repo.GetBranch(ctx, salonID, *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	effective := resolveHours(salon, branch, date)
+	return &effective, nil
+}
+
+// resolveHours applies hours.Resolve to a salon and an optional branch
+func resolveHours(salon *model.Salon, branch *model.Branch, date time.Time) hours.Effective {
+	var salonSource, branchSource hours.Source
+	if salon != nil {
+		salonSource = hours.Source{WorkingHours: salon.WorkingHours, Holidays: salon.Holidays}
+	}
+	if branch != nil {
+		branchSource = hours.Source{WorkingHours: branch.WorkingHours, Holidays: branch.Holidays}
+	}
+	return hours.Resolve(date, branchSource, salonSource)
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"salon-service/internal/model"
 
@@ -188,6 +191,7 @@ type CreateServiceParams struct {
 	Price           float64
 	Tags            []string
 	Status          model.ServiceStatus
+	TaxExempt       bool
 }
 
 func (p CreateServiceParams) Validate() error {
@@ -226,6 +230,7 @@ type UpdateServiceParams struct {
 	Price           float64
 	Tags            []string
 	Status          model.ServiceStatus
+	TaxExempt       bool
 }
 
 func (p UpdateServiceParams) Validate() error {
@@ -259,6 +264,7 @@ func (p UpdateServiceParams) Validate() error {
 
 type CreateStaffParams struct {
 	SalonID        string
+	BranchID       *string
 	Name           string
 	PhoneNumber    string
 	Email          *string
@@ -266,7 +272,7 @@ type CreateStaffParams struct {
 	Specialization *string
 	Photo          *string
 	Status         model.StaffStatus
-	Shifts         map[string]any
+	Shifts         model.Shifts
 }
 
 func (p CreateStaffParams) Validate() error {
@@ -274,6 +280,11 @@ func (p CreateStaffParams) Validate() error {
 	if _, err := uuid.Parse(strings.TrimSpace(p.SalonID)); err != nil {
 		errs = sharederrors.AppendValidationError(errs, "salon_id", "must be a valid UUID")
 	}
+	if p.BranchID != nil {
+		if _, err := uuid.Parse(strings.TrimSpace(*p.BranchID)); err != nil {
+			errs = sharederrors.AppendValidationError(errs, "branch_id", "must be a valid UUID")
+		}
+	}
 	if strings.TrimSpace(p.Name) == "" {
 		errs = sharederrors.AppendValidationError(errs, "name", "is required")
 	}
@@ -283,9 +294,13 @@ func (p CreateStaffParams) Validate() error {
 	if p.Email != nil && strings.TrimSpace(*p.Email) == "" {
 		errs = sharederrors.AppendValidationError(errs, "email", "must not be empty if provided")
 	}
+	if p.Role != nil && !isValidStaffRole(*p.Role) {
+		errs = sharederrors.AppendValidationError(errs, "role", "must be 'owner', 'manager' or 'stylist'")
+	}
 	if !isValidStaffStatus(p.Status) {
 		errs = sharederrors.AppendValidationError(errs, "status", "must be 'active' or 'inactive'")
 	}
+	errs = validateShifts(errs, p.Shifts)
 	if len(errs) > 0 {
 		return errs
 	}
@@ -302,7 +317,7 @@ type UpdateStaffParams struct {
 	Specialization *string
 	Photo          *string
 	Status         model.StaffStatus
-	Shifts         map[string]any
+	Shifts         model.Shifts
 }
 
 func (p UpdateStaffParams) Validate() error {
@@ -322,17 +337,73 @@ func (p UpdateStaffParams) Validate() error {
 	if p.Email != nil && strings.TrimSpace(*p.Email) == "" {
 		errs = sharederrors.AppendValidationError(errs, "email", "must not be empty if provided")
 	}
+	if p.Role != nil && !isValidStaffRole(*p.Role) {
+		errs = sharederrors.AppendValidationError(errs, "role", "must be 'owner', 'manager' or 'stylist'")
+	}
 	if !isValidStaffStatus(p.Status) {
 		errs = sharederrors.AppendValidationError(errs, "status", "must be 'active' or 'inactive'")
 	}
+	errs = validateShifts(errs, p.Shifts)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// maxTimeOffDays bounds a single time-off block
+const maxTimeOffDays = 366
+
+type CreateStaffTimeOffParams struct {
+	SalonID   string
+	StaffID   string
+	StartDate string
+	EndDate   string
+	Reason    *string
+}
+
+func (p CreateStaffTimeOffParams) Validate() error {
+	var errs sharederrors.ValidationErrors
+	if _, err := uuid.Parse(strings.TrimSpace(p.SalonID)); err != nil {
+		errs = sharederrors.AppendValidationError(errs, "salon_id", "must be a valid UUID")
+	}
+	if _, err := uuid.Parse(strings.TrimSpace(p.StaffID)); err != nil {
+		errs = sharederrors.AppendValidationError(errs, "staff_id", "must be a valid UUID")
+	}
+	start, startErr := time.Parse("2006-01-02", strings.TrimSpace(p.StartDate))
+	if startErr != nil {
+		errs = sharederrors.AppendValidationError(errs, "start_date", "must be a YYYY-MM-DD date")
+	}
+	end, endErr := time.Parse("2006-01-02", strings.TrimSpace(p.EndDate))
+	if endErr != nil {
+		errs = sharederrors.AppendValidationError(errs, "end_date", "must be a YYYY-MM-DD date")
+	}
+	if startErr == nil && endErr == nil {
+		if end.Before(start) {
+			errs = sharederrors.AppendValidationError(errs, "end_date", "must not be before start_date")
+		} else if end.Sub(start) >= maxTimeOffDays*24*time.Hour {
+			errs = sharederrors.AppendValidationError(errs, "end_date", fmt.Sprintf("time off cannot exceed %d days", maxTimeOffDays))
+		}
+	}
+	if p.Reason != nil && len(*p.Reason) > 500 {
+		errs = sharederrors.AppendValidationError(errs, "reason", "must be at most 500 characters")
+	}
 	if len(errs) > 0 {
 		return errs
 	}
 	return nil
 }
 
+// dates returns the parsed start and end dates of validated params
+func (p CreateStaffTimeOffParams) dates() (start, end time.Time) {
+	start, _ = time.Parse("2006-01-02", strings.TrimSpace(p.StartDate))
+	end, _ = time.Parse("2006-01-02", strings.TrimSpace(p.EndDate))
+	return start, end
+}
+
 type RequestStaffOTPParams struct {
 	PhoneNumber string
+	// Channel optionally picks the first delivery channel; empty uses the configured order
+	Channel string
 }
 
 func (p RequestStaffOTPParams) Validate() error {
@@ -382,3 +453,97 @@ func isValidStaffStatus(status model.StaffStatus) bool {
 		return false
 	}
 }
+
+func isValidStaffRole(role string) bool {
+	switch role {
+	case model.StaffRoleOwner, model.StaffRoleManager, model.StaffRoleStylist:
+		return true
+	default:
+		return false
+	}
+}
+
+var weekdays = map[string]bool{
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true,
+	"friday": true, "saturday": true, "sunday": true,
+}
+
+// validateShifts checks that every shift block and break has well-formed
+// HH:MM times with the end after the start, that blocks on the same day do not
+// overlap, and that breaks fall within their block without overlapping
+func validateShifts(errs sharederrors.ValidationErrors, shifts model.Shifts) sharederrors.ValidationErrors {
+	days := make([]string, 0, len(shifts))
+	for day := range shifts {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		if !weekdays[day] {
+			errs = sharederrors.AppendValidationError(errs, "shifts."+day, "must be a lowercase weekday name")
+			continue
+		}
+
+		var blocks []clockSpan
+		for i, block := range shifts[day] {
+			field := fmt.Sprintf("shifts.%s[%d]", day, i)
+			span, ok := parseClockSpan(block.Start, block.End)
+			if !ok {
+				errs = sharederrors.AppendValidationError(errs, field, "start and end must be HH:MM times with end after start")
+				continue
+			}
+			if other := overlapping(blocks, span); other >= 0 {
+				errs = sharederrors.AppendValidationError(errs, field, fmt.Sprintf("overlaps shifts.%s[%d]", day, blocks[other].index))
+			}
+			span.index = i
+			blocks = append(blocks, span)
+
+			var breaks []clockSpan
+			for j, br := range block.Breaks {
+				breakField := fmt.Sprintf("%s.breaks[%d]", field, j)
+				breakSpan, ok := parseClockSpan(br.Start, br.End)
+				if !ok {
+					errs = sharederrors.AppendValidationError(errs, breakField, "start and end must be HH:MM times with end after start")
+					continue
+				}
+				if breakSpan.start < span.start || breakSpan.end > span.end {
+					errs = sharederrors.AppendValidationError(errs, breakField, fmt.Sprintf("must be within the shift %s-%s", block.Start, block.End))
+				}
+				if other := overlapping(breaks, breakSpan); other >= 0 {
+					errs = sharederrors.AppendValidationError(errs, breakField, fmt.Sprintf("overlaps %s.breaks[%d]", field, breaks[other].index))
+				}
+				breakSpan.index = j
+				breaks = append(breaks, breakSpan)
+			}
+		}
+	}
+	return errs
+}
+
+// clockSpan is a time range in minutes since midnight
+type clockSpan struct {
+	start, end int
+	index      int
+}
+
+func parseClockSpan(start, end string) (clockSpan, bool) {
+	s, err := time.Parse("15:04", strings.TrimSpace(start))
+	if err != nil {
+		return clockSpan{}, false
+	}
+	e, err := time.Parse("15:04", strings.TrimSpace(end))
+	if err != nil || !e.After(s) {
+		return clockSpan{}, false
+	}
+	return clockSpan{start: s.Hour()*60 + s.Minute(), end: e.Hour()*60 + e.Minute()}, true
+}
+
+// overlapping returns the position in spans of the first span overlapping span, or -1
+func overlapping(spans []clockSpan, span clockSpan) int {
+	for i, other := range spans {
+		if span.start < other.end && other.start < span.end {
+			return i
+		}
+	}
+	return -1
+}
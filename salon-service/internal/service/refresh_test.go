@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"salon-service/internal/model"
+	"salon-service/internal/repository"
+
+	sharedauth "github.com/EricsAntony/salon/salon-shared/auth"
+	sharedconfig "github.com/EricsAntony/salon/salon-shared/config"
+	"github.com/google/uuid"
+)
+
+// fakeStaffRepository serves staff from memory. Methods a test does not need
+// are left to the embedded nil interface and panic if called.
+type fakeStaffRepository struct {
+	repository.SalonRepository
+
+	staff map[string]*model.Staff
+}
+
+// GetStaff is scoped to a salon like the real query, so looking staff up
+// with their own ID as the salon ID finds nothing
+func (f *fakeStaffRepository) GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error) {
+	staff, ok := f.staff[staffID]
+	if !ok || staff.SalonID != salonID {
+		return nil, repository.ErrNotFound
+	}
+	return staff, nil
+}
+
+func (f *fakeStaffRepository) GetStaffByID(ctx context.Context, staffID string) (*model.Staff, error) {
+	staff, ok := f.staff[staffID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return staff, nil
+}
+
+// fakeStaffAuthRepository keeps refresh token hashes in memory. Methods a
+// test does not need are left to the embedded nil interface and panic if called.
+type fakeStaffAuthRepository struct {
+	repository.StaffAuthRepository
+
+	mu sync.Mutex
+	// refreshTokens holds the valid token hashes by staff ID
+	refreshTokens map[string]map[string]bool
+}
+
+func (f *fakeStaffAuthRepository) CreateRefreshToken(ctx context.Context, staffID, tokenHash string, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refreshTokens[staffID] == nil {
+		f.refreshTokens[staffID] = map[string]bool{}
+	}
+	f.refreshTokens[staffID][tokenHash] = true
+	return nil
+}
+
+func (f *fakeStaffAuthRepository) IsRefreshTokenValid(ctx context.Context, staffID, tokenHash string, now time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.refreshTokens[staffID][tokenHash], nil
+}
+
+func (f *fakeStaffAuthRepository) RevokeRefreshToken(ctx context.Context, staffID, tokenHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.refreshTokens[staffID], tokenHash)
+	return nil
+}
+
+// Refreshing used to look staff up with GetStaff(ctx, staffID, staffID),
+// which never matches a salon, so every valid refresh failed
+func TestRefreshStaffSession(t *testing.T) {
+	staff := &model.Staff{ID: uuid.NewString(), SalonID: uuid.NewString(), Status: model.StaffStatusActive}
+	const refreshToken = "issued-refresh-token"
+
+	cfg := &sharedconfig.Config{}
+	cfg.JWT.AccessSecret = "test-access-secret"
+	cfg.JWT.RefreshSecret = "test-refresh-secret"
+	cfg.JWT.AccessTTLMinutes = 5
+	cfg.JWT.RefreshTTLDays = 1
+	jwt := sharedauth.NewJWTManager(cfg)
+
+	authRepo := &fakeStaffAuthRepository{refreshTokens: map[string]map[string]bool{}}
+	if err := authRepo.CreateRefreshToken(context.Background(), staff.ID, sharedauth.HashString(refreshToken), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	s := &salonService{
+		repo:     &fakeStaffRepository{staff: map[string]*model.Staff{staff.ID: staff}},
+		authRepo: authRepo,
+		cfg:      cfg,
+		jwt:      jwt,
+	}
+
+	result, err := s.RefreshStaffSession(context.Background(), staff.ID, refreshToken)
+	if err != nil {
+		t.Fatalf("RefreshStaffSession with a valid refresh token: %v", err)
+	}
+	if result.Staff == nil || result.Staff.ID != staff.ID {
+		t.Errorf("refreshed session is for staff %v, want %s", result.Staff, staff.ID)
+	}
+	claims, err := jwt.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("issued access token is invalid: %v", err)
+	}
+	if claims.UserID != staff.ID || claims.UserType != sharedauth.UserTypeSalon {
+		t.Errorf("access token is for %s/%s, want %s/%s", claims.UserID, claims.UserType, staff.ID, sharedauth.UserTypeSalon)
+	}
+	if !authRepo.refreshTokens[staff.ID][sharedauth.HashString(result.RefreshToken)] {
+		t.Error("issued refresh token was not stored")
+	}
+
+	if _, err := s.RefreshStaffSession(context.Background(), staff.ID, refreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("replaying a rotated refresh token: got %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+	if _, err := s.RefreshStaffSession(context.Background(), uuid.NewString(), result.RefreshToken); !errors.Is(err, ErrStaffNotFound) {
+		t.Errorf("refreshing for unknown staff: got %v, want %v", err, ErrStaffNotFound)
+	}
+}
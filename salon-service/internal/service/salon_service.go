@@ -13,23 +13,39 @@ import (
 	sharedauth "github.com/EricsAntony/salon/salon-shared/auth"
 	sharedconfig "github.com/EricsAntony/salon/salon-shared/config"
 	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/hours"
+	"github.com/EricsAntony/salon/salon-shared/lockout"
+	"github.com/EricsAntony/salon/salon-shared/pagination"
 	sharedvalidation "github.com/EricsAntony/salon/salon-shared/validation"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type SalonService interface {
 	CreateSalon(ctx context.Context, params CreateSalonParams) (*model.Salon, error)
 	GetSalon(ctx context.Context, id string) (*model.Salon, error)
-	ListSalons(ctx context.Context) ([]*model.Salon, error)
+	ListSalons(ctx context.Context, page pagination.Params, includeDeleted bool) (*pagination.ListResponse[*model.Salon], error)
 	UpdateSalon(ctx context.Context, params UpdateSalonParams) (*model.Salon, error)
 	DeleteSalon(ctx context.Context, id string) error
+	RestoreSalon(ctx context.Context, id string) (*model.Salon, error)
+	// SearchSalonsNearby pages salons within radiusKm of a point, nearest
+	// first. A zero radius uses DefaultSearchRadiusKm; larger radii are capped
+	// at MaxSearchRadiusKm.
+	SearchSalonsNearby(ctx context.Context, lat, lng, radiusKm float64, filter model.NearbySalonFilter, page pagination.Params) (*pagination.ListResponse[*model.NearbySalon], error)
 
 	CreateBranch(ctx context.Context, params CreateBranchParams) (*model.Branch, error)
 	GetBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error)
-	ListBranches(ctx context.Context, salonID string) ([]*model.Branch, error)
+	ListBranches(ctx context.Context, salonID string, includeDeleted bool) ([]*model.Branch, error)
 	UpdateBranch(ctx context.Context, params UpdateBranchParams) (*model.Branch, error)
 	DeleteBranch(ctx context.Context, salonID, branchID string) error
+	RestoreBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error)
+
+	SetSalonHours(ctx context.Context, salonID string, workingHours hours.WorkingHours) (*model.Salon, error)
+	SetBranchHours(ctx context.Context, salonID, branchID string, workingHours hours.WorkingHours) (*model.Branch, error)
+	// GetEffectiveHours resolves the hours in effect on date for a branch, or
+	// for the salon when branchID is nil
+	GetEffectiveHours(ctx context.Context, salonID string, branchID *string, date time.Time) (*hours.Effective, error)
 
 	CreateCategory(ctx context.Context, params CreateCategoryParams) (*model.Category, error)
 	ListCategories(ctx context.Context, salonID string) ([]*model.Category, error)
@@ -38,16 +54,31 @@ type SalonService interface {
 
 	CreateService(ctx context.Context, params CreateServiceParams) (*model.Service, error)
 	ListServices(ctx context.Context, salonID string, categoryID *string) ([]*model.Service, error)
+	ListServicesFiltered(ctx context.Context, salonID string, filter model.ServiceFilter, page pagination.Params) (*pagination.ListResponse[*model.Service], error)
 	UpdateService(ctx context.Context, params UpdateServiceParams) (*model.Service, error)
 	DeleteService(ctx context.Context, salonID, serviceID string) error
 
 	CreateStaff(ctx context.Context, params CreateStaffParams) (*model.Staff, error)
 	ListStaff(ctx context.Context, salonID string, status *model.StaffStatus) ([]*model.Staff, error)
+	ListStaffFiltered(ctx context.Context, salonID string, filter model.StaffFilter) ([]*model.Staff, error)
 	UpdateStaff(ctx context.Context, params UpdateStaffParams) (*model.Staff, error)
 	DeleteStaff(ctx context.Context, salonID, staffID string) error
+	GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error)
+	AssignStaffBranch(ctx context.Context, salonID, staffID string, branchID *string) (*model.Staff, error)
+	RestoreStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error)
 	SetStaffServices(ctx context.Context, salonID, staffID string, serviceIDs []string) error
-	ListStaffServices(ctx context.Context, staffID string) ([]string, error)
-	RequestStaffOTP(ctx context.Context, params RequestStaffOTPParams) error
+	ListStaffServices(ctx context.Context, salonID, staffID string) ([]string, error)
+	// AddStaffService and RemoveStaffService change a single assignment
+	// idempotently and return the staff member's resulting service IDs
+	AddStaffService(ctx context.Context, salonID, staffID, serviceID string) ([]string, error)
+	RemoveStaffService(ctx context.Context, salonID, staffID, serviceID string) ([]string, error)
+	CreateStaffTimeOff(ctx context.Context, params CreateStaffTimeOffParams) (*model.StaffTimeOff, error)
+	ListStaffTimeOff(ctx context.Context, salonID, staffID string, from time.Time) ([]*model.StaffTimeOff, error)
+	DeleteStaffTimeOff(ctx context.Context, salonID, staffID, timeOffID string) error
+	GetStaffSchedule(ctx context.Context, salonID, staffID string, branchID *string, date time.Time) (*model.StylistSchedule, error)
+	// RequestStaffOTP sends an OTP to the staff phone and returns the code so
+	// non-production environments can echo it, with the channel that delivered it
+	RequestStaffOTP(ctx context.Context, params RequestStaffOTPParams) (*StaffOTPSent, error)
 	AuthenticateStaff(ctx context.Context, params AuthenticateStaffParams) (*AuthenticateStaffResult, error)
 	RefreshStaffSession(ctx context.Context, staffID, refreshToken string) (*AuthenticateStaffResult, error)
 
@@ -55,11 +86,24 @@ type SalonService interface {
 }
 
 type salonService struct {
-	repo      *repository.Store
-	authRepo  repository.StaffAuthRepository
-	cfg       *sharedconfig.Config
-	jwt       JWTIssuer
-	otpExpiry time.Duration
+	repo          repository.SalonRepository
+	authRepo      repository.StaffAuthRepository
+	cfg           *sharedconfig.Config
+	jwt           JWTIssuer
+	otpExpiry     time.Duration
+	otpPolicy     OTPPolicy
+	lockouts      *lockout.Guard
+	otpDispatcher *sharedauth.OTPDispatcher
+}
+
+// OTPPolicy limits staff OTP requests and verification attempts per phone number
+type OTPPolicy struct {
+	MaxFailedAttempts int
+	FailureWindow     time.Duration
+	// LockoutDuration is how long a phone stays locked after MaxFailedAttempts
+	LockoutDuration  time.Duration
+	RequestsPerPhone int
+	RequestWindow     time.Duration
 }
 
 type JWTIssuer interface {
@@ -69,13 +113,20 @@ type JWTIssuer interface {
 	GenerateRefreshTokenWithType(userID, userType string) (string, time.Time, error)
 }
 
-func New(repo *repository.Store, authRepo repository.StaffAuthRepository, cfg *sharedconfig.Config, jwt JWTIssuer) SalonService {
+func New(repo repository.SalonRepository, authRepo repository.StaffAuthRepository, lockouts lockout.Store, cfg *sharedconfig.Config, jwt JWTIssuer, otpPolicy OTPPolicy, otpDispatcher *sharedauth.OTPDispatcher) SalonService {
 	return &salonService{
-		repo:      repo,
-		authRepo:  authRepo,
-		cfg:       cfg,
-		jwt:       jwt,
-		otpExpiry: time.Duration(cfg.OTP.ExpiryMinutes) * time.Minute,
+		repo:          repo,
+		authRepo:      authRepo,
+		cfg:           cfg,
+		jwt:           jwt,
+		otpExpiry:     time.Duration(cfg.OTP.ExpiryMinutes) * time.Minute,
+		otpPolicy:     otpPolicy,
+		otpDispatcher: otpDispatcher,
+		lockouts: lockout.NewGuard(lockouts, lockout.Policy{
+			MaxFailures: otpPolicy.MaxFailedAttempts,
+			Window:      otpPolicy.FailureWindow,
+			Duration:    otpPolicy.LockoutDuration,
+		}, cfg.Log.ServiceName),
 	}
 }
 
@@ -111,8 +162,56 @@ func (s *salonService) GetSalon(ctx context.Context, id string) (*model.Salon, e
 }
 
 
-func (s *salonService) ListSalons(ctx context.Context) ([]*model.Salon, error) {
-	return s.repo.ListSalons(ctx)
+func (s *salonService) ListSalons(ctx context.Context, page pagination.Params, includeDeleted bool) (*pagination.ListResponse[*model.Salon], error) {
+	page = page.Normalize()
+	salons, total, err := s.repo.ListSalonsPage(ctx, page, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return pagination.NewListResponse(salons, total, page), nil
+}
+
+// Proximity search radius bounds in kilometres
+const (
+	DefaultSearchRadiusKm = 10.0
+	MaxSearchRadiusKm     = 50.0
+)
+
+func (s *salonService) SearchSalonsNearby(ctx context.Context, lat, lng, radiusKm float64, filter model.NearbySalonFilter, page pagination.Params) (*pagination.ListResponse[*model.NearbySalon], error) {
+	var errs sharederrors.ValidationErrors
+	if !(lat >= -90 && lat <= 90) {
+		errs = sharederrors.AppendValidationError(errs, "lat", "must be between -90 and 90")
+	}
+	if !(lng >= -180 && lng <= 180) {
+		errs = sharederrors.AppendValidationError(errs, "lng", "must be between -180 and 180")
+	}
+	if !(radiusKm >= 0) {
+		errs = sharederrors.AppendValidationError(errs, "radius", "must be greater than 0")
+	}
+	if filter.ServiceID != nil {
+		if _, err := uuid.Parse(*filter.ServiceID); err != nil {
+			errs = sharederrors.AppendValidationError(errs, "service_id", "must be a valid UUID")
+		}
+	}
+	if filter.CategoryID != nil {
+		if _, err := uuid.Parse(*filter.CategoryID); err != nil {
+			errs = sharederrors.AppendValidationError(errs, "category_id", "must be a valid UUID")
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if radiusKm == 0 {
+		radiusKm = DefaultSearchRadiusKm
+	}
+	radiusKm = min(radiusKm, MaxSearchRadiusKm)
+	page = page.Normalize()
+	salons, total, err := s.repo.SearchSalonsNearby(ctx, lat, lng, radiusKm, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return pagination.NewListResponse(salons, total, page), nil
 }
 
 func (s *salonService) UpdateSalon(ctx context.Context, params UpdateSalonParams) (*model.Salon, error) {
@@ -146,6 +245,13 @@ func (s *salonService) DeleteSalon(ctx context.Context, id string) error {
 	return s.repo.DeleteSalon(ctx, id)
 }
 
+func (s *salonService) RestoreSalon(ctx context.Context, id string) (*model.Salon, error) {
+	if err := validateUUID("salon_id", id); err != nil {
+		return nil, err
+	}
+	return s.repo.RestoreSalon(ctx, id)
+}
+
 func (s *salonService) CreateBranch(ctx context.Context, params CreateBranchParams) (*model.Branch, error) {
 	if err := params.Validate(); err != nil {
 		return nil, err
@@ -174,11 +280,11 @@ func (s *salonService) GetBranch(ctx context.Context, salonID, branchID string)
 	return s.repo.GetBranch(ctx, salonID, branchID)
 }
 
-func (s *salonService) ListBranches(ctx context.Context, salonID string) ([]*model.Branch, error) {
+func (s *salonService) ListBranches(ctx context.Context, salonID string, includeDeleted bool) ([]*model.Branch, error) {
 	if err := validateUUID("salon_id", salonID); err != nil {
 		return nil, err
 	}
-	return s.repo.ListBranches(ctx, salonID)
+	return s.repo.ListBranches(ctx, salonID, includeDeleted)
 }
 
 func (s *salonService) UpdateBranch(ctx context.Context, params UpdateBranchParams) (*model.Branch, error) {
@@ -209,6 +315,16 @@ func (s *salonService) DeleteBranch(ctx context.Context, salonID, branchID strin
 	return s.repo.DeleteBranch(ctx, salonID, branchID)
 }
 
+func (s *salonService) RestoreBranch(ctx context.Context, salonID, branchID string) (*model.Branch, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("branch_id", branchID); err != nil {
+		return nil, err
+	}
+	return s.repo.RestoreBranch(ctx, salonID, branchID)
+}
+
 func (s *salonService) CreateCategory(ctx context.Context, params CreateCategoryParams) (*model.Category, error) {
 	if err := params.Validate(); err != nil {
 		return nil, err
@@ -266,6 +382,7 @@ func (s *salonService) CreateService(ctx context.Context, params CreateServicePa
 		Price:       params.Price,
 		Tags:        params.Tags,
 		Status:      params.Status,
+		TaxExempt:   params.TaxExempt,
 	}
 	return s.repo.CreateService(ctx, service)
 }
@@ -282,6 +399,36 @@ func (s *salonService) ListServices(ctx context.Context, salonID string, categor
 	return s.repo.ListServices(ctx, salonID, categoryID)
 }
 
+func (s *salonService) ListServicesFiltered(ctx context.Context, salonID string, filter model.ServiceFilter, page pagination.Params) (*pagination.ListResponse[*model.Service], error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if filter.CategoryID != nil {
+		if err := validateUUID("category_id", *filter.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	var errs sharederrors.ValidationErrors
+	if filter.MinPrice != nil && *filter.MinPrice < 0 {
+		errs = sharederrors.AppendValidationError(errs, "min_price", "must not be negative")
+	}
+	if filter.MaxPrice != nil && *filter.MaxPrice < 0 {
+		errs = sharederrors.AppendValidationError(errs, "max_price", "must not be negative")
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		errs = sharederrors.AppendValidationError(errs, "min_price", "must not exceed max_price")
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	page = page.Normalize()
+	services, total, err := s.repo.ListServicesFiltered(ctx, salonID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return pagination.NewListResponse(services, total, page), nil
+}
+
 func (s *salonService) UpdateService(ctx context.Context, params UpdateServiceParams) (*model.Service, error) {
 	if err := params.Validate(); err != nil {
 		return nil, err
@@ -296,6 +443,7 @@ func (s *salonService) UpdateService(ctx context.Context, params UpdateServicePa
 		Price:       params.Price,
 		Tags:        params.Tags,
 		Status:      params.Status,
+		TaxExempt:   params.TaxExempt,
 	}
 	return s.repo.UpdateService(ctx, service)
 }
@@ -320,9 +468,15 @@ func (s *salonService) CreateStaff(ctx context.Context, params CreateStaffParams
 		return nil, err
 	}
 	params.PhoneNumber = normalizedPhone
+	if params.BranchID != nil {
+		if err := s.ensureBranchInSalon(ctx, params.SalonID, *params.BranchID); err != nil {
+			return nil, err
+		}
+	}
 	staff := &model.Staff{
 		ID:             uuid.NewString(),
 		SalonID:        params.SalonID,
+		BranchID:       params.BranchID,
 		Name:           params.Name,
 		PhoneNumber:    params.PhoneNumber,
 		Email:          params.Email,
@@ -343,10 +497,22 @@ func (s *salonService) CreateStaff(ctx context.Context, params CreateStaffParams
 }
 
 func (s *salonService) ListStaff(ctx context.Context, salonID string, status *model.StaffStatus) ([]*model.Staff, error) {
+	return s.ListStaffFiltered(ctx, salonID, model.StaffFilter{Status: status})
+}
+
+func (s *salonService) ListStaffFiltered(ctx context.Context, salonID string, filter model.StaffFilter) ([]*model.Staff, error) {
 	if err := validateUUID("salon_id", salonID); err != nil {
 		return nil, err
 	}
-	return s.repo.ListStaff(ctx, salonID, status)
+	if filter.Status != nil && !isValidStaffStatus(*filter.Status) {
+		return nil, sharederrors.NewValidationError("status", "must be 'active' or 'inactive'")
+	}
+	if filter.BranchID != nil {
+		if err := validateUUID("branch_id", *filter.BranchID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.ListStaffFiltered(ctx, salonID, filter)
 }
 
 func (s *salonService) UpdateStaff(ctx context.Context, params UpdateStaffParams) (*model.Staff, error) {
@@ -391,6 +557,65 @@ func (s *salonService) DeleteStaff(ctx context.Context, salonID, staffID string)
 	return s.repo.DeleteStaff(ctx, salonID, staffID)
 }
 
+func (s *salonService) GetStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStaff(ctx, salonID, staffID)
+}
+
+// AssignStaffBranch moves a staff member to a branch of their salon, or
+// unassigns them from any branch when branchID is nil
+func (s *salonService) AssignStaffBranch(ctx context.Context, salonID, staffID string, branchID *string) (*model.Staff, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return nil, err
+	}
+	if branchID != nil {
+		if err := validateUUID("branch_id", *branchID); err != nil {
+			return nil, err
+		}
+		if err := s.ensureBranchInSalon(ctx, salonID, *branchID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.SetStaffBranch(ctx, salonID, staffID, branchID)
+}
+
+// ensureBranchInSalon rejects branches that do not exist within the salon
+func (s *salonService) ensureBranchInSalon(ctx context.Context, salonID, branchID string) error {
+	if _, err := s.repo.GetBranch(ctx, salonID, branchID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return sharederrors.NewValidationError("branch_id", "must reference a branch of this salon")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *salonService) RestoreStaff(ctx context.Context, salonID, staffID string) (*model.Staff, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return nil, err
+	}
+	restored, err := s.repo.RestoreStaff(ctx, salonID, staffID)
+	if err != nil {
+		// Another active staff member took over the phone number while this one was deleted
+		if repository.IsUniqueViolation(err) {
+			return nil, sharederrors.NewConflictError("staff", "phone number is registered to another staff member")
+		}
+		return nil, err
+	}
+	return restored, nil
+}
+
 func (s *salonService) SetStaffServices(ctx context.Context, salonID, staffID string, serviceIDs []string) error {
 	if err := validateUUID("salon_id", salonID); err != nil {
 		return err
@@ -403,48 +628,155 @@ func (s *salonService) SetStaffServices(ctx context.Context, salonID, staffID st
 			return err
 		}
 	}
+	if err := s.ensureStaffInSalon(ctx, salonID, staffID); err != nil {
+		return err
+	}
 	return s.repo.SetStaffServices(ctx, salonID, staffID, serviceIDs)
 }
 
-func (s *salonService) ListStaffServices(ctx context.Context, staffID string) ([]string, error) {
+func (s *salonService) ListStaffServices(ctx context.Context, salonID, staffID string) ([]string, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
 	if err := validateUUID("staff_id", staffID); err != nil {
 		return nil, err
 	}
+	if err := s.ensureStaffInSalon(ctx, salonID, staffID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStaffServices(ctx, staffID)
+}
+
+func (s *salonService) AddStaffService(ctx context.Context, salonID, staffID, serviceID string) ([]string, error) {
+	if err := s.validateStaffServiceChange(ctx, salonID, staffID, serviceID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddStaffService(ctx, staffID, serviceID); err != nil {
+		return nil, err
+	}
 	return s.repo.ListStaffServices(ctx, staffID)
 }
 
-func (s *salonService) RequestStaffOTP(ctx context.Context, params RequestStaffOTPParams) error {
+func (s *salonService) RemoveStaffService(ctx context.Context, salonID, staffID, serviceID string) ([]string, error) {
+	if err := s.validateStaffServiceChange(ctx, salonID, staffID, serviceID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RemoveStaffService(ctx, staffID, serviceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStaffServices(ctx, staffID)
+}
+
+// validateStaffServiceChange checks that both the staff member and the service
+// belong to the salon before a single assignment is added or removed
+func (s *salonService) validateStaffServiceChange(ctx context.Context, salonID, staffID, serviceID string) error {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return err
+	}
+	if err := validateUUID("service_id", serviceID); err != nil {
+		return err
+	}
+	if err := s.ensureStaffInSalon(ctx, salonID, staffID); err != nil {
+		return err
+	}
+	return s.ensureServiceInSalon(ctx, salonID, serviceID)
+}
+
+// ensureServiceInSalon rejects services that do not exist within the salon
+func (s *salonService) ensureServiceInSalon(ctx context.Context, salonID, serviceID string) error {
+	if _, err := s.repo.GetService(ctx, salonID, serviceID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return sharederrors.NewValidationError("service_id", "must reference a service of this salon")
+		}
+		return err
+	}
+	return nil
+}
+
+// ensureStaffInSalon returns repository.ErrNotFound when the staff does not
+// belong to the salon, so staff of another tenant are indistinguishable from
+// staff that do not exist
+func (s *salonService) ensureStaffInSalon(ctx context.Context, salonID, staffID string) error {
+	_, err := s.repo.GetStaff(ctx, salonID, staffID)
+	return err
+}
+
+// StaffOTPSent is a delivered staff OTP and the channel that delivered it
+type StaffOTPSent struct {
+	Code    string
+	Channel string
+}
+
+func (s *salonService) RequestStaffOTP(ctx context.Context, params RequestStaffOTPParams) (*StaffOTPSent, error) {
 	// Use shared phone validation and normalization
 	phone, err := sharedvalidation.ValidatePhone(params.PhoneNumber)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := s.otpDispatcher.ValidateChannel(params.Channel); err != nil {
+		return nil, err
+	}
+
+	// Limit how often a phone number can request codes, regardless of client IP
+	recent, err := s.authRepo.CountOTPsSince(ctx, phone, time.Now().Add(-s.otpPolicy.RequestWindow))
+	if err != nil {
+		return nil, fmt.Errorf("count recent OTPs: %w", err)
+	}
+	if recent >= s.otpPolicy.RequestsPerPhone {
+		log.Warn().Str("phone", phone).Int("recent_requests", recent).Msg("staff OTP request limit exceeded")
+		return nil, sharederrors.ErrRateLimited
 	}
 
 	// Check if staff exists with this phone number
 	staff, err := s.repo.GetStaffByPhone(ctx, phone)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if staff == nil {
-		return ErrStaffNotFound
+		return nil, ErrStaffNotFound
 	}
 
 	// Generate OTP using shared utility
 	otpCode, err := sharedvalidation.GenerateOTP()
 	if err != nil {
-		return fmt.Errorf("generate OTP: %w", err)
+		return nil, fmt.Errorf("generate OTP: %w", err)
+	}
+
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(otpCode), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash OTP: %w", err)
 	}
 
-	// Store OTP with expiry
+	// Store OTP with expiry against the phone number it is verified by
 	expiry := time.Now().Add(s.otpExpiry)
-	if err := s.authRepo.CreateOTP(ctx, staff.ID, otpCode, expiry); err != nil {
-		return fmt.Errorf("store OTP: %w", err)
+	otpID, err := s.authRepo.CreateOTP(ctx, phone, string(codeHash), expiry)
+	if err != nil {
+		return nil, fmt.Errorf("store OTP: %w", err)
 	}
 
-	// Use shared OTP sending utility
-	sharedauth.SendOTPViaSMS(phone, otpCode)
+	delivery, err := s.otpDispatcher.Send(ctx, phone, otpCode, params.Channel)
+	if err != nil {
+		log.Error().Err(err).Str("phone", phone).Msg("failed to deliver staff OTP")
+		return nil, err
+	}
+	if err := s.authRepo.SetOTPDeliveryChannel(ctx, otpID, delivery.Channel); err != nil {
+		log.Warn().Err(err).Int64("otp_id", otpID).Msg("failed to record staff OTP delivery channel")
+	}
 
-	return nil
+	// The timed fallback outlives the request, so it uses its own context
+	s.otpDispatcher.ScheduleFallback(phone, otpCode, delivery, sharedauth.OTPFallbackHooks{
+		Pending: func(ctx context.Context) (bool, error) {
+			return s.authRepo.IsOTPPending(ctx, otpID)
+		},
+		Delivered: func(ctx context.Context, channel string) error {
+			return s.authRepo.SetOTPDeliveryChannel(ctx, otpID, channel)
+		},
+	})
+
+	return &StaffOTPSent{Code: otpCode, Channel: delivery.Channel}, nil
 }
 
 type AuthenticateStaffResult struct {
@@ -478,6 +810,11 @@ func (s *salonService) AuthenticateStaff(ctx context.Context, params Authenticat
 		return nil, ErrStaffNotFound
 	}
 
+	// Reject numbers locked out by repeated failures here or at customer login
+	if err := s.lockouts.Check(ctx, phone); err != nil {
+		return nil, err
+	}
+
 	otp, err := s.authRepo.GetLatestOTP(ctx, phone)
 	if err != nil {
 		return nil, err
@@ -490,8 +827,20 @@ func (s *salonService) AuthenticateStaff(ctx context.Context, params Authenticat
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(otp.CodeHash), []byte(params.OTP)); err != nil {
 		_ = s.authRepo.IncrementOTPAttempts(ctx, otp.ID)
+		if err := s.lockouts.RecordFailure(ctx, phone); err != nil {
+			if errors.Is(err, lockout.ErrLocked) {
+				return nil, err
+			}
+			log.Error().Err(err).Str("phone", phone).Msg("failed to record staff OTP failure")
+		}
 		return nil, ErrInvalidOTP
 	}
+	if err := s.authRepo.MarkOTPVerified(ctx, otp.ID); err != nil {
+		log.Warn().Err(err).Int64("otp_id", otp.ID).Msg("failed to mark staff OTP verified")
+	}
+	if err := s.lockouts.Reset(ctx, phone); err != nil {
+		log.Warn().Err(err).Str("phone", phone).Msg("failed to reset staff OTP failures")
+	}
 
 	return s.issueTokens(ctx, staff)
 }
@@ -501,21 +850,26 @@ func (s *salonService) RefreshStaffSession(ctx context.Context, staffID, refresh
 		return nil, err
 	}
 
-	staff, err := s.repo.GetStaff(ctx, staffID, staffID)
+	staff, err := s.repo.GetStaffByID(ctx, staffID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrStaffNotFound
+		}
 		return nil, err
 	}
-	if staff == nil {
-		return nil, ErrStaffNotFound
-	}
 
-	valid, err := s.authRepo.IsRefreshTokenValid(ctx, staff.ID, refreshToken, time.Now())
+	tokenHash := sharedauth.HashString(refreshToken)
+	valid, err := s.authRepo.IsRefreshTokenValid(ctx, staff.ID, tokenHash, time.Now())
 	if err != nil {
 		return nil, err
 	}
 	if !valid {
 		return nil, ErrRefreshTokenInvalid
 	}
+	// Rotate the refresh token so a used token cannot be replayed
+	if err := s.authRepo.RevokeRefreshToken(ctx, staff.ID, tokenHash); err != nil {
+		return nil, err
+	}
 
 	return s.issueTokens(ctx, staff)
 }
@@ -529,11 +883,9 @@ func (s *salonService) issueTokens(ctx context.Context, staff *model.Staff) (*Au
 	if err != nil {
 		return nil, err
 	}
-	refreshHash, err := bcrypt.GenerateFromPassword([]byte(refreshToken), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, fmt.Errorf("hash refresh token: %w", err)
-	}
-	if err := s.authRepo.CreateRefreshToken(ctx, staff.ID, string(refreshHash), refreshExpiry); err != nil {
+	// Refresh tokens are looked up by hash, so use a deterministic digest rather than bcrypt
+	refreshHash := sharedauth.HashString(refreshToken)
+	if err := s.authRepo.CreateRefreshToken(ctx, staff.ID, refreshHash, refreshExpiry); err != nil {
 		return nil, err
 	}
 
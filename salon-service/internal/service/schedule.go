@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"salon-service/internal/model"
+
+	"github.com/EricsAntony/salon/salon-shared/hours"
+)
+
+// Staff shifts are stored as JSON keyed by lowercase weekday, each day holding
+// a list of shift blocks with optional breaks (see model.Shifts), e.g.
+//
+//	{"monday": [{"start": "09:00", "end": "17:00",
+//	             "breaks": [{"start": "13:00", "end": "14:00", "type": "lunch"}]}]}
+//
+// Shifts are limited to the branch opening hours resolved by hours.Resolve from
+// the branch and salon working hours, overrides and holidays. Branches carry no
+// time zone, so schedules are computed in UTC, which is also what
+// booking-service falls back to.
+
+func (s *salonService) GetStaffSchedule(ctx context.Context, salonID, staffID string, branchID *string, date time.Time) (*model.StylistSchedule, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return nil, err
+	}
+	if branchID != nil {
+		if err := validateUUID("branch_id", *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	staff, err := s.repo.GetStaff(ctx, salonID, staffID)
+	if err != nil {
+		return nil, err
+	}
+
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	schedule := &model.StylistSchedule{
+		StylistID:    staff.ID,
+		Date:         date,
+		WorkingHours: []model.ScheduleWindow{},
+		Breaks:       []model.ScheduleBreak{},
+	}
+	if staff.Status != model.StaffStatusActive {
+		return schedule, nil
+	}
+
+	onLeave, err := s.repo.IsStaffOnLeave(ctx, staffID, date)
+	if err != nil {
+		return nil, err
+	}
+	if onLeave {
+		schedule.OnLeave = true
+		return schedule, nil
+	}
+
+	// Default to the branch the staff member is assigned to
+	if branchID == nil {
+		branchID = staff.BranchID
+	}
+
+	salon, err := s.repo.GetSalon(ctx, salonID)
+	if err != nil {
+		return nil, err
+	}
+	var branch *model.Branch
+	if branchID != nil {
+		if branch, err = s.repo.GetBranch(ctx, salonID, *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	effective := resolveHours(salon, branch, date)
+	if !effective.Open {
+		return schedule, nil
+	}
+	openWindows := effective.Windows(date)
+
+	for _, block := range shiftBlocksOn(staff.Shifts, date) {
+		windows := []model.ScheduleWindow{block.ScheduleWindow}
+		if effective.Restricted {
+			windows = clipToWindows(block.ScheduleWindow, openWindows)
+		}
+		if len(windows) == 0 {
+			continue
+		}
+		schedule.WorkingHours = append(schedule.WorkingHours, windows...)
+
+		for _, br := range block.breaks {
+			for _, window := range windows {
+				if br.StartTime.Before(window.EndTime) && br.EndTime.After(window.StartTime) {
+					schedule.Breaks = append(schedule.Breaks, br)
+					break
+				}
+			}
+		}
+	}
+
+	return schedule, nil
+}
+
+// clipToWindows returns the parts of a shift that fall within the opening windows
+func clipToWindows(shift model.ScheduleWindow, openWindows []hours.Window) []model.ScheduleWindow {
+	var clipped []model.ScheduleWindow
+	for _, open := range openWindows {
+		start, end := shift.StartTime, shift.EndTime
+		if start.Before(open.Start) {
+			start = open.Start
+		}
+		if end.After(open.End) {
+			end = open.End
+		}
+		if end.After(start) {
+			clipped = append(clipped, model.ScheduleWindow{StartTime: start, EndTime: end})
+		}
+	}
+	return clipped
+}
+
+type shiftBlock struct {
+	model.ScheduleWindow
+	breaks []model.ScheduleBreak
+}
+
+// shiftBlocksOn returns the shift blocks defined for the weekday of date.
+// Blocks with malformed times, which validation rejects on write, are skipped.
+func shiftBlocksOn(shifts model.Shifts, date time.Time) []shiftBlock {
+	var blocks []shiftBlock
+	for _, entry := range shifts[strings.ToLower(date.Weekday().String())] {
+		start, end, ok := clockRange(date, entry.Start, entry.End)
+		if !ok {
+			continue
+		}
+		block := shiftBlock{ScheduleWindow: model.ScheduleWindow{StartTime: start, EndTime: end}}
+
+		for _, b := range entry.Breaks {
+			breakStart, breakEnd, ok := clockRange(date, b.Start, b.End)
+			if !ok {
+				continue
+			}
+			breakType := b.Type
+			if breakType == "" {
+				breakType = "break"
+			}
+			block.breaks = append(block.breaks, model.ScheduleBreak{StartTime: breakStart, EndTime: breakEnd, Type: breakType})
+		}
+
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// clockRange parses HH:MM start and end values on date, requiring end after start
+func clockRange(date time.Time, startStr, endStr string) (start, end time.Time, ok bool) {
+	start, err := clockOnDate(date, startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err = clockOnDate(date, endStr)
+	if err != nil || !end.After(start) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// clockOnDate combines a HH:MM clock value with the given date
+func clockOnDate(date time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", clock, err)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location()), nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"salon-service/internal/model"
+	"salon-service/internal/repository"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+)
+
+// CreateStaffTimeOff records a leave block for a staff member. Blocks of the
+// same staff member may not overlap.
+func (s *salonService) CreateStaffTimeOff(ctx context.Context, params CreateStaffTimeOffParams) (*model.StaffTimeOff, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	start, end := params.dates()
+	timeOff, err := s.repo.CreateStaffTimeOff(ctx, params.SalonID, params.StaffID, start, end, params.Reason)
+	if errors.Is(err, repository.ErrTimeOffOverlap) {
+		return nil, sharederrors.NewConflictError("time_off", "overlaps existing time off")
+	}
+	return timeOff, err
+}
+
+// ListStaffTimeOff lists a staff member's time-off blocks that end on or after from
+func (s *salonService) ListStaffTimeOff(ctx context.Context, salonID, staffID string, from time.Time) ([]*model.StaffTimeOff, error) {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return nil, err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return nil, err
+	}
+	if err := s.ensureStaffInSalon(ctx, salonID, staffID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStaffTimeOff(ctx, staffID, from)
+}
+
+func (s *salonService) DeleteStaffTimeOff(ctx context.Context, salonID, staffID, timeOffID string) error {
+	if err := validateUUID("salon_id", salonID); err != nil {
+		return err
+	}
+	if err := validateUUID("staff_id", staffID); err != nil {
+		return err
+	}
+	if err := validateUUID("time_off_id", timeOffID); err != nil {
+		return err
+	}
+	if err := s.ensureStaffInSalon(ctx, salonID, staffID); err != nil {
+		return err
+	}
+	return s.repo.DeleteStaffTimeOff(ctx, staffID, timeOffID)
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_staff_phone_number;
+CREATE UNIQUE INDEX idx_staff_phone_number ON staff (phone_number) WHERE phone_number IS NOT NULL;
+
+ALTER TABLE staff DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE branches DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE salons DROP COLUMN IF EXISTS deleted_at;
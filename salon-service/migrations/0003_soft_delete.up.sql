@@ -0,0 +1,7 @@
+ALTER TABLE salons ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE branches ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE staff ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Phone numbers of deleted staff may be reused by new staff
+DROP INDEX IF EXISTS idx_staff_phone_number;
+CREATE UNIQUE INDEX idx_staff_phone_number ON staff (phone_number) WHERE phone_number IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_staff_branch_id;
+ALTER TABLE staff DROP COLUMN IF EXISTS branch_id;
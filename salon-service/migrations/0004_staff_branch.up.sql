@@ -0,0 +1,3 @@
+ALTER TABLE staff ADD COLUMN branch_id UUID REFERENCES branches(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_staff_branch_id ON staff (branch_id) WHERE branch_id IS NOT NULL;
@@ -0,0 +1,2 @@
+-- user-service owns the same table; dropping it here also clears customer lockouts
+DROP TABLE IF EXISTS otp_lockouts;
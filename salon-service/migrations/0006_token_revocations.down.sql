@@ -0,0 +1,2 @@
+-- user-service owns the same table; dropping it here also clears customer revocations
+DROP TABLE IF EXISTS token_revocations;
@@ -0,0 +1,6 @@
+-- Shared with user-service: access tokens issued before tokens_valid_after are rejected
+CREATE TABLE IF NOT EXISTS token_revocations (
+    subject_id UUID PRIMARY KEY,
+    tokens_valid_after TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_staff_time_off_staff_dates;
+DROP TABLE IF EXISTS staff_time_off;
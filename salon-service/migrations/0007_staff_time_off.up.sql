@@ -0,0 +1,12 @@
+-- Leave blocks during which a staff member has no schedule. Dates are inclusive.
+CREATE TABLE staff_time_off (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    staff_id UUID NOT NULL REFERENCES staff(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX idx_staff_time_off_staff_dates ON staff_time_off (staff_id, start_date, end_date);
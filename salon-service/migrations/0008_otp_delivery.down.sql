@@ -0,0 +1,3 @@
+ALTER TABLE staff_otps
+    DROP COLUMN IF EXISTS verified_at,
+    DROP COLUMN IF EXISTS delivery_channel;
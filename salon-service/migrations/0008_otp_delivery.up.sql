@@ -0,0 +1,5 @@
+-- Channel that last delivered each code and when it was verified, so timed
+-- fallback to another channel skips codes that were already used
+ALTER TABLE staff_otps
+    ADD COLUMN delivery_channel TEXT,
+    ADD COLUMN verified_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Tax-exempt services (e.g. gift cards) are left out of the taxable amount
+-- when bookings are priced
+ALTER TABLE services
+    ADD COLUMN tax_exempt BOOLEAN NOT NULL DEFAULT false;
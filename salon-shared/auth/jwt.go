@@ -13,6 +13,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/EricsAntony/salon/salon-shared/config"
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
 )
 
 type JWTManager struct {
@@ -20,6 +21,8 @@ type JWTManager struct {
 	refreshSecret []byte
 	accessTTL     time.Duration
 	refreshTTL    time.Duration
+	// revocations is consulted by CheckRevoked; nil disables the check
+	revocations RevocationStore
 }
 
 const (
@@ -42,16 +45,63 @@ func NewJWTManager(cfg *config.Config) *JWTManager {
 	}
 }
 
+// SetRevocationStore enables revocation checks for access tokens
+func (m *JWTManager) SetRevocationStore(store RevocationStore) {
+	m.revocations = store
+}
+
+// CheckRevoked returns ErrTokenRevoked if the token was issued before its
+// subject's tokens were revoked. Issue times have second precision, so the
+// cutoff is truncated to the second to keep tokens issued right after a
+// revocation valid.
+func (m *JWTManager) CheckRevoked(ctx context.Context, claims *Claims) error {
+	if m.revocations == nil {
+		return nil
+	}
+	validAfter, err := m.revocations.TokensValidAfter(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if validAfter.IsZero() {
+		return nil
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if issuedAt.Before(validAfter.Truncate(time.Second)) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// RejectRevoked writes an error response and returns true when the request's
+// token has been revoked or revocation could not be checked
+func (m *JWTManager) RejectRevoked(w http.ResponseWriter, r *http.Request, claims *Claims) bool {
+	err := m.CheckRevoked(r.Context(), claims)
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrTokenRevoked) {
+		sharederrors.WriteError(w, http.StatusUnauthorized, "token revoked")
+		return true
+	}
+	log.Error().Err(err).Str("user_id", claims.UserID).Msg("failed to check token revocation")
+	sharederrors.WriteError(w, http.StatusServiceUnavailable, "unable to verify token")
+	return true
+}
+
 func (m *JWTManager) GenerateAccessToken(userID string) (string, time.Time, error) {
 	return m.GenerateAccessTokenWithType(userID, "")
 }
 
 func (m *JWTManager) GenerateAccessTokenWithType(userID, userType string) (string, time.Time, error) {
-	exp := time.Now().Add(m.accessTTL)
+	now := time.Now()
+	exp := now.Add(m.accessTTL)
 	claims := &Claims{
 		UserID:   userID,
 		UserType: userType,
-		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp), IssuedAt: jwt.NewNumericDate(now)},
 	}
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	s, err := t.SignedString(m.accessSecret)
@@ -63,11 +113,12 @@ func (m *JWTManager) GenerateRefreshToken(userID string) (string, time.Time, err
 }
 
 func (m *JWTManager) GenerateRefreshTokenWithType(userID, userType string) (string, time.Time, error) {
-	exp := time.Now().Add(m.refreshTTL)
+	now := time.Now()
+	exp := now.Add(m.refreshTTL)
 	claims := &Claims{
 		UserID:   userID,
 		UserType: userType,
-		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp), IssuedAt: jwt.NewNumericDate(now)},
 	}
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	s, err := t.SignedString(m.refreshSecret)
@@ -120,6 +171,16 @@ type ctxKey string
 
 const CtxUserID ctxKey = "uid"
 
+// CtxAccessToken holds the raw bearer token of the authenticated request so
+// services can forward it on calls to other services
+const CtxAccessToken ctxKey = "access_token"
+
+// AccessTokenFromContext returns the bearer token stored by the auth middleware, if any
+func AccessTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(CtxAccessToken).(string)
+	return token
+}
+
 // Middleware validates the Authorization: Bearer <token> header and injects the
 // authenticated user id into the request context using CtxUserID.
 func (m *JWTManager) Middleware() func(http.Handler) http.Handler {
@@ -128,22 +189,21 @@ func (m *JWTManager) Middleware() func(http.Handler) http.Handler {
 			authz := r.Header.Get("Authorization")
 			parts := strings.SplitN(authz, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				sharederrors.WriteError(w, http.StatusUnauthorized, "missing bearer token")
 				return
 			}
 			claims, err := m.ValidateAccessToken(parts[1])
 			if err != nil {
 				log.Warn().Err(err).Msg("invalid access token")
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				sharederrors.WriteError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+			if m.RejectRevoked(w, r, claims) {
 				return
 			}
 			ctx := context.WithValue(r.Context(), CtxUserID, claims.UserID)
+			ctx = context.WithValue(ctx, CtxAccessToken, parts[1])
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
-
-// For demo purposes, OTP code will be logged; in production integrate with an SMS provider.
-func SendOTPViaSMS(phone, code string) {
-	log.Info().Str("phone", phone).Str("otp_code", code).Msg("OTP generated (send via SMS in production)")
-}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+)
+
+// OTPDispatcher delivers codes over the configured channels in order. A
+// failed send moves straight to the next channel; with a fallback delay, a
+// code that is still unverified is also resent over the next channel.
+type OTPDispatcher struct {
+	sender        OTPSender
+	channels      []string
+	fallbackAfter time.Duration
+}
+
+// OTPDelivery is the outcome of a send: the channel that delivered the code
+// and the channels still available for a timed fallback
+type OTPDelivery struct {
+	Channel   string
+	Remaining []string
+}
+
+// OTPFallbackHooks lets the caller decide whether a fallback is still needed
+// and record the channel it was delivered over
+type OTPFallbackHooks struct {
+	// Pending reports whether the code is still unused and unexpired
+	Pending func(ctx context.Context) (bool, error)
+	// Delivered records that a fallback send succeeded on channel
+	Delivered func(ctx context.Context, channel string) error
+}
+
+// NewOTPDispatcher builds the configured sender and wraps it in a dispatcher
+func NewOTPDispatcher(cfg OTPSenderConfig) (*OTPDispatcher, error) {
+	sender, err := NewOTPSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &OTPDispatcher{
+		sender:        sender,
+		channels:      cfg.channels(),
+		fallbackAfter: time.Duration(cfg.FallbackAfterSeconds) * time.Second,
+	}, nil
+}
+
+// Sender returns the underlying provider
+func (d *OTPDispatcher) Sender() OTPSender {
+	return d.sender
+}
+
+// ValidateChannel checks that a requested channel is configured; an empty
+// channel is always valid
+func (d *OTPDispatcher) ValidateChannel(channel string) error {
+	_, err := d.order(channel)
+	return err
+}
+
+// Send delivers code starting with the preferred channel, or the first
+// configured channel when preferred is empty. A preferred channel that is not
+// configured is a validation error.
+func (d *OTPDispatcher) Send(ctx context.Context, phone, code, preferred string) (*OTPDelivery, error) {
+	order, err := d.order(preferred)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i, channel := range order {
+		if err := d.sender.SendOTP(ctx, channel, phone, code); err != nil {
+			log.Warn().Err(err).Str("channel", channel).Str("phone", phone).Msg("OTP send failed, trying next channel")
+			errs = append(errs, err)
+			continue
+		}
+		return &OTPDelivery{Channel: channel, Remaining: order[i+1:]}, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ScheduleFallback resends code over the next remaining channel once the
+// fallback delay passes, as long as hooks.Pending still reports the code
+// unused, and keeps falling back until a send succeeds or channels run out.
+// It does nothing when no delay is configured.
+func (d *OTPDispatcher) ScheduleFallback(phone, code string, delivery *OTPDelivery, hooks OTPFallbackHooks) {
+	if d.fallbackAfter <= 0 || delivery == nil || len(delivery.Remaining) == 0 {
+		return
+	}
+	remaining := append([]string(nil), delivery.Remaining...)
+	time.AfterFunc(d.fallbackAfter, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), otpSendTimeout*time.Duration(len(remaining)+1))
+		defer cancel()
+
+		if hooks.Pending != nil {
+			pending, err := hooks.Pending(ctx)
+			if err != nil {
+				log.Error().Err(err).Str("phone", phone).Msg("failed to check OTP before fallback")
+				return
+			}
+			if !pending {
+				return
+			}
+		}
+
+		for i, channel := range remaining {
+			if err := d.sender.SendOTP(ctx, channel, phone, code); err != nil {
+				log.Warn().Err(err).Str("channel", channel).Str("phone", phone).Msg("OTP fallback send failed")
+				continue
+			}
+			log.Info().Str("channel", channel).Str("phone", phone).Msg("OTP resent over fallback channel")
+			if hooks.Delivered != nil {
+				if err := hooks.Delivered(ctx, channel); err != nil {
+					log.Warn().Err(err).Str("channel", channel).Str("phone", phone).Msg("failed to record OTP fallback channel")
+				}
+			}
+			d.ScheduleFallback(phone, code, &OTPDelivery{Channel: channel, Remaining: remaining[i+1:]}, hooks)
+			return
+		}
+		log.Error().Str("phone", phone).Msg("OTP fallback exhausted every channel")
+	})
+}
+
+// order returns the channels to try, preferred first
+func (d *OTPDispatcher) order(preferred string) ([]string, error) {
+	preferred = normalizeOTPChannel(preferred)
+	if preferred == "" {
+		return d.channels, nil
+	}
+	order := []string{preferred}
+	found := false
+	for _, channel := range d.channels {
+		if channel == preferred {
+			found = true
+			continue
+		}
+		order = append(order, channel)
+	}
+	if !found {
+		return nil, sharederrors.NewValidationError("channel", fmt.Sprintf("must be one of %s", strings.Join(d.channels, ", ")))
+	}
+	return order, nil
+}
@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+)
+
+// OTP delivery providers selectable through OTPSenderConfig.Provider
+const (
+	OTPProviderLog    = "log"
+	OTPProviderTwilio = "twilio"
+	OTPProviderMSG91  = "msg91"
+)
+
+// Channels an OTP can be delivered over. The code is the same whichever
+// channel delivers it, so verification does not depend on the channel.
+const (
+	OTPChannelSMS      = "sms"
+	OTPChannelWhatsApp = "whatsapp"
+	OTPChannelVoice    = "voice"
+)
+
+// otpSendTimeout bounds a single call to a delivery provider
+const otpSendTimeout = 10 * time.Second
+
+// otpMessageFormat is the message body for providers that take free text
+const otpMessageFormat = "%s is your verification code. Do not share it with anyone."
+
+// otpVoiceFormat is read out on voice calls, with the digits spaced so they
+// are spoken one at a time
+const otpVoiceFormat = "Your verification code is %s. Again, your code is %s."
+
+// ErrOTPDelivery is returned when a provider fails to deliver a code. It wraps
+// ErrServiceUnavailable so handlers report a 503 rather than a generic 500.
+var ErrOTPDelivery = fmt.Errorf("%w: OTP delivery failed", sharederrors.ErrServiceUnavailable)
+
+// ErrOTPChannelUnsupported is returned when a sender is asked to use a channel
+// its provider cannot deliver over
+var ErrOTPChannelUnsupported = errors.New("OTP channel not supported by provider")
+
+// OTPSender delivers one-time passcodes to a phone number over a channel
+type OTPSender interface {
+	SendOTP(ctx context.Context, channel, phone, code string) error
+	// Supports reports whether the sender can deliver over the channel
+	Supports(channel string) bool
+}
+
+// OTPSenderConfig selects the OTP delivery provider and holds its credentials.
+// Only the fields of the selected provider are required.
+type OTPSenderConfig struct {
+	// Provider is "log" (development only), "twilio" or "msg91"
+	Provider string
+
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFromNumber string
+	// TwilioWhatsAppFrom is the WhatsApp sender number; WhatsApp delivery is
+	// unavailable without it
+	TwilioWhatsAppFrom string
+
+	MSG91AuthKey    string
+	MSG91TemplateID string
+
+	// Channels lists the channels codes may be sent over, in fallback order.
+	// Empty means SMS only.
+	Channels []string
+	// FallbackAfterSeconds is how long an unverified code waits before it is
+	// resent over the next channel; 0 only falls back when a send fails
+	FallbackAfterSeconds int
+}
+
+// Validate checks that the provider is known, its credentials are set and it
+// supports every configured channel
+func (c OTPSenderConfig) Validate() error {
+	var missing []string
+	switch c.provider() {
+	case OTPProviderLog:
+	case OTPProviderTwilio:
+		if c.TwilioAccountSID == "" {
+			missing = append(missing, "twilio account SID")
+		}
+		if c.TwilioAuthToken == "" {
+			missing = append(missing, "twilio auth token")
+		}
+		if c.TwilioFromNumber == "" {
+			missing = append(missing, "twilio from number")
+		}
+	case OTPProviderMSG91:
+		if c.MSG91AuthKey == "" {
+			missing = append(missing, "msg91 auth key")
+		}
+		if c.MSG91TemplateID == "" {
+			missing = append(missing, "msg91 template ID")
+		}
+	default:
+		return fmt.Errorf("unsupported OTP provider %q (expected log, twilio or msg91)", c.Provider)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("OTP provider %s is missing %s", c.provider(), strings.Join(missing, ", "))
+	}
+
+	if c.FallbackAfterSeconds < 0 {
+		return fmt.Errorf("OTP fallback delay must not be negative")
+	}
+	seen := map[string]bool{}
+	for _, channel := range c.channels() {
+		if seen[channel] {
+			return fmt.Errorf("OTP channel %q is listed more than once", channel)
+		}
+		seen[channel] = true
+		if !c.supports(channel) {
+			return fmt.Errorf("OTP provider %s cannot deliver over channel %q", c.provider(), channel)
+		}
+	}
+	return nil
+}
+
+// channels returns the normalized channel list, defaulting to SMS only
+func (c OTPSenderConfig) channels() []string {
+	channels := make([]string, 0, len(c.Channels))
+	for _, channel := range c.Channels {
+		if channel = normalizeOTPChannel(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) == 0 {
+		return []string{OTPChannelSMS}
+	}
+	return channels
+}
+
+// supports reports whether the configured provider can deliver over channel
+func (c OTPSenderConfig) supports(channel string) bool {
+	switch c.provider() {
+	case OTPProviderLog:
+		return channel == OTPChannelSMS || channel == OTPChannelWhatsApp || channel == OTPChannelVoice
+	case OTPProviderTwilio:
+		return channel == OTPChannelSMS || channel == OTPChannelVoice ||
+			(channel == OTPChannelWhatsApp && c.TwilioWhatsAppFrom != "")
+	case OTPProviderMSG91:
+		return channel == OTPChannelSMS
+	default:
+		return false
+	}
+}
+
+func normalizeOTPChannel(channel string) string {
+	return strings.ToLower(strings.TrimSpace(channel))
+}
+
+func (c OTPSenderConfig) provider() string {
+	provider := strings.ToLower(strings.TrimSpace(c.Provider))
+	if provider == "" {
+		return OTPProviderLog
+	}
+	return provider
+}
+
+// NewOTPSender returns the sender for the configured provider. An empty
+// provider selects the log sender.
+func NewOTPSender(cfg OTPSenderConfig) (OTPSender, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: otpSendTimeout}
+	switch cfg.provider() {
+	case OTPProviderTwilio:
+		return &TwilioOTPSender{
+			accountSID:   cfg.TwilioAccountSID,
+			authToken:    cfg.TwilioAuthToken,
+			fromNumber:   cfg.TwilioFromNumber,
+			whatsAppFrom: cfg.TwilioWhatsAppFrom,
+			client:       client,
+		}, nil
+	case OTPProviderMSG91:
+		return &MSG91OTPSender{
+			authKey:    cfg.MSG91AuthKey,
+			templateID: cfg.MSG91TemplateID,
+			client:     client,
+		}, nil
+	default:
+		return LogOTPSender{}, nil
+	}
+}
+
+// LogOTPSender writes codes to the log instead of sending them, for local
+// development and testing
+type LogOTPSender struct{}
+
+// SendOTP logs the code
+func (LogOTPSender) SendOTP(ctx context.Context, channel, phone, code string) error {
+	log.Info().Str("channel", channel).Str("phone", phone).Str("otp_code", code).Msg("OTP generated (log provider, not delivered)")
+	return nil
+}
+
+// Supports reports true for every channel
+func (LogOTPSender) Supports(channel string) bool {
+	return OTPSenderConfig{Provider: OTPProviderLog}.supports(channel)
+}
+
+// TwilioOTPSender sends codes as SMS or WhatsApp messages through the Twilio
+// Messages API, or reads them out on a call through the Calls API
+type TwilioOTPSender struct {
+	accountSID   string
+	authToken    string
+	fromNumber   string
+	whatsAppFrom string
+	client       *http.Client
+}
+
+const (
+	twilioMessagesURL = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json"
+	twilioCallsURL    = "https://api.twilio.com/2010-04-01/Accounts/%s/Calls.json"
+)
+
+// Supports reports whether the channel can be used; WhatsApp needs a sender number
+func (t *TwilioOTPSender) Supports(channel string) bool {
+	return OTPSenderConfig{Provider: OTPProviderTwilio, TwilioWhatsAppFrom: t.whatsAppFrom}.supports(channel)
+}
+
+// SendOTP sends the code over the channel
+func (t *TwilioOTPSender) SendOTP(ctx context.Context, channel, phone, code string) error {
+	form := url.Values{}
+	endpoint := twilioMessagesURL
+	switch channel {
+	case OTPChannelSMS:
+		form.Set("To", phone)
+		form.Set("From", t.fromNumber)
+		form.Set("Body", fmt.Sprintf(otpMessageFormat, code))
+	case OTPChannelWhatsApp:
+		if t.whatsAppFrom == "" {
+			return fmt.Errorf("%w: twilio: %q", ErrOTPChannelUnsupported, channel)
+		}
+		form.Set("To", "whatsapp:"+phone)
+		form.Set("From", "whatsapp:"+t.whatsAppFrom)
+		form.Set("Body", fmt.Sprintf(otpMessageFormat, code))
+	case OTPChannelVoice:
+		endpoint = twilioCallsURL
+		spoken := strings.Join(strings.Split(code, ""), " ")
+		form.Set("To", phone)
+		form.Set("From", t.fromNumber)
+		form.Set("Twiml", "<Response><Say>"+fmt.Sprintf(otpVoiceFormat, spoken, spoken)+"</Say></Response>")
+	default:
+		return fmt.Errorf("%w: twilio: %q", ErrOTPChannelUnsupported, channel)
+	}
+
+	endpoint = fmt.Sprintf(endpoint, url.PathEscape(t.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("%w: twilio: %v", ErrOTPDelivery, err)
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: twilio: %v", ErrOTPDelivery, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+		return fmt.Errorf("%w: twilio returned %d (code %d): %s", ErrOTPDelivery, resp.StatusCode, body.Code, body.Message)
+	}
+	return nil
+}
+
+// MSG91OTPSender sends codes through the MSG91 OTP API using a DLT approved
+// template that contains the ##OTP## placeholder
+type MSG91OTPSender struct {
+	authKey    string
+	templateID string
+	client     *http.Client
+}
+
+const msg91OTPURL = "https://control.msg91.com/api/v5/otp"
+
+// Supports reports whether the channel can be used; only SMS is supported
+func (m *MSG91OTPSender) Supports(channel string) bool {
+	return OTPSenderConfig{Provider: OTPProviderMSG91}.supports(channel)
+}
+
+// SendOTP sends the code by SMS with the configured template. MSG91 expects
+// the number with its country code and without a leading +.
+func (m *MSG91OTPSender) SendOTP(ctx context.Context, channel, phone, code string) error {
+	if channel != OTPChannelSMS {
+		return fmt.Errorf("%w: msg91: %q", ErrOTPChannelUnsupported, channel)
+	}
+	query := url.Values{}
+	query.Set("template_id", m.templateID)
+	query.Set("mobile", strings.TrimPrefix(phone, "+"))
+	query.Set("otp", code)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg91OTPURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("%w: msg91: %v", ErrOTPDelivery, err)
+	}
+	req.Header.Set("authkey", m.authKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: msg91: %v", ErrOTPDelivery, err)
+	}
+	defer resp.Body.Close()
+
+	// MSG91 reports failures in the body, sometimes with a 200 status
+	var body struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || !strings.EqualFold(body.Type, "success") {
+		return fmt.Errorf("%w: msg91 returned %d (%s): %s", ErrOTPDelivery, resp.StatusCode, body.Type, body.Message)
+	}
+	return nil
+}
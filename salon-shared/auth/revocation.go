@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTokenRevoked is returned for access tokens issued before their subject's
+// tokens were revoked
+var ErrTokenRevoked = errors.New("token revoked")
+
+// DefaultRevocationCacheTTL bounds how long a revocation can take to reach
+// services other than the one that recorded it
+const DefaultRevocationCacheTTL = 15 * time.Second
+
+// RevocationStore reports the time before which a subject's tokens are no
+// longer valid, or the zero time if they were never revoked
+type RevocationStore interface {
+	TokensValidAfter(ctx context.Context, subjectID string) (time.Time, error)
+}
+
+type revocationEntry struct {
+	validAfter time.Time
+	expires    time.Time
+}
+
+// RevocationCache caches RevocationStore lookups so the auth middleware does
+// not hit the database on every request
+type RevocationCache struct {
+	store   RevocationStore
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]revocationEntry
+}
+
+// NewRevocationCache wraps store with a cache holding lookups for ttl
+func NewRevocationCache(store RevocationStore, ttl time.Duration) *RevocationCache {
+	return &RevocationCache{store: store, ttl: ttl, entries: make(map[string]revocationEntry)}
+}
+
+// TokensValidAfter returns the cached cutoff for subjectID, loading it from the
+// store when missing or stale
+func (c *RevocationCache) TokensValidAfter(ctx context.Context, subjectID string) (time.Time, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[subjectID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.validAfter, nil
+	}
+
+	validAfter, err := c.store.TokensValidAfter(ctx, subjectID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c.mu.Lock()
+	// Drop stale entries once the cache grows so it stays bounded by active users
+	if len(c.entries) >= 10000 {
+		for id, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[subjectID] = revocationEntry{validAfter: validAfter, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return validAfter, nil
+}
+
+// Forget drops the cached cutoff for subjectID so a revocation recorded by this
+// process takes effect immediately
+func (c *RevocationCache) Forget(subjectID string) {
+	c.mu.Lock()
+	delete(c.entries, subjectID)
+	c.mu.Unlock()
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/spf13/viper"
 	"github.com/subosito/gotenv"
+
+	"github.com/EricsAntony/salon/salon-shared/configcheck"
 )
 
 type Config struct {
@@ -34,6 +36,30 @@ type Config struct {
 	}
 }
 
+// Token lifetimes used when a service does not configure its own
+const (
+	DefaultAccessTTLMinutes = 15
+	DefaultRefreshTTLDays   = 7
+)
+
+// ValidateJWTTTLs checks that both token lifetimes are positive and that access
+// tokens expire before the refresh tokens used to renew them
+func ValidateJWTTTLs(accessTTLMinutes, refreshTTLDays int) error {
+	if accessTTLMinutes <= 0 || refreshTTLDays <= 0 {
+		return fmt.Errorf("jwt access and refresh TTLs must be greater than 0")
+	}
+	if accessTTLMinutes >= refreshTTLDays*24*60 {
+		return fmt.Errorf("jwt access TTL (%d minutes) must be shorter than refresh TTL (%d days)", accessTTLMinutes, refreshTTLDays)
+	}
+	return nil
+}
+
+// IsDevelopment reports whether the service runs in a local or dev
+// environment, where debugging aids such as echoing OTP codes are allowed
+func (c *Config) IsDevelopment() bool {
+	return configcheck.IsDevelopment(c.Env)
+}
+
 // Load loads configuration from configs/config.yaml (optional) and env variables.
 // For backward compatibility, it uses the USER_SERVICE_ prefix by default.
 func Load() (*Config, error) {
@@ -55,8 +81,8 @@ func LoadWithPrefix(prefix, configDir string) (*Config, error) {
 
 	v.SetDefault("server.port", "8080")
 	v.SetDefault("env", "dev")
-	v.SetDefault("jwt.accessttlminutes", 15)
-	v.SetDefault("jwt.refreshttldays", 7)
+	v.SetDefault("jwt.accessttlminutes", DefaultAccessTTLMinutes)
+	v.SetDefault("jwt.refreshttldays", DefaultRefreshTTLDays)
 	v.SetDefault("otp.expiryminutes", 5)
 	v.SetDefault("ratelimit.otprequestsperminute", 3)
 	v.SetDefault("log.level", "info")
@@ -68,5 +94,8 @@ func LoadWithPrefix(prefix, configDir string) (*Config, error) {
 	if err := v.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("config unmarshal: %w", err)
 	}
+	if err := ValidateJWTTTLs(cfg.JWT.AccessTTLMinutes, cfg.JWT.RefreshTTLDays); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
@@ -0,0 +1,133 @@
+// Package configcheck collects configuration problems so a service can report
+// every missing or invalid setting at startup in one message, instead of
+// failing on the first one or later at runtime.
+//
+// It only depends on the standard library so every service can import it,
+// including those that do not load configuration through viper.
+package configcheck
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MinSecretLength is the shortest signing secret accepted outside development
+const MinSecretLength = 32
+
+// IsDevelopment reports whether env names a local or dev environment, where
+// placeholder secrets and debugging aids are allowed
+func IsDevelopment(env string) bool {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "dev", "development", "local", "docker":
+		return true
+	default:
+		return false
+	}
+}
+
+// Problems accumulates configuration problems. The zero value is ready to use.
+type Problems struct {
+	list []string
+}
+
+// Addf records a problem
+func (p *Problems) Addf(format string, args ...any) {
+	p.list = append(p.list, fmt.Sprintf(format, args...))
+}
+
+// Required records a problem when value is blank
+func (p *Problems) Required(name, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		p.Addf("%s is required", name)
+		return false
+	}
+	return true
+}
+
+// Secret requires value and, when strict, at least MinSecretLength characters.
+// Development environments pass strict=false so placeholder secrets still work.
+func (p *Problems) Secret(name, value string, strict bool) {
+	if !p.Required(name, value) {
+		return
+	}
+	if strict && len(value) < MinSecretLength {
+		p.Addf("%s must be at least %d characters", name, MinSecretLength)
+	}
+}
+
+// Positive records a problem when value is not greater than 0
+func (p *Problems) Positive(name string, value int) {
+	if value <= 0 {
+		p.Addf("%s must be greater than 0", name)
+	}
+}
+
+// NonNegative records a problem when value is below 0
+func (p *Problems) NonNegative(name string, value int) {
+	if value < 0 {
+		p.Addf("%s must not be negative", name)
+	}
+}
+
+// Range records a problem when value is outside [min, max]
+func (p *Problems) Range(name string, value, min, max int) {
+	if value < min || value > max {
+		p.Addf("%s must be between %d and %d", name, min, max)
+	}
+}
+
+// Port records a problem when value is not a TCP port number
+func (p *Problems) Port(name, value string) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 1 || port > 65535 {
+		p.Addf("%s must be a port between 1 and 65535", name)
+	}
+}
+
+// URL records a problem when value is not an absolute http or https URL
+func (p *Problems) URL(name, value string) {
+	if !p.Required(name, value) {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.Addf("%s must be an absolute http(s) URL", name)
+	}
+}
+
+// OneOf records a problem when value is not one of allowed
+func (p *Problems) OneOf(name, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	p.Addf("%s must be one of %s", name, strings.Join(allowed, ", "))
+}
+
+// Check records err as a problem when it is not nil
+func (p *Problems) Check(err error) {
+	if err != nil {
+		p.Addf("%s", err.Error())
+	}
+}
+
+// Err returns every recorded problem as one error, or nil when there are none
+func (p *Problems) Err(service string) error {
+	if len(p.list) == 0 {
+		return nil
+	}
+	return &Error{Service: service, Problems: append([]string(nil), p.list...)}
+}
+
+// Error lists the configuration problems of a service
+type Error struct {
+	Service  string
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s configuration: %s", e.Service, strings.Join(e.Problems, "; "))
+}
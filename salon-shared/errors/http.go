@@ -4,13 +4,41 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 )
 
-// APIError represents an HTTP API error response
+// APIError represents an HTTP API error response. Details carries optional
+// structured context such as per-field validation errors. RetryAfterSeconds
+// is set for rate limited and locked out requests.
 type APIError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Type    string `json:"type,omitempty"`
+	Code              int         `json:"code"`
+	Message           string      `json:"message"`
+	Type              string      `json:"type"`
+	Details           interface{} `json:"details,omitempty"`
+	RetryAfterSeconds int         `json:"retry_after_seconds,omitempty"`
+}
+
+// ErrorResponse is the JSON envelope every service returns for failed requests:
+//
+//	{"success": false, "error": {"code": 400, "type": "validation_error", "message": "...", "details": [...]}}
+type ErrorResponse struct {
+	Success bool      `json:"success"`
+	Error   *APIError `json:"error"`
+}
+
+// retryAfter is implemented by errors that tell clients when to try again,
+// such as lockout.LockedError
+type retryAfter interface {
+	error
+	RetryAfterSeconds() int
+}
+
+// fieldError is implemented by errors about a single request field, such as
+// utils.DecodeError for request bodies that cannot be decoded
+type fieldError interface {
+	error
+	Field() string
+	Reason() string
 }
 
 func (e APIError) Error() string {
@@ -26,14 +54,50 @@ func NewAPIError(code int, message, errorType string) *APIError {
 	}
 }
 
-// MapToAPIError maps internal errors to HTTP API errors
+// MapToAPIError maps internal errors to HTTP API errors. Typed errors are
+// matched through wrapping, so fmt.Errorf("...: %w", err) keeps its status.
+// Unrecognised errors become a 500 without exposing their message.
 func MapToAPIError(err error) *APIError {
+	// Already mapped
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
 	// Handle validation errors
-	if _, ok := err.(ValidationErrors); ok {
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
 		return &APIError{
 			Code:    http.StatusBadRequest,
 			Message: "Validation failed",
 			Type:    ErrorTypeValidation,
+			Details: validationErrs,
+		}
+	}
+
+	var fieldErr fieldError
+	if errors.As(err, &fieldErr) {
+		return &APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Validation failed",
+			Type:    ErrorTypeValidation,
+			Details: NewValidationError(fieldErr.Field(), fieldErr.Reason()),
+		}
+	}
+
+	// Bodies cut off by http.MaxBytesReader
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return NewAPIError(http.StatusRequestEntityTooLarge, "request body too large", ErrorTypeValidation)
+	}
+
+	var retry retryAfter
+	if errors.As(err, &retry) {
+		return &APIError{
+			Code:              http.StatusTooManyRequests,
+			Message:           retry.Error(),
+			Type:              ErrorTypeRateLimit,
+			RetryAfterSeconds: retry.RetryAfterSeconds(),
 		}
 	}
 	
@@ -86,15 +150,18 @@ func MapToAPIError(err error) *APIError {
 	}
 	
 	// Handle custom error types
-	if conflictErr, ok := err.(*ConflictError); ok {
+	var conflictErr *ConflictError
+	if errors.As(err, &conflictErr) {
 		return NewAPIError(http.StatusConflict, conflictErr.Error(), ErrorTypeConflict)
 	}
 	
-	if notFoundErr, ok := err.(*NotFoundError); ok {
+	var notFoundErr *NotFoundError
+	if errors.As(err, &notFoundErr) {
 		return NewAPIError(http.StatusNotFound, notFoundErr.Error(), ErrorTypeNotFound)
 	}
 	
-	if authErr, ok := err.(*AuthError); ok {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
 		return NewAPIError(http.StatusUnauthorized, authErr.Error(), ErrorTypeAuth)
 	}
 	
@@ -102,25 +169,46 @@ func MapToAPIError(err error) *APIError {
 	return NewAPIError(http.StatusInternalServerError, "Internal server error", ErrorTypeInternal)
 }
 
-// WriteAPIError writes an API error response to the HTTP response writer
+// WriteAPIError maps err and writes it in the standard error envelope. A
+// missing type is derived from the status code and Retry-After is set when
+// the error says when to retry.
 func WriteAPIError(w http.ResponseWriter, err error) {
-	apiErr := MapToAPIError(err)
+	apiErr := *MapToAPIError(err)
+	if apiErr.Type == "" {
+		apiErr.Type = TypeForStatus(apiErr.Code)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	if apiErr.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(apiErr.RetryAfterSeconds))
+	}
 	w.WriteHeader(apiErr.Code)
-	
-	response := map[string]interface{}{
-		"success": false,
-		"error": map[string]interface{}{
-			"code":    apiErr.Code,
-			"message": apiErr.Message,
-			"type":    apiErr.Type,
-		},
+
+	json.NewEncoder(w).Encode(ErrorResponse{Success: false, Error: &apiErr})
+}
+
+// WriteError writes a plain message with the given status in the standard error envelope
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteAPIError(w, NewAPIError(status, message, TypeForStatus(status)))
+}
+
+// TypeForStatus returns the error type clients see for an HTTP status code
+func TypeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorTypeAuth
+	case http.StatusNotFound:
+		return ErrorTypeNotFound
+	case http.StatusConflict:
+		return ErrorTypeConflict
+	case http.StatusTooManyRequests:
+		return ErrorTypeRateLimit
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrorTypeUnavailable
 	}
-	
-	// Add validation details if applicable
-	if validationErrs, ok := err.(ValidationErrors); ok {
-		response["error"].(map[string]interface{})["details"] = validationErrs
+	if status >= http.StatusInternalServerError {
+		return ErrorTypeInternal
 	}
-	
-	json.NewEncoder(w).Encode(response)
+	// Remaining 4xx statuses reject the request as sent
+	return ErrorTypeValidation
 }
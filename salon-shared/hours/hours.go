@@ -0,0 +1,334 @@
+// Package hours models salon and branch working hours: standard weekly hours,
+// special-date overrides (e.g. festival hours) and holiday closures. Both
+// salon-service and booking-service resolve the hours in effect on a date
+// through Resolve so they agree on when a branch is open.
+//
+// Hours are stored as JSON keyed by lowercase weekday, with date overrides
+// under "overrides" keyed by date, e.g.
+//
+//	{"monday": {"open": "09:00", "close": "18:00"},
+//	 "tuesday": {"open": "09:00", "close": "18:00",
+//	             "ranges": [{"open": "09:00", "close": "13:00"}, {"open": "14:00", "close": "18:00"}]},
+//	 "sunday": {"closed": true},
+//	 "overrides": {"2024-11-01": {"open": "10:00", "close": "14:00"}}}
+//
+// Every open day keeps "open" and "close" (its first opening and last closing
+// time) so readers of the original single-range format still work. A weekday
+// that is missing, null or marked {"closed": true} is closed.
+package hours
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EricsAntony/salon/salon-shared/errors"
+)
+
+// DateLayout is the layout of override and holiday dates
+const DateLayout = "2006-01-02"
+
+const (
+	clockLayout  = "15:04"
+	overridesKey = "overrides"
+)
+
+// Weekdays lists the weekday keys in week order
+var Weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// Range is an opening period within a day in HH:MM clock time
+type Range struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// Day is the hours for one weekday or date. A day with no ranges is closed.
+type Day struct {
+	Closed bool    `json:"closed,omitempty"`
+	Ranges []Range `json:"ranges,omitempty"`
+}
+
+// IsOpen reports whether the day has any opening period
+func (d Day) IsOpen() bool {
+	return !d.Closed && len(d.Ranges) > 0
+}
+
+// WorkingHours is a weekly schedule keyed by lowercase weekday plus
+// overrides keyed by date (YYYY-MM-DD) that replace the weekly hours on that date
+type WorkingHours struct {
+	Weekly    map[string]Day `json:"weekly"`
+	Overrides map[string]Day `json:"overrides,omitempty"`
+}
+
+// Validate checks that weekday and date keys are well formed, that every range
+// is a pair of HH:MM times with close after open, and that each day's ranges
+// are listed in order without overlapping
+func (h WorkingHours) Validate() errors.ValidationErrors {
+	var errs errors.ValidationErrors
+	for _, day := range sortedKeys(h.Weekly) {
+		field := "weekly." + day
+		if !isWeekday(day) {
+			errs = errors.AppendValidationError(errs, field, "must be a lowercase weekday name")
+			continue
+		}
+		errs = validateDay(errs, field, h.Weekly[day])
+	}
+	for _, date := range sortedKeys(h.Overrides) {
+		field := "overrides." + date
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			errs = errors.AppendValidationError(errs, field, "must be a YYYY-MM-DD date")
+			continue
+		}
+		errs = validateDay(errs, field, h.Overrides[date])
+	}
+	return errs
+}
+
+func validateDay(errs errors.ValidationErrors, field string, day Day) errors.ValidationErrors {
+	if day.Closed {
+		if len(day.Ranges) > 0 {
+			errs = errors.AppendValidationError(errs, field, "a closed day cannot have ranges")
+		}
+		return errs
+	}
+	if len(day.Ranges) == 0 {
+		return errors.AppendValidationError(errs, field, "must have at least one range or be closed")
+	}
+
+	previousClose := -1
+	for i, r := range day.Ranges {
+		rangeField := fmt.Sprintf("%s.ranges[%d]", field, i)
+		open, close, ok := parseRange(r)
+		if !ok {
+			errs = errors.AppendValidationError(errs, rangeField, "open and close must be HH:MM times with close after open")
+			continue
+		}
+		if open < previousClose {
+			errs = errors.AppendValidationError(errs, rangeField, fmt.Sprintf("must start after %s.ranges[%d] closes", field, i-1))
+		}
+		previousClose = close
+	}
+	return errs
+}
+
+// FromMap reads hours stored as JSON. Malformed days are treated as closed,
+// matching how hours were read before they were validated on write.
+func FromMap(raw map[string]any) WorkingHours {
+	h := WorkingHours{Weekly: map[string]Day{}, Overrides: map[string]Day{}}
+	for key, value := range raw {
+		if key == overridesKey {
+			overrides, _ := value.(map[string]any)
+			for date, dayValue := range overrides {
+				if _, err := time.Parse(DateLayout, date); err == nil {
+					h.Overrides[date] = dayFromValue(dayValue)
+				}
+			}
+			continue
+		}
+		if isWeekday(key) {
+			h.Weekly[key] = dayFromValue(value)
+		}
+	}
+	return h
+}
+
+func dayFromValue(value any) Day {
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return Day{Closed: true}
+	}
+	if closed, _ := fields["closed"].(bool); closed {
+		return Day{Closed: true}
+	}
+
+	var ranges []Range
+	if list, ok := fields["ranges"].([]any); ok {
+		for _, item := range list {
+			rangeFields, _ := item.(map[string]any)
+			open, _ := rangeFields["open"].(string)
+			close, _ := rangeFields["close"].(string)
+			ranges = append(ranges, Range{Open: open, Close: close})
+		}
+	} else {
+		open, _ := fields["open"].(string)
+		close, _ := fields["close"].(string)
+		ranges = []Range{{Open: open, Close: close}}
+	}
+
+	for _, r := range ranges {
+		if _, _, ok := parseRange(r); !ok {
+			return Day{Closed: true}
+		}
+	}
+	if len(ranges) == 0 {
+		return Day{Closed: true}
+	}
+	return Day{Ranges: ranges}
+}
+
+// ToMap converts hours to their stored JSON form
+func (h WorkingHours) ToMap() map[string]any {
+	raw := make(map[string]any, len(h.Weekly)+1)
+	for day, hours := range h.Weekly {
+		raw[day] = dayToValue(hours)
+	}
+	if len(h.Overrides) > 0 {
+		overrides := make(map[string]any, len(h.Overrides))
+		for date, hours := range h.Overrides {
+			overrides[date] = dayToValue(hours)
+		}
+		raw[overridesKey] = overrides
+	}
+	return raw
+}
+
+func dayToValue(day Day) map[string]any {
+	if !day.IsOpen() {
+		return map[string]any{"closed": true}
+	}
+	value := map[string]any{
+		"open":  day.Ranges[0].Open,
+		"close": day.Ranges[len(day.Ranges)-1].Close,
+	}
+	if len(day.Ranges) > 1 {
+		ranges := make([]any, len(day.Ranges))
+		for i, r := range day.Ranges {
+			ranges[i] = map[string]any{"open": r.Open, "close": r.Close}
+		}
+		value["ranges"] = ranges
+	}
+	return value
+}
+
+// Source is the stored hours and holidays of a salon or branch. Holidays are
+// keyed by date; their values are not interpreted.
+type Source struct {
+	WorkingHours map[string]any
+	Holidays     map[string]any
+}
+
+// Where the effective hours for a date came from
+const (
+	FromBranchOverride = "branch_override"
+	FromSalonOverride  = "salon_override"
+	FromHoliday        = "holiday"
+	FromBranchWeekly   = "branch_weekly"
+	FromSalonWeekly    = "salon_weekly"
+	FromUnrestricted   = "unrestricted"
+)
+
+// Effective is the opening hours in effect on one date. Restricted is false
+// when neither the branch nor the salon has hours configured, in which case
+// staff schedules apply as-is.
+type Effective struct {
+	Date       string  `json:"date"`
+	Open       bool    `json:"open"`
+	Restricted bool    `json:"restricted"`
+	Ranges     []Range `json:"ranges"`
+	Source     string  `json:"source"`
+}
+
+// Resolve computes the hours of a branch on date. The first rule that applies wins:
+//
+//  1. a branch override for the date
+//  2. a salon override for the date
+//  3. a branch or salon holiday on the date, which closes the branch
+//  4. the branch weekly hours, when the branch has any
+//  5. the salon weekly hours, when the salon has any
+//  6. otherwise the branch is unrestricted
+//
+// Pass a zero Source for salon-level hours without a branch.
+func Resolve(date time.Time, branch, salon Source) Effective {
+	key := date.Format(DateLayout)
+	branchHours := FromMap(branch.WorkingHours)
+	salonHours := FromMap(salon.WorkingHours)
+
+	if day, ok := branchHours.Overrides[key]; ok {
+		return effectiveDay(key, day, FromBranchOverride)
+	}
+	if day, ok := salonHours.Overrides[key]; ok {
+		return effectiveDay(key, day, FromSalonOverride)
+	}
+	if isHoliday(branch.Holidays, key) || isHoliday(salon.Holidays, key) {
+		return effectiveDay(key, Day{Closed: true}, FromHoliday)
+	}
+
+	weekday := strings.ToLower(date.Weekday().String())
+	if len(branchHours.Weekly) > 0 {
+		return effectiveDay(key, branchHours.Weekly[weekday], FromBranchWeekly)
+	}
+	if len(salonHours.Weekly) > 0 {
+		return effectiveDay(key, salonHours.Weekly[weekday], FromSalonWeekly)
+	}
+	return Effective{Date: key, Open: true, Ranges: []Range{}, Source: FromUnrestricted}
+}
+
+func effectiveDay(date string, day Day, source string) Effective {
+	effective := Effective{Date: date, Open: day.IsOpen(), Restricted: true, Ranges: []Range{}, Source: source}
+	if effective.Open {
+		effective.Ranges = day.Ranges
+	}
+	return effective
+}
+
+// Window is an opening period as instants
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Windows returns the opening periods on date, in date's location. It is
+// empty when closed or unrestricted.
+func (e Effective) Windows(date time.Time) []Window {
+	windows := make([]Window, 0, len(e.Ranges))
+	for _, r := range e.Ranges {
+		open, close, ok := parseRange(r)
+		if !ok {
+			continue
+		}
+		windows = append(windows, Window{Start: clockOn(date, open), End: clockOn(date, close)})
+	}
+	return windows
+}
+
+// clockOn returns the instant minutes past midnight on date's calendar day
+func clockOn(date time.Time, minutes int) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), minutes/60, minutes%60, 0, 0, date.Location())
+}
+
+// parseRange returns a range as minutes since midnight
+func parseRange(r Range) (open, close int, ok bool) {
+	o, err := time.Parse(clockLayout, strings.TrimSpace(r.Open))
+	if err != nil {
+		return 0, 0, false
+	}
+	c, err := time.Parse(clockLayout, strings.TrimSpace(r.Close))
+	if err != nil || !c.After(o) {
+		return 0, 0, false
+	}
+	return o.Hour()*60 + o.Minute(), c.Hour()*60 + c.Minute(), true
+}
+
+func isHoliday(holidays map[string]any, date string) bool {
+	_, ok := holidays[date]
+	return ok
+}
+
+func isWeekday(day string) bool {
+	for _, weekday := range Weekdays {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(days map[string]Day) []string {
+	keys := make([]string, 0, len(days))
+	for key := range days {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,134 @@
+// Package lockout temporarily blocks OTP verification for a phone number after
+// repeated failures. The failure count is kept in a store shared by every
+// service that verifies OTPs, so switching endpoints or phone formats does not
+// reset it.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrLocked matches any *LockedError via errors.Is
+var ErrLocked = errors.New("too many failed attempts")
+
+// LockedError reports that a phone number is locked out and when to retry
+type LockedError struct {
+	Until      time.Time
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts; try again in %d seconds", e.RetryAfterSeconds())
+}
+
+// Is lets errors.Is(err, ErrLocked) match
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// RetryAfterSeconds rounds the remaining lockout up to whole seconds
+func (e *LockedError) RetryAfterSeconds() int {
+	secs := int(e.RetryAfter / time.Second)
+	if e.RetryAfter%time.Second != 0 {
+		secs++
+	}
+	return secs
+}
+
+// Store persists failure counts and lockouts by key. Implementations must
+// share one table across services.
+type Store interface {
+	// RecordFailure counts a failure for key at now. Failures recorded before
+	// windowStart are forgotten first. It returns the count in the current window.
+	RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error)
+	// Lock blocks key until the given time and clears its failure count
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil returns when key's lockout ends, or the zero time if it has none
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset clears failures and any lockout for key
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy sets how many failures within Window trigger a lockout of Duration
+type Policy struct {
+	MaxFailures int
+	Window      time.Duration
+	Duration    time.Duration
+}
+
+// Guard applies a Policy to a Store on behalf of one service
+type Guard struct {
+	store   Store
+	policy  Policy
+	service string
+}
+
+// NewGuard creates a Guard; service names the caller in audit logs
+func NewGuard(store Store, policy Policy, service string) *Guard {
+	return &Guard{store: store, policy: policy, service: service}
+}
+
+// Key normalizes a phone number to its digits so every format of the same
+// number shares one counter
+func Key(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Check returns a *LockedError if phone is currently locked out
+func (g *Guard) Check(ctx context.Context, phone string) error {
+	until, err := g.store.LockedUntil(ctx, Key(phone))
+	if err != nil {
+		return fmt.Errorf("check lockout: %w", err)
+	}
+	if now := time.Now(); until.After(now) {
+		return &LockedError{Until: until, RetryAfter: until.Sub(now)}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt for phone. When the failure reaches
+// the policy limit the phone is locked out, an audit entry is logged and a
+// *LockedError is returned.
+func (g *Guard) RecordFailure(ctx context.Context, phone string) error {
+	key := Key(phone)
+	now := time.Now()
+	failures, err := g.store.RecordFailure(ctx, key, now, now.Add(-g.policy.Window))
+	if err != nil {
+		return fmt.Errorf("record failed attempt: %w", err)
+	}
+	if failures < g.policy.MaxFailures {
+		return nil
+	}
+
+	until := now.Add(g.policy.Duration)
+	if err := g.store.Lock(ctx, key, until); err != nil {
+		return fmt.Errorf("lock out: %w", err)
+	}
+	log.Warn().
+		Str("audit_type", "security").
+		Str("service_name", g.service).
+		Str("action", "otp_lockout").
+		Str("resource", "phone").
+		Str("resource_id", key).
+		Int("failed_attempts", failures).
+		Time("locked_until", until).
+		Msg("audit log")
+	return &LockedError{Until: until, RetryAfter: g.policy.Duration}
+}
+
+// Reset clears the failure count for phone, e.g. after a successful login
+func (g *Guard) Reset(ctx context.Context, phone string) error {
+	return g.store.Reset(ctx, Key(phone))
+}
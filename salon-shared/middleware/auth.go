@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/EricsAntony/salon/salon-shared/errors"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,14 +17,17 @@ func UserTypeMiddleware(jwt *auth.JWTManager, expectedUserType string) func(http
 			authz := r.Header.Get("Authorization")
 			parts := strings.SplitN(authz, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				errors.WriteError(w, http.StatusUnauthorized, "missing bearer token")
 				return
 			}
 
 			claims, err := jwt.ValidateAccessToken(parts[1])
 			if err != nil {
 				log.Warn().Err(err).Msg("invalid access token")
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				errors.WriteError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+			if jwt.RejectRevoked(w, r, claims) {
 				return
 			}
 
@@ -33,11 +37,12 @@ func UserTypeMiddleware(jwt *auth.JWTManager, expectedUserType string) func(http
 					Str("expected_type", expectedUserType).
 					Str("actual_type", claims.UserType).
 					Msg("unauthorized user type")
-				http.Error(w, "unauthorized user type", http.StatusForbidden)
+				errors.WriteError(w, http.StatusForbidden, "unauthorized user type")
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), auth.CtxUserID, claims.UserID)
+			ctx = context.WithValue(ctx, auth.CtxAccessToken, parts[1])
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -60,18 +65,22 @@ func RequireAuthMiddleware(jwt *auth.JWTManager) func(http.Handler) http.Handler
 			authz := r.Header.Get("Authorization")
 			parts := strings.SplitN(authz, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				errors.WriteError(w, http.StatusUnauthorized, "missing bearer token")
 				return
 			}
 
 			claims, err := jwt.ValidateAccessToken(parts[1])
 			if err != nil {
 				log.Warn().Err(err).Msg("invalid access token")
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				errors.WriteError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+			if jwt.RejectRevoked(w, r, claims) {
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), auth.CtxUserID, claims.UserID)
+			ctx = context.WithValue(ctx, auth.CtxAccessToken, parts[1])
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
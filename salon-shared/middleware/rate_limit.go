@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/EricsAntony/salon/salon-shared/errors"
 	"github.com/rs/zerolog/log"
 )
 
@@ -133,7 +134,7 @@ func OTPRateLimitMiddleware(rateLimiter *RateLimiter) func(http.Handler) http.Ha
 					Str("client_ip", clientIP).
 					Int("current_count", rateLimiter.GetCurrentCount(clientIP)).
 					Msg("OTP rate limit exceeded")
-				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				errors.WriteError(w, http.StatusTooManyRequests, "rate limit exceeded")
 				return
 			}
 			
@@ -153,7 +154,7 @@ func GenericRateLimitMiddleware(rateLimiter *RateLimiter, keyExtractor func(*htt
 					Str("rate_limit_key", key).
 					Int("current_count", rateLimiter.GetCurrentCount(key)).
 					Msg("rate limit exceeded")
-				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				errors.WriteError(w, http.StatusTooManyRequests, "rate limit exceeded")
 				return
 			}
 			
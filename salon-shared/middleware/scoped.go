@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/EricsAntony/salon/salon-shared/errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 )
@@ -22,7 +23,7 @@ func SalonScopedMiddleware(repo SalonRepository) func(http.Handler) http.Handler
 			salonID := chi.URLParam(r, "salonID")
 			
 			if salonID == "" {
-				http.Error(w, "salon ID required", http.StatusBadRequest)
+				errors.WriteError(w, http.StatusBadRequest, "salon ID required")
 				return
 			}
 
@@ -30,13 +31,49 @@ func SalonScopedMiddleware(repo SalonRepository) func(http.Handler) http.Handler
 			hasAccess, err := repo.StaffHasAccessToSalon(r.Context(), userID, salonID)
 			if err != nil {
 				log.Error().Err(err).Str("staff_id", userID).Str("salon_id", salonID).Msg("failed to check salon access")
-				http.Error(w, "internal error", http.StatusInternalServerError)
+				errors.WriteError(w, http.StatusInternalServerError, "internal error")
 				return
 			}
 
 			if !hasAccess {
 				log.Warn().Str("staff_id", userID).Str("salon_id", salonID).Msg("unauthorized salon access attempt")
-				http.Error(w, "access denied to salon", http.StatusForbidden)
+				errors.WriteError(w, http.StatusForbidden, "access denied to salon")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BranchRepository interface for checking staff branch access
+type BranchRepository interface {
+	StaffHasAccessToBranch(ctx context.Context, staffID, branchID string) (bool, error)
+}
+
+// BranchScopedMiddleware ensures staff can only access branches of their own
+// salon. param names the chi URL parameter holding the branch ID.
+func BranchScopedMiddleware(repo BranchRepository, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(auth.CtxUserID).(string)
+			branchID := chi.URLParam(r, param)
+
+			if branchID == "" {
+				errors.WriteError(w, http.StatusBadRequest, "branch ID required")
+				return
+			}
+
+			hasAccess, err := repo.StaffHasAccessToBranch(r.Context(), userID, branchID)
+			if err != nil {
+				log.Error().Err(err).Str("staff_id", userID).Str("branch_id", branchID).Msg("failed to check branch access")
+				errors.WriteError(w, http.StatusInternalServerError, "internal error")
+				return
+			}
+
+			if !hasAccess {
+				log.Warn().Str("staff_id", userID).Str("branch_id", branchID).Msg("unauthorized branch access attempt")
+				errors.WriteError(w, http.StatusForbidden, "access denied to branch")
 				return
 			}
 
@@ -53,14 +90,14 @@ func UserScopedMiddleware() func(http.Handler) http.Handler {
 			targetUserID := chi.URLParam(r, "id")
 			
 			if targetUserID == "" {
-				http.Error(w, "user ID required", http.StatusBadRequest)
+				errors.WriteError(w, http.StatusBadRequest, "user ID required")
 				return
 			}
 
 			// Check if the user is trying to access their own data
 			if userID != targetUserID {
 				log.Warn().Str("user_id", userID).Str("target_user_id", targetUserID).Msg("unauthorized user access attempt")
-				http.Error(w, "access denied: can only access own data", http.StatusForbidden)
+				errors.WriteError(w, http.StatusForbidden, "access denied: can only access own data")
 				return
 			}
 
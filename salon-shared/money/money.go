@@ -0,0 +1,42 @@
+// Package money rounds monetary amounts to two decimal places at every step so
+// that totals always equal the sum of their displayed components. Amounts stay
+// float64 for compatibility but are computed in integer minor units (paise, cents).
+package money
+
+import "math"
+
+// ToMinor converts an amount to integer minor units, rounding half away from zero
+func ToMinor(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromMinor converts integer minor units back to an amount
+func FromMinor(minor int64) float64 {
+	return float64(minor) / 100
+}
+
+// Round rounds an amount to two decimal places
+func Round(amount float64) float64 {
+	return FromMinor(ToMinor(amount))
+}
+
+// Sum adds amounts after rounding each one, so the result equals the sum of
+// the rounded components exactly
+func Sum(amounts ...float64) float64 {
+	var total int64
+	for _, amount := range amounts {
+		total += ToMinor(amount)
+	}
+	return FromMinor(total)
+}
+
+// Sub subtracts b from a after rounding both
+func Sub(a, b float64) float64 {
+	return FromMinor(ToMinor(a) - ToMinor(b))
+}
+
+// Percentage returns percent of amount rounded to two decimal places, e.g.
+// Percentage(99.99, 18) is 18.00
+func Percentage(amount, percent float64) float64 {
+	return FromMinor(int64(math.Round(float64(ToMinor(amount)) * percent / 100)))
+}
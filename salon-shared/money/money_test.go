@@ -0,0 +1,108 @@
+package money
+
+import "testing"
+
+func TestSumHasNoFloatDrift(t *testing.T) {
+	tests := []struct {
+		name    string
+		amounts []float64
+		want    float64
+	}{
+		{name: "0.1 + 0.2", amounts: []float64{0.1, 0.2}, want: 0.3},
+		{name: "ten times 0.1", amounts: []float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1}, want: 1},
+		{name: "prices and fee", amounts: []float64{19.99, 0.01, 5.55}, want: 25.55},
+		{name: "line items with tax", amounts: []float64{1.1, 2.2, 3.3}, want: 6.6},
+		{name: "refund", amounts: []float64{100.1, -0.3}, want: 99.8},
+		{name: "sub-paise components are rounded first", amounts: []float64{0.004, 0.004, 0.004}, want: 0},
+		{name: "none", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sum(tt.amounts...); got != tt.want {
+				t.Errorf("Sum(%v) = %v, want %v", tt.amounts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	tests := []struct {
+		a, b, want float64
+	}{
+		{a: 0.3, b: 0.1, want: 0.2},
+		{a: 1, b: 0.9, want: 0.1},
+		{a: 1180, b: 180, want: 1000},
+		{a: 0.1, b: 0.3, want: -0.2},
+	}
+
+	for _, tt := range tests {
+		if got := Sub(tt.a, tt.b); got != tt.want {
+			t.Errorf("Sub(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMinorUnitRoundTrip(t *testing.T) {
+	for minor := int64(-100000); minor <= 100000; minor++ {
+		if got := ToMinor(FromMinor(minor)); got != minor {
+			t.Fatalf("ToMinor(FromMinor(%d)) = %d", minor, got)
+		}
+	}
+
+	tests := []struct {
+		amount float64
+		minor  int64
+	}{
+		{amount: 0, minor: 0},
+		{amount: 0.01, minor: 1},
+		{amount: 0.29, minor: 29},
+		{amount: 1.15, minor: 115},
+		{amount: 12345.67, minor: 1234567},
+		{amount: 99999999.99, minor: 9999999999},
+		{amount: -4.35, minor: -435},
+	}
+	for _, tt := range tests {
+		if got := ToMinor(tt.amount); got != tt.minor {
+			t.Errorf("ToMinor(%v) = %d, want %d", tt.amount, got, tt.minor)
+		}
+		if got := FromMinor(tt.minor); got != tt.amount {
+			t.Errorf("FromMinor(%d) = %v, want %v", tt.minor, got, tt.amount)
+		}
+	}
+}
+
+func TestRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   float64
+	}{
+		{amount: 0.125, want: 0.13},
+		{amount: -0.125, want: -0.13},
+		{amount: 0.124, want: 0.12},
+		{amount: 10.005000001, want: 10.01},
+	}
+
+	for _, tt := range tests {
+		if got := Round(tt.amount); got != tt.want {
+			t.Errorf("Round(%v) = %v, want %v", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	tests := []struct {
+		amount, percent, want float64
+	}{
+		{amount: 99.99, percent: 18, want: 18},
+		{amount: 1000, percent: 9, want: 90},
+		{amount: 0.1, percent: 50, want: 0.05},
+		{amount: 333.33, percent: 2.5, want: 8.33},
+	}
+
+	for _, tt := range tests {
+		if got := Percentage(tt.amount, tt.percent); got != tt.want {
+			t.Errorf("Percentage(%v, %v) = %v, want %v", tt.amount, tt.percent, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package pagination parses list query parameters and builds the list
+// envelope every service returns, so clients page through any collection
+// the same way.
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page size bounds applied to every paginated listing
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Params selects a window of a listing. Cursor is an opaque resume token for
+// listings that support keyset paging; when set, Offset is ignored.
+type Params struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// ParamError reports an invalid pagination query parameter
+type ParamError struct {
+	Param   string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+// Parse reads the optional limit, offset and cursor query parameters. A
+// missing limit defaults to DefaultLimit and larger limits are capped at
+// MaxLimit; malformed or negative values are rejected.
+func Parse(query url.Values) (Params, error) {
+	params := Params{Limit: DefaultLimit}
+	if val := strings.TrimSpace(query.Get("limit")); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit < 1 {
+			return params, &ParamError{Param: "limit", Message: "limit must be a positive integer"}
+		}
+		params.Limit = min(limit, MaxLimit)
+	}
+	if val := strings.TrimSpace(query.Get("offset")); val != "" {
+		offset, err := strconv.Atoi(val)
+		if err != nil || offset < 0 {
+			return params, &ParamError{Param: "offset", Message: "offset must be a non-negative integer"}
+		}
+		params.Offset = offset
+	}
+	params.Cursor = strings.TrimSpace(query.Get("cursor"))
+	return params, nil
+}
+
+// Normalize applies the default page size and caps oversized or negative
+// values, for callers that build Params without Parse
+func (p Params) Normalize() Params {
+	if p.Limit <= 0 {
+		p.Limit = DefaultLimit
+	}
+	if p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
+// ListResponse is the standard list envelope. Total counts every item
+// matching the query, not just this page. NextCursor is only set by cursor
+// paged listings when more items remain.
+type ListResponse[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewListResponse builds the envelope for one page, encoding nil items as an empty list
+func NewListResponse[T any](items []T, total int, params Params) *ListResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return &ListResponse[T]{Items: items, Total: total, Limit: params.Limit, Offset: params.Offset}
+}
@@ -0,0 +1,127 @@
+// Package requestbody limits the size of request bodies and requires JSON
+// bodies on write requests. It has no dependencies on other salon-shared
+// packages so every service can use it regardless of how it imports
+// salon-shared; rejections are written through the service's error writer.
+package requestbody
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// DefaultMaxBytes is the body size limit used when none is configured
+const DefaultMaxBytes int64 = 1 << 20
+
+// ErrorWriter writes a rejected request's status and message, normally
+// errors.WriteError from salon-shared
+type ErrorWriter func(w http.ResponseWriter, status int, message string)
+
+// Config configures the middleware
+type Config struct {
+	// MaxBytes is the largest body accepted; DefaultMaxBytes when not positive
+	MaxBytes int64
+	// WriteError writes 413 and 415 responses in the service's error envelope
+	WriteError ErrorWriter
+}
+
+// Middleware applies both Limit and RequireJSON
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	limit, requireJSON := Limit(cfg), RequireJSON(cfg)
+	return func(next http.Handler) http.Handler {
+		return requireJSON(limit(next))
+	}
+}
+
+// RequireJSON rejects POST, PUT and PATCH bodies that are not
+// application/json with 415. Requests without a body pass through.
+func RequireJSON(cfg Config) func(http.Handler) http.Handler {
+	writeError := cfg.errorWriter()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasBody(r) && isWrite(r.Method) && !isJSON(r.Header.Get("Content-Type")) {
+				writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Limit rejects bodies larger than cfg.MaxBytes with 413.
+//
+// Bodies of known length are checked against the limit before the handler
+// runs and then capped with http.MaxBytesReader. Chunked bodies are read up
+// to the limit first, so an oversized upload is always answered with 413
+// rather than surfacing as a decode error inside the handler.
+func Limit(cfg Config) func(http.Handler) http.Handler {
+	maxBytes := cfg.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	writeError := cfg.errorWriter()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasBody(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, tooLargeMessage)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			if r.ContentLength < 0 {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						writeError(w, http.StatusRequestEntityTooLarge, tooLargeMessage)
+					} else {
+						writeError(w, http.StatusBadRequest, "failed to read request body")
+					}
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				r.ContentLength = int64(len(body))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+const tooLargeMessage = "request body too large"
+
+func (cfg Config) errorWriter() ErrorWriter {
+	if cfg.WriteError != nil {
+		return cfg.WriteError
+	}
+	return func(w http.ResponseWriter, status int, message string) {
+		http.Error(w, message, status)
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// isJSON reports whether a Content-Type header names application/json,
+// ignoring parameters such as charset
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
@@ -13,18 +13,6 @@ func WriteJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-// WriteError writes a standardized error response
-func WriteError(w http.ResponseWriter, status int, message string) {
-	WriteJSON(w, status, map[string]any{
-		"success": false,
-		"error": map[string]any{
-			"code":    status,
-			"type":    http.StatusText(status),
-			"message": message,
-		},
-	})
-}
-
 // WriteSuccess writes a standardized success response
 func WriteSuccess(w http.ResponseWriter, data any) {
 	WriteJSON(w, http.StatusOK, map[string]any{
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// BodyField is the field reported for errors about the request body as a whole
+const BodyField = "request_body"
+
+// DecodeError explains why a JSON request body was rejected. errors.MapToAPIError
+// reports it as a validation error on Field.
+type DecodeError struct {
+	field  string
+	reason string
+}
+
+func (e *DecodeError) Error() string {
+	return e.field + ": " + e.reason
+}
+
+// Field is the JSON name of the offending field, or BodyField when the body
+// as a whole is malformed
+func (e *DecodeError) Field() string {
+	return e.field
+}
+
+// Reason describes what is wrong with the field
+func (e *DecodeError) Reason() string {
+	return e.reason
+}
+
+// DecodeJSON decodes a JSON request body into v, rejecting fields v does not
+// declare so a misspelt field fails instead of silently decoding as zero.
+// Failures are a *DecodeError, except that a body cut off by
+// http.MaxBytesReader returns the *http.MaxBytesError unchanged.
+func DecodeJSON(body io.Reader, v any) error {
+	return decodeJSON(body, v, true)
+}
+
+// DecodeJSONLenient is DecodeJSON but ignores unknown fields. Use it only for
+// payloads that may gain fields before this service knows them, such as
+// gateway webhooks and events from other services.
+func DecodeJSONLenient(body io.Reader, v any) error {
+	return decodeJSON(body, v, false)
+}
+
+func decodeJSON(body io.Reader, v any, strict bool) error {
+	dec := json.NewDecoder(body)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if dec.More() {
+		return &DecodeError{field: BodyField, reason: "must contain a single JSON value"}
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return &DecodeError{field: BodyField, reason: "is empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{field: BodyField, reason: "is incomplete JSON"}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{field: BodyField, reason: fmt.Sprintf("is malformed JSON at offset %d", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return &DecodeError{field: BodyField, reason: "must be " + jsonTypeName(typeErr.Type)}
+		}
+		return &DecodeError{field: typeErr.Field, reason: "must be " + jsonTypeName(typeErr.Type)}
+	}
+
+	// encoding/json has no type for unknown fields: json: unknown field "name"
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &DecodeError{field: strings.Trim(name, `"`), reason: "is not a known field"}
+	}
+	// Errors from UnmarshalJSON methods, e.g. an invalid UUID or time
+	return &DecodeError{field: BodyField, reason: err.Error()}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// jsonTypeName names the JSON type a Go type decodes from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// UUIDs, times and similar types decode from strings
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "a string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
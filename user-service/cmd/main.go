@@ -15,15 +15,17 @@ import (
 
 	"user-service/internal/api"
 	"user-service/internal/cleanup"
+	"user-service/internal/db"
+	"user-service/internal/notification"
 	"user-service/internal/repository"
 	"user-service/internal/service"
 	"user-service/internal/tracing"
 
 	"github.com/EricsAntony/salon/salon-shared/auth"
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
 	"github.com/EricsAntony/salon/salon-shared/logger"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
 	"user-service/internal/config"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
@@ -32,6 +34,9 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to load config")
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("invalid config")
+	}
 
 	// Convert to shared config for compatibility
 	sharedCfg := cfg.ToSharedConfig()
@@ -48,7 +53,7 @@ func main() {
 	}
 
 	// Connect DB
-	pool, err := pgxpool.New(context.Background(), cfg.DB.URL)
+	pool, err := db.NewPool(context.Background(), cfg.DB.URL, cfg.DBPool())
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
@@ -58,9 +63,22 @@ func main() {
 	userRepo := repository.NewUserRepository(pool)
 	otpRepo := repository.NewOTPRepository(pool)
 	tokenRepo := repository.NewTokenRepository(pool)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(pool)
+	notifier := notification.NewClient(cfg.Notification.URL)
+	revocationRepo := repository.NewRevocationRepository(pool)
+	revocationCache := auth.NewRevocationCache(revocationRepo, auth.DefaultRevocationCacheTTL)
 	jwtMgr := auth.NewJWTManager(sharedCfg)
-	userSvc := service.NewUserService(userRepo, otpRepo, tokenRepo, jwtMgr, cfg)
-	h := api.NewHandler(userSvc, jwtMgr, sharedCfg)
+	jwtMgr.SetRevocationStore(revocationCache)
+	lockoutStore := repository.NewLockoutStore(pool)
+	otpDispatcher, err := auth.NewOTPDispatcher(cfg.SMS)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to configure OTP provider")
+	}
+	if _, ok := otpDispatcher.Sender().(auth.LogOTPSender); ok && !sharedCfg.IsDevelopment() {
+		log.Warn().Str("env", cfg.Env).Msg("OTP codes are only logged; configure an SMS provider")
+	}
+	userSvc := service.NewUserService(userRepo, otpRepo, tokenRepo, emailVerificationRepo, lockoutStore, revocationRepo, revocationCache, notifier, otpDispatcher, jwtMgr, cfg)
+	h := api.NewHandler(userSvc, jwtMgr, sharedCfg, cfg.Internal.ServiceToken)
 
 	// Start OTP cleanup service
 	cleanupSvc := cleanup.NewOTPCleanupService(otpRepo, 1*time.Hour, 24*time.Hour)
@@ -73,6 +91,10 @@ func main() {
 	r.Use(middleware.RequestID)
 	r.Use(api.RequestLogger)
 	r.Use(middleware.Recoverer)
+	r.Use(requestbody.Middleware(requestbody.Config{
+		MaxBytes:   int64(cfg.Server.MaxBodyBytes),
+		WriteError: sharederrors.WriteError,
+	}))
 
 	// Add Prometheus metrics endpoint
 	r.Handle("/metrics", promhttp.Handler())
@@ -5,6 +5,7 @@ import (
 	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -17,6 +18,8 @@ import (
 
 	"github.com/EricsAntony/salon/salon-shared/auth"
 	"github.com/EricsAntony/salon/salon-shared/config"
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/lockout"
 	sharedMiddleware "github.com/EricsAntony/salon/salon-shared/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -28,17 +31,20 @@ type Handler struct {
 	jwt         *auth.JWTManager
 	cfg         *config.Config
 	rateLimiter *sharedMiddleware.RateLimiter
+	// serviceToken guards the /internal routes used by other services
+	serviceToken string
 }
 
-func NewHandler(svc service.UserService, jwt *auth.JWTManager, cfg *config.Config) *Handler {
+func NewHandler(svc service.UserService, jwt *auth.JWTManager, cfg *config.Config, serviceToken string) *Handler {
 	// Create rate limiter: 3 OTP requests per 10 minutes per IP
 	rateLimiter := sharedMiddleware.NewRateLimiter(3, 10*time.Minute)
 	
 	return &Handler{
-		svc:         svc,
-		jwt:         jwt,
-		cfg:         cfg,
-		rateLimiter: rateLimiter,
+		svc:          svc,
+		jwt:          jwt,
+		cfg:          cfg,
+		rateLimiter:  rateLimiter,
+		serviceToken: serviceToken,
 	}
 }
 
@@ -55,11 +61,20 @@ func (h *Handler) RegisterRoutes(r *chi.Mux) {
 	r.Post("/user/register", h.register)
 	r.Post("/user/authenticate", h.authenticate)
 	r.Post("/auth/refresh", h.refresh)
+	// Opened from the emailed link, so it carries no bearer token
+	r.Get("/user/email/verify", h.verifyEmail)
 
 	// Health endpoints (no auth required)
 	r.Get("/health", h.health)
 	r.Get("/ready", h.readiness)
 
+	// Service-to-service routes, authenticated by the shared service token
+	r.Group(func(r chi.Router) {
+		r.Use(RequireServiceToken(h.serviceToken))
+		r.Get("/internal/users/{id}", h.getInternalUser)
+		r.Post("/internal/users/guests", h.createGuestUser)
+	})
+
 	r.Group(func(r chi.Router) {
 		// Use customer-specific middleware for protected routes
 		r.Use(sharedMiddleware.CustomerMiddleware(h.jwt))
@@ -71,6 +86,11 @@ func (h *Handler) RegisterRoutes(r *chi.Mux) {
 		r.With(sharedMiddleware.UserScopedMiddleware()).Put("/users/{id}", h.updateUser)
 		r.With(sharedMiddleware.UserScopedMiddleware()).Delete("/users/{id}", h.deleteUser)
 		
+		// Phone number change for the authenticated user
+		r.With(sharedMiddleware.OTPRateLimitMiddleware(h.rateLimiter)).Post("/user/phone/change-request", h.requestPhoneChange)
+		r.Post("/user/phone/change-confirm", h.confirmPhoneChange)
+		r.Post("/user/email/verification", h.resendEmailVerification)
+
 		// Auth operations (no user scoping needed)
 		r.Post("/auth/revoke", h.revoke)
 	})
@@ -86,12 +106,12 @@ func (h *Handler) requestOTP(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	code, err := h.svc.RequestOTP(r.Context(), req.PhoneNumber)
+	sent, err := h.svc.RequestOTP(r.Context(), req.PhoneNumber, req.Channel)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, err.Error())
+		writeAPIError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "otp_sent", "code": code})
+	writeJSON(w, http.StatusOK, h.otpSentResponse(sent))
 }
 
 func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
@@ -115,6 +135,10 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 		OTP:      req.OTP,
 	}
 	u, access, refresh, err := h.svc.Register(r.Context(), params)
+	if errors.Is(err, lockout.ErrLocked) {
+		writeAPIError(w, err)
+		return
+	}
 	if err != nil {
 		writeErr(w, http.StatusBadRequest, err.Error())
 		return
@@ -158,6 +182,62 @@ func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, u)
 }
 
+// internalUserInfo is the minimal user view shared with other services
+type internalUserInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Phone         string `json:"phone"`
+	IsGuest       bool   `json:"is_guest"`
+}
+
+func (h *Handler) getInternalUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(strings.TrimSpace(id)); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	u, err := h.svc.GetUser(r.Context(), id)
+	if err != nil {
+		writeErr(w, http.StatusNotFound, err.Error())
+		return
+	}
+	info := internalUserInfo{ID: u.ID, Name: u.Name, Phone: u.PhoneNumber, EmailVerified: u.EmailVerified, IsGuest: u.IsGuest}
+	if u.Email != nil {
+		info.Email = *u.Email
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// createGuestUser returns the user registered with the phone number, creating
+// a guest record for walk-in bookings when there is none (201)
+func (h *Handler) createGuestUser(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.GuestReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if err := req.ValidateStrict(); err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, created, err := h.svc.CreateGuest(r.Context(), service.GuestParams{Phone: req.PhoneNumber, Name: req.Name, Email: req.Email})
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	info := internalUserInfo{ID: u.ID, Name: u.Name, Phone: u.PhoneNumber, EmailVerified: u.EmailVerified, IsGuest: u.IsGuest}
+	if u.Email != nil {
+		info.Email = *u.Email
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, info)
+}
+
 func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
 	csrfHdr := r.Header.Get("X-CSRF-Token")
 	csrfC, err := r.Cookie("csrf_token")
@@ -242,6 +322,85 @@ func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, u)
 }
 
+func (h *Handler) requestPhoneChange(w http.ResponseWriter, r *http.Request) {
+	uid, _ := r.Context().Value(auth.CtxUserID).(string)
+	if uid == "" {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req interfaces.PhoneChangeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if err := req.ValidateStrict(); err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sent, err := h.svc.RequestPhoneChange(r.Context(), uid, req.PhoneNumber, req.Channel)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.otpSentResponse(sent))
+}
+
+func (h *Handler) confirmPhoneChange(w http.ResponseWriter, r *http.Request) {
+	uid, _ := r.Context().Value(auth.CtxUserID).(string)
+	if uid == "" {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req interfaces.PhoneChangeConfirmReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if err := req.ValidateStrict(); err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := h.svc.ConfirmPhoneChange(r.Context(), uid, req.PhoneNumber, req.OTP)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	// All refresh tokens were revoked; drop this session's cookies too
+	h.clearRefreshCookie(w)
+	h.clearCSRFCookie(w)
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (h *Handler) resendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	uid, _ := r.Context().Value(auth.CtxUserID).(string)
+	if uid == "" {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if err := h.svc.SendEmailVerification(r.Context(), uid); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "verification_sent"})
+}
+
+func (h *Handler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if len(token) != 64 {
+		writeErr(w, http.StatusBadRequest, "invalid token")
+		return
+	}
+	if _, err := hex.DecodeString(token); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid token")
+		return
+	}
+	if err := h.svc.VerifyEmail(r.Context(), token); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "email_verified"})
+}
+
 func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
 	targetID := chi.URLParam(r, "id")
 	if _, err := uuid.Parse(strings.TrimSpace(targetID)); err != nil {
@@ -288,8 +447,17 @@ func (h *Handler) refreshCookieExpiry() time.Time {
 }
 
 func (h *Handler) secureCookies() bool {
-	env := strings.ToLower(strings.TrimSpace(h.cfg.Env))
-	return env != "dev" && env != "docker" && env != "local"
+	return !h.cfg.IsDevelopment()
+}
+
+// otpSentResponse only echoes the OTP code outside production so it can't
+// leak from a live environment
+func (h *Handler) otpSentResponse(sent *service.OTPSent) map[string]string {
+	resp := map[string]string{"status": "otp_sent", "channel": sent.Channel}
+	if h.cfg.IsDevelopment() {
+		resp["code"] = sent.Code
+	}
+	return resp
 }
 
 func (h *Handler) setRefreshCookie(w http.ResponseWriter, token string) {
@@ -358,26 +526,13 @@ func writeJSON(w http.ResponseWriter, code int, v interface{}) {
 }
 
 func writeErr(w http.ResponseWriter, code int, msg string) {
-	writeJSON(w, code, map[string]interface{}{
-		"success": false,
-		"error": map[string]interface{}{
-			"code":    code,
-			"message": msg,
-		},
-	})
+	sharederrors.WriteError(w, code, msg)
 }
 
 func writeAPIError(w http.ResponseWriter, err error) {
 	apiErr := appErrors.MapToAPIError(err)
 	log.Error().Err(err).Int("status_code", apiErr.Code).Str("error_type", apiErr.Type).Msg("api error")
-	writeJSON(w, apiErr.Code, map[string]interface{}{
-		"success": false,
-		"error": map[string]interface{}{
-			"code":    apiErr.Code,
-			"message": apiErr.Message,
-			"type":    apiErr.Type,
-		},
-	})
+	sharederrors.WriteAPIError(w, apiErr)
 }
 
 // RequireCSRF validates CSRF token for unsafe HTTP methods by comparing
@@ -19,6 +19,8 @@ var (
 // POST /otp/request
 type ReqOTP struct {
 	PhoneNumber string `json:"phone_number"`
+	// Channel optionally picks the first delivery channel (sms, whatsapp or voice)
+	Channel string `json:"channel,omitempty"`
 }
 
 func (r *ReqOTP) ValidateStrict() error {
@@ -95,6 +97,38 @@ func (r *RegisterReq) ValidateStrict() error {
 	return nil
 }
 
+// GuestReq represents the request body for creating a walk-in guest user
+// POST /internal/users/guests
+type GuestReq struct {
+	PhoneNumber string  `json:"phone_number"`
+	Name        string  `json:"name"`
+	Email       *string `json:"email"`
+}
+
+func (r *GuestReq) ValidateStrict() error {
+	if r == nil { return errors.New("invalid request") }
+	r.PhoneNumber = strings.TrimSpace(r.PhoneNumber)
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Email != nil { v := strings.TrimSpace(*r.Email); r.Email = &v }
+	if r.PhoneNumber == "" || r.Name == "" {
+		return errors.New("missing required fields")
+	}
+	if !utils.ValidPhone(r.PhoneNumber) {
+		return errors.New("invalid phone number format")
+	}
+	if len(r.Name) < 2 || len(r.Name) > 100 || !nameRe.MatchString(r.Name) {
+		return errors.New("invalid name")
+	}
+	if r.Email != nil && *r.Email != "" {
+		if _, err := mail.ParseAddress(*r.Email); err != nil {
+			return errors.New("invalid email")
+		}
+	} else {
+		r.Email = nil
+	}
+	return nil
+}
+
 // AuthReq represents the request body for authenticating using OTP
 // POST /user/authenticate
 type AuthReq struct {
@@ -118,6 +152,44 @@ func (r *AuthReq) ValidateStrict() error {
 	return nil
 }
 
+// PhoneChangeReq represents the request body for starting a phone number change
+// POST /user/phone/change-request
+type PhoneChangeReq struct {
+	PhoneNumber string `json:"phone_number"`
+	Channel     string `json:"channel,omitempty"`
+}
+
+func (r *PhoneChangeReq) ValidateStrict() error {
+	if r == nil { return errors.New("invalid request") }
+	r.PhoneNumber = strings.TrimSpace(r.PhoneNumber)
+	if r.PhoneNumber == "" { return errors.New("phone number required") }
+	if !utils.ValidPhone(r.PhoneNumber) { return errors.New("invalid phone number format") }
+	return nil
+}
+
+// PhoneChangeConfirmReq represents the request body for confirming a phone number change
+// POST /user/phone/change-confirm
+type PhoneChangeConfirmReq struct {
+	PhoneNumber string `json:"phone_number"`
+	OTP         string `json:"otp"`
+}
+
+func (r *PhoneChangeConfirmReq) ValidateStrict() error {
+	if r == nil { return errors.New("invalid request") }
+	r.PhoneNumber = strings.TrimSpace(r.PhoneNumber)
+	r.OTP = strings.TrimSpace(r.OTP)
+	if r.PhoneNumber == "" || r.OTP == "" {
+		return errors.New("missing phone or otp")
+	}
+	if !utils.ValidPhone(r.PhoneNumber) {
+		return errors.New("invalid phone number format")
+	}
+	if !otpRe.MatchString(r.OTP) {
+		return errors.New("invalid otp format")
+	}
+	return nil
+}
+
 // UpdateReq represents the request body for updating a user profile
 // PUT /users/{id}
 type UpdateReq struct {
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-service/internal/service"
+
+	"github.com/EricsAntony/salon/salon-shared/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const testOTPCode = "482913"
+
+// fakeOTPService returns a fixed OTP. Other methods panic if called.
+type fakeOTPService struct {
+	service.UserService
+}
+
+func (fakeOTPService) RequestOTP(ctx context.Context, phone, channel string) (*service.OTPSent, error) {
+	return &service.OTPSent{Code: testOTPCode, Channel: "sms"}, nil
+}
+
+// captureLogs sends the global logger to a buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	previous, previousLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&logs)
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	t.Cleanup(func() {
+		log.Logger = previous
+		zerolog.SetGlobalLevel(previousLevel)
+	})
+	return &logs
+}
+
+func TestRequestOTPEchoesCodeOnlyOutsideProduction(t *testing.T) {
+	tests := []struct {
+		env      string
+		wantCode bool
+	}{
+		{env: "production", wantCode: false},
+		{env: "staging", wantCode: false},
+		{env: "", wantCode: false},
+		{env: "dev", wantCode: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("env "+tt.env, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			h := NewHandler(fakeOTPService{}, nil, &config.Config{Env: tt.env}, "")
+			r := chi.NewRouter()
+			r.Use(RequestLogger)
+			h.RegisterRoutes(r)
+
+			req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"phone_number":"+919876543210"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("POST /otp/request = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if got := strings.Contains(rec.Body.String(), testOTPCode); got != tt.wantCode {
+				t.Errorf("response contains the code = %v, want %v: %s", got, tt.wantCode, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), `"status":"otp_sent"`) {
+				t.Errorf("response is missing the otp_sent status: %s", rec.Body.String())
+			}
+			if logs.Len() == 0 {
+				t.Fatal("request wrote no logs to check")
+			}
+			if strings.Contains(logs.String(), testOTPCode) {
+				t.Errorf("logs contain the OTP code: %s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireServiceToken only admits callers presenting the shared service
+// token as a Bearer credential. Customer JWTs never match it, so internal
+// routes stay closed to end users. If no token is configured every request
+// is rejected.
+func RequireServiceToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			presented := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+			if token == "" || !strings.HasPrefix(authHeader, "Bearer ") || presented == "" {
+				writeErr(w, http.StatusUnauthorized, "service token required")
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				writeErr(w, http.StatusForbidden, "invalid service token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,19 +3,33 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/subosito/gotenv"
 	"github.com/spf13/viper"
+	sharedAuth "github.com/EricsAntony/salon/salon-shared/auth"
 	sharedConfig "github.com/EricsAntony/salon/salon-shared/config"
+	"github.com/EricsAntony/salon/salon-shared/configcheck"
+	"github.com/EricsAntony/salon/salon-shared/requestbody"
+
+	"user-service/internal/db"
 )
 
 type Config struct {
 	Env    string
 	Server struct {
 		Port string
+		// MaxBodyBytes is the largest request body accepted
+		MaxBodyBytes int
 	}
 	DB struct {
 		URL string
+		// Connection pool limits
+		MaxConns                 int
+		MinConns                 int
+		MaxConnIdleMinutes       int
+		MaxConnLifetimeMinutes   int
+		HealthCheckPeriodSeconds int
 	}
 	JWT struct {
 		AccessSecret     string
@@ -27,14 +41,32 @@ type Config struct {
 		ExpiryMinutes int
 		MaxFailedAttempts int
 		FailureWindowMinutes int
+		// LockoutMinutes is how long a phone stays locked after MaxFailedAttempts
+		LockoutMinutes int
 	}
 	RateLimit struct {
 		OTPRequestsPerMinute int
 	}
+	// SMS selects the provider OTP codes are delivered through
+	SMS    sharedAuth.OTPSenderConfig
 	Log struct {
 		Level       string
 		ServiceName string
 	}
+	Notification struct {
+		URL string
+	}
+	EmailVerification struct {
+		TokenTTLHours     int
+		MaxSendsPerWindow int
+		SendWindowMinutes int
+		// LinkBaseURL is the public base URL used to build verification links
+		LinkBaseURL string
+	}
+	// Internal holds the shared secret other services present on /internal routes
+	Internal struct {
+		ServiceToken string
+	}
 }
 
 func Load() (*Config, error) {
@@ -50,15 +82,37 @@ func Load() (*Config, error) {
 
 	// Defaults
 	v.SetDefault("server.port", "8080")
+	v.SetDefault("server.maxbodybytes", requestbody.DefaultMaxBytes)
 	v.SetDefault("env", "dev")
-	v.SetDefault("jwt.accessttlminutes", 15)
-	v.SetDefault("jwt.refreshttldays", 7)
+	v.SetDefault("db.maxconns", 10)
+	v.SetDefault("db.minconns", 2)
+	v.SetDefault("db.maxconnidleminutes", 30)
+	v.SetDefault("db.maxconnlifetimeminutes", 60)
+	v.SetDefault("db.healthcheckperiodseconds", 60)
+	v.SetDefault("jwt.accessttlminutes", sharedConfig.DefaultAccessTTLMinutes)
+	v.SetDefault("jwt.refreshttldays", sharedConfig.DefaultRefreshTTLDays)
 	v.SetDefault("otp.expiryminutes", 5)
 	v.SetDefault("otp.maxfailedattempts", 3)
 	v.SetDefault("otp.failurewindowminutes", 15)
+	v.SetDefault("otp.lockoutminutes", 15)
 	v.SetDefault("ratelimit.otprequestsperminute", 3)
+	v.SetDefault("sms.provider", sharedAuth.OTPProviderLog)
+	v.SetDefault("sms.twilioaccountsid", "")
+	v.SetDefault("sms.twilioauthtoken", "")
+	v.SetDefault("sms.twiliofromnumber", "")
+	v.SetDefault("sms.msg91authkey", "")
+	v.SetDefault("sms.msg91templateid", "")
+	v.SetDefault("sms.twiliowhatsappfrom", "")
+	v.SetDefault("sms.channels", []string{sharedAuth.OTPChannelSMS})
+	v.SetDefault("sms.fallbackafterseconds", 0)
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.servicename", "user-service")
+	v.SetDefault("internal.servicetoken", "")
+	v.SetDefault("notification.url", "http://localhost:8084")
+	v.SetDefault("emailverification.tokenttlhours", 24)
+	v.SetDefault("emailverification.maxsendsperwindow", 3)
+	v.SetDefault("emailverification.sendwindowminutes", 60)
+	v.SetDefault("emailverification.linkbaseurl", "http://localhost:8080")
 
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -74,6 +128,57 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// Validate reports every missing or out of range setting at once. Secrets must
+// be at least configcheck.MinSecretLength characters outside development.
+func (c *Config) Validate() error {
+	var p configcheck.Problems
+	strict := !configcheck.IsDevelopment(c.Env)
+
+	p.Port("USER_SERVICE_SERVER_PORT", c.Server.Port)
+	p.Positive("USER_SERVICE_SERVER_MAXBODYBYTES", c.Server.MaxBodyBytes)
+	p.Required("USER_SERVICE_DB_URL", c.DB.URL)
+	p.Positive("USER_SERVICE_DB_MAXCONNS", c.DB.MaxConns)
+	p.Range("USER_SERVICE_DB_MINCONNS", c.DB.MinConns, 0, c.DB.MaxConns)
+	p.Positive("USER_SERVICE_DB_MAXCONNIDLEMINUTES", c.DB.MaxConnIdleMinutes)
+	p.Positive("USER_SERVICE_DB_MAXCONNLIFETIMEMINUTES", c.DB.MaxConnLifetimeMinutes)
+	p.Positive("USER_SERVICE_DB_HEALTHCHECKPERIODSECONDS", c.DB.HealthCheckPeriodSeconds)
+	p.Secret("USER_SERVICE_JWT_ACCESSSECRET", c.JWT.AccessSecret, strict)
+	p.Secret("USER_SERVICE_JWT_REFRESHSECRET", c.JWT.RefreshSecret, strict)
+	if c.JWT.AccessSecret != "" && c.JWT.AccessSecret == c.JWT.RefreshSecret {
+		p.Addf("USER_SERVICE_JWT_ACCESSSECRET and USER_SERVICE_JWT_REFRESHSECRET must differ")
+	}
+	p.Check(sharedConfig.ValidateJWTTTLs(c.JWT.AccessTTLMinutes, c.JWT.RefreshTTLDays))
+	if c.Internal.ServiceToken != "" {
+		p.Secret("USER_SERVICE_INTERNAL_SERVICETOKEN", c.Internal.ServiceToken, strict)
+	}
+
+	p.Positive("USER_SERVICE_OTP_EXPIRYMINUTES", c.OTP.ExpiryMinutes)
+	p.Positive("USER_SERVICE_OTP_MAXFAILEDATTEMPTS", c.OTP.MaxFailedAttempts)
+	p.Positive("USER_SERVICE_OTP_FAILUREWINDOWMINUTES", c.OTP.FailureWindowMinutes)
+	p.Positive("USER_SERVICE_OTP_LOCKOUTMINUTES", c.OTP.LockoutMinutes)
+	p.Positive("USER_SERVICE_RATELIMIT_OTPREQUESTSPERMINUTE", c.RateLimit.OTPRequestsPerMinute)
+	p.Check(c.SMS.Validate())
+
+	p.URL("USER_SERVICE_NOTIFICATION_URL", c.Notification.URL)
+	p.URL("USER_SERVICE_EMAILVERIFICATION_LINKBASEURL", c.EmailVerification.LinkBaseURL)
+	p.Positive("USER_SERVICE_EMAILVERIFICATION_TOKENTTLHOURS", c.EmailVerification.TokenTTLHours)
+	p.Positive("USER_SERVICE_EMAILVERIFICATION_MAXSENDSPERWINDOW", c.EmailVerification.MaxSendsPerWindow)
+	p.Positive("USER_SERVICE_EMAILVERIFICATION_SENDWINDOWMINUTES", c.EmailVerification.SendWindowMinutes)
+
+	return p.Err("user-service")
+}
+
+// DBPool returns the connection pool limits for the pgx pool
+func (c *Config) DBPool() db.PoolConfig {
+	return db.PoolConfig{
+		MaxConns:          int32(c.DB.MaxConns),
+		MinConns:          int32(c.DB.MinConns),
+		MaxConnIdleTime:   time.Duration(c.DB.MaxConnIdleMinutes) * time.Minute,
+		MaxConnLifetime:   time.Duration(c.DB.MaxConnLifetimeMinutes) * time.Minute,
+		HealthCheckPeriod: time.Duration(c.DB.HealthCheckPeriodSeconds) * time.Second,
+	}
+}
+
 // ToSharedConfig converts local config to shared config for compatibility
 func (c *Config) ToSharedConfig() *sharedConfig.Config {
 	return &sharedConfig.Config{
@@ -3,6 +3,9 @@ package errors
 import (
 	"errors"
 	"net/http"
+
+	sharederrors "github.com/EricsAntony/salon/salon-shared/errors"
+	"github.com/EricsAntony/salon/salon-shared/lockout"
 )
 
 // Application error types
@@ -18,36 +21,40 @@ var (
 	ErrRateLimited       = errors.New("rate limited")
 	ErrInternalError     = errors.New("internal server error")
 	ErrUserNotRegistered = errors.New("user not registered")
+	ErrPhoneTaken        = errors.New("phone number already registered")
+	ErrPhoneUnchanged    = errors.New("phone number unchanged")
+	ErrNoEmail           = errors.New("no email address on account")
+	ErrEmailVerified     = errors.New("email already verified")
+	ErrInvalidEmailToken = errors.New("invalid or expired email verification token")
 )
 
 const (
 	OtpExpired        = "OTP_EXPIRED"
 	UserNotRegistered = "USER_NOT_REGISTERED"
+	PhoneTaken        = "PHONE_TAKEN"
+	OTPLocked         = "OTP_LOCKED"
 )
 
-// APIError represents an API error with HTTP status code
-type APIError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Type    string `json:"type,omitempty"`
-}
-
-func (e APIError) Error() string {
-	return e.Message
-}
+// APIError is the shared error payload, so user-service errors use the same
+// envelope as every other service
+type APIError = sharederrors.APIError
 
 // NewAPIError creates a new API error
 func NewAPIError(code int, message, errorType string) *APIError {
-	return &APIError{
-		Code:    code,
-		Message: message,
-		Type:    errorType,
-	}
+	return sharederrors.NewAPIError(code, message, errorType)
 }
 
 // MapToAPIError maps internal errors to API errors
 func MapToAPIError(err error) *APIError {
+	var locked *lockout.LockedError
+	var validationErrs sharederrors.ValidationErrors
 	switch {
+	case errors.As(err, &validationErrs):
+		return sharederrors.MapToAPIError(validationErrs)
+	case errors.As(err, &locked):
+		apiErr := NewAPIError(http.StatusTooManyRequests, locked.Error(), OTPLocked)
+		apiErr.RetryAfterSeconds = locked.RetryAfterSeconds()
+		return apiErr
 	case errors.Is(err, ErrInvalidInput):
 		return NewAPIError(http.StatusBadRequest, "Invalid input provided", "validation_error")
 	case errors.Is(err, ErrUserNotFound):
@@ -66,6 +73,18 @@ func MapToAPIError(err error) *APIError {
 		return NewAPIError(http.StatusUnauthorized, "Your OTP has expired", OtpExpired)
 	case errors.Is(err, ErrUserNotRegistered):
 		return NewAPIError(http.StatusNotFound, "User not found", UserNotRegistered)
+	case errors.Is(err, ErrPhoneTaken):
+		return NewAPIError(http.StatusConflict, "Phone number is already registered to another account", PhoneTaken)
+	case errors.Is(err, ErrNoEmail):
+		return NewAPIError(http.StatusBadRequest, "Add an email address before verifying it", "validation_error")
+	case errors.Is(err, ErrEmailVerified):
+		return NewAPIError(http.StatusConflict, "Email address is already verified", "conflict")
+	case errors.Is(err, ErrInvalidEmailToken):
+		return NewAPIError(http.StatusBadRequest, "Verification link is invalid or has expired", "validation_error")
+	case errors.Is(err, ErrPhoneUnchanged):
+		return NewAPIError(http.StatusBadRequest, "New phone number matches the current one", "validation_error")
+	case errors.Is(err, sharederrors.ErrServiceUnavailable):
+		return NewAPIError(http.StatusServiceUnavailable, "Could not send the code, please try again", sharederrors.ErrorTypeUnavailable)
 	default:
 		return NewAPIError(http.StatusInternalServerError, "Internal server error", "internal_error")
 	}
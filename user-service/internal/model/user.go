@@ -13,16 +13,20 @@ const (
 )
 
 type User struct {
-	ID          string    `json:"id"`
-	PhoneNumber string    `json:"phone_number"`
-	Name        string    `json:"name"`
-	Gender      Gender    `json:"gender"`
-	Email       *string   `json:"email,omitempty"`
-	Location    *string   `json:"location,omitempty"`
-	Lat         *float64  `json:"lat,omitempty"`
-	Lng         *float64  `json:"lng,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	PhoneNumber   string    `json:"phone_number"`
+	Name          string    `json:"name"`
+	Gender        Gender    `json:"gender"`
+	Email         *string   `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	// IsGuest marks a record created by staff for a walk-in customer who has
+	// not registered; Gender is empty until they do
+	IsGuest       bool      `json:"is_guest"`
+	Location      *string   `json:"location,omitempty"`
+	Lat           *float64  `json:"lat,omitempty"`
+	Lng           *float64  `json:"lng,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type RefreshToken struct {
@@ -34,6 +38,17 @@ type RefreshToken struct {
 	Revoked   bool
 }
 
+// EmailVerificationToken is a single-use token proving ownership of Email
+type EmailVerificationToken struct {
+	ID        int64
+	UserID    string
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
 type OTP struct {
 	ID          int64
 	PhoneNumber string
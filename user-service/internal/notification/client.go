@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// EventEmailVerification is the notification event type for verification
+// emails. notification-service should treat it as transactional so user
+// preferences never suppress it.
+const EventEmailVerification = "user.email_verification"
+
+// Client sends notifications through notification-service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a notification-service client
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type sendRequest struct {
+	UserID    string `json:"user_id,omitempty"`
+	Type      string `json:"type"`
+	EventType string `json:"event_type,omitempty"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject,omitempty"`
+	Content   string `json:"content"`
+}
+
+// SendEmailVerification emails the verification link to the user
+func (c *Client) SendEmailVerification(ctx context.Context, userID, email, link string) error {
+	return c.send(ctx, sendRequest{
+		UserID:    userID,
+		Type:      "email",
+		EventType: EventEmailVerification,
+		Recipient: email,
+		Subject:   "Verify your email address",
+		Content:   fmt.Sprintf("Please confirm your email address by opening this link: %s\n\nIf you did not add this address to your account, you can ignore this email.", link),
+	})
+}
+
+func (c *Client) send(ctx context.Context, body sendRequest) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/notifications/send", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call notification service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notification service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
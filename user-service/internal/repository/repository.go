@@ -5,9 +5,12 @@ import (
 	"errors"
 	"time"
 
+	"github.com/EricsAntony/salon/salon-shared/lockout"
+	appErrors "user-service/internal/errors"
 	models "user-service/internal/model"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
@@ -16,7 +19,12 @@ type UserRepository interface {
 	Create(ctx context.Context, u *models.User) error
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	GetByPhone(ctx context.Context, phone string) (*models.User, error)
+	// ClaimGuest completes a guest record with the registration details in u.
+	// It returns pgx.ErrNoRows when u.ID is not a guest.
+	ClaimGuest(ctx context.Context, u *models.User) error
 	Update(ctx context.Context, u *models.User) error
+	ChangePhone(ctx context.Context, id, phone string) error
+	MarkEmailVerified(ctx context.Context, tokenHash string, now time.Time) (string, error)
 	Delete(ctx context.Context, id string) error
 	HealthCheck(ctx context.Context) error
 }
@@ -27,7 +35,7 @@ func (r *tokenRepository) RevokeExact(ctx context.Context, userID, tokenHash str
 }
 
 func (r *userRepository) Update(ctx context.Context, u *models.User) error {
-	ct, err := r.db.Exec(ctx, `UPDATE users SET phone_number = $2, name = $3, gender = $4, email = $5, location = $6, lat = COALESCE($7, lat), lng = COALESCE($8, lng), updated_at = NOW() WHERE id = $1`,
+	ct, err := r.db.Exec(ctx, `UPDATE users SET phone_number = $2, name = $3, gender = NULLIF($4::text, '')::gender, email = $5, location = $6, lat = COALESCE($7, lat), lng = COALESCE($8, lng), updated_at = NOW() WHERE id = $1`,
 		u.ID, u.PhoneNumber, u.Name, u.Gender, u.Email, u.Location, u.Lat, u.Lng)
 	if err != nil {
 		return err
@@ -38,19 +46,101 @@ func (r *userRepository) Update(ctx context.Context, u *models.User) error {
 	return nil
 }
 
+// ChangePhone updates a user's phone number and revokes their refresh tokens in
+// one transaction. It returns appErrors.ErrPhoneTaken when the number belongs to
+// another user and pgx.ErrNoRows when the user does not exist.
+func (r *userRepository) ChangePhone(ctx context.Context, id, phone string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `UPDATE users SET phone_number = $2, updated_at = NOW() WHERE id = $1`, id, phone)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return appErrors.ErrPhoneTaken
+		}
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked = true WHERE user_id = $1 AND revoked = false`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkEmailVerified consumes an unused, unexpired verification token and marks
+// the user's email verified, provided the email has not changed since the
+// token was issued. It returns the user ID, or pgx.ErrNoRows when the token is
+// unknown, used, expired or stale.
+func (r *userRepository) MarkEmailVerified(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, email string
+	row := tx.QueryRow(ctx, `UPDATE email_verification_tokens SET used_at = $2 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2 RETURNING user_id, email`, tokenHash, now)
+	if err := row.Scan(&userID, &email); err != nil {
+		return "", err
+	}
+	ct, err := tx.Exec(ctx, `UPDATE users SET email_verified = true, updated_at = NOW() WHERE id = $1 AND email = $2`, userID, email)
+	if err != nil {
+		return "", err
+	}
+	if ct.RowsAffected() == 0 {
+		return "", pgx.ErrNoRows
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
 func (r *userRepository) Delete(ctx context.Context, id string) error {
 	_, err := r.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
 	return err
 }
 
 type OTPRepository interface {
-	Create(ctx context.Context, phone, codeHash string, expiresAt time.Time) error
+	Create(ctx context.Context, phone, codeHash string, expiresAt time.Time) (int64, error)
 	GetLatest(ctx context.Context, phone string) (*models.OTP, error)
 	IncrementAttempts(ctx context.Context, id int64) error
-	GetFailedAttemptsCount(ctx context.Context, phone string, windowMinutes int) (int, error)
+	// SetDeliveryChannel records the channel that last delivered the code
+	SetDeliveryChannel(ctx context.Context, id int64, channel string) error
+	MarkVerified(ctx context.Context, id int64) error
+	// IsPending reports whether the code is the latest for its phone, unexpired and not yet verified
+	IsPending(ctx context.Context, id int64) (bool, error)
 	DeleteExpired(ctx context.Context, maxAge time.Duration) (int, error)
 }
 
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, userID, email, tokenHash string, expiresAt time.Time) error
+	CountSince(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+// RevocationRepository records when a user's access tokens were revoked
+type RevocationRepository interface {
+	RevokeAccessTokens(ctx context.Context, userID string, at time.Time) error
+	TokensValidAfter(ctx context.Context, userID string) (time.Time, error)
+}
+
+type revocationRepository struct{ db *pgxpool.Pool }
+
+func NewRevocationRepository(db *pgxpool.Pool) RevocationRepository {
+	return &revocationRepository{db}
+}
+
+type lockoutStore struct{ db *pgxpool.Pool }
+
+// NewLockoutStore returns the OTP lockout store shared with salon-service
+func NewLockoutStore(db *pgxpool.Pool) lockout.Store { return &lockoutStore{db} }
+
 type TokenRepository interface {
 	Save(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
 	RevokeAllForUser(ctx context.Context, userID string) error
@@ -64,22 +154,39 @@ type otpRepository struct{ db *pgxpool.Pool }
 
 type tokenRepository struct{ db *pgxpool.Pool }
 
+type emailVerificationRepository struct{ db *pgxpool.Pool }
+
 func NewUserRepository(db *pgxpool.Pool) UserRepository   { return &userRepository{db} }
 func NewOTPRepository(db *pgxpool.Pool) OTPRepository     { return &otpRepository{db} }
 func NewTokenRepository(db *pgxpool.Pool) TokenRepository { return &tokenRepository{db} }
+func NewEmailVerificationRepository(db *pgxpool.Pool) EmailVerificationRepository {
+	return &emailVerificationRepository{db}
+}
 
 func (r *userRepository) Create(ctx context.Context, u *models.User) error {
-	_, err := r.db.Exec(ctx, `INSERT INTO users (id, phone_number, name, gender, email, location, lat, lng, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8, NOW(), NOW())`,
-		u.ID, u.PhoneNumber, u.Name, u.Gender, u.Email, u.Location, u.Lat, u.Lng)
+	_, err := r.db.Exec(ctx, `INSERT INTO users (id, phone_number, name, gender, email, location, lat, lng, is_guest, created_at, updated_at) VALUES ($1,$2,$3,NULLIF($4::text, '')::gender,$5,$6,$7,$8,$9, NOW(), NOW())`,
+		u.ID, u.PhoneNumber, u.Name, u.Gender, u.Email, u.Location, u.Lat, u.Lng, u.IsGuest)
 	return err
 }
 
+func (r *userRepository) ClaimGuest(ctx context.Context, u *models.User) error {
+	ct, err := r.db.Exec(ctx, `UPDATE users SET name = $2, gender = $3, email = $4, email_verified = false, location = $5, lat = $6, lng = $7, is_guest = false, updated_at = NOW() WHERE id = $1 AND is_guest`,
+		u.ID, u.Name, u.Gender, u.Email, u.Location, u.Lat, u.Lng)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
-	row := r.db.QueryRow(ctx, `SELECT id, phone_number, name, gender, email, location, lat, lng, created_at, updated_at FROM users WHERE id = $1`, id)
+	row := r.db.QueryRow(ctx, `SELECT id, phone_number, name, COALESCE(gender::text, ''), email, email_verified, is_guest, location, lat, lng, created_at, updated_at FROM users WHERE id = $1`, id)
 	var u models.User
 	var email, loc *string
 	var lat, lng *float64
-	if err := row.Scan(&u.ID, &u.PhoneNumber, &u.Name, &u.Gender, &email, &loc, &lat, &lng, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.PhoneNumber, &u.Name, &u.Gender, &email, &u.EmailVerified, &u.IsGuest, &loc, &lat, &lng, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
@@ -93,11 +200,11 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User,
 }
 
 func (r *userRepository) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
-	row := r.db.QueryRow(ctx, `SELECT id, phone_number, name, gender, email, location, lat, lng, created_at, updated_at FROM users WHERE phone_number = $1`, phone)
+	row := r.db.QueryRow(ctx, `SELECT id, phone_number, name, COALESCE(gender::text, ''), email, email_verified, is_guest, location, lat, lng, created_at, updated_at FROM users WHERE phone_number = $1`, phone)
 	var u models.User
 	var email, loc *string
 	var lat, lng *float64
-	if err := row.Scan(&u.ID, &u.PhoneNumber, &u.Name, &u.Gender, &email, &loc, &lat, &lng, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.PhoneNumber, &u.Name, &u.Gender, &email, &u.EmailVerified, &u.IsGuest, &loc, &lat, &lng, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
@@ -115,11 +222,33 @@ func (r *userRepository) HealthCheck(ctx context.Context) error {
 	return r.db.Ping(ctx)
 }
 
-func (r *otpRepository) Create(ctx context.Context, phone, codeHash string, expiresAt time.Time) error {
-	_, err := r.db.Exec(ctx, `INSERT INTO otps (phone_number, code_hash, expires_at, created_at) VALUES ($1,$2,$3,NOW())`, phone, codeHash, expiresAt)
+func (r *otpRepository) Create(ctx context.Context, phone, codeHash string, expiresAt time.Time) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `INSERT INTO otps (phone_number, code_hash, expires_at, created_at) VALUES ($1,$2,$3,NOW()) RETURNING id`, phone, codeHash, expiresAt).Scan(&id)
+	return id, err
+}
+
+func (r *otpRepository) SetDeliveryChannel(ctx context.Context, id int64, channel string) error {
+	_, err := r.db.Exec(ctx, `UPDATE otps SET delivery_channel = $2 WHERE id = $1`, id, channel)
 	return err
 }
 
+func (r *otpRepository) MarkVerified(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE otps SET verified_at = NOW() WHERE id = $1 AND verified_at IS NULL`, id)
+	return err
+}
+
+func (r *otpRepository) IsPending(ctx context.Context, id int64) (bool, error) {
+	var pending bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM otps o
+			WHERE o.id = $1 AND o.verified_at IS NULL AND o.expires_at > NOW()
+			  AND NOT EXISTS (SELECT 1 FROM otps n WHERE n.phone_number = o.phone_number AND n.id > o.id)
+		)`, id).Scan(&pending)
+	return pending, err
+}
+
 func (r *otpRepository) GetLatest(ctx context.Context, phone string) (*models.OTP, error) {
 	row := r.db.QueryRow(ctx, `SELECT id, phone_number, code_hash, expires_at, attempts, created_at FROM otps WHERE phone_number = $1 ORDER BY id DESC LIMIT 1`, phone)
 	var o models.OTP
@@ -143,21 +272,6 @@ func (r *otpRepository) IncrementAttempts(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *otpRepository) GetFailedAttemptsCount(ctx context.Context, phone string, windowMinutes int) (int, error) {
-	row := r.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(attempts), 0) 
-		FROM otps 
-		WHERE phone_number = $1 
-		AND created_at > NOW() - (INTERVAL '1 minute') * $2
-		AND attempts > 0`, phone, windowMinutes)
-
-	var count int
-	if err := row.Scan(&count); err != nil {
-		return 0, err
-	}
-	return count, nil
-}
-
 func (r *otpRepository) DeleteExpired(ctx context.Context, maxAge time.Duration) (int, error) {
 	cutoff := time.Now().Add(-maxAge)
 	result, err := r.db.Exec(ctx, `DELETE FROM otps WHERE created_at < $1`, cutoff)
@@ -188,3 +302,77 @@ func (r *tokenRepository) IsValid(ctx context.Context, userID, tokenHash string,
 	}
 	return true, nil
 }
+
+func (r *emailVerificationRepository) Create(ctx context.Context, userID, email, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at, created_at) VALUES ($1,$2,$3,$4,NOW())`, userID, email, tokenHash, expiresAt)
+	return err
+}
+
+func (r *emailVerificationRepository) CountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	row := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM email_verification_tokens WHERE user_id = $1 AND created_at > $2`, userID, since)
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *lockoutStore) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	row := r.db.QueryRow(ctx, `
+		INSERT INTO otp_lockouts (phone_key, failures, window_started_at, updated_at)
+		VALUES ($1, 1, $2, $2)
+		ON CONFLICT (phone_key) DO UPDATE SET
+			failures = CASE WHEN otp_lockouts.window_started_at < $3 THEN 1 ELSE otp_lockouts.failures + 1 END,
+			window_started_at = CASE WHEN otp_lockouts.window_started_at < $3 THEN $2 ELSE otp_lockouts.window_started_at END,
+			updated_at = $2
+		RETURNING failures`, key, now, windowStart)
+	var failures int
+	if err := row.Scan(&failures); err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func (r *lockoutStore) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE otp_lockouts SET locked_until = $2, failures = 0, updated_at = NOW() WHERE phone_key = $1`, key, until)
+	return err
+}
+
+func (r *lockoutStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	row := r.db.QueryRow(ctx, `SELECT locked_until FROM otp_lockouts WHERE phone_key = $1`, key)
+	var until *time.Time
+	if err := row.Scan(&until); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+func (r *lockoutStore) Reset(ctx context.Context, key string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM otp_lockouts WHERE phone_key = $1`, key)
+	return err
+}
+
+func (r *revocationRepository) RevokeAccessTokens(ctx context.Context, userID string, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO token_revocations (subject_id, tokens_valid_after, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (subject_id) DO UPDATE SET tokens_valid_after = GREATEST(token_revocations.tokens_valid_after, EXCLUDED.tokens_valid_after), updated_at = NOW()`, userID, at)
+	return err
+}
+
+func (r *revocationRepository) TokensValidAfter(ctx context.Context, userID string) (time.Time, error) {
+	row := r.db.QueryRow(ctx, `SELECT tokens_valid_after FROM token_revocations WHERE subject_id = $1`, userID)
+	var validAfter time.Time
+	if err := row.Scan(&validAfter); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return validAfter, nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
 	"errors"
 	"math/rand"
 	"strings"
@@ -12,9 +14,11 @@ import (
 	"user-service/internal/repository"
 
 	sharedauth "github.com/EricsAntony/salon/salon-shared/auth"
+	"github.com/EricsAntony/salon/salon-shared/lockout"
 	sharedvalidation "github.com/EricsAntony/salon/salon-shared/validation"
 	models "user-service/internal/model"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
 )
 
@@ -30,6 +34,13 @@ type RegisterParams struct {
 	OTP      string
 }
 
+// GuestParams bundles inputs to CreateGuest
+type GuestParams struct {
+	Phone string
+	Name  string
+	Email *string
+}
+
 type UpdateUserParams struct {
 	UserID   string
 	Location *string
@@ -37,51 +48,107 @@ type UpdateUserParams struct {
 	Lng      *float64
 }
 
+// OTPSent is a code that was delivered and the channel that delivered it. The
+// code is only echoed to clients in development.
+type OTPSent struct {
+	Code    string
+	Channel string
+}
+
 type UserService interface {
-	RequestOTP(ctx context.Context, phone string) (string, error)
+	RequestOTP(ctx context.Context, phone, channel string) (*OTPSent, error)
 	Register(ctx context.Context, p RegisterParams) (*models.User, string, string, error)
 	Authenticate(ctx context.Context, phone, otp string) (string, string, string, error)
 	GetUser(ctx context.Context, id string) (*models.User, error)
+	CreateGuest(ctx context.Context, p GuestParams) (*models.User, bool, error)
 	Refresh(ctx context.Context, refreshToken string) (string, string, error)
 	Revoke(ctx context.Context, userID string) error
 	UpdateUser(ctx context.Context, p UpdateUserParams) (*models.User, error)
+	RequestPhoneChange(ctx context.Context, userID, newPhone, channel string) (*OTPSent, error)
+	ConfirmPhoneChange(ctx context.Context, userID, newPhone, otp string) (*models.User, error)
+	SendEmailVerification(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, token string) error
 	DeleteUser(ctx context.Context, requesterID, targetID string) error
 	HealthCheck(ctx context.Context) error
 }
 
+// EmailNotifier delivers email verification links
+type EmailNotifier interface {
+	SendEmailVerification(ctx context.Context, userID, email, link string) error
+}
+
 type userService struct {
-	users  repository.UserRepository
-	otps   repository.OTPRepository
-	tokens repository.TokenRepository
-	jwt    *sharedauth.JWTManager
-	cfg    *config.Config
+	users           repository.UserRepository
+	otps            repository.OTPRepository
+	tokens          repository.TokenRepository
+	verifications   repository.EmailVerificationRepository
+	lockouts        *lockout.Guard
+	revocations     repository.RevocationRepository
+	revocationCache *sharedauth.RevocationCache
+	notifier        EmailNotifier
+	otpDispatcher   *sharedauth.OTPDispatcher
+	jwt             *sharedauth.JWTManager
+	cfg             *config.Config
 }
 
-func NewUserService(u repository.UserRepository, o repository.OTPRepository, t repository.TokenRepository, jwt *sharedauth.JWTManager, cfg *config.Config) UserService {
-	return &userService{users: u, otps: o, tokens: t, jwt: jwt, cfg: cfg}
+func NewUserService(u repository.UserRepository, o repository.OTPRepository, t repository.TokenRepository, ev repository.EmailVerificationRepository, lockouts lockout.Store, revocations repository.RevocationRepository, revocationCache *sharedauth.RevocationCache, notifier EmailNotifier, otpDispatcher *sharedauth.OTPDispatcher, jwt *sharedauth.JWTManager, cfg *config.Config) UserService {
+	guard := lockout.NewGuard(lockouts, lockout.Policy{
+		MaxFailures: cfg.OTP.MaxFailedAttempts,
+		Window:      time.Duration(cfg.OTP.FailureWindowMinutes) * time.Minute,
+		Duration:    time.Duration(cfg.OTP.LockoutMinutes) * time.Minute,
+	}, cfg.Log.ServiceName)
+	return &userService{users: u, otps: o, tokens: t, verifications: ev, lockouts: guard, revocations: revocations, revocationCache: revocationCache, notifier: notifier, otpDispatcher: otpDispatcher, jwt: jwt, cfg: cfg}
 }
 
 
-func (s *userService) RequestOTP(ctx context.Context, phone string) (string, error) {
+// RequestOTP sends a code over channel, or the first configured channel when
+// channel is empty, falling back to the other configured channels
+func (s *userService) RequestOTP(ctx context.Context, phone, channel string) (*OTPSent, error) {
 	// Use shared phone validation and normalization
 	normalizedPhone, err := sharedvalidation.ValidatePhone(phone)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	
+	return s.sendOTP(ctx, normalizedPhone, channel)
+}
+
+// sendOTP generates, stores and delivers an OTP to an already normalized phone
+func (s *userService) sendOTP(ctx context.Context, normalizedPhone, channel string) (*OTPSent, error) {
+	if err := s.otpDispatcher.ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+
 	// Generate OTP using shared utility
 	code, err := sharedvalidation.GenerateOTP()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	
 	exp := time.Now().Add(time.Duration(s.cfg.OTP.ExpiryMinutes) * time.Minute)
 	hash := sharedauth.HashString(code)
-	if err := s.otps.Create(ctx, normalizedPhone, hash, exp); err != nil {
-		return "", err
+	id, err := s.otps.Create(ctx, normalizedPhone, hash, exp)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.otpDispatcher.Send(ctx, normalizedPhone, code, channel)
+	if err != nil {
+		log.Error().Err(err).Str("phone", normalizedPhone).Msg("failed to deliver OTP")
+		return nil, err
 	}
-	sharedauth.SendOTPViaSMS(normalizedPhone, code)
-	return code, nil
+	if err := s.otps.SetDeliveryChannel(ctx, id, delivery.Channel); err != nil {
+		log.Warn().Err(err).Int64("otp_id", id).Msg("failed to record OTP delivery channel")
+	}
+
+	// The timed fallback outlives the request, so it uses its own context
+	s.otpDispatcher.ScheduleFallback(normalizedPhone, code, delivery, sharedauth.OTPFallbackHooks{
+		Pending: func(ctx context.Context) (bool, error) {
+			return s.otps.IsPending(ctx, id)
+		},
+		Delivered: func(ctx context.Context, channel string) error {
+			return s.otps.SetDeliveryChannel(ctx, id, channel)
+		},
+	})
+	return &OTPSent{Code: code, Channel: delivery.Channel}, nil
 }
 
 func (s *userService) verifyOTP(ctx context.Context, phone, otp string) error {
@@ -96,17 +163,14 @@ func (s *userService) verifyOTP(ctx context.Context, phone, otp string) error {
 		return err
 	}
 	
-	// Check rate limiting for failed attempts
-	failedCount, err := s.otps.GetFailedAttemptsCount(ctx, normalizedPhone, s.cfg.OTP.FailureWindowMinutes)
-	log.Info().Int("failure_window_minutes", s.cfg.OTP.FailureWindowMinutes).Msg("OTP failure count")
-	if err != nil {
-		log.Error().Err(err).Str("phone", normalizedPhone).Msg("failed to check OTP failure count")
+	// Reject numbers locked out by repeated failures here or at staff login
+	if err := s.lockouts.Check(ctx, normalizedPhone); err != nil {
+		if errors.Is(err, lockout.ErrLocked) {
+			return err
+		}
+		log.Error().Err(err).Str("phone", normalizedPhone).Msg("failed to check OTP lockout")
 		return appErrors.ErrInternalError
 	}
-	if failedCount >= s.cfg.OTP.MaxFailedAttempts {
-		log.Warn().Str("phone", normalizedPhone).Int("failed_count", failedCount).Msg("OTP rate limit exceeded")
-		return appErrors.ErrRateLimited
-	}
 
 	rec, err := s.otps.GetLatest(ctx, normalizedPhone)
 	if err != nil {
@@ -117,11 +181,23 @@ func (s *userService) verifyOTP(ctx context.Context, phone, otp string) error {
 	}
 	if sharedauth.HashString(otp) != rec.CodeHash {
 		_ = s.otps.IncrementAttempts(ctx, rec.ID)
+		if err := s.lockouts.RecordFailure(ctx, normalizedPhone); err != nil {
+			if errors.Is(err, lockout.ErrLocked) {
+				return err
+			}
+			log.Error().Err(err).Str("phone", normalizedPhone).Msg("failed to record OTP failure")
+		}
 		return appErrors.ErrInvalidOTP
 	}
 	if time.Now().After(rec.ExpiresAt) {
 		return appErrors.ErrOTPExpired
 	}
+	if err := s.otps.MarkVerified(ctx, rec.ID); err != nil {
+		log.Warn().Err(err).Int64("otp_id", rec.ID).Msg("failed to mark OTP verified")
+	}
+	if err := s.lockouts.Reset(ctx, normalizedPhone); err != nil {
+		log.Warn().Err(err).Str("phone", normalizedPhone).Msg("failed to reset OTP failures")
+	}
 	return nil
 }
 
@@ -138,11 +214,14 @@ func (s *userService) Register(ctx context.Context, p RegisterParams) (*models.U
 	if err != nil {
 		return nil, "", "", err
 	}
-	if existing != nil {
+	if existing != nil && !existing.IsGuest {
 		return nil, "", "", errors.New("user already exists; please authenticate")
 	}
-	// Create user
+	// Create user, or complete the guest record staff created for this phone
 	uid := uuid.NewString()
+	if existing != nil {
+		uid = existing.ID
+	}
 	u := &models.User{ID: uid, PhoneNumber: phone, Name: p.Name, Gender: models.Gender(strings.ToLower(p.Gender)), Email: p.Email, Location: p.Location}
 	// Set optional geo fields for response as well
 	u.Lat = p.Lat
@@ -150,7 +229,11 @@ func (s *userService) Register(ctx context.Context, p RegisterParams) (*models.U
 	if u.Gender != models.GenderMale && u.Gender != models.GenderFemale && u.Gender != models.GenderOther {
 		return nil, "", "", errors.New("invalid gender")
 	}
-	if err := s.users.Create(ctx, u); err != nil {
+	if existing != nil {
+		if err := s.users.ClaimGuest(ctx, u); err != nil {
+			return nil, "", "", err
+		}
+	} else if err := s.users.Create(ctx, u); err != nil {
 		return nil, "", "", err
 	}
 	// Tokens
@@ -169,6 +252,13 @@ func (s *userService) Register(ctx context.Context, p RegisterParams) (*models.U
 	if err := s.tokens.Save(ctx, uid, sharedauth.HashString(refresh), rexp); err != nil {
 		return nil, "", "", err
 	}
+	// Registration succeeds even if the verification email can't be sent;
+	// the user can request another one
+	if u.Email != nil && *u.Email != "" {
+		if err := s.SendEmailVerification(ctx, uid); err != nil {
+			log.Warn().Err(err).Str("user_id", uid).Msg("failed to send email verification")
+		}
+	}
 	return u, access, refresh, nil
 }
 
@@ -217,6 +307,33 @@ func (s *userService) GetUser(ctx context.Context, id string) (*models.User, err
 	return u, nil
 }
 
+// CreateGuest returns the user registered with the phone number, creating a
+// guest record when there is none. The boolean reports whether it was created.
+func (s *userService) CreateGuest(ctx context.Context, p GuestParams) (*models.User, bool, error) {
+	phone, err := sharedvalidation.ValidatePhone(p.Phone)
+	if err != nil {
+		return nil, false, err
+	}
+	existing, err := s.users.GetByPhone(ctx, phone)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	u := &models.User{ID: uuid.NewString(), PhoneNumber: phone, Name: p.Name, Email: p.Email, IsGuest: true}
+	if err := s.users.Create(ctx, u); err != nil {
+		// Another request created a user for the phone first
+		if existing, getErr := s.users.GetByPhone(ctx, phone); getErr == nil && existing != nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	log.Info().Str("user_id", u.ID).Msg("guest user created")
+	return u, true, nil
+}
+
 func (s *userService) Refresh(ctx context.Context, refreshToken string) (string, string, error) {
 	claims, err := s.jwt.ValidateRefreshToken(refreshToken)
 	if err != nil {
@@ -249,8 +366,23 @@ func (s *userService) Refresh(ctx context.Context, refreshToken string) (string,
 	return access, newRefresh, nil
 }
 
+// Revoke invalidates the user's refresh tokens and every access token issued
+// so far, in all services
 func (s *userService) Revoke(ctx context.Context, userID string) error {
-	return s.tokens.RevokeAllForUser(ctx, userID)
+	if err := s.tokens.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.revokeAccessTokens(ctx, userID)
+}
+
+// revokeAccessTokens rejects the user's outstanding access tokens. Other
+// services notice within their revocation cache TTL.
+func (s *userService) revokeAccessTokens(ctx context.Context, userID string) error {
+	if err := s.revocations.RevokeAccessTokens(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	s.revocationCache.Forget(userID)
+	return nil
 }
 
 func (s *userService) UpdateUser(ctx context.Context, p UpdateUserParams) (*models.User, error) {
@@ -277,6 +409,121 @@ func (s *userService) UpdateUser(ctx context.Context, p UpdateUserParams) (*mode
 	return u, nil
 }
 
+// RequestPhoneChange sends an OTP to the new phone number. The number must
+// differ from the current one and must not belong to another user.
+func (s *userService) RequestPhoneChange(ctx context.Context, userID, newPhone, channel string) (*OTPSent, error) {
+	phone, err := s.checkPhoneChange(ctx, userID, newPhone)
+	if err != nil {
+		return nil, err
+	}
+	return s.sendOTP(ctx, phone, channel)
+}
+
+// ConfirmPhoneChange verifies the OTP sent to the new number, switches the
+// user's phone number and revokes their refresh tokens so existing sessions
+// have to sign in again.
+func (s *userService) ConfirmPhoneChange(ctx context.Context, userID, newPhone, otp string) (*models.User, error) {
+	phone, err := s.checkPhoneChange(ctx, userID, newPhone)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyOTP(ctx, phone, otp); err != nil {
+		return nil, err
+	}
+	if err := s.users.ChangePhone(ctx, userID, phone); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, appErrors.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := s.revokeAccessTokens(ctx, userID); err != nil {
+		log.Error().Err(err).Str("user_id", userID).Msg("failed to revoke access tokens after phone change")
+	}
+	log.Info().Str("user_id", userID).Msg("phone number changed; sessions revoked")
+	return s.GetUser(ctx, userID)
+}
+
+// checkPhoneChange normalizes newPhone and rejects it if it is the user's
+// current number or is registered to someone else
+func (s *userService) checkPhoneChange(ctx context.Context, userID, newPhone string) (string, error) {
+	phone, err := sharedvalidation.ValidatePhone(newPhone)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if u == nil {
+		return "", appErrors.ErrUserNotFound
+	}
+	if u.PhoneNumber == phone {
+		return "", appErrors.ErrPhoneUnchanged
+	}
+	existing, err := s.users.GetByPhone(ctx, phone)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", appErrors.ErrPhoneTaken
+	}
+	return phone, nil
+}
+
+// SendEmailVerification issues a single-use token for the user's current email
+// and sends the verification link. Sends are capped per user within a window.
+func (s *userService) SendEmailVerification(ctx context.Context, userID string) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return appErrors.ErrUserNotFound
+	}
+	if u.Email == nil || *u.Email == "" {
+		return appErrors.ErrNoEmail
+	}
+	if u.EmailVerified {
+		return appErrors.ErrEmailVerified
+	}
+
+	cfg := s.cfg.EmailVerification
+	since := time.Now().Add(-time.Duration(cfg.SendWindowMinutes) * time.Minute)
+	sent, err := s.verifications.CountSince(ctx, userID, since)
+	if err != nil {
+		return err
+	}
+	if sent >= cfg.MaxSendsPerWindow {
+		return appErrors.ErrRateLimited
+	}
+
+	raw := make([]byte, 32)
+	if _, err := cryptorand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+	exp := time.Now().Add(time.Duration(cfg.TokenTTLHours) * time.Hour)
+	if err := s.verifications.Create(ctx, userID, *u.Email, sharedauth.HashString(token), exp); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(cfg.LinkBaseURL, "/") + "/user/email/verify?token=" + token
+	return s.notifier.SendEmailVerification(ctx, userID, *u.Email, link)
+}
+
+// VerifyEmail consumes a verification token and marks the email verified
+func (s *userService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.users.MarkEmailVerified(ctx, sharedauth.HashString(token), time.Now())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return appErrors.ErrInvalidEmailToken
+		}
+		return err
+	}
+	log.Info().Str("user_id", userID).Msg("email verified")
+	return nil
+}
+
 func (s *userService) DeleteUser(ctx context.Context, requesterID, targetID string) error {
 	if requesterID != targetID {
 		return errors.New("forbidden")
@@ -0,0 +1,9 @@
+-- +migrate Up
+-- Shared with salon-service: failed OTP attempts by phone digits across customer and staff login
+CREATE TABLE IF NOT EXISTS otp_lockouts (
+    phone_key VARCHAR(20) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    window_started_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- Read by every service's auth middleware: access tokens issued before
+-- tokens_valid_after are rejected
+CREATE TABLE IF NOT EXISTS token_revocations (
+    subject_id UUID PRIMARY KEY,
+    tokens_valid_after TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- +migrate Up
+-- Channel that last delivered each code and when it was verified, so timed
+-- fallback to another channel skips codes that were already used
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS delivery_channel TEXT;
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+-- +migrate Up
+-- Guest users are created by salon staff for walk-in bookings with just a name
+-- and phone number. Registering later with the same number claims the record.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ALTER COLUMN gender DROP NOT NULL;